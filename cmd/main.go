@@ -4,28 +4,47 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
-	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/matt-gp/core/logger"
 	"github.com/matt-gp/core/otel"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/handler"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/integration"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/listener"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/metricview"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/mockbackend"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/startup"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/util/cert"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/ipfamily"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/warmup"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/watchdog"
+	otelapi "go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 )
 
 var (
 	errAttrKey                  = "error"
 	httpAddressAttrKey          = "http.address"
 	httpTLSEnabledAttrKey       = "http.tls.enabled"
+	grpcAddressAttrKey          = "grpc.address"
+	tlsClientAuthAttrKey        = "tls.client_auth"
 	httpClientURLAttrKey        = "http.client.url"
 	httpClientTimeoutAttrKey    = "http.client.timeout"
 	httpClientTLSEnabledAttrKey = "http.client.tls.enabled"
+	httpClientDiscoveryAttrKey  = "http.client.discovery.service"
+	serviceInstanceIDAttrKey    = "service.instance.id"
+	integrationsAttrKey         = "integrations"
+	receiverAttrKey             = "receiver"
 )
 
 func main() {
@@ -38,48 +57,112 @@ func main() {
 		panic(err)
 	}
 
+	// Identify this replica in the self-telemetry resource
+	if err := setInstanceResourceAttribute(cfg.Service.InstanceID); err != nil {
+		panic(err)
+	}
+
 	// Initialize OpenTelemetry provider
 	provider, err := otel.NewProvider(ctx)
 	if err != nil {
 		panic(err)
 	}
 
+	// Apply the metric views to the self-instrumentation, including the instruments of the instrumentation libraries
+	views, err := metricview.New(&cfg.MetricViews, provider.MeterProvider)
+	if err != nil {
+		panic(err)
+	}
+	otelapi.SetMeterProvider(views)
+
 	// Initialize OpenTelemetry providers
 	loggingProvider := provider.LoggerProvider.Logger("logs")
-	meterProvider := provider.MeterProvider.Meter("metrics")
+	meterProvider := views.Meter("metrics")
 	tracerProvider := provider.TracerProvider.Tracer("traces")
 
 	// Initialize logger
 	logger.SetProvider(loggingProvider)
 
-	// Start application
-	logger.Info(ctx, "Starting application")
-
 	// Initialize signal handling
 	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Run the mock backend instead of the proxy when requested, before the proxy configuration is validated.
+	if len(os.Args) > 1 && os.Args[1] == "mockbackend" {
+		if err := mockbackend.Run(ctx, cfg); err != nil {
+			logger.Error(ctx, "mock backend failed", attribute.String(errAttrKey, err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Start application
+	logger.Info(ctx, "Starting application",
+		attribute.String(serviceInstanceIDAttrKey, cfg.Service.InstanceID),
+		attribute.StringSlice(integrationsAttrKey, integration.Compiled()),
+	)
+
+	// Fail when the configuration enables an integration the binary was built without
+	if err := integration.Validate(cfg); err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	// Validate the tenant format, forwarding tenants unformatted when configured to tolerate an invalid one
+	if err := cfg.Tenant.ValidateFormat(); err != nil {
+		if cfg.Tenant.InvalidFormat != config.InvalidFormatRaw {
+			logger.Error(ctx, err.Error())
+			os.Exit(1)
+		}
+		logger.Warn(ctx, "forwarding tenants unformatted", attribute.String(errAttrKey, err.Error()))
+		cfg.Tenant.Format = "%s"
+	}
+
+	// Summarize the configuration and warn about the suspicious combinations of settings
+	logger.Info(ctx, "configuration", startup.Banner(cfg)...)
+	for _, warning := range startup.Check(cfg) {
+		logger.Warn(ctx, warning)
+	}
+
+	// Create the TLS session cache shared by all backend clients
+	sessionCache := cert.NewClientSessionCache(cfg.TLSSessionCacheSize)
+
 	// Create HTTP clients for logs
-	logsClient, err := newClient(ctx, &cfg.Logs)
+	logsClient, err := newClient(ctx, &cfg.Logs, cfg.Tenant.Header, sessionCache)
 	if err != nil {
 		logger.Error(ctx, "failed to create logs client", attribute.String(errAttrKey, err.Error()))
 		os.Exit(1)
 	}
 
 	// Create HTTP clients for metrics
-	metricsClient, err := newClient(ctx, &cfg.Metrics)
+	metricsClient, err := newClient(ctx, &cfg.Metrics, cfg.Tenant.Header, sessionCache)
 	if err != nil {
 		logger.Error(ctx, "failed to create metrics client", attribute.String(errAttrKey, err.Error()))
 		os.Exit(1)
 	}
 
 	// Create HTTP clients for traces
-	tracesClient, err := newClient(ctx, &cfg.Traces)
+	tracesClient, err := newClient(ctx, &cfg.Traces, cfg.Tenant.Header, sessionCache)
 	if err != nil {
 		logger.Error(ctx, "failed to create traces client", attribute.String(errAttrKey, err.Error()))
 		os.Exit(1)
 	}
 
+	// Create HTTP clients for profiles, only forwarded when a profiles backend is configured
+	var profilesClient processor.Client
+	if cfg.Profiles.Address != "" {
+		profilesClient, err = newClient(ctx, &cfg.Profiles, cfg.Tenant.Header, sessionCache)
+		if err != nil {
+			logger.Error(ctx, "failed to create profiles client", attribute.String(errAttrKey, err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	// Pre-establish the backend connections so the first requests do not pay the handshake latency
+	if cfg.Warmup.Enabled {
+		warmUp(ctx, cfg, logsClient, metricsClient, tracesClient, profilesClient)
+	}
+
 	// Initialize handlers
 	h, err := handler.New(
 		cfg,
@@ -87,6 +170,7 @@ func main() {
 		logsClient,
 		metricsClient,
 		tracesClient,
+		profilesClient,
 		meterProvider,
 		tracerProvider,
 	)
@@ -95,22 +179,27 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Health check endpoint
-	h.Register(ctx, "GET /health", h.Health)
+	// Watch the goroutines, file descriptors and heap objects of the proxy to catch leaks
+	dog, err := watchdog.New(&cfg.Watchdog, meterProvider)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+	go dog.Run(ctx)
 
-	// register the logs handler.
-	h.Register(ctx, "POST /v1/logs", h.Logs)
+	// Restore and periodically persist the per-tenant usage counters, until the requests in flight at shutdown are
+	// drained so their counters are saved too
+	stopPersist := persistStats(ctx, h, cfg)
 
-	// register the metrics handler.
-	h.Register(ctx, "POST /v1/metrics", h.Metrics)
+	// Restore the circuits opened through the admin API and persist later changes
+	closeCircuits := restoreCircuits(ctx, h, cfg)
+	defer closeCircuits()
 
-	// register the traces handler.
-	h.Register(ctx, "POST /v1/traces", h.Traces)
+	// Register the routes of the endpoints enabled by the configuration
+	registerRoutes(ctx, h, cfg)
 
-	// Initialize TLS configuration
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS13,
-	}
+	// Start the receivers compiled into the binary and enabled by the configuration
+	startReceivers(ctx, h, cfg, meterProvider)
 
 	// Add attributes for TLS configuration
 	tlsEnabled := cert.TLSEnabled(&cfg.HTTP.TLS)
@@ -119,50 +208,45 @@ func main() {
 		attribute.Bool(httpTLSEnabledAttrKey, tlsEnabled),
 	}
 
-	// Load TLS certificates
+	// Load the TLS configuration, reloaded on SIGHUP so certificates and the client auth policy change without a restart
+	var tlsConfig, grpcTLSConfig *tls.Config
 	if tlsEnabled {
-		certPair, err := tls.LoadX509KeyPair(cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile)
+		reloader, err := cert.NewServerReloader(&cfg.HTTP.TLS)
 		if err != nil {
-			logger.Error(ctx, "unable to read certificate or key file",
+			logger.Error(ctx, "unable to load TLS configuration",
 				append(httpAttributes, attribute.String(errAttrKey, err.Error()))...,
 			)
 			os.Exit(1)
 		}
 
-		caPool := x509.NewCertPool()
-		caCert, err := os.ReadFile(cfg.HTTP.TLS.CAFile)
-		if err != nil {
-			logger.Error(ctx, "unable to read CA file",
-				append(httpAttributes, attribute.String(errAttrKey, err.Error()))...,
-			)
-			os.Exit(1)
+		nextProtos := []string{"http/1.1"}
+		if cfg.HTTP.HTTP2 {
+			nextProtos = []string{"h2", "http/1.1"}
 		}
+		tlsConfig = reloader.TLSConfig(nextProtos...)
+		grpcTLSConfig = reloader.TLSConfig("h2")
 
-		caPool.AppendCertsFromPEM(caCert)
-
-		tlsConfig.Certificates = []tls.Certificate{certPair}
-		tlsConfig.RootCAs = caPool
-		tlsConfig.ClientAuth = cert.StringClientAuthType(cfg.HTTP.TLS.ClientAuthType)
+		go reloadTLS(ctx, reloader, httpAttributes)
 	}
 
-	// Create new HTTP server with the provided TLS configuration.
-	server := h.NewServer(tlsConfig)
+	// Create an HTTP server per listen address, the plaintext addresses being served without the TLS configuration.
+	servers := listener.New()
+	addServer(ctx, h, servers, cfg.HTTP.Address, tlsConfig)
+	for _, address := range cfg.HTTP.PlaintextAddresses {
+		addServer(ctx, h, servers, address, nil)
+	}
 
+	serveErrs := servers.Start(ctx)
 	go func() {
-		logger.Info(ctx, "starting server", httpAttributes...)
-
-		if tlsEnabled {
-			err = server.ListenAndServeTLS("", "")
-		} else {
-			err = server.ListenAndServe()
-		}
-
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			logger.Error(ctx, err.Error(), httpAttributes...)
+		if err, ok := <-serveErrs; ok {
+			logger.Error(ctx, err.Error())
 			os.Exit(1)
 		}
 	}()
 
+	// Start the OTLP/gRPC server, sharing the TLS configuration of the HTTP server.
+	stopGRPC := startGRPCServer(ctx, cfg, h, grpcTLSConfig)
+
 	// Wait for the application to exit.
 	<-ctx.Done()
 	stop()
@@ -170,23 +254,347 @@ func main() {
 	// Shutdown the server.
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.TimeoutShutdown)
 	defer cancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
+	if err := servers.Shutdown(shutdownCtx); err != nil {
 		logger.Error(ctx, "http close error",
 			append(httpAttributes, attribute.String(errAttrKey, err.Error()))...,
 		)
 		os.Exit(1)
 	}
+
+	// Stop the gRPC server, cancelling the calls still running after the shutdown timeout.
+	stopGRPC(shutdownCtx)
+
+	// Wait for the requests answered in async mode to be forwarded.
+	if err := h.Wait(shutdownCtx); err != nil {
+		logger.Error(ctx, "abandoning async dispatches still in flight",
+			append(httpAttributes, attribute.String(errAttrKey, err.Error()))...,
+		)
+	}
+
+	// Save the usage counters, including those recorded while draining, and wait for them to be persisted.
+	stopPersist()
+}
+
+// persistStats restores the per-tenant usage counters and persists them periodically, returning the function saving
+// them a last time and closing the store. It exits when the store cannot be opened or loaded.
+func persistStats(ctx context.Context, h *handler.Handlers, cfg *config.Config) (stop func()) {
+	if cfg.Stats.Path == "" {
+		return func() {}
+	}
+
+	store, err := stats.OpenStore(cfg.Stats.Path)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	counters, err := store.Load()
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+	h.Stats().Restore(counters)
+
+	persistCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Stats().Persist(persistCtx, store, cfg.Stats.PersistInterval)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		_ = store.Close()
+	}
 }
 
-// newClient creates a new HTTP client with the specified timeout and TLS configuration.
-func newClient(ctx context.Context, endpoint *config.Endpoint) (*http.Client, error) {
+// restoreCircuits restores the circuits opened through the admin API, later changes being persisted to the store, and
+// returns the function closing the store. It exits when the store cannot be opened or restored.
+func restoreCircuits(ctx context.Context, h *handler.Handlers, cfg *config.Config) (closeStore func()) {
+	if cfg.Circuit.Path == "" {
+		return func() {}
+	}
+
+	store, err := circuit.OpenStore(cfg.Circuit.Path)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+
+	if err := h.Circuits().Restore(store); err != nil {
+		_ = store.Close()
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+	return func() { _ = store.Close() }
+}
+
+// registerRoutes registers the handlers of the endpoints enabled by the configuration.
+func registerRoutes(ctx context.Context, h *handler.Handlers, cfg *config.Config) {
+	// Health check endpoint
+	h.Register(ctx, "GET /health", h.Health)
+	h.Register(ctx, "GET /ready", h.Ready)
+
+	// register the logs handler.
+	h.Register(ctx, "POST /v1/logs", h.Logs)
+
+	// register the metrics handler.
+	h.Register(ctx, "POST /v1/metrics", h.Metrics)
+
+	// register the traces handler.
+	h.Register(ctx, "POST /v1/traces", h.Traces)
+
+	// register the profiles handler when a profiles backend is configured.
+	if cfg.Profiles.Address != "" {
+		h.Register(ctx, "POST /v1development/profiles", h.Profiles)
+	}
+
+	// answer the OTLP paths with a status body for other methods than POST, and unknown paths, in strict mode.
+	if cfg.Ingest.Strict {
+		h.Register(ctx, "/v1/logs", h.MethodNotAllowed)
+		h.Register(ctx, "/v1/metrics", h.MethodNotAllowed)
+		h.Register(ctx, "/v1/traces", h.MethodNotAllowed)
+		if cfg.Profiles.Address != "" {
+			h.Register(ctx, "/v1development/profiles", h.MethodNotAllowed)
+		}
+		h.Register(ctx, "/", h.NotFound)
+	}
+
+	// register the gRPC-Web export services.
+	if cfg.HTTP.GRPCWeb {
+		h.Register(ctx, "POST /opentelemetry.proto.collector.logs.v1.LogsService/Export", h.GRPCWeb(h.Logs))
+		h.Register(ctx, "POST /opentelemetry.proto.collector.metrics.v1.MetricsService/Export", h.GRPCWeb(h.Metrics))
+		h.Register(ctx, "POST /opentelemetry.proto.collector.trace.v1.TraceService/Export", h.GRPCWeb(h.Traces))
+	}
+
+	// register the Influx line protocol write handler.
+	if cfg.Influx.Enabled {
+		h.Register(ctx, "POST /api/v2/write", h.InfluxWrite)
+	}
+
+	// register the Datadog agent intake handlers.
+	if cfg.Datadog.Enabled {
+		h.Register(ctx, "GET /api/v1/validate", h.DatadogValidate)
+		h.Register(ctx, "POST /api/v2/series", h.DatadogSeries)
+		h.Register(ctx, "POST /api/v2/logs", h.DatadogLogs)
+	}
+
+	// register the Loki push API handler.
+	if cfg.LokiPush.Enabled {
+		h.Register(ctx, "POST /loki/api/v1/push", h.LokiPush)
+	}
+
+	// register the handlers of the HTTP integrations compiled into the binary, such as Amazon Data Firehose.
+	h.RegisterIntegrations(ctx)
+
+	// register the stream ingest endpoint of long-lived senders.
+	if cfg.StreamIngest.Enabled {
+		h.Register(ctx, "GET /v1/stream", h.Stream)
+	}
+
+	// register the Zipkin and Jaeger span receivers.
+	if cfg.Zipkin.Enabled {
+		h.Register(ctx, "POST /api/v2/spans", h.ZipkinSpans)
+	}
+	if cfg.Jaeger.Enabled {
+		h.Register(ctx, "POST /api/traces", h.JaegerTraces)
+	}
+
+	// register the admin handlers.
+	if cfg.Admin.Enabled {
+		h.Register(ctx, "GET /admin/config", h.AdminConfig)
+		h.Register(ctx, "GET /admin/stats", h.AdminStats)
+		h.Register(ctx, "GET /admin/topk", h.AdminTopK)
+		h.Register(ctx, "GET /admin/features", h.AdminFeatures)
+		h.Register(ctx, "GET /admin/circuits", h.AdminCircuits)
+		h.Register(ctx, "POST /admin/circuits/{signal}/{tenant}/open", h.AdminOpenCircuit)
+		h.Register(ctx, "POST /admin/circuits/{signal}/{tenant}/close", h.AdminCloseCircuit)
+		h.Register(ctx, "GET /admin/support-bundle", h.AdminSupportBundle)
+		h.Register(ctx, "GET /admin/decisions", h.AdminDecisions)
+	}
+}
+
+// startReceivers starts the receivers compiled into the binary and enabled by the configuration, forwarding their data
+// through the handlers and reporting the depth of their queues in the admin stats, exiting when one cannot be created
+// or fails.
+func startReceivers(ctx context.Context, h *handler.Handlers, cfg *config.Config, meter metric.Meter) {
+	receivers, err := integration.NewReceivers(cfg, integration.Sinks{
+		Logs:    h.IngestLogs,
+		Metrics: h.IngestMetrics,
+		Traces:  h.IngestTraces,
+	}, meter)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		os.Exit(1)
+	}
+	for _, receiver := range receivers {
+		// Report the depth of the receivers batching their data in the admin stats
+		if queue, ok := receiver.Runner.(integration.Queue); ok {
+			h.Stats().RegisterQueue(receiver.Name, queue.Pending)
+		}
+
+		go func() {
+			if err := receiver.Run(ctx); err != nil {
+				logger.Error(ctx, "receiver failed",
+					attribute.String(receiverAttrKey, receiver.Name), attribute.String(errAttrKey, err.Error()),
+				)
+				os.Exit(1)
+			}
+		}()
+	}
+}
+
+// addServer adds an HTTP server listening on the address to the servers, serving TLS when the TLS configuration is
+// set, exiting when the address cannot be listened on.
+func addServer(
+	ctx context.Context,
+	h *handler.Handlers,
+	servers *listener.Manager,
+	address string,
+	tlsConfig *tls.Config,
+) {
+	ln, err := h.Listen(address)
+	if err != nil {
+		logger.Error(ctx, err.Error(),
+			attribute.String(httpAddressAttrKey, address), attribute.Bool(httpTLSEnabledAttrKey, tlsConfig != nil),
+		)
+		os.Exit(1)
+	}
+	servers.Add(h.NewServer(tlsConfig), ln, tlsConfig != nil)
+}
+
+// startGRPCServer starts the OTLP/gRPC server when a gRPC address is configured, exiting when it cannot listen or
+// serve. It returns the function stopping it gracefully, cancelling the calls still running once the context is done.
+func startGRPCServer(
+	ctx context.Context,
+	cfg *config.Config,
+	h *handler.Handlers,
+	tlsConfig *tls.Config,
+) (stop func(context.Context)) {
+	if cfg.GRPC.Address == "" {
+		return func(context.Context) {}
+	}
+
+	grpcAttributes := []attribute.KeyValue{
+		attribute.String(grpcAddressAttrKey, cfg.GRPC.Address),
+		attribute.Bool(httpTLSEnabledAttrKey, tlsConfig != nil),
+	}
+
+	grpcServer := h.NewGRPCServer(tlsConfig)
+
+	grpcListener, err := h.ListenGRPC()
+	if err != nil {
+		logger.Error(ctx, err.Error(), grpcAttributes...)
+		os.Exit(1)
+	}
+
+	go func() {
+		logger.Info(ctx, "starting grpc server", grpcAttributes...)
+
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Error(ctx, err.Error(), grpcAttributes...)
+			os.Exit(1)
+		}
+	}()
+
+	return func(shutdownCtx context.Context) {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			grpcServer.Stop()
+		}
+	}
+}
+
+// reloadTLS reloads the TLS configuration of the servers on SIGHUP until the context is cancelled, keeping the current
+// configuration when the new one cannot be loaded.
+func reloadTLS(ctx context.Context, reloader *cert.ServerReloader, attrs []attribute.KeyValue) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := reloader.Reload(); err != nil {
+				logger.Error(ctx, "failed to reload TLS configuration, keeping the current one",
+					append(attrs, attribute.String(errAttrKey, err.Error()))...,
+				)
+				continue
+			}
+			logger.Info(ctx, "reloaded TLS configuration",
+				append(attrs, attribute.String(tlsClientAuthAttrKey, reloader.ClientAuth().String()))...,
+			)
+		}
+	}
+}
+
+// setInstanceResourceAttribute adds the instance identifier to OTEL_RESOURCE_ATTRIBUTES unless it is already set there.
+func setInstanceResourceAttribute(instanceID string) error {
+	attrs := os.Getenv("OTEL_RESOURCE_ATTRIBUTES")
+	if instanceID == "" || strings.Contains(attrs, serviceInstanceIDAttrKey+"=") {
+		return nil
+	}
+
+	if attrs != "" {
+		attrs += ","
+	}
+	return os.Setenv("OTEL_RESOURCE_ATTRIBUTES", attrs+serviceInstanceIDAttrKey+"="+url.PathEscape(instanceID))
+}
+
+// newClient creates a new HTTP client with the specified timeout and TLS configuration, or a Kafka producer when the
+// endpoint writes to Kafka.
+func newClient(
+	ctx context.Context,
+	endpoint *config.Endpoint,
+	tenantHeader string,
+	sessionCache tls.ClientSessionCache,
+) (processor.Client, error) {
 	clientAttributes := []attribute.KeyValue{
 		attribute.String(httpClientURLAttrKey, endpoint.Address),
 		attribute.Int64(httpClientTimeoutAttrKey, int64(endpoint.Timeout.Seconds())),
 		attribute.Bool(httpClientTLSEnabledAttrKey, cert.TLSEnabled(&endpoint.TLS)),
 	}
 
-	c := &http.Client{Timeout: endpoint.Timeout}
+	if endpoint.Protocol == config.ProtocolKafka {
+		var tlsConfig *tls.Config
+		if cert.TLSEnabled(&endpoint.TLS) {
+			var err error
+			tlsConfig, err = cert.CreateTLSConfig(endpoint)
+			if err != nil {
+				logger.Error(ctx, "failed to create TLS config",
+					append(clientAttributes, attribute.String(errAttrKey, err.Error()))...,
+				)
+				return nil, err
+			}
+		}
+
+		producer, err := integration.NewProducer(endpoint, tenantHeader, tlsConfig)
+		if err != nil {
+			logger.Error(ctx, "failed to create Kafka producer",
+				append(clientAttributes, attribute.String(errAttrKey, err.Error()))...,
+			)
+			return nil, err
+		}
+		logger.Info(ctx, "created Kafka producer", clientAttributes...)
+		return producer, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = ipfamily.DialContext(endpoint)
+	c := &http.Client{
+		Timeout:       endpoint.Timeout,
+		Transport:     transport,
+		CheckRedirect: processor.CheckRedirect(endpoint.Redirect),
+	}
 	if cert.TLSEnabled(&endpoint.TLS) {
 		tlsConfig, err := cert.CreateTLSConfig(endpoint)
 		if err != nil {
@@ -195,10 +603,70 @@ func newClient(ctx context.Context, endpoint *config.Endpoint) (*http.Client, er
 			)
 			return nil, err
 		}
-		c.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		tlsConfig.ClientSessionCache = sessionCache
+		c.Transport = &http.Transport{TLSClientConfig: tlsConfig, DialContext: transport.DialContext}
+	}
+
+	balancer, err := integration.NewBalancer(&endpoint.Discovery)
+	if err != nil {
+		logger.Error(ctx, "failed to create backend discovery",
+			append(clientAttributes, attribute.String(errAttrKey, err.Error()))...,
+		)
+		return nil, err
+	}
+	if balancer != nil {
+		c.Transport = balancer.Transport(discoveryTransport(c.Transport, endpoint.Address))
+		go balancer.Run(ctx)
+		clientAttributes = append(clientAttributes,
+			attribute.String(httpClientDiscoveryAttrKey, endpoint.Discovery.Service),
+		)
 	}
 
 	logger.Info(ctx, "created HTTP client", clientAttributes...)
 
 	return c, nil
 }
+
+// warmUp pre-establishes the connections to the backends, exiting when they cannot all be reached and the warmup is
+// required.
+func warmUp(ctx context.Context, cfg *config.Config, logs, metrics, traces, profiles processor.Client) {
+	backends := []warmup.Backend{
+		warmupBackend("logs", &cfg.Logs, logs),
+		warmupBackend("metrics", &cfg.Metrics, metrics),
+		warmupBackend("traces", &cfg.Traces, traces),
+	}
+	if profiles != nil {
+		backends = append(backends, warmupBackend("profiles", &cfg.Profiles, profiles))
+	}
+	err := warmup.Run(ctx, cfg.Warmup.Timeout, backends)
+	if err != nil && cfg.Warmup.Required {
+		logger.Error(ctx, "failed to warm up backends", attribute.String(errAttrKey, err.Error()))
+		os.Exit(1)
+	}
+}
+
+// warmupBackend returns the backend of a signal to warm up. Backends written to Kafka are not reached over HTTP, and
+// are left without an address so they are skipped.
+func warmupBackend(signal string, endpoint *config.Endpoint, client processor.Client) warmup.Backend {
+	httpClient, ok := client.(*http.Client)
+	if !ok {
+		return warmup.Backend{Signal: signal}
+	}
+	return warmup.Backend{Signal: signal, Address: endpoint.Address, Client: httpClient}
+}
+
+// discoveryTransport returns the transport of a client balanced across discovered endpoints. The TLS server name is
+// pinned to the host of the address, as the connections are dialled to pod addresses.
+func discoveryTransport(base http.RoundTripper, address string) *http.Transport {
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if u, err := url.Parse(address); err == nil && transport.TLSClientConfig.ServerName == "" {
+		transport.TLSClientConfig.ServerName = u.Hostname()
+	}
+	return transport
+}
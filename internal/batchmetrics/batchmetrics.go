@@ -0,0 +1,115 @@
+// Package batchmetrics records the metrics of the components batching telemetry before forwarding it.
+package batchmetrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	processorAttrKey = "processor"
+	exporterAttrKey  = "exporter"
+	dataTypeAttrKey  = "data_type"
+)
+
+// Batch send triggers.
+const (
+	TriggerSize     = "size"
+	TriggerTimeout  = "timeout"
+	TriggerShutdown = "shutdown"
+)
+
+// Metrics records the batches sent by a component and the depth of its queue.
+type Metrics struct {
+	sendSize       metric.Int64Histogram
+	sizeTrigger    metric.Int64Counter
+	timeoutTrigger metric.Int64Counter
+	processorAttr  metric.MeasurementOption
+}
+
+// New creates the batch metrics of the named component batching the given signal, reporting the current queue size
+// through the callback against the queue capacity.
+func New(meter metric.Meter, component, signal string, capacity int64, size func() int64) (*Metrics, error) {
+	sendSize, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_processor_batch_batch_send_size",
+		metric.WithDescription("Number of units in the batch"),
+		metric.WithUnit("{units}"),
+		metric.WithExplicitBucketBoundaries(10, 25, 50, 75, 100, 250, 500, 750, 1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000, 9000, 10000, 20000, 30000, 50000, 100000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch send size histogram: %w", err)
+	}
+
+	sizeTrigger, err := meter.Int64Counter(
+		"otel_lgtm_proxy_processor_batch_batch_size_trigger_send_total",
+		metric.WithDescription("Number of times the batch was sent due to a size trigger"),
+		metric.WithUnit("{times}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch size trigger counter: %w", err)
+	}
+
+	timeoutTrigger, err := meter.Int64Counter(
+		"otel_lgtm_proxy_processor_batch_timeout_trigger_send_total",
+		metric.WithDescription("Number of times the batch was sent due to a timeout trigger"),
+		metric.WithUnit("{times}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch timeout trigger counter: %w", err)
+	}
+
+	queueSize, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_exporter_queue_size",
+		metric.WithDescription("Current number of units waiting in the queue"),
+		metric.WithUnit("{units}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue size gauge: %w", err)
+	}
+
+	queueCapacity, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_exporter_queue_capacity",
+		metric.WithDescription("Fixed capacity of the queue"),
+		metric.WithUnit("{units}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create queue capacity gauge: %w", err)
+	}
+
+	queueAttrs := metric.WithAttributes(
+		attribute.String(exporterAttrKey, component),
+		attribute.String(dataTypeAttrKey, signal),
+	)
+	if _, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(queueSize, size(), queueAttrs)
+		o.ObserveInt64(queueCapacity, capacity, queueAttrs)
+		return nil
+	}, queueSize, queueCapacity); err != nil {
+		return nil, fmt.Errorf("failed to register queue callback: %w", err)
+	}
+
+	return &Metrics{
+		sendSize:       sendSize,
+		sizeTrigger:    sizeTrigger,
+		timeoutTrigger: timeoutTrigger,
+		processorAttr:  metric.WithAttributes(attribute.String(processorAttrKey, component)),
+	}, nil
+}
+
+// Sent records a batch of the given number of units sent because of the trigger. A nil Metrics records nothing.
+func (m *Metrics) Sent(ctx context.Context, units int, trigger string) {
+	if m == nil || units == 0 {
+		return
+	}
+
+	m.sendSize.Record(ctx, int64(units), m.processorAttr)
+	switch trigger {
+	case TriggerSize:
+		m.sizeTrigger.Add(ctx, 1, m.processorAttr)
+	case TriggerTimeout:
+		m.timeoutTrigger.Add(ctx, 1, m.processorAttr)
+	}
+}
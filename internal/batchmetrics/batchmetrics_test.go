@@ -0,0 +1,75 @@
+package batchmetrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	metrics, err := New(meter, "syslog", "logs", 1000, func() int64 { return 42 })
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	metrics.Sent(ctx, 1000, TriggerSize)
+	metrics.Sent(ctx, 10, TriggerTimeout)
+	metrics.Sent(ctx, 5, TriggerShutdown)
+	metrics.Sent(ctx, 0, TriggerTimeout)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[int64]:
+				for _, dp := range data.DataPoints {
+					assertAttribute(t, dp.Attributes, processorAttrKey, "syslog")
+					got[m.Name] = int64(dp.Count)
+					got[m.Name+"_sum"] = dp.Sum
+				}
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					assertAttribute(t, dp.Attributes, processorAttrKey, "syslog")
+					got[m.Name] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					assertAttribute(t, dp.Attributes, exporterAttrKey, "syslog")
+					assertAttribute(t, dp.Attributes, dataTypeAttrKey, "logs")
+					got[m.Name] = dp.Value
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int64{
+		"otel_lgtm_proxy_processor_batch_batch_send_size":               3,
+		"otel_lgtm_proxy_processor_batch_batch_send_size_sum":           1015,
+		"otel_lgtm_proxy_processor_batch_batch_size_trigger_send_total": 1,
+		"otel_lgtm_proxy_processor_batch_timeout_trigger_send_total":    1,
+		"otel_lgtm_proxy_exporter_queue_size":                           42,
+		"otel_lgtm_proxy_exporter_queue_capacity":                       1000,
+	}, got)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var metrics *Metrics
+	metrics.Sent(context.Background(), 10, TriggerSize)
+}
+
+func assertAttribute(t *testing.T, set attribute.Set, key, want string) {
+	t.Helper()
+	value, ok := set.Value(attribute.Key(key))
+	assert.True(t, ok, "missing attribute %s", key)
+	assert.Equal(t, want, value.AsString())
+}
@@ -0,0 +1,12 @@
+// Package batchmetrics records the metrics of the components batching telemetry before forwarding it.
+//
+// The metrics are named and labelled after their OpenTelemetry Collector counterparts, with the otelcol_ prefix
+// replaced by otel_lgtm_proxy_, so dashboards and alerts built for the batch processor and exporter queues of the
+// collector can be reused by swapping the prefix:
+//
+//   - otel_lgtm_proxy_processor_batch_batch_send_size, labelled by processor
+//   - otel_lgtm_proxy_processor_batch_batch_size_trigger_send_total, labelled by processor
+//   - otel_lgtm_proxy_processor_batch_timeout_trigger_send_total, labelled by processor
+//   - otel_lgtm_proxy_exporter_queue_size, labelled by exporter and data_type
+//   - otel_lgtm_proxy_exporter_queue_capacity, labelled by exporter and data_type
+package batchmetrics
@@ -0,0 +1,127 @@
+// Package circuit provides manual circuit overrides pausing the forwarding of a tenant to its backend.
+package circuit
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned for data of a tenant whose circuit is open.
+var ErrOpen = errors.New("circuit open")
+
+// StateOpen is the state of a circuit pausing forwarding.
+const StateOpen = "open"
+
+// Override is a manually opened circuit of a signal and tenant.
+type Override struct {
+	Signal   string    `json:"signal"`
+	Tenant   string    `json:"tenant"`
+	State    string    `json:"state"`
+	OpenedAt time.Time `json:"opened_at"`
+}
+
+// key identifies the circuit of a signal and tenant.
+type key struct {
+	signal string
+	tenant string
+}
+
+// Overrides holds the manually opened circuits. A nil Overrides has every circuit closed.
+type Overrides struct {
+	mu        sync.RWMutex
+	overrides map[key]Override
+	store     *Store
+	now       func() time.Time
+}
+
+// New creates a new Overrides with every circuit closed.
+func New() *Overrides {
+	return &Overrides{
+		overrides: make(map[key]Override),
+		now:       time.Now,
+	}
+}
+
+// Restore restores the overrides persisted in the store and persists every later change to it.
+func (o *Overrides) Restore(store *Store) error {
+	overrides, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, override := range overrides {
+		o.overrides[key{signal: override.Signal, tenant: override.Tenant}] = override
+	}
+	o.store = store
+	return nil
+}
+
+// IsOpen reports whether the circuit of the signal and tenant is open.
+func (o *Overrides) IsOpen(signal, tenant string) bool {
+	if o == nil {
+		return false
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	_, ok := o.overrides[key{signal: signal, tenant: tenant}]
+	return ok
+}
+
+// Open opens the circuit of the signal and tenant, pausing forwarding until it is closed.
+func (o *Overrides) Open(signal, tenant string) (Override, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	k := key{signal: signal, tenant: tenant}
+	if override, ok := o.overrides[k]; ok {
+		return override, nil
+	}
+
+	override := Override{Signal: signal, Tenant: tenant, State: StateOpen, OpenedAt: o.now().UTC()}
+	if o.store != nil {
+		if err := o.store.Save(override); err != nil {
+			return Override{}, err
+		}
+	}
+
+	o.overrides[k] = override
+	return override, nil
+}
+
+// Close closes the circuit of the signal and tenant, resuming forwarding.
+func (o *Overrides) Close(signal, tenant string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.store != nil {
+		if err := o.store.Delete(signal, tenant); err != nil {
+			return err
+		}
+	}
+
+	delete(o.overrides, key{signal: signal, tenant: tenant})
+	return nil
+}
+
+// List returns the open circuits sorted by signal and tenant.
+func (o *Overrides) List() []Override {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	overrides := make([]Override, 0, len(o.overrides))
+	for _, override := range o.overrides {
+		overrides = append(overrides, override)
+	}
+	slices.SortFunc(overrides, func(a, b Override) int {
+		return cmp.Or(cmp.Compare(a.Signal, b.Signal), cmp.Compare(a.Tenant, b.Tenant))
+	})
+	return overrides
+}
@@ -0,0 +1,66 @@
+package circuit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrides(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	overrides := New()
+	overrides.now = func() time.Time { return now }
+
+	assert.False(t, overrides.IsOpen("logs", "tenant-a"))
+
+	opened, err := overrides.Open("logs", "tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, Override{Signal: "logs", Tenant: "tenant-a", State: StateOpen, OpenedAt: now}, opened)
+
+	// Opening an open circuit keeps the original override.
+	overrides.now = func() time.Time { return now.Add(time.Hour) }
+	reopened, err := overrides.Open("logs", "tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, opened, reopened)
+
+	assert.True(t, overrides.IsOpen("logs", "tenant-a"))
+	assert.False(t, overrides.IsOpen("traces", "tenant-a"))
+	assert.False(t, overrides.IsOpen("logs", "tenant-b"))
+	assert.Equal(t, []Override{opened}, overrides.List())
+
+	require.NoError(t, overrides.Close("logs", "tenant-a"))
+	assert.False(t, overrides.IsOpen("logs", "tenant-a"))
+	assert.Empty(t, overrides.List())
+
+	var closed *Overrides
+	assert.False(t, closed.IsOpen("logs", "tenant-a"))
+}
+
+func TestOverrides_Restore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "circuits.db")
+
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+
+	overrides := New()
+	require.NoError(t, overrides.Restore(store))
+	_, err = overrides.Open("logs", "tenant-a")
+	require.NoError(t, err)
+	_, err = overrides.Open("metrics", "tenant/with/slashes")
+	require.NoError(t, err)
+	require.NoError(t, overrides.Close("logs", "tenant-a"))
+	require.NoError(t, store.Close())
+
+	store, err = OpenStore(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, store.Close()) }()
+
+	restored := New()
+	require.NoError(t, restored.Restore(store))
+	assert.False(t, restored.IsOpen("logs", "tenant-a"))
+	assert.True(t, restored.IsOpen("metrics", "tenant/with/slashes"))
+	assert.Equal(t, overrides.List(), restored.List())
+}
@@ -0,0 +1,10 @@
+// Package circuit provides manual circuit overrides pausing the forwarding of a tenant to its backend.
+//
+// Operators open the circuit of a signal and tenant through the admin API, for example
+// while the backend organisation of the tenant is being migrated. While the circuit is
+// open the processors reject the tenant's data with ErrOpen instead of forwarding it,
+// so clients retry later. Closing the circuit resumes forwarding.
+//
+// Overrides can be persisted to a local bbolt Store, so a paused tenant stays paused
+// across restarts.
+package circuit
@@ -0,0 +1,94 @@
+// Package circuit provides manual circuit overrides pausing the forwarding of a tenant to its backend.
+package circuit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var overridesBucket = []byte("circuit_overrides")
+
+// Store persists the circuit overrides in a local bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens, creating it if needed, the bbolt database at the given path.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open circuit store %q: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(overridesBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialise circuit store %q: %w", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Load returns the persisted circuit overrides.
+func (s *Store) Load() ([]Override, error) {
+	overrides := []Override{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(overridesBucket).ForEach(func(_, value []byte) error {
+			var override Override
+			if err := json.Unmarshal(value, &override); err != nil {
+				return err
+			}
+			overrides = append(overrides, override)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load circuit overrides: %w", err)
+	}
+
+	return overrides, nil
+}
+
+// Save persists the override, replacing the stored override of the same signal and tenant.
+func (s *Store) Save(override Override) error {
+	value, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("failed to save circuit override: %w", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(overridesBucket).Put(storeKey(override.Signal, override.Tenant), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save circuit override: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the persisted override of the signal and tenant.
+func (s *Store) Delete(signal, tenant string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(overridesBucket).Delete(storeKey(signal, tenant))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete circuit override: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// storeKey returns the database key of the override of the signal and tenant.
+func storeKey(signal, tenant string) []byte {
+	return []byte(signal + "\x00" + tenant)
+}
@@ -0,0 +1,109 @@
+// Package clientpool provides a bounded cache of HTTP clients.
+package clientpool
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+)
+
+// entry is a cached client.
+type entry struct {
+	key      string
+	client   *http.Client
+	lastUsed time.Time
+}
+
+// Pool is a least recently used cache of HTTP clients with idle eviction.
+type Pool struct {
+	size        int
+	idleTimeout time.Duration
+	clock       clock.Clock
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+// Option configures optional dependencies of a Pool.
+type Option func(*Pool)
+
+// WithClock sets the clock used to track when clients were last used.
+func WithClock(c clock.Clock) Option {
+	return func(p *Pool) {
+		p.clock = c
+	}
+}
+
+// New creates a new Pool holding up to size clients, evicting clients unused for longer than the idle timeout. A size
+// that is not positive keeps a single client, and an idle timeout that is not positive disables idle eviction.
+func New(size int, idleTimeout time.Duration, opts ...Option) *Pool {
+	p := &Pool{
+		size:        max(size, 1),
+		idleTimeout: idleTimeout,
+		clock:       clock.New(),
+		order:       list.New(),
+		entries:     make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Get returns the client cached under the key, creating it with create when it is not cached.
+func (p *Pool) Get(key string, create func() (*http.Client, error)) (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	p.evictIdle(now)
+
+	if element, ok := p.entries[key]; ok {
+		e := element.Value.(*entry)
+		e.lastUsed = now
+		p.order.MoveToFront(element)
+		return e.client, nil
+	}
+
+	client, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	p.entries[key] = p.order.PushFront(&entry{key: key, client: client, lastUsed: now})
+	for p.order.Len() > p.size {
+		p.evict(p.order.Back())
+	}
+	return client, nil
+}
+
+// Len returns the number of cached clients.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.order.Len()
+}
+
+// evictIdle evicts the clients unused since before the idle timeout, which are at the back of the list.
+func (p *Pool) evictIdle(now time.Time) {
+	if p.idleTimeout <= 0 {
+		return
+	}
+	for element := p.order.Back(); element != nil; element = p.order.Back() {
+		if now.Sub(element.Value.(*entry).lastUsed) <= p.idleTimeout {
+			return
+		}
+		p.evict(element)
+	}
+}
+
+// evict removes the client from the cache and closes its idle connections.
+func (p *Pool) evict(element *list.Element) {
+	e := p.order.Remove(element).(*entry)
+	delete(p.entries, e.key)
+	e.client.CloseIdleConnections()
+}
@@ -0,0 +1,76 @@
+package clientpool
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool_Get(t *testing.T) {
+	pool := New(2, 0)
+
+	created := 0
+	create := func() (*http.Client, error) {
+		created++
+		return &http.Client{}, nil
+	}
+
+	a, err := pool.Get("a", create)
+	require.NoError(t, err)
+	again, err := pool.Get("a", create)
+	require.NoError(t, err)
+	assert.Same(t, a, again)
+	assert.Equal(t, 1, created)
+
+	// Adding a third client evicts the least recently used one
+	_, err = pool.Get("b", create)
+	require.NoError(t, err)
+	_, err = pool.Get("a", create)
+	require.NoError(t, err)
+	_, err = pool.Get("c", create)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, 3, created)
+
+	_, err = pool.Get("a", create)
+	require.NoError(t, err)
+	assert.Equal(t, 3, created, "a was used more recently than b and should still be cached")
+	_, err = pool.Get("b", create)
+	require.NoError(t, err)
+	assert.Equal(t, 4, created, "b should have been evicted")
+}
+
+func TestPool_IdleEviction(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+	pool := New(10, time.Minute, WithClock(fake))
+
+	create := func() (*http.Client, error) { return &http.Client{}, nil }
+	_, err := pool.Get("a", create)
+	require.NoError(t, err)
+
+	fake.Advance(30 * time.Second)
+	_, err = pool.Get("b", create)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pool.Len())
+
+	// Only the client unused for longer than the idle timeout is evicted
+	fake.Advance(45 * time.Second)
+	_, err = pool.Get("b", create)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pool.Len())
+}
+
+func TestPool_CreateError(t *testing.T) {
+	pool := New(2, 0)
+	errCreate := errors.New("bad certificate")
+
+	client, err := pool.Get("a", func() (*http.Client, error) { return nil, errCreate })
+	assert.ErrorIs(t, err, errCreate)
+	assert.Nil(t, client)
+	assert.Equal(t, 0, pool.Len())
+}
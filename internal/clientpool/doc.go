@@ -0,0 +1,8 @@
+// Package clientpool provides a bounded cache of HTTP clients.
+//
+// Backends that authenticate each tenant with its own credentials, such as a client certificate per tenant, need a
+// client with its own transport per tenant. Each transport keeps its own pool of connections, so with thousands of
+// tenants the transports would pin connections and memory forever. The Pool keeps at most a fixed number of clients,
+// evicting the least recently used client when full and clients that have been idle for longer than the idle timeout.
+// The idle connections of evicted clients are closed.
+package clientpool
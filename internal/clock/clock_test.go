@@ -0,0 +1,68 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	c := New()
+
+	before := time.Now()
+	assert.False(t, c.Now().Before(before))
+
+	ticker := c.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a tick")
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	ticker := c.NewTicker(10 * time.Second)
+
+	c.Advance(9 * time.Second)
+	assert.Equal(t, start.Add(9*time.Second), c.Now())
+	assert.Empty(t, ticker.C())
+
+	c.Advance(time.Second)
+	require.Len(t, ticker.C(), 1)
+	assert.Equal(t, start.Add(10*time.Second), <-ticker.C())
+
+	// Ticks that cannot be delivered are dropped
+	c.Advance(time.Minute)
+	require.Len(t, ticker.C(), 1)
+	assert.Equal(t, start.Add(20*time.Second), <-ticker.C())
+
+	c.Advance(10 * time.Second)
+	require.Len(t, ticker.C(), 1)
+	assert.Equal(t, start.Add(80*time.Second), <-ticker.C())
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	assert.Empty(t, ticker.C())
+}
+
+func TestFake_BlockUntilTickers(t *testing.T) {
+	c := NewFake(time.Time{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.BlockUntilTickers(1)
+	}()
+
+	c.NewTicker(time.Second)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the ticker")
+	}
+}
@@ -0,0 +1,8 @@
+// Package clock abstracts the passage of time for the time-dependent subsystems of the proxy.
+//
+// Components that timestamp data, batch on an interval or evict idle state take a
+// Clock instead of calling the time package directly. Production code uses the
+// clock returned by New, backed by the system time. Tests use a Fake, whose time
+// only moves when Advance is called, firing the tickers that fall due, so flushes
+// and evictions can be unit-tested deterministically without sleeping.
+package clock
@@ -2,6 +2,7 @@
 package config
 
 import (
+	"os"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -11,45 +12,517 @@ import (
 type Config struct {
 	Service         Service       `envPrefix:"OTEL_SERVICE_"`
 	TimeoutShutdown time.Duration `env:"TIMEOUT_SHUTDOWN" envDefault:"15s"`
+	Admin           Admin         `envPrefix:"ADMIN_"`
+	Debug           Debug         `envPrefix:"DEBUG_"`
+	Stats           Stats         `envPrefix:"STATS_"`
+	Circuit         Circuit       `envPrefix:"CIRCUIT_"`
+	Warmup          Warmup        `envPrefix:"WARMUP_"`
+	Ready           Ready         `envPrefix:"READY_"`
+	Watchdog        Watchdog      `envPrefix:"WATCHDOG_"`
+	MetricViews     MetricViews   `envPrefix:"METRIC_VIEWS_"`
 
-	HTTP   Endpoint `envPrefix:"HTTP_LISTEN_"`
-	Tenant Tenant   `envPrefix:"TENANT_"`
+	HTTP      Listener     `envPrefix:"HTTP_LISTEN_"`
+	GRPC      GRPCListener `envPrefix:"GRPC_LISTEN_"`
+	Ingest    Ingest       `envPrefix:"INGEST_"`
+	Signature Signature    `envPrefix:"SIGNATURE_"`
+	Tenant    Tenant       `envPrefix:"TENANT_"`
+	TopK      TopK         `envPrefix:"TOPK_"`
+	Decisions Decisions    `envPrefix:"DECISIONS_"`
+	Dispatch  Dispatch     `envPrefix:"DISPATCH_"`
 
-	Logs    Endpoint `envPrefix:"OLP_LOGS_"`
-	Metrics Endpoint `envPrefix:"OLP_METRICS_"`
-	Traces  Endpoint `envPrefix:"OLP_TRACES_"`
+	Transform Transform `envPrefix:"TRANSFORM_"`
+
+	Logs     Endpoint `envPrefix:"OLP_LOGS_"`
+	Metrics  Endpoint `envPrefix:"OLP_METRICS_"`
+	Traces   Endpoint `envPrefix:"OLP_TRACES_"`
+	Profiles Endpoint `envPrefix:"OLP_PROFILES_"`
+
+	TLSSessionCacheSize int           `env:"OLP_TLS_SESSION_CACHE_SIZE" envDefault:"64"`
+	TenantClients       TenantClients `envPrefix:"OLP_TENANT_CLIENT_"`
+	GrafanaCloud        GrafanaCloud  `envPrefix:"GRAFANA_CLOUD_"`
+
+	FluentForward FluentForward `envPrefix:"FLUENT_FORWARD_"`
+	Syslog        Syslog        `envPrefix:"SYSLOG_"`
+	StatsD        StatsD        `envPrefix:"STATSD_"`
+	Influx        Influx        `envPrefix:"INFLUX_"`
+	Datadog       Datadog       `envPrefix:"DATADOG_"`
+	LokiPush      LokiPush      `envPrefix:"LOKI_PUSH_"`
+	Firehose      Firehose      `envPrefix:"FIREHOSE_"`
+	StreamIngest  StreamIngest  `envPrefix:"STREAM_INGEST_"`
+	Zipkin        Zipkin        `envPrefix:"ZIPKIN_"`
+	Jaeger        Jaeger        `envPrefix:"JAEGER_"`
+	Scrape        Scrape        `envPrefix:"SCRAPE_"`
+	Kafka         Kafka         `envPrefix:"KAFKA_"`
+
+	MockBackend MockBackend `envPrefix:"MOCKBACKEND_"`
 }
 
 // Service represents the service name and version configuration.
 type Service struct {
-	Name    string `env:"NAME"    envDefault:"otel-lgtm-proxy"`
-	Version string `env:"VERSION" envDefault:"1.0.0"`
+	Name       string `env:"NAME"        envDefault:"otel-lgtm-proxy"`
+	Version    string `env:"VERSION"     envDefault:"1.0.0"`
+	InstanceID string `env:"INSTANCE_ID" envDefault:""`
+}
+
+// MetricViews represents the configuration of the views applied to the instruments of the self-instrumentation,
+// dropping the instruments matching a name, which may contain * and ? wildcards, or renaming them with old=new.
+type MetricViews struct {
+	Drop   []string `env:"DROP"   envDefault:""`
+	Rename []string `env:"RENAME" envDefault:""`
+}
+
+// Admin represents the configuration for the administrative endpoints.
+type Admin struct {
+	Enabled bool `env:"ENABLED" envDefault:"false"`
+}
+
+// Debug represents the configuration for echoing how a request was processed in its response headers.
+type Debug struct {
+	Header string `env:"HEADER" envDefault:"X-Proxy-Debug-Token"`
+	Token  string `env:"TOKEN"  envDefault:""                    secret:"true"`
+}
+
+// Stats represents the configuration for persisting the per-tenant usage counters.
+type Stats struct {
+	Path            string        `env:"PATH"             envDefault:""`
+	PersistInterval time.Duration `env:"PERSIST_INTERVAL" envDefault:"30s"`
+}
+
+// Circuit represents the configuration for persisting the circuits opened through the admin API.
+type Circuit struct {
+	Path string `env:"PATH" envDefault:""`
 }
 
 // Endpoint represents the configuration for an endpoint.
 type Endpoint struct {
-	Address string        `env:"ADDRESS"`
-	Headers string        `env:"HEADERS" envDefault:""`
-	Timeout time.Duration `env:"TIMEOUT" envDefault:"15s"`
-	TLS     TLSConfig     `envPrefix:"TLS_"`
+	Address  string        `env:"ADDRESS"`
+	Headers  string        `env:"HEADERS"  envDefault:""         secret:"values"`
+	Timeout  time.Duration `env:"TIMEOUT"  envDefault:"15s"`
+	Hooks    []string      `env:"HOOKS"    envDefault:""`
+	Encoding string        `env:"ENCODING" envDefault:"protobuf"`
+	Stream   bool          `env:"STREAM"   envDefault:"false"`
+	Region   string        `env:"REGION"   envDefault:""`
+	Protocol string        `env:"PROTOCOL" envDefault:"otlp"`
+	TLS      TLSConfig     `envPrefix:"TLS_"`
+
+	MaxRecords int    `env:"MAX_RECORDS" envDefault:"0"`
+	Redirect   string `env:"REDIRECT"    envDefault:"same-host"`
+
+	IPFamily      string        `env:"IP_FAMILY"      envDefault:"dual"`
+	FallbackDelay time.Duration `env:"FALLBACK_DELAY" envDefault:"300ms"`
+
+	LokiLabels []string `env:"LOKI_LABELS" envDefault:"service.name,service.namespace,deployment.environment"`
+
+	RateLimit   RateLimit     `envPrefix:"RATE_LIMIT_"`
+	HeaderLimit HeaderLimit   `envPrefix:"HEADER_LIMIT_"`
+	Discovery   Discovery     `envPrefix:"DISCOVERY_"`
+	Signing     Signing       `envPrefix:"SIGNING_"`
+	Kafka       KafkaProducer `envPrefix:"KAFKA_"`
+}
+
+// KafkaProducer represents the configuration of the producer of a backend written to with the kafka protocol.
+type KafkaProducer struct {
+	Compression string    `env:"COMPRESSION" envDefault:"none"`
+	SASL        KafkaSASL `envPrefix:"SASL_"`
+}
+
+// Signing represents the configuration for signing the bodies of the requests sent to a backend with the HMAC secret
+// of their tenant, disabled when no secret is configured.
+type Signing struct {
+	Header      string   `env:"HEADER"        envDefault:"X-Signature"`
+	KeyIDHeader string   `env:"KEY_ID_HEADER" envDefault:"X-Signature-Key-Id"`
+	Secrets     []string `env:"SECRETS"       envDefault:""                   secret:"true"`
+}
+
+// Discovery represents the configuration for resolving the ready pods behind a Kubernetes Service and balancing the
+// requests of a backend across them, disabled when no service is set.
+type Discovery struct {
+	Service       string        `env:"SERVICE"        envDefault:""`
+	Mode          string        `env:"MODE"           envDefault:"dns"`
+	Interval      time.Duration `env:"INTERVAL"       envDefault:"30s"`
+	ClusterDomain string        `env:"CLUSTER_DOMAIN" envDefault:"cluster.local"`
+}
+
+// Discovery modes deciding how the pods behind a Kubernetes Service are resolved.
+const (
+	DiscoveryDNS = "dns"
+	DiscoveryAPI = "api"
+)
+
+// RateLimit represents the configuration for the outbound rate limits of a backend, disabled when zero.
+type RateLimit struct {
+	Requests     float64 `env:"REQUESTS"      envDefault:"0"`
+	RequestBurst float64 `env:"REQUEST_BURST" envDefault:"0"`
+	Bytes        float64 `env:"BYTES"         envDefault:"0"`
+	ByteBurst    float64 `env:"BYTE_BURST"    envDefault:"0"`
+}
+
+// HeaderLimit represents the configuration for the limits on the headers of the requests sent to a backend, disabled
+// when zero. The size counts every header line as sent over HTTP/1.1.
+type HeaderLimit struct {
+	Bytes int `env:"BYTES" envDefault:"16384"`
+	Count int `env:"COUNT" envDefault:"100"`
+}
+
+// Outbound payload encodings.
+const (
+	EncodingProtobuf = "protobuf"
+	EncodingJSON     = "json"
+)
+
+// Outbound protocols.
+const (
+	ProtocolOTLP        = "otlp"
+	ProtocolLokiPush    = "lokipush"
+	ProtocolRemoteWrite = "remotewrite"
+	ProtocolKafka       = "kafka"
+)
+
+// IP families of the addresses listened and dialled on.
+const (
+	IPFamilyDual = "dual"
+	IPFamilyIPv4 = "ipv4"
+	IPFamilyIPv6 = "ipv6"
+)
+
+// Redirect policies deciding which redirects of a backend are followed.
+const (
+	RedirectFollow   = "follow"
+	RedirectSameHost = "same-host"
+	RedirectRefuse   = "refuse"
+)
+
+// TenantClients represents the configuration for the cache of backend clients dedicated to a tenant.
+type TenantClients struct {
+	CacheSize   int           `env:"CACHE_SIZE"   envDefault:"256"`
+	IdleTimeout time.Duration `env:"IDLE_TIMEOUT" envDefault:"5m"`
+}
+
+// Listener represents the configuration for the inbound HTTP server.
+type Listener struct {
+	Endpoint
+	TrustedProxies    []string      `env:"TRUSTED_PROXIES"     envDefault:""`
+	GRPCWeb           bool          `env:"GRPC_WEB"            envDefault:"false"`
+	HTTP2             bool          `env:"HTTP2"               envDefault:"true"`
+	H2C               bool          `env:"H2C"                 envDefault:"false"`
+	MaxConnections    int           `env:"MAX_CONNECTIONS"     envDefault:"0"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT"        envDefault:"0s"`
+	DisableKeepAlives bool          `env:"DISABLE_KEEP_ALIVES" envDefault:"false"`
+
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT"        envDefault:"0s"`
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"0s"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT"       envDefault:"0s"`
+	MaxHeaderBytes    int           `env:"MAX_HEADER_BYTES"    envDefault:"1048576"`
+
+	PlaintextAddresses []string `env:"PLAINTEXT_ADDRESSES" envDefault:""`
+
+	MaxBodyBytes         int64 `env:"MAX_BODY_BYTES"          envDefault:"20971520"`
+	LogsMaxBodyBytes     int64 `env:"LOGS_MAX_BODY_BYTES"     envDefault:"0"`
+	MetricsMaxBodyBytes  int64 `env:"METRICS_MAX_BODY_BYTES"  envDefault:"0"`
+	TracesMaxBodyBytes   int64 `env:"TRACES_MAX_BODY_BYTES"   envDefault:"0"`
+	ProfilesMaxBodyBytes int64 `env:"PROFILES_MAX_BODY_BYTES" envDefault:"0"`
+
+	HTTP2MaxConcurrentStreams   int `env:"HTTP2_MAX_CONCURRENT_STREAMS"    envDefault:"250"`
+	HTTP2MaxReceiveBuffer       int `env:"HTTP2_MAX_RECEIVE_BUFFER"        envDefault:"1048576"`
+	HTTP2MaxStreamReceiveBuffer int `env:"HTTP2_MAX_STREAM_RECEIVE_BUFFER" envDefault:"1048576"`
+}
+
+// GRPCListener represents the configuration for the inbound OTLP/gRPC server, which shares the TLS configuration of
+// the HTTP server.
+type GRPCListener struct {
+	Address             string        `env:"ADDRESS"               envDefault:""`
+	MaxRecvMsgSize      int           `env:"MAX_RECV_MSG_SIZE"     envDefault:"4194304"`
+	Reflection          bool          `env:"REFLECTION"            envDefault:"true"`
+	HealthWatchInterval time.Duration `env:"HEALTH_WATCH_INTERVAL" envDefault:"5s"`
 }
 
 // TLSConfig represents the configuration for TLS.
 type TLSConfig struct {
+	CertFile           string `env:"CERT_FILE"             envDefault:""`
+	KeyFile            string `env:"KEY_FILE"              envDefault:""`
+	CAFile             string `env:"CA_FILE"               envDefault:""`
+	ClientAuthType     string `env:"CLIENT_AUTH_TYPE"      envDefault:"NoClientCert"`
+	ClientAuthTypeFile string `env:"CLIENT_AUTH_TYPE_FILE" envDefault:""`
+	InsecureSkipVerify bool   `env:"INSECURE_SKIP_VERIFY"  envDefault:"false"`
+	TenantCertDir      string `env:"TENANT_CERT_DIR"       envDefault:""`
+}
+
+// Empty payload policies for inbound requests without any resources.
+const (
+	EmptyPayloadAccept = "accept"
+	EmptyPayloadReject = "reject"
+)
+
+// Invalid resource policies for inbound requests containing resources that cannot be parsed.
+const (
+	InvalidResourcesReject = "reject"
+	InvalidResourcesSkip   = "skip"
+)
+
+// Content type policies for inbound requests without a supported OTLP content type.
+const (
+	ContentTypeLenient = "lenient"
+	ContentTypeStrict  = "strict"
+)
+
+// Ingest represents the configuration for handling inbound payloads.
+type Ingest struct {
+	EmptyPayload      string   `env:"EMPTY_PAYLOAD"       envDefault:"accept"`
+	InvalidResources  string   `env:"INVALID_RESOURCES"   envDefault:"reject"`
+	AllowedSchemaURLs []string `env:"ALLOWED_SCHEMA_URLS" envDefault:""`
+	ContentType       string   `env:"CONTENT_TYPE"        envDefault:"lenient"`
+	Strict            bool     `env:"STRICT"              envDefault:"false"`
+	Heartbeats        bool     `env:"HEARTBEATS"          envDefault:"false"`
+}
+
+// Signature represents the configuration for verifying the HMAC signatures of inbound OTLP requests, disabled when no
+// secret is configured.
+type Signature struct {
+	Header      string   `env:"HEADER"        envDefault:"X-Signature"`
+	KeyIDHeader string   `env:"KEY_ID_HEADER" envDefault:"X-Signature-Key-Id"`
+	Secrets     []string `env:"SECRETS"       envDefault:""                   secret:"true"`
+}
+
+// Warmup represents the configuration for pre-establishing the backend connections at startup.
+type Warmup struct {
+	Enabled  bool          `env:"ENABLED"  envDefault:"false"`
+	Timeout  time.Duration `env:"TIMEOUT"  envDefault:"10s"`
+	Required bool          `env:"REQUIRED" envDefault:"false"`
+}
+
+// Ready represents the configuration for the readiness of the proxy, derived from the health of each signal pipeline.
+type Ready struct {
+	Policy           string `env:"POLICY"            envDefault:"all"`
+	FailureThreshold int    `env:"FAILURE_THRESHOLD" envDefault:"3"`
+}
+
+// Watchdog represents the configuration for watching the resources held by the proxy to catch leaks.
+type Watchdog struct {
+	Interval                time.Duration `env:"INTERVAL"                  envDefault:"30s"`
+	GoroutineThreshold      int64         `env:"GOROUTINE_THRESHOLD"       envDefault:"10000"`
+	FileDescriptorThreshold int64         `env:"FILE_DESCRIPTOR_THRESHOLD" envDefault:"0"`
+	HeapObjectThreshold     int64         `env:"HEAP_OBJECT_THRESHOLD"     envDefault:"0"`
+}
+
+// Dispatch error policies deciding how the failure of one tenant affects the others of a request.
+const (
+	DispatchBestEffort = "best-effort"
+	DispatchFailFast   = "fail-fast"
+	DispatchAtLeastOne = "at-least-one"
+)
+
+// Dispatch modes deciding whether requests are answered after or before their data is forwarded.
+const (
+	DispatchSync  = "sync"
+	DispatchAsync = "async"
+)
+
+// Dispatch represents the configuration for dispatching the tenants of a request to the backend.
+type Dispatch struct {
+	ErrorPolicy      string `env:"ERROR_POLICY"       envDefault:"best-effort"`
+	Mode             string `env:"MODE"               envDefault:"sync"`
+	AsyncMaxInflight int    `env:"ASYNC_MAX_INFLIGHT" envDefault:"64"`
+	Ordered          bool   `env:"ORDERED"            envDefault:"false"`
+}
+
+// Tenant represents the configuration for a tenant.
+type Tenant struct {
+	Label         string   `env:"LABEL"          envDefault:"tenant.id"`
+	Labels        []string `env:"LABELS"         envDefault:""`
+	Format        string   `env:"FORMAT"         envDefault:"%s"`
+	InvalidFormat string   `env:"INVALID_FORMAT" envDefault:"fail"`
+	Header        string   `env:"HEADER"         envDefault:"X-Scope-OrgID"`
+	Default       string   `env:"DEFAULT"        envDefault:"default"`
+	Delimiter     string   `env:"DELIMITER"      envDefault:""`
+	Aliases       []string `env:"ALIASES"        envDefault:""`
+	Regions       []string `env:"REGIONS"        envDefault:""`
+	Headers       []string `env:"HEADERS"        envDefault:""              secret:"values"`
+}
+
+// Transform represents the configuration for transforming telemetry before it is forwarded.
+type Transform struct {
+	MetricAttributeAllowlist []string `env:"METRIC_ATTRIBUTE_ALLOWLIST" envDefault:""`
+	DedupSpans               bool     `env:"DEDUP_SPANS"                envDefault:"false"`
+	DedupLogs                bool     `env:"DEDUP_LOGS"                 envDefault:"false"`
+	ReceivedAtAttribute      string   `env:"RECEIVED_AT_ATTRIBUTE"      envDefault:""`
+	InstanceAttribute        string   `env:"INSTANCE_ATTRIBUTE"         envDefault:""`
+}
+
+// TopK represents the configuration for tracking the tenants with the highest volume.
+type TopK struct {
+	Size    int           `env:"SIZE"    envDefault:"10"`
+	Window  time.Duration `env:"WINDOW"  envDefault:"5m"`
+	Buckets int           `env:"BUCKETS" envDefault:"5"`
+}
+
+// Decisions represents the configuration for the sampled feed of routing decisions streamed by the admin API.
+type Decisions struct {
+	SampleRate float64 `env:"SAMPLE_RATE" envDefault:"0.1"`
+	Buffer     int     `env:"BUFFER"      envDefault:"256"`
+}
+
+// FluentForward represents the configuration for the Fluent Forward log receiver.
+type FluentForward struct {
+	Address string        `env:"ADDRESS" envDefault:""`
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"60s"`
+}
+
+// Syslog represents the configuration for the syslog log receiver.
+type Syslog struct {
+	TCPAddress    string        `env:"TCP_ADDRESS"    envDefault:""`
+	UDPAddress    string        `env:"UDP_ADDRESS"    envDefault:""`
+	Timeout       time.Duration `env:"TIMEOUT"        envDefault:"60s"`
+	FlushInterval time.Duration `env:"FLUSH_INTERVAL" envDefault:"1s"`
+	BatchSize     int           `env:"BATCH_SIZE"     envDefault:"1000"`
+	TenantSDID    string        `env:"TENANT_SD_ID"   envDefault:""`
+	TenantSources []string      `env:"TENANT_SOURCES" envDefault:""`
+	TLS           TLSConfig     `envPrefix:"TLS_"`
+
+	ResourceAttributes []string `env:"RESOURCE_ATTRIBUTES" envDefault:""`
+}
+
+// StatsD represents the configuration for the StatsD metric receiver.
+type StatsD struct {
+	TCPAddress     string        `env:"TCP_ADDRESS"     envDefault:""`
+	UDPAddress     string        `env:"UDP_ADDRESS"     envDefault:""`
+	Timeout        time.Duration `env:"TIMEOUT"         envDefault:"60s"`
+	FlushInterval  time.Duration `env:"FLUSH_INTERVAL"  envDefault:"10s"`
+	TenantTag      string        `env:"TENANT_TAG"      envDefault:""`
+	TenantMappings []string      `env:"TENANT_MAPPINGS" envDefault:""`
+	Temporality    string        `env:"TEMPORALITY"     envDefault:"delta"`
+}
+
+// Aggregation temporalities of the counters of the StatsD receiver.
+const (
+	TemporalityDelta      = "delta"
+	TemporalityCumulative = "cumulative"
+)
+
+// Influx represents the configuration for the Influx line protocol write endpoint.
+type Influx struct {
+	Enabled   bool   `env:"ENABLED"    envDefault:"false"`
+	TenantTag string `env:"TENANT_TAG" envDefault:""`
+}
+
+// Datadog represents the configuration for the Datadog agent intake endpoints.
+type Datadog struct {
+	Enabled   bool   `env:"ENABLED"    envDefault:"false"`
+	TenantTag string `env:"TENANT_TAG" envDefault:""`
+}
+
+// LokiPush represents the configuration for the Loki push API endpoint.
+type LokiPush struct {
+	Enabled     bool   `env:"ENABLED"      envDefault:"false"`
+	TenantLabel string `env:"TENANT_LABEL" envDefault:""`
+}
+
+// Firehose represents the configuration for the Amazon Data Firehose HTTP endpoint delivery.
+type Firehose struct {
+	Enabled         bool   `env:"ENABLED"          envDefault:"false"`
+	AccessKey       string `env:"ACCESS_KEY"       envDefault:""      secret:"true"`
+	TenantAttribute string `env:"TENANT_ATTRIBUTE" envDefault:""`
+}
+
+// StreamIngest represents the configuration for the WebSocket endpoint of senders streaming OTLP frames.
+type StreamIngest struct {
+	Enabled     bool          `env:"ENABLED"      envDefault:"false"`
+	IdleTimeout time.Duration `env:"IDLE_TIMEOUT" envDefault:"5m"`
+}
+
+// Zipkin represents the configuration for the Zipkin span receiver.
+type Zipkin struct {
+	Enabled   bool   `env:"ENABLED"    envDefault:"false"`
+	TenantTag string `env:"TENANT_TAG" envDefault:""`
+}
+
+// Jaeger represents the configuration for the Jaeger span receiver.
+type Jaeger struct {
+	Enabled   bool   `env:"ENABLED"    envDefault:"false"`
+	TenantTag string `env:"TENANT_TAG" envDefault:""`
+}
+
+// Scrape represents the configuration for scraping Prometheus targets.
+type Scrape struct {
+	Targets     []string      `env:"TARGETS"      envDefault:""`
+	FileSD      string        `env:"FILE_SD"      envDefault:""`
+	Interval    time.Duration `env:"INTERVAL"     envDefault:"30s"`
+	Timeout     time.Duration `env:"TIMEOUT"      envDefault:"10s"`
+	TenantLabel string        `env:"TENANT_LABEL" envDefault:"tenant"`
+}
+
+// Kafka represents the configuration for consuming OTLP payloads from Kafka topics, disabled without brokers.
+type Kafka struct {
+	Brokers           []string      `env:"BROKERS"             envDefault:""`
+	ClientID          string        `env:"CLIENT_ID"           envDefault:"otel-lgtm-proxy"`
+	GroupID           string        `env:"GROUP_ID"            envDefault:"otel-lgtm-proxy"`
+	LogsTopic         string        `env:"LOGS_TOPIC"          envDefault:"otlp_logs"`
+	MetricsTopic      string        `env:"METRICS_TOPIC"       envDefault:"otlp_metrics"`
+	TracesTopic       string        `env:"TRACES_TOPIC"        envDefault:"otlp_spans"`
+	Encoding          string        `env:"ENCODING"            envDefault:"protobuf"`
+	InitialOffset     string        `env:"INITIAL_OFFSET"      envDefault:"latest"`
+	SessionTimeout    time.Duration `env:"SESSION_TIMEOUT"     envDefault:"10s"`
+	RebalanceTimeout  time.Duration `env:"REBALANCE_TIMEOUT"   envDefault:"30s"`
+	HeartbeatInterval time.Duration `env:"HEARTBEAT_INTERVAL"  envDefault:"3s"`
+	MaxWait           time.Duration `env:"MAX_WAIT"            envDefault:"500ms"`
+	MaxPartitionBytes int32         `env:"MAX_PARTITION_BYTES" envDefault:"1048576"`
+	MaxRetries        int           `env:"MAX_RETRIES"         envDefault:"3"`
+	RetryBackoff      time.Duration `env:"RETRY_BACKOFF"       envDefault:"1s"`
+	RetryBudget       RetryBudget   `envPrefix:"RETRY_BUDGET_"`
+	TLS               KafkaTLS      `envPrefix:"TLS_"`
+	SASL              KafkaSASL     `envPrefix:"SASL_"`
+}
+
+// RetryBudget represents the configuration for suppressing retries while the recent failure rate of a backend
+// exceeds a threshold, disabled when the threshold is zero.
+type RetryBudget struct {
+	Threshold   float64       `env:"THRESHOLD"    envDefault:"0.5"`
+	Window      time.Duration `env:"WINDOW"       envDefault:"1m"`
+	MinForwards int           `env:"MIN_FORWARDS" envDefault:"10"`
+}
+
+// KafkaTLS represents the TLS configuration of the connections to the Kafka brokers.
+type KafkaTLS struct {
+	Enabled            bool   `env:"ENABLED"              envDefault:"false"`
+	CAFile             string `env:"CA_FILE"              envDefault:""`
 	CertFile           string `env:"CERT_FILE"            envDefault:""`
 	KeyFile            string `env:"KEY_FILE"             envDefault:""`
-	CAFile             string `env:"CA_FILE"              envDefault:""`
-	ClientAuthType     string `env:"CLIENT_AUTH_TYPE"     envDefault:"NoClientCert"`
 	InsecureSkipVerify bool   `env:"INSECURE_SKIP_VERIFY" envDefault:"false"`
 }
 
-// Tenant represents the configuration for a tenant.
-type Tenant struct {
-	Label   string   `env:"LABEL"   envDefault:"tenant.id"`
-	Labels  []string `env:"LABELS"  envDefault:""`
-	Format  string   `env:"FORMAT"  envDefault:"%s"`
-	Header  string   `env:"HEADER"  envDefault:"X-Scope-OrgID"`
-	Default string   `env:"DEFAULT" envDefault:"default"`
+// KafkaSASL represents the SASL authentication of the connections to the Kafka brokers, disabled without a mechanism.
+type KafkaSASL struct {
+	Mechanism string `env:"MECHANISM" envDefault:""`
+	Username  string `env:"USERNAME"  envDefault:""`
+	Password  string `env:"PASSWORD"  envDefault:""  secret:"true"`
+}
+
+// SASL mechanisms authenticating the connections to the Kafka brokers.
+const (
+	KafkaSASLPlain       = "PLAIN"
+	KafkaSASLScramSHA256 = "SCRAM-SHA-256"
+	KafkaSASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// Compression codecs of the record batches written to Kafka.
+const (
+	KafkaCompressionNone   = "none"
+	KafkaCompressionGzip   = "gzip"
+	KafkaCompressionSnappy = "snappy"
+	KafkaCompressionLz4    = "lz4"
+	KafkaCompressionZstd   = "zstd"
+)
+
+// Initial offsets deciding where a consumer group starts reading partitions without a committed offset.
+const (
+	KafkaOffsetLatest   = "latest"
+	KafkaOffsetEarliest = "earliest"
+)
+
+// MockBackend represents the configuration for the mock backend subcommand.
+type MockBackend struct {
+	Addresses    []string      `env:"ADDRESSES"     envDefault:":3100,:8080,:3201"`
+	TenantHeader string        `env:"TENANT_HEADER" envDefault:"X-Scope-OrgID"`
+	Timeout      time.Duration `env:"TIMEOUT"       envDefault:"15s"`
 }
 
 // Parse parses the configuration from environment variables
@@ -58,5 +531,29 @@ func Parse() (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	if cfg.Service.InstanceID == "" {
+		cfg.Service.InstanceID = instanceID()
+	}
+	if cfg.GrafanaCloud.Enabled() {
+		if err := cfg.GrafanaCloud.validate(); err != nil {
+			return nil, err
+		}
+		cfg.GrafanaCloud.apply(cfg)
+	}
 	return cfg, nil
 }
+
+// instanceID returns the identifier of this replica, preferring the pod name exposed through the downward API.
+func instanceID() string {
+	for _, key := range []string{"POD_NAME", "HOSTNAME"} {
+		if id := os.Getenv(key); id != "" {
+			return id
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
@@ -29,27 +29,254 @@ func TestParse_Defaults(t *testing.T) {
 	if cfg.Tenant.Format != "%s" {
 		t.Errorf("Tenant.Format = %v, want %%s", cfg.Tenant.Format)
 	}
+	if cfg.Tenant.InvalidFormat != InvalidFormatFail {
+		t.Errorf("Tenant.InvalidFormat = %v, want %v", cfg.Tenant.InvalidFormat, InvalidFormatFail)
+	}
 	if cfg.Tenant.Header != "X-Scope-OrgID" {
 		t.Errorf("Tenant.Header = %v, want X-Scope-OrgID", cfg.Tenant.Header)
 	}
 	if cfg.Tenant.Default != "default" {
 		t.Errorf("Tenant.Default = %v, want default", cfg.Tenant.Default)
 	}
+	if cfg.Warmup.Enabled || cfg.Warmup.Timeout != 10*time.Second || cfg.Warmup.Required {
+		t.Errorf("Warmup = %+v, want disabled with a 10s timeout", cfg.Warmup)
+	}
+	if cfg.Watchdog.Interval != 30*time.Second || cfg.Watchdog.GoroutineThreshold != 10000 ||
+		cfg.Watchdog.FileDescriptorThreshold != 0 || cfg.Watchdog.HeapObjectThreshold != 0 {
+		t.Errorf("Watchdog = %+v, want a 30s interval with a goroutine threshold of 10000", cfg.Watchdog)
+	}
+	if cfg.Ready.Policy != "all" || cfg.Ready.FailureThreshold != 3 {
+		t.Errorf("Ready = %+v, want policy all with a failure threshold of 3", cfg.Ready)
+	}
+	if cfg.Dispatch.ErrorPolicy != DispatchBestEffort {
+		t.Errorf("Dispatch.ErrorPolicy = %v, want %v", cfg.Dispatch.ErrorPolicy, DispatchBestEffort)
+	}
+	if len(cfg.MetricViews.Drop) != 0 || len(cfg.MetricViews.Rename) != 0 {
+		t.Errorf("MetricViews = %+v, want no views", cfg.MetricViews)
+	}
+	if cfg.Dispatch.Ordered {
+		t.Errorf("Dispatch.Ordered = %v, want false", cfg.Dispatch.Ordered)
+	}
+	if cfg.Tenant.Delimiter != "" {
+		t.Errorf("Tenant.Delimiter = %v, want empty", cfg.Tenant.Delimiter)
+	}
+	if len(cfg.Tenant.Aliases) != 0 {
+		t.Errorf("Tenant.Aliases = %v, want empty", cfg.Tenant.Aliases)
+	}
 
 	// Endpoint defaults
 	if cfg.Logs.Timeout != 15*time.Second {
 		t.Errorf("Logs.Timeout = %v, want 15s", cfg.Logs.Timeout)
 	}
+	if cfg.Logs.Stream {
+		t.Errorf("Logs.Stream = %v, want false", cfg.Logs.Stream)
+	}
+	if cfg.Logs.MaxRecords != 0 {
+		t.Errorf("Logs.MaxRecords = %v, want 0", cfg.Logs.MaxRecords)
+	}
+	if cfg.Logs.Redirect != RedirectSameHost {
+		t.Errorf("Logs.Redirect = %v, want %v", cfg.Logs.Redirect, RedirectSameHost)
+	}
+	if cfg.Logs.Kafka.Compression != KafkaCompressionNone {
+		t.Errorf("Logs.Kafka.Compression = %v, want %v", cfg.Logs.Kafka.Compression, KafkaCompressionNone)
+	}
+	if cfg.Logs.Signing.Header != "X-Signature" {
+		t.Errorf("Logs.Signing.Header = %v, want %v", cfg.Logs.Signing.Header, "X-Signature")
+	}
+	if cfg.Logs.Signing.KeyIDHeader != "X-Signature-Key-Id" {
+		t.Errorf("Logs.Signing.KeyIDHeader = %v, want %v", cfg.Logs.Signing.KeyIDHeader, "X-Signature-Key-Id")
+	}
+	if cfg.Logs.IPFamily != IPFamilyDual {
+		t.Errorf("Logs.IPFamily = %v, want %v", cfg.Logs.IPFamily, IPFamilyDual)
+	}
+	if cfg.Logs.FallbackDelay != 300*time.Millisecond {
+		t.Errorf("Logs.FallbackDelay = %v, want 300ms", cfg.Logs.FallbackDelay)
+	}
+	if cfg.Logs.HeaderLimit.Bytes != 16384 {
+		t.Errorf("Logs.HeaderLimit.Bytes = %v, want 16384", cfg.Logs.HeaderLimit.Bytes)
+	}
+	if cfg.Logs.HeaderLimit.Count != 100 {
+		t.Errorf("Logs.HeaderLimit.Count = %v, want 100", cfg.Logs.HeaderLimit.Count)
+	}
 	if cfg.Metrics.Timeout != 15*time.Second {
 		t.Errorf("Metrics.Timeout = %v, want 15s", cfg.Metrics.Timeout)
 	}
 	if cfg.Traces.Timeout != 15*time.Second {
 		t.Errorf("Traces.Timeout = %v, want 15s", cfg.Traces.Timeout)
 	}
+	if cfg.Logs.Discovery.Service != "" || cfg.Logs.Discovery.Mode != DiscoveryDNS ||
+		cfg.Logs.Discovery.Interval != 30*time.Second || cfg.Logs.Discovery.ClusterDomain != "cluster.local" {
+		t.Errorf("Logs.Discovery = %+v, want disabled DNS discovery every 30s in cluster.local", cfg.Logs.Discovery)
+	}
 	if cfg.TimeoutShutdown != 15*time.Second {
 		t.Errorf("TimeoutShutdown = %v, want 15s", cfg.TimeoutShutdown)
 	}
 
+	// TopK defaults
+	if cfg.TopK.Size != 10 {
+		t.Errorf("TopK.Size = %v, want 10", cfg.TopK.Size)
+	}
+	if cfg.TopK.Window != 5*time.Minute {
+		t.Errorf("TopK.Window = %v, want 5m", cfg.TopK.Window)
+	}
+	if cfg.TopK.Buckets != 5 {
+		t.Errorf("TopK.Buckets = %v, want 5", cfg.TopK.Buckets)
+	}
+
+	// Decisions defaults
+	if cfg.Decisions.SampleRate != 0.1 {
+		t.Errorf("Decisions.SampleRate = %v, want 0.1", cfg.Decisions.SampleRate)
+	}
+	if cfg.Decisions.Buffer != 256 {
+		t.Errorf("Decisions.Buffer = %v, want 256", cfg.Decisions.Buffer)
+	}
+
+	// Transform defaults
+	if cfg.Transform.DedupSpans {
+		t.Errorf("Transform.DedupSpans = %v, want false", cfg.Transform.DedupSpans)
+	}
+	if cfg.Transform.DedupLogs {
+		t.Errorf("Transform.DedupLogs = %v, want false", cfg.Transform.DedupLogs)
+	}
+	if cfg.Transform.ReceivedAtAttribute != "" || cfg.Transform.InstanceAttribute != "" {
+		t.Errorf("Transform enrichment attributes = %q, %q, want empty",
+			cfg.Transform.ReceivedAtAttribute, cfg.Transform.InstanceAttribute)
+	}
+
+	// Circuit defaults
+	if cfg.Circuit.Path != "" {
+		t.Errorf("Circuit.Path = %v, want empty", cfg.Circuit.Path)
+	}
+
+	// Debug defaults
+	if cfg.Debug.Header != "X-Proxy-Debug-Token" {
+		t.Errorf("Debug.Header = %v, want X-Proxy-Debug-Token", cfg.Debug.Header)
+	}
+	if cfg.Debug.Token != "" {
+		t.Errorf("Debug.Token = %v, want empty", cfg.Debug.Token)
+	}
+
+	// Listener defaults
+	if !cfg.HTTP.HTTP2 {
+		t.Errorf("HTTP.HTTP2 = %v, want true", cfg.HTTP.HTTP2)
+	}
+	if cfg.HTTP.H2C {
+		t.Errorf("HTTP.H2C = %v, want false", cfg.HTTP.H2C)
+	}
+	if cfg.HTTP.IPFamily != IPFamilyDual {
+		t.Errorf("HTTP.IPFamily = %v, want %v", cfg.HTTP.IPFamily, IPFamilyDual)
+	}
+	if len(cfg.HTTP.PlaintextAddresses) != 0 {
+		t.Errorf("HTTP.PlaintextAddresses = %v, want empty", cfg.HTTP.PlaintextAddresses)
+	}
+	if cfg.HTTP.MaxBodyBytes != 20<<20 {
+		t.Errorf("HTTP.MaxBodyBytes = %v, want %v", cfg.HTTP.MaxBodyBytes, 20<<20)
+	}
+	if cfg.HTTP.LogsMaxBodyBytes != 0 || cfg.HTTP.MetricsMaxBodyBytes != 0 || cfg.HTTP.TracesMaxBodyBytes != 0 ||
+		cfg.HTTP.ProfilesMaxBodyBytes != 0 {
+		t.Errorf("HTTP signal max body bytes = %+v, want 0", cfg.HTTP)
+	}
+	if cfg.HTTP.HTTP2MaxConcurrentStreams != 250 {
+		t.Errorf("HTTP.HTTP2MaxConcurrentStreams = %v, want 250", cfg.HTTP.HTTP2MaxConcurrentStreams)
+	}
+	if cfg.HTTP.HTTP2MaxReceiveBuffer != 1<<20 {
+		t.Errorf("HTTP.HTTP2MaxReceiveBuffer = %v, want %v", cfg.HTTP.HTTP2MaxReceiveBuffer, 1<<20)
+	}
+	if cfg.HTTP.HTTP2MaxStreamReceiveBuffer != 1<<20 {
+		t.Errorf("HTTP.HTTP2MaxStreamReceiveBuffer = %v, want %v", cfg.HTTP.HTTP2MaxStreamReceiveBuffer, 1<<20)
+	}
+	if cfg.GRPC.Address != "" || cfg.GRPC.MaxRecvMsgSize != 4194304 {
+		t.Errorf("GRPC = %+v, want disabled with a 4MB message limit", cfg.GRPC)
+	}
+	if !cfg.GRPC.Reflection {
+		t.Errorf("GRPC.Reflection = %v, want true", cfg.GRPC.Reflection)
+	}
+	if cfg.GRPC.HealthWatchInterval != 5*time.Second {
+		t.Errorf("GRPC.HealthWatchInterval = %v, want %v", cfg.GRPC.HealthWatchInterval, 5*time.Second)
+	}
+	if cfg.HTTP.MaxConnections != 0 {
+		t.Errorf("HTTP.MaxConnections = %v, want 0", cfg.HTTP.MaxConnections)
+	}
+	if cfg.HTTP.ReadTimeout != 0 || cfg.HTTP.ReadHeaderTimeout != 0 || cfg.HTTP.WriteTimeout != 0 {
+		t.Errorf("HTTP timeouts = %v/%v/%v, want 0 to use HTTP.Timeout",
+			cfg.HTTP.ReadTimeout, cfg.HTTP.ReadHeaderTimeout, cfg.HTTP.WriteTimeout)
+	}
+	if cfg.HTTP.MaxHeaderBytes != 1<<20 {
+		t.Errorf("HTTP.MaxHeaderBytes = %v, want %v", cfg.HTTP.MaxHeaderBytes, 1<<20)
+	}
+	if cfg.HTTP.IdleTimeout != 0 {
+		t.Errorf("HTTP.IdleTimeout = %v, want 0s", cfg.HTTP.IdleTimeout)
+	}
+	if cfg.HTTP.DisableKeepAlives {
+		t.Errorf("HTTP.DisableKeepAlives = %v, want false", cfg.HTTP.DisableKeepAlives)
+	}
+
+	// Tenant client defaults
+	if cfg.TenantClients.CacheSize != 256 {
+		t.Errorf("TenantClients.CacheSize = %v, want 256", cfg.TenantClients.CacheSize)
+	}
+	if cfg.TenantClients.IdleTimeout != 5*time.Minute {
+		t.Errorf("TenantClients.IdleTimeout = %v, want 5m", cfg.TenantClients.IdleTimeout)
+	}
+
+	// Syslog defaults
+	if cfg.Syslog.FlushInterval != time.Second {
+		t.Errorf("Syslog.FlushInterval = %v, want 1s", cfg.Syslog.FlushInterval)
+	}
+	if cfg.Syslog.BatchSize != 1000 {
+		t.Errorf("Syslog.BatchSize = %v, want 1000", cfg.Syslog.BatchSize)
+	}
+	if len(cfg.Syslog.ResourceAttributes) != 0 {
+		t.Errorf("Syslog.ResourceAttributes = %v, want empty", cfg.Syslog.ResourceAttributes)
+	}
+
+	// StatsD defaults
+	if cfg.StatsD.FlushInterval != 10*time.Second {
+		t.Errorf("StatsD.FlushInterval = %v, want 10s", cfg.StatsD.FlushInterval)
+	}
+	if cfg.StatsD.Temporality != TemporalityDelta {
+		t.Errorf("StatsD.Temporality = %v, want %v", cfg.StatsD.Temporality, TemporalityDelta)
+	}
+
+	// Stream ingest defaults
+	if cfg.StreamIngest.Enabled {
+		t.Errorf("StreamIngest.Enabled = %v, want false", cfg.StreamIngest.Enabled)
+	}
+	if cfg.StreamIngest.IdleTimeout != 5*time.Minute {
+		t.Errorf("StreamIngest.IdleTimeout = %v, want 5m", cfg.StreamIngest.IdleTimeout)
+	}
+
+	// Scrape defaults
+	if len(cfg.Scrape.Targets) != 0 {
+		t.Errorf("Scrape.Targets = %v, want empty", cfg.Scrape.Targets)
+	}
+	if cfg.Scrape.Interval != 30*time.Second {
+		t.Errorf("Scrape.Interval = %v, want 30s", cfg.Scrape.Interval)
+	}
+	if cfg.Scrape.TenantLabel != "tenant" {
+		t.Errorf("Scrape.TenantLabel = %v, want tenant", cfg.Scrape.TenantLabel)
+	}
+
+	// Kafka defaults
+	if len(cfg.Kafka.Brokers) != 0 {
+		t.Errorf("Kafka.Brokers = %v, want empty", cfg.Kafka.Brokers)
+	}
+	if cfg.Kafka.GroupID != "otel-lgtm-proxy" {
+		t.Errorf("Kafka.GroupID = %v, want otel-lgtm-proxy", cfg.Kafka.GroupID)
+	}
+	if cfg.Kafka.TracesTopic != "otlp_spans" {
+		t.Errorf("Kafka.TracesTopic = %v, want otlp_spans", cfg.Kafka.TracesTopic)
+	}
+	if cfg.Kafka.InitialOffset != KafkaOffsetLatest {
+		t.Errorf("Kafka.InitialOffset = %v, want %v", cfg.Kafka.InitialOffset, KafkaOffsetLatest)
+	}
+	if cfg.Kafka.MaxPartitionBytes != 1<<20 {
+		t.Errorf("Kafka.MaxPartitionBytes = %v, want %v", cfg.Kafka.MaxPartitionBytes, 1<<20)
+	}
+	if want := (RetryBudget{Threshold: 0.5, Window: time.Minute, MinForwards: 10}); cfg.Kafka.RetryBudget != want {
+		t.Errorf("Kafka.RetryBudget = %+v, want %+v", cfg.Kafka.RetryBudget, want)
+	}
+
 	// TLS defaults
 	if cfg.Logs.TLS.ClientAuthType != "NoClientCert" {
 		t.Errorf("Logs.TLS.ClientAuthType = %v, want NoClientCert", cfg.Logs.TLS.ClientAuthType)
@@ -230,3 +457,125 @@ func TestParse_AllValues(t *testing.T) {
 		)
 	}
 }
+
+func TestParse_Ingest(t *testing.T) {
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v, want nil", err)
+	}
+	if cfg.Ingest.EmptyPayload != EmptyPayloadAccept {
+		t.Errorf("Ingest.EmptyPayload = %v, want %v", cfg.Ingest.EmptyPayload, EmptyPayloadAccept)
+	}
+	if cfg.Ingest.InvalidResources != InvalidResourcesReject {
+		t.Errorf("Ingest.InvalidResources = %v, want %v", cfg.Ingest.InvalidResources, InvalidResourcesReject)
+	}
+	if cfg.Ingest.ContentType != ContentTypeLenient {
+		t.Errorf("Ingest.ContentType = %v, want %v", cfg.Ingest.ContentType, ContentTypeLenient)
+	}
+	if cfg.Ingest.Strict {
+		t.Errorf("Ingest.Strict = %v, want false", cfg.Ingest.Strict)
+	}
+	if cfg.Ingest.Heartbeats {
+		t.Errorf("Ingest.Heartbeats = %v, want false", cfg.Ingest.Heartbeats)
+	}
+
+	t.Setenv("INGEST_EMPTY_PAYLOAD", "reject")
+	t.Setenv("INGEST_INVALID_RESOURCES", "skip")
+	t.Setenv("INGEST_CONTENT_TYPE", "strict")
+
+	cfg, err = Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v, want nil", err)
+	}
+	if cfg.Ingest.EmptyPayload != EmptyPayloadReject {
+		t.Errorf("Ingest.EmptyPayload = %v, want %v", cfg.Ingest.EmptyPayload, EmptyPayloadReject)
+	}
+	if cfg.Ingest.InvalidResources != InvalidResourcesSkip {
+		t.Errorf("Ingest.InvalidResources = %v, want %v", cfg.Ingest.InvalidResources, InvalidResourcesSkip)
+	}
+	if cfg.Ingest.ContentType != ContentTypeStrict {
+		t.Errorf("Ingest.ContentType = %v, want %v", cfg.Ingest.ContentType, ContentTypeStrict)
+	}
+}
+
+func TestParse_TrustedProxies(t *testing.T) {
+	t.Setenv("HTTP_LISTEN_ADDRESS", ":8443")
+	t.Setenv("HTTP_LISTEN_TRUSTED_PROXIES", "10.0.0.0/8,192.0.2.1")
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v, want nil", err)
+	}
+	if cfg.HTTP.Address != ":8443" {
+		t.Errorf("HTTP.Address = %v, want :8443", cfg.HTTP.Address)
+	}
+	expected := []string{"10.0.0.0/8", "192.0.2.1"}
+	if len(cfg.HTTP.TrustedProxies) != len(expected) {
+		t.Fatalf("HTTP.TrustedProxies = %v, want %v", cfg.HTTP.TrustedProxies, expected)
+	}
+	for i, proxy := range expected {
+		if cfg.HTTP.TrustedProxies[i] != proxy {
+			t.Errorf("HTTP.TrustedProxies[%d] = %v, want %v", i, cfg.HTTP.TrustedProxies[i], proxy)
+		}
+	}
+}
+
+func TestParse_InstanceID(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "explicit instance id",
+			env:  map[string]string{"OTEL_SERVICE_INSTANCE_ID": "replica-1", "POD_NAME": "pod-1", "HOSTNAME": "host-1"},
+			want: "replica-1",
+		},
+		{
+			name: "pod name from the downward api",
+			env:  map[string]string{"POD_NAME": "pod-1", "HOSTNAME": "host-1"},
+			want: "pod-1",
+		},
+		{
+			name: "hostname",
+			env:  map[string]string{"HOSTNAME": "host-1"},
+			want: "host-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POD_NAME", "")
+			t.Setenv("HOSTNAME", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := Parse()
+			if err != nil {
+				t.Fatalf("Parse() error = %v, want nil", err)
+			}
+			if cfg.Service.InstanceID != tt.want {
+				t.Errorf("Service.InstanceID = %v, want %v", cfg.Service.InstanceID, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_MetricAttributeAllowlist(t *testing.T) {
+	t.Setenv("TRANSFORM_METRIC_ATTRIBUTE_ALLOWLIST", "tenant-a=http.route|service.name,*=service.name")
+
+	cfg, err := Parse()
+	if err != nil {
+		t.Fatalf("Parse() error = %v, want nil", err)
+	}
+	expected := []string{"tenant-a=http.route|service.name", "*=service.name"}
+	if len(cfg.Transform.MetricAttributeAllowlist) != len(expected) {
+		t.Fatalf("Transform.MetricAttributeAllowlist = %v, want %v", cfg.Transform.MetricAttributeAllowlist, expected)
+	}
+	for i, entry := range expected {
+		if cfg.Transform.MetricAttributeAllowlist[i] != entry {
+			t.Errorf("Transform.MetricAttributeAllowlist[%d] = %v, want %v", i, cfg.Transform.MetricAttributeAllowlist[i], entry)
+		}
+	}
+}
@@ -4,7 +4,8 @@
 //   - Service metadata (name, version)
 //   - HTTP server settings (address, TLS configuration)
 //   - Tenant identification configuration
-//   - Downstream endpoint configurations for logs, metrics, and traces
+//   - Downstream endpoint configurations for logs, metrics, and traces, which
+//     can be expanded from a Grafana Cloud stack
 //   - TLS settings for both server and client connections
 //   - Timeout settings for shutdown and HTTP requests
 //
@@ -0,0 +1,84 @@
+// Package config provides the configuration for the application.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GrafanaCloud represents the configuration of a Grafana Cloud stack receiving the signals through its OTLP gateway,
+// disabled when no zone is set.
+type GrafanaCloud struct {
+	Zone       string `env:"ZONE"        envDefault:""`
+	InstanceID string `env:"INSTANCE_ID" envDefault:""`
+	Token      string `env:"TOKEN"       envDefault:"" secret:"true"`
+}
+
+// grafanaCloudZone matches the zones of Grafana Cloud stacks, such as prod-us-central-0.
+var grafanaCloudZone = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Enabled returns true when a Grafana Cloud stack is configured.
+func (g *GrafanaCloud) Enabled() bool {
+	return g.Zone != "" || g.InstanceID != "" || g.Token != ""
+}
+
+// gatewayURL returns the base URL of the OTLP gateway of the stack.
+func (g *GrafanaCloud) gatewayURL() string {
+	return "https://otlp-gateway-" + g.Zone + ".grafana.net/otlp"
+}
+
+// validate returns an error when the stack is only partially configured.
+func (g *GrafanaCloud) validate() error {
+	if g.Zone == "" || g.InstanceID == "" || g.Token == "" {
+		return errors.New("GRAFANA_CLOUD_ZONE, GRAFANA_CLOUD_INSTANCE_ID and GRAFANA_CLOUD_TOKEN must be set together")
+	}
+	if !grafanaCloudZone.MatchString(g.Zone) {
+		return fmt.Errorf("invalid grafana cloud zone %q, want a zone such as prod-us-central-0", g.Zone)
+	}
+	if _, err := strconv.ParseUint(g.InstanceID, 10, 64); err != nil {
+		return fmt.Errorf("invalid grafana cloud instance id %q, want the numeric id of the stack", g.InstanceID)
+	}
+	return nil
+}
+
+// apply points the OTLP endpoints without an address at the OTLP gateway of the stack, authenticated with the
+// instance ID and token of the stack. Grafana Cloud identifies the tenant by the credentials, so the default tenant
+// format is replaced by the instance ID to send it as the tenant of every request.
+func (g *GrafanaCloud) apply(cfg *Config) {
+	credentials := base64.StdEncoding.EncodeToString([]byte(g.InstanceID + ":" + g.Token))
+	for _, target := range []struct {
+		endpoint *Endpoint
+		path     string
+	}{
+		{endpoint: &cfg.Logs, path: "/v1/logs"},
+		{endpoint: &cfg.Metrics, path: "/v1/metrics"},
+		{endpoint: &cfg.Traces, path: "/v1/traces"},
+	} {
+		endpoint := target.endpoint
+		if endpoint.Address != "" || (endpoint.Protocol != "" && endpoint.Protocol != ProtocolOTLP) {
+			continue
+		}
+		endpoint.Address = g.gatewayURL() + target.path
+		if !hasHeader(endpoint.Headers, "Authorization") {
+			endpoint.Headers = strings.TrimPrefix(endpoint.Headers+",Authorization=Basic "+credentials, ",")
+		}
+	}
+
+	if cfg.Tenant.Format == "" || cfg.Tenant.Format == "%s" {
+		cfg.Tenant.Format = g.InstanceID
+	}
+}
+
+// hasHeader reports whether the custom headers, of the form key=value separated by commas, set the header.
+func hasHeader(headers, name string) bool {
+	for header := range strings.SplitSeq(headers, ",") {
+		if key, _, ok := strings.Cut(header, "="); ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+// Package config provides the configuration for the application.
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Redacted is the placeholder used in place of secret configuration values.
+const Redacted = "REDACTED"
+
+// Redact returns a copy of the configuration with all secret values replaced.
+//
+// String fields tagged with `secret:"true"` are replaced entirely, while fields tagged
+// with `secret:"values"` hold comma-separated key=value pairs and only have their values replaced.
+// String slices tagged with `secret:"values"` have the value after the first "=" of each element replaced.
+func (c *Config) Redact() *Config {
+	redacted := *c
+	redactValue(reflect.ValueOf(&redacted).Elem())
+	return &redacted
+}
+
+// redactValue walks the struct and redacts the tagged fields in place.
+func redactValue(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Struct:
+			redactValue(field)
+		case reflect.String:
+			switch t.Field(i).Tag.Get("secret") {
+			case "true":
+				if field.String() != "" {
+					field.SetString(Redacted)
+				}
+			case "values":
+				field.SetString(redactPairs(field.String()))
+			}
+		case reflect.Slice:
+			switch t.Field(i).Tag.Get("secret") {
+			case "true":
+				if field.Len() > 0 {
+					field.Set(reflect.ValueOf([]string{Redacted}))
+				}
+			case "values":
+				// Copy the elements rather than redacting them in place, the slice being shared with the original
+				values := make([]string, field.Len())
+				for j := range values {
+					values[j] = redactPairs(field.Index(j).String())
+				}
+				if len(values) > 0 {
+					field.Set(reflect.ValueOf(values))
+				}
+			}
+		}
+	}
+}
+
+// redactPairs replaces the values of comma-separated key=value pairs.
+func redactPairs(pairs string) string {
+	if pairs == "" {
+		return ""
+	}
+
+	parts := strings.Split(pairs, ",")
+	for i, part := range parts {
+		if key, _, ok := strings.Cut(part, "="); ok {
+			parts[i] = key + "=" + Redacted
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
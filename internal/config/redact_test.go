@@ -0,0 +1,103 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		want    string
+	}{
+		{
+			name:    "no headers",
+			headers: "",
+			want:    "",
+		},
+		{
+			name:    "single header",
+			headers: "Authorization=Bearer xyz",
+			want:    "Authorization=" + Redacted,
+		},
+		{
+			name:    "multiple headers",
+			headers: "Authorization=Bearer xyz,X-Custom=value",
+			want:    "Authorization=" + Redacted + ",X-Custom=" + Redacted,
+		},
+		{
+			name:    "value containing equals sign",
+			headers: "Authorization=Basic dXNlcjpwYXNz==",
+			want:    "Authorization=" + Redacted,
+		},
+		{
+			name:    "malformed entry is kept",
+			headers: "InvalidHeader",
+			want:    "InvalidHeader",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Logs:    Endpoint{Address: "http://loki:3100", Headers: tt.headers},
+				Metrics: Endpoint{Headers: tt.headers},
+				Traces:  Endpoint{Headers: tt.headers},
+			}
+
+			got := cfg.Redact()
+
+			for name, endpoint := range map[string]Endpoint{"Logs": got.Logs, "Metrics": got.Metrics, "Traces": got.Traces} {
+				if endpoint.Headers != tt.want {
+					t.Errorf("%s.Headers = %v, want %v", name, endpoint.Headers, tt.want)
+				}
+			}
+			if got.Logs.Address != "http://loki:3100" {
+				t.Errorf("Logs.Address = %v, want http://loki:3100", got.Logs.Address)
+			}
+			if cfg.Logs.Headers != tt.headers {
+				t.Errorf("original Logs.Headers = %v, want %v", cfg.Logs.Headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestRedact_TenantHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    []string
+	}{
+		{
+			name:    "no headers",
+			headers: nil,
+			want:    nil,
+		},
+		{
+			name:    "headers of every signal and of a signal",
+			headers: []string{"acme:Authorization=Bearer xyz", "acme:traces:X-Retention=7d"},
+			want:    []string{"acme:Authorization=" + Redacted, "acme:traces:X-Retention=" + Redacted},
+		},
+		{
+			name:    "malformed entry is kept",
+			headers: []string{"acme"},
+			want:    []string{"acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Tenant: Tenant{Headers: slices.Clone(tt.headers)}}
+
+			got := cfg.Redact()
+
+			if !slices.Equal(got.Tenant.Headers, tt.want) {
+				t.Errorf("Tenant.Headers = %v, want %v", got.Tenant.Headers, tt.want)
+			}
+			if !slices.Equal(cfg.Tenant.Headers, tt.headers) {
+				t.Errorf("original Tenant.Headers = %v, want %v", cfg.Tenant.Headers, tt.headers)
+			}
+		})
+	}
+}
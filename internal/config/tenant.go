@@ -0,0 +1,211 @@
+// Package config provides the configuration for the application.
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Invalid tenant format policies.
+const (
+	InvalidFormatFail = "fail"
+	InvalidFormatRaw  = "raw"
+)
+
+// ValidateFormat returns an error when the tenant format is not usable.
+//
+// A format may contain at most one %s or %v verb, optionally with flags, width and precision, and any number of %%
+// escapes. A format without a verb yields the same static tenant for every request.
+func (t *Tenant) ValidateFormat() error {
+	_, err := formatVerbs(t.Format)
+	return err
+}
+
+// FormatTenant applies the tenant format to the tenant.
+//
+// An empty format forwards the tenant as is, and a format without a verb forwards the format itself.
+func (t *Tenant) FormatTenant(tenant string) string {
+	if t.Format == "" {
+		return tenant
+	}
+	if verbs, err := formatVerbs(t.Format); err == nil && verbs == 0 {
+		return strings.ReplaceAll(t.Format, "%%", "%")
+	}
+	return fmt.Sprintf(t.Format, tenant)
+}
+
+// Static reports whether the tenant format has no verb, forwarding the same static tenant for every request.
+func (t *Tenant) Static() bool {
+	verbs, err := formatVerbs(t.Format)
+	return t.Format != "" && err == nil && verbs == 0
+}
+
+// Alias is a tenant rename, data for the old tenant is written to both tenants until the end of the migration and only
+// to the new tenant afterwards.
+type Alias struct {
+	From  string
+	To    string
+	Until time.Time
+}
+
+// DualWrite reports whether data for the old tenant is still written to both tenants at the given time. An alias without
+// an end time dual-writes indefinitely.
+func (a Alias) DualWrite(now time.Time) bool {
+	return a.Until.IsZero() || now.Before(a.Until)
+}
+
+// ParseAliases parses the tenant aliases of the form old=new or old=new@until, where until is an RFC 3339 time.
+func (t *Tenant) ParseAliases() ([]Alias, error) {
+	aliases := make([]Alias, 0, len(t.Aliases))
+	for _, entry := range t.Aliases {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		from, rest, ok := strings.Cut(entry, "=")
+		to, until, hasUntil := strings.Cut(rest, "@")
+		alias := Alias{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+		if !ok || alias.From == "" || alias.To == "" || alias.From == alias.To {
+			return nil, fmt.Errorf("invalid tenant alias %q, expected old=new or old=new@until", entry)
+		}
+		if hasUntil {
+			var err error
+			if alias.Until, err = time.Parse(time.RFC3339, strings.TrimSpace(until)); err != nil {
+				return nil, fmt.Errorf("invalid tenant alias %q end time: %w", entry, err)
+			}
+		}
+		aliases = append(aliases, alias)
+	}
+
+	// Chained aliases would make the target of a rename depend on the order they are applied in
+	for _, alias := range aliases {
+		for _, other := range aliases {
+			if alias.From == other.To {
+				return nil, fmt.Errorf("tenant alias for %q conflicts with an alias renaming to it", alias.From)
+			}
+			if alias.From == other.From && alias != other {
+				return nil, fmt.Errorf("multiple tenant aliases for %q", alias.From)
+			}
+		}
+	}
+
+	return aliases, nil
+}
+
+// ParseRegions parses the tenant data residency regions of the form tenant=region, indexed by tenant.
+func (t *Tenant) ParseRegions() (map[string]string, error) {
+	regions := make(map[string]string, len(t.Regions))
+	for _, entry := range t.Regions {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tenant, region, ok := strings.Cut(entry, "=")
+		tenant, region = strings.TrimSpace(tenant), strings.TrimSpace(region)
+		if !ok || tenant == "" || region == "" {
+			return nil, fmt.Errorf("invalid tenant region %q, expected tenant=region", entry)
+		}
+		if existing, ok := regions[tenant]; ok && existing != region {
+			return nil, fmt.Errorf("multiple regions for tenant %q", tenant)
+		}
+		regions[tenant] = region
+	}
+	return regions, nil
+}
+
+// ParseHeaders parses the extra headers of the tenants of the form tenant:Header=value, or tenant:signal:Header=value
+// for a header only sent with one signal, and returns those sent with the signal, indexed by tenant. The headers of a
+// signal replace the headers of the same name sent with every signal.
+//
+// The content type and the tenant header are set by the proxy and cannot be configured per tenant.
+func (t *Tenant) ParseHeaders(signal string) (map[string]http.Header, error) {
+	all := make(map[string]http.Header)
+	scoped := make(map[string]http.Header)
+	for _, entry := range t.Headers {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		target, value, ok := strings.Cut(entry, "=")
+		parts := strings.Split(target, ":")
+		if !ok || len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid tenant header %q, expected tenant:Header=value or tenant:signal:Header=value", entry)
+		}
+		tenant, key := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[len(parts)-1])
+		if tenant == "" || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("invalid tenant header %q, expected tenant:Header=value or tenant:signal:Header=value", entry)
+		}
+		if strings.EqualFold(key, "Content-Type") || strings.EqualFold(key, t.Header) {
+			return nil, fmt.Errorf("invalid tenant header %q, the %s header is set by the proxy", entry, key)
+		}
+
+		headers := all
+		if len(parts) == 3 {
+			entrySignal := strings.TrimSpace(parts[1])
+			if !slices.Contains([]string{"logs", "metrics", "traces", "profiles"}, entrySignal) {
+				return nil, fmt.Errorf("invalid tenant header %q, unknown signal %q", entry, entrySignal)
+			}
+			if entrySignal != signal {
+				continue
+			}
+			headers = scoped
+		}
+		if headers[tenant] == nil {
+			headers[tenant] = make(http.Header)
+		}
+		headers[tenant].Add(key, strings.TrimSpace(value))
+	}
+
+	for tenant, header := range scoped {
+		if all[tenant] == nil {
+			all[tenant] = make(http.Header)
+		}
+		for key, values := range header {
+			all[tenant][key] = values
+		}
+	}
+	return all, nil
+}
+
+// formatVerbs returns the number of verbs of the format, or an error when it contains an unsupported or more than one
+// verb.
+func formatVerbs(format string) (int, error) {
+	verbs := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+
+		// Skip the flags, width and precision
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j == len(format) {
+			return 0, fmt.Errorf("invalid tenant format %q: incomplete verb at end", format)
+		}
+
+		switch format[j] {
+		case '%':
+			if j != i+1 {
+				return 0, fmt.Errorf("invalid tenant format %q: %%%% must not have flags", format)
+			}
+		case 's', 'v':
+			verbs++
+			if verbs > 1 {
+				return 0, fmt.Errorf("invalid tenant format %q: at most one verb is supported", format)
+			}
+		default:
+			return 0, fmt.Errorf("invalid tenant format %q: unsupported verb %%%c, use %%s", format, format[j])
+		}
+		i = j
+	}
+
+	return verbs, nil
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTenant_ValidateFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "default", format: "%s"},
+		{name: "prefix and suffix", format: "org-%s-prod"},
+		{name: "value verb", format: "%v"},
+		{name: "precision", format: "%.8s"},
+		{name: "static", format: "shared"},
+		{name: "escaped percent", format: "100%%-%s"},
+		{name: "empty", format: ""},
+		{name: "multiple verbs", format: "%s-%s", wantErr: true},
+		{name: "unsupported verb", format: "%d", wantErr: true},
+		{name: "quoted verb", format: "%q", wantErr: true},
+		{name: "trailing percent", format: "tenant-%", wantErr: true},
+		{name: "argument index", format: "%[1]s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Format: tt.format}
+			if err := tenant.ValidateFormat(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTenant_FormatTenant(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "default", format: "%s", want: "tenant-a"},
+		{name: "prefix and suffix", format: "org-%s-prod", want: "org-tenant-a-prod"},
+		{name: "static", format: "shared", want: "shared"},
+		{name: "static with escaped percent", format: "100%%", want: "100%"},
+		{name: "escaped percent with verb", format: "100%%-%s", want: "100%-tenant-a"},
+		{name: "empty", format: "", want: "tenant-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Format: tt.format}
+			if got := tenant.FormatTenant("tenant-a"); got != tt.want {
+				t.Errorf("FormatTenant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenant_Static(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   bool
+	}{
+		{name: "default", format: "%s"},
+		{name: "static", format: "shared", want: true},
+		{name: "static with escaped percent", format: "100%%", want: true},
+		{name: "empty", format: ""},
+		{name: "invalid", format: "%d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Format: tt.format}
+			if got := tenant.Static(); got != tt.want {
+				t.Errorf("Static() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenant_ParseAliases(t *testing.T) {
+	until := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		aliases []string
+		want    []Alias
+		wantErr bool
+	}{
+		{name: "none", want: []Alias{}},
+		{name: "dual-write indefinitely", aliases: []string{"old=new"}, want: []Alias{{From: "old", To: "new"}}},
+		{
+			name:    "dual-write until",
+			aliases: []string{" old = new @ 2026-12-31T00:00:00Z", ""},
+			want:    []Alias{{From: "old", To: "new", Until: until}},
+		},
+		{name: "missing target", aliases: []string{"old="}, wantErr: true},
+		{name: "missing separator", aliases: []string{"old"}, wantErr: true},
+		{name: "same tenant", aliases: []string{"old=old"}, wantErr: true},
+		{name: "invalid end time", aliases: []string{"old=new@tomorrow"}, wantErr: true},
+		{name: "chained", aliases: []string{"a=b", "b=c"}, wantErr: true},
+		{name: "conflicting", aliases: []string{"a=b", "a=c"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Aliases: tt.aliases}
+			got, err := tenant.ParseAliases()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAliases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAliases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenant_ParseRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		regions []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "none", want: map[string]string{}},
+		{
+			name:    "regions",
+			regions: []string{" acme = eu-west-1 ", "", "globex=us-east-1", "acme=eu-west-1"},
+			want:    map[string]string{"acme": "eu-west-1", "globex": "us-east-1"},
+		},
+		{name: "missing region", regions: []string{"acme="}, wantErr: true},
+		{name: "missing tenant", regions: []string{"=eu-west-1"}, wantErr: true},
+		{name: "missing separator", regions: []string{"acme"}, wantErr: true},
+		{name: "conflicting", regions: []string{"acme=eu-west-1", "acme=us-east-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Regions: tt.regions}
+			got, err := tenant.ParseRegions()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRegions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenant_ParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []string
+		want    map[string]http.Header
+		wantErr bool
+	}{
+		{name: "none", want: map[string]http.Header{}},
+		{
+			name:    "every signal",
+			headers: []string{" acme:x-retention = 30d ", "", "acme:X-Tier=hot", "globex:X-Retention=7d"},
+			want: map[string]http.Header{
+				"acme":   {"X-Retention": {"30d"}, "X-Tier": {"hot"}},
+				"globex": {"X-Retention": {"7d"}},
+			},
+		},
+		{
+			name:    "signal headers replace the headers of every signal",
+			headers: []string{"acme:X-Retention=30d", "acme:logs:X-Retention=90d", "acme:traces:X-Retention=7d"},
+			want:    map[string]http.Header{"acme": {"X-Retention": {"90d"}}},
+		},
+		{
+			name:    "headers of other signals",
+			headers: []string{"acme:metrics:X-Retention=1y"},
+			want:    map[string]http.Header{},
+		},
+		{
+			name:    "value with a colon",
+			headers: []string{"acme:X-Bucket=s3://logs"},
+			want:    map[string]http.Header{"acme": {"X-Bucket": {"s3://logs"}}},
+		},
+		{name: "missing tenant", headers: []string{":X-Retention=30d"}, wantErr: true},
+		{name: "missing header", headers: []string{"acme=30d"}, wantErr: true},
+		{name: "missing value", headers: []string{"acme:X-Retention"}, wantErr: true},
+		{name: "unknown signal", headers: []string{"acme:events:X-Retention=30d"}, wantErr: true},
+		{name: "tenant header", headers: []string{"acme:x-scope-orgid=other"}, wantErr: true},
+		{name: "content type", headers: []string{"acme:Content-Type=text/plain"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tenant := &Tenant{Header: "X-Scope-OrgID", Headers: tt.headers}
+			got, err := tenant.ParseHeaders("logs")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlias_DualWrite(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		alias Alias
+		want  bool
+	}{
+		{name: "no end time", alias: Alias{From: "old", To: "new"}, want: true},
+		{name: "before end time", alias: Alias{From: "old", To: "new", Until: now.Add(time.Hour)}, want: true},
+		{name: "after end time", alias: Alias{From: "old", To: "new", Until: now.Add(-time.Hour)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.alias.DualWrite(now); got != tt.want {
+				t.Errorf("DualWrite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package datadog converts Datadog agent intake payloads into OTLP.
+package datadog
+
+import (
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+var (
+	hostNameAttrKey    = "host.name"
+	serviceNameAttrKey = "service.name"
+	sourceAttrKey      = "datadog.source"
+	resourceAttrPrefix = "datadog.resource."
+)
+
+// scopeName is the instrumentation scope of the converted signals.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/datadog"
+
+// Converter converts Datadog payloads into OTLP resources grouped by tenant.
+type Converter struct {
+	tenantLabel string
+	tenantTag   string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a series or log
+// is read from its tenantTag tag when set.
+func New(tenantLabel, tenantTag string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantTag: tenantTag}
+}
+
+// tag is a parsed key:value tag, tags without a value have an empty value.
+type tag struct {
+	key   string
+	value string
+}
+
+// parseTags parses key:value tags.
+func parseTags(tags []string) []tag {
+	parsed := make([]tag, 0, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(t, ":")
+		parsed = append(parsed, tag{key: key, value: value})
+	}
+	return parsed
+}
+
+// tenant returns the value of the tenant tag, or an empty string when there is none.
+func (c *Converter) tenant(tags []tag) string {
+	if c.tenantTag == "" {
+		return ""
+	}
+	for _, t := range tags {
+		if t.key == c.tenantTag {
+			return t.value
+		}
+	}
+	return ""
+}
+
+// resourceAttributes returns the tenant attribute, when there is a tenant, followed by the given attributes.
+func (c *Converter) resourceAttributes(tenant string, attributes ...*commonpb.KeyValue) []*commonpb.KeyValue {
+	if tenant == "" {
+		return attributes
+	}
+	return append([]*commonpb.KeyValue{stringKeyValue(c.tenantLabel, tenant)}, attributes...)
+}
+
+// tagAttributes returns the tags as attributes.
+func tagAttributes(tags []tag) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(tags))
+	for _, t := range tags {
+		attributes = append(attributes, stringKeyValue(t.key, t.value))
+	}
+	return attributes
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
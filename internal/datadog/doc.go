@@ -0,0 +1,18 @@
+// Package datadog converts Datadog agent intake payloads into OTLP.
+//
+// Metric series posted to /api/v2/series, either as the agent's protobuf
+// MetricPayload or as JSON, are converted into OTLP metrics:
+//   - Counts become delta sums over the interval of the series
+//   - Rates and gauges become gauges
+//   - Tags become data point attributes
+//   - The host resource becomes the host.name resource attribute
+//
+// Logs posted to /api/v2/logs are converted into OTLP log records:
+//   - The message becomes the body and the status the severity
+//   - The hostname and service become host.name and service.name resource attributes
+//   - The source, tags and any other attribute become log record attributes
+//
+// The tenant of a series or log is read from a configured tag and written to
+// the tenant resource attribute, so the converted resources are partitioned
+// like OTLP payloads.
+package datadog
@@ -0,0 +1,174 @@
+// Package datadog converts Datadog agent intake payloads into OTLP.
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// Reserved attributes of a Datadog log.
+const (
+	messageKey   = "message"
+	statusKey    = "status"
+	hostnameKey  = "hostname"
+	serviceKey   = "service"
+	sourceKey    = "ddsource"
+	tagsKey      = "ddtags"
+	timestampKey = "timestamp"
+)
+
+// severities maps Datadog log statuses to OTLP severity numbers.
+var severities = map[string]logpb.SeverityNumber{
+	"emergency": logpb.SeverityNumber_SEVERITY_NUMBER_FATAL4,
+	"emerg":     logpb.SeverityNumber_SEVERITY_NUMBER_FATAL4,
+	"alert":     logpb.SeverityNumber_SEVERITY_NUMBER_FATAL3,
+	"critical":  logpb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	"crit":      logpb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	"error":     logpb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"err":       logpb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"warning":   logpb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"warn":      logpb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"notice":    logpb.SeverityNumber_SEVERITY_NUMBER_INFO2,
+	"info":      logpb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"ok":        logpb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"debug":     logpb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	"trace":     logpb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+}
+
+// Log is a Datadog log, holding the reserved attributes and any other attribute sent by the agent.
+type Log map[string]any
+
+// ParseLogs parses the JSON body of a /api/v2/logs request, which is either an array of logs or a single log.
+func ParseLogs(b []byte) ([]Log, error) {
+	var logs []Log
+	if err := json.Unmarshal(b, &logs); err == nil {
+		return logs, nil
+	}
+
+	var log Log
+	if err := json.Unmarshal(b, &log); err != nil {
+		return nil, fmt.Errorf("invalid logs payload: %w", err)
+	}
+	return []Log{log}, nil
+}
+
+// ResourceLogs converts the logs into OTLP logs, grouping them into one resource per tenant, host and service.
+//
+// The message becomes the body and the status the severity. The hostname and service become the host.name and
+// service.name resource attributes, the source the datadog.source attribute, and the tags and any other attribute
+// become log record attributes. Logs without a timestamp are given the time now.
+func (c *Converter) ResourceLogs(logs []Log, now time.Time) []*logpb.ResourceLogs {
+	var resources []*logpb.ResourceLogs
+	scopes := make(map[[3]string]*logpb.ScopeLogs)
+
+	for _, log := range logs {
+		tags := parseTags(strings.Split(log.string(tagsKey), ","))
+		tenant := c.tenant(tags)
+		hostname, service := log.string(hostnameKey), log.string(serviceKey)
+
+		key := [3]string{tenant, hostname, service}
+		scope, ok := scopes[key]
+		if !ok {
+			var attributes []*commonpb.KeyValue
+			if hostname != "" {
+				attributes = append(attributes, stringKeyValue(hostNameAttrKey, hostname))
+			}
+			if service != "" {
+				attributes = append(attributes, stringKeyValue(serviceNameAttrKey, service))
+			}
+
+			scope = &logpb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &logpb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: c.resourceAttributes(tenant, attributes...)},
+				ScopeLogs: []*logpb.ScopeLogs{scope},
+			})
+			scopes[key] = scope
+		}
+
+		scope.LogRecords = append(scope.LogRecords, convertLog(log, tags, now))
+	}
+
+	return resources
+}
+
+// convertLog converts a log into an OTLP log record.
+func convertLog(log Log, tags []tag, now time.Time) *logpb.LogRecord {
+	timestamp := now
+	if ms, ok := log[timestampKey].(float64); ok {
+		timestamp = time.UnixMilli(int64(ms))
+	}
+
+	status := log.string(statusKey)
+	record := &logpb.LogRecord{
+		TimeUnixNano:         uint64(timestamp.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityText:         status,
+		SeverityNumber:       severities[strings.ToLower(status)],
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: log.string(messageKey)}},
+	}
+
+	if source := log.string(sourceKey); source != "" {
+		record.Attributes = append(record.Attributes, stringKeyValue(sourceAttrKey, source))
+	}
+	record.Attributes = append(record.Attributes, tagAttributes(tags)...)
+
+	keys := make([]string, 0, len(log))
+	for key := range log {
+		switch key {
+		case messageKey, statusKey, hostnameKey, serviceKey, sourceKey, tagsKey, timestampKey:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: key, Value: anyValue(log[key])})
+	}
+
+	return record
+}
+
+// string returns the string value of a key, or an empty string when it is missing or not a string.
+func (l Log) string(key string) string {
+	s, _ := l[key].(string)
+	return s
+}
+
+// anyValue converts a decoded JSON value into an AnyValue.
+func anyValue(v any) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []any:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, value := range v {
+			values = append(values, anyValue(value))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		values := make([]*commonpb.KeyValue, 0, len(v))
+		for _, key := range keys {
+			values = append(values, &commonpb.KeyValue{Key: key, Value: anyValue(v[key])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+	default:
+		return &commonpb.AnyValue{}
+	}
+}
@@ -0,0 +1,92 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestParseLogs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []Log
+		wantErr bool
+	}{
+		{
+			name: "array",
+			body: `[{"message":"a"},{"message":"b"}]`,
+			want: []Log{{"message": "a"}, {"message": "b"}},
+		},
+		{
+			name: "single log",
+			body: `{"message":"a"}`,
+			want: []Log{{"message": "a"}},
+		},
+		{
+			name:    "invalid",
+			body:    `"message"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLogs([]byte(tt.body))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConverter_ResourceLogs(t *testing.T) {
+	now := time.Unix(1700000100, 0)
+	logs, err := ParseLogs([]byte(`[
+		{"message":"first","status":"Error","hostname":"host-1","service":"checkout","ddsource":"nginx",
+		 "ddtags":"tenant:tenant-a,env:prod","timestamp":1700000000123,"http":{"status_code":500}},
+		{"message":"second","status":"info","hostname":"host-1","service":"checkout","ddtags":"tenant:tenant-a"},
+		{"message":"third","hostname":"host-2"}
+	]`))
+	require.NoError(t, err)
+
+	resources := New("tenant.id", "tenant").ResourceLogs(logs, now)
+
+	require.Len(t, resources, 2)
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue("tenant.id", "tenant-a"),
+		stringKeyValue(hostNameAttrKey, "host-1"),
+		stringKeyValue(serviceNameAttrKey, "checkout"),
+	}, resources[0].GetResource().GetAttributes())
+
+	records := resources[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, 2)
+	assert.Equal(t, &logpb.LogRecord{
+		TimeUnixNano:         uint64(time.UnixMilli(1700000000123).UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       logpb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+		SeverityText:         "Error",
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "first"}},
+		Attributes: []*commonpb.KeyValue{
+			stringKeyValue(sourceAttrKey, "nginx"),
+			stringKeyValue("tenant", "tenant-a"),
+			stringKeyValue("env", "prod"),
+			{Key: "http", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{{Key: "status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 500}}}},
+			}}}},
+		},
+	}, records[0])
+	assert.Equal(t, logpb.SeverityNumber_SEVERITY_NUMBER_INFO, records[1].GetSeverityNumber())
+	assert.Equal(t, uint64(now.UnixNano()), records[1].GetTimeUnixNano())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue(hostNameAttrKey, "host-2")}, resources[1].GetResource().GetAttributes())
+	assert.Equal(t, logpb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, resources[1].GetScopeLogs()[0].GetLogRecords()[0].GetSeverityNumber())
+}
@@ -0,0 +1,99 @@
+// Package datadog converts Datadog agent intake payloads into OTLP.
+package datadog
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the agent's MetricPayload protobuf messages.
+const (
+	payloadSeriesField = 1
+
+	seriesResourcesField = 1
+	seriesMetricField    = 2
+	seriesTagsField      = 3
+	seriesPointsField    = 4
+	seriesTypeField      = 5
+	seriesUnitField      = 6
+	seriesIntervalField  = 8
+
+	pointValueField     = 1
+	pointTimestampField = 2
+
+	resourceTypeField = 1
+	resourceNameField = 2
+)
+
+// unmarshalSeriesProto decodes the MetricPayload protobuf message sent by the agent, the fields the conversion does
+// not use are skipped.
+func unmarshalSeriesProto(b []byte) (*SeriesPayload, error) {
+	payload := &SeriesPayload{}
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != payloadSeriesField || typ != protowire.BytesType {
+			return nil
+		}
+		series, err := unmarshalSeries(v)
+		if err != nil {
+			return err
+		}
+		payload.Series = append(payload.Series, series)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+	return payload, nil
+}
+
+// unmarshalSeries decodes a MetricSeries message.
+func unmarshalSeries(b []byte) (Series, error) {
+	var series Series
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == seriesResourcesField && typ == protowire.BytesType:
+			var resource Resource
+			if err := proto.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				switch {
+				case num == resourceTypeField && typ == protowire.BytesType:
+					resource.Type = string(v)
+				case num == resourceNameField && typ == protowire.BytesType:
+					resource.Name = string(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			series.Resources = append(series.Resources, resource)
+		case num == seriesMetricField && typ == protowire.BytesType:
+			series.Metric = string(v)
+		case num == seriesTagsField && typ == protowire.BytesType:
+			series.Tags = append(series.Tags, string(v))
+		case num == seriesPointsField && typ == protowire.BytesType:
+			var point Point
+			if err := proto.Walk(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+				switch {
+				case num == pointValueField && typ == protowire.Fixed64Type:
+					point.Value = math.Float64frombits(n)
+				case num == pointTimestampField && typ == protowire.VarintType:
+					point.Timestamp = int64(n)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			series.Points = append(series.Points, point)
+		case num == seriesTypeField && typ == protowire.VarintType:
+			series.Type = MetricType(n)
+		case num == seriesUnitField && typ == protowire.BytesType:
+			series.Unit = string(v)
+		case num == seriesIntervalField && typ == protowire.VarintType:
+			series.Interval = int64(n)
+		}
+		return nil
+	})
+	return series, err
+}
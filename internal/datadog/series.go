@@ -0,0 +1,140 @@
+// Package datadog converts Datadog agent intake payloads into OTLP.
+package datadog
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// MetricType is the type of a Datadog series.
+type MetricType int
+
+// Datadog series types.
+const (
+	MetricTypeUnspecified MetricType = iota
+	MetricTypeCount
+	MetricTypeRate
+	MetricTypeGauge
+)
+
+// hostResourceType is the type of the series resource naming the host.
+const hostResourceType = "host"
+
+// SeriesPayload is the body of a /api/v2/series request.
+type SeriesPayload struct {
+	Series []Series `json:"series"`
+}
+
+// Series is a Datadog metric series.
+type Series struct {
+	Metric    string     `json:"metric"`
+	Type      MetricType `json:"type"`
+	Points    []Point    `json:"points"`
+	Tags      []string   `json:"tags"`
+	Resources []Resource `json:"resources"`
+	Unit      string     `json:"unit"`
+	Interval  int64      `json:"interval"`
+}
+
+// Point is a point of a series, the timestamp is in seconds.
+type Point struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+// Resource is a resource a series is associated with.
+type Resource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// contentTypeProtobuf is the content type of the protobuf series payloads sent by the agent.
+const contentTypeProtobuf = "application/x-protobuf"
+
+// ParseSeries parses the body of a /api/v2/series request, which is a protobuf MetricPayload when sent by the agent
+// and JSON otherwise.
+func ParseSeries(b []byte, contentType string) (*SeriesPayload, error) {
+	if contentType == contentTypeProtobuf {
+		return unmarshalSeriesProto(b)
+	}
+
+	var payload SeriesPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, fmt.Errorf("invalid series payload: %w", err)
+	}
+	return &payload, nil
+}
+
+// ResourceMetrics converts the series into OTLP metrics, grouping them into one resource per tenant and host.
+//
+// Counts become delta sums over the interval of the series, rates and gauges become gauges. The tags become data
+// point attributes, the host resource becomes the host.name resource attribute and the other resources become
+// datadog.resource.<type> resource attributes.
+func (c *Converter) ResourceMetrics(payload *SeriesPayload) []*metricpb.ResourceMetrics {
+	var resources []*metricpb.ResourceMetrics
+	scopes := make(map[string]*metricpb.ScopeMetrics)
+
+	for _, series := range payload.Series {
+		tags := parseTags(series.Tags)
+		tenant := c.tenant(tags)
+
+		var attributes []*commonpb.KeyValue
+		key := tenant
+		for _, resource := range series.Resources {
+			attrKey := resourceAttrPrefix + resource.Type
+			if resource.Type == hostResourceType {
+				attrKey = hostNameAttrKey
+			}
+			attributes = append(attributes, stringKeyValue(attrKey, resource.Name))
+			key += "\x00" + attrKey + "=" + resource.Name
+		}
+
+		scope, ok := scopes[key]
+		if !ok {
+			scope = &metricpb.ScopeMetrics{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &metricpb.ResourceMetrics{
+				Resource:     &resourcepb.Resource{Attributes: c.resourceAttributes(tenant, attributes...)},
+				ScopeMetrics: []*metricpb.ScopeMetrics{scope},
+			})
+			scopes[key] = scope
+		}
+
+		scope.Metrics = append(scope.Metrics, convertSeries(&series, tagAttributes(tags)))
+	}
+
+	return resources
+}
+
+// convertSeries converts a series into an OTLP metric.
+func convertSeries(series *Series, attributes []*commonpb.KeyValue) *metricpb.Metric {
+	points := make([]*metricpb.NumberDataPoint, 0, len(series.Points))
+	for _, point := range series.Points {
+		timestamp := time.Unix(point.Timestamp, 0)
+		dataPoint := &metricpb.NumberDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: uint64(timestamp.UnixNano()),
+			Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: point.Value},
+		}
+		if series.Type == MetricTypeCount && series.Interval > 0 {
+			dataPoint.StartTimeUnixNano = uint64(timestamp.Add(-time.Duration(series.Interval) * time.Second).UnixNano())
+		}
+		points = append(points, dataPoint)
+	}
+
+	metric := &metricpb.Metric{Name: series.Metric, Unit: series.Unit}
+	if series.Type == MetricTypeCount {
+		metric.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			DataPoints:             points,
+		}}
+		return metric
+	}
+
+	metric.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: points}}
+	return metric
+}
@@ -0,0 +1,152 @@
+package datadog
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// appendBytesField appends a length-delimited field.
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func TestParseSeries(t *testing.T) {
+	want := &SeriesPayload{Series: []Series{{
+		Metric:    "system.load.1",
+		Type:      MetricTypeCount,
+		Points:    []Point{{Timestamp: 1700000000, Value: 0.5}},
+		Tags:      []string{"env:prod", "tenant:tenant-a"},
+		Resources: []Resource{{Name: "host-1", Type: "host"}},
+		Unit:      "request",
+		Interval:  10,
+	}}}
+
+	var resource []byte
+	resource = appendBytesField(resource, resourceTypeField, []byte("host"))
+	resource = appendBytesField(resource, resourceNameField, []byte("host-1"))
+
+	var point []byte
+	point = protowire.AppendTag(point, pointValueField, protowire.Fixed64Type)
+	point = protowire.AppendFixed64(point, math.Float64bits(0.5))
+	point = protowire.AppendTag(point, pointTimestampField, protowire.VarintType)
+	point = protowire.AppendVarint(point, 1700000000)
+
+	var series []byte
+	series = appendBytesField(series, seriesResourcesField, resource)
+	series = appendBytesField(series, seriesMetricField, []byte("system.load.1"))
+	series = appendBytesField(series, seriesTagsField, []byte("env:prod"))
+	series = appendBytesField(series, seriesTagsField, []byte("tenant:tenant-a"))
+	series = appendBytesField(series, seriesPointsField, point)
+	series = protowire.AppendTag(series, seriesTypeField, protowire.VarintType)
+	series = protowire.AppendVarint(series, uint64(MetricTypeCount))
+	series = appendBytesField(series, seriesUnitField, []byte("request"))
+	series = appendBytesField(series, 7, []byte("source type is skipped"))
+	series = protowire.AppendTag(series, seriesIntervalField, protowire.VarintType)
+	series = protowire.AppendVarint(series, 10)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        *SeriesPayload
+		wantErr     bool
+	}{
+		{
+			name:        "protobuf",
+			body:        appendBytesField(nil, payloadSeriesField, series),
+			contentType: "application/x-protobuf",
+			want:        want,
+		},
+		{
+			name: "json",
+			body: []byte(`{"series":[{"metric":"system.load.1","type":1,"points":[{"timestamp":1700000000,"value":0.5}],` +
+				`"tags":["env:prod","tenant:tenant-a"],"resources":[{"name":"host-1","type":"host"}],"unit":"request","interval":10}]}`),
+			contentType: "application/json",
+			want:        want,
+		},
+		{
+			name:        "truncated protobuf",
+			body:        appendBytesField(nil, payloadSeriesField, series)[:10],
+			contentType: "application/x-protobuf",
+			wantErr:     true,
+		},
+		{
+			name:        "invalid json",
+			body:        []byte(`{"series":`),
+			contentType: "application/json",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeries(tt.body, tt.contentType)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConverter_ResourceMetrics(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	payload := &SeriesPayload{Series: []Series{
+		{
+			Metric:    "requests",
+			Type:      MetricTypeCount,
+			Points:    []Point{{Timestamp: ts.Unix(), Value: 3}},
+			Tags:      []string{"tenant:tenant-a", "env:prod"},
+			Resources: []Resource{{Name: "host-1", Type: "host"}},
+			Interval:  10,
+		},
+		{
+			Metric:    "load",
+			Type:      MetricTypeGauge,
+			Points:    []Point{{Timestamp: ts.Unix(), Value: 0.5}},
+			Tags:      []string{"tenant:tenant-a"},
+			Resources: []Resource{{Name: "host-1", Type: "host"}},
+		},
+		{
+			Metric:    "queue.rate",
+			Type:      MetricTypeRate,
+			Points:    []Point{{Timestamp: ts.Unix(), Value: 1.5}},
+			Resources: []Resource{{Name: "cluster-1", Type: "cluster"}},
+		},
+	}}
+
+	resources := New("tenant.id", "tenant").ResourceMetrics(payload)
+
+	require.Len(t, resources, 2)
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue("tenant.id", "tenant-a"),
+		stringKeyValue(hostNameAttrKey, "host-1"),
+	}, resources[0].GetResource().GetAttributes())
+
+	metrics := resources[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, &metricpb.Metric{Name: "requests", Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+		AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		DataPoints: []*metricpb.NumberDataPoint{{
+			Attributes:        []*commonpb.KeyValue{stringKeyValue("tenant", "tenant-a"), stringKeyValue("env", "prod")},
+			StartTimeUnixNano: uint64(ts.Add(-10 * time.Second).UnixNano()),
+			TimeUnixNano:      uint64(ts.UnixNano()),
+			Value:             &metricpb.NumberDataPoint_AsDouble{AsDouble: 3},
+		}},
+	}}}, metrics[0])
+	assert.Equal(t, 0.5, metrics[1].GetGauge().GetDataPoints()[0].GetAsDouble())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("datadog.resource.cluster", "cluster-1")}, resources[1].GetResource().GetAttributes())
+	assert.Equal(t, 1.5, resources[1].GetScopeMetrics()[0].GetMetrics()[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+}
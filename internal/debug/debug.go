@@ -0,0 +1,81 @@
+// Package debug collects how a request was processed, so it can be echoed to the client.
+package debug
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Outcomes of entries that did not receive a backend response.
+const (
+	OutcomeError       = "error"
+	OutcomeNoTenant    = "no-tenant"
+	OutcomeCircuitOpen = "circuit-open"
+	OutcomeResidency   = "residency"
+)
+
+// Entry describes the resources of a signal dispatched for a tenant.
+type Entry struct {
+	Signal    string
+	Tenant    string
+	Resources int
+	// Outcome is the backend response status code, OutcomeError when the backend request failed, OutcomeNoTenant
+	// when the resources were dropped because no tenant could be resolved, or OutcomeCircuitOpen when the circuit of
+	// the tenant is open, or OutcomeResidency when the backend is outside the data residency region of the tenant.
+	Outcome string
+}
+
+// String returns the entry in the format echoed to the client.
+func (e Entry) String() string {
+	return fmt.Sprintf("signal=%s; tenant=%s; resources=%d; outcome=%s", e.Signal, e.Tenant, e.Resources, e.Outcome)
+}
+
+// Report collects the entries of a request, it is safe for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// reportKey is the context key of the Report.
+type reportKey struct{}
+
+// NewContext returns a copy of the context carrying the report.
+func NewContext(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// FromContext returns the report carried by the context, or nil when there is none.
+func FromContext(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
+
+// Add adds an entry to the report, it does nothing on a nil report.
+func (r *Report) Add(entry Entry) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the entries of the report ordered by signal and tenant.
+func (r *Report) Entries() []Entry {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	entries := slices.Clone(r.entries)
+	r.mu.Unlock()
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(cmp.Compare(a.Signal, b.Signal), cmp.Compare(a.Tenant, b.Tenant))
+	})
+	return entries
+}
@@ -0,0 +1,63 @@
+package debug
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, *Report)
+		entries []Entry
+		want    []Entry
+	}{
+		{
+			name: "collects entries in order",
+			ctx: func() (context.Context, *Report) {
+				report := &Report{}
+				return NewContext(t.Context(), report), report
+			},
+			entries: []Entry{
+				{Signal: "traces", Tenant: "a", Resources: 1, Outcome: "202"},
+				{Signal: "logs", Tenant: "b", Resources: 2, Outcome: OutcomeError},
+				{Signal: "logs", Tenant: "a", Resources: 3, Outcome: "202"},
+			},
+			want: []Entry{
+				{Signal: "logs", Tenant: "a", Resources: 3, Outcome: "202"},
+				{Signal: "logs", Tenant: "b", Resources: 2, Outcome: OutcomeError},
+				{Signal: "traces", Tenant: "a", Resources: 1, Outcome: "202"},
+			},
+		},
+		{
+			name: "ignores entries without a report",
+			ctx: func() (context.Context, *Report) {
+				return t.Context(), nil
+			},
+			entries: []Entry{{Signal: "logs", Tenant: "a", Resources: 1, Outcome: "202"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, report := tt.ctx()
+
+			var wg sync.WaitGroup
+			for _, entry := range tt.entries {
+				wg.Go(func() { FromContext(ctx).Add(entry) })
+			}
+			wg.Wait()
+
+			assert.Equal(t, report, FromContext(ctx))
+			assert.Equal(t, tt.want, FromContext(ctx).Entries())
+		})
+	}
+}
+
+func TestEntry_String(t *testing.T) {
+	entry := Entry{Signal: "logs", Tenant: "tenant-a", Resources: 3, Outcome: "202"}
+	assert.Equal(t, "signal=logs; tenant=tenant-a; resources=3; outcome=202", entry.String())
+}
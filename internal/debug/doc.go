@@ -0,0 +1,8 @@
+// Package debug collects how a request was processed, so it can be echoed to the client.
+//
+// A Report is carried in the request context. The processors add an entry for every
+// tenant they resolve, with the number of resources dispatched and the outcome of the
+// backend request, and for the resources dropped because no tenant could be resolved.
+// The HTTP handlers only attach a Report when the client presents the configured debug
+// token, so processing is unaffected for every other request.
+package debug
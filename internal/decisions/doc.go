@@ -0,0 +1,15 @@
+// Package decisions broadcasts a sampled feed of the routing decisions of the processors.
+//
+// Every time a processor decides what to do with the resources of a tenant —
+// forward them, or hold them back because of an open circuit, a data residency
+// rule or a missing tenant — it publishes a Decision to the Feed. A fraction of
+// the decisions is sampled and fanned out to the subscribers, which the admin
+// API streams to operators as server-sent events, so the effect of a
+// configuration change can be watched live.
+//
+// Decisions never carry telemetry data, request headers or full backend
+// addresses: only the signal, the tenant, the resource and record counts, the
+// host of the backend and the outcome. Publishing never blocks the processors:
+// nothing is sampled while there are no subscribers, and decisions are dropped
+// for subscribers that do not keep up.
+package decisions
@@ -0,0 +1,112 @@
+// Package decisions broadcasts a sampled feed of the routing decisions of the processors.
+package decisions
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+)
+
+// Decision is what a processor decided to do with the resources of a tenant.
+type Decision struct {
+	Time        time.Time `json:"time"`
+	Signal      string    `json:"signal"`
+	Tenant      string    `json:"tenant"`
+	Resources   int       `json:"resources"`
+	Records     int       `json:"records"`
+	Destination string    `json:"destination"`
+	// Outcome is the backend response status code, or one of the debug outcomes when no backend response was received.
+	Outcome string `json:"outcome"`
+}
+
+// Subscription receives the decisions sampled after it was created.
+type Subscription struct {
+	// C delivers the sampled decisions, it is closed by Close.
+	C <-chan Decision
+
+	feed    *Feed
+	c       chan Decision
+	dropped uint64
+}
+
+// Feed fans the sampled decisions out to its subscribers. A nil Feed samples nothing.
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	sampleRate  float64
+	buffer      int
+	random      func() float64
+}
+
+// New creates a new Feed sampling the configured fraction of the decisions.
+func New(cfg *config.Decisions) *Feed {
+	return &Feed{
+		subscribers: make(map[*Subscription]struct{}),
+		sampleRate:  cfg.SampleRate,
+		buffer:      max(cfg.Buffer, 1),
+		random:      rand.Float64,
+	}
+}
+
+// Sample reports whether the next decision should be published, so callers only build the decisions sampled. It is
+// false while there are no subscribers.
+func (f *Feed) Sample() bool {
+	if f == nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.subscribers) == 0 || f.sampleRate <= 0 {
+		return false
+	}
+	return f.sampleRate >= 1 || f.random() < f.sampleRate
+}
+
+// Publish sends the decision to every subscriber, dropping it for the subscribers whose buffer is full.
+func (f *Feed) Publish(decision Decision) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for s := range f.subscribers {
+		select {
+		case s.c <- decision:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// Subscribe returns a new subscription, which must be closed once the caller stops receiving.
+func (f *Feed) Subscribe() *Subscription {
+	c := make(chan Decision, f.buffer)
+	s := &Subscription{C: c, feed: f, c: c}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers[s] = struct{}{}
+	return s
+}
+
+// Dropped returns the number of decisions dropped because the subscriber did not keep up.
+func (s *Subscription) Dropped() uint64 {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.dropped
+}
+
+// Close removes the subscription from the feed and closes its channel, it is safe to call more than once.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if _, ok := s.feed.subscribers[s]; !ok {
+		return
+	}
+	delete(s.feed.subscribers, s)
+	close(s.c)
+}
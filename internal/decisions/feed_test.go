@@ -0,0 +1,79 @@
+package decisions
+
+import (
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeed_Sample(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampleRate  float64
+		random      float64
+		subscribers int
+		want        bool
+	}{
+		{name: "no subscribers", sampleRate: 1, want: false},
+		{name: "all sampled", sampleRate: 1, random: 0.99, subscribers: 1, want: true},
+		{name: "disabled", sampleRate: 0, subscribers: 1, want: false},
+		{name: "sampled", sampleRate: 0.1, random: 0.05, subscribers: 1, want: true},
+		{name: "not sampled", sampleRate: 0.1, random: 0.5, subscribers: 2, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(&config.Decisions{SampleRate: tt.sampleRate, Buffer: 1})
+			f.random = func() float64 { return tt.random }
+			for range tt.subscribers {
+				defer f.Subscribe().Close()
+			}
+
+			assert.Equal(t, tt.want, f.Sample())
+		})
+	}
+
+	t.Run("nil feed", func(t *testing.T) {
+		var f *Feed
+		assert.False(t, f.Sample())
+		f.Publish(Decision{})
+	})
+}
+
+func TestFeed_Publish(t *testing.T) {
+	f := New(&config.Decisions{SampleRate: 1, Buffer: 2})
+	fast := f.Subscribe()
+	defer fast.Close()
+	slow := f.Subscribe()
+	defer slow.Close()
+
+	// The slow subscriber drops what does not fit in its buffer without holding back the others
+	for i := range 3 {
+		f.Publish(Decision{Tenant: "tenant-a", Resources: i})
+		if i < 2 {
+			assert.Equal(t, i, (<-fast.C).Resources)
+		}
+	}
+	assert.Equal(t, 2, (<-fast.C).Resources)
+	assert.Zero(t, fast.Dropped())
+
+	assert.Equal(t, 0, (<-slow.C).Resources)
+	assert.Equal(t, 1, (<-slow.C).Resources)
+	assert.Equal(t, uint64(1), slow.Dropped())
+}
+
+func TestSubscription_Close(t *testing.T) {
+	f := New(&config.Decisions{SampleRate: 1, Buffer: 1})
+	s := f.Subscribe()
+
+	s.Close()
+	s.Close()
+
+	_, ok := <-s.C
+	assert.False(t, ok)
+	assert.False(t, f.Sample())
+
+	// Decisions published after closing are not delivered
+	f.Publish(Decision{})
+}
@@ -0,0 +1,197 @@
+// Package discovery balances the requests of a backend across the ready pods behind a Kubernetes Service.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	serviceAttrKey   = "service"
+	endpointsAttrKey = "endpoints"
+	errAttrKey       = "error"
+)
+
+// Option configures optional dependencies of a Balancer.
+type Option func(*Balancer)
+
+// WithClock sets the clock driving the refresh interval.
+func WithClock(c clock.Clock) Option {
+	return func(b *Balancer) {
+		b.clock = c
+	}
+}
+
+// WithResolver replaces the resolver selected by the discovery mode.
+func WithResolver(r Resolver) Option {
+	return func(b *Balancer) {
+		b.resolver = r
+	}
+}
+
+// Balancer spreads requests round-robin across the resolved endpoints of a Service.
+type Balancer struct {
+	config   *config.Discovery
+	service  Service
+	clock    clock.Clock
+	resolver Resolver
+
+	mu        sync.RWMutex
+	endpoints []string
+	next      atomic.Uint64
+}
+
+// New creates a Balancer for the Service of the configuration, it returns nil when no Service is configured.
+func New(config *config.Discovery, opts ...Option) (*Balancer, error) {
+	if config.Service == "" {
+		return nil, nil
+	}
+
+	service, err := ParseService(config.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Balancer{
+		config:  config,
+		service: service,
+		clock:   clock.New(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.resolver != nil {
+		return b, nil
+	}
+
+	switch config.Mode {
+	case "", modeDNS():
+		b.resolver = newDNSResolver(service, config.ClusterDomain)
+	case modeAPI():
+		if b.resolver, err = newAPIResolver(service); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown discovery mode %q", config.Mode)
+	}
+	return b, nil
+}
+
+// modeDNS returns the DNS discovery mode, the config parameter of New shadows the config package.
+func modeDNS() string { return config.DiscoveryDNS }
+
+// modeAPI returns the Kubernetes API discovery mode.
+func modeAPI() string { return config.DiscoveryAPI }
+
+// Endpoints returns the endpoints of the last successful resolution.
+func (b *Balancer) Endpoints() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return slices.Clone(b.endpoints)
+}
+
+// Next returns the next endpoint in round-robin order, or false when none is known.
+func (b *Balancer) Next() (string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.endpoints) == 0 {
+		return "", false
+	}
+	return b.endpoints[(b.next.Add(1)-1)%uint64(len(b.endpoints))], true
+}
+
+// Run refreshes the endpoints immediately, then every interval until the context is cancelled. A non positive
+// interval resolves the endpoints only once.
+func (b *Balancer) Run(ctx context.Context) {
+	b.refresh(ctx)
+	if b.config.Interval <= 0 {
+		return
+	}
+
+	ticker := b.clock.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			b.refresh(ctx)
+		}
+	}
+}
+
+// refresh resolves the endpoints and logs the errors and changes.
+func (b *Balancer) refresh(ctx context.Context) {
+	changed, err := b.Refresh(ctx)
+	if err != nil {
+		logger.Warn(ctx, "failed to resolve the backend service, keeping the last endpoints",
+			attribute.String(serviceAttrKey, b.service.String()),
+			attribute.String(errAttrKey, err.Error()),
+		)
+		return
+	}
+	if changed {
+		logger.Info(ctx, "backend service endpoints changed",
+			attribute.String(serviceAttrKey, b.service.String()),
+			attribute.String(endpointsAttrKey, strings.Join(b.Endpoints(), ",")),
+		)
+	}
+}
+
+// Refresh resolves the endpoints of the Service and reports whether they changed. The last endpoints are kept when the
+// resolution fails, so a flaky DNS server or API server does not stop the traffic.
+func (b *Balancer) Refresh(ctx context.Context) (bool, error) {
+	endpoints, err := b.resolver.Resolve(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if slices.Equal(b.endpoints, endpoints) {
+		return false, nil
+	}
+	b.endpoints = endpoints
+	return true, nil
+}
+
+// Transport wraps the base transport to send each request to the next endpoint, keeping the original host in the
+// Host header. Requests go to their original address when no endpoint is known.
+func (b *Balancer) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{balancer: b, base: base}
+}
+
+// transport is the round tripper returned by Balancer.Transport.
+type transport struct {
+	balancer *Balancer
+	base     http.RoundTripper
+}
+
+// RoundTrip sends a copy of the request to the next endpoint, the request itself is not modified.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint, ok := t.balancer.Next()
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	out := req.Clone(req.Context())
+	if out.Host == "" {
+		out.Host = req.URL.Host
+	}
+	out.URL.Host = endpoint
+	return t.base.RoundTrip(out)
+}
@@ -0,0 +1,195 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResolver returns the configured endpoints and error, signalling each resolution.
+type fakeResolver struct {
+	mu        sync.Mutex
+	endpoints []string
+	err       error
+	resolved  chan struct{}
+}
+
+func (r *fakeResolver) set(endpoints []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.endpoints, r.err = endpoints, err
+}
+
+func (r *fakeResolver) Resolve(context.Context) ([]string, error) {
+	r.mu.Lock()
+	endpoints, err := r.endpoints, r.err
+	r.mu.Unlock()
+
+	if r.resolved != nil {
+		r.resolved <- struct{}{}
+	}
+	return endpoints, err
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Discovery
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "dns", cfg: config.Discovery{Service: "obs/loki/http", Mode: config.DiscoveryDNS}},
+		{name: "default mode", cfg: config.Discovery{Service: "obs/loki/http"}},
+		{name: "invalid service", cfg: config.Discovery{Service: "loki"}, wantErr: true},
+		{name: "unknown mode", cfg: config.Discovery{Service: "obs/loki/http", Mode: "consul"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := New(&tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, b)
+				return
+			}
+			assert.NotNil(t, b)
+		})
+	}
+}
+
+func TestBalancer_Refresh(t *testing.T) {
+	resolver := &fakeResolver{endpoints: []string{"10.0.0.1:3100", "10.0.0.2:3100"}}
+	b, err := New(&config.Discovery{Service: "obs/loki/http"}, WithResolver(resolver))
+	require.NoError(t, err)
+
+	_, ok := b.Next()
+	assert.False(t, ok)
+
+	changed, err := b.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = b.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// The last endpoints are kept when the resolution fails
+	resolver.set(nil, errors.New("dns timeout"))
+	_, err = b.Refresh(t.Context())
+	assert.Error(t, err)
+	assert.Equal(t, []string{"10.0.0.1:3100", "10.0.0.2:3100"}, b.Endpoints())
+
+	var got []string
+	for range 4 {
+		endpoint, ok := b.Next()
+		require.True(t, ok)
+		got = append(got, endpoint)
+	}
+	assert.Equal(t, []string{"10.0.0.1:3100", "10.0.0.2:3100", "10.0.0.1:3100", "10.0.0.2:3100"}, got)
+
+	// No ready pod
+	resolver.set([]string{}, nil)
+	changed, err = b.Refresh(t.Context())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, ok = b.Next()
+	assert.False(t, ok)
+}
+
+func TestBalancer_Run(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	resolver := &fakeResolver{endpoints: []string{"10.0.0.1:3100"}, resolved: make(chan struct{}, 1)}
+	b, err := New(&config.Discovery{Service: "obs/loki/http", Interval: 30 * time.Second},
+		WithResolver(resolver), WithClock(fake))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Run(ctx)
+	}()
+
+	// The endpoints are resolved immediately, then every interval
+	<-resolver.resolved
+	fake.BlockUntilTickers(1)
+	assert.Equal(t, []string{"10.0.0.1:3100"}, b.Endpoints())
+
+	resolver.set([]string{"10.0.0.2:3100"}, nil)
+	fake.Advance(30 * time.Second)
+	<-resolver.resolved
+
+	cancel()
+	<-done
+	assert.Equal(t, []string{"10.0.0.2:3100"}, b.Endpoints())
+}
+
+func TestBalancer_Transport(t *testing.T) {
+	var mu sync.Mutex
+	hosts := map[string]string{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			hosts[name] = r.Host
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	pod1 := httptest.NewServer(handler("pod1"))
+	defer pod1.Close()
+	pod2 := httptest.NewServer(handler("pod2"))
+	defer pod2.Close()
+	service := httptest.NewServer(handler("service"))
+	defer service.Close()
+
+	resolver := &fakeResolver{}
+	b, err := New(&config.Discovery{Service: "obs/loki/http"}, WithResolver(resolver))
+	require.NoError(t, err)
+	client := &http.Client{Transport: b.Transport(http.DefaultTransport)}
+
+	send := func() {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, service.URL+"/loki/api/v1/push", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, service.URL, req.URL.Scheme+"://"+req.URL.Host, "the request must not be modified")
+	}
+
+	// Without endpoints the request goes to the configured address
+	send()
+	assert.Contains(t, hosts, "service")
+
+	resolver.set([]string{hostOf(t, pod1.URL), hostOf(t, pod2.URL)}, nil)
+	_, err = b.Refresh(t.Context())
+	require.NoError(t, err)
+	send()
+	send()
+
+	assert.Equal(t, hostOf(t, service.URL), hosts["pod1"])
+	assert.Equal(t, hostOf(t, service.URL), hosts["pod2"])
+}
+
+func hostOf(t *testing.T, address string) string {
+	t.Helper()
+
+	u, err := url.Parse(address)
+	require.NoError(t, err)
+	return u.Host
+}
@@ -0,0 +1,22 @@
+// Package discovery balances the requests of a backend across the ready pods behind a Kubernetes Service.
+//
+// Sending to the virtual IP of a Service leaves the balancing to kube-proxy,
+// which picks a pod per connection: the long-lived keep-alive connections of
+// the proxy then stick to the pods that were ready when they were opened,
+// leaving new pods idle after a scale-up or a rollout. A Balancer resolves the
+// pods itself and spreads the requests across them round-robin, refreshing
+// them periodically.
+//
+// Services are referenced as namespace/name/port and resolved through either:
+//   - DNS, with the records of a headless Service: SRV records when the port is
+//     named, A/AAAA records with the given port number otherwise. Only ready
+//     pods are published, unless the Service publishes not-ready addresses.
+//   - The Kubernetes API, listing the EndpointSlices of the Service with the
+//     service account of the pod and keeping the ready endpoints. The port is
+//     looked up by name in the slices, a number is used as the pod port.
+//
+// Requests keep the original host in their Host header and TLS server name, so
+// virtual hosting and certificate verification are unaffected. Until the
+// first resolution succeeds, or when no pod is ready, requests are sent to the
+// configured address.
+package discovery
@@ -0,0 +1,224 @@
+// Package discovery balances the requests of a backend across the ready pods behind a Kubernetes Service.
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Paths of the credentials of the service account mounted in every pod.
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Resolver resolves the addresses of the ready pods of a Service as host:port pairs.
+type Resolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// Service references a Kubernetes Service port.
+type Service struct {
+	Namespace string
+	Name      string
+	// Port is the name of the Service port, or the number of the pod port.
+	Port string
+}
+
+// ParseService parses a namespace/name/port Service reference.
+func ParseService(ref string) (Service, error) {
+	parts := strings.Split(strings.TrimSpace(ref), "/")
+	if len(parts) != 3 || slices.Contains(parts, "") {
+		return Service{}, fmt.Errorf("invalid service reference %q, expected namespace/name/port", ref)
+	}
+	return Service{Namespace: parts[0], Name: parts[1], Port: parts[2]}, nil
+}
+
+// String returns the Service reference.
+func (s Service) String() string {
+	return s.Namespace + "/" + s.Name + "/" + s.Port
+}
+
+// portNumber returns the port number, or false when the port is named.
+func (s Service) portNumber() (int, bool) {
+	port, err := strconv.Atoi(s.Port)
+	return port, err == nil
+}
+
+// dnsResolver resolves the pods of a headless Service through the cluster DNS.
+type dnsResolver struct {
+	service    Service
+	domain     string
+	lookupSRV  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+}
+
+// newDNSResolver returns a resolver looking up the records of the Service in the cluster domain.
+func newDNSResolver(service Service, domain string) *dnsResolver {
+	return &dnsResolver{
+		service:    service,
+		domain:     domain,
+		lookupSRV:  net.DefaultResolver.LookupSRV,
+		lookupHost: net.DefaultResolver.LookupHost,
+	}
+}
+
+// Resolve looks up the SRV records of a named port, or the addresses of the Service for a numbered port.
+func (r *dnsResolver) Resolve(ctx context.Context) ([]string, error) {
+	host := fmt.Sprintf("%s.%s.svc.%s", r.service.Name, r.service.Namespace, r.domain)
+
+	if port, ok := r.service.portNumber(); ok {
+		addrs, err := r.lookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		endpoints := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			endpoints = append(endpoints, net.JoinHostPort(addr, strconv.Itoa(port)))
+		}
+		return sorted(endpoints), nil
+	}
+
+	_, records, err := r.lookupSRV(ctx, r.service.Port, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+	}
+	return sorted(endpoints), nil
+}
+
+// apiResolver resolves the ready pods of a Service from its EndpointSlices in the Kubernetes API.
+type apiResolver struct {
+	service   Service
+	baseURL   string
+	tokenFile string
+	client    *http.Client
+}
+
+// newAPIResolver returns a resolver authenticating to the API server of the cluster with the service account of the
+// pod.
+func newAPIResolver(service Service) (*apiResolver, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("the kubernetes API is only available in a pod: KUBERNETES_SERVICE_HOST is not set")
+	}
+
+	ca, err := os.ReadFile(serviceAccountCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("failed to parse the service account CA")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return &apiResolver{
+		service:   service,
+		baseURL:   "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountToken,
+		client:    &http.Client{Transport: transport},
+	}, nil
+}
+
+// endpointSliceList is the subset of an EndpointSlice list needed to resolve the ready endpoints.
+type endpointSliceList struct {
+	Items []struct {
+		Endpoints []struct {
+			Addresses  []string `json:"addresses"`
+			Conditions struct {
+				Ready *bool `json:"ready"`
+			} `json:"conditions"`
+		} `json:"endpoints"`
+		Ports []endpointPort `json:"ports"`
+	} `json:"items"`
+}
+
+// endpointPort is a port of an EndpointSlice.
+type endpointPort struct {
+	Name *string `json:"name"`
+	Port *int    `json:"port"`
+}
+
+// Resolve lists the EndpointSlices of the Service and returns the addresses of its ready endpoints. The token is read
+// on every call, as projected service account tokens are rotated.
+func (r *apiResolver) Resolve(ctx context.Context) ([]string, error) {
+	token, err := os.ReadFile(r.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account token: %w", err)
+	}
+
+	query := url.Values{"labelSelector": {"kubernetes.io/service-name=" + r.service.Name}}
+	address := fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s",
+		r.baseURL, url.PathEscape(r.service.Namespace), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to list the endpoint slices of %s: %s: %s", r.service, resp.Status,
+			strings.TrimSpace(string(body)))
+	}
+
+	var list endpointSliceList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode the endpoint slices of %s: %w", r.service, err)
+	}
+
+	var endpoints []string
+	for _, slice := range list.Items {
+		port, ok := r.service.portNumber()
+		if !ok {
+			i := slices.IndexFunc(slice.Ports, func(p endpointPort) bool {
+				return p.Name != nil && *p.Name == r.service.Port && p.Port != nil
+			})
+			if i < 0 {
+				continue
+			}
+			port = *slice.Ports[i].Port
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			// A missing condition means ready
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, addr := range endpoint.Addresses {
+				endpoints = append(endpoints, net.JoinHostPort(addr, strconv.Itoa(port)))
+			}
+		}
+	}
+	return sorted(endpoints), nil
+}
+
+// sorted returns the endpoints sorted and without duplicates, so unchanged results compare equal.
+func sorted(endpoints []string) []string {
+	slices.Sort(endpoints)
+	return slices.Compact(endpoints)
+}
@@ -0,0 +1,182 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseService(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		want    Service
+		wantErr bool
+	}{
+		{name: "named port", ref: "obs/loki/http", want: Service{Namespace: "obs", Name: "loki", Port: "http"}},
+		{name: "numbered port", ref: " obs/mimir/8080 ", want: Service{Namespace: "obs", Name: "mimir", Port: "8080"}},
+		{name: "missing port", ref: "observability/loki", wantErr: true},
+		{name: "empty name", ref: "observability//http", wantErr: true},
+		{name: "too many parts", ref: "a/b/c/d", wantErr: true},
+		{name: "empty", ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseService(tt.ref)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.Namespace+"/"+tt.want.Name+"/"+tt.want.Port, got.String())
+		})
+	}
+}
+
+func TestDNSResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		srv     []*net.SRV
+		hosts   []string
+		err     error
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "named port",
+			service: Service{Namespace: "obs", Name: "loki", Port: "http"},
+			srv: []*net.SRV{
+				{Target: "10-0-0-2.loki.obs.svc.cluster.local.", Port: 3100},
+				{Target: "10-0-0-1.loki.obs.svc.cluster.local.", Port: 3100},
+			},
+			want: []string{"10-0-0-1.loki.obs.svc.cluster.local:3100", "10-0-0-2.loki.obs.svc.cluster.local:3100"},
+		},
+		{
+			name:    "numbered port",
+			service: Service{Namespace: "obs", Name: "loki", Port: "3100"},
+			hosts:   []string{"10.0.0.2", "10.0.0.1", "fd00::1", "10.0.0.1"},
+			want:    []string{"10.0.0.1:3100", "10.0.0.2:3100", "[fd00::1]:3100"},
+		},
+		{
+			name:    "lookup error",
+			service: Service{Namespace: "obs", Name: "loki", Port: "http"},
+			err:     errors.New("no such host"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newDNSResolver(tt.service, "cluster.local")
+			r.lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				assert.Equal(t, tt.service.Port, service)
+				assert.Equal(t, "tcp", proto)
+				assert.Equal(t, "loki.obs.svc.cluster.local", name)
+				return "", tt.srv, tt.err
+			}
+			r.lookupHost = func(_ context.Context, host string) ([]string, error) {
+				assert.Equal(t, "loki.obs.svc.cluster.local", host)
+				return tt.hosts, tt.err
+			}
+
+			got, err := r.Resolve(t.Context())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+const endpointSlices = `{"items":[
+	{
+		"endpoints":[
+			{"addresses":["10.0.0.1"],"conditions":{"ready":true}},
+			{"addresses":["10.0.0.2"],"conditions":{"ready":false}},
+			{"addresses":["10.0.0.3"],"conditions":{}}
+		],
+		"ports":[{"name":"grpc","port":9095},{"name":"http","port":3100}]
+	},
+	{
+		"endpoints":[{"addresses":["10.0.1.1"],"conditions":{"ready":true}}],
+		"ports":[{"name":"http","port":3100}]
+	},
+	{
+		"endpoints":[{"addresses":["10.0.2.1"],"conditions":{"ready":true}}],
+		"ports":[{"name":"metrics","port":9090}]
+	}
+]}`
+
+func TestAPIResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		service Service
+		status  int
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "named port",
+			service: Service{Namespace: "obs", Name: "loki", Port: "http"},
+			status:  http.StatusOK,
+			want:    []string{"10.0.0.1:3100", "10.0.0.3:3100", "10.0.1.1:3100"},
+		},
+		{
+			name:    "numbered port",
+			service: Service{Namespace: "obs", Name: "loki", Port: "8080"},
+			status:  http.StatusOK,
+			want:    []string{"10.0.0.1:8080", "10.0.0.3:8080", "10.0.1.1:8080", "10.0.2.1:8080"},
+		},
+		{
+			name:    "forbidden",
+			service: Service{Namespace: "obs", Name: "loki", Port: "http"},
+			status:  http.StatusForbidden,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/apis/discovery.k8s.io/v1/namespaces/obs/endpointslices", r.URL.Path)
+				assert.Equal(t, "kubernetes.io/service-name=loki", r.URL.Query().Get("labelSelector"))
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(endpointSlices))
+			}))
+			defer server.Close()
+
+			tokenFile := filepath.Join(t.TempDir(), "token")
+			require.NoError(t, os.WriteFile(tokenFile, []byte("token\n"), 0o600))
+
+			r := &apiResolver{service: tt.service, baseURL: server.URL, tokenFile: tokenFile, client: server.Client()}
+			got, err := r.Resolve(t.Context())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewAPIResolver_OutsideCluster(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	_, err := newAPIResolver(Service{Namespace: "obs", Name: "loki", Port: "http"})
+	assert.Error(t, err)
+}
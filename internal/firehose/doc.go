@@ -0,0 +1,17 @@
+// Package firehose converts Amazon Data Firehose HTTP endpoint delivery requests into OTLP logs.
+//
+// Firehose posts batches of base64 encoded records as JSON. Each record is
+// either a CloudWatch Logs subscription payload, gzip compressed JSON holding
+// the events of a log stream, or any other data delivered as is:
+//   - The events of a subscription payload become log records of a resource
+//     per log group and stream, with the cloud.account.id,
+//     aws.log.group.names and aws.log.stream.names resource attributes
+//   - Control messages, sent by CloudWatch Logs to check the destination,
+//     are dropped
+//   - Other records become log records whose body is the record data
+//
+// The common attributes of the delivery stream, sent in the
+// X-Amz-Firehose-Common-Attributes header, become resource attributes, and
+// the tenant is read from a configured common attribute and written to the
+// tenant resource attribute.
+package firehose
@@ -0,0 +1,128 @@
+// Package firehose converts Amazon Data Firehose HTTP endpoint delivery requests into OTLP logs.
+package firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Headers of the delivery requests.
+const (
+	RequestIDHeader        = "X-Amz-Firehose-Request-Id"
+	AccessKeyHeader        = "X-Amz-Firehose-Access-Key"
+	CommonAttributesHeader = "X-Amz-Firehose-Common-Attributes"
+)
+
+// Message types of CloudWatch Logs subscription payloads.
+const (
+	dataMessage    = "DATA_MESSAGE"
+	controlMessage = "CONTROL_MESSAGE"
+)
+
+// Request is a delivery request, holding the records of a batch.
+type Request struct {
+	RequestID string   `json:"requestId"`
+	Timestamp int64    `json:"timestamp"`
+	Records   []Record `json:"records"`
+}
+
+// Record is a record of a delivery request. Logs is set when the data is a CloudWatch Logs subscription payload.
+type Record struct {
+	Data []byte    `json:"data"`
+	Logs *LogsData `json:"-"`
+}
+
+// LogsData is a CloudWatch Logs subscription payload, holding the events of a log stream.
+type LogsData struct {
+	MessageType         string     `json:"messageType"`
+	Owner               string     `json:"owner"`
+	LogGroup            string     `json:"logGroup"`
+	LogStream           string     `json:"logStream"`
+	SubscriptionFilters []string   `json:"subscriptionFilters"`
+	LogEvents           []LogEvent `json:"logEvents"`
+}
+
+// LogEvent is an event of a CloudWatch Logs subscription payload, its timestamp in Unix milliseconds.
+type LogEvent struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// Response is the body answering a delivery request, Firehose retries the requests answered with an error message.
+type Response struct {
+	RequestID    string `json:"requestId"`
+	Timestamp    int64  `json:"timestamp"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// commonAttributes is the content of the common attributes header.
+type commonAttributes struct {
+	CommonAttributes map[string]string `json:"commonAttributes"`
+}
+
+// Parse parses the JSON body of a delivery request, decoding the CloudWatch Logs subscription payloads of its records.
+func Parse(b []byte, limit int64) (*Request, error) {
+	var request Request
+	if err := json.Unmarshal(b, &request); err != nil {
+		return nil, fmt.Errorf("invalid delivery request: %w", err)
+	}
+
+	for i := range request.Records {
+		logs, err := parseLogs(request.Records[i].Data, limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid record %d: %w", i, err)
+		}
+		request.Records[i].Logs = logs
+	}
+	return &request, nil
+}
+
+// parseLogs decodes the record data when it is gzip compressed, as CloudWatch Logs subscription payloads are, up to
+// limit decompressed bytes. It returns nil for other data.
+func parseLogs(data []byte, limit int64) (*LogsData, error) {
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return nil, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	b, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data: %w", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, fmt.Errorf("decompressed data larger than %d bytes", limit)
+	}
+
+	var logs LogsData
+	if err := json.Unmarshal(b, &logs); err != nil {
+		return nil, fmt.Errorf("invalid CloudWatch Logs payload: %w", err)
+	}
+	switch logs.MessageType {
+	case dataMessage, controlMessage:
+	default:
+		return nil, fmt.Errorf("unknown CloudWatch Logs message type %q", logs.MessageType)
+	}
+	return &logs, nil
+}
+
+// ParseCommonAttributes parses the value of the common attributes header, an empty value having no attributes.
+func ParseCommonAttributes(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var attributes commonAttributes
+	if err := json.Unmarshal([]byte(value), &attributes); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", CommonAttributesHeader, err)
+	}
+	return attributes.CommonAttributes, nil
+}
@@ -0,0 +1,141 @@
+package firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gzipJSON returns the value encoded as gzip compressed JSON, like a CloudWatch Logs subscription payload.
+func gzipJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, err = writer.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+// deliveryRequest returns the JSON body of a delivery request of the records.
+func deliveryRequest(records ...[]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"requestId":"ed4acda5-034f-9f42-bba1-f29aea6d7d8f","timestamp":1700000000000,"records":[`)
+	for i, record := range records {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(`{"data":"` + base64.StdEncoding.EncodeToString(record) + `"}`)
+	}
+	b.WriteString("]}")
+	return b.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	logs := LogsData{
+		MessageType: dataMessage,
+		Owner:       "123456789012",
+		LogGroup:    "/aws/lambda/checkout",
+		LogStream:   "2024/01/01/[$LATEST]abc",
+		LogEvents:   []LogEvent{{ID: "1", Timestamp: 1700000000001, Message: "hello"}},
+	}
+
+	tests := []struct {
+		name     string
+		body     []byte
+		wantLogs []*LogsData
+		wantErr  string
+	}{
+		{
+			name:     "subscription payload and raw record",
+			body:     deliveryRequest(gzipJSON(t, logs), []byte("raw line")),
+			wantLogs: []*LogsData{&logs, nil},
+		},
+		{
+			name:     "control message",
+			body:     deliveryRequest(gzipJSON(t, LogsData{MessageType: controlMessage})),
+			wantLogs: []*LogsData{{MessageType: controlMessage}},
+		},
+		{
+			name:    "invalid JSON",
+			body:    []byte(`{"records":`),
+			wantErr: "invalid delivery request",
+		},
+		{
+			name:    "invalid base64",
+			body:    []byte(`{"records":[{"data":"!"}]}`),
+			wantErr: "invalid delivery request",
+		},
+		{
+			name:    "invalid gzip",
+			body:    deliveryRequest([]byte{0x1f, 0x8b, 0x00}),
+			wantErr: "invalid record 0: invalid gzip data",
+		},
+		{
+			name:    "unknown message type",
+			body:    deliveryRequest(gzipJSON(t, LogsData{MessageType: "OTHER"})),
+			wantErr: `invalid record 0: unknown CloudWatch Logs message type "OTHER"`,
+		},
+		{
+			name:    "too large",
+			body:    deliveryRequest(gzipJSON(t, LogsData{MessageType: dataMessage, LogGroup: strings.Repeat("a", 1024)})),
+			wantErr: "invalid record 0: decompressed data larger than 1024 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.body, 1024)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "ed4acda5-034f-9f42-bba1-f29aea6d7d8f", got.RequestID)
+			assert.Equal(t, int64(1700000000000), got.Timestamp)
+			require.Len(t, got.Records, len(tt.wantLogs))
+			for i, want := range tt.wantLogs {
+				assert.Equal(t, want, got.Records[i].Logs)
+			}
+		})
+	}
+}
+
+func TestParseCommonAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{
+			name:  "attributes",
+			value: `{"commonAttributes":{"tenant":"tenant-a","env":"prod"}}`,
+			want:  map[string]string{"tenant": "tenant-a", "env": "prod"},
+		},
+		{name: "invalid", value: `tenant=tenant-a`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommonAttributes(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package firehose converts Amazon Data Firehose HTTP endpoint delivery requests into OTLP logs.
+package firehose
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+var (
+	cloudProviderAttrKey  = "cloud.provider"
+	cloudAccountAttrKey   = "cloud.account.id"
+	logGroupNamesAttrKey  = "aws.log.group.names"
+	logStreamNamesAttrKey = "aws.log.stream.names"
+)
+
+// scopeName is the instrumentation scope of the logs converted from delivery requests.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/firehose"
+
+// Converter converts delivery requests into OTLP resources grouped by tenant.
+type Converter struct {
+	tenantLabel     string
+	tenantAttribute string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a request is
+// read from its tenantAttribute common attribute when set.
+func New(tenantLabel, tenantAttribute string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantAttribute: tenantAttribute}
+}
+
+// ResourceLogs converts the records of the delivery request into OTLP logs.
+//
+// The events of the CloudWatch Logs subscription payloads become log records of a resource per log group and stream,
+// and the other records log records of a single resource, with the record data as the body and the time of the
+// request. The common attributes become resource attributes. The tenant is the value of the tenant common attribute,
+// falling back to the tenant given, such as the one of the tenant header of the request; requests without either are
+// left without a tenant.
+func (c *Converter) ResourceLogs(
+	request *Request, common map[string]string, tenant string, now time.Time,
+) []*logpb.ResourceLogs {
+	if value := common[c.tenantAttribute]; c.tenantAttribute != "" && value != "" {
+		tenant = value
+	}
+
+	var attributes []*commonpb.KeyValue
+	if tenant != "" {
+		attributes = append(attributes, stringKeyValue(c.tenantLabel, tenant))
+	}
+	attributes = append(attributes, stringKeyValue(cloudProviderAttrKey, "aws"))
+	for _, name := range sortedKeys(common) {
+		if tenant != "" && name == c.tenantLabel {
+			continue
+		}
+		attributes = append(attributes, stringKeyValue(name, common[name]))
+	}
+
+	var resources []*logpb.ResourceLogs
+	var raw *logpb.ScopeLogs
+	for _, record := range request.Records {
+		if record.Logs == nil {
+			if raw == nil {
+				raw = &logpb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+				resources = append(resources, &logpb.ResourceLogs{
+					Resource:  &resourcepb.Resource{Attributes: attributes},
+					ScopeLogs: []*logpb.ScopeLogs{raw},
+				})
+			}
+			raw.LogRecords = append(raw.LogRecords, logRecord(request.Timestamp, string(record.Data), now))
+			continue
+		}
+
+		logs := record.Logs
+		if logs.MessageType != dataMessage || len(logs.LogEvents) == 0 {
+			continue
+		}
+
+		scope := &logpb.ScopeLogs{
+			Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+			LogRecords: make([]*logpb.LogRecord, 0, len(logs.LogEvents)),
+		}
+		for _, event := range logs.LogEvents {
+			scope.LogRecords = append(scope.LogRecords, logRecord(event.Timestamp, event.Message, now))
+		}
+
+		resources = append(resources, &logpb.ResourceLogs{
+			Resource: &resourcepb.Resource{Attributes: append(slices.Clip(attributes),
+				stringKeyValue(cloudAccountAttrKey, logs.Owner),
+				stringsKeyValue(logGroupNamesAttrKey, logs.LogGroup),
+				stringsKeyValue(logStreamNamesAttrKey, logs.LogStream),
+			)},
+			ScopeLogs: []*logpb.ScopeLogs{scope},
+		})
+	}
+	return resources
+}
+
+// logRecord returns a log record of the line at the given time in Unix milliseconds.
+func logRecord(timestamp int64, line string, now time.Time) *logpb.LogRecord {
+	return &logpb.LogRecord{
+		TimeUnixNano:         uint64(time.UnixMilli(timestamp).UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{
+			StringValue: strings.TrimSuffix(line, "\n"),
+		}},
+	}
+}
+
+// sortedKeys returns the keys of the attributes in order.
+func sortedKeys(attributes map[string]string) []string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+// stringsKeyValue returns a string array attribute.
+func stringsKeyValue(key string, values ...string) *commonpb.KeyValue {
+	array := &commonpb.ArrayValue{Values: make([]*commonpb.AnyValue, 0, len(values))}
+	for _, v := range values {
+		array.Values = append(array.Values, &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}})
+	}
+	value := &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	return &commonpb.KeyValue{Key: key, Value: value}
+}
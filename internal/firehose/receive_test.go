@@ -0,0 +1,88 @@
+package firehose
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+func TestConverter_ResourceLogs(t *testing.T) {
+	now := time.Unix(1700000100, 0)
+	request := &Request{Timestamp: 1700000000000, Records: []Record{
+		{Logs: &LogsData{
+			MessageType: dataMessage,
+			Owner:       "123456789012",
+			LogGroup:    "/aws/lambda/checkout",
+			LogStream:   "stream-1",
+			LogEvents: []LogEvent{
+				{ID: "1", Timestamp: 1700000000001, Message: "hello\n"},
+				{ID: "2", Timestamp: 1700000000002, Message: "world"},
+			},
+		}},
+		{Logs: &LogsData{MessageType: controlMessage, LogEvents: []LogEvent{{Message: "CWL CONTROL MESSAGE"}}}},
+		{Data: []byte("raw line\n")},
+		{Data: []byte("another line")},
+	}}
+	cloudWatch := []*commonpb.KeyValue{
+		stringKeyValue(cloudAccountAttrKey, "123456789012"),
+		stringsKeyValue(logGroupNamesAttrKey, "/aws/lambda/checkout"),
+		stringsKeyValue(logStreamNamesAttrKey, "stream-1"),
+	}
+
+	tests := []struct {
+		name            string
+		tenantAttribute string
+		common          map[string]string
+		tenant          string
+		want            []*commonpb.KeyValue
+	}{
+		{
+			name:            "tenant attribute",
+			tenantAttribute: "tenant",
+			common:          map[string]string{"tenant": "tenant-b", "env": "prod"},
+			tenant:          "tenant-a",
+			want: []*commonpb.KeyValue{
+				stringKeyValue("tenant.id", "tenant-b"), stringKeyValue("cloud.provider", "aws"),
+				stringKeyValue("env", "prod"), stringKeyValue("tenant", "tenant-b"),
+			},
+		},
+		{
+			name:            "tenant header",
+			tenantAttribute: "tenant",
+			common:          map[string]string{"tenant.id": "spoofed"},
+			tenant:          "tenant-a",
+			want:            []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-a"), stringKeyValue("cloud.provider", "aws")},
+		},
+		{
+			name:   "no tenant",
+			common: map[string]string{"tenant.id": "spoofed"},
+			want:   []*commonpb.KeyValue{stringKeyValue("cloud.provider", "aws"), stringKeyValue("tenant.id", "spoofed")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New("tenant.id", tt.tenantAttribute).ResourceLogs(request, tt.common, tt.tenant, now)
+
+			require.Len(t, got, 2)
+			assert.Equal(t, append(tt.want, cloudWatch...), got[0].GetResource().GetAttributes())
+			assert.Equal(t, tt.want, got[1].GetResource().GetAttributes())
+
+			records := got[0].GetScopeLogs()[0].GetLogRecords()
+			require.Len(t, records, 2)
+			assert.Equal(t, scopeName, got[0].GetScopeLogs()[0].GetScope().GetName())
+			assert.Equal(t, uint64(1700000000001000000), records[0].GetTimeUnixNano())
+			assert.Equal(t, uint64(now.UnixNano()), records[0].GetObservedTimeUnixNano())
+			assert.Equal(t, "hello", records[0].GetBody().GetStringValue())
+
+			records = got[1].GetScopeLogs()[0].GetLogRecords()
+			require.Len(t, records, 2)
+			assert.Equal(t, uint64(1700000000000000000), records[0].GetTimeUnixNano())
+			assert.Equal(t, "raw line", records[0].GetBody().GetStringValue())
+			assert.Equal(t, "another line", records[1].GetBody().GetStringValue())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package fluentforward provides a Fluent Forward protocol receiver for logs.
+//
+// The receiver accepts the forward protocol used by fluentd and fluent-bit on a
+// TCP listener, supporting the Message, Forward, PackedForward and gzip
+// CompressedPackedForward modes. Each event is converted into an OTLP LogRecord:
+//   - The log, message or msg key of the record becomes the body
+//   - The remaining keys of the record become log record attributes
+//   - The tag of the event is recorded as the fluent.tag attribute
+//   - Record keys matching the tenant labels are promoted to resource attributes
+//
+// The converted resources are handed to a Sink, which feeds them through the
+// tenant partitioning pipeline. Chunk acknowledgements are only sent once the
+// Sink accepted the events, so clients retry events that were not forwarded.
+package fluentforward
@@ -0,0 +1,468 @@
+// Package fluentforward provides a Fluent Forward protocol receiver for logs.
+package fluentforward
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"go.opentelemetry.io/otel/attribute"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+var (
+	addressAttrKey = "fluentforward.address"
+	remoteAttrKey  = "fluentforward.remote"
+	errAttrKey     = "error"
+	tagAttrKey     = "fluent.tag"
+)
+
+// scopeName is the instrumentation scope of the converted log records.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/fluentforward"
+
+// eventTimeExtID is the msgpack extension type of the Fluent Forward EventTime.
+const eventTimeExtID = 0
+
+// maxDecompressedSize limits the size of the entries of a CompressedPackedForward mode message.
+const maxDecompressedSize = 64 << 20
+
+// bodyKeys are the record keys used as the log record body, in order of preference.
+var bodyKeys = []string{"log", "message", "msg"}
+
+// Sink receives the log resources converted from Fluent Forward events.
+type Sink func(ctx context.Context, resources []*logpb.ResourceLogs) error
+
+// Server receives Fluent Forward events and hands them to a Sink.
+type Server struct {
+	config *config.FluentForward
+	tenant *config.Tenant
+	sink   Sink
+}
+
+// event is a single Fluent Forward event.
+type event struct {
+	time   time.Time
+	record map[string]any
+}
+
+// message is a decoded Fluent Forward message.
+type message struct {
+	tag    string
+	events []event
+	chunk  string
+}
+
+// New creates a new Server handing the converted events to the sink.
+func New(config *config.FluentForward, tenant *config.Tenant, sink Sink) *Server {
+	return &Server{
+		config: config,
+		tenant: tenant,
+		sink:   sink,
+	}
+}
+
+// Run listens on the configured address and serves connections until the context is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(ctx, "starting fluent forward receiver", attribute.String(addressAttrKey, s.config.Address))
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves connections accepted by the listener until the context is cancelled.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Go(func() { s.serveConn(ctx, conn) })
+	}
+}
+
+// serveConn decodes the messages of a connection, acknowledging the chunks forwarded to the sink.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer func() { _ = conn.Close() }()
+
+	remote := attribute.String(remoteAttrKey, conn.RemoteAddr().String())
+	decoder := newDecoder(bufio.NewReader(conn))
+	encoder := msgpack.NewEncoder(conn)
+
+	for {
+		if s.config.Timeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.config.Timeout))
+		}
+
+		msg, err := decodeMessage(decoder)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				logger.Warn(ctx, "failed to decode fluent forward message", remote, attribute.String(errAttrKey, err.Error()))
+			}
+			return
+		}
+
+		// Close the connection without acknowledging the chunk so the client retries it.
+		if err := s.sink(ctx, s.resources(msg)); err != nil {
+			logger.Error(ctx, "failed to forward fluent forward events", remote, attribute.String(errAttrKey, err.Error()))
+			return
+		}
+
+		if msg.chunk != "" {
+			if err := encoder.Encode(map[string]string{"ack": msg.chunk}); err != nil {
+				logger.Warn(ctx, "failed to acknowledge fluent forward chunk", remote, attribute.String(errAttrKey, err.Error()))
+				return
+			}
+		}
+	}
+}
+
+// resources converts the events of a message into log resources, grouping events by their tenant attributes.
+func (s *Server) resources(msg *message) []*logpb.ResourceLogs {
+	labels := append([]string{s.tenant.Label}, s.tenant.Labels...)
+	observed := uint64(time.Now().UnixNano())
+
+	var resources []*logpb.ResourceLogs
+	index := make(map[string]*logpb.ScopeLogs)
+	for _, e := range msg.events {
+		var (
+			resourceAttrs []*commonpb.KeyValue
+			key           strings.Builder
+		)
+		for _, label := range labels {
+			value, ok := e.record[label].(string)
+			if label == "" || !ok {
+				continue
+			}
+			delete(e.record, label)
+			resourceAttrs = append(resourceAttrs, stringKeyValue(label, value))
+			key.WriteString(label + "=" + value + "\x00")
+		}
+
+		scope, ok := index[key.String()]
+		if !ok {
+			scope = &logpb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &logpb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: resourceAttrs},
+				ScopeLogs: []*logpb.ScopeLogs{scope},
+			})
+			index[key.String()] = scope
+		}
+
+		scope.LogRecords = append(scope.LogRecords, logRecord(msg.tag, e, observed))
+	}
+
+	return resources
+}
+
+// logRecord converts an event into a log record.
+func logRecord(tag string, e event, observed uint64) *logpb.LogRecord {
+	record := &logpb.LogRecord{
+		TimeUnixNano:         uint64(e.time.UnixNano()),
+		ObservedTimeUnixNano: observed,
+		Attributes:           []*commonpb.KeyValue{stringKeyValue(tagAttrKey, tag)},
+	}
+
+	for _, key := range bodyKeys {
+		if value, ok := e.record[key]; ok {
+			record.Body = anyValue(value)
+			delete(e.record, key)
+			break
+		}
+	}
+
+	keys := make([]string, 0, len(e.record))
+	for key := range e.record {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: key, Value: anyValue(e.record[key])})
+	}
+
+	return record
+}
+
+// newDecoder returns a decoder converting integers, floats and binary values to int64, uint64, float64 and string.
+func newDecoder(r io.Reader) *msgpack.Decoder {
+	decoder := msgpack.NewDecoder(r)
+	decoder.UseLooseInterfaceDecoding(true)
+	return decoder
+}
+
+// decodeMessage decodes a Message, Forward, PackedForward or CompressedPackedForward mode message.
+func decodeMessage(decoder *msgpack.Decoder) (*message, error) {
+	n, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || n > 4 {
+		return nil, fmt.Errorf("invalid fluent forward message with %d elements", n)
+	}
+
+	msg := &message{}
+	if msg.tag, err = decoder.DecodeString(); err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		packed   []byte
+		consumed int
+	)
+	switch {
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		// Forward mode: [tag, [[time, record], ...], option]
+		if msg.events, err = decodeEntries(decoder); err != nil {
+			return nil, err
+		}
+		consumed = 2
+	case msgpcode.IsString(code) || msgpcode.IsBin(code):
+		// PackedForward mode: [tag, entries stream, option]
+		if packed, err = decoder.DecodeBytes(); err != nil {
+			return nil, err
+		}
+		consumed = 2
+	default:
+		// Message mode: [tag, time, record, option]
+		e, err := decodeEvent(decoder)
+		if err != nil {
+			return nil, err
+		}
+		msg.events = []event{e}
+		consumed = 3
+	}
+
+	var option map[string]any
+	switch n - consumed {
+	case 0:
+	case 1:
+		if err := decoder.Decode(&option); err != nil {
+			return nil, fmt.Errorf("invalid option: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid fluent forward message with %d elements", n)
+	}
+	msg.chunk, _ = option["chunk"].(string)
+
+	if packed != nil {
+		if compressed, _ := option["compressed"].(string); compressed != "" {
+			if packed, err = decompress(compressed, packed); err != nil {
+				return nil, err
+			}
+		}
+		if msg.events, err = decodeStream(packed); err != nil {
+			return nil, err
+		}
+	}
+
+	return msg, nil
+}
+
+// decodeEntries decodes the array of [time, record] entries of a Forward mode message.
+func decodeEntries(decoder *msgpack.Decoder) ([]event, error) {
+	n, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]event, 0, min(n, 1024))
+	for range n {
+		e, err := decodeEntry(decoder)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// decodeStream decodes the concatenated [time, record] entries of a PackedForward mode message.
+func decodeStream(b []byte) ([]event, error) {
+	decoder := newDecoder(bytes.NewReader(b))
+
+	var events []event
+	for {
+		e, err := decodeEntry(decoder)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+}
+
+// decodeEntry decodes a [time, record] entry.
+func decodeEntry(decoder *msgpack.Decoder) (event, error) {
+	n, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return event{}, err
+	}
+	if n != 2 {
+		return event{}, fmt.Errorf("invalid fluent forward entry with %d elements", n)
+	}
+	return decodeEvent(decoder)
+}
+
+// decodeEvent decodes the time and record of an event.
+func decodeEvent(decoder *msgpack.Decoder) (event, error) {
+	t, err := decodeTime(decoder)
+	if err != nil {
+		return event{}, err
+	}
+
+	value, err := decoder.DecodeInterface()
+	if err != nil {
+		return event{}, fmt.Errorf("invalid record: %w", err)
+	}
+	record, ok := value.(map[string]any)
+	if !ok {
+		return event{}, fmt.Errorf("invalid record of type %T", value)
+	}
+
+	return event{time: t, record: record}, nil
+}
+
+// decodeTime decodes an event time, either an EventTime extension or a number of seconds.
+func decodeTime(decoder *msgpack.Decoder) (time.Time, error) {
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if msgpcode.IsExt(code) {
+		id, length, err := decoder.DecodeExtHeader()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if id != eventTimeExtID || length != 8 {
+			return time.Time{}, fmt.Errorf("invalid event time extension %d of length %d", id, length)
+		}
+
+		b := make([]byte, length)
+		if err := decoder.ReadFull(b); err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(b[:4])), int64(binary.BigEndian.Uint32(b[4:]))), nil
+	}
+
+	value, err := decoder.DecodeInterface()
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := value.(type) {
+	case int64:
+		return time.Unix(v, 0), nil
+	case uint64:
+		return time.Unix(int64(v), 0), nil
+	case float64:
+		return time.Unix(0, int64(v*float64(time.Second))), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid event time of type %T", value)
+	}
+}
+
+// decompress decompresses the entries of a CompressedPackedForward mode message.
+func decompress(compression string, b []byte) ([]byte, error) {
+	if compression != "gzip" {
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	b, err = io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed entries exceed %d bytes", maxDecompressedSize)
+	}
+	return b, nil
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+// anyValue converts a decoded msgpack value into an OTLP value.
+func anyValue(value any) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case uint64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case []any:
+		values := make([]*commonpb.AnyValue, 0, len(v))
+		for _, item := range v {
+			values = append(values, anyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		values := make([]*commonpb.KeyValue, 0, len(v))
+		for _, key := range keys {
+			values = append(values, &commonpb.KeyValue{Key: key, Value: anyValue(v[key])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
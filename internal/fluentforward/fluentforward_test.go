@@ -0,0 +1,237 @@
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// eventTime encodes a Fluent Forward EventTime extension.
+type eventTime time.Time
+
+var _ msgpack.CustomEncoder = eventTime{}
+
+func (t eventTime) EncodeMsgpack(encoder *msgpack.Encoder) error {
+	if err := encoder.EncodeExtHeader(eventTimeExtID, 8); err != nil {
+		return err
+	}
+	b := binary.BigEndian.AppendUint32(nil, uint32(time.Time(t).Unix()))
+	b = binary.BigEndian.AppendUint32(b, uint32(time.Time(t).Nanosecond()))
+	_, err := encoder.Writer().Write(b)
+	return err
+}
+
+func encode(t *testing.T, values ...any) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	for _, value := range values {
+		require.NoError(t, encoder.Encode(value))
+	}
+	return buf.Bytes()
+}
+
+func stringValue(v string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	record := map[string]any{"log": "hello", "tenant.id": "tenant-a", "level": "info"}
+	entries := encode(t, []any{eventTime(ts), record}, []any{int64(1700000001), record})
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write(entries)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	tests := []struct {
+		name      string
+		message   []any
+		wantTimes []time.Time
+		wantChunk string
+		wantErr   bool
+	}{
+		{
+			name:      "message mode",
+			message:   []any{"app", eventTime(ts), record},
+			wantTimes: []time.Time{ts},
+		},
+		{
+			name:      "message mode with chunk",
+			message:   []any{"app", int64(1700000001), record, map[string]any{"chunk": "abc"}},
+			wantTimes: []time.Time{time.Unix(1700000001, 0)},
+			wantChunk: "abc",
+		},
+		{
+			name:      "forward mode",
+			message:   []any{"app", []any{[]any{eventTime(ts), record}, []any{int64(1700000001), record}}},
+			wantTimes: []time.Time{ts, time.Unix(1700000001, 0)},
+		},
+		{
+			name:      "packed forward mode",
+			message:   []any{"app", entries, map[string]any{"chunk": "def"}},
+			wantTimes: []time.Time{ts, time.Unix(1700000001, 0)},
+			wantChunk: "def",
+		},
+		{
+			name:      "compressed packed forward mode",
+			message:   []any{"app", compressed.Bytes(), map[string]any{"compressed": "gzip"}},
+			wantTimes: []time.Time{ts, time.Unix(1700000001, 0)},
+		},
+		{
+			name:    "unsupported compression",
+			message: []any{"app", entries, map[string]any{"compressed": "zstd"}},
+			wantErr: true,
+		},
+		{
+			name:    "too few elements",
+			message: []any{"app"},
+			wantErr: true,
+		},
+		{
+			name:    "record is not a map",
+			message: []any{"app", int64(1700000001), "not a record"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := decodeMessage(newDecoder(bytes.NewReader(encode(t, tt.message))))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "app", msg.tag)
+			assert.Equal(t, tt.wantChunk, msg.chunk)
+			require.Len(t, msg.events, len(tt.wantTimes))
+			for i, e := range msg.events {
+				assert.True(t, tt.wantTimes[i].Equal(e.time), "event %d time = %v, want %v", i, e.time, tt.wantTimes[i])
+				assert.Equal(t, "hello", e.record["log"])
+			}
+		})
+	}
+}
+
+func TestServer_Resources(t *testing.T) {
+	s := New(&config.FluentForward{}, &config.Tenant{Label: "tenant.id", Labels: []string{"org"}}, nil)
+	ts := time.Unix(1700000000, 0)
+
+	resources := s.resources(&message{
+		tag: "app",
+		events: []event{
+			{time: ts, record: map[string]any{"message": "a", "tenant.id": "tenant-a", "count": int64(1)}},
+			{time: ts, record: map[string]any{"message": "b", "org": "tenant-b"}},
+			{time: ts, record: map[string]any{"message": "c", "tenant.id": "tenant-a", "nested": map[string]any{"k": "v"}}},
+			{time: ts, record: map[string]any{"other": "d"}},
+		},
+	})
+
+	require.Len(t, resources, 3)
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-a")}, resources[0].GetResource().GetAttributes())
+	records := resources[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, 2)
+	assert.Equal(t, stringValue("a"), records[0].GetBody())
+	assert.Equal(t, uint64(ts.UnixNano()), records[0].GetTimeUnixNano())
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue(tagAttrKey, "app"),
+		{Key: "count", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 1}}},
+	}, records[0].GetAttributes())
+	assert.Equal(t, &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+		Values: []*commonpb.KeyValue{stringKeyValue("k", "v")},
+	}}}, records[1].GetAttributes()[1].GetValue())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("org", "tenant-b")}, resources[1].GetResource().GetAttributes())
+
+	assert.Empty(t, resources[2].GetResource().GetAttributes())
+	other := resources[2].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Nil(t, other.GetBody())
+	assert.Contains(t, other.GetAttributes(), stringKeyValue("other", "d"))
+}
+
+func TestServer_Serve(t *testing.T) {
+	tests := []struct {
+		name    string
+		sinkErr error
+		wantAck bool
+	}{
+		{
+			name:    "chunk acknowledged once forwarded",
+			wantAck: true,
+		},
+		{
+			name:    "chunk not acknowledged when forwarding fails",
+			sinkErr: errors.New("backend unavailable"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []*logpb.ResourceLogs, 1)
+			s := New(&config.FluentForward{Timeout: time.Second}, &config.Tenant{Label: "tenant.id"},
+				func(_ context.Context, resources []*logpb.ResourceLogs) error {
+					received <- resources
+					return tt.sinkErr
+				},
+			)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			done := make(chan error, 1)
+			go func() { done <- s.Serve(ctx, listener) }()
+
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+
+			_, err = conn.Write(encode(t, []any{"app", int64(1700000000), map[string]any{"log": "hello", "tenant.id": "tenant-a"}, map[string]any{"chunk": "abc"}}))
+			require.NoError(t, err)
+
+			select {
+			case resources := <-received:
+				require.Len(t, resources, 1)
+				assert.Equal(t, stringValue("hello"), resources[0].GetScopeLogs()[0].GetLogRecords()[0].GetBody())
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for events")
+			}
+
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			var ack map[string]string
+			err = msgpack.NewDecoder(conn).Decode(&ack)
+			if tt.wantAck {
+				require.NoError(t, err)
+				assert.Equal(t, map[string]string{"ack": "abc"}, ack)
+			} else {
+				assert.Error(t, err)
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the server to stop")
+			}
+		})
+	}
+}
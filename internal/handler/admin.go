@@ -0,0 +1,179 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/supportbundle"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AdminConfig handles requests for the effective configuration with secrets redacted.
+func (h *Handlers) AdminConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.config.Redact())
+}
+
+// AdminStats handles requests for the per-tenant throughput, error, queue and circuit statistics.
+func (h *Handlers) AdminStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.stats.Snapshot())
+}
+
+// AdminTopK handles requests for the tenants with the highest volume over the sliding window.
+func (h *Handlers) AdminTopK(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.topK.Top())
+}
+
+// AdminFeatures handles requests for the build of the proxy and the subsystems compiled in and enabled.
+func (h *Handlers) AdminFeatures(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.features())
+}
+
+// AdminCircuits handles requests for the circuits opened by operators.
+func (h *Handlers) AdminCircuits(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.circuits.List())
+}
+
+// circuitStats returns the callback listing the circuits opened by operators in the admin stats, each named after its
+// signal and tenant.
+func circuitStats(circuits *circuit.Overrides) func() []stats.CircuitStats {
+	return func() []stats.CircuitStats {
+		overrides := circuits.List()
+		states := make([]stats.CircuitStats, 0, len(overrides))
+		for _, override := range overrides {
+			states = append(states, stats.CircuitStats{Name: override.Signal + "/" + override.Tenant, State: override.State})
+		}
+		return states
+	}
+}
+
+// AdminOpenCircuit handles requests opening the circuit of a signal and tenant, pausing its forwarding.
+func (h *Handlers) AdminOpenCircuit(w http.ResponseWriter, r *http.Request) {
+	signal, tenant, ok := h.circuitPath(w, r)
+	if !ok {
+		return
+	}
+
+	override, err := h.circuits.Open(signal, tenant)
+	if err != nil {
+		logger.Error(r.Context(), err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(r.Context(), "opened circuit", attribute.String(signalTypeAttrKey, signal), attribute.String(signalTenantAttrKey, tenant))
+	writeJSON(w, r, http.StatusOK, override)
+}
+
+// AdminCloseCircuit handles requests closing the circuit of a signal and tenant, resuming its forwarding.
+func (h *Handlers) AdminCloseCircuit(w http.ResponseWriter, r *http.Request) {
+	signal, tenant, ok := h.circuitPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.circuits.Close(signal, tenant); err != nil {
+		logger.Error(r.Context(), err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info(r.Context(), "closed circuit", attribute.String(signalTypeAttrKey, signal), attribute.String(signalTenantAttrKey, tenant))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Circuits returns the manual circuit overrides backing the admin circuit endpoints.
+func (h *Handlers) Circuits() *circuit.Overrides {
+	return h.circuits
+}
+
+// AdminSupportBundle handles requests for a support bundle: a tarball of the version, redacted configuration,
+// statistics, recent backend errors, runtime statistics and goroutine stacks of the proxy, to attach to bug reports.
+func (h *Handlers) AdminSupportBundle(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+
+	files := make([]supportbundle.File, 0, 9)
+	for name, v := range map[string]any{
+		"version.json":  supportbundle.BuildVersion(h.config.Service.Name, h.config.Service.Version, h.config.Service.InstanceID),
+		"config.json":   h.config.Redact(),
+		"stats.json":    h.stats.Snapshot(),
+		"topk.json":     h.topK.Top(),
+		"circuits.json": h.circuits.List(),
+		"errors.json":   h.stats.RecentErrors(),
+		"features.json": h.features().Features,
+		"runtime.json":  supportbundle.ReadRuntime(),
+	} {
+		file, err := supportbundle.JSON(name, v)
+		if err != nil {
+			logger.Error(r.Context(), err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		files = append(files, file)
+	}
+
+	goroutines, err := supportbundle.Goroutines("goroutines.txt")
+	if err != nil {
+		logger.Error(r.Context(), err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	files = append(files, goroutines)
+	slices.SortFunc(files, func(a, b supportbundle.File) int { return strings.Compare(a.Name, b.Name) })
+
+	// Build the bundle before responding, so a failure is reported instead of a truncated tarball
+	var buf bytes.Buffer
+	if err := supportbundle.Write(&buf, now, files); err != nil {
+		logger.Error(r.Context(), err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="support-bundle-%s.tar.gz"`,
+		now.Format("20060102T150405Z")))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logger.Error(r.Context(), err.Error())
+	}
+}
+
+// circuitPath returns the signal and tenant of a circuit request, writing a bad request response when the signal is
+// unknown, profiles being known only when they are forwarded.
+func (h *Handlers) circuitPath(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	signals := []string{"logs", "metrics", "traces"}
+	if h.config.Profiles.Address != "" {
+		signals = append(signals, "profiles")
+	}
+
+	signal, tenant := r.PathValue("signal"), r.PathValue("tenant")
+	if !slices.Contains(signals, signal) {
+		http.Error(w, fmt.Sprintf("unknown signal %q", signal), http.StatusBadRequest)
+		return "", "", false
+	}
+	return signal, tenant, true
+}
+
+// Stats returns the tracker backing the admin stats endpoint.
+func (h *Handlers) Stats() *stats.Tracker {
+	return h.stats
+}
+
+// writeJSON writes the given value as an indented JSON response.
+func writeJSON(w http.ResponseWriter, r *http.Request, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		logger.Error(r.Context(), err.Error())
+	}
+}
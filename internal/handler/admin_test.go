@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/integration"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/topk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAdminConfig(t *testing.T) {
+	cfg := &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+		Logs: config.Endpoint{
+			Address: "http://localhost:3100",
+			Headers: "Authorization=Bearer secret-token",
+		},
+	}
+	h := newTestHandlers(t, cfg, &http.Client{})
+
+	rec := httptest.NewRecorder()
+	h.AdminConfig(rec, httptest.NewRequest(http.MethodGet, "/admin/config", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.NotContains(t, rec.Body.String(), "secret-token")
+
+	var got config.Config
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "http://localhost:3100", got.Logs.Address)
+	assert.Equal(t, "Authorization="+config.Redacted, got.Logs.Headers)
+	assert.Equal(t, "Authorization=Bearer secret-token", cfg.Logs.Headers, "original config must not be modified")
+}
+
+func TestAdminStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil)
+
+	h := newTestHandlers(t, &config.Config{
+		Service: config.Service{InstanceID: "replica-1"},
+		Tenant:  config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, client)
+
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+	h.Logs(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+	_, err = h.Circuits().Open("traces", "tenant-b")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.AdminStats(rec, httptest.NewRequest(http.MethodGet, "/admin/stats", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got stats.Snapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "replica-1", got.Instance)
+	require.Len(t, got.Tenants, 1)
+	assert.Equal(t, "logs", got.Tenants[0].Signal)
+	assert.Equal(t, "tenant-a", got.Tenants[0].Tenant)
+	assert.Equal(t, int64(1), got.Tenants[0].Requests)
+	assert.Equal(t, int64(1), got.Tenants[0].Errors)
+	assert.InDelta(t, 1.0, got.Tenants[0].ErrorRate, 0.0001)
+	assert.Equal(t, []stats.CircuitStats{{Name: "traces/tenant-b", State: "open"}}, got.Circuits)
+}
+
+func TestAdminTopK(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil).Times(2)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		TopK:   config.TopK{Size: 1, Window: time.Minute, Buckets: 1},
+	}, client)
+
+	body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+		{Resource: testResource("tenant-a"), ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{}}}}},
+		{Resource: testResource("tenant-b"), ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{}}}}},
+		{Resource: testResource("tenant-b"), ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{}, {}}}}},
+	}})
+	require.NoError(t, err)
+	h.Traces(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body)))
+
+	rec := httptest.NewRecorder()
+	h.AdminTopK(rec, httptest.NewRequest(http.MethodGet, "/admin/topk", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got []topk.Tenant
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "tenant-b", got[0].Tenant)
+	assert.Equal(t, uint64(3), got[0].Records)
+}
+
+func TestAdminFeatures(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		Service: config.Service{Name: "otel-lgtm-proxy", Version: "1.2.3"},
+		GRPC:    config.GRPCListener{Address: ":4317"},
+		Influx:  config.Influx{Enabled: true},
+		Syslog:  config.Syslog{UDPAddress: ":514"},
+	}, &http.Client{})
+
+	rec := httptest.NewRecorder()
+	h.AdminFeatures(rec, httptest.NewRequest(http.MethodGet, "/admin/features", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got Features
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "1.2.3", got.Version.Version)
+
+	features := map[string]integration.Feature{}
+	var names []string
+	for _, feature := range got.Features {
+		features[feature.Name] = feature
+		names = append(names, feature.Name)
+	}
+	assert.IsNonDecreasing(t, names)
+	assert.Equal(t, integration.Feature{Name: "grpc", Compiled: true, Enabled: true}, features["grpc"])
+	assert.Equal(t, integration.Feature{Name: "influx", Compiled: true, Enabled: true}, features["influx"])
+	assert.Equal(t, integration.Feature{Name: "zipkin", Compiled: true}, features["zipkin"])
+	assert.Equal(t, features["syslog"].Compiled, features["syslog"].Enabled)
+	assert.False(t, features["kafka"].Enabled)
+}
+
+func TestAdminCircuits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil).Times(2)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, client)
+
+	circuitRequest := func(signal, tenant string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/admin/circuits/"+signal+"/"+tenant, nil)
+		req.SetPathValue("signal", signal)
+		req.SetPathValue("tenant", tenant)
+		return req
+	}
+	sendLogs := func(tenant string) int {
+		body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource(tenant)}}})
+		require.NoError(t, err)
+		rec := httptest.NewRecorder()
+		h.Logs(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+		return rec.Code
+	}
+
+	rec := httptest.NewRecorder()
+	h.AdminOpenCircuit(rec, circuitRequest("profiles", "tenant-a"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.AdminOpenCircuit(rec, circuitRequest("logs", "tenant-a"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The open circuit only pauses the logs of tenant-a.
+	assert.Equal(t, http.StatusServiceUnavailable, sendLogs("tenant-a"))
+	assert.Equal(t, http.StatusAccepted, sendLogs("tenant-b"))
+
+	rec = httptest.NewRecorder()
+	h.AdminCircuits(rec, httptest.NewRequest(http.MethodGet, "/admin/circuits", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var got []circuit.Override
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "logs", got[0].Signal)
+	assert.Equal(t, "tenant-a", got[0].Tenant)
+	assert.Equal(t, circuit.StateOpen, got[0].State)
+
+	rec = httptest.NewRecorder()
+	h.AdminCloseCircuit(rec, circuitRequest("logs", "tenant-a"))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	assert.Equal(t, http.StatusAccepted, sendLogs("tenant-a"))
+}
+
+func TestAdminSupportBundle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil)
+
+	h := newTestHandlers(t, &config.Config{
+		Service: config.Service{Name: "otel-lgtm-proxy", Version: "1.2.3", InstanceID: "replica-1"},
+		Tenant:  config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Logs:    config.Endpoint{Address: "http://localhost:3100", Headers: "Authorization=Bearer secret-token"},
+	}, client)
+
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+	h.Logs(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+	rec := httptest.NewRecorder()
+	h.AdminSupportBundle(rec, httptest.NewRequest(http.MethodGet, "/admin/support-bundle", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "support-bundle-")
+
+	gz, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = data
+		names = append(names, header.Name)
+	}
+
+	assert.Equal(t, []string{
+		"circuits.json", "config.json", "errors.json", "features.json", "goroutines.txt",
+		"runtime.json", "stats.json", "topk.json", "version.json",
+	}, names)
+	assert.NotContains(t, string(files["config.json"]), "secret-token")
+	assert.Contains(t, string(files["version.json"]), `"version": "1.2.3"`)
+
+	var errs []stats.ErrorEvent
+	require.NoError(t, json.Unmarshal(files["errors.json"], &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "logs", errs[0].Signal)
+	assert.Equal(t, "tenant-a", errs[0].Tenant)
+	assert.Equal(t, "backend responded with status 503", errs[0].Error)
+}
@@ -0,0 +1,78 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// validateDispatch returns an error when the error policy or the mode of the dispatch is unknown, an empty policy being
+// best-effort and an empty mode sync.
+func validateDispatch(dispatch *config.Dispatch) error {
+	switch dispatch.ErrorPolicy {
+	case "", config.DispatchBestEffort, config.DispatchFailFast, config.DispatchAtLeastOne:
+	default:
+		return fmt.Errorf("invalid dispatch error policy %q, expected %s, %s or %s", dispatch.ErrorPolicy,
+			config.DispatchBestEffort, config.DispatchFailFast, config.DispatchAtLeastOne)
+	}
+
+	switch dispatch.Mode {
+	case "", config.DispatchSync, config.DispatchAsync:
+	default:
+		return fmt.Errorf("invalid dispatch mode %q, expected %s or %s", dispatch.Mode,
+			config.DispatchSync, config.DispatchAsync)
+	}
+	return nil
+}
+
+// newInflight returns the semaphore bounding the dispatches running in the background in async mode, or nil in sync
+// mode.
+func newInflight(dispatch *config.Dispatch) chan struct{} {
+	if dispatch.Mode != config.DispatchAsync {
+		return nil
+	}
+	return make(chan struct{}, max(dispatch.AsyncMaxInflight, 1))
+}
+
+// dispatchAsync runs the dispatch in the background with a context detached from the cancellation of the request, so
+// the request can be answered before its data is forwarded. It returns false in sync mode, and when too many dispatches
+// are already in flight so the request is forwarded before being answered, pushing back on the client.
+func (h *Handlers) dispatchAsync(ctx context.Context, signal string, dispatch func(ctx context.Context)) bool {
+	if h.inflight == nil {
+		return false
+	}
+
+	select {
+	case h.inflight <- struct{}{}:
+	default:
+		h.asyncSaturatedMetric.Add(ctx, 1, metric.WithAttributes(attribute.String(signalTypeAttrKey, signal)))
+		return false
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	h.background.Go(func() {
+		defer func() { <-h.inflight }()
+		dispatch(ctx)
+	})
+	return true
+}
+
+// Wait waits for the dispatches running in the background in async mode to complete, or for the context to be done.
+func (h *Handlers) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
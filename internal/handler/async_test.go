@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignalHandlers_Async(t *testing.T) {
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		return req
+	}
+
+	newHandlers := func(t *testing.T, client processor.Client) *Handlers {
+		return newTestHandlers(t, &config.Config{
+			Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			Dispatch: config.Dispatch{Mode: config.DispatchAsync, AsyncMaxInflight: 1},
+		}, client)
+	}
+
+	t.Run("answers before forwarding", func(t *testing.T) {
+		release := make(chan struct{})
+		ctrl := gomock.NewController(t)
+		client := processor.NewMockClient(ctrl)
+		client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			<-release
+			// The dispatch outlives the request, so it must not be cancelled with it
+			assert.NoError(t, req.Context().Err())
+			return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil
+		})
+
+		h := newHandlers(t, client)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		rec := httptest.NewRecorder()
+		h.Logs(rec, newRequest().WithContext(ctx))
+		cancel()
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer waitCancel()
+		assert.ErrorIs(t, h.Wait(waitCtx), context.DeadlineExceeded)
+
+		close(release)
+		assert.NoError(t, h.Wait(context.Background()))
+	})
+
+	t.Run("forwards inline when saturated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		client := processor.NewMockClient(ctrl)
+		client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody}, nil)
+
+		h := newHandlers(t, client)
+		h.inflight <- struct{}{}
+		defer func() { <-h.inflight }()
+
+		rec := httptest.NewRecorder()
+		h.Logs(rec, newRequest())
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
+
+func TestNewInflight(t *testing.T) {
+	tests := []struct {
+		name     string
+		dispatch config.Dispatch
+		wantCap  int
+		wantNil  bool
+	}{
+		{name: "sync", dispatch: config.Dispatch{Mode: config.DispatchSync, AsyncMaxInflight: 8}, wantNil: true},
+		{name: "async", dispatch: config.Dispatch{Mode: config.DispatchAsync, AsyncMaxInflight: 8}, wantCap: 8},
+		{name: "async without a limit", dispatch: config.Dispatch{Mode: config.DispatchAsync}, wantCap: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inflight := newInflight(&tt.dispatch)
+			if tt.wantNil {
+				assert.Nil(t, inflight)
+				return
+			}
+			assert.Equal(t, tt.wantCap, cap(inflight))
+		})
+	}
+}
+
+func TestValidateDispatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		dispatch config.Dispatch
+		wantErr  string
+	}{
+		{name: "defaults"},
+		{name: "best-effort", dispatch: config.Dispatch{ErrorPolicy: config.DispatchBestEffort}},
+		{name: "fail-fast", dispatch: config.Dispatch{ErrorPolicy: config.DispatchFailFast}},
+		{name: "at-least-one", dispatch: config.Dispatch{ErrorPolicy: config.DispatchAtLeastOne}},
+		{
+			name:     "unknown error policy",
+			dispatch: config.Dispatch{ErrorPolicy: "failfast"},
+			wantErr:  `invalid dispatch error policy "failfast"`,
+		},
+		{name: "sync", dispatch: config.Dispatch{Mode: config.DispatchSync}},
+		{name: "async", dispatch: config.Dispatch{Mode: config.DispatchAsync}},
+		{
+			name:     "unknown mode",
+			dispatch: config.Dispatch{Mode: "asnyc"},
+			wantErr:  `invalid dispatch mode "asnyc"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDispatch(&tt.dispatch)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
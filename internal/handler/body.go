@@ -0,0 +1,49 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxBodySize limits the size of a decompressed request body.
+const maxBodySize = 64 << 20
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// readBody reads the request body, decompressing gzip and deflate encoded bodies, up to limit decompressed bytes.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	body := r.Body
+	switch encoding := r.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer func() { _ = reader.Close() }()
+		body = reader
+	case "deflate":
+		reader, err := zlib.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate body: %w", err)
+		}
+		defer func() { _ = reader.Close() }()
+		body = reader
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return b, nil
+}
@@ -0,0 +1,106 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var maxBodyBytesAttrKey = "http.request.body.max_size"
+
+// maxBodyBytes returns the maximum size of the request bodies of the signal, or zero when unlimited. The limit of the
+// signal overrides the limit of the listener.
+func (h *Handlers) maxBodyBytes(signal string) int64 {
+	limit := h.config.HTTP.MaxBodyBytes
+	override := map[string]int64{
+		"logs":     h.config.HTTP.LogsMaxBodyBytes,
+		"metrics":  h.config.HTTP.MetricsMaxBodyBytes,
+		"traces":   h.config.HTTP.TracesMaxBodyBytes,
+		"profiles": h.config.HTTP.ProfilesMaxBodyBytes,
+	}[signal]
+	if override > 0 {
+		limit = override
+	}
+	return limit
+}
+
+// boundedBodyBytes returns the maximum size of the bodies of the signal read whole, such as those of the receivers
+// and the stream frames: the body size limit of the signal, or the size of a decompressed request body when the
+// signal is unlimited so a body cannot take gigabytes.
+func (h *Handlers) boundedBodyBytes(signal string) int64 {
+	if limit := h.maxBodyBytes(signal); limit > 0 {
+		return limit
+	}
+	return maxBodySize
+}
+
+// readSignalBody reads the decompressed body of a request to a receiver of the signal, up to the bounded body size
+// of the signal. Bodies announced or read as larger are answered with 413 like OTLP requests, and errBodyTooLarge is
+// returned so the receiver does not answer them again.
+func (h *Handlers) readSignalBody(w http.ResponseWriter, r *http.Request, signal string) ([]byte, error) {
+	limit := h.boundedBodyBytes(signal)
+	if r.ContentLength > limit {
+		h.rejectOversized(r.Context(), w, r, signal, limit)
+		return nil, errBodyTooLarge
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+	b, err := readBody(r, limit)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) || errors.Is(err, errBodyTooLarge) {
+		h.rejectOversized(r.Context(), w, r, signal, limit)
+		return nil, errBodyTooLarge
+	}
+	return b, err
+}
+
+// validateMaxBodyBytes returns an error when a body size limit of the listener is negative.
+func validateMaxBodyBytes(listener *config.Listener) error {
+	for name, limit := range map[string]int64{
+		"max body bytes":          listener.MaxBodyBytes,
+		"logs max body bytes":     listener.LogsMaxBodyBytes,
+		"metrics max body bytes":  listener.MetricsMaxBodyBytes,
+		"traces max body bytes":   listener.TracesMaxBodyBytes,
+		"profiles max body bytes": listener.ProfilesMaxBodyBytes,
+	} {
+		if limit < 0 {
+			return fmt.Errorf("invalid %s %d", name, limit)
+		}
+	}
+	return nil
+}
+
+// rejectOversized answers a request whose body exceeds the limit with 413 and a google.rpc.Status, so OTLP exporters
+// report why it was dropped rather than retrying it.
+func (h *Handlers) rejectOversized(
+	ctx context.Context,
+	w http.ResponseWriter,
+	r *http.Request,
+	signal string,
+	limit int64,
+) {
+	err := fmt.Errorf("%w: the limit is %d bytes", errBodyTooLarge, limit)
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(clientAddressAttrKey, requestmeta.FromContext(ctx).ClientAddress),
+	}
+	h.oversizedPayloadsMetric.Add(ctx, 1, metric.WithAttributes(attrs...))
+	logger.Warn(ctx, err.Error(), append(attrs, attribute.Int64(maxBodyBytesAttrKey, limit))...)
+
+	// Close the connection rather than reading the rest of the body to reuse it
+	w.Header().Set("Connection", "close")
+	h.writeStatus(ctx, w, r, http.StatusRequestEntityTooLarge, err)
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMaxBodyBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		listener config.Listener
+		signal   string
+		want     int64
+	}{
+		{
+			name:   "unlimited",
+			signal: "logs",
+		},
+		{
+			name:     "listener limit",
+			listener: config.Listener{MaxBodyBytes: 1000, TracesMaxBodyBytes: 2000},
+			signal:   "logs",
+			want:     1000,
+		},
+		{
+			name:     "signal limit overrides the listener limit",
+			listener: config.Listener{MaxBodyBytes: 1000, TracesMaxBodyBytes: 2000},
+			signal:   "traces",
+			want:     2000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handlers{config: &config.Config{HTTP: tt.listener}}
+			assert.Equal(t, tt.want, h.maxBodyBytes(tt.signal))
+		})
+	}
+}
+
+func TestValidateMaxBodyBytes(t *testing.T) {
+	assert.NoError(t, validateMaxBodyBytes(&config.Listener{MaxBodyBytes: 1 << 20}))
+	assert.ErrorContains(t, validateMaxBodyBytes(&config.Listener{MetricsMaxBodyBytes: -1}),
+		"invalid metrics max body bytes")
+}
+
+func TestSignalHandlers_MaxBodyBytes(t *testing.T) {
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		limit         int64
+		chunked       bool
+		wantStatus    int
+		wantForwarded bool
+	}{
+		{
+			name:          "within the limit",
+			limit:         int64(len(body)),
+			wantStatus:    http.StatusAccepted,
+			wantForwarded: true,
+		},
+		{
+			name:       "announced larger than the limit",
+			limit:      int64(len(body) - 1),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "chunked body larger than the limit",
+			limit:      int64(len(body) - 1),
+			chunked:    true,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.wantForwarded {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				HTTP:   config.Listener{MaxBodyBytes: 1, LogsMaxBodyBytes: tt.limit},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			var reader io.Reader = bytes.NewReader(body)
+			if tt.chunked {
+				reader = io.MultiReader(reader)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", reader)
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusRequestEntityTooLarge {
+				return
+			}
+
+			// Oversized bodies are answered with an OTLP error payload, even outside strict mode
+			assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+			status := &spb.Status{}
+			require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), status))
+			assert.Equal(t, int32(grpcStatusUnknown), status.GetCode())
+			assert.Contains(t, status.GetMessage(), "request body too large")
+		})
+	}
+}
+
+func TestReceivers_MaxBodyBytes(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		HTTP:   config.Listener{MaxBodyBytes: 16},
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, processor.NewMockClient(gomock.NewController(t)))
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		contentType string
+		body        string
+	}{
+		{name: "datadog series", handler: h.DatadogSeries, contentType: "application/json", body: `{"series":[{"metric":"cpu"}]}`},
+		{name: "datadog logs", handler: h.DatadogLogs, contentType: "application/json", body: `[{"message":"hello"}]`},
+		{name: "influx", handler: h.InfluxWrite, contentType: "text/plain", body: "cpu,host=a value=1 1"},
+		{name: "loki", handler: h.LokiPush, contentType: "application/json", body: `{"streams":[{"stream":{}}]}`},
+		{name: "zipkin", handler: h.ZipkinSpans, contentType: "application/json", body: `[{"traceId":"1","id":"2"}]`},
+		{name: "jaeger", handler: h.JaegerTraces, contentType: "application/x-thrift", body: "0123456789abcdefgh"},
+	}
+
+	for _, tt := range tests {
+		for _, chunked := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s chunked %t", tt.name, chunked), func(t *testing.T) {
+				var reader io.Reader = strings.NewReader(tt.body)
+				if chunked {
+					reader = io.MultiReader(reader)
+				}
+				req := httptest.NewRequest(http.MethodPost, "/", reader)
+				req.Header.Set("Content-Type", tt.contentType)
+				if chunked {
+					req.ContentLength = -1
+				}
+				rec := httptest.NewRecorder()
+				tt.handler(rec, req)
+
+				// Bodies over the limit are answered like OTLP ones, without reaching the backend
+				assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+				assert.Equal(t, "close", rec.Header().Get("Connection"))
+			})
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	clientAddressAttrKey      = "client.address"
+	userAgentAttrKey          = "user_agent.original"
+	requestBodySizeAttrKey    = "http.request.body.size"
+	requestMethodAttrKey      = "http.request.method"
+	requestPathAttrKey        = "url.path"
+	responseStatusCodeAttrKey = "http.response.status_code"
+	accessLogMessage          = "handled request"
+)
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader records the status code before writing it.
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	s.statusCode = statusCode
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap returns the underlying response writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// clientAttributes returns the attributes identifying the client that sent the request.
+func (h *Handlers) clientAttributes(r *http.Request) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(clientAddressAttrKey, h.clientAddress(r)),
+		attribute.String(userAgentAttrKey, r.UserAgent()),
+		attribute.Int64(requestBodySizeAttrKey, r.ContentLength),
+	}
+}
+
+// withAccessLog annotates the request span with client attributes and emits an access log once the request is handled.
+func (h *Handlers) withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attrs := h.clientAttributes(r)
+		trace.SpanFromContext(r.Context()).SetAttributes(attrs...)
+
+		rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		logger.Debug(r.Context(), accessLogMessage, append(attrs,
+			attribute.String(requestMethodAttrKey, r.Method),
+			attribute.String(requestPathAttrKey, r.URL.Path),
+			attribute.Int(responseStatusCodeAttrKey, rec.statusCode),
+		)...)
+	})
+}
+
+// parseTrustedProxies parses the configured trusted proxy CIDRs, accepting bare IP addresses as single host prefixes.
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// trusted reports whether the given address belongs to one of the trusted proxies.
+func (h *Handlers) trusted(address string) bool {
+	addr, err := netip.ParseAddr(strings.TrimSpace(address))
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, prefix := range h.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withSender returns the request with its sender recorded in the request metadata, for the logs and spans of every
+// stage handling its data.
+func (h *Handlers) withSender(r *http.Request) *http.Request {
+	metadata := requestmeta.FromContext(r.Context())
+	metadata.ClientAddress, metadata.UserAgent = h.clientAddress(r), r.UserAgent()
+	return r.WithContext(requestmeta.NewContext(r.Context(), metadata))
+}
+
+// clientAddress returns the address of the client that sent the request.
+//
+// X-Forwarded-For and X-Real-IP are only honored when the request was received from a trusted proxy,
+// in which case the right-most untrusted address of the forwarding chain is used.
+func (h *Handlers) clientAddress(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !h.trusted(remote) {
+		return remote
+	}
+
+	if forwardedFor := r.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if !h.trusted(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+
+	return remote
+}
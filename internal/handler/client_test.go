@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/embedded"
+	"go.opentelemetry.io/otel/log/global"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{
+			name:    "empty list",
+			proxies: []string{},
+			want:    []netip.Prefix{},
+		},
+		{
+			name:    "cidrs and bare addresses",
+			proxies: []string{"10.0.0.0/8", " 192.0.2.1 ", "2001:db8::/32"},
+			want: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.0.2.1/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+		},
+		{
+			name:    "unmasked cidr is normalized",
+			proxies: []string{"10.1.2.3/16"},
+			want:    []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")},
+		},
+		{
+			name:    "invalid cidr",
+			proxies: []string{"10.0.0.0/99"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid address",
+			proxies: []string{"not-an-ip"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTrustedProxies(tt.proxies)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		headers        map[string]string
+		want           string
+	}{
+		{
+			name:       "ipv4 host and port",
+			remoteAddr: "192.0.2.10:54321",
+			want:       "192.0.2.10",
+		},
+		{
+			name:       "ipv6 host and port",
+			remoteAddr: "[2001:db8::1]:54321",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "address without port",
+			remoteAddr: "192.0.2.10",
+			want:       "192.0.2.10",
+		},
+		{
+			name:       "forwarded headers ignored from untrusted peer",
+			remoteAddr: "192.0.2.10:54321",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Real-IP": "198.51.100.8"},
+			want:       "192.0.2.10",
+		},
+		{
+			name:           "forwarded for honored from trusted peer",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:54321",
+			headers:        map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			want:           "198.51.100.7",
+		},
+		{
+			name:           "right-most untrusted hop is used",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:54321",
+			headers:        map[string]string{"X-Forwarded-For": "203.0.113.99, 198.51.100.7, 10.0.0.5"},
+			want:           "198.51.100.7",
+		},
+		{
+			name:           "left-most hop used when every hop is trusted",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:54321",
+			headers:        map[string]string{"X-Forwarded-For": "10.0.0.4, 10.0.0.5"},
+			want:           "10.0.0.4",
+		},
+		{
+			name:           "real ip honored from trusted peer",
+			trustedProxies: []string{"10.0.0.2"},
+			remoteAddr:     "10.0.0.2:54321",
+			headers:        map[string]string{"X-Real-IP": "198.51.100.8"},
+			want:           "198.51.100.8",
+		},
+		{
+			name:           "trusted peer without forwarding headers",
+			trustedProxies: []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.2:54321",
+			want:           "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trustedProxies, err := parseTrustedProxies(tt.trustedProxies)
+			require.NoError(t, err)
+			h := &Handlers{trustedProxies: trustedProxies}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tt.want, h.clientAddress(req))
+		})
+	}
+}
+
+func TestClientAttributes(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader("payload"))
+	req.RemoteAddr = "192.0.2.10:54321"
+	req.Header.Set("User-Agent", "OTel-OTLP-Exporter-Go/1.0.0")
+
+	attrs := (&Handlers{}).clientAttributes(req)
+
+	assert.Contains(t, attrs, attribute.String(clientAddressAttrKey, "192.0.2.10"))
+	assert.Contains(t, attrs, attribute.String(userAgentAttrKey, "OTel-OTLP-Exporter-Go/1.0.0"))
+	assert.Contains(t, attrs, attribute.Int64(requestBodySizeAttrKey, int64(len("payload"))))
+}
+
+func TestWithAccessLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{
+			name:       "implicit ok status",
+			handler:    func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("OK")) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "explicit status",
+			handler:    func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusAccepted) },
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := &recordingLogger{}
+			logger.SetProvider(records)
+			t.Cleanup(func() { logger.SetProvider(global.GetLoggerProvider().Logger("default")) })
+
+			rec := httptest.NewRecorder()
+			(&Handlers{}).withAccessLog(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+
+			// The access log carries the status code as an integer, as the semantic conventions define it
+			require.Len(t, records.records, 1)
+			var status log.Value
+			records.records[0].WalkAttributes(func(kv log.KeyValue) bool {
+				if kv.Key == responseStatusCodeAttrKey {
+					status = kv.Value
+				}
+				return true
+			})
+			assert.Equal(t, log.Int64Value(int64(tt.wantStatus)), status)
+		})
+	}
+}
+
+// recordingLogger is a logger recording the emitted log records.
+type recordingLogger struct {
+	embedded.Logger
+	records []log.Record
+}
+
+// Emit records the log record.
+func (l *recordingLogger) Emit(_ context.Context, record log.Record) {
+	l.records = append(l.records, record)
+}
+
+// Enabled reports that every log record is recorded.
+func (l *recordingLogger) Enabled(context.Context, log.EnabledParameters) bool {
+	return true
+}
@@ -0,0 +1,47 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// continueBody is the body of a request expecting 100 Continue, recording whether it was read. The server only sends
+// 100 Continue when the body is first read, so a request answered before is rejected without its body being uploaded.
+type continueBody struct {
+	io.ReadCloser
+	read bool
+}
+
+// Read reads the body, which asks the client to upload it.
+func (b *continueBody) Read(p []byte) (int, error) {
+	b.read = true
+	return b.ReadCloser.Read(p)
+}
+
+// expectsContinue reports whether the client waits for 100 Continue before uploading the body of the request.
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue") && r.ContentLength != 0
+}
+
+// trackContinue wraps the body of a request expecting 100 Continue, returning the function counting the request as
+// rejected before its upload when its body was not read once it has been answered. It returns a no-op for other
+// requests.
+func (h *Handlers) trackContinue(r *http.Request, signal string) func(ctx context.Context) {
+	if !expectsContinue(r) {
+		return func(context.Context) {}
+	}
+
+	body := &continueBody{ReadCloser: r.Body}
+	r.Body = body
+	return func(ctx context.Context) {
+		if !body.read {
+			h.continueRejectionsMetric.Add(ctx, 1, metric.WithAttributes(attribute.String(signalTypeAttrKey, signal)))
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+// uploadBody is a request body recording whether the client uploaded it.
+type uploadBody struct {
+	*bytes.Reader
+	uploaded atomic.Bool
+}
+
+func (b *uploadBody) Read(p []byte) (int, error) {
+	b.uploaded.Store(true)
+	return b.Reader.Read(p)
+}
+
+func TestExpectContinue(t *testing.T) {
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		backendCalls int
+		wantStatus   int
+		wantUpload   bool
+	}{
+		{
+			name:         "accepted request is uploaded",
+			backendCalls: 1,
+			wantStatus:   http.StatusAccepted,
+			wantUpload:   true,
+		},
+		{
+			name:       "oversized request is rejected before its upload",
+			cfg:        config.Config{HTTP: config.Listener{MaxBodyBytes: 10}},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "unsigned request is rejected before its upload",
+			cfg: config.Config{Signature: config.Signature{
+				Secrets:     []string{"tenant-a=secret"},
+				Header:      "X-Signature",
+				KeyIDHeader: "X-Signature-Key-Id",
+			}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unsupported content type is rejected before its upload",
+			cfg:        config.Config{Ingest: config.Ingest{ContentType: config.ContentTypeStrict}},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := processor.NewMockClient(gomock.NewController(t))
+			client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil).
+				Times(tt.backendCalls)
+
+			reader := sdkmetric.NewManualReader()
+			cfg := tt.cfg
+			cfg.Tenant = config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"}
+			h, err := New(&cfg, http.NewServeMux(), client, client, client, client,
+				sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+			)
+			require.NoError(t, err)
+			h.Register(t.Context(), "POST /v1/logs", h.Logs)
+
+			server := httptest.NewServer(h.router)
+			defer server.Close()
+
+			upload := &uploadBody{Reader: bytes.NewReader(body)}
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL+"/v1/logs", upload)
+			require.NoError(t, err)
+			req.ContentLength = int64(len(body))
+			req.Header.Set("Expect", "100-continue")
+			if tt.wantStatus != http.StatusUnsupportedMediaType {
+				req.Header.Set("Content-Type", "application/x-protobuf")
+			}
+
+			// Wait long enough for the client to only upload the body after 100 Continue
+			resp, err := (&http.Client{Transport: &http.Transport{ExpectContinueTimeout: time.Minute}}).Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantUpload, upload.uploaded.Load())
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.Background(), &rm))
+			var rejections int64
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name != "otel_lgtm_proxy_expect_continue_rejections_total" {
+						continue
+					}
+					for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						rejections += dp.Value
+					}
+				}
+			}
+			assert.Equal(t, tt.wantUpload, rejections == 0)
+		})
+	}
+}
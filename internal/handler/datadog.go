@@ -0,0 +1,102 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/datadog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DatadogSeries handles Datadog agent metric series, converting them into metrics.
+func (h *Handlers) DatadogSeries(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "metrics"))
+
+	b, err := h.readSignalBody(w, r, "metrics")
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		writeDatadogError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	payload, err := datadog.ParseSeries(b, r.Header.Get("Content-Type"))
+	if err != nil {
+		writeDatadogError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	resources := datadog.New(h.config.Tenant.Label, h.config.Datadog.TenantTag).ResourceMetrics(payload)
+	if err := h.IngestMetrics(ctx, resources); err != nil {
+		writeDatadogError(ctx, w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	writeDatadogResponse(ctx, w, http.StatusAccepted, map[string][]string{"errors": {}})
+}
+
+// DatadogLogs handles Datadog agent logs, converting them into logs.
+func (h *Handlers) DatadogLogs(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "logs"))
+
+	b, err := h.readSignalBody(w, r, "logs")
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		writeDatadogError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	logs, err := datadog.ParseLogs(b)
+	if err != nil {
+		writeDatadogError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	resources := datadog.New(h.config.Tenant.Label, h.config.Datadog.TenantTag).ResourceLogs(logs, time.Now())
+	if err := h.IngestLogs(ctx, resources); err != nil {
+		writeDatadogError(ctx, w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	writeDatadogResponse(ctx, w, http.StatusAccepted, map[string]any{})
+}
+
+// DatadogValidate answers the API key validation of the agent, the key itself is not checked.
+func (h *Handlers) DatadogValidate(w http.ResponseWriter, r *http.Request) {
+	writeDatadogResponse(r.Context(), w, http.StatusOK, map[string]bool{"valid": true})
+}
+
+// writeDatadogError records the error and writes it in the format of the Datadog API.
+func writeDatadogError(ctx context.Context, w http.ResponseWriter, span trace.Span, status int, err error) {
+	logger.Error(ctx, err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	writeDatadogResponse(ctx, w, status, map[string][]string{"errors": {err.Error()}})
+}
+
+// writeDatadogResponse writes a JSON response.
+func writeDatadogResponse(ctx context.Context, w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error(ctx, err.Error())
+	}
+}
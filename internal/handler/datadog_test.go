@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"compress/zlib"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDatadog(t *testing.T) {
+	deflated := func(s string) []byte {
+		var buf bytes.Buffer
+		writer := zlib.NewWriter(&buf)
+		_, err := writer.Write([]byte(s))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name            string
+		handler         func(h *Handlers) http.HandlerFunc
+		body            []byte
+		contentEncoding string
+		wantTenants     []string
+		wantStatus      int
+		wantBody        string
+	}{
+		{
+			name:    "series",
+			handler: func(h *Handlers) http.HandlerFunc { return h.DatadogSeries },
+			body: deflated(`{"series":[{"metric":"load","type":3,"points":[{"timestamp":1700000000,"value":1}],"tags":["tenant:tenant-a"]},` +
+				`{"metric":"load","type":3,"points":[{"timestamp":1700000000,"value":2}]}]}`),
+			contentEncoding: "deflate",
+			wantTenants:     []string{"tenant-a", "default"},
+			wantStatus:      http.StatusAccepted,
+			wantBody:        `{"errors":[]}`,
+		},
+		{
+			name:        "logs",
+			handler:     func(h *Handlers) http.HandlerFunc { return h.DatadogLogs },
+			body:        []byte(`[{"message":"hello","ddtags":"tenant:tenant-b"}]`),
+			wantTenants: []string{"tenant-b"},
+			wantStatus:  http.StatusAccepted,
+			wantBody:    `{}`,
+		},
+		{
+			name:       "invalid series",
+			handler:    func(h *Handlers) http.HandlerFunc { return h.DatadogSeries },
+			body:       []byte(`{"series":`),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errors":["invalid series payload`,
+		},
+		{
+			name:            "unsupported encoding",
+			handler:         func(h *Handlers) http.HandlerFunc { return h.DatadogLogs },
+			body:            []byte(`[]`),
+			contentEncoding: "zstd",
+			wantStatus:      http.StatusBadRequest,
+			wantBody:        `{"errors":["unsupported content encoding`,
+		},
+		{
+			name:       "validate",
+			handler:    func(h *Handlers) http.HandlerFunc { return h.DatadogValidate },
+			wantStatus: http.StatusOK,
+			wantBody:   `{"valid":true}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+
+			var mu sync.Mutex
+			var tenants []string
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(len(tt.wantTenants))
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant:  config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Datadog: config.Datadog{Enabled: true, TenantTag: "tenant"},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(h)(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+			assert.ElementsMatch(t, tt.wantTenants, tenants)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/debug"
+)
+
+// debugHeader is the response header describing how the request was processed, one value per tenant of each signal.
+const debugHeader = "X-Proxy-Debug"
+
+// debugWriter adds the debug report to the response headers before they are written.
+type debugWriter struct {
+	http.ResponseWriter
+	report  *debug.Report
+	written bool
+}
+
+// WriteHeader adds the debug report to the headers before writing them.
+func (d *debugWriter) WriteHeader(statusCode int) {
+	d.addHeaders()
+	d.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write adds the debug report to the headers before writing the body, in case the headers were not written yet.
+func (d *debugWriter) Write(b []byte) (int, error) {
+	d.addHeaders()
+	return d.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying response writer.
+func (d *debugWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// addHeaders adds the entries of the debug report to the response headers, once.
+func (d *debugWriter) addHeaders() {
+	if d.written {
+		return
+	}
+	d.written = true
+
+	for _, entry := range d.report.Entries() {
+		d.Header().Add(debugHeader, entry.String())
+	}
+}
+
+// withDebug echoes how the request was processed in the response headers when the client presents the debug token.
+//
+// Requests without the token are handled as is, so the report is never collected or disclosed to other clients.
+func (h *Handlers) withDebug(next http.Handler) http.Handler {
+	token := []byte(h.config.Debug.Token)
+	if len(token) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := r.Header.Get(h.config.Debug.Header)
+		if presented == "" || subtle.ConstantTimeCompare([]byte(presented), token) != 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		report := &debug.Report{}
+		next.ServeHTTP(&debugWriter{ResponseWriter: w, report: report}, r.WithContext(debug.NewContext(r.Context(), report)))
+	})
+}
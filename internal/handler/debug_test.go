@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestWithDebug(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		presented   string
+		wantHeaders []string
+	}{
+		{
+			name:      "echoes the report for the debug token",
+			token:     "secret",
+			presented: "secret",
+			wantHeaders: []string{
+				"signal=logs; tenant=; resources=1; outcome=no-tenant",
+				"signal=logs; tenant=tenant-a; resources=2; outcome=202",
+				"signal=logs; tenant=tenant-b; resources=1; outcome=202",
+			},
+		},
+		{
+			name:      "ignores a wrong token",
+			token:     "secret",
+			presented: "guess",
+		},
+		{
+			name:      "disabled without a token",
+			presented: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(*http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+			}).Times(2)
+
+			h := newTestHandlers(t, &config.Config{
+				Debug:  config.Debug{Header: "X-Proxy-Debug-Token", Token: tt.token},
+				Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID", Format: "%s"},
+				Logs:   config.Endpoint{Address: "http://localhost:3100"},
+			}, client)
+
+			body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{
+				{Resource: testResource("tenant-a")},
+				{Resource: testResource("tenant-a")},
+				{Resource: testResource("tenant-b")},
+				{},
+			}})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			req.Header.Set("X-Proxy-Debug-Token", tt.presented)
+			rec := httptest.NewRecorder()
+
+			h.withDebug(http.HandlerFunc(h.Logs)).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+			assert.Equal(t, tt.wantHeaders, rec.Header().Values(debugHeader))
+		})
+	}
+}
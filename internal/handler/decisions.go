@@ -0,0 +1,79 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+)
+
+// decisionsKeepAlive is the interval of the comments keeping an idle decisions stream open through proxies.
+const decisionsKeepAlive = 15 * time.Second
+
+// AdminDecisions handles requests streaming the sampled routing decisions of the processors as server-sent events,
+// optionally filtered by the signal and tenant query parameters, until the client goes away.
+func (h *Handlers) AdminDecisions(w http.ResponseWriter, r *http.Request) {
+	signal, tenant := r.URL.Query().Get("signal"), r.URL.Query().Get("tenant")
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the write timeout of the server, which is not supported by every writer
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	subscription := h.decisions.Subscribe()
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		logger.Error(r.Context(), err.Error())
+		return
+	}
+
+	keepAlive := time.NewTicker(decisionsKeepAlive)
+	defer keepAlive.Stop()
+
+	var dropped uint64
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case decision := <-subscription.C:
+			if (signal != "" && decision.Signal != signal) || (tenant != "" && decision.Tenant != tenant) {
+				continue
+			}
+			if n := subscription.Dropped(); n > dropped {
+				err = writeEvent(w, "dropped", map[string]uint64{"dropped": n - dropped})
+				dropped = n
+			}
+			if err == nil {
+				err = writeEvent(w, "decision", decision)
+			}
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			logger.Debug(r.Context(), "decisions stream closed: "+err.Error())
+			return
+		}
+	}
+}
+
+// writeEvent writes the value as a server-sent event of the given type.
+func writeEvent(w io.Writer, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
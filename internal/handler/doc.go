@@ -12,5 +12,6 @@
 //   - Returns appropriate HTTP status codes and error responses
 //
 // The package also includes a health check endpoint at /healthz for monitoring
-// the service's operational status.
+// the service's operational status, and a readiness endpoint at /ready reporting
+// the health of each signal pipeline.
 package handler
@@ -0,0 +1,48 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/integration"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/supportbundle"
+)
+
+// Features reports the build of the proxy and the subsystems it was built with and enabled.
+type Features struct {
+	Version  supportbundle.Version `json:"version"`
+	Features []integration.Feature `json:"features"`
+}
+
+// features returns the build and the subsystems of the proxy sorted by name: the optional integrations, which may be
+// left out of the binary by build tags, and the subsystems always compiled in.
+func (h *Handlers) features() Features {
+	cfg := h.config
+	features := integration.Features(cfg)
+	for name, enabled := range map[string]bool{
+		"async-dispatch": cfg.Dispatch.Mode == config.DispatchAsync,
+		"datadog":        cfg.Datadog.Enabled,
+		"grpc":           cfg.GRPC.Address != "",
+		"grpc-web":       cfg.HTTP.GRPCWeb,
+		"heartbeats":     cfg.Ingest.Heartbeats,
+		"influx":         cfg.Influx.Enabled,
+		"jaeger":         cfg.Jaeger.Enabled,
+		"loki-push":      cfg.LokiPush.Enabled,
+		"profiles":       cfg.Profiles.Address != "",
+		"signatures":     h.signatures != nil,
+		"stream-ingest":  cfg.StreamIngest.Enabled,
+		"strict":         cfg.Ingest.Strict,
+		"warmup":         cfg.Warmup.Enabled,
+		"zipkin":         cfg.Zipkin.Enabled,
+	} {
+		features = append(features, integration.Feature{Name: name, Compiled: true, Enabled: enabled})
+	}
+	slices.SortFunc(features, func(a, b integration.Feature) int { return strings.Compare(a.Name, b.Name) })
+
+	return Features{
+		Version:  supportbundle.BuildVersion(cfg.Service.Name, cfg.Service.Version, cfg.Service.InstanceID),
+		Features: features,
+	}
+}
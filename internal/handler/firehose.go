@@ -0,0 +1,109 @@
+//go:build !minimal || firehose
+
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/firehose"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errInvalidAccessKey = errors.New("invalid access key")
+
+func init() {
+	integrationRoutes = append(integrationRoutes, func(h *Handlers) []route {
+		if !h.config.Firehose.Enabled {
+			return nil
+		}
+		converter := firehose.New(h.config.Tenant.Label, h.config.Firehose.TenantAttribute)
+		return []route{{pattern: "POST /aws/firehose", handler: h.firehoseHandler(converter)}}
+	})
+}
+
+// firehoseHandler returns the handler of Amazon Data Firehose HTTP endpoint delivery requests, converting the records,
+// raw or CloudWatch Logs subscription payloads, into logs with the converter.
+//
+// The access key of the request must match the configured one when set. The tenant is read from the configured common
+// attribute of the delivery stream, falling back to the tenant header of the request.
+func (h *Handlers) firehoseHandler(converter *firehose.Converter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = h.withSender(r)
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.String(signalTypeAttrKey, "logs"))
+
+		requestID := r.Header.Get(firehose.RequestIDHeader)
+		if accessKey := h.config.Firehose.AccessKey; accessKey != "" {
+			key := r.Header.Get(firehose.AccessKeyHeader)
+			if subtle.ConstantTimeCompare([]byte(key), []byte(accessKey)) != 1 {
+				writeFirehoseError(ctx, w, span, requestID, http.StatusUnauthorized, errInvalidAccessKey)
+				return
+			}
+		}
+
+		common, err := firehose.ParseCommonAttributes(r.Header.Get(firehose.CommonAttributesHeader))
+		if err != nil {
+			writeFirehoseError(ctx, w, span, requestID, http.StatusBadRequest, err)
+			return
+		}
+
+		b, err := h.readSignalBody(w, r, "logs")
+		if errors.Is(err, errBodyTooLarge) {
+			return
+		}
+		if err != nil {
+			writeFirehoseError(ctx, w, span, requestID, http.StatusBadRequest, err)
+			return
+		}
+
+		request, err := firehose.Parse(b, h.boundedBodyBytes("logs"))
+		if err != nil {
+			writeFirehoseError(ctx, w, span, requestID, http.StatusBadRequest, err)
+			return
+		}
+		if request.RequestID != "" {
+			requestID = request.RequestID
+		}
+
+		resources := converter.ResourceLogs(request, common, r.Header.Get(h.config.Tenant.Header), time.Now())
+		if err := h.IngestLogs(ctx, resources); err != nil {
+			writeFirehoseError(ctx, w, span, requestID, h.processStatus(err), err)
+			return
+		}
+
+		span.SetStatus(codes.Ok, "processed successfully")
+		writeFirehoseResponse(w, http.StatusOK, firehose.Response{RequestID: requestID, Timestamp: time.Now().UnixMilli()})
+	}
+}
+
+// writeFirehoseError records the error and writes it as the error message of the response, Firehose retrying the
+// delivery until its retry duration is exhausted.
+func writeFirehoseError(
+	ctx context.Context, w http.ResponseWriter, span trace.Span, requestID string, status int, err error,
+) {
+	logger.Error(ctx, err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	writeFirehoseResponse(w, status, firehose.Response{
+		RequestID:    requestID,
+		Timestamp:    time.Now().UnixMilli(),
+		ErrorMessage: err.Error(),
+	})
+}
+
+// writeFirehoseResponse writes the response as JSON.
+func writeFirehoseResponse(w http.ResponseWriter, status int, response firehose.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,199 @@
+//go:build !minimal || firehose
+
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/firehose"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFirehose(t *testing.T) {
+	var subscription bytes.Buffer
+	writer := gzip.NewWriter(&subscription)
+	_, err := writer.Write([]byte(`{"messageType":"DATA_MESSAGE","owner":"123456789012","logGroup":"/aws/lambda/app",` +
+		`"logStream":"stream-1","logEvents":[{"id":"1","timestamp":1700000000001,"message":"hello"}]}`))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	body := func(records ...[]byte) string {
+		data := make([]map[string][]byte, 0, len(records))
+		for _, record := range records {
+			data = append(data, map[string][]byte{"data": record})
+		}
+		b, err := json.Marshal(map[string]any{"requestId": "request-1", "timestamp": 1700000000000, "records": data})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	tests := []struct {
+		name             string
+		body             string
+		accessKey        string
+		commonAttributes string
+		orgID            string
+		strict           bool
+		backendStatus    int
+		wantTenants      []string
+		wantStatus       int
+	}{
+		{
+			name:             "subscription payload and raw record",
+			body:             body(subscription.Bytes(), []byte("raw line")),
+			accessKey:        "secret",
+			commonAttributes: `{"commonAttributes":{"tenant":"tenant-b"}}`,
+			wantTenants:      []string{"tenant-b"},
+			wantStatus:       http.StatusOK,
+		},
+		{
+			name:        "tenant from header",
+			body:        body([]byte("raw line")),
+			accessKey:   "secret",
+			orgID:       "tenant-a",
+			wantTenants: []string{"tenant-a"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "default tenant",
+			body:        body([]byte("raw line")),
+			accessKey:   "secret",
+			wantTenants: []string{"default"},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:          "backend unavailable",
+			body:          body([]byte("raw line")),
+			accessKey:     "secret",
+			orgID:         "tenant-a",
+			backendStatus: http.StatusServiceUnavailable,
+			wantTenants:   []string{"tenant-a"},
+			wantStatus:    http.StatusInternalServerError,
+		},
+		{
+			name:          "backend unavailable in strict mode",
+			body:          body([]byte("raw line")),
+			accessKey:     "secret",
+			orgID:         "tenant-a",
+			strict:        true,
+			backendStatus: http.StatusServiceUnavailable,
+			wantTenants:   []string{"tenant-a"},
+			wantStatus:    http.StatusServiceUnavailable,
+		},
+		{
+			name:       "invalid access key",
+			body:       body([]byte("raw line")),
+			accessKey:  "other",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:             "invalid common attributes",
+			body:             body([]byte("raw line")),
+			accessKey:        "secret",
+			commonAttributes: "tenant=tenant-b",
+			wantStatus:       http.StatusBadRequest,
+		},
+		{
+			name:       "invalid body",
+			body:       `{"records":[{"data":"!"}]}`,
+			accessKey:  "secret",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+
+			var mu sync.Mutex
+			var tenants []string
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				data := &logpb.LogsData{}
+				require.NoError(t, proto.Unmarshal(forwarded, data))
+				assert.NotEmpty(t, data.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords())
+
+				mu.Lock()
+				tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+				mu.Unlock()
+				return &http.Response{StatusCode: cmp.Or(tt.backendStatus, http.StatusOK), Body: http.NoBody}, nil
+			}).Times(len(tt.wantTenants))
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Firehose: config.Firehose{Enabled: true, AccessKey: "secret", TenantAttribute: "tenant"},
+				Ingest:   config.Ingest{Strict: tt.strict},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/aws/firehose", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(firehose.RequestIDHeader, "request-1")
+			req.Header.Set(firehose.AccessKeyHeader, tt.accessKey)
+			if tt.commonAttributes != "" {
+				req.Header.Set(firehose.CommonAttributesHeader, tt.commonAttributes)
+			}
+			if tt.orgID != "" {
+				req.Header.Set("X-Scope-OrgID", tt.orgID)
+			}
+			rec := httptest.NewRecorder()
+			h.RegisterIntegrations(t.Context())
+			h.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.ElementsMatch(t, tt.wantTenants, tenants)
+
+			var response firehose.Response
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, "request-1", response.RequestID)
+			assert.NotZero(t, response.Timestamp)
+			assert.Equal(t, tt.wantStatus != http.StatusOK, response.ErrorMessage != "")
+		})
+	}
+}
+
+func TestFirehose_MaxBodyBytes(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		HTTP:     config.Listener{MaxBodyBytes: 16},
+		Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Firehose: config.Firehose{Enabled: true},
+	}, processor.NewMockClient(gomock.NewController(t)))
+	h.RegisterIntegrations(t.Context())
+
+	req := httptest.NewRequest(http.MethodPost, "/aws/firehose", bytes.NewReader([]byte(`{"requestId":"1","records":[]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+
+	// Bodies over the limit are answered like OTLP ones, without reaching the backend
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, "close", rec.Header().Get("Connection"))
+}
+
+func TestFirehose_Disabled(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, processor.NewMockClient(gomock.NewController(t)))
+	h.RegisterIntegrations(t.Context())
+
+	req := httptest.NewRequest(http.MethodPost, "/aws/firehose", bytes.NewReader([]byte(`{"records":[]}`)))
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
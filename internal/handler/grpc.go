@@ -0,0 +1,294 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/health"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/ipfamily"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// defaultHealthWatchInterval is the interval at which the health watches sample the status when none is configured.
+const defaultHealthWatchInterval = 5 * time.Second
+
+// grpcServices maps the OTLP gRPC services to their signal, used to report the serving status of each service.
+var grpcServices = map[string]string{
+	collogspb.LogsService_ServiceDesc.ServiceName:       "logs",
+	colmetricspb.MetricsService_ServiceDesc.ServiceName: "metrics",
+	coltracepb.TraceService_ServiceDesc.ServiceName:     "traces",
+}
+
+// NewGRPCServer creates a new gRPC server serving the OTLP Export services, the gRPC health service and, when enabled,
+// server reflection, using the provided TLS configuration when it is not nil.
+//
+// Messages are limited to the configured maximum size, or the gRPC default of 4MB when it is not set.
+func (h *Handlers) NewGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	var opts []grpc.ServerOption
+	if h.config.GRPC.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(h.config.GRPC.MaxRecvMsgSize))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	collogspb.RegisterLogsServiceServer(server, &logsService{h: h})
+	colmetricspb.RegisterMetricsServiceServer(server, &metricsService{h: h})
+	coltracepb.RegisterTraceServiceServer(server, &traceService{h: h})
+	healthpb.RegisterHealthServer(server, &healthService{h: h})
+	if h.config.GRPC.Reflection {
+		reflection.Register(server)
+	}
+
+	return server
+}
+
+// ListenGRPC listens on the configured gRPC address, with the IP family of the HTTP listener.
+func (h *Handlers) ListenGRPC() (net.Listener, error) {
+	return ipfamily.Listen(h.config.HTTP.IPFamily, h.config.GRPC.Address)
+}
+
+// logsService implements the OTLP LogsService.
+type logsService struct {
+	collogspb.UnimplementedLogsServiceServer
+	h *Handlers
+}
+
+// Export forwards the logs of an export request.
+func (s *logsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	response, err := export(ctx, s.h, "logs", &s.h.logsProcessor, req.GetResourceLogs(), s.h.dedupLogRecords)
+	if err != nil {
+		return nil, err
+	}
+	return response.(*collogspb.ExportLogsServiceResponse), nil
+}
+
+// metricsService implements the OTLP MetricsService.
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	h *Handlers
+}
+
+// Export forwards the metrics of an export request.
+func (s *metricsService) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	response, err := export(ctx, s.h, "metrics", &s.h.metricsProcessor, req.GetResourceMetrics(), s.h.allowMetricAttributes)
+	if err != nil {
+		return nil, err
+	}
+	return response.(*colmetricspb.ExportMetricsServiceResponse), nil
+}
+
+// traceService implements the OTLP TraceService.
+type traceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+	h *Handlers
+}
+
+// Export forwards the spans of an export request.
+func (s *traceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	response, err := export(ctx, s.h, "traces", &s.h.tracesProcessor, req.GetResourceSpans(), s.h.dedupSpans)
+	if err != nil {
+		return nil, err
+	}
+	return response.(*coltracepb.ExportTraceServiceResponse), nil
+}
+
+// export forwards the resources of an export request through the tenant partitioning pipeline, returning the export
+// response with a partial success when records were not forwarded.
+func export[T processor.ResourceData](
+	ctx context.Context,
+	h *Handlers,
+	signal string,
+	p *processor.Processor[T],
+	resources []T,
+	transforms ...func(ctx context.Context, tenant string, resources []T),
+) (protobuf.Message, error) {
+	ctx = requestmeta.NewContext(ctx, grpcMetadata(ctx))
+	ctx, span := h.tracer.Start(ctx, "grpc.export", trace.WithAttributes(append(
+		requestmeta.FromContext(ctx).Attributes(), attribute.String(signalTypeAttrKey, signal))...,
+	))
+	defer span.End()
+
+	if len(resources) == 0 && h.rejectEmptyPayload(ctx, signal) {
+		span.RecordError(errEmptyPayload)
+		span.SetStatus(otelcodes.Error, errEmptyPayload.Error())
+		return nil, status.Error(codes.InvalidArgument, errEmptyPayload.Error())
+	}
+
+	partial, err := process(ctx, h, signal, p, resources, transforms...)
+	if err != nil {
+		logger.Error(ctx, err.Error(), requestmeta.FromContext(ctx).Attributes()...)
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+		return nil, exportError(err)
+	}
+
+	span.SetStatus(otelcodes.Ok, "processed successfully")
+	return exportResponse(signal, partial), nil
+}
+
+// grpcMetadata returns the metadata of the client of a gRPC call.
+func grpcMetadata(ctx context.Context) requestmeta.Metadata {
+	var metadata requestmeta.Metadata
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		metadata.ClientAddress = p.Addr.String()
+		if host, _, err := net.SplitHostPort(metadata.ClientAddress); err == nil {
+			metadata.ClientAddress = host
+		}
+	}
+	if userAgent := grpcmd.ValueFromIncomingContext(ctx, "user-agent"); len(userAgent) > 0 {
+		metadata.UserAgent = userAgent[0]
+	}
+	return metadata
+}
+
+// exportError returns the gRPC status of data that could not be forwarded, carrying the delay the backend asked to
+// wait before retrying as a RetryInfo detail of retryable statuses, as required by the OTLP specification.
+func exportError(err error) error {
+	code := codes.Code(grpcStatus(otlpStatus(err)))
+	st := status.New(code, err.Error())
+
+	delay := retryAfter(err)
+	if delay <= 0 || (code != codes.ResourceExhausted && code != codes.Unavailable) {
+		return st.Err()
+	}
+
+	detailed, detailErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// healthService implements the gRPC health checking protocol, reporting the health of each signal pipeline.
+type healthService struct {
+	healthpb.UnimplementedHealthServer
+	h *Handlers
+}
+
+// Check returns the serving status of an OTLP service, or the readiness of the proxy for the empty service name.
+func (s *healthService) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus, ok := s.status(req.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// List returns the serving status of the proxy, under the empty service name, and of each OTLP service.
+func (s *healthService) List(context.Context, *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	statuses := make(map[string]*healthpb.HealthCheckResponse, len(grpcServices)+1)
+	for _, service := range append(slices.Collect(maps.Keys(grpcServices)), "") {
+		servingStatus, _ := s.status(service)
+		statuses[service] = &healthpb.HealthCheckResponse{Status: servingStatus}
+	}
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch streams the serving status of a service, sending it first and then whenever it changes. The status is sampled
+// at the health watch interval, as the health of the pipelines is only known when data is forwarded. Unknown services
+// are reported as SERVICE_UNKNOWN rather than failing the call, as the health checking protocol requires.
+func (s *healthService) Watch(
+	req *healthpb.HealthCheckRequest,
+	stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse],
+) error {
+	interval := s.h.config.GRPC.HealthWatchInterval
+	if interval <= 0 {
+		interval = defaultHealthWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_ServingStatus(-1)
+	for {
+		servingStatus, ok := s.status(req.GetService())
+		if !ok {
+			servingStatus = healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		}
+		if servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			last = servingStatus
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// status returns the serving status of an OTLP service, or the readiness of the proxy for the empty service name. It
+// returns false for unknown services.
+func (s *healthService) status(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if service == "" {
+		if s.h.health.Report().Ready {
+			return healthpb.HealthCheckResponse_SERVING, true
+		}
+		return healthpb.HealthCheckResponse_NOT_SERVING, true
+	}
+
+	signal, ok := grpcServices[service]
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+	}
+	if s.h.health.Status(signal) == health.StatusServing {
+		return healthpb.HealthCheckResponse_SERVING, true
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING, true
+}
+
+// otlpStatus returns the OTLP/HTTP status code of data that could not be forwarded, following the OTLP retry
+// semantics: requests throttled by the backend or the outbound rate limits are answered with 429, and data rejected by
+// the backend or refused by data residency or the outbound header limits with 400 so clients do not retry it, every
+// other failure is answered with 503 so clients retry later. When several tenants failed, a retryable failure of any
+// of them makes the request retryable.
+func otlpStatus(err error) int {
+	if errors.Is(err, circuit.ErrOpen) || errors.Is(err, processor.ErrBackendUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, processor.ErrThrottled) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, processor.ErrResidency) || errors.Is(err, processor.ErrHeaderLimit) {
+		return http.StatusBadRequest
+	}
+
+	var statusErr *processor.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode >= http.StatusBadRequest && statusErr.StatusCode < http.StatusInternalServerError {
+			return http.StatusBadRequest
+		}
+	}
+	return http.StatusServiceUnavailable
+}
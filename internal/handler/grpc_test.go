@@ -0,0 +1,387 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcmd "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCConn serves the gRPC server of the handlers over an in-memory listener and returns a connection to it.
+func newTestGRPCConn(t *testing.T, h *Handlers) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := h.NewGRPCServer(nil)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestGRPCExport(t *testing.T) {
+	tests := []struct {
+		name           string
+		backendStatus  int
+		retryAfter     string
+		wantCode       codes.Code
+		wantRetryDelay time.Duration
+	}{
+		{name: "accepted", backendStatus: http.StatusOK, wantCode: codes.OK},
+		{name: "rejected by the backend", backendStatus: http.StatusBadRequest, wantCode: codes.InvalidArgument},
+		{name: "throttled by the backend", backendStatus: http.StatusTooManyRequests, wantCode: codes.ResourceExhausted},
+		{name: "backend unavailable", backendStatus: http.StatusBadGateway, wantCode: codes.Unavailable},
+		{
+			name:           "throttled by the backend with a retry delay",
+			backendStatus:  http.StatusTooManyRequests,
+			retryAfter:     "12",
+			wantCode:       codes.ResourceExhausted,
+			wantRetryDelay: 12 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "tenant-a", req.Header.Get("X-Scope-OrgID"))
+				header := http.Header{}
+				if tt.retryAfter != "" {
+					header.Set("Retry-After", tt.retryAfter)
+				}
+				return &http.Response{StatusCode: tt.backendStatus, Header: header, Body: http.NoBody}, nil
+			})
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+			conn := newTestGRPCConn(t, h)
+
+			response, err := coltracepb.NewTraceServiceClient(conn).Export(context.Background(), &coltracepb.ExportTraceServiceRequest{
+				ResourceSpans: []*tracepb.ResourceSpans{{Resource: testResource("tenant-a")}},
+			})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode == codes.OK {
+				assert.Nil(t, response.GetPartialSuccess())
+			}
+
+			var retryDelay time.Duration
+			for _, detail := range status.Convert(err).Details() {
+				if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+					retryDelay = retryInfo.GetRetryDelay().AsDuration()
+				}
+			}
+			assert.Equal(t, tt.wantRetryDelay, retryDelay)
+		})
+	}
+}
+
+func TestGRPCExport_PartialSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, client)
+	conn := newTestGRPCConn(t, h)
+
+	response, err := collogspb.NewLogsServiceClient(conn).Export(context.Background(), &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logpb.ResourceLogs{
+			{Resource: testResource("tenant-a")},
+			{
+				Resource:  &resourcepb.Resource{},
+				ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{}, {}}}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), response.GetPartialSuccess().GetRejectedLogRecords())
+	assert.Equal(t, "dropped 1 resources without a tenant from services unknown_service",
+		response.GetPartialSuccess().GetErrorMessage())
+}
+
+func TestGRPCExport_EmptyPayload(t *testing.T) {
+	tests := []struct {
+		name         string
+		emptyPayload string
+		wantCode     codes.Code
+	}{
+		{name: "accepted", emptyPayload: config.EmptyPayloadAccept, wantCode: codes.OK},
+		{name: "rejected", emptyPayload: config.EmptyPayloadReject, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			client := processor.NewMockClient(gomock.NewController(t))
+			h, err := New(&config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Ingest: config.Ingest{EmptyPayload: tt.emptyPayload},
+			}, http.NewServeMux(), client, client, client, client,
+				sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+			)
+			require.NoError(t, err)
+			conn := newTestGRPCConn(t, h)
+
+			_, err = collogspb.NewLogsServiceClient(conn).Export(context.Background(), &collogspb.ExportLogsServiceRequest{})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+
+			// Empty exports are counted per signal and client, whether they are accepted or rejected
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.Background(), &rm))
+			var dataPoints []metricdata.DataPoint[int64]
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name == "otel_lgtm_proxy_empty_payloads_total" {
+						dataPoints = append(dataPoints, m.Data.(metricdata.Sum[int64]).DataPoints...)
+					}
+				}
+			}
+			require.Len(t, dataPoints, 1)
+			assert.Equal(t, int64(1), dataPoints[0].Value)
+			signal, _ := dataPoints[0].Attributes.Value(attribute.Key(signalTypeAttrKey))
+			assert.Equal(t, "logs", signal.AsString())
+			address, _ := dataPoints[0].Attributes.Value(attribute.Key(clientAddressAttrKey))
+			assert.Equal(t, "bufconn", address.AsString())
+		})
+	}
+}
+
+func TestGRPCHealth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Ready:  config.Ready{FailureThreshold: 1},
+	}, client)
+	conn := newTestGRPCConn(t, h)
+	ctx := context.Background()
+
+	_, err := collogspb.NewLogsServiceClient(conn).Export(ctx, &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}},
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	tests := []struct {
+		service  string
+		want     healthpb.HealthCheckResponse_ServingStatus
+		wantCode codes.Code
+	}{
+		{service: "", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "opentelemetry.proto.collector.logs.v1.LogsService", want: healthpb.HealthCheckResponse_NOT_SERVING},
+		{service: "opentelemetry.proto.collector.trace.v1.TraceService", want: healthpb.HealthCheckResponse_SERVING},
+		{service: "unknown.Service", wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			response, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: tt.service})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.want, response.GetStatus())
+		})
+	}
+}
+
+func TestGRPCHealthList(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{}, processor.NewMockClient(gomock.NewController(t)))
+	conn := newTestGRPCConn(t, h)
+
+	response, err := healthpb.NewHealthClient(conn).List(context.Background(), &healthpb.HealthListRequest{})
+	require.NoError(t, err)
+
+	statuses := map[string]healthpb.HealthCheckResponse_ServingStatus{}
+	for service, status := range response.GetStatuses() {
+		statuses[service] = status.GetStatus()
+	}
+	assert.Equal(t, map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"": healthpb.HealthCheckResponse_SERVING,
+		"opentelemetry.proto.collector.logs.v1.LogsService":       healthpb.HealthCheckResponse_SERVING,
+		"opentelemetry.proto.collector.metrics.v1.MetricsService": healthpb.HealthCheckResponse_SERVING,
+		"opentelemetry.proto.collector.trace.v1.TraceService":     healthpb.HealthCheckResponse_SERVING,
+	}, statuses)
+}
+
+func TestGRPCHealthWatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil)
+
+	h := newTestHandlers(t, &config.Config{
+		GRPC:   config.GRPCListener{HealthWatchInterval: 10 * time.Millisecond},
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Ready:  config.Ready{FailureThreshold: 1},
+	}, client)
+	conn := newTestGRPCConn(t, h)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	unknown, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	require.NoError(t, err)
+	response, err := unknown.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, response.GetStatus())
+
+	watch, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{
+		Service: "opentelemetry.proto.collector.logs.v1.LogsService",
+	})
+	require.NoError(t, err)
+	response, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatus())
+
+	_, err = collogspb.NewLogsServiceClient(conn).Export(ctx, &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}},
+	})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	response, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.GetStatus())
+}
+
+func TestGRPCReflection(t *testing.T) {
+	tests := []struct {
+		name       string
+		reflection bool
+		wantCode   codes.Code
+	}{
+		{name: "enabled", reflection: true},
+		{name: "disabled", wantCode: codes.Unimplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(t, &config.Config{GRPC: config.GRPCListener{Reflection: tt.reflection}},
+				processor.NewMockClient(gomock.NewController(t)))
+			conn := newTestGRPCConn(t, h)
+
+			stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+			}))
+			response, err := stream.Recv()
+			require.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			var services []string
+			for _, service := range response.GetListServicesResponse().GetService() {
+				services = append(services, service.GetName())
+			}
+			assert.Subset(t, services, []string{
+				"grpc.health.v1.Health",
+				"opentelemetry.proto.collector.logs.v1.LogsService",
+				"opentelemetry.proto.collector.metrics.v1.MetricsService",
+				"opentelemetry.proto.collector.trace.v1.TraceService",
+			})
+		})
+	}
+}
+
+func TestOTLPStatus(t *testing.T) {
+	tenantErr := func(tenant string, err error) error { return fmt.Errorf("tenant %s: %w", tenant, err) }
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "rejected by the backend",
+			err:  tenantErr("a", &processor.StatusError{StatusCode: http.StatusBadRequest}),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "throttled by the backend",
+			err:  tenantErr("a", &processor.StatusError{StatusCode: http.StatusTooManyRequests}),
+			want: http.StatusTooManyRequests,
+		},
+		{
+			name: "backend unavailable",
+			err:  tenantErr("a", fmt.Errorf("%w: connection refused", processor.ErrBackendUnavailable)),
+			want: http.StatusServiceUnavailable,
+		},
+		{
+			name: "unknown failure",
+			err:  errors.New("failed to marshal data"),
+			want: http.StatusServiceUnavailable,
+		},
+		{
+			name: "retryable failure of one of the tenants",
+			err: fmt.Errorf("%w: %w", processor.ErrPartialFailure, errors.Join(
+				tenantErr("a", processor.ErrResidency),
+				tenantErr("b", &processor.StatusError{StatusCode: http.StatusBadGateway}),
+			)),
+			want: http.StatusServiceUnavailable,
+		},
+		{
+			name: "throttled and rejected tenants",
+			err: errors.Join(
+				tenantErr("a", &processor.StatusError{StatusCode: http.StatusBadRequest}),
+				tenantErr("b", fmt.Errorf("%w: outbound rate limit exceeded", processor.ErrThrottled)),
+			),
+			want: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, otlpStatus(tt.err))
+		})
+	}
+}
+
+func TestGRPCMetadata(t *testing.T) {
+	assert.Equal(t, requestmeta.Metadata{}, grpcMetadata(t.Context()))
+
+	ctx := peer.NewContext(t.Context(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4317}})
+	ctx = grpcmd.NewIncomingContext(ctx, grpcmd.Pairs("user-agent", "grpc-go/1.81.1"))
+	assert.Equal(t, requestmeta.Metadata{ClientAddress: "10.0.0.1", UserAgent: "grpc-go/1.81.1"}, grpcMetadata(ctx))
+}
@@ -0,0 +1,213 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+
+	grpcWebFrameHeaderSize = 5
+	grpcWebFrameCompressed = 0x01
+	grpcWebFrameTrailer    = 0x80
+)
+
+// gRPC status codes returned to gRPC-Web clients.
+const (
+	grpcStatusOK                = 0
+	grpcStatusUnknown           = 2
+	grpcStatusInvalidArgument   = 3
+	grpcStatusPermissionDenied  = 7
+	grpcStatusResourceExhausted = 8
+	grpcStatusUnimplemented     = 12
+	grpcStatusInternal          = 13
+	grpcStatusUnavailable       = 14
+	grpcStatusUnauthenticated   = 16
+)
+
+var errGRPCWebCompressed = errors.New("compressed grpc-web messages are not supported")
+
+// bufferedResponse buffers the response of an OTLP/HTTP handler called with a translated request.
+type bufferedResponse struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// Header returns the header map of the buffered response.
+func (g *bufferedResponse) Header() http.Header {
+	return g.header
+}
+
+// Write buffers the response body.
+func (g *bufferedResponse) Write(b []byte) (int, error) {
+	if g.statusCode == 0 {
+		g.statusCode = http.StatusOK
+	}
+	return g.body.Write(b)
+}
+
+// WriteHeader records the response status code.
+func (g *bufferedResponse) WriteHeader(statusCode int) {
+	if g.statusCode == 0 {
+		g.statusCode = statusCode
+	}
+}
+
+// GRPCWeb translates gRPC-Web calls of an OTLP Export service to the given OTLP/HTTP handler.
+//
+// Both the binary (application/grpc-web+proto) and the base64 (application/grpc-web-text+proto) variants are
+// supported, the export request is forwarded to the handler as a protobuf payload and its response is framed back
+// with the gRPC status in the trailers.
+func (h *Handlers) GRPCWeb(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		contentType := r.Header.Get("Content-Type")
+		text := strings.HasPrefix(contentType, contentTypeGRPCWebText)
+		if !text && !strings.HasPrefix(contentType, contentTypeGRPCWeb) {
+			http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		message, err := readGRPCWebMessage(r.Body, text)
+		if err != nil {
+			logger.Error(ctx, err.Error())
+			status := grpcStatusInvalidArgument
+			if errors.Is(err, errGRPCWebCompressed) {
+				status = grpcStatusUnimplemented
+			}
+			writeGRPCWeb(w, contentType, text, nil, status, err.Error())
+			return
+		}
+
+		// Export requests share the wire format of the OTLP data messages accepted by the handlers.
+		req := r.Clone(ctx)
+		req.Body = io.NopCloser(bytes.NewReader(message))
+		req.ContentLength = int64(len(message))
+		req.Header.Set("Content-Type", proto.ContentType(config.EncodingProtobuf))
+
+		response := &bufferedResponse{header: make(http.Header)}
+		next(response, req)
+
+		status := grpcStatus(response.statusCode)
+		if status != grpcStatusOK {
+			writeGRPCWeb(w, contentType, text, nil, status, errorMessage(response))
+			return
+		}
+
+		// A successful export either carries a partial success response or an empty export response.
+		writeGRPCWeb(w, contentType, text, response.body.Bytes(), status, "")
+	}
+}
+
+// errorMessage returns the error message of a failed export, read from the status body written in strict mode or the
+// plain text body otherwise.
+func errorMessage(response *bufferedResponse) string {
+	if response.header.Get("Content-Type") == proto.ContentType(config.EncodingProtobuf) {
+		status := &spb.Status{}
+		if err := protobuf.Unmarshal(response.body.Bytes(), status); err == nil {
+			return status.GetMessage()
+		}
+	}
+	return strings.TrimSpace(response.body.String())
+}
+
+// readGRPCWebMessage reads the single data frame of a gRPC-Web request.
+func readGRPCWebMessage(body io.Reader, text bool) ([]byte, error) {
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	header := make([]byte, grpcWebFrameHeaderSize)
+	if _, err := io.ReadFull(body, header); err != nil {
+		return nil, fmt.Errorf("failed to read grpc-web frame header: %w", err)
+	}
+	if header[0]&grpcWebFrameCompressed != 0 {
+		return nil, errGRPCWebCompressed
+	}
+	if header[0]&grpcWebFrameTrailer != 0 {
+		return nil, errors.New("grpc-web request does not start with a data frame")
+	}
+
+	// Read through a limited reader rather than allocating the announced length up front.
+	length := binary.BigEndian.Uint32(header[1:])
+	message, err := io.ReadAll(io.LimitReader(body, int64(length)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grpc-web message: %w", err)
+	}
+	if len(message) != int(length) {
+		return nil, fmt.Errorf("failed to read grpc-web message: %w", io.ErrUnexpectedEOF)
+	}
+
+	return message, nil
+}
+
+// writeGRPCWeb writes the gRPC-Web response, the message frame is only written for successful calls.
+func writeGRPCWeb(w http.ResponseWriter, contentType string, text bool, message []byte, status int, statusMessage string) {
+	var frames []byte
+	if status == grpcStatusOK {
+		frames = appendGRPCWebFrame(frames, 0, message)
+	}
+
+	trailer := "grpc-status: " + strconv.Itoa(status) + "\r\n"
+	if statusMessage != "" {
+		trailer += "grpc-message: " + url.PathEscape(statusMessage) + "\r\n"
+	}
+	frames = appendGRPCWebFrame(frames, grpcWebFrameTrailer, []byte(trailer))
+
+	if text {
+		frames = []byte(base64.StdEncoding.EncodeToString(frames))
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(frames)
+}
+
+// appendGRPCWebFrame appends a length-prefixed gRPC-Web frame.
+func appendGRPCWebFrame(b []byte, flags byte, payload []byte) []byte {
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// grpcStatus maps the status code of an OTLP/HTTP response to the equivalent gRPC status code.
+func grpcStatus(statusCode int) int {
+	switch {
+	case statusCode == 0 || statusCode >= 200 && statusCode < 300:
+		return grpcStatusOK
+	case statusCode == http.StatusBadRequest:
+		return grpcStatusInvalidArgument
+	case statusCode == http.StatusUnauthorized:
+		return grpcStatusUnauthenticated
+	case statusCode == http.StatusForbidden:
+		return grpcStatusPermissionDenied
+	case statusCode == http.StatusNotFound:
+		return grpcStatusUnimplemented
+	case statusCode == http.StatusTooManyRequests:
+		return grpcStatusResourceExhausted
+	case statusCode == http.StatusServiceUnavailable, statusCode == http.StatusBadGateway, statusCode == http.StatusGatewayTimeout:
+		return grpcStatusUnavailable
+	case statusCode >= 500:
+		return grpcStatusInternal
+	default:
+		return grpcStatusUnknown
+	}
+}
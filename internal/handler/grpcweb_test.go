@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCWeb(t *testing.T) {
+	message, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+	frame := appendGRPCWebFrame(nil, 0, message)
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          []byte
+		strict        bool
+		backendStatus int
+		wantStatus    string
+		wantMessage   bool
+	}{
+		{
+			name:          "binary",
+			contentType:   "application/grpc-web+proto",
+			body:          frame,
+			backendStatus: http.StatusOK,
+			wantStatus:    "grpc-status: 0\r\n",
+			wantMessage:   true,
+		},
+		{
+			name:          "text",
+			contentType:   "application/grpc-web-text+proto",
+			body:          []byte(base64.StdEncoding.EncodeToString(frame)),
+			backendStatus: http.StatusOK,
+			wantStatus:    "grpc-status: 0\r\n",
+			wantMessage:   true,
+		},
+		{
+			name:          "backend failure",
+			contentType:   "application/grpc-web+proto",
+			body:          frame,
+			backendStatus: http.StatusServiceUnavailable,
+			wantStatus:    "grpc-status: 13\r\ngrpc-message: ",
+		},
+		{
+			name:          "backend failure in strict mode",
+			contentType:   "application/grpc-web+proto",
+			body:          frame,
+			strict:        true,
+			backendStatus: http.StatusServiceUnavailable,
+			wantStatus:    "grpc-status: 14\r\ngrpc-message: tenant%20tenant-a:%20received%20non-success%20status%20code:%20503\r\n",
+		},
+		{
+			name:        "truncated frame",
+			contentType: "application/grpc-web+proto",
+			body:        frame[:len(frame)-1],
+			wantStatus:  "grpc-status: 3\r\ngrpc-message: ",
+		},
+		{
+			name:        "compressed frame",
+			contentType: "application/grpc-web+proto",
+			body:        appendGRPCWebFrame(nil, grpcWebFrameCompressed, message),
+			wantStatus:  "grpc-status: 12\r\ngrpc-message: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.backendStatus != 0 {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: tt.backendStatus, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{Strict: tt.strict},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.GRPCWeb(h.Traces)(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+
+			body := rec.Body.Bytes()
+			if tt.contentType == "application/grpc-web-text+proto" {
+				body, err = base64.StdEncoding.DecodeString(string(body))
+				require.NoError(t, err)
+			}
+
+			if tt.wantMessage {
+				require.GreaterOrEqual(t, len(body), grpcWebFrameHeaderSize)
+				assert.Equal(t, byte(0), body[0])
+				response := &coltracepb.ExportTraceServiceResponse{}
+				message, err := readGRPCWebMessage(bytes.NewReader(body), false)
+				require.NoError(t, err)
+				require.NoError(t, proto.Unmarshal(message, response))
+				body = body[grpcWebFrameHeaderSize+len(message):]
+			}
+
+			require.Greater(t, len(body), grpcWebFrameHeaderSize)
+			assert.Equal(t, byte(grpcWebFrameTrailer), body[0])
+			assert.Contains(t, string(body[grpcWebFrameHeaderSize:]), tt.wantStatus)
+		})
+	}
+}
+
+func TestGRPCWeb_UnsupportedContentType(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{}, processor.NewMockClient(gomock.NewController(t)))
+
+	req := httptest.NewRequest(http.MethodPost, "/opentelemetry.proto.collector.trace.v1.TraceService/Export", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	rec := httptest.NewRecorder()
+	h.GRPCWeb(h.Traces)(rec, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
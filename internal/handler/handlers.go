@@ -4,11 +4,27 @@ package handler
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"sync"
+	"time"
 
 	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clientpool"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/decisions"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/health"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/heartbeat"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/profilepb"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/signature"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/topk"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/transform"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/ipfamily"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
@@ -18,19 +34,51 @@ import (
 	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"golang.org/x/net/netutil"
+	"golang.org/x/net/websocket"
 )
 
 var signalTypeAttrKey = "signal.type"
 
+// defaultMaxHeaderBytes is the maximum size of the request headers when none is configured.
+const defaultMaxHeaderBytes = 1 << 20
+
 // Handlers contains the dependencies needed for all OTLP signal handlers.
 type Handlers struct {
-	config           *config.Config
-	router           *http.ServeMux
-	meter            metric.Meter
-	tracer           trace.Tracer
-	logsProcessor    processor.Processor[*logpb.ResourceLogs]
-	metricsProcessor processor.Processor[*metricpb.ResourceMetrics]
-	tracesProcessor  processor.Processor[*tracepb.ResourceSpans]
+	config                        *config.Config
+	router                        *http.ServeMux
+	meter                         metric.Meter
+	tracer                        trace.Tracer
+	logsProcessor                 processor.Processor[*logpb.ResourceLogs]
+	metricsProcessor              processor.Processor[*metricpb.ResourceMetrics]
+	tracesProcessor               processor.Processor[*tracepb.ResourceSpans]
+	profilesProcessor             processor.Processor[*profilepb.ResourceProfiles]
+	trustedProxies                []netip.Prefix
+	emptyPayloadsMetric           metric.Int64Counter
+	schemaURLsMetric              metric.Int64Counter
+	duplicatesMetric              metric.Int64Counter
+	invalidResourcesMetric        metric.Int64Counter
+	unsupportedContentTypesMetric metric.Int64Counter
+	oversizedPayloadsMetric       metric.Int64Counter
+	continueRejectionsMetric      metric.Int64Counter
+	metricAllowlist               *transform.MetricAttributeAllowlist
+	enrichment                    *transform.Enrichment
+	stats                         *stats.Tracker
+	topK                          *topk.Tracker
+	circuits                      *circuit.Overrides
+	health                        *health.Tracker
+	decisions                     *decisions.Feed
+	heartbeats                    *heartbeat.Tracker
+	asyncSaturatedMetric          metric.Int64Counter
+	signatures                    *signature.Verifier
+	signerAliases                 map[string]string
+	signatureFailuresMetric       metric.Int64Counter
+	streamConnectionsMetric       metric.Int64UpDownCounter
+	streamsMu                     sync.Mutex
+	streams                       map[*websocket.Conn]struct{}
+	routes                        []route
+	inflight                      chan struct{}
+	background                    sync.WaitGroup
 }
 
 // New creates a new Handlers instance.
@@ -40,10 +88,55 @@ func New(
 	logsClient processor.Client,
 	metricsClient processor.Client,
 	tracesClient processor.Client,
+	profilesClient processor.Client,
 	meter metric.Meter,
 	tracer trace.Tracer,
 ) (*Handlers, error) {
-	// Create logs processor
+	// Create the tracker backing the admin stats endpoint
+	tracker := stats.New(config.Service.InstanceID)
+
+	// Create the manual circuit overrides pausing the forwarding of tenants
+	circuits := circuit.New()
+	tracker.RegisterCircuits(circuitStats(circuits))
+
+	// Create the cache of the backend clients dedicated to tenants with their own certificate
+	clients := clientpool.New(config.TenantClients.CacheSize, config.TenantClients.IdleTimeout)
+
+	// Create the tracker of the health of each signal pipeline backing the readiness endpoint
+	signals := []string{"logs", "metrics", "traces"}
+	if config.Profiles.Address != "" {
+		signals = append(signals, "profiles")
+	}
+	healthTracker, err := health.New(signals, config.Ready.Policy, config.Ready.FailureThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the tracker of the tenants with the highest volume
+	topK := topk.New(&config.TopK)
+	if err := topK.RegisterMetrics(meter); err != nil {
+		return nil, err
+	}
+
+	// Create the feed of the routing decisions streamed by the admin API
+	feed := decisions.New(&config.Decisions)
+
+	// Create the tracker of the heartbeats of the authenticated senders
+	var heartbeats *heartbeat.Tracker
+	if config.Ingest.Heartbeats {
+		heartbeats = heartbeat.New()
+		if err := heartbeats.RegisterMetrics(meter); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the outbound protocols
+	if err := validateProtocols(config); err != nil {
+		return nil, err
+	}
+
+	// Create logs processor, pushing to Loki natively when configured to
+	marshalLogs, logsOpts := logsMarshaller(&config.Logs)
 	logsProcessor, err := processor.New(
 		config,
 		&config.Logs,
@@ -54,18 +147,25 @@ func New(
 		func(rl *logpb.ResourceLogs) *resourcepb.Resource {
 			return rl.GetResource()
 		},
-		func(resources []*logpb.ResourceLogs) ([]byte, error) {
-			data := &logpb.LogsData{
-				ResourceLogs: resources,
-			}
-			return proto.Marshal(data)
-		},
+		marshalLogs,
+		append([]processor.Option{
+			processor.WithStats(tracker),
+			processor.WithTopK(topK),
+			processor.WithCircuits(circuits),
+			processor.WithClientPool(clients),
+			processor.WithHealth(healthTracker),
+			processor.WithDecisions(feed),
+		}, logsOpts...)...,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create metrics processor
+	// Create metrics processor, writing to Prometheus remote write when configured to
+	marshalMetrics, metricsOpts, err := metricsMarshaller(&config.Metrics, meter)
+	if err != nil {
+		return nil, err
+	}
 	metricsProcessor, err := processor.New(
 		config,
 		&config.Metrics,
@@ -76,12 +176,15 @@ func New(
 		func(rm *metricpb.ResourceMetrics) *resourcepb.Resource {
 			return rm.GetResource()
 		},
-		func(resources []*metricpb.ResourceMetrics) ([]byte, error) {
-			data := &metricpb.MetricsData{
-				ResourceMetrics: resources,
-			}
-			return proto.Marshal(data)
-		},
+		marshalMetrics,
+		append([]processor.Option{
+			processor.WithStats(tracker),
+			processor.WithTopK(topK),
+			processor.WithCircuits(circuits),
+			processor.WithClientPool(clients),
+			processor.WithHealth(healthTracker),
+			processor.WithDecisions(feed),
+		}, metricsOpts...)...,
 	)
 	if err != nil {
 		return nil, err
@@ -102,39 +205,349 @@ func New(
 			data := &tracepb.TracesData{
 				ResourceSpans: resources,
 			}
-			return proto.Marshal(data)
+			return proto.MarshalEncoding(data, config.Traces.Encoding)
 		},
+		processor.WithStats(tracker),
+		processor.WithTopK(topK),
+		processor.WithCircuits(circuits),
+		processor.WithClientPool(clients),
+		processor.WithHealth(healthTracker),
+		processor.WithDecisions(feed),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create profiles processor, only when a profiles backend is configured since the signal is in development
+	var profilesProcessor processor.Processor[*profilepb.ResourceProfiles]
+	if config.Profiles.Address != "" {
+		p, err := newProfilesProcessor(config, profilesClient, meter, tracer,
+			processor.WithStats(tracker),
+			processor.WithTopK(topK),
+			processor.WithCircuits(circuits),
+			processor.WithClientPool(clients),
+			processor.WithHealth(healthTracker),
+			processor.WithDecisions(feed),
+		)
+		if err != nil {
+			return nil, err
+		}
+		profilesProcessor = *p
+	}
+
+	// Validate the settings of the listener, of the inbound payloads and of the dispatch of the requests
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	// Parse the proxies trusted to report the client address
+	trustedProxies, err := parseTrustedProxies(config.HTTP.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the per-tenant metric attribute allowlist
+	metricAllowlist, err := transform.NewMetricAttributeAllowlist(config.Transform.MetricAttributeAllowlist)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the enrichment stamping the ingestion time and the proxy instance onto the forwarded resources
+	enrichment := transform.NewEnrichment(
+		config.Transform.ReceivedAtAttribute,
+		config.Transform.InstanceAttribute,
+		config.Service.InstanceID,
+	)
+
+	// Create a counter for the number of inbound payloads without any resources
+	emptyPayloadsMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_empty_payloads_total",
+		metric.WithDescription("Total number of inbound payloads received without any resources"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy empty payloads counter: %w", err)
+	}
+
+	// Create a counter for the number of inbound payloads per schema URL
+	schemaURLsMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_schema_url_payloads_total",
+		metric.WithDescription("Total number of inbound payloads containing resources of each schema URL"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy schema url payloads counter: %w", err)
+	}
+
+	// Create a counter for the number of duplicate records dropped within a request
+	duplicatesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_duplicate_records_total",
+		metric.WithDescription("Total number of duplicate records dropped within a request"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy duplicate records counter: %w", err)
+	}
+
+	// Create a counter for the number of inbound resources skipped because they could not be parsed
+	invalidResourcesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_invalid_resources_total",
+		metric.WithDescription("Total number of inbound resources skipped because they could not be parsed"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy invalid resources counter: %w", err)
+	}
+
+	// Create a counter for the number of inbound payloads without a supported content type
+	unsupportedContentTypesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_unsupported_content_type_payloads_total",
+		metric.WithDescription("Total number of inbound payloads without a supported OTLP content type"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy unsupported content type payloads counter: %w", err)
+	}
+
+	// Create a counter for the number of inbound payloads larger than the body size limit of their signal
+	oversizedPayloadsMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_oversized_payloads_total",
+		metric.WithDescription("Total number of inbound payloads rejected for exceeding the body size limit"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy oversized payloads counter: %w", err)
+	}
+
+	// Create a counter for the number of requests expecting 100 Continue rejected before their body was uploaded
+	continueRejectionsMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_expect_continue_rejections_total",
+		metric.WithDescription("Total number of requests expecting 100 Continue rejected before their body was uploaded"),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy expect continue rejections counter: %w", err)
+	}
+
+	// Create a counter for the number of requests forwarded inline because the async dispatch limit was reached
+	asyncSaturatedMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_async_dispatch_saturated_total",
+		metric.WithDescription("Total number of requests forwarded inline because too many async dispatches were in flight"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy async dispatch saturated counter: %w", err)
+	}
+
+	// Create the verifier of the inbound request signatures
+	signatures, err := signature.New(&config.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Index the tenants the aliases rename to by the tenant they rename, signed requests may carry both
+	aliases, err := config.Tenant.ParseAliases()
 	if err != nil {
 		return nil, err
 	}
+	signerAliases := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		signerAliases[alias.To] = alias.From
+	}
 
-	return &Handlers{
-		config:           config,
-		router:           router,
-		meter:            meter,
-		tracer:           tracer,
-		logsProcessor:    *logsProcessor,
-		metricsProcessor: *metricsProcessor,
-		tracesProcessor:  *tracesProcessor,
-	}, nil
+	// Create a counter for the number of inbound requests rejected because of their signature
+	signatureFailuresMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_signature_failures_total",
+		metric.WithDescription("Total number of inbound requests rejected because of their signature"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy signature failures counter: %w", err)
+	}
+
+	// Stream frames carry no signature, so they cannot be verified
+	if config.StreamIngest.Enabled && signatures != nil {
+		return nil, errStreamSignatures
+	}
+
+	// Create a gauge for the number of open stream ingest connections
+	streamConnectionsMetric, err := meter.Int64UpDownCounter(
+		"otel_lgtm_proxy_stream_connections",
+		metric.WithDescription("Number of open connections of the stream ingest endpoint"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy stream connections gauge: %w", err)
+	}
+
+	h := &Handlers{
+		config:                        config,
+		router:                        router,
+		meter:                         meter,
+		tracer:                        tracer,
+		logsProcessor:                 *logsProcessor,
+		metricsProcessor:              *metricsProcessor,
+		tracesProcessor:               *tracesProcessor,
+		profilesProcessor:             profilesProcessor,
+		trustedProxies:                trustedProxies,
+		emptyPayloadsMetric:           emptyPayloadsMetric,
+		schemaURLsMetric:              schemaURLsMetric,
+		duplicatesMetric:              duplicatesMetric,
+		invalidResourcesMetric:        invalidResourcesMetric,
+		unsupportedContentTypesMetric: unsupportedContentTypesMetric,
+		oversizedPayloadsMetric:       oversizedPayloadsMetric,
+		continueRejectionsMetric:      continueRejectionsMetric,
+		metricAllowlist:               metricAllowlist,
+		enrichment:                    enrichment,
+		stats:                         tracker,
+		topK:                          topK,
+		circuits:                      circuits,
+		health:                        healthTracker,
+		decisions:                     feed,
+		heartbeats:                    heartbeats,
+		asyncSaturatedMetric:          asyncSaturatedMetric,
+		inflight:                      newInflight(&config.Dispatch),
+		signatures:                    signatures,
+		signerAliases:                 signerAliases,
+		signatureFailuresMetric:       signatureFailuresMetric,
+		streamConnectionsMetric:       streamConnectionsMetric,
+		streams:                       make(map[*websocket.Conn]struct{}),
+	}
+
+	// Create the routes of the HTTP integrations compiled into the binary
+	for _, routes := range integrationRoutes {
+		h.routes = append(h.routes, routes(h)...)
+	}
+
+	return h, nil
 }
 
 // Register registers the given handler function for the specified pattern on the provided router.
 func (h *Handlers) Register(ctx context.Context, pattern string, handlerFunc func(http.ResponseWriter, *http.Request)) {
 	logger.Info(ctx, "registering handler "+pattern)
-	h.router.Handle(pattern, otelhttp.NewHandler(http.HandlerFunc(handlerFunc), pattern))
+	h.router.Handle(pattern, otelhttp.NewHandler(h.withAccessLog(h.withDebug(http.HandlerFunc(handlerFunc))), pattern))
 }
 
 // NewServer creates a new HTTP server with the provided TLS configuration.
+//
+// HTTP/1.1 is always served, HTTP/2 is served over TLS when enabled and over cleartext (h2c) when enabled. The read,
+// read header and write timeouts default to the timeout of the listener, and idle connections are closed after the
+// idle timeout, or the read timeout when it is not set. The number of concurrent streams and the flow control windows
+// of HTTP/2 connections bound how much a single multiplexed connection can carry.
+// The connections of the stream ingest endpoint, hijacked from the server, are closed when it shuts down.
 func (h *Handlers) NewServer(tlsConfig *tls.Config) *http.Server {
-	return &http.Server{
-		MaxHeaderBytes:    1 << 20, // 1MB max header size
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(h.config.HTTP.HTTP2)
+	protocols.SetUnencryptedHTTP2(h.config.HTTP.H2C)
+
+	server := &http.Server{
+		Protocols:         protocols,
+		MaxHeaderBytes:    h.maxHeaderBytes(),
 		Addr:              h.config.HTTP.Address,
 		Handler:           h.router,
 		TLSConfig:         tlsConfig,
-		ReadHeaderTimeout: h.config.HTTP.Timeout,
-		ReadTimeout:       h.config.HTTP.Timeout,
-		WriteTimeout:      h.config.HTTP.Timeout,
+		ReadHeaderTimeout: h.serverTimeout(h.config.HTTP.ReadHeaderTimeout),
+		ReadTimeout:       h.serverTimeout(h.config.HTTP.ReadTimeout),
+		WriteTimeout:      h.serverTimeout(h.config.HTTP.WriteTimeout),
+		IdleTimeout:       h.config.HTTP.IdleTimeout,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams:          h.config.HTTP.HTTP2MaxConcurrentStreams,
+			MaxReceiveBufferPerConnection: h.config.HTTP.HTTP2MaxReceiveBuffer,
+			MaxReceiveBufferPerStream:     h.config.HTTP.HTTP2MaxStreamReceiveBuffer,
+		},
+	}
+	server.SetKeepAlivesEnabled(!h.config.HTTP.DisableKeepAlives)
+	server.RegisterOnShutdown(h.closeStreams)
+
+	return server
+}
+
+// serverTimeout returns a timeout of the server, or the timeout of the listener when it is not set.
+func (h *Handlers) serverTimeout(timeout time.Duration) time.Duration {
+	if timeout == 0 {
+		return h.config.HTTP.Timeout
+	}
+	return timeout
+}
+
+// maxHeaderBytes returns the maximum size of the request headers, or 1MB when it is not set.
+func (h *Handlers) maxHeaderBytes() int {
+	if h.config.HTTP.MaxHeaderBytes == 0 {
+		return defaultMaxHeaderBytes
+	}
+	return h.config.HTTP.MaxHeaderBytes
+}
+
+// validateConfig returns an error when a setting of the listener, of the inbound payloads or of the dispatch of the
+// requests is invalid, rather than letting an unknown value silently fall back to the default.
+func validateConfig(config *config.Config) error {
+	// Validate the timeouts and header size limit of the listener
+	if err := validateServer(&config.HTTP); err != nil {
+		return err
+	}
+
+	// Validate the HTTP/2 settings of the listener
+	if err := validateHTTP2(&config.HTTP); err != nil {
+		return err
+	}
+
+	// Validate the body size limits of the listener
+	if err := validateMaxBodyBytes(&config.HTTP); err != nil {
+		return err
+	}
+
+	// Validate the IP family of the listeners
+	if err := ipfamily.Validate(config.HTTP.IPFamily); err != nil {
+		return err
+	}
+
+	// Validate the policies for inbound payloads
+	if err := validateIngest(&config.Ingest); err != nil {
+		return err
+	}
+
+	// Validate the error policy and the mode of the dispatch
+	return validateDispatch(&config.Dispatch)
+}
+
+// validateServer returns an error when a timeout or the header size limit of the listener is negative.
+func validateServer(listener *config.Listener) error {
+	for name, timeout := range map[string]time.Duration{
+		"read timeout":        listener.ReadTimeout,
+		"read header timeout": listener.ReadHeaderTimeout,
+		"write timeout":       listener.WriteTimeout,
+		"idle timeout":        listener.IdleTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("invalid %s %s", name, timeout)
+		}
+	}
+	if listener.MaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid max header bytes %d", listener.MaxHeaderBytes)
+	}
+	return nil
+}
+
+// validateHTTP2 returns an error when the HTTP/2 settings of the listener are out of range, rather than letting the
+// server silently fall back to its defaults. Zero keeps the default of the server.
+func validateHTTP2(listener *config.Listener) error {
+	const maxBuffer = 4 << 20
+	if listener.HTTP2MaxConcurrentStreams < 0 {
+		return fmt.Errorf("invalid http2 max concurrent streams %d", listener.HTTP2MaxConcurrentStreams)
+	}
+	if buffer := listener.HTTP2MaxReceiveBuffer; buffer != 0 && (buffer < 64<<10 || buffer >= maxBuffer) {
+		return fmt.Errorf("invalid http2 max receive buffer %d, want at least 64KiB and less than 4MiB", buffer)
+	}
+	if buffer := listener.HTTP2MaxStreamReceiveBuffer; buffer < 0 || buffer >= maxBuffer {
+		return fmt.Errorf("invalid http2 max stream receive buffer %d, want less than 4MiB", buffer)
+	}
+	return nil
+}
+
+// Listen listens on the address, one of the configured listen addresses, with the IP family of the listener and
+// limiting the number of concurrent connections when configured. The limit applies to each address separately.
+//
+// Connections beyond the limit wait in the listen backlog until a connection is closed, rather than consuming a file
+// descriptor each.
+func (h *Handlers) Listen(address string) (net.Listener, error) {
+	listener, err := ipfamily.Listen(h.config.HTTP.IPFamily, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.config.HTTP.MaxConnections > 0 {
+		listener = netutil.LimitListener(listener, h.config.HTTP.MaxConnections)
 	}
+	return listener, nil
 }
@@ -3,15 +3,19 @@ package handler
 import (
 	"context"
 	"crypto/tls"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	noopmetric "go.opentelemetry.io/otel/metric/noop"
 	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/net/http2"
 )
 
 func TestNew(t *testing.T) {
@@ -59,6 +63,7 @@ func TestNew(t *testing.T) {
 				tt.logsClient,
 				tt.metricsClient,
 				tt.tracesClient,
+				&http.Client{},
 				meter,
 				tracer,
 			)
@@ -110,6 +115,7 @@ func TestRegister(t *testing.T) {
 			&http.Client{},
 			&http.Client{},
 			&http.Client{},
+			&http.Client{},
 			meter,
 			tracer,
 		)
@@ -164,8 +170,10 @@ func TestNewServer(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg := &config.Config{
-				HTTP: config.Endpoint{
-					Address: tt.address,
+				HTTP: config.Listener{
+					Endpoint: config.Endpoint{
+						Address: tt.address,
+					},
 				},
 				Tenant: config.Tenant{
 					Label:   "tenant.id",
@@ -192,6 +200,7 @@ func TestNewServer(t *testing.T) {
 				&http.Client{},
 				&http.Client{},
 				&http.Client{},
+				&http.Client{},
 				meter,
 				tracer,
 			)
@@ -213,3 +222,342 @@ func TestNewServer(t *testing.T) {
 		})
 	}
 }
+
+func TestNewServer_Timeouts(t *testing.T) {
+	tests := []struct {
+		name                  string
+		listener              config.Listener
+		wantReadTimeout       time.Duration
+		wantReadHeaderTimeout time.Duration
+		wantWriteTimeout      time.Duration
+		wantIdleTimeout       time.Duration
+		wantMaxHeaderBytes    int
+	}{
+		{
+			name:                  "defaults to the listener timeout",
+			listener:              config.Listener{Endpoint: config.Endpoint{Timeout: 15 * time.Second}},
+			wantReadTimeout:       15 * time.Second,
+			wantReadHeaderTimeout: 15 * time.Second,
+			wantWriteTimeout:      15 * time.Second,
+			wantMaxHeaderBytes:    1 << 20,
+		},
+		{
+			name: "configured",
+			listener: config.Listener{
+				Endpoint:          config.Endpoint{Timeout: 15 * time.Second},
+				ReadTimeout:       time.Minute,
+				ReadHeaderTimeout: 5 * time.Second,
+				WriteTimeout:      2 * time.Minute,
+				IdleTimeout:       90 * time.Second,
+				MaxHeaderBytes:    64 << 10,
+			},
+			wantReadTimeout:       time.Minute,
+			wantReadHeaderTimeout: 5 * time.Second,
+			wantWriteTimeout:      2 * time.Minute,
+			wantIdleTimeout:       90 * time.Second,
+			wantMaxHeaderBytes:    64 << 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, err := New(
+				&config.Config{HTTP: tt.listener, Tenant: config.Tenant{Label: "tenant.id", Default: "default"}},
+				http.NewServeMux(),
+				&http.Client{},
+				&http.Client{},
+				&http.Client{},
+				&http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+			)
+			require.NoError(t, err)
+
+			server := handlers.NewServer(nil)
+
+			assert.Equal(t, tt.wantReadTimeout, server.ReadTimeout)
+			assert.Equal(t, tt.wantReadHeaderTimeout, server.ReadHeaderTimeout)
+			assert.Equal(t, tt.wantWriteTimeout, server.WriteTimeout)
+			assert.Equal(t, tt.wantIdleTimeout, server.IdleTimeout)
+			assert.Equal(t, tt.wantMaxHeaderBytes, server.MaxHeaderBytes)
+		})
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		listener config.Listener
+		wantErr  string
+	}{
+		{name: "defaults"},
+		{
+			name:     "negative read timeout",
+			listener: config.Listener{ReadTimeout: -time.Second},
+			wantErr:  "invalid read timeout",
+		},
+		{
+			name:     "negative read header timeout",
+			listener: config.Listener{ReadHeaderTimeout: -time.Second},
+			wantErr:  "invalid read header timeout",
+		},
+		{
+			name:     "negative write timeout",
+			listener: config.Listener{WriteTimeout: -time.Second},
+			wantErr:  "invalid write timeout",
+		},
+		{
+			name:     "negative idle timeout",
+			listener: config.Listener{IdleTimeout: -time.Second},
+			wantErr:  "invalid idle timeout",
+		},
+		{
+			name:     "negative max header bytes",
+			listener: config.Listener{MaxHeaderBytes: -1},
+			wantErr:  "invalid max header bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateServer(&tt.listener)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewServer_H2C(t *testing.T) {
+	tests := []struct {
+		name      string
+		h2c       bool
+		wantProto string
+		wantErr   bool
+	}{
+		{
+			name:      "serves h2c when enabled",
+			h2c:       true,
+			wantProto: "HTTP/2.0",
+		},
+		{
+			name:    "rejects h2c when disabled",
+			h2c:     false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := http.NewServeMux()
+			router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.Proto))
+			})
+
+			handlers, err := New(
+				&config.Config{
+					HTTP:   config.Listener{HTTP2: true, H2C: tt.h2c},
+					Tenant: config.Tenant{Label: "tenant.id", Default: "default"},
+				},
+				router,
+				&http.Client{},
+				&http.Client{},
+				&http.Client{},
+				&http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+			)
+			require.NoError(t, err)
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			server := handlers.NewServer(nil)
+			go func() { _ = server.Serve(listener) }()
+			defer func() { _ = server.Close() }()
+
+			protocols := &http.Protocols{}
+			protocols.SetUnencryptedHTTP2(true)
+			client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+
+			resp, err := client.Get("http://" + listener.Addr().String())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantProto, string(body))
+		})
+	}
+}
+
+func TestNewServer_HTTP2Settings(t *testing.T) {
+	handlers, err := New(
+		&config.Config{
+			HTTP: config.Listener{
+				HTTP2:                       true,
+				H2C:                         true,
+				HTTP2MaxConcurrentStreams:   7,
+				HTTP2MaxReceiveBuffer:       2 << 20,
+				HTTP2MaxStreamReceiveBuffer: 512 << 10,
+			},
+			Tenant: config.Tenant{Label: "tenant.id", Default: "default"},
+		},
+		http.NewServeMux(),
+		&http.Client{},
+		&http.Client{},
+		&http.Client{},
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+	)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := handlers.NewServer(nil)
+	go func() { _ = server.Serve(listener) }()
+	defer func() { _ = server.Close() }()
+
+	// Read the settings the server announces to a prior-knowledge h2c client
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = io.WriteString(conn, http2.ClientPreface)
+	require.NoError(t, err)
+
+	framer := http2.NewFramer(conn, conn)
+	require.NoError(t, framer.WriteSettings())
+	frame, err := framer.ReadFrame()
+	require.NoError(t, err)
+	settings, ok := frame.(*http2.SettingsFrame)
+	require.True(t, ok, "got %T, want the settings frame", frame)
+
+	streams, _ := settings.Value(http2.SettingMaxConcurrentStreams)
+	assert.Equal(t, uint32(7), streams)
+	window, _ := settings.Value(http2.SettingInitialWindowSize)
+	assert.Equal(t, uint32(512<<10), window)
+}
+
+func TestValidateHTTP2(t *testing.T) {
+	tests := []struct {
+		name     string
+		listener config.Listener
+		wantErr  bool
+	}{
+		{name: "server defaults"},
+		{
+			name:     "valid",
+			listener: config.Listener{HTTP2MaxConcurrentStreams: 250, HTTP2MaxReceiveBuffer: 1 << 20, HTTP2MaxStreamReceiveBuffer: 1 << 20},
+		},
+		{name: "negative streams", listener: config.Listener{HTTP2MaxConcurrentStreams: -1}, wantErr: true},
+		{name: "connection buffer too small", listener: config.Listener{HTTP2MaxReceiveBuffer: 1024}, wantErr: true},
+		{name: "connection buffer too large", listener: config.Listener{HTTP2MaxReceiveBuffer: 4 << 20}, wantErr: true},
+		{name: "stream buffer too large", listener: config.Listener{HTTP2MaxStreamReceiveBuffer: 8 << 20}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateHTTP2(&tt.listener)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestListen_MaxConnections(t *testing.T) {
+	handlers, err := New(
+		&config.Config{
+			HTTP:   config.Listener{Endpoint: config.Endpoint{Address: "127.0.0.1:0"}, MaxConnections: 1},
+			Tenant: config.Tenant{Label: "tenant.id", Default: "default"},
+		},
+		http.NewServeMux(),
+		&http.Client{},
+		&http.Client{},
+		&http.Client{},
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+	)
+	require.NoError(t, err)
+
+	listener, err := handlers.Listen(handlers.config.HTTP.Address)
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	first, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = first.Close() }()
+	second, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+
+	// Only the first connection is accepted until it is closed.
+	conn := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("accepted a connection beyond the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, conn.Close())
+	select {
+	case conn := <-accepted:
+		_ = conn.Close()
+	case <-time.After(time.Second):
+		t.Fatal("connection not accepted after closing the first")
+	}
+}
+
+func TestListen_IPFamily(t *testing.T) {
+	newHandlers := func(family string) (*Handlers, error) {
+		return New(
+			&config.Config{
+				HTTP:   config.Listener{Endpoint: config.Endpoint{IPFamily: family}},
+				Tenant: config.Tenant{Label: "tenant.id", Default: "default"},
+			},
+			http.NewServeMux(),
+			&http.Client{},
+			&http.Client{},
+			&http.Client{},
+			&http.Client{},
+			noopmetric.NewMeterProvider().Meter("test"),
+			nooptrace.NewTracerProvider().Tracer("test"),
+		)
+	}
+
+	_, err := newHandlers("ipv5")
+	assert.ErrorContains(t, err, "invalid ip family")
+
+	handlers, err := newHandlers(config.IPFamilyIPv4)
+	require.NoError(t, err)
+
+	listener, err := handlers.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	_ = listener.Close()
+
+	// IPv6 addresses are refused by an IPv4 listener, whether the host has IPv6 or not
+	_, err = handlers.Listen("[::1]:0")
+	assert.Error(t, err)
+}
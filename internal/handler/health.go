@@ -14,3 +14,17 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 		logger.Error(r.Context(), err.Error())
 	}
 }
+
+// Ready handles incoming readiness requests, reporting the health of each signal pipeline.
+//
+// The proxy is ready when the signals satisfy the configured readiness policy, otherwise it responds with
+// 503 Service Unavailable so that load balancers stop routing to it.
+func (h *Handlers) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Report()
+
+	statusCode := http.StatusOK
+	if !report.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, statusCode, report)
+}
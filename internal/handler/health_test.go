@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/health"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestReady(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		backendStatus  int
+		wantStatusCode int
+		wantLogs       string
+	}{
+		{
+			name:           "backend accepting",
+			policy:         health.PolicyAll,
+			backendStatus:  http.StatusOK,
+			wantStatusCode: http.StatusOK,
+			wantLogs:       health.StatusServing,
+		},
+		{
+			name:           "backend rejecting the data",
+			policy:         health.PolicyAll,
+			backendStatus:  http.StatusBadRequest,
+			wantStatusCode: http.StatusOK,
+			wantLogs:       health.StatusServing,
+		},
+		{
+			name:           "backend unavailable",
+			policy:         health.PolicyAll,
+			backendStatus:  http.StatusServiceUnavailable,
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantLogs:       health.StatusNotServing,
+		},
+		{
+			name:           "backend unavailable with any policy",
+			policy:         health.PolicyAny,
+			backendStatus:  http.StatusServiceUnavailable,
+			wantStatusCode: http.StatusOK,
+			wantLogs:       health.StatusNotServing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: tt.backendStatus, Body: http.NoBody}, nil)
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Ready:  config.Ready{Policy: tt.policy, FailureThreshold: 1},
+			}, client)
+
+			body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+			require.NoError(t, err)
+			h.Logs(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+			rec := httptest.NewRecorder()
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			assert.Equal(t, tt.wantStatusCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var got health.Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			assert.Equal(t, tt.policy, got.Policy)
+			assert.Equal(t, tt.wantLogs, got.Signals["logs"].Status)
+			assert.Equal(t, health.StatusServing, got.Signals["metrics"].Status)
+			assert.Equal(t, health.StatusServing, got.Signals["traces"].Status)
+		})
+	}
+}
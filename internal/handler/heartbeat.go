@@ -0,0 +1,71 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var heartbeatSenderAttrKey = "heartbeat.sender"
+
+// heartbeatSender returns the authenticated sender of the request: the tenant that signed it, or else the common name
+// of its verified client certificate, falling back to its first DNS name.
+func heartbeatSender(ctx context.Context, r *http.Request) (string, bool) {
+	if signer, ok := signerFromContext(ctx); ok {
+		return signer, true
+	}
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	leaf := r.TLS.VerifiedChains[0][0]
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName, true
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0], true
+	}
+
+	return "", false
+}
+
+// heartbeat answers an empty payload of an authenticated sender as a heartbeat, recording when the sender was last
+// seen, and reports whether it did.
+func (h *Handlers) heartbeat(ctx context.Context, w http.ResponseWriter, r *http.Request, signal string) bool {
+	if h.heartbeats == nil {
+		return false
+	}
+
+	sender, ok := heartbeatSender(ctx, r)
+	if !ok {
+		return false
+	}
+
+	h.heartbeats.Seen(signal, sender, time.Now())
+	logger.Debug(ctx, "received heartbeat",
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(heartbeatSenderAttrKey, sender),
+	)
+
+	encoding := responseEncoding(r)
+	body, err := proto.MarshalEncoding(exportResponse(signal, partial{}), encoding)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
+
+	w.Header().Set("Content-Type", proto.ContentType(encoding))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logger.Error(ctx, err.Error())
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandlers_Heartbeat(t *testing.T) {
+	tests := []struct {
+		name       string
+		heartbeats bool
+		signer     string
+		cert       *x509.Certificate
+		wantStatus int
+		wantSender string
+	}{
+		{
+			name:       "signed empty payload is a heartbeat",
+			heartbeats: true,
+			signer:     "tenant-a",
+			wantStatus: http.StatusOK,
+			wantSender: "tenant-a",
+		},
+		{
+			name:       "empty payload with a client certificate is a heartbeat",
+			heartbeats: true,
+			cert:       &x509.Certificate{Subject: pkix.Name{CommonName: "agent-a"}},
+			wantStatus: http.StatusOK,
+			wantSender: "agent-a",
+		},
+		{
+			name:       "client certificate without a common name falls back to its dns name",
+			heartbeats: true,
+			cert:       &x509.Certificate{DNSNames: []string{"agent-b.example.com"}},
+			wantStatus: http.StatusOK,
+			wantSender: "agent-b.example.com",
+		},
+		{
+			name:       "unauthenticated empty payload is not a heartbeat",
+			heartbeats: true,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "heartbeats are disabled by default",
+			signer:     "tenant-a",
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).Times(0)
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{EmptyPayload: config.EmptyPayloadAccept, Heartbeats: tt.heartbeats},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(nil))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			if tt.signer != "" {
+				req = req.WithContext(requestmeta.NewContext(req.Context(), requestmeta.Metadata{Signer: tt.signer}))
+			}
+			if tt.cert != nil {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.cert}}}
+			}
+			rec := httptest.NewRecorder()
+			h.Metrics(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantSender == "" {
+				if h.heartbeats != nil {
+					assert.Empty(t, h.heartbeats.Senders())
+				}
+				return
+			}
+			senders := h.heartbeats.Senders()
+			if assert.Len(t, senders, 1) {
+				assert.Equal(t, "metrics", senders[0].Signal)
+				assert.Equal(t, tt.wantSender, senders[0].Sender)
+			}
+		})
+	}
+}
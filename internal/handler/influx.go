@@ -0,0 +1,86 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/influx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Influx API error codes.
+const (
+	influxCodeInvalid       = "invalid"
+	influxCodeInternalError = "internal error"
+)
+
+// InfluxWrite handles Influx line protocol write requests, converting the points into metrics.
+//
+// The tenant of a point is read from the configured tenant tag, falling back to the org query parameter.
+func (h *Handlers) InfluxWrite(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "metrics"))
+
+	points, err := h.readInfluxPoints(w, r)
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		writeInfluxError(ctx, w, http.StatusBadRequest, influxCodeInvalid, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	converter := influx.New(h.config.Tenant.Label, h.config.Influx.TenantTag)
+	resources, dropped := converter.ResourceMetrics(points, r.URL.Query().Get("org"))
+	if dropped > 0 {
+		logger.Debug(ctx, "dropped influx string fields", attribute.Int("fields", dropped))
+	}
+
+	if err := h.IngestMetrics(ctx, resources); err != nil {
+		logger.Error(ctx, err.Error())
+		writeInfluxError(ctx, w, http.StatusInternalServerError, influxCodeInternalError, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readInfluxPoints reads and parses the optionally compressed body of a write request, returning errBodyTooLarge
+// once a body over the limit has been answered.
+func (h *Handlers) readInfluxPoints(w http.ResponseWriter, r *http.Request) ([]influx.Point, error) {
+	precision, err := influx.ParsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := h.readSignalBody(w, r, "metrics")
+	if err != nil {
+		return nil, err
+	}
+
+	return influx.Parse(b, precision, time.Now())
+}
+
+// writeInfluxError writes an error response in the format of the Influx API.
+func writeInfluxError(ctx context.Context, w http.ResponseWriter, status int, code string, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"code": code, "message": err.Error()}); err != nil {
+		logger.Error(ctx, err.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestInfluxWrite(t *testing.T) {
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, err := writer.Write([]byte(s))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name            string
+		target          string
+		body            []byte
+		contentEncoding string
+		tenantTag       string
+		wantTenants     []string
+		wantStatus      int
+	}{
+		{
+			name:        "tenant from org",
+			target:      "/api/v2/write?org=tenant-a&bucket=metrics&precision=s",
+			body:        []byte("cpu,host=a usage_idle=90.5,usage_user=9.5 1700000000\n"),
+			wantTenants: []string{"tenant-a"},
+			wantStatus:  http.StatusNoContent,
+		},
+		{
+			name:        "tenant from tag",
+			target:      "/api/v2/write?org=tenant-a",
+			body:        []byte("cpu,host=a,tenant=tenant-b value=1\nmem,host=a used=2i\n"),
+			tenantTag:   "tenant",
+			wantTenants: []string{"tenant-b", "tenant-a"},
+			wantStatus:  http.StatusNoContent,
+		},
+		{
+			name:            "gzip body",
+			target:          "/api/v2/write?org=tenant-a",
+			body:            gzipped("cpu value=1\n"),
+			contentEncoding: "gzip",
+			wantTenants:     []string{"tenant-a"},
+			wantStatus:      http.StatusNoContent,
+		},
+		{
+			name:       "invalid line",
+			target:     "/api/v2/write?org=tenant-a",
+			body:       []byte("cpu\n"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid precision",
+			target:     "/api/v2/write?org=tenant-a&precision=h",
+			body:       []byte("cpu value=1\n"),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+
+			var mu sync.Mutex
+			var tenants []string
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				data := &metricpb.MetricsData{}
+				require.NoError(t, proto.Unmarshal(forwarded, data))
+				assert.NotEmpty(t, data.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics())
+
+				mu.Lock()
+				tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(len(tt.wantTenants))
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Influx: config.Influx{Enabled: true, TenantTag: tt.tenantTag},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(tt.body))
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.InfluxWrite(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.ElementsMatch(t, tt.wantTenants, tenants)
+			if tt.wantStatus == http.StatusBadRequest {
+				assert.Contains(t, rec.Body.String(), `"code":"invalid"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"net/http"
+)
+
+// route is an HTTP endpoint of an optional integration.
+type route struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// integrationRoutes create the routes of the HTTP integrations compiled into the binary, each registered from a file
+// guarded by the build tags of its integration. They are called once when the handlers are created, creating the
+// dependencies of their handlers, and return no routes when the integration is not enabled.
+var integrationRoutes []func(h *Handlers) []route
+
+// RegisterIntegrations registers the routes of the HTTP integrations compiled into the binary and enabled by the
+// configuration.
+func (h *Handlers) RegisterIntegrations(ctx context.Context) {
+	for _, r := range h.routes {
+		h.Register(ctx, r.pattern, r.handler)
+	}
+}
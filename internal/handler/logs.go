@@ -2,41 +2,30 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/matt-gp/core/logger"
-	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/trace"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/transform"
 	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
 )
 
 // Logs handles incoming OTLP log requests.
 func (h *Handlers) Logs(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	span := trace.SpanFromContext(ctx)
-	span.SetAttributes(attribute.String(signalTypeAttrKey, "logs"))
+	handle(h, w, r, "logs", &h.logsProcessor, &logpb.LogsData{}, (*logpb.LogsData).GetResourceLogs,
+		h.dedupLogRecords,
+	)
+}
 
-	// Unmarshal the incoming log data
-	data, err := proto.Unmarshal(r, &logpb.LogsData{})
-	if err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return
-	}
+// IngestLogs forwards the log resources received by the non-OTLP receivers through the tenant partitioning pipeline.
+func (h *Handlers) IngestLogs(ctx context.Context, resources []*logpb.ResourceLogs) error {
+	_, err := process(ctx, h, "logs", &h.logsProcessor, resources, h.dedupLogRecords)
+	return err
+}
 
-	// Process the log data
-	if err := h.logsProcessor.Dispatch(ctx, h.logsProcessor.Partition(ctx, data.GetResourceLogs())); err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
+// dedupLogRecords drops the log records of the tenant that share a timestamp and body, when enabled.
+func (h *Handlers) dedupLogRecords(ctx context.Context, tenant string, resources []*logpb.ResourceLogs) {
+	if !h.config.Transform.DedupLogs {
 		return
 	}
-
-	span.SetStatus(codes.Ok, "processed successfully")
-	w.WriteHeader(http.StatusAccepted)
+	h.recordDuplicates(ctx, "logs", tenant, transform.DedupLogRecords(resources))
 }
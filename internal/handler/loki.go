@@ -0,0 +1,70 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/loki"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LokiPush handles Loki push API requests, encoded as JSON or snappy compressed protobuf, converting the streams into
+// logs.
+//
+// The tenant of a stream is read from the configured tenant label, falling back to the tenant header of the request.
+func (h *Handlers) LokiPush(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "logs"))
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "", "application/json", loki.ProtoContentType:
+	default:
+		err := fmt.Errorf("unsupported content type %q", r.Header.Get("Content-Type"))
+		writeLokiError(ctx, w, span, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	b, err := h.readSignalBody(w, r, "logs")
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		writeLokiError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	request, err := loki.Parse(b, mediaType)
+	if err != nil {
+		writeLokiError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	converter := loki.New(h.config.Tenant.Label, h.config.LokiPush.TenantLabel)
+	resources := converter.ResourceLogs(request, r.Header.Get(h.config.Tenant.Header), time.Now())
+	if err := h.IngestLogs(ctx, resources); err != nil {
+		writeLokiError(ctx, w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLokiError records the error and writes it as plain text, like Loki.
+func writeLokiError(ctx context.Context, w http.ResponseWriter, span trace.Span, status int, err error) {
+	logger.Error(ctx, err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	http.Error(w, err.Error(), status)
+}
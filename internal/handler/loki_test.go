@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestLokiPush(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		orgID       string
+		tenantLabel string
+		wantTenants []string
+		wantStatus  int
+	}{
+		{
+			name:        "tenant from header",
+			body:        `{"streams":[{"stream":{"job":"varlogs"},"values":[["1700000000000000000","hello"]]}]}`,
+			contentType: "application/json",
+			orgID:       "tenant-a",
+			wantTenants: []string{"tenant-a"},
+			wantStatus:  http.StatusNoContent,
+		},
+		{
+			name: "tenant from label",
+			body: `{"streams":[{"stream":{"job":"varlogs","tenant":"tenant-b"},"values":[["1","hello"]]},` +
+				`{"stream":{"job":"app"},"values":[["2","world"]]}]}`,
+			orgID:       "tenant-a",
+			tenantLabel: "tenant",
+			wantTenants: []string{"tenant-b", "tenant-a"},
+			wantStatus:  http.StatusNoContent,
+		},
+		{
+			name:        "default tenant",
+			body:        `{"streams":[{"stream":{"job":"varlogs"},"values":[["1","hello"]]}]}`,
+			contentType: "application/json; charset=utf-8",
+			wantTenants: []string{"default"},
+			wantStatus:  http.StatusNoContent,
+		},
+		{
+			name:        "invalid body",
+			body:        `{"streams":[{"stream":{"job":"varlogs"},"values":[["now","hello"]]}]}`,
+			contentType: "application/json",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unsupported content type",
+			body:        `job=varlogs hello`,
+			contentType: "text/plain",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+
+			var mu sync.Mutex
+			var tenants []string
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				data := &logpb.LogsData{}
+				require.NoError(t, proto.Unmarshal(forwarded, data))
+				assert.NotEmpty(t, data.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords())
+
+				mu.Lock()
+				tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(len(tt.wantTenants))
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				LokiPush: config.LokiPush{Enabled: true, TenantLabel: tt.tenantLabel},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/loki/api/v1/push", bytes.NewReader([]byte(tt.body)))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.orgID != "" {
+				req.Header.Set("X-Scope-OrgID", tt.orgID)
+			}
+			rec := httptest.NewRecorder()
+			h.LokiPush(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.ElementsMatch(t, tt.wantTenants, tenants)
+		})
+	}
+}
@@ -2,41 +2,27 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/matt-gp/core/logger"
-	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/trace"
 	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
 )
 
 // Metrics handles incoming OTLP metric requests.
 func (h *Handlers) Metrics(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	span := trace.SpanFromContext(ctx)
-	span.SetAttributes(attribute.String(signalTypeAttrKey, "metrics"))
-
-	// Unmarshal the incoming metric data
-	data, err := proto.Unmarshal(r, &metricpb.MetricsData{})
-	if err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return
-	}
+	handle(h, w, r, "metrics", &h.metricsProcessor, &metricpb.MetricsData{}, (*metricpb.MetricsData).GetResourceMetrics,
+		h.allowMetricAttributes,
+	)
+}
 
-	// Process the metric data
-	if err := h.metricsProcessor.Dispatch(ctx, h.metricsProcessor.Partition(ctx, data.GetResourceMetrics())); err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return
-	}
+// IngestMetrics forwards the metric resources received by the non-OTLP receivers through the tenant partitioning
+// pipeline.
+func (h *Handlers) IngestMetrics(ctx context.Context, resources []*metricpb.ResourceMetrics) error {
+	_, err := process(ctx, h, "metrics", &h.metricsProcessor, resources, h.allowMetricAttributes)
+	return err
+}
 
-	span.SetStatus(codes.Ok, "processed successfully")
-	w.WriteHeader(http.StatusAccepted)
+// allowMetricAttributes applies the metric attribute allowlist of the tenant.
+func (h *Handlers) allowMetricAttributes(_ context.Context, tenant string, resources []*metricpb.ResourceMetrics) {
+	h.metricAllowlist.Apply(tenant, resources)
 }
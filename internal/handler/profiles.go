@@ -0,0 +1,73 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/profilepb"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// errProfilesJSON is returned for profiles encoded as JSON, only the fields the proxy partitions the profiles by are
+// known to it and the JSON encoding cannot carry the others through.
+var errProfilesJSON = errors.New("unsupported content type: profiles are only accepted as application/x-protobuf")
+
+// Profiles handles incoming OTLP profile requests.
+func (h *Handlers) Profiles(w http.ResponseWriter, r *http.Request) {
+	if proto.IsJSON(r.Header.Get("Content-Type")) {
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.String(signalTypeAttrKey, "profiles"))
+		h.writeError(ctx, w, r, http.StatusUnsupportedMediaType, errProfilesJSON)
+		span.RecordError(errProfilesJSON)
+		span.SetStatus(codes.Error, errProfilesJSON.Error())
+		return
+	}
+
+	handle(h, w, r, "profiles", &h.profilesProcessor, &profilepb.ProfilesData{}, profilepb.Resources)
+}
+
+// newProfilesProcessor creates the processor forwarding the profiles to the profiles backend. The profiles are
+// forwarded as they were received, so the backend must take OTLP protobuf requests, or a Kafka topic.
+func newProfilesProcessor(
+	cfg *config.Config,
+	client processor.Client,
+	meter metric.Meter,
+	tracer trace.Tracer,
+	opts ...processor.Option,
+) (*processor.Processor[*profilepb.ResourceProfiles], error) {
+	if cfg.Profiles.Encoding == config.EncodingJSON {
+		return nil, errors.New("profiles can only be forwarded with the protobuf encoding")
+	}
+	if cfg.Profiles.Stream {
+		return nil, errors.New("profiles cannot be streamed, a streamed body carries the resources without their dictionary")
+	}
+
+	return processor.New(
+		cfg,
+		&cfg.Profiles,
+		attribute.String(signalTypeAttrKey, "profiles"),
+		client,
+		meter,
+		tracer,
+		func(rp *profilepb.ResourceProfiles) *resourcepb.Resource {
+			return rp.GetResource()
+		},
+		func(resources []*profilepb.ResourceProfiles) ([]byte, error) {
+			data, err := profilepb.NewProfilesData(resources)
+			if err != nil {
+				return nil, err
+			}
+			return proto.MarshalEncoding(data, config.EncodingProtobuf)
+		},
+		opts...,
+	)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/profilepb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestProfiles(t *testing.T) {
+	// The string table of the dictionary and the sample of a profile, unknown to the partial schema
+	stringTable := protowire.AppendString(protowire.AppendTag(nil, 5, protowire.BytesType), "main")
+	sample := protowire.AppendBytes(protowire.AppendTag(nil, 5, protowire.BytesType), []byte{0x08, 0x01})
+
+	dictionary := &profilepb.ProfilesDictionary{}
+	dictionary.ProtoReflect().SetUnknown(stringTable)
+	newResource := func(tenant string) *profilepb.ResourceProfiles {
+		profile := &profilepb.Profile{}
+		profile.ProtoReflect().SetUnknown(sample)
+		return &profilepb.ResourceProfiles{
+			Resource:      testResource(tenant),
+			ScopeProfiles: []*profilepb.ScopeProfiles{{Profiles: []*profilepb.Profile{profile}}},
+		}
+	}
+
+	body, err := proto.Marshal(&profilepb.ProfilesData{
+		ResourceProfiles: []*profilepb.ResourceProfiles{newResource("tenant-a"), newResource("tenant-b")},
+		Dictionary:       dictionary,
+	})
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		tenants []string
+	)
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		data := &profilepb.ProfilesData{}
+		require.NoError(t, proto.Unmarshal(forwarded, data))
+		require.Len(t, data.GetResourceProfiles(), 1)
+
+		rp := data.GetResourceProfiles()[0]
+		assert.Empty(t, rp.ProtoReflect().GetUnknown())
+		assert.Equal(t, sample, []byte(rp.GetScopeProfiles()[0].GetProfiles()[0].ProtoReflect().GetUnknown()))
+		assert.Equal(t, stringTable, []byte(data.GetDictionary().ProtoReflect().GetUnknown()))
+		assert.Equal(t, "http://pyroscope:4040/v1development/profiles", req.URL.String())
+
+		mu.Lock()
+		tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}).Times(2)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+		Profiles: config.Endpoint{Address: "http://pyroscope:4040/v1development/profiles"},
+	}, client)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1development/profiles", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	h.Profiles(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.ElementsMatch(t, []string{"tenant-a", "tenant-b"}, tenants)
+
+	response := &profilepb.ExportProfilesServiceResponse{}
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), response))
+	assert.Nil(t, response.GetPartialSuccess())
+}
+
+func TestProfiles_JSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+		Profiles: config.Endpoint{Address: "http://pyroscope:4040/v1development/profiles"},
+	}, client)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1development/profiles", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	h.Profiles(rec, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+func TestNewProfilesProcessor(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint config.Endpoint
+		wantErr  bool
+	}{
+		{name: "protobuf", endpoint: config.Endpoint{Address: "http://pyroscope:4040", Encoding: config.EncodingProtobuf}},
+		{name: "json", endpoint: config.Endpoint{Address: "http://pyroscope:4040", Encoding: config.EncodingJSON}, wantErr: true},
+		{name: "streamed", endpoint: config.Endpoint{Address: "http://pyroscope:4040", Stream: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Profiles: tt.endpoint,
+			}
+			_, err := New(cfg, http.NewServeMux(), &http.Client{}, &http.Client{}, &http.Client{}, &http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"), nooptrace.NewTracerProvider().Tracer("test"))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/loki"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/remotewrite"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"go.opentelemetry.io/otel/metric"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// validateProtocols returns an error when the outbound protocol of a signal is unknown, or not supported by the signal.
+// Only logs can be pushed to Loki natively, and only metrics with Prometheus remote write. Every signal can be written
+// to Kafka, and profiles only with OTLP or to Kafka.
+func validateProtocols(cfg *config.Config) error {
+	for _, endpoint := range []struct {
+		signal   string
+		protocol string
+		native   string
+	}{
+		{signal: "logs", protocol: cfg.Logs.Protocol, native: config.ProtocolLokiPush},
+		{signal: "metrics", protocol: cfg.Metrics.Protocol, native: config.ProtocolRemoteWrite},
+		{signal: "traces", protocol: cfg.Traces.Protocol},
+		{signal: "profiles", protocol: cfg.Profiles.Protocol},
+	} {
+		switch endpoint.protocol {
+		case "", config.ProtocolOTLP, config.ProtocolKafka, endpoint.native:
+		case config.ProtocolLokiPush, config.ProtocolRemoteWrite:
+			return fmt.Errorf("the %s protocol is not supported by %s", endpoint.protocol, endpoint.signal)
+		default:
+			return fmt.Errorf("unknown %s protocol %q", endpoint.signal, endpoint.protocol)
+		}
+	}
+	return nil
+}
+
+// logsMarshaller returns the function marshalling the resource logs of a backend request, and the processor options
+// it requires: the Loki push format when the logs endpoint speaks it, OTLP otherwise.
+func logsMarshaller(endpoint *config.Endpoint) (func([]*logpb.ResourceLogs) ([]byte, error), []processor.Option) {
+	if endpoint.Protocol == config.ProtocolLokiPush {
+		return func(resources []*logpb.ResourceLogs) ([]byte, error) {
+			return loki.Marshal(resources, endpoint.LokiLabels, time.Now())
+		}, []processor.Option{processor.WithContentType(loki.ContentType)}
+	}
+
+	return func(resources []*logpb.ResourceLogs) ([]byte, error) {
+		data := &logpb.LogsData{
+			ResourceLogs: resources,
+		}
+		return proto.MarshalEncoding(data, endpoint.Encoding)
+	}, nil
+}
+
+// metricsMarshaller returns the function marshalling the resource metrics of a backend request, and the processor
+// options it requires: Prometheus remote write when the metrics endpoint speaks it, OTLP otherwise. The data points
+// remote write cannot represent are counted by the dropped points metric.
+func metricsMarshaller(
+	endpoint *config.Endpoint,
+	meter metric.Meter,
+) (func([]*metricpb.ResourceMetrics) ([]byte, error), []processor.Option, error) {
+	if endpoint.Protocol != config.ProtocolRemoteWrite {
+		return func(resources []*metricpb.ResourceMetrics) ([]byte, error) {
+			data := &metricpb.MetricsData{
+				ResourceMetrics: resources,
+			}
+			return proto.MarshalEncoding(data, endpoint.Encoding)
+		}, nil, nil
+	}
+
+	// Create a counter for the data points dropped because remote write cannot represent them
+	droppedMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_remote_write_dropped_points_total",
+		metric.WithDescription("Total number of delta and exponential histogram data points dropped by remote write"),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create otel lgtm proxy remote write dropped points counter: %w", err)
+	}
+
+	marshal := func(resources []*metricpb.ResourceMetrics) ([]byte, error) {
+		body, dropped := remotewrite.Marshal(resources)
+		if dropped > 0 {
+			droppedMetric.Add(context.Background(), int64(dropped))
+		}
+		return body, nil
+	}
+	return marshal, []processor.Option{
+		processor.WithContentType(remotewrite.ContentType),
+		processor.WithHeader("Content-Encoding", remotewrite.ContentEncoding),
+		processor.WithHeader("X-Prometheus-Remote-Write-Version", remotewrite.Version),
+	}, nil
+}
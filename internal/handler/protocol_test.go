@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestValidateProtocols(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{
+			name: "otlp",
+			cfg: config.Config{
+				Logs:    config.Endpoint{Protocol: config.ProtocolOTLP},
+				Metrics: config.Endpoint{Protocol: config.ProtocolOTLP},
+				Traces:  config.Endpoint{Protocol: config.ProtocolOTLP},
+			},
+		},
+		{name: "loki push logs", cfg: config.Config{Logs: config.Endpoint{Protocol: config.ProtocolLokiPush}}},
+		{
+			name:    "loki push metrics",
+			cfg:     config.Config{Metrics: config.Endpoint{Protocol: config.ProtocolLokiPush}},
+			wantErr: true,
+		},
+		{
+			name:    "loki push traces",
+			cfg:     config.Config{Traces: config.Endpoint{Protocol: config.ProtocolLokiPush}},
+			wantErr: true,
+		},
+		{name: "remote write metrics", cfg: config.Config{Metrics: config.Endpoint{Protocol: config.ProtocolRemoteWrite}}},
+		{
+			name:    "remote write logs",
+			cfg:     config.Config{Logs: config.Endpoint{Protocol: config.ProtocolRemoteWrite}},
+			wantErr: true,
+		},
+		{
+			name:    "remote write traces",
+			cfg:     config.Config{Traces: config.Endpoint{Protocol: config.ProtocolRemoteWrite}},
+			wantErr: true,
+		},
+		{
+			name: "kafka",
+			cfg: config.Config{
+				Logs:    config.Endpoint{Protocol: config.ProtocolKafka},
+				Metrics: config.Endpoint{Protocol: config.ProtocolKafka},
+				Traces:  config.Endpoint{Protocol: config.ProtocolKafka},
+			},
+		},
+		{name: "unknown", cfg: config.Config{Logs: config.Endpoint{Protocol: "syslog"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProtocols(&tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSignalHandlers_LokiPush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+
+	var got *http.Request
+	var gotBody []byte
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		got = req
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		gotBody = body
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody}, nil
+	})
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Logs: config.Endpoint{
+			Address:    "http://loki:3100/loki/api/v1/push",
+			Encoding:   config.EncodingProtobuf,
+			Protocol:   config.ProtocolLokiPush,
+			LokiLabels: []string{"tenant.id"},
+		},
+	}, client)
+
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{
+		Resource: testResource("tenant-a"),
+		ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{
+			TimeUnixNano: 1700000000000000000,
+			Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}},
+		}}}},
+	}}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	h.Logs(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "tenant-a", got.Header.Get("X-Scope-OrgID"))
+	assert.JSONEq(t, `{"streams":[{
+		"stream":{"tenant_id":"tenant-a"},
+		"values":[["1700000000000000000","hello"]]
+	}]}`, string(gotBody))
+}
+
+func TestSignalHandlers_RemoteWrite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+
+	var got *http.Request
+	var gotBody []byte
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		got = req
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		gotBody = body
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Metrics: config.Endpoint{
+			Address:  "http://mimir:8080/api/v1/push",
+			Headers:  "Authorization=Bearer token",
+			Protocol: config.ProtocolRemoteWrite,
+		},
+	}, client)
+
+	body, err := proto.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{{
+		Resource: testResource("tenant-a"),
+		ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{{
+			Name: "up",
+			Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+				TimeUnixNano: 1700000000000000000,
+				Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: 1},
+			}}}},
+		}}}},
+	}}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec := httptest.NewRecorder()
+	h.Metrics(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.NotNil(t, got)
+	assert.Equal(t, "application/x-protobuf", got.Header.Get("Content-Type"))
+	assert.Equal(t, "snappy", got.Header.Get("Content-Encoding"))
+	assert.Equal(t, "0.1.0", got.Header.Get("X-Prometheus-Remote-Write-Version"))
+	assert.Equal(t, "Bearer token", got.Header.Get("Authorization"))
+	assert.Equal(t, "tenant-a", got.Header.Get("X-Scope-OrgID"))
+
+	encoded, err := snappy.Decode(nil, gotBody)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), "tenant_id")
+	assert.Contains(t, string(encoded), "target_info")
+}
@@ -0,0 +1,173 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/profilepb"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+var serviceNameAttrKey = "service.name"
+
+// unknownService is the service name reported for resources without a service.name attribute.
+const unknownService = "unknown_service"
+
+// maxUntenantedServices bounds the service names listed in the partial success message, so a payload of many
+// services cannot produce an unbounded response.
+const maxUntenantedServices = 10
+
+// untenanted describes the resources dropped because no tenant could be resolved.
+type untenanted struct {
+	resources int
+	records   int
+	// services are the distinct service names of the resources in order of appearance, at most maxUntenantedServices.
+	services []string
+	// omitted is the number of distinct service names beyond maxUntenantedServices.
+	omitted int
+}
+
+// newUntenanted describes the resources dropped because no tenant could be resolved.
+func newUntenanted[T processor.ResourceData](resources []T) untenanted {
+	u := untenanted{resources: len(resources)}
+	seen := make(map[string]bool)
+
+	for _, resource := range resources {
+		u.records += proto.CountRecords(resource)
+
+		service := unknownService
+		for _, attr := range resource.GetResource().GetAttributes() {
+			if attr.GetKey() == serviceNameAttrKey && attr.GetValue().GetStringValue() != "" {
+				service = attr.GetValue().GetStringValue()
+				break
+			}
+		}
+
+		if seen[service] {
+			continue
+		}
+		seen[service] = true
+
+		if len(u.services) < maxUntenantedServices {
+			u.services = append(u.services, service)
+		} else {
+			u.omitted++
+		}
+	}
+
+	return u
+}
+
+// message describes the dropped resources and the services that sent them.
+func (u untenanted) message() string {
+	message := fmt.Sprintf("dropped %d resources without a tenant from services %s", u.resources, strings.Join(u.services, ", "))
+	if u.omitted > 0 {
+		message += fmt.Sprintf(" and %d more", u.omitted)
+	}
+	return message
+}
+
+// partial describes the records of an accepted request that were not forwarded.
+type partial struct {
+	// skipped are the resources that could not be parsed.
+	skipped proto.Skipped
+	// dropped are the resources without a tenant.
+	dropped untenanted
+	// rejected are the records of the tenants that failed in a request accepted by the at-least-one policy.
+	rejected processor.Rejected
+}
+
+// records returns the number of records that were not forwarded.
+func (p partial) records() int {
+	return p.skipped.Records + p.dropped.records + p.rejected.Records
+}
+
+// message describes why the records were not forwarded.
+func (p partial) message() string {
+	var messages []string
+	if p.skipped.Resources > 0 {
+		messages = append(messages, fmt.Sprintf("skipped %d resources that could not be parsed", p.skipped.Resources))
+	}
+	if p.dropped.resources > 0 {
+		messages = append(messages, p.dropped.message())
+	}
+	if len(p.rejected.Tenants) > 0 {
+		messages = append(messages, p.rejected.Message())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// exportResponse returns the export response of the signal, with a partial success reporting the records that were
+// not forwarded when there are any.
+func exportResponse(signal string, p partial) protobuf.Message {
+	accepted := p.skipped.Resources == 0 && p.dropped.resources == 0 && len(p.rejected.Tenants) == 0
+	rejected := int64(p.records())
+	message := p.message()
+
+	switch signal {
+	case "logs":
+		if accepted {
+			return &collogspb.ExportLogsServiceResponse{}
+		}
+		return &collogspb.ExportLogsServiceResponse{PartialSuccess: &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: rejected,
+			ErrorMessage:       message,
+		}}
+	case "metrics":
+		if accepted {
+			return &colmetricspb.ExportMetricsServiceResponse{}
+		}
+		return &colmetricspb.ExportMetricsServiceResponse{PartialSuccess: &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       message,
+		}}
+	case "profiles":
+		if accepted {
+			return &profilepb.ExportProfilesServiceResponse{}
+		}
+		return &profilepb.ExportProfilesServiceResponse{PartialSuccess: &profilepb.ExportProfilesPartialSuccess{
+			RejectedProfiles: rejected,
+			ErrorMessage:     message,
+		}}
+	default:
+		if accepted {
+			return &coltracepb.ExportTraceServiceResponse{}
+		}
+		return &coltracepb.ExportTraceServiceResponse{PartialSuccess: &coltracepb.ExportTracePartialSuccess{
+			RejectedSpans: rejected,
+			ErrorMessage:  message,
+		}}
+	}
+}
+
+// writeExportResponse writes the export response of the signal using the encoding of the request. Accepted requests
+// are answered with 200 in strict mode, as required by the OTLP specification, and 202 otherwise.
+func (h *Handlers) writeExportResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, signal string, p partial) {
+	statusCode := http.StatusAccepted
+	if h.config.Ingest.Strict {
+		statusCode = http.StatusOK
+	}
+
+	encoding := responseEncoding(r)
+	body, err := proto.MarshalEncoding(exportResponse(signal, p), encoding)
+	if err != nil {
+		logger.Error(ctx, err.Error())
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", proto.ContentType(encoding))
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		logger.Error(ctx, err.Error())
+	}
+}
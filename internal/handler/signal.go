@@ -0,0 +1,326 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/transform"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+var (
+	errEmptyPayload           = errors.New("empty payload: no resources found")
+	errUnsupportedContentType = errors.New("unsupported content type: expected application/x-protobuf or application/json")
+
+	schemaURLAttrKey        = "schema.url"
+	schemaURLAllowedAttrKey = "schema.url.allowed"
+	signalTenantAttrKey     = "signal.tenant"
+	contentTypeAttrKey      = "http.request.header.content-type"
+)
+
+// handle unmarshals an incoming OTLP payload, partitions its resources by tenant, applies the transforms to the
+// resources of each tenant and dispatches them to the backend.
+func handle[T processor.ResourceData, M protobuf.Message](
+	h *Handlers,
+	w http.ResponseWriter,
+	r *http.Request,
+	signal string,
+	p *processor.Processor[T],
+	target M,
+	getResources func(M) []T,
+	transforms ...func(ctx context.Context, tenant string, resources []T),
+) {
+	// Record the sender of the request for the logs and spans of every stage handling its data
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, signal))
+
+	// Only exports are accepted, whatever the route the handler is registered on
+	if r.Method != http.MethodPost {
+		h.MethodNotAllowed(w, r)
+		return
+	}
+
+	// The checks below answer the request before reading its body when they can, sparing clients expecting
+	// 100 Continue the upload of a rejected body
+	defer h.trackContinue(r, signal)(ctx)
+
+	// Check the content type, payloads without a supported one are decoded as protobuf binary unless strict
+	if !proto.IsSupported(r.Header.Get("Content-Type")) && h.rejectContentType(ctx, r, signal) {
+		h.writeError(ctx, w, r, http.StatusUnsupportedMediaType, errUnsupportedContentType)
+		span.RecordError(errUnsupportedContentType)
+		span.SetStatus(codes.Error, errUnsupportedContentType.Error())
+		return
+	}
+
+	// Enforce the body size limit of the signal, refusing bodies announced as larger before reading them
+	if limit := h.maxBodyBytes(signal); limit > 0 {
+		if r.ContentLength > limit {
+			h.rejectOversized(ctx, w, r, signal, limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+
+	// Verify the signature of the request body, binding the request to the tenant that signed it
+	if h.signatures != nil {
+		signed, statusCode, err := h.verifySignature(r, signal)
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			h.rejectOversized(ctx, w, r, signal, maxBytesErr.Limit)
+			return
+		}
+		if err != nil {
+			h.writeError(ctx, w, r, statusCode, err)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return
+		}
+		r, ctx = signed, signed.Context()
+	}
+
+	// Unmarshal the incoming data
+	unmarshalStart := time.Now()
+	data, skipped, err := unmarshal(h, r, target)
+	p.RecordStage(ctx, processor.StageUnmarshal, unmarshalStart)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.rejectOversized(ctx, w, r, signal, maxBytesErr.Limit)
+			return
+		}
+
+		statusCode := http.StatusBadRequest
+		if !proto.IsSupported(r.Header.Get("Content-Type")) {
+			// Tell the sender the payload was decoded as protobuf because of its content type
+			err = fmt.Errorf("%w: decoding as application/x-protobuf failed: %w", errUnsupportedContentType, err)
+		}
+
+		logger.Error(ctx, err.Error(), requestmeta.FromContext(ctx).Attributes()...)
+		h.writeError(ctx, w, r, statusCode, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	if skipped.Resources > 0 {
+		h.recordInvalidResources(ctx, signal, skipped)
+	}
+
+	resources := getResources(data)
+	if len(resources) == 0 && skipped.Resources == 0 {
+		// Answer the heartbeats of authenticated senders without going through the pipeline
+		if h.heartbeat(ctx, w, r, signal) {
+			span.SetStatus(codes.Ok, "heartbeat")
+			return
+		}
+
+		if h.rejectEmptyPayload(ctx, signal) {
+			h.writeError(ctx, w, r, http.StatusBadRequest, errEmptyPayload)
+			span.RecordError(errEmptyPayload)
+			span.SetStatus(codes.Error, errEmptyPayload.Error())
+			return
+		}
+	}
+
+	// Process the data
+	partial, err := process(ctx, h, signal, p, resources, transforms...)
+	if err != nil {
+		logger.Error(ctx, err.Error(), requestmeta.FromContext(ctx).Attributes()...)
+		statusCode := h.processStatus(err)
+		setRetryAfter(w, statusCode, err)
+		h.writeError(ctx, w, r, statusCode, err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	partial.skipped = skipped
+	h.writeExportResponse(ctx, w, r, signal, partial)
+}
+
+// process partitions the resources by tenant, applies the transforms to the resources of each tenant and dispatches
+// them to the backend, returning the resources dropped because no tenant could be resolved and the records of the
+// tenants that failed in an accepted request.
+func process[T processor.ResourceData](
+	ctx context.Context,
+	h *Handlers,
+	signal string,
+	p *processor.Processor[T],
+	resources []T,
+	transforms ...func(ctx context.Context, tenant string, resources []T),
+) (partial, error) {
+	receivedAt := time.Now()
+
+	// Record and enforce the schema URLs of the resources
+	resources = filterSchemaURLs(ctx, h, signal, resources)
+
+	// Partition and transform the data per tenant
+	tenantMap, dropped, err := p.Partition(ctx, resources)
+	if err != nil {
+		return partial{}, err
+	}
+	if err := checkSigner(ctx, h, signal, tenantMap); err != nil {
+		return partial{}, err
+	}
+	for tenant, tenantResources := range tenantMap {
+		for _, transform := range transforms {
+			transform(ctx, tenant, tenantResources)
+		}
+		transform.Enrich(h.enrichment, tenantResources, receivedAt)
+	}
+
+	// In async mode the request is answered before its data is forwarded, failures are only logged and counted
+	if h.dispatchAsync(ctx, signal, func(ctx context.Context) { _, _ = p.DispatchPartial(ctx, tenantMap) }) {
+		return partial{dropped: newUntenanted(dropped)}, nil
+	}
+
+	rejected, err := p.DispatchPartial(ctx, tenantMap)
+	return partial{dropped: newUntenanted(dropped), rejected: rejected}, err
+}
+
+// unmarshal unmarshals the incoming payload, skipping the resources that cannot be parsed when configured to.
+func unmarshal[M protobuf.Message](h *Handlers, r *http.Request, target M) (M, proto.Skipped, error) {
+	if h.config.Ingest.InvalidResources == config.InvalidResourcesSkip {
+		return proto.UnmarshalLenient(r, target)
+	}
+
+	data, err := proto.Unmarshal(r, target)
+	return data, proto.Skipped{}, err
+}
+
+// rejectEmptyPayload records an empty payload and reports whether it should be rejected.
+func (h *Handlers) rejectEmptyPayload(ctx context.Context, signal string) bool {
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(clientAddressAttrKey, requestmeta.FromContext(ctx).ClientAddress),
+	}
+	h.emptyPayloadsMetric.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+	if h.config.Ingest.EmptyPayload != config.EmptyPayloadReject {
+		logger.Debug(ctx, "received empty payload", attrs...)
+		return false
+	}
+
+	logger.Warn(ctx, errEmptyPayload.Error(), attrs...)
+	return true
+}
+
+// rejectContentType records a payload without a supported content type and reports whether it should be rejected.
+func (h *Handlers) rejectContentType(ctx context.Context, r *http.Request, signal string) bool {
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(clientAddressAttrKey, requestmeta.FromContext(ctx).ClientAddress),
+	}
+	h.unsupportedContentTypesMetric.Add(ctx, 1, metric.WithAttributes(attrs...))
+
+	attrs = append(attrs, attribute.String(contentTypeAttrKey, r.Header.Get("Content-Type")))
+	if h.config.Ingest.ContentType != config.ContentTypeStrict && !h.config.Ingest.Strict {
+		logger.Debug(ctx, "decoding payload without a supported content type as protobuf", attrs...)
+		return false
+	}
+
+	logger.Warn(ctx, errUnsupportedContentType.Error(), attrs...)
+	return true
+}
+
+// recordInvalidResources records the resources skipped because they could not be parsed.
+func (h *Handlers) recordInvalidResources(ctx context.Context, signal string, skipped proto.Skipped) {
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(clientAddressAttrKey, requestmeta.FromContext(ctx).ClientAddress),
+	}
+	h.invalidResourcesMetric.Add(ctx, int64(skipped.Resources), metric.WithAttributes(attrs...))
+	logger.Warn(ctx, "skipped resources that could not be parsed",
+		append(attrs, attribute.Int("resources", skipped.Resources), attribute.Int("records", skipped.Records))...)
+}
+
+// validateIngest returns an error when a policy for inbound payloads is unknown, empty policies keeping their default:
+// accepting empty payloads, rejecting payloads with invalid resources and decoding payloads without a supported content
+// type leniently.
+func validateIngest(ingest *config.Ingest) error {
+	switch ingest.EmptyPayload {
+	case "", config.EmptyPayloadAccept, config.EmptyPayloadReject:
+	default:
+		return fmt.Errorf("invalid ingest empty payload policy %q, expected %s or %s", ingest.EmptyPayload,
+			config.EmptyPayloadAccept, config.EmptyPayloadReject)
+	}
+
+	switch ingest.InvalidResources {
+	case "", config.InvalidResourcesReject, config.InvalidResourcesSkip:
+	default:
+		return fmt.Errorf("invalid ingest invalid resources policy %q, expected %s or %s", ingest.InvalidResources,
+			config.InvalidResourcesReject, config.InvalidResourcesSkip)
+	}
+
+	switch ingest.ContentType {
+	case "", config.ContentTypeLenient, config.ContentTypeStrict:
+	default:
+		return fmt.Errorf("invalid ingest content type policy %q, expected %s or %s", ingest.ContentType,
+			config.ContentTypeLenient, config.ContentTypeStrict)
+	}
+	return nil
+}
+
+// filterSchemaURLs records the schema URLs of the resources and drops resources whose schema URL is not allowed.
+//
+// Resources without a schema URL are always accepted. When no schema URLs are configured every resource is accepted.
+func filterSchemaURLs[T processor.ResourceData](ctx context.Context, h *Handlers, signal string, resources []T) []T {
+	allowed := h.config.Ingest.AllowedSchemaURLs
+	seen := make(map[string]bool)
+	filtered := make([]T, 0, len(resources))
+
+	for _, resource := range resources {
+		schemaURL := resource.GetSchemaUrl()
+		ok := schemaURL == "" || len(allowed) == 0 || slices.Contains(allowed, schemaURL)
+		if ok {
+			filtered = append(filtered, resource)
+		}
+
+		if _, recorded := seen[schemaURL]; recorded {
+			continue
+		}
+		seen[schemaURL] = ok
+
+		attrs := []attribute.KeyValue{
+			attribute.String(signalTypeAttrKey, signal),
+			attribute.String(schemaURLAttrKey, schemaURL),
+			attribute.Bool(schemaURLAllowedAttrKey, ok),
+		}
+		h.schemaURLsMetric.Add(ctx, 1, metric.WithAttributes(attrs...))
+		if !ok {
+			logger.Warn(ctx, "dropping resources with a schema url that is not allowed", attrs...)
+		}
+	}
+
+	return filtered
+}
+
+// recordDuplicates records the duplicate records dropped from the resources of a tenant.
+func (h *Handlers) recordDuplicates(ctx context.Context, signal, tenant string, dropped int) {
+	if dropped == 0 {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(signalTenantAttrKey, tenant),
+	}
+	h.duplicatesMetric.Add(ctx, int64(dropped), metric.WithAttributes(attrs...))
+	logger.Debug(ctx, "dropped duplicate records", append(attrs, attribute.Int("dropped", dropped))...)
+}
@@ -0,0 +1,904 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func testResource(tenant string) *resourcepb.Resource {
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}}},
+		},
+	}
+}
+
+func newTestHandlers(t *testing.T, cfg *config.Config, client processor.Client) *Handlers {
+	t.Helper()
+
+	h, err := New(
+		cfg,
+		http.NewServeMux(),
+		client,
+		client,
+		client,
+		client,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+	)
+	require.NoError(t, err)
+
+	return h
+}
+
+func TestSignalHandlers(t *testing.T) {
+	tests := []struct {
+		name         string
+		emptyPayload string
+		path         string
+		payload      proto.Message
+		body         []byte
+		backendCalls int
+		wantStatus   int
+	}{
+		{
+			name:         "logs are dispatched",
+			path:         "/v1/logs",
+			payload:      &logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}},
+			backendCalls: 1,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:         "metrics are dispatched",
+			path:         "/v1/metrics",
+			payload:      &metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{{Resource: testResource("tenant-a")}}},
+			backendCalls: 1,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name: "traces are dispatched per tenant",
+			path: "/v1/traces",
+			payload: &tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+				{Resource: testResource("tenant-a")},
+				{Resource: testResource("tenant-b")},
+			}},
+			backendCalls: 2,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:       "invalid payload is rejected",
+			path:       "/v1/logs",
+			body:       []byte("invalid data"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "empty payload is accepted by default",
+			emptyPayload: config.EmptyPayloadAccept,
+			path:         "/v1/metrics",
+			payload:      &metricpb.MetricsData{},
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:         "empty payload is rejected when configured",
+			emptyPayload: config.EmptyPayloadReject,
+			path:         "/v1/traces",
+			payload:      &tracepb.TracesData{},
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(*http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+			}).Times(tt.backendCalls)
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{EmptyPayload: tt.emptyPayload},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			body := tt.body
+			if tt.payload != nil {
+				var err error
+				body, err = proto.Marshal(tt.payload)
+				require.NoError(t, err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			rec := httptest.NewRecorder()
+
+			switch tt.path {
+			case "/v1/logs":
+				h.Logs(rec, req)
+			case "/v1/metrics":
+				h.Metrics(rec, req)
+			case "/v1/traces":
+				h.Traces(rec, req)
+			}
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestSignalHandlers_PreservesUnknownFields(t *testing.T) {
+	unknown := protowire.AppendString(protowire.AppendTag(nil, 1000, protowire.BytesType), "newer schema field")
+
+	span := &tracepb.Span{Name: "span"}
+	span.ProtoReflect().SetUnknown(unknown)
+	resource := testResource("tenant-a")
+	resource.ProtoReflect().SetUnknown(unknown)
+	// The second resource has no tenant and is modified by the proxy to carry the default tenant.
+	untenanted := &resourcepb.Resource{}
+	untenanted.ProtoReflect().SetUnknown(unknown)
+
+	body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+		{Resource: resource, ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span}}}},
+		{Resource: untenanted},
+	}})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		data := &tracepb.TracesData{}
+		require.NoError(t, proto.Unmarshal(forwarded, data))
+		require.Len(t, data.GetResourceSpans(), 1)
+
+		rs := data.GetResourceSpans()[0]
+		assert.Equal(t, unknown, []byte(rs.GetResource().ProtoReflect().GetUnknown()))
+		if spans := rs.GetScopeSpans(); len(spans) > 0 {
+			assert.Equal(t, unknown, []byte(spans[0].GetSpans()[0].ProtoReflect().GetUnknown()))
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}).Times(2)
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+	}, client)
+
+	rec := httptest.NewRecorder()
+	h.Traces(rec, httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestSignalHandlers_PreservesSamplingState(t *testing.T) {
+	span := &tracepb.Span{
+		TraceId:      bytes.Repeat([]byte{0x01}, 16),
+		SpanId:       bytes.Repeat([]byte{0x02}, 8),
+		ParentSpanId: bytes.Repeat([]byte{0x03}, 8),
+		Name:         "span",
+		TraceState:   "rojo=00f067aa0ba902b7,th:8",
+		Flags:        uint32(0x01 | tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK | tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_IS_REMOTE_MASK),
+		Attributes: []*commonpb.KeyValue{
+			{Key: "sampling.priority", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 1}}},
+		},
+		Links: []*tracepb.Span_Link{{
+			TraceId:    bytes.Repeat([]byte{0x04}, 16),
+			SpanId:     bytes.Repeat([]byte{0x05}, 8),
+			TraceState: "congo=t61rcWkgMzE",
+			Flags:      uint32(tracepb.SpanFlags_SPAN_FLAGS_CONTEXT_HAS_IS_REMOTE_MASK),
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		encoding  string
+		unmarshal func([]byte, proto.Message) error
+	}{
+		{
+			name:      "protobuf",
+			encoding:  config.EncodingProtobuf,
+			unmarshal: proto.Unmarshal,
+		},
+		{
+			name:      "json",
+			encoding:  config.EncodingJSON,
+			unmarshal: protojson.Unmarshal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+
+				data := &tracepb.TracesData{}
+				require.NoError(t, tt.unmarshal(forwarded, data))
+				require.Len(t, data.GetResourceSpans(), 1)
+				require.Len(t, data.GetResourceSpans()[0].GetScopeSpans(), 1)
+				require.Len(t, data.GetResourceSpans()[0].GetScopeSpans()[0].GetSpans(), 1)
+
+				assert.True(t, proto.Equal(span, data.GetResourceSpans()[0].GetScopeSpans()[0].GetSpans()[0]))
+
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			})
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Traces: config.Endpoint{Address: "http://tempo:4318/v1/traces", Encoding: tt.encoding},
+			}, client)
+
+			body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+				Resource:   testResource("tenant-a"),
+				ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span}}},
+			}}})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.Traces(rec, httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body)))
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+		})
+	}
+}
+
+func TestSignalHandlers_JSONEncoding(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		data := &metricpb.MetricsData{}
+		require.NoError(t, protojson.Unmarshal(forwarded, data))
+		assert.Len(t, data.GetResourceMetrics(), 1)
+
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant:  config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Metrics: config.Endpoint{Address: "http://gateway:4318/v1/metrics", Encoding: config.EncodingJSON},
+	}, client)
+
+	body, err := proto.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.Metrics(rec, httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestSignalHandlers_SchemaURLs(t *testing.T) {
+	const (
+		v121 = "https://opentelemetry.io/schemas/1.21.0"
+		v126 = "https://opentelemetry.io/schemas/1.26.0"
+	)
+
+	tests := []struct {
+		name        string
+		allowed     []string
+		wantSchemas []string
+	}{
+		{
+			name:        "schema urls are preserved",
+			wantSchemas: []string{"", v121, v126},
+		},
+		{
+			name:        "resources with a schema url that is not allowed are dropped",
+			allowed:     []string{v126},
+			wantSchemas: []string{"", v126},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := []string{}
+
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				data := &logpb.LogsData{}
+				require.NoError(t, proto.Unmarshal(forwarded, data))
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, rl := range data.GetResourceLogs() {
+					got = append(got, rl.GetSchemaUrl())
+					for _, sl := range rl.GetScopeLogs() {
+						assert.Equal(t, rl.GetSchemaUrl(), sl.GetSchemaUrl(), "scope schema url must be preserved")
+					}
+				}
+
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).AnyTimes()
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{AllowedSchemaURLs: tt.allowed},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			resourceLogs := []*logpb.ResourceLogs{}
+			for i, schemaURL := range []string{"", v121, v126} {
+				resourceLogs = append(resourceLogs, &logpb.ResourceLogs{
+					Resource:  testResource(fmt.Sprintf("tenant-%d", i)),
+					SchemaUrl: schemaURL,
+					ScopeLogs: []*logpb.ScopeLogs{{SchemaUrl: schemaURL}},
+				})
+			}
+			body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: resourceLogs})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.Logs(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+			assert.ElementsMatch(t, tt.wantSchemas, got)
+		})
+	}
+}
+
+func TestSignalHandlers_MetricAttributeAllowlist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		data := &metricpb.MetricsData{}
+		require.NoError(t, proto.Unmarshal(forwarded, data))
+
+		points := data.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()[0].GetSum().GetDataPoints()
+		require.Len(t, points, 1)
+		assert.Equal(t, int64(3), points[0].GetAsInt())
+		require.Len(t, points[0].GetAttributes(), 1)
+		assert.Equal(t, "http.route", points[0].GetAttributes()[0].GetKey())
+
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	h := newTestHandlers(t, &config.Config{
+		Tenant:    config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Transform: config.Transform{MetricAttributeAllowlist: []string{"tenant-a=http.route"}},
+	}, client)
+
+	point := func(pod string, value int64) *metricpb.NumberDataPoint {
+		return &metricpb.NumberDataPoint{
+			Attributes: []*commonpb.KeyValue{
+				{Key: "http.route", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "/"}}},
+				{Key: "k8s.pod.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: pod}}},
+			},
+			Value: &metricpb.NumberDataPoint_AsInt{AsInt: value},
+		}
+	}
+	body, err := proto.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{{
+		Resource: testResource("tenant-a"),
+		ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{{
+			Name: "http.server.requests",
+			Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{point("a", 1), point("b", 2)}}},
+		}}}},
+	}}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.Metrics(rec, httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestSignalHandlers_Enrichment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		forwarded, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		data := &logpb.LogsData{}
+		require.NoError(t, proto.Unmarshal(forwarded, data))
+
+		attrs := map[string]string{}
+		for _, attr := range data.GetResourceLogs()[0].GetResource().GetAttributes() {
+			attrs[attr.GetKey()] = attr.GetValue().GetStringValue()
+		}
+		assert.Equal(t, "tenant-a", attrs["tenant.id"])
+		assert.Equal(t, "replica-1", attrs["proxy.instance"])
+		receivedAt, err := time.Parse(time.RFC3339Nano, attrs["proxy.received_at"])
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), receivedAt, time.Minute)
+
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	h := newTestHandlers(t, &config.Config{
+		Service: config.Service{InstanceID: "replica-1"},
+		Tenant:  config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		Transform: config.Transform{
+			ReceivedAtAttribute: "proxy.received_at",
+			InstanceAttribute:   "proxy.instance",
+		},
+	}, client)
+
+	body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.Logs(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestSignalHandlers_DedupSpans(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		wantSpans int
+	}{
+		{
+			name:      "disabled forwards duplicates",
+			wantSpans: 3,
+		},
+		{
+			name:      "enabled drops duplicates",
+			enabled:   true,
+			wantSpans: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				forwarded, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+
+				data := &tracepb.TracesData{}
+				require.NoError(t, proto.Unmarshal(forwarded, data))
+
+				spans := 0
+				for _, rs := range data.GetResourceSpans() {
+					for _, ss := range rs.GetScopeSpans() {
+						spans += len(ss.GetSpans())
+					}
+				}
+				assert.Equal(t, tt.wantSpans, spans)
+
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			})
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant:    config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Transform: config.Transform{DedupSpans: tt.enabled},
+			}, client)
+
+			traceID := bytes.Repeat([]byte{0x01}, 16)
+			body, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+				{
+					Resource:   testResource("tenant-a"),
+					ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{TraceId: traceID, SpanId: []byte{1, 0, 0, 0, 0, 0, 0, 0}}}}},
+				},
+				{
+					Resource: testResource("tenant-a"),
+					ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{
+						{TraceId: traceID, SpanId: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+						{TraceId: traceID, SpanId: []byte{2, 0, 0, 0, 0, 0, 0, 0}},
+					}}},
+				},
+			}})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.Traces(rec, httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(body)))
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+		})
+	}
+}
+
+func TestSignalHandlers_InvalidResources(t *testing.T) {
+	valid, err := proto.Marshal(&logpb.ResourceLogs{Resource: testResource("tenant-a")})
+	require.NoError(t, err)
+
+	// A resource with a log record followed by a schema url that is not valid UTF-8.
+	invalid, err := proto.Marshal(&logpb.ResourceLogs{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{}}}}})
+	require.NoError(t, err)
+	invalid = protowire.AppendBytes(protowire.AppendTag(invalid, 3, protowire.BytesType), []byte{0xff})
+
+	var body []byte
+	for _, entry := range [][]byte{valid, invalid} {
+		body = protowire.AppendBytes(protowire.AppendTag(body, 1, protowire.BytesType), entry)
+	}
+
+	tests := []struct {
+		name             string
+		invalidResources string
+		wantStatus       int
+		wantForwarded    bool
+	}{
+		{
+			name:             "reject fails the request",
+			invalidResources: config.InvalidResourcesReject,
+			wantStatus:       http.StatusBadRequest,
+		},
+		{
+			name:             "skip forwards the remainder",
+			invalidResources: config.InvalidResourcesSkip,
+			wantStatus:       http.StatusAccepted,
+			wantForwarded:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.wantForwarded {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{InvalidResources: tt.invalidResources},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			rec := httptest.NewRecorder()
+			h.Logs(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if !tt.wantForwarded {
+				return
+			}
+
+			response := &collogspb.ExportLogsServiceResponse{}
+			require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), response))
+			assert.Equal(t, int64(1), response.GetPartialSuccess().GetRejectedLogRecords())
+			assert.NotEmpty(t, response.GetPartialSuccess().GetErrorMessage())
+		})
+	}
+}
+
+func TestSignalHandlers_UntenantedResources(t *testing.T) {
+	service := func(name string, records int) *logpb.ResourceLogs {
+		resource := &logpb.ResourceLogs{
+			Resource:  &resourcepb.Resource{},
+			ScopeLogs: []*logpb.ScopeLogs{{LogRecords: make([]*logpb.LogRecord, records)}},
+		}
+		for i := range records {
+			resource.ScopeLogs[0].LogRecords[i] = &logpb.LogRecord{}
+		}
+		if name != "" {
+			resource.Resource.Attributes = []*commonpb.KeyValue{
+				{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: name}}},
+			}
+		}
+		return resource
+	}
+
+	tests := []struct {
+		name          string
+		resources     []*logpb.ResourceLogs
+		wantRejected  int64
+		wantMessage   string
+		wantForwarded bool
+	}{
+		{
+			name: "lists the services without a tenant",
+			resources: []*logpb.ResourceLogs{
+				{Resource: testResource("tenant-a")},
+				service("checkout", 2),
+				service("checkout", 1),
+				service("", 1),
+			},
+			wantRejected:  4,
+			wantMessage:   "dropped 3 resources without a tenant from services checkout, unknown_service",
+			wantForwarded: true,
+		},
+		{
+			name: "bounds the listed services",
+			resources: func() []*logpb.ResourceLogs {
+				var resources []*logpb.ResourceLogs
+				for i := range maxUntenantedServices + 2 {
+					resources = append(resources, service(fmt.Sprintf("svc-%02d", i), 1))
+				}
+				return resources
+			}(),
+			wantRejected: int64(maxUntenantedServices + 2),
+			wantMessage: "dropped 12 resources without a tenant from services svc-00, svc-01, svc-02, svc-03, svc-04, " +
+				"svc-05, svc-06, svc-07, svc-08, svc-09 and 2 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.wantForwarded {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: tt.resources})
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.Logs(rec, httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body)))
+
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+
+			response := &collogspb.ExportLogsServiceResponse{}
+			require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), response))
+			assert.Equal(t, tt.wantRejected, response.GetPartialSuccess().GetRejectedLogRecords())
+			assert.Equal(t, tt.wantMessage, response.GetPartialSuccess().GetErrorMessage())
+		})
+	}
+}
+
+func TestSignalHandlers_ContentType(t *testing.T) {
+	payload := &logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}}
+	binary, err := proto.Marshal(payload)
+	require.NoError(t, err)
+	jsonBody, err := protojson.Marshal(payload)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		contentType   string
+		policy        string
+		body          []byte
+		wantStatus    int
+		wantError     string
+		wantForwarded bool
+	}{
+		{
+			name:          "missing content type is decoded as protobuf by default",
+			policy:        config.ContentTypeLenient,
+			body:          binary,
+			wantStatus:    http.StatusAccepted,
+			wantForwarded: true,
+		},
+		{
+			name:       "missing content type is rejected when strict",
+			policy:     config.ContentTypeStrict,
+			body:       binary,
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "json without a supported content type is not guessed",
+			contentType: "text/plain",
+			policy:      config.ContentTypeLenient,
+			body:        jsonBody,
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "unsupported content type",
+		},
+		{
+			name:        "json labelled protobuf is rejected",
+			contentType: "application/x-protobuf",
+			policy:      config.ContentTypeLenient,
+			body:        jsonBody,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "protobuf labelled json is rejected",
+			contentType: "application/json",
+			policy:      config.ContentTypeLenient,
+			body:        binary,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:          "json with charset is accepted when strict",
+			contentType:   "application/json; charset=utf-8",
+			policy:        config.ContentTypeStrict,
+			body:          jsonBody,
+			wantStatus:    http.StatusAccepted,
+			wantForwarded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.wantForwarded {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{ContentType: tt.policy},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantError)
+		})
+	}
+}
+
+func TestSignalHandlers_ExportResponse(t *testing.T) {
+	tenantLogs := func(tenant string, records int) *logpb.ResourceLogs {
+		scope := &logpb.ScopeLogs{}
+		for range records {
+			scope.LogRecords = append(scope.LogRecords, &logpb.LogRecord{})
+		}
+		return &logpb.ResourceLogs{Resource: testResource(tenant), ScopeLogs: []*logpb.ScopeLogs{scope}}
+	}
+
+	tests := []struct {
+		name            string
+		contentType     string
+		strict          bool
+		policy          string
+		failing         string
+		wantStatusCode  int
+		wantContentType string
+		wantRejected    int64
+		wantMessage     string
+	}{
+		{
+			name:            "protobuf",
+			contentType:     "application/x-protobuf",
+			wantStatusCode:  http.StatusAccepted,
+			wantContentType: "application/x-protobuf",
+		},
+		{
+			name:            "json",
+			contentType:     "application/json",
+			wantStatusCode:  http.StatusAccepted,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "strict",
+			contentType:     "application/x-protobuf",
+			strict:          true,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: "application/x-protobuf",
+		},
+		{
+			name:            "failed tenant accepted by the at-least-one policy",
+			contentType:     "application/x-protobuf",
+			policy:          config.DispatchAtLeastOne,
+			failing:         "tenant-b",
+			wantStatusCode:  http.StatusAccepted,
+			wantContentType: "application/x-protobuf",
+			wantRejected:    3,
+			wantMessage:     "failed to forward 3 records of tenants tenant-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("X-Scope-OrgID") == tt.failing {
+					return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(2)
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant:   config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Ingest:   config.Ingest{Strict: tt.strict},
+				Dispatch: config.Dispatch{ErrorPolicy: tt.policy},
+			}, client)
+
+			payload := &logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{
+				tenantLogs("tenant-a", 2),
+				tenantLogs("tenant-b", 3),
+			}}
+			var (
+				body []byte
+				err  error
+			)
+			if tt.contentType == "application/json" {
+				body, err = protojson.Marshal(payload)
+			} else {
+				body, err = proto.Marshal(payload)
+			}
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, tt.wantStatusCode, rec.Code)
+			assert.Equal(t, tt.wantContentType, rec.Header().Get("Content-Type"))
+
+			response := &collogspb.ExportLogsServiceResponse{}
+			if tt.contentType == "application/json" {
+				require.NoError(t, protojson.Unmarshal(rec.Body.Bytes(), response))
+			} else {
+				require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), response))
+			}
+			if tt.wantMessage == "" {
+				assert.Nil(t, response.GetPartialSuccess())
+				return
+			}
+			assert.Equal(t, tt.wantRejected, response.GetPartialSuccess().GetRejectedLogRecords())
+			assert.Equal(t, tt.wantMessage, response.GetPartialSuccess().GetErrorMessage())
+		})
+	}
+}
+
+func TestValidateIngest(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingest  config.Ingest
+		wantErr string
+	}{
+		{name: "defaults"},
+		{
+			name: "valid",
+			ingest: config.Ingest{
+				EmptyPayload:     config.EmptyPayloadReject,
+				InvalidResources: config.InvalidResourcesSkip,
+				ContentType:      config.ContentTypeStrict,
+			},
+		},
+		{
+			name:    "unknown empty payload policy",
+			ingest:  config.Ingest{EmptyPayload: "rejct"},
+			wantErr: `invalid ingest empty payload policy "rejct"`,
+		},
+		{
+			name:    "unknown invalid resources policy",
+			ingest:  config.Ingest{InvalidResources: "drop"},
+			wantErr: `invalid ingest invalid resources policy "drop"`,
+		},
+		{
+			name:    "unknown content type policy",
+			ingest:  config.Ingest{ContentType: "loose"},
+			wantErr: `invalid ingest content type policy "loose"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIngest(&tt.ingest)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
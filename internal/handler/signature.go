@@ -0,0 +1,111 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/signature"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	errSignerTenant = errors.New("request carries data of a tenant other than the one that signed it")
+
+	signatureReasonAttrKey = "signature.failure.reason"
+	signerAttrKey          = "signature.tenant"
+)
+
+// signerFromContext returns the tenant that signed the request, if it was verified.
+func signerFromContext(ctx context.Context) (string, bool) {
+	signer := requestmeta.FromContext(ctx).Signer
+	return signer, signer != ""
+}
+
+// verifySignature verifies the signature of the request body and restores the body for decoding. It returns the
+// request with the tenant that signed it in the metadata of its context, or the status code answering the request and
+// the error when the signature is not valid.
+//
+// Requests without a signature or signed with an unknown key are rejected before their body is read, so clients
+// expecting 100 Continue do not upload it.
+func (h *Handlers) verifySignature(r *http.Request, signal string) (*http.Request, int, error) {
+	if _, err := h.signatures.Signer(r.Header); err != nil {
+		return r, http.StatusUnauthorized, h.signatureFailure(r, signal, err)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return r, http.StatusRequestEntityTooLarge, err
+		}
+		return r, http.StatusBadRequest, err
+	}
+	if len(body) > maxBodySize {
+		return r, http.StatusRequestEntityTooLarge, errBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	signer, err := h.signatures.Verify(r.Header, body)
+	if err != nil {
+		return r, http.StatusUnauthorized, h.signatureFailure(r, signal, err)
+	}
+
+	metadata := requestmeta.FromContext(r.Context())
+	metadata.Signer = signer
+	return r.WithContext(requestmeta.NewContext(r.Context(), metadata)), 0, nil
+}
+
+// signatureFailure records the reason a request signature could not be verified and returns the error.
+func (h *Handlers) signatureFailure(r *http.Request, signal string, err error) error {
+	reason := "mismatch"
+	switch {
+	case errors.Is(err, signature.ErrMissing):
+		reason = "missing"
+	case errors.Is(err, signature.ErrUnknownKey):
+		reason = "unknown_key"
+	}
+	h.recordSignatureFailure(r.Context(), signal, reason, err, requestmeta.FromContext(r.Context()).Attributes()...)
+	return err
+}
+
+// checkSigner returns an error when the tenants of a signed request include another tenant than the one that signed
+// it, or than the tenants an alias renames it to.
+func checkSigner[T any](ctx context.Context, h *Handlers, signal string, tenantMap map[string][]T) error {
+	signer, ok := signerFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	for tenant := range tenantMap {
+		if tenant == signer || h.signerAliases[tenant] == signer {
+			continue
+		}
+		err := fmt.Errorf("%w: %s", errSignerTenant, tenant)
+		h.recordSignatureFailure(ctx, signal, "tenant", err, attribute.String(signerAttrKey, signer))
+		return err
+	}
+	return nil
+}
+
+// recordSignatureFailure records a request rejected because of its signature, logging it with the extra attributes.
+func (h *Handlers) recordSignatureFailure(
+	ctx context.Context,
+	signal, reason string,
+	err error,
+	extra ...attribute.KeyValue,
+) {
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, signal),
+		attribute.String(signatureReasonAttrKey, reason),
+	}
+	h.signatureFailuresMetric.Add(ctx, 1, metric.WithAttributes(attrs...))
+	logger.Warn(ctx, err.Error(), append(attrs, extra...)...)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignalHandlers_Signature(t *testing.T) {
+	secret := []byte("s3cr3t")
+	payload := func(tenant string) []byte {
+		body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource(tenant)}}})
+		require.NoError(t, err)
+		return body
+	}
+
+	tests := []struct {
+		name         string
+		body         []byte
+		keyID        string
+		signature    string
+		aliases      []string
+		backendCalls int
+		wantStatus   int
+	}{
+		{
+			name:         "signed",
+			body:         payload("tenant-a"),
+			keyID:        "tenant-a",
+			signature:    signature.Sign(secret, payload("tenant-a")),
+			backendCalls: 1,
+			wantStatus:   http.StatusAccepted,
+		},
+		{
+			name:       "unsigned",
+			body:       payload("tenant-a"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown key id",
+			body:       payload("tenant-a"),
+			keyID:      "tenant-c",
+			signature:  signature.Sign(secret, payload("tenant-a")),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "tampered body",
+			body:       payload("tenant-a"),
+			keyID:      "tenant-a",
+			signature:  signature.Sign(secret, payload("tenant-x")),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "data of another tenant",
+			body:       payload("tenant-b"),
+			keyID:      "tenant-a",
+			signature:  signature.Sign(secret, payload("tenant-b")),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:         "data of the tenant an alias renames the signer to",
+			body:         payload("tenant-a"),
+			keyID:        "tenant-a",
+			signature:    signature.Sign(secret, payload("tenant-a")),
+			aliases:      []string{"tenant-a=tenant-z"},
+			backendCalls: 2,
+			wantStatus:   http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil).
+				Times(tt.backendCalls)
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Aliases: tt.aliases},
+				Signature: config.Signature{
+					Header:      "X-Signature",
+					KeyIDHeader: "X-Signature-Key-Id",
+					Secrets:     []string{"tenant-a=s3cr3t", "tenant-b=other"},
+				},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+			if tt.keyID != "" {
+				req.Header.Set("X-Signature-Key-Id", tt.keyID)
+			}
+			if tt.signature != "" {
+				req.Header.Set("X-Signature", tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
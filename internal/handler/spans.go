@@ -0,0 +1,107 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/jaeger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/zipkin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ZipkinSpans handles Zipkin v2 JSON span requests, converting the spans into traces.
+func (h *Handlers) ZipkinSpans(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "traces"))
+
+	b, err := h.readSignalBody(w, r, "traces")
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		writeSpansError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	spans, err := zipkin.Parse(b)
+	if err != nil {
+		writeSpansError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	resources, err := zipkin.New(h.config.Tenant.Label, h.config.Zipkin.TenantTag).ResourceSpans(spans)
+	if err != nil {
+		writeSpansError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.IngestTraces(ctx, resources); err != nil {
+		writeSpansError(ctx, w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// JaegerTraces handles Jaeger span batches, encoded with Thrift binary or protobuf, converting the spans into traces.
+func (h *Handlers) JaegerTraces(w http.ResponseWriter, r *http.Request) {
+	r = h.withSender(r)
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String(signalTypeAttrKey, "traces"))
+
+	var unmarshal func([]byte) (*jaeger.Batch, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+		unmarshal = jaeger.UnmarshalThrift
+	case "application/x-protobuf", "application/protobuf":
+		unmarshal = jaeger.UnmarshalProto
+	default:
+		err := fmt.Errorf("unsupported content type %q", r.Header.Get("Content-Type"))
+		writeSpansError(ctx, w, span, http.StatusUnsupportedMediaType, err)
+		return
+	}
+
+	b, err := h.readSignalBody(w, r, "traces")
+	if errors.Is(err, errBodyTooLarge) {
+		return
+	}
+	if err != nil {
+		writeSpansError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	batch, err := unmarshal(b)
+	if err != nil {
+		writeSpansError(ctx, w, span, http.StatusBadRequest, err)
+		return
+	}
+
+	resources := jaeger.New(h.config.Tenant.Label, h.config.Jaeger.TenantTag).ResourceSpans([]jaeger.Batch{*batch})
+	if err := h.IngestTraces(ctx, resources); err != nil {
+		writeSpansError(ctx, w, span, http.StatusInternalServerError, err)
+		return
+	}
+
+	span.SetStatus(codes.Ok, "processed successfully")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeSpansError records the error and writes it as plain text, like the Zipkin and Jaeger collectors.
+func writeSpansError(ctx context.Context, w http.ResponseWriter, span trace.Span, status int, err error) {
+	logger.Error(ctx, err.Error())
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestSpans(t *testing.T) {
+	// A model.proto Batch with one span tagged with the tenant
+	message := func(b []byte, num protowire.Number, v []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), v)
+	}
+	var tag []byte
+	tag = message(tag, 1, []byte("tenant"))
+	tag = message(tag, 3, []byte("tenant-b"))
+	var span []byte
+	span = message(span, 1, bytes.Repeat([]byte{1}, 16))
+	span = message(span, 2, bytes.Repeat([]byte{2}, 8))
+	span = message(span, 3, []byte("charge"))
+	span = message(span, 8, tag)
+	batch := message(message(nil, 1, span), 2, message(nil, 1, []byte("checkout")))
+
+	tests := []struct {
+		name        string
+		handler     func(h *Handlers) http.HandlerFunc
+		body        []byte
+		contentType string
+		wantTenants []string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:    "zipkin",
+			handler: func(h *Handlers) http.HandlerFunc { return h.ZipkinSpans },
+			body: []byte(`[{"traceId":"8448eb211c80319c","id":"b7ad6b7169203331","name":"get","tags":{"tenant":"tenant-a"}},` +
+				`{"traceId":"8448eb211c80319c","id":"b7ad6b7169203332","name":"get"}]`),
+			contentType: "application/json",
+			wantTenants: []string{"tenant-a", "default"},
+			wantStatus:  http.StatusAccepted,
+		},
+		{
+			name:        "invalid zipkin id",
+			handler:     func(h *Handlers) http.HandlerFunc { return h.ZipkinSpans },
+			body:        []byte(`[{"traceId":"trace","id":"b7ad6b7169203331"}]`),
+			contentType: "application/json",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid trace id",
+		},
+		{
+			name:        "jaeger protobuf",
+			handler:     func(h *Handlers) http.HandlerFunc { return h.JaegerTraces },
+			body:        batch,
+			contentType: "application/x-protobuf",
+			wantTenants: []string{"tenant-b"},
+			wantStatus:  http.StatusAccepted,
+		},
+		{
+			name:        "invalid jaeger thrift",
+			handler:     func(h *Handlers) http.HandlerFunc { return h.JaegerTraces },
+			body:        []byte{0x0c, 0x00},
+			contentType: "application/vnd.apache.thrift.binary",
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    "invalid jaeger thrift batch",
+		},
+		{
+			name:        "unsupported jaeger content type",
+			handler:     func(h *Handlers) http.HandlerFunc { return h.JaegerTraces },
+			body:        []byte(`{}`),
+			contentType: "application/json",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    "unsupported content type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+
+			var mu sync.Mutex
+			var tenants []string
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				tenants = append(tenants, req.Header.Get("X-Scope-OrgID"))
+				mu.Unlock()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(len(tt.wantTenants))
+
+			h := newTestHandlers(t, &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Zipkin: config.Zipkin{Enabled: true, TenantTag: "tenant"},
+				Jaeger: config.Jaeger{Enabled: true, TenantTag: "tenant"},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			tt.handler(h)(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+			assert.ElementsMatch(t, tt.wantTenants, tenants)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"golang.org/x/net/websocket"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	streamFrameHeaderSize = 5
+	streamFrameLogs       = 0x01
+	streamFrameMetrics    = 0x02
+	streamFrameTraces     = 0x03
+	streamFrameError      = 0x80
+)
+
+var errStreamSignatures = errors.New("the stream ingest endpoint cannot be combined with request signatures")
+
+// streamSignals are the signals of the frame types.
+var streamSignals = map[byte]string{
+	streamFrameLogs:    "logs",
+	streamFrameMetrics: "metrics",
+	streamFrameTraces:  "traces",
+}
+
+// hijacker exposes the hijacking of the connection by the wrapped response writers to the WebSocket server, which
+// type-asserts the response writer it is given.
+type hijacker struct {
+	http.ResponseWriter
+}
+
+// Hijack takes over the connection of the request.
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
+
+// Stream serves the WebSocket endpoint of long-lived senders streaming OTLP export requests over one connection.
+//
+// Each frame is a type byte (0x01 logs, 0x02 metrics, 0x03 traces), the big-endian uint32 length of the payload and
+// the protobuf export request of the signal; frames may span WebSocket messages. They are forwarded one at a time
+// through the handler of their signal, and answered in order with a frame of the same type carrying the export
+// response, or a 0x80 frame carrying the google.rpc.Status of the error.
+func (h *Handlers) Stream(w http.ResponseWriter, r *http.Request) {
+	server := websocket.Server{
+		// Agents are not browsers and usually send no Origin, so the origin is not checked.
+		Handshake: nil,
+		Handler: func(conn *websocket.Conn) {
+			h.serveStream(r, conn)
+		},
+	}
+	server.ServeHTTP(hijacker{w}, r)
+}
+
+// serveStream forwards the frames of the connection until it is closed, idle for longer than the idle timeout or the
+// server shuts down.
+func (h *Handlers) serveStream(r *http.Request, conn *websocket.Conn) {
+	r = h.withSender(r)
+	ctx := r.Context()
+
+	conn.PayloadType = websocket.BinaryFrame
+	h.trackStream(conn)
+	defer h.untrackStream(conn)
+	h.streamConnectionsMetric.Add(ctx, 1)
+	defer h.streamConnectionsMetric.Add(ctx, -1)
+
+	for {
+		// The deadlines of the server were set for the upgrade request, each frame gets its own
+		if err := conn.SetReadDeadline(streamDeadline(h.config.StreamIngest.IdleTimeout)); err != nil {
+			return
+		}
+		frame, err := h.readStreamFrame(conn)
+		if err != nil {
+			h.closeStream(ctx, err)
+			return
+		}
+
+		response := h.forwardStreamFrame(r, frame)
+
+		if err := conn.SetWriteDeadline(streamDeadline(h.serverTimeout(h.config.HTTP.WriteTimeout))); err != nil {
+			return
+		}
+		if _, err := conn.Write(response); err != nil {
+			h.closeStream(ctx, err)
+			return
+		}
+	}
+}
+
+// streamDeadline returns the deadline of a stream operation, none when the timeout is disabled.
+func streamDeadline(timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(timeout)
+}
+
+// closeStream logs why a stream is closed, unless it was closed by the sender or the server.
+func (h *Handlers) closeStream(ctx context.Context, err error) {
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return
+	}
+	logger.Warn(ctx, "closing stream: "+err.Error(), requestmeta.FromContext(ctx).Attributes()...)
+}
+
+// streamFrame is a frame read from a stream, whose payload is discarded when the frame cannot be forwarded.
+type streamFrame struct {
+	frameType byte
+	signal    string
+	payload   []byte
+	discarded bool
+}
+
+// readStreamFrame reads the next frame of the connection. The payloads of unknown frame types and of frames larger
+// than the frame size limit of their signal are discarded without being buffered. An error is only returned when the
+// connection cannot be read.
+func (h *Handlers) readStreamFrame(conn io.Reader) (streamFrame, error) {
+	header := make([]byte, streamFrameHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return streamFrame{}, err
+	}
+	frame := streamFrame{frameType: header[0], signal: streamSignals[header[0]]}
+	length := int64(binary.BigEndian.Uint32(header[1:]))
+
+	if frame.signal == "" || length > h.boundedBodyBytes(frame.signal) {
+		if _, err := io.CopyN(io.Discard, conn, length); err != nil {
+			return streamFrame{}, err
+		}
+		frame.discarded = true
+		return frame, nil
+	}
+
+	// Read through a limited reader rather than allocating the announced length up front.
+	payload, err := io.ReadAll(io.LimitReader(conn, length))
+	if err != nil {
+		return streamFrame{}, err
+	}
+	if int64(len(payload)) != length {
+		return streamFrame{}, fmt.Errorf("failed to read stream frame: %w", io.ErrUnexpectedEOF)
+	}
+	frame.payload = payload
+
+	return frame, nil
+}
+
+// forwardStreamFrame forwards the export request of the frame through the handler of its signal, returning the frame
+// answering it.
+func (h *Handlers) forwardStreamFrame(r *http.Request, frame streamFrame) []byte {
+	ctx := r.Context()
+	if frame.signal == "" {
+		return streamErrorFrame(grpcStatusInvalidArgument, fmt.Sprintf("unknown stream frame type 0x%02x", frame.frameType))
+	}
+
+	response := &bufferedResponse{header: make(http.Header)}
+	if frame.discarded {
+		h.rejectOversized(ctx, response, r, frame.signal, h.boundedBodyBytes(frame.signal))
+	} else {
+		// Export requests share the wire format of the OTLP data messages accepted by the handlers.
+		req := r.Clone(ctx)
+		req.Method = http.MethodPost
+		req.Body = io.NopCloser(bytes.NewReader(frame.payload))
+		req.ContentLength = int64(len(frame.payload))
+		req.Header.Set("Content-Type", proto.ContentType(config.EncodingProtobuf))
+		h.streamHandler(frame.signal)(response, req)
+	}
+
+	if status := grpcStatus(response.statusCode); status != grpcStatusOK {
+		return streamErrorFrame(status, errorMessage(response))
+	}
+	return appendGRPCWebFrame(nil, frame.frameType, response.body.Bytes())
+}
+
+// streamHandler returns the OTLP/HTTP handler of the signal.
+func (h *Handlers) streamHandler(signal string) http.HandlerFunc {
+	switch signal {
+	case "logs":
+		return h.Logs
+	case "metrics":
+		return h.Metrics
+	default:
+		return h.Traces
+	}
+}
+
+// streamErrorFrame returns the frame carrying the google.rpc.Status of an error.
+func streamErrorFrame(code int, message string) []byte {
+	status, err := protobuf.Marshal(&spb.Status{Code: int32(code), Message: message})
+	if err != nil {
+		status = nil
+	}
+	return appendGRPCWebFrame(nil, streamFrameError, status)
+}
+
+// trackStream records an open stream, closed when the server shuts down.
+func (h *Handlers) trackStream(conn *websocket.Conn) {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+	h.streams[conn] = struct{}{}
+}
+
+// untrackStream forgets a closed stream.
+func (h *Handlers) untrackStream(conn *websocket.Conn) {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+	delete(h.streams, conn)
+}
+
+// closeStreams closes the open streams, as the hijacked connections are not closed by the shutdown of the server.
+func (h *Handlers) closeStreams() {
+	h.streamsMu.Lock()
+	defer h.streamsMu.Unlock()
+	for conn := range h.streams {
+		_ = conn.Close()
+	}
+}
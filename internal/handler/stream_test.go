@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/websocket"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// dialStream serves the stream endpoint of the handlers and opens a stream to it.
+func dialStream(t *testing.T, h *Handlers) *websocket.Conn {
+	t.Helper()
+
+	h.Register(context.Background(), "GET /v1/stream", h.Stream)
+	server := httptest.NewServer(h.router)
+	t.Cleanup(server.Close)
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stream", "", "http://localhost/")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// readStreamResponse reads the next response frame of a stream.
+func readStreamResponse(t *testing.T, conn io.Reader) (byte, []byte) {
+	t.Helper()
+
+	header := make([]byte, streamFrameHeaderSize)
+	_, err := io.ReadFull(conn, header)
+	require.NoError(t, err)
+	payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+	_, err = io.ReadFull(conn, payload)
+	require.NoError(t, err)
+
+	return header[0], payload
+}
+
+func TestStream(t *testing.T) {
+	traces, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{Resource: testResource("tenant-a")}},
+	})
+	require.NoError(t, err)
+	logs, err := proto.Marshal(&collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		frames        [][]byte
+		backendStatus int
+		wantType      byte
+		wantCode      int32
+		wantMessage   string
+	}{
+		{
+			name:          "traces",
+			frames:        [][]byte{appendGRPCWebFrame(nil, streamFrameTraces, traces)},
+			backendStatus: http.StatusOK,
+			wantType:      streamFrameTraces,
+		},
+		{
+			name: "frame split across messages",
+			frames: [][]byte{
+				appendGRPCWebFrame(nil, streamFrameTraces, traces)[:3],
+				appendGRPCWebFrame(nil, streamFrameTraces, traces)[3:],
+			},
+			backendStatus: http.StatusOK,
+			wantType:      streamFrameTraces,
+		},
+		{
+			name:          "backend failure",
+			frames:        [][]byte{appendGRPCWebFrame(nil, streamFrameTraces, traces)},
+			backendStatus: http.StatusServiceUnavailable,
+			wantType:      streamFrameError,
+			wantCode:      grpcStatusInternal,
+			wantMessage:   "received non-success status code: 503",
+		},
+		{
+			name:        "unknown frame type",
+			frames:      [][]byte{appendGRPCWebFrame(nil, 0x09, traces)},
+			wantType:    streamFrameError,
+			wantCode:    grpcStatusInvalidArgument,
+			wantMessage: "unknown stream frame type 0x09",
+		},
+		{
+			name:        "frame over the body size limit",
+			frames:      [][]byte{appendGRPCWebFrame(nil, streamFrameLogs, logs)},
+			wantType:    streamFrameError,
+			wantCode:    grpcStatusUnknown,
+			wantMessage: "request body too large: the limit is 4 bytes",
+		},
+		{
+			name:        "invalid payload",
+			frames:      [][]byte{appendGRPCWebFrame(nil, streamFrameMetrics, []byte{0xff})},
+			wantType:    streamFrameError,
+			wantCode:    grpcStatusInvalidArgument,
+			wantMessage: "cannot parse invalid wire-format data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.backendStatus != 0 {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: tt.backendStatus, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				HTTP:         config.Listener{Endpoint: config.Endpoint{Timeout: 5 * time.Second}, LogsMaxBodyBytes: 4},
+				Tenant:       config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				StreamIngest: config.StreamIngest{Enabled: true, IdleTimeout: 5 * time.Second},
+			}, client)
+			conn := dialStream(t, h)
+
+			for _, frame := range tt.frames {
+				_, err := conn.Write(frame)
+				require.NoError(t, err)
+			}
+
+			frameType, payload := readStreamResponse(t, conn)
+			require.Equal(t, tt.wantType, frameType)
+			if tt.wantType != streamFrameError {
+				assert.NoError(t, proto.Unmarshal(payload, &coltracepb.ExportTraceServiceResponse{}))
+				return
+			}
+
+			status := &spb.Status{}
+			require.NoError(t, proto.Unmarshal(payload, status))
+			assert.Equal(t, tt.wantCode, status.GetCode())
+			assert.Contains(t, status.GetMessage(), tt.wantMessage)
+		})
+	}
+}
+
+func TestStream_Sequence(t *testing.T) {
+	traces, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{Resource: testResource("tenant-a")}},
+	})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	client := processor.NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil).Times(3)
+
+	h := newTestHandlers(t, &config.Config{
+		HTTP:         config.Listener{Endpoint: config.Endpoint{Timeout: 5 * time.Second}},
+		Tenant:       config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+		StreamIngest: config.StreamIngest{Enabled: true, IdleTimeout: 5 * time.Second},
+	}, client)
+	conn := dialStream(t, h)
+
+	// Frames of one message are answered in order, the stream staying open after an error.
+	var frames []byte
+	frames = appendGRPCWebFrame(frames, streamFrameTraces, traces)
+	frames = appendGRPCWebFrame(frames, 0x09, nil)
+	frames = appendGRPCWebFrame(frames, streamFrameTraces, traces)
+	frames = appendGRPCWebFrame(frames, streamFrameTraces, traces)
+	_, err = conn.Write(frames)
+	require.NoError(t, err)
+
+	for _, want := range []byte{streamFrameTraces, streamFrameError, streamFrameTraces, streamFrameTraces} {
+		frameType, _ := readStreamResponse(t, conn)
+		assert.Equal(t, want, frameType)
+	}
+
+	// Shutting down the server closes the stream.
+	h.closeStreams()
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestStream_IdleTimeout(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		HTTP:         config.Listener{Endpoint: config.Endpoint{Timeout: 5 * time.Second}},
+		StreamIngest: config.StreamIngest{Enabled: true, IdleTimeout: 50 * time.Millisecond},
+	}, processor.NewMockClient(gomock.NewController(t)))
+	conn := dialStream(t, h)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err := conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestNew_StreamSignatures(t *testing.T) {
+	_, err := New(
+		&config.Config{
+			StreamIngest: config.StreamIngest{Enabled: true},
+			Signature:    config.Signature{Secrets: []string{"tenant-a=secret"}},
+		},
+		http.NewServeMux(),
+		nil, nil, nil, nil,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+	)
+	assert.ErrorIs(t, err, errStreamSignatures)
+}
+
+func TestReadStreamFrame_Unlimited(t *testing.T) {
+	h := &Handlers{config: &config.Config{}}
+
+	// A frame of a signal without a body size limit is still bounded, and discarded without being buffered
+	header := []byte{streamFrameLogs, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], maxBodySize+1)
+	conn := io.MultiReader(bytes.NewReader(header), io.LimitReader(zeroReader{}, maxBodySize+1))
+
+	frame, err := h.readStreamFrame(conn)
+	require.NoError(t, err)
+	assert.True(t, frame.discarded)
+	assert.Nil(t, frame.payload)
+	assert.Equal(t, int64(maxBodySize), h.boundedBodyBytes("logs"))
+}
+
+// zeroReader reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,135 @@
+// Package handler contains the HTTP handlers for processing incoming OTLP signals.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+)
+
+// writeError writes an error response. In strict mode the body is a google.rpc.Status encoded like the request, as
+// required by the OTLP specification, otherwise it is the plain text error message.
+func (h *Handlers) writeError(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	if !h.config.Ingest.Strict {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+	h.writeStatus(ctx, w, r, statusCode, err)
+}
+
+// writeStatus writes an error response whose body is a google.rpc.Status encoded like the request, whatever the mode.
+func (h *Handlers) writeStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, err error) {
+	writeStatusEncoding(ctx, w, statusCode, err, responseEncoding(r))
+}
+
+// writeStatusEncoding writes an error response whose body is a google.rpc.Status in the encoding.
+func writeStatusEncoding(ctx context.Context, w http.ResponseWriter, statusCode int, err error, encoding string) {
+	body, marshalErr := proto.MarshalEncoding(&spb.Status{
+		Code:    int32(grpcStatus(statusCode)),
+		Message: err.Error(),
+	}, encoding)
+	if marshalErr != nil {
+		logger.Error(ctx, marshalErr.Error())
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", proto.ContentType(encoding))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		logger.Error(ctx, err.Error())
+	}
+}
+
+// processStatus returns the status code answering a request whose data could not be forwarded. Data of a tenant other
+// than the one that signed the request is answered with 403. In strict mode the OTLP retry semantics of otlpStatus
+// apply, otherwise every other failure but an open circuit is answered with 500.
+func (h *Handlers) processStatus(err error) int {
+	if errors.Is(err, errSignerTenant) {
+		return http.StatusForbidden
+	}
+	if !h.config.Ingest.Strict && !errors.Is(err, circuit.ErrOpen) {
+		return http.StatusInternalServerError
+	}
+	return otlpStatus(err)
+}
+
+// retryAfter returns the delay the backend asked to wait before retrying data that could not be forwarded, or zero.
+func retryAfter(err error) time.Duration {
+	var statusErr *processor.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	return 0
+}
+
+// setRetryAfter passes the delay the backend asked to wait before retrying on to the client in a Retry-After header,
+// for the retryable status codes only.
+func setRetryAfter(w http.ResponseWriter, statusCode int, err error) {
+	delay := retryAfter(err)
+	if delay <= 0 || (statusCode != http.StatusTooManyRequests && statusCode != http.StatusServiceUnavailable) {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10))
+}
+
+// MethodNotAllowed answers OTLP requests made with another method than POST, registered in strict mode so the
+// response carries a status body.
+func (h *Handlers) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", http.MethodPost)
+	h.writeError(r.Context(), w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, expected POST", r.Method))
+}
+
+// NotFound answers the requests matching no endpoint, registered in strict mode so the response carries a status body.
+// A request to an endpoint made with a method it does not serve is answered with 405 and the methods it allows.
+//
+// The status is encoded as protobuf for protobuf requests and as JSON otherwise, as the requests reaching no endpoint
+// are rarely OTLP exports.
+func (h *Handlers) NotFound(w http.ResponseWriter, r *http.Request) {
+	encoding := config.EncodingJSON
+	if proto.IsSupported(r.Header.Get("Content-Type")) && !proto.IsJSON(r.Header.Get("Content-Type")) {
+		encoding = config.EncodingProtobuf
+	}
+
+	if allowed := h.allowedMethods(r); len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatusEncoding(r.Context(), w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed, expected %s",
+			r.Method, strings.Join(allowed, " or ")), encoding)
+		return
+	}
+	writeStatusEncoding(r.Context(), w, http.StatusNotFound, fmt.Errorf("path %s not found", r.URL.Path), encoding)
+}
+
+// allowedMethods returns the methods served on the path of the request by the endpoints other than NotFound.
+func (h *Handlers) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := h.router.Handler(probe); pattern != "" && pattern != "/" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// responseEncoding returns the encoding of the response to a request, JSON for JSON requests and protobuf otherwise.
+func responseEncoding(r *http.Request) string {
+	if proto.IsJSON(r.Header.Get("Content-Type")) {
+		return config.EncodingJSON
+	}
+	return config.EncodingProtobuf
+}
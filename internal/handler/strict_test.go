@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/mock/gomock"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSignalHandlers_Strict(t *testing.T) {
+	payload := &logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}}
+	body, err := proto.Marshal(payload)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		strict         bool
+		contentType    string
+		body           []byte
+		maxBodyBytes   int64
+		regions        []string
+		backendStatus  int
+		retryAfter     string
+		backendErr     error
+		wantStatus     int
+		wantRetryAfter string
+		wantCode       int32
+	}{
+		{
+			name:        "unsupported content type",
+			strict:      true,
+			contentType: "text/plain",
+			body:        body,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    grpcStatusUnknown,
+		},
+		{
+			name:         "request too large",
+			strict:       true,
+			contentType:  "application/x-protobuf",
+			body:         body,
+			maxBodyBytes: int64(len(body) - 1),
+			wantStatus:   http.StatusRequestEntityTooLarge,
+			wantCode:     grpcStatusUnknown,
+		},
+		{
+			name:        "invalid json payload",
+			strict:      true,
+			contentType: "application/json",
+			body:        []byte("{"),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    grpcStatusInvalidArgument,
+		},
+		{
+			name:          "backend throttling is retryable",
+			strict:        true,
+			contentType:   "application/x-protobuf",
+			body:          body,
+			backendStatus: http.StatusTooManyRequests,
+			wantStatus:    http.StatusTooManyRequests,
+			wantCode:      grpcStatusResourceExhausted,
+		},
+		{
+			name:           "backend retry delay is passed on",
+			strict:         true,
+			contentType:    "application/x-protobuf",
+			body:           body,
+			backendStatus:  http.StatusServiceUnavailable,
+			retryAfter:     "30",
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "30",
+			wantCode:       grpcStatusUnavailable,
+		},
+		{
+			name:          "backend retry delay of a rejection is dropped",
+			strict:        true,
+			contentType:   "application/x-protobuf",
+			body:          body,
+			backendStatus: http.StatusBadRequest,
+			retryAfter:    "30",
+			wantStatus:    http.StatusBadRequest,
+			wantCode:      grpcStatusInvalidArgument,
+		},
+		{
+			name:          "backend rejection is not retryable",
+			strict:        true,
+			contentType:   "application/x-protobuf",
+			body:          body,
+			backendStatus: http.StatusBadRequest,
+			wantStatus:    http.StatusBadRequest,
+			wantCode:      grpcStatusInvalidArgument,
+		},
+		{
+			name:          "backend failure is retryable",
+			strict:        true,
+			contentType:   "application/x-protobuf",
+			body:          body,
+			backendStatus: http.StatusInternalServerError,
+			wantStatus:    http.StatusServiceUnavailable,
+			wantCode:      grpcStatusUnavailable,
+		},
+		{
+			name:        "backend unreachable is retryable",
+			strict:      true,
+			contentType: "application/x-protobuf",
+			body:        body,
+			backendErr:  errors.New("connection refused"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    grpcStatusUnavailable,
+		},
+		{
+			name:        "data residency refusal is not retryable",
+			strict:      true,
+			contentType: "application/x-protobuf",
+			body:        body,
+			regions:     []string{"tenant-a=eu-west-1"},
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    grpcStatusInvalidArgument,
+		},
+		{
+			name:          "backend throttling without strict mode",
+			contentType:   "application/x-protobuf",
+			body:          body,
+			backendStatus: http.StatusTooManyRequests,
+			wantStatus:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := processor.NewMockClient(ctrl)
+			if tt.backendErr != nil {
+				client.EXPECT().Do(gomock.Any()).Return(nil, tt.backendErr)
+			} else if tt.backendStatus != 0 {
+				header := http.Header{}
+				if tt.retryAfter != "" {
+					header.Set("Retry-After", tt.retryAfter)
+				}
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: tt.backendStatus, Header: header, Body: http.NoBody}, nil)
+			}
+
+			h := newTestHandlers(t, &config.Config{
+				HTTP:   config.Listener{MaxBodyBytes: tt.maxBodyBytes},
+				Ingest: config.Ingest{Strict: tt.strict},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Regions: tt.regions},
+			}, client)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantRetryAfter, rec.Header().Get("Retry-After"))
+			if !tt.strict {
+				assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"))
+				return
+			}
+
+			status := &spb.Status{}
+			if tt.contentType == "application/json" {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				require.NoError(t, protojson.Unmarshal(rec.Body.Bytes(), status))
+			} else {
+				assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+				require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), status))
+			}
+			assert.Equal(t, tt.wantCode, status.GetCode())
+			assert.NotEmpty(t, status.GetMessage())
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	h := newTestHandlers(t, &config.Config{
+		Ingest: config.Ingest{Strict: true},
+		Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+	}, processor.NewMockClient(gomock.NewController(t)))
+	h.Register(t.Context(), "POST /v1/logs", h.Logs)
+	h.Register(t.Context(), "/v1/logs", h.MethodNotAllowed)
+
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/logs", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+
+	status := &spb.Status{}
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), status))
+	assert.Equal(t, "method GET not allowed, expected POST", status.GetMessage())
+}
+
+func TestMethodNotAllowed_Handler(t *testing.T) {
+	tests := []struct {
+		name        string
+		strict      bool
+		contentType string
+	}{
+		{name: "plain text"},
+		{name: "strict", strict: true, contentType: "application/x-protobuf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The client has no expectations, so forwarding the body fails the test
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{Strict: tt.strict},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, processor.NewMockClient(gomock.NewController(t)))
+
+			body, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodGet, "/v1/logs", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-protobuf")
+
+			rec := httptest.NewRecorder()
+			h.Logs(rec, req)
+
+			assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+			assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		wantStatus  int
+		wantAllow   string
+		wantCode    int32
+		wantMessage string
+	}{
+		{
+			name:        "unknown path",
+			method:      http.MethodGet,
+			path:        "/v2/logs",
+			wantStatus:  http.StatusNotFound,
+			wantCode:    grpcStatusUnimplemented,
+			wantMessage: "path /v2/logs not found",
+		},
+		{
+			name:        "unknown path of a protobuf export",
+			method:      http.MethodPost,
+			path:        "/v1/log",
+			contentType: "application/x-protobuf",
+			wantStatus:  http.StatusNotFound,
+			wantCode:    grpcStatusUnimplemented,
+			wantMessage: "path /v1/log not found",
+		},
+		{
+			name:        "method not served by the endpoint",
+			method:      http.MethodPost,
+			path:        "/health",
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantAllow:   http.MethodGet,
+			wantCode:    grpcStatusUnknown,
+			wantMessage: "method POST not allowed, expected GET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(t, &config.Config{
+				Ingest: config.Ingest{Strict: true},
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+			}, processor.NewMockClient(gomock.NewController(t)))
+			h.Register(t.Context(), "GET /health", h.Health)
+			h.Register(t.Context(), "POST /v1/logs", h.Logs)
+			h.Register(t.Context(), "/", h.NotFound)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantAllow, rec.Header().Get("Allow"))
+
+			status := &spb.Status{}
+			if tt.contentType == "" {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				require.NoError(t, protojson.Unmarshal(rec.Body.Bytes(), status))
+			} else {
+				assert.Equal(t, "application/x-protobuf", rec.Header().Get("Content-Type"))
+				require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), status))
+			}
+			assert.Equal(t, tt.wantCode, status.GetCode())
+			assert.Equal(t, tt.wantMessage, status.GetMessage())
+		})
+	}
+}
@@ -2,41 +2,31 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/matt-gp/core/logger"
-	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/trace"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/transform"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
 // Traces handles incoming OTLP trace requests.
 func (h *Handlers) Traces(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	span := trace.SpanFromContext(ctx)
-	span.SetAttributes(attribute.String(signalTypeAttrKey, "traces"))
+	handle(h, w, r, "traces", &h.tracesProcessor, &tracepb.TracesData{}, (*tracepb.TracesData).GetResourceSpans,
+		h.dedupSpans,
+	)
+}
 
-	// Unmarshal the incoming trace data
-	data, err := proto.Unmarshal(r, &tracepb.TracesData{})
-	if err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		return
-	}
+// IngestTraces forwards the trace resources received by the non-OTLP receivers through the tenant partitioning
+// pipeline.
+func (h *Handlers) IngestTraces(ctx context.Context, resources []*tracepb.ResourceSpans) error {
+	_, err := process(ctx, h, "traces", &h.tracesProcessor, resources, h.dedupSpans)
+	return err
+}
 
-	// Process the trace data
-	if err := h.tracesProcessor.Dispatch(ctx, h.tracesProcessor.Partition(ctx, data.GetResourceSpans())); err != nil {
-		logger.Error(ctx, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
+// dedupSpans drops the spans of the tenant that share a trace and span ID, when enabled.
+func (h *Handlers) dedupSpans(ctx context.Context, tenant string, resources []*tracepb.ResourceSpans) {
+	if !h.config.Transform.DedupSpans {
 		return
 	}
-
-	span.SetStatus(codes.Ok, "processed successfully")
-	w.WriteHeader(http.StatusAccepted)
+	h.recordDuplicates(ctx, "traces", tenant, transform.DedupSpans(resources))
 }
@@ -0,0 +1,14 @@
+// Package health tracks the health of each signal pipeline of the proxy.
+//
+// Every backend request of a signal is reported to a Tracker. A signal stops
+// serving once its backend failed a configured number of consecutive times, and
+// serves again after the next success. Rejections of the data by the backend
+// are not failures, as the backend is up. Signals without any request yet are
+// serving.
+//
+// A readiness Report combines the signals with a policy, so that a replica can
+// stay in rotation while only one of its backends is down:
+//   - all: ready only while every signal is serving
+//   - any: ready while at least one signal is serving
+//   - always: always ready, the signal statuses are informational
+package health
@@ -0,0 +1,167 @@
+// Package health tracks the health of each signal pipeline of the proxy.
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+)
+
+// Serving statuses of a signal, named after the gRPC health checking protocol.
+const (
+	StatusServing    = "SERVING"
+	StatusNotServing = "NOT_SERVING"
+)
+
+// Readiness policies combining the statuses of the signals.
+const (
+	PolicyAll    = "all"
+	PolicyAny    = "any"
+	PolicyAlways = "always"
+)
+
+// Signal holds the health of a signal pipeline.
+type Signal struct {
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+}
+
+// Report is the readiness of the proxy with the health of each signal.
+type Report struct {
+	Ready   bool              `json:"ready"`
+	Policy  string            `json:"policy"`
+	Signals map[string]Signal `json:"signals"`
+}
+
+// Option configures optional dependencies of a Tracker.
+type Option func(*Tracker)
+
+// WithClock sets the clock timestamping the successes and failures.
+func WithClock(c clock.Clock) Option {
+	return func(t *Tracker) {
+		t.clock = c
+	}
+}
+
+// Tracker tracks the health of the signal pipelines. A nil Tracker discards everything reported.
+type Tracker struct {
+	policy    string
+	threshold int
+	clock     clock.Clock
+
+	mu      sync.Mutex
+	signals map[string]*Signal
+}
+
+// New creates a new Tracker for the given signals, a signal stops serving after threshold consecutive failures.
+//
+// An empty policy defaults to all.
+func New(signals []string, policy string, threshold int, opts ...Option) (*Tracker, error) {
+	switch policy {
+	case "":
+		policy = PolicyAll
+	case PolicyAll, PolicyAny, PolicyAlways:
+	default:
+		return nil, fmt.Errorf("invalid readiness policy %q, expected %s, %s or %s", policy, PolicyAll, PolicyAny, PolicyAlways)
+	}
+
+	t := &Tracker{
+		policy:    policy,
+		threshold: max(threshold, 1),
+		clock:     clock.New(),
+		signals:   make(map[string]*Signal, len(signals)),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	for _, signal := range signals {
+		t.signals[signal] = &Signal{Status: StatusServing}
+	}
+	return t, nil
+}
+
+// Success records a successful backend request of the signal, which serves again.
+func (t *Tracker) Success(signal string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.signal(signal)
+	now := t.clock.Now()
+	s.LastSuccess = &now
+	s.ConsecutiveFailures = 0
+	s.Status = StatusServing
+}
+
+// Failure records a failed backend request of the signal, which stops serving once the threshold is reached.
+func (t *Tracker) Failure(signal string, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.signal(signal)
+	now := t.clock.Now()
+	s.LastFailure = &now
+	s.LastError = err.Error()
+	s.ConsecutiveFailures++
+	if s.ConsecutiveFailures >= t.threshold {
+		s.Status = StatusNotServing
+	}
+}
+
+// Status returns the serving status of the signal, signals that are not tracked are not serving.
+func (t *Tracker) Status(signal string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, ok := t.signals[signal]; ok {
+		return s.Status
+	}
+	return StatusNotServing
+}
+
+// Report returns the readiness of the proxy according to the policy, with the health of each signal.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{Policy: t.policy, Signals: make(map[string]Signal, len(t.signals))}
+	serving := 0
+	for name, s := range t.signals {
+		report.Signals[name] = *s
+		if s.Status == StatusServing {
+			serving++
+		}
+	}
+
+	switch t.policy {
+	case PolicyAny:
+		report.Ready = serving > 0
+	case PolicyAlways:
+		report.Ready = true
+	default:
+		report.Ready = serving == len(t.signals)
+	}
+	return report
+}
+
+// signal returns the health of the signal, tracking it when it is not tracked yet. The caller must hold the lock.
+func (t *Tracker) signal(name string) *Signal {
+	s, ok := t.signals[name]
+	if !ok {
+		s = &Signal{Status: StatusServing}
+		t.signals[name] = s
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_Status(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker, err := New([]string{"logs", "traces"}, PolicyAll, 2, WithClock(clock.NewFake(now)))
+	require.NoError(t, err)
+
+	assert.Equal(t, StatusServing, tracker.Status("logs"))
+	assert.Equal(t, StatusNotServing, tracker.Status("unknown"))
+
+	tracker.Failure("logs", errors.New("connection refused"))
+	assert.Equal(t, StatusServing, tracker.Status("logs"), "below the threshold")
+
+	tracker.Failure("logs", errors.New("connection refused"))
+	assert.Equal(t, StatusNotServing, tracker.Status("logs"))
+	assert.Equal(t, StatusServing, tracker.Status("traces"))
+
+	signal := tracker.Report().Signals["logs"]
+	assert.Equal(t, 2, signal.ConsecutiveFailures)
+	assert.Equal(t, "connection refused", signal.LastError)
+	require.NotNil(t, signal.LastFailure)
+	assert.Equal(t, now, *signal.LastFailure)
+	assert.Nil(t, signal.LastSuccess)
+
+	tracker.Success("logs")
+	assert.Equal(t, StatusServing, tracker.Status("logs"))
+	assert.Zero(t, tracker.Report().Signals["logs"].ConsecutiveFailures)
+}
+
+func TestTracker_Report(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		failing []string
+		want    bool
+	}{
+		{name: "all serving", policy: PolicyAll, want: true},
+		{name: "all with one failing", policy: PolicyAll, failing: []string{"logs"}, want: false},
+		{name: "any with one failing", policy: PolicyAny, failing: []string{"logs"}, want: true},
+		{name: "any with all failing", policy: PolicyAny, failing: []string{"logs", "metrics"}, want: false},
+		{name: "always with all failing", policy: PolicyAlways, failing: []string{"logs", "metrics"}, want: true},
+		{name: "empty policy defaults to all", failing: []string{"logs"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := New([]string{"logs", "metrics"}, tt.policy, 1)
+			require.NoError(t, err)
+
+			for _, signal := range tt.failing {
+				tracker.Failure(signal, errors.New("failed"))
+			}
+
+			report := tracker.Report()
+			assert.Equal(t, tt.want, report.Ready)
+			assert.Len(t, report.Signals, 2)
+		})
+	}
+}
+
+func TestNew_InvalidPolicy(t *testing.T) {
+	_, err := New([]string{"logs"}, "most", 1)
+	assert.Error(t, err)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	assert.NotPanics(t, func() {
+		tracker.Success("logs")
+		tracker.Failure("logs", errors.New("failed"))
+	})
+}
@@ -0,0 +1,12 @@
+// Package heartbeat tracks the heartbeats of the authenticated senders.
+//
+// Some agents post empty OTLP payloads periodically to show they are alive.
+// When heartbeats are enabled, the empty payloads of authenticated senders are
+// answered immediately without going through the pipeline, and the time each
+// sender was last seen is recorded per signal.
+//
+// The last seen times are exported as a gauge of Unix timestamps, so alerts
+// can fire when an agent goes quiet. Senders are identified by the tenant that
+// signed their requests or by the subject of their client certificate, which
+// keeps the number of series bounded by the number of credentials.
+package heartbeat
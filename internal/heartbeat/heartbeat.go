@@ -0,0 +1,96 @@
+// Package heartbeat tracks the heartbeats of the authenticated senders.
+package heartbeat
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	signalTypeAttrKey = "signal.type"
+	senderAttrKey     = "heartbeat.sender"
+)
+
+// Sender is the last heartbeat of a sender for a signal.
+type Sender struct {
+	Signal   string    `json:"signal"`
+	Sender   string    `json:"sender"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// key identifies a sender of a signal.
+type key struct {
+	signal string
+	sender string
+}
+
+// Tracker records the last heartbeat of each sender.
+type Tracker struct {
+	mu       sync.Mutex
+	lastSeen map[key]time.Time
+}
+
+// New creates a new Tracker.
+func New() *Tracker {
+	return &Tracker{lastSeen: make(map[key]time.Time)}
+}
+
+// Seen records a heartbeat of the sender for the signal.
+func (t *Tracker) Seen(signal, sender string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{signal: signal, sender: sender}
+	if at.After(t.lastSeen[k]) {
+		t.lastSeen[k] = at
+	}
+}
+
+// Senders returns the last heartbeat of every sender, sorted by signal and sender.
+func (t *Tracker) Senders() []Sender {
+	t.mu.Lock()
+	senders := make([]Sender, 0, len(t.lastSeen))
+	for k, lastSeen := range t.lastSeen {
+		senders = append(senders, Sender{Signal: k.signal, Sender: k.sender, LastSeen: lastSeen})
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(senders, func(a, b Sender) int {
+		return cmp.Or(cmp.Compare(a.Signal, b.Signal), cmp.Compare(a.Sender, b.Sender))
+	})
+	return senders
+}
+
+// RegisterMetrics registers a gauge reporting when each sender was last seen.
+func (t *Tracker) RegisterMetrics(meter metric.Meter) error {
+	lastSeenGauge, err := meter.Float64ObservableGauge(
+		"otel_lgtm_proxy_heartbeat_last_seen_seconds",
+		metric.WithDescription("Unix time of the last heartbeat of each authenticated sender"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create otel lgtm proxy heartbeat last seen gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, sender := range t.Senders() {
+			o.ObserveFloat64(lastSeenGauge, float64(sender.LastSeen.UnixNano())/1e9, metric.WithAttributes(
+				attribute.String(signalTypeAttrKey, sender.Signal),
+				attribute.String(senderAttrKey, sender.Sender),
+			))
+		}
+		return nil
+	}, lastSeenGauge)
+	if err != nil {
+		return fmt.Errorf("failed to register otel lgtm proxy heartbeat callback: %w", err)
+	}
+
+	return nil
+}
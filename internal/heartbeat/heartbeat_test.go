@@ -0,0 +1,57 @@
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestTracker_Senders(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tracker := New()
+
+	tracker.Seen("metrics", "agent-b", now)
+	tracker.Seen("logs", "agent-a", now)
+	tracker.Seen("metrics", "agent-a", now)
+	tracker.Seen("metrics", "agent-a", now.Add(30*time.Second))
+	// An older heartbeat, for example of a slow request, does not move the last seen time back
+	tracker.Seen("metrics", "agent-a", now.Add(10*time.Second))
+
+	assert.Equal(t, []Sender{
+		{Signal: "logs", Sender: "agent-a", LastSeen: now},
+		{Signal: "metrics", Sender: "agent-a", LastSeen: now.Add(30 * time.Second)},
+		{Signal: "metrics", Sender: "agent-b", LastSeen: now},
+	}, tracker.Senders())
+}
+
+func TestTracker_RegisterMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	tracker := New()
+	require.NoError(t, tracker.RegisterMetrics(meter))
+	tracker.Seen("traces", "agent-a", time.Unix(1700000000, 500000000))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "otel_lgtm_proxy_heartbeat_last_seen_seconds", m.Name)
+
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	require.True(t, ok)
+	require.Len(t, gauge.DataPoints, 1)
+	assert.InDelta(t, 1700000000.5, gauge.DataPoints[0].Value, 1e-3)
+	assert.Equal(t, attribute.NewSet(
+		attribute.String(signalTypeAttrKey, "traces"),
+		attribute.String(senderAttrKey, "agent-a"),
+	), gauge.DataPoints[0].Attributes)
+}
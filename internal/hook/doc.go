@@ -0,0 +1,13 @@
+// Package hook provides extension points for mutating outbound backend requests.
+//
+// Hooks run after the proxy has built a backend request and before it is sent,
+// allowing proprietary backend requirements to be met without patching the
+// processor:
+//   - Request signing and custom authentication schemes
+//   - Payload checksums
+//   - Additional headers derived from the payload
+//
+// Hooks are registered by name, typically from the init function of an extension
+// package linked into the binary with a blank import, and enabled per destination
+// through the OLP_*_HOOKS configuration.
+package hook
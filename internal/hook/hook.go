@@ -0,0 +1,86 @@
+// Package hook provides extension points for mutating outbound backend requests.
+package hook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ContentSHA256Header is the header set by the built-in content-sha256 hook.
+const ContentSHA256Header = "X-Content-Sha256"
+
+// Hook mutates an outbound request before it is sent to the backend.
+type Hook interface {
+	// Mutate is called with the request and its encoded body, returning an error aborts the request.
+	Mutate(req *http.Request, body []byte) error
+}
+
+// Func is an adapter allowing ordinary functions to be used as hooks.
+type Func func(req *http.Request, body []byte) error
+
+// Mutate calls f(req, body).
+func (f Func) Mutate(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Hook{
+		"content-sha256": Func(contentSHA256),
+	}
+)
+
+// Register registers a hook under the given name, replacing any hook previously registered with the same name.
+func Register(name string, hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = hook
+}
+
+// Names returns the sorted names of the registered hooks.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Resolve returns the hooks registered under the given names, in order.
+func Resolve(names []string) ([]Hook, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	hooks := make([]Hook, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		hook, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown request hook %q", name)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// contentSHA256 sets the hex encoded SHA-256 checksum of the body.
+func contentSHA256(req *http.Request, body []byte) error {
+	sum := sha256.Sum256(body)
+	req.Header.Set(ContentSHA256Header, hex.EncodeToString(sum[:]))
+	return nil
+}
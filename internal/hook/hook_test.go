@@ -0,0 +1,59 @@
+package hook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	Register("test-hook", Func(func(req *http.Request, _ []byte) error {
+		req.Header.Set("X-Test", "true")
+		return nil
+	}))
+
+	tests := []struct {
+		name    string
+		names   []string
+		want    int
+		wantErr bool
+	}{
+		{name: "no hooks", names: nil, want: 0},
+		{name: "blank names are ignored", names: []string{" ", ""}, want: 0},
+		{name: "built-in and registered hooks", names: []string{"content-sha256", " test-hook "}, want: 2},
+		{name: "unknown hook", names: []string{"missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := Resolve(tt.names)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, hooks, tt.want)
+		})
+	}
+
+	assert.Contains(t, Names(), "test-hook")
+}
+
+func TestContentSHA256(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	require.NoError(t, contentSHA256(req, []byte("payload")))
+	assert.Equal(t, "239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5", req.Header.Get(ContentSHA256Header))
+}
+
+func TestFunc(t *testing.T) {
+	errHook := errors.New("hook failed")
+	hook := Func(func(*http.Request, []byte) error { return errHook })
+
+	assert.ErrorIs(t, hook.Mutate(httptest.NewRequest(http.MethodPost, "/", nil), nil), errHook)
+}
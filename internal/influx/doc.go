@@ -0,0 +1,14 @@
+// Package influx converts Influx line protocol into OTLP metrics.
+//
+// Lines written to the Influx v2 write API are parsed into points, supporting
+// escaped measurements, tags and field keys, quoted string fields, the float,
+// integer, unsigned and boolean field types, and the ns, us, ms and s
+// timestamp precisions. Each point is converted into OTLP gauges:
+//   - Every numeric or boolean field becomes a gauge data point
+//   - The gauge is named measurement_field, or measurement for the value field
+//   - The tags become data point attributes
+//   - String fields cannot be represented as metrics and are dropped
+//
+// Points are grouped into one resource per tenant, the tenant of a point is
+// read from a configured tag, falling back to the tenant of the request.
+package influx
@@ -0,0 +1,117 @@
+// Package influx converts Influx line protocol into OTLP metrics.
+package influx
+
+import (
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// scopeName is the instrumentation scope of the converted metrics.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/influx"
+
+// valueField is the field name whose metric is named after the measurement alone.
+const valueField = "value"
+
+// Converter converts points into OTLP metrics grouped by tenant.
+type Converter struct {
+	tenantLabel string
+	tenantTag   string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a point is read
+// from its tenantTag tag when set.
+func New(tenantLabel, tenantTag string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantTag: tenantTag}
+}
+
+// ResourceMetrics converts the points into one resource per tenant.
+//
+// The tenant of a point is the value of the tenant tag, falling back to the given tenant, usually the org of the
+// request. Each numeric or boolean field becomes a gauge named measurement_field, or just measurement for the value
+// field, with the tags as attributes. String fields cannot be represented as metrics and are dropped, the number of
+// dropped fields is returned.
+func (c *Converter) ResourceMetrics(points []Point, tenant string) ([]*metricpb.ResourceMetrics, int) {
+	var resources []*metricpb.ResourceMetrics
+	scopes := make(map[string]*metricpb.ScopeMetrics)
+	metrics := make(map[[2]string]*metricpb.Metric)
+	dropped := 0
+
+	for _, point := range points {
+		pointTenant := c.tenant(point, tenant)
+
+		scope, ok := scopes[pointTenant]
+		if !ok {
+			resource := &resourcepb.Resource{}
+			if pointTenant != "" {
+				resource.Attributes = []*commonpb.KeyValue{stringKeyValue(c.tenantLabel, pointTenant)}
+			}
+			scope = &metricpb.ScopeMetrics{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &metricpb.ResourceMetrics{Resource: resource, ScopeMetrics: []*metricpb.ScopeMetrics{scope}})
+			scopes[pointTenant] = scope
+		}
+
+		attributes := make([]*commonpb.KeyValue, 0, len(point.Tags))
+		for _, tag := range point.Tags {
+			attributes = append(attributes, stringKeyValue(tag.Key, tag.Value))
+		}
+
+		for _, field := range point.Fields {
+			dataPoint := &metricpb.NumberDataPoint{Attributes: attributes, TimeUnixNano: uint64(point.Time.UnixNano())}
+			switch value := field.Value.(type) {
+			case float64:
+				dataPoint.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: value}
+			case int64:
+				dataPoint.Value = &metricpb.NumberDataPoint_AsInt{AsInt: value}
+			case uint64:
+				dataPoint.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: float64(value)}
+			case bool:
+				dataPoint.Value = &metricpb.NumberDataPoint_AsInt{AsInt: boolToInt(value)}
+			default:
+				dropped++
+				continue
+			}
+
+			name := point.Measurement
+			if field.Key != valueField {
+				name += "_" + field.Key
+			}
+
+			metric, ok := metrics[[2]string{pointTenant, name}]
+			if !ok {
+				metric = &metricpb.Metric{Name: name, Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{}}}
+				scope.Metrics = append(scope.Metrics, metric)
+				metrics[[2]string{pointTenant, name}] = metric
+			}
+			metric.GetGauge().DataPoints = append(metric.GetGauge().DataPoints, dataPoint)
+		}
+	}
+
+	return resources, dropped
+}
+
+// tenant returns the value of the tenant tag of the point, or the fallback when it has none.
+func (c *Converter) tenant(point Point, fallback string) string {
+	if c.tenantTag == "" {
+		return fallback
+	}
+	for _, tag := range point.Tags {
+		if tag.Key == c.tenantTag {
+			return tag.Value
+		}
+	}
+	return fallback
+}
+
+// boolToInt returns 1 for true and 0 for false.
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
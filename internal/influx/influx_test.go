@@ -0,0 +1,67 @@
+package influx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func TestConverter_ResourceMetrics(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	points := []Point{
+		{
+			Measurement: "cpu",
+			Tags:        []Tag{{Key: "host", Value: "a"}},
+			Fields:      []Field{{Key: "value", Value: 1.5}, {Key: "cores", Value: int64(4)}, {Key: "model", Value: "x86"}},
+			Time:        ts,
+		},
+		{
+			Measurement: "cpu",
+			Tags:        []Tag{{Key: "host", Value: "b"}, {Key: "tenant", Value: "tenant-b"}},
+			Fields:      []Field{{Key: "value", Value: uint64(2)}, {Key: "up", Value: true}},
+			Time:        ts,
+		},
+		{
+			Measurement: "cpu",
+			Tags:        []Tag{{Key: "host", Value: "c"}},
+			Fields:      []Field{{Key: "value", Value: 3.0}},
+			Time:        ts,
+		},
+	}
+
+	resources, dropped := New("tenant.id", "tenant").ResourceMetrics(points, "tenant-a")
+
+	assert.Equal(t, 1, dropped)
+	require.Len(t, resources, 2)
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-a")}, resources[0].GetResource().GetAttributes())
+	metrics := resources[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "cpu", metrics[0].GetName())
+	require.Len(t, metrics[0].GetGauge().GetDataPoints(), 2)
+	assert.Equal(t, &metricpb.NumberDataPoint{
+		Attributes:   []*commonpb.KeyValue{stringKeyValue("host", "a")},
+		TimeUnixNano: uint64(ts.UnixNano()),
+		Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: 1.5},
+	}, metrics[0].GetGauge().GetDataPoints()[0])
+	assert.Equal(t, "cpu_cores", metrics[1].GetName())
+	assert.Equal(t, int64(4), metrics[1].GetGauge().GetDataPoints()[0].GetAsInt())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-b")}, resources[1].GetResource().GetAttributes())
+	metrics = resources[1].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, 2.0, metrics[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+	assert.Equal(t, "cpu_up", metrics[1].GetName())
+	assert.Equal(t, int64(1), metrics[1].GetGauge().GetDataPoints()[0].GetAsInt())
+}
+
+func TestConverter_ResourceMetrics_NoTenant(t *testing.T) {
+	resources, _ := New("tenant.id", "").ResourceMetrics([]Point{{Measurement: "cpu", Fields: []Field{{Key: "value", Value: 1.0}}}}, "")
+
+	require.Len(t, resources, 1)
+	assert.Empty(t, resources[0].GetResource().GetAttributes())
+}
@@ -0,0 +1,227 @@
+// Package influx converts Influx line protocol into OTLP metrics.
+package influx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Precision is the unit of the timestamps of a write request.
+type Precision string
+
+// Timestamp precisions accepted by the write API.
+const (
+	Nanoseconds  Precision = "ns"
+	Microseconds Precision = "us"
+	Milliseconds Precision = "ms"
+	Seconds      Precision = "s"
+)
+
+// maxLineSize limits the size of a single line.
+const maxLineSize = 1 << 20
+
+// Tag is a tag of a point.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field is a field of a point, its value is a float64, int64, uint64, bool or string.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Point is a parsed line.
+type Point struct {
+	Measurement string
+	Tags        []Tag
+	Fields      []Field
+	Time        time.Time
+}
+
+// ParsePrecision parses the precision query parameter, defaulting to nanoseconds. The v1 short forms n and u are
+// accepted as well.
+func ParsePrecision(s string) (Precision, error) {
+	switch s {
+	case "", "n", "ns":
+		return Nanoseconds, nil
+	case "u", "us":
+		return Microseconds, nil
+	case "ms":
+		return Milliseconds, nil
+	case "s":
+		return Seconds, nil
+	default:
+		return "", fmt.Errorf("invalid precision %q", s)
+	}
+}
+
+// Parse parses the lines of a write request, points without a timestamp are given the time now.
+func Parse(b []byte, precision Precision, now time.Time) ([]Point, error) {
+	var points []Point
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, maxLineSize)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		point, err := parseLine(line, precision, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		points = append(points, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// parseLine parses a line of the form measurement[,tag=value...] field=value[,field=value...] [timestamp].
+func parseLine(line string, precision Precision, now time.Time) (Point, error) {
+	var point Point
+
+	measurement, rest := readUntil(line, ", ")
+	if measurement == "" {
+		return point, errors.New("missing measurement")
+	}
+	point.Measurement = measurement
+
+	for strings.HasPrefix(rest, ",") {
+		var key, value string
+		key, rest = readUntil(rest[1:], "=")
+		if key == "" || !strings.HasPrefix(rest, "=") {
+			return point, fmt.Errorf("invalid tag of measurement %s", measurement)
+		}
+		value, rest = readUntil(rest[1:], ", ")
+		if value == "" {
+			return point, fmt.Errorf("missing value of tag %s", key)
+		}
+		point.Tags = append(point.Tags, Tag{Key: key, Value: value})
+	}
+
+	if !strings.HasPrefix(rest, " ") {
+		return point, fmt.Errorf("missing fields of measurement %s", measurement)
+	}
+	rest = strings.TrimLeft(rest, " ")
+
+	for {
+		var key string
+		key, rest = readUntil(rest, "=")
+		if key == "" || !strings.HasPrefix(rest, "=") {
+			return point, fmt.Errorf("invalid field of measurement %s", measurement)
+		}
+
+		value, remaining, err := parseFieldValue(rest[1:])
+		if err != nil {
+			return point, fmt.Errorf("invalid value of field %s: %w", key, err)
+		}
+		point.Fields = append(point.Fields, Field{Key: key, Value: value})
+		rest = remaining
+
+		if !strings.HasPrefix(rest, ",") {
+			break
+		}
+		rest = rest[1:]
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		point.Time = now
+		return point, nil
+	}
+
+	timestamp, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return point, fmt.Errorf("invalid timestamp %q", rest)
+	}
+	point.Time = toTime(timestamp, precision)
+	return point, nil
+}
+
+// parseFieldValue parses a field value, returning the remainder of the line.
+func parseFieldValue(s string) (any, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		var value strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				return value.String(), s[i+1:], nil
+			case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+				i++
+				value.WriteByte(s[i])
+			default:
+				value.WriteByte(c)
+			}
+		}
+		return nil, "", errors.New("unterminated string")
+	}
+
+	end := strings.IndexAny(s, ", ")
+	if end < 0 {
+		end = len(s)
+	}
+	raw, rest := s[:end], s[end:]
+
+	switch {
+	case raw == "":
+		return nil, "", errors.New("missing value")
+	case strings.HasSuffix(raw, "i"):
+		v, err := strconv.ParseInt(raw[:len(raw)-1], 10, 64)
+		return v, rest, err
+	case strings.HasSuffix(raw, "u"):
+		v, err := strconv.ParseUint(raw[:len(raw)-1], 10, 64)
+		return v, rest, err
+	}
+
+	switch raw {
+	case "t", "T", "true", "True", "TRUE":
+		return true, rest, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, rest, nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	return v, rest, err
+}
+
+// readUntil reads up to the first unescaped stop character, unescaping backslash escaped characters.
+func readUntil(s, stops string) (string, string) {
+	var token strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && strings.IndexByte(`,= \`, s[i+1]) >= 0 {
+			i++
+			token.WriteByte(s[i])
+			continue
+		}
+		if strings.IndexByte(stops, c) >= 0 {
+			return token.String(), s[i:]
+		}
+		token.WriteByte(c)
+	}
+	return token.String(), ""
+}
+
+// toTime converts a timestamp of the precision into a time.
+func toTime(timestamp int64, precision Precision) time.Time {
+	switch precision {
+	case Microseconds:
+		return time.UnixMicro(timestamp)
+	case Milliseconds:
+		return time.UnixMilli(timestamp)
+	case Seconds:
+		return time.Unix(timestamp, 0)
+	default:
+		return time.Unix(0, timestamp)
+	}
+}
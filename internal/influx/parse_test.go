@@ -0,0 +1,125 @@
+package influx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrecision(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Precision
+		wantErr bool
+	}{
+		{input: "", want: Nanoseconds},
+		{input: "n", want: Nanoseconds},
+		{input: "u", want: Microseconds},
+		{input: "ms", want: Milliseconds},
+		{input: "s", want: Seconds},
+		{input: "h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePrecision(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name      string
+		input     string
+		precision Precision
+		want      []Point
+		wantErr   bool
+	}{
+		{
+			name:      "fields of every type",
+			input:     `cpu,host=server01,region=us-west f=1.5,i=-2i,u=3u,b=t,s="a \"quoted\" string" 1700000000000`,
+			precision: Milliseconds,
+			want: []Point{{
+				Measurement: "cpu",
+				Tags:        []Tag{{Key: "host", Value: "server01"}, {Key: "region", Value: "us-west"}},
+				Fields: []Field{
+					{Key: "f", Value: 1.5},
+					{Key: "i", Value: int64(-2)},
+					{Key: "u", Value: uint64(3)},
+					{Key: "b", Value: true},
+					{Key: "s", Value: `a "quoted" string`},
+				},
+				Time: time.UnixMilli(1700000000000),
+			}},
+		},
+		{
+			name:      "escaped characters",
+			input:     `disk\ io,path=C:\\,mount\ point=a\,b read\=bytes=1 1700000000`,
+			precision: Seconds,
+			want: []Point{{
+				Measurement: "disk io",
+				Tags:        []Tag{{Key: "path", Value: `C:\`}, {Key: "mount point", Value: "a,b"}},
+				Fields:      []Field{{Key: "read=bytes", Value: 1.0}},
+				Time:        time.Unix(1700000000, 0),
+			}},
+		},
+		{
+			name:      "comments, blank lines and missing timestamps",
+			input:     "# comment\n\nmem value=1\r\nmem value=2 1700000000000000000\n",
+			precision: Nanoseconds,
+			want: []Point{
+				{Measurement: "mem", Fields: []Field{{Key: "value", Value: 1.0}}, Time: now},
+				{Measurement: "mem", Fields: []Field{{Key: "value", Value: 2.0}}, Time: time.Unix(0, 1700000000000000000)},
+			},
+		},
+		{
+			name:    "missing fields",
+			input:   "cpu,host=a",
+			wantErr: true,
+		},
+		{
+			name:    "missing tag value",
+			input:   "cpu,host= value=1",
+			wantErr: true,
+		},
+		{
+			name:    "invalid field value",
+			input:   "cpu value=abc",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated string",
+			input:   `cpu value="abc`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid timestamp",
+			input:   "cpu value=1 yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input), tt.precision, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !minimal
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+)
+
+func TestDefaultBuild(t *testing.T) {
+	names := make([]string, 0, len(optionals))
+	for _, o := range optionals {
+		names = append(names, o.name)
+	}
+	assert.Equal(t, names, Compiled())
+
+	cfg := &config.Config{
+		Logs:   config.Endpoint{Protocol: config.ProtocolKafka, Discovery: config.Discovery{Service: "o/loki/http"}},
+		Kafka:  config.Kafka{Brokers: []string{"kafka:9092"}},
+		Syslog: config.Syslog{UDPAddress: ":514"},
+	}
+	require.NoError(t, Validate(cfg))
+
+	b, err := NewBalancer(&config.Discovery{})
+	require.NoError(t, err)
+	assert.Nil(t, b, "a disabled discovery must not return a typed nil balancer")
+
+	runners, err := NewReceivers(&config.Config{}, Sinks{}, noopmetric.NewMeterProvider().Meter("test"))
+	require.NoError(t, err)
+	assert.Empty(t, runners)
+}
@@ -0,0 +1,19 @@
+//go:build !minimal || discovery
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/discovery"
+)
+
+func init() {
+	RegisterDiscovery(func(cfg *config.Discovery) (Balancer, error) {
+		balancer, err := discovery.New(cfg)
+		if err != nil || balancer == nil {
+			return nil, err
+		}
+		return balancer, nil
+	})
+}
@@ -0,0 +1,25 @@
+// Package integration registers the optional subsystems compiled into the proxy.
+//
+// Receivers, backend protocols and service discovery that only some
+// deployments use are kept out of the core of the proxy. Each registers itself
+// from the init function of a file of this package guarded by a build tag, so
+// a binary only contains the subsystems it was built with. The receivers
+// served by the HTTP handlers register their routes from a file of the
+// handler package guarded by the same build tag:
+//   - The default build includes every integration.
+//   - Building with the minimal tag excludes all of them, and adding the tag
+//     of an integration, such as "minimal,kafka", includes it again.
+//
+// The integrations are:
+//   - discovery: Kubernetes service discovery of the backend pods
+//   - firehose: the Amazon Data Firehose HTTP endpoint delivery receiver
+//   - fluentforward: the Fluent Forward log receiver
+//   - kafka: the Kafka consumer and the kafka backend protocol
+//   - scraper: the Prometheus scraper
+//   - statsd: the StatsD metric receiver
+//   - syslog: the syslog log receiver
+//
+// Validate fails when the configuration enables an integration the binary was
+// built without, so it is reported at startup instead of being silently
+// ignored.
+package integration
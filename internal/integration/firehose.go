@@ -0,0 +1,8 @@
+//go:build !minimal || firehose
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+func init() {
+	RegisterHandler("firehose")
+}
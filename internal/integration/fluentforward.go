@@ -0,0 +1,19 @@
+//go:build !minimal || fluentforward
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/fluentforward"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func init() {
+	RegisterReceiver("fluentforward", func(cfg *config.Config, sinks Sinks, _ metric.Meter) (Runner, error) {
+		if cfg.FluentForward.Address == "" {
+			return nil, nil
+		}
+		return fluentforward.New(&cfg.FluentForward, &cfg.Tenant, sinks.Logs), nil
+	})
+}
@@ -0,0 +1,247 @@
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"go.opentelemetry.io/otel/metric"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Sinks receive the resources ingested by the receivers.
+type Sinks struct {
+	Logs    func(ctx context.Context, resources []*logpb.ResourceLogs) error
+	Metrics func(ctx context.Context, resources []*metricpb.ResourceMetrics) error
+	Traces  func(ctx context.Context, resources []*tracepb.ResourceSpans) error
+}
+
+// Runner runs a receiver until the context is cancelled.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// Queue is implemented by the Runners batching data before ingesting it.
+type Queue interface {
+	// Pending returns the number of items waiting to be ingested.
+	Pending() int64
+}
+
+// Receiver creates a receiver ingesting into the sinks, it returns a nil Runner when the receiver is not configured.
+type Receiver func(cfg *config.Config, sinks Sinks, meter metric.Meter) (Runner, error)
+
+// Producer creates the client of a backend written to with another protocol than HTTP.
+type Producer func(endpoint *config.Endpoint, tenantHeader string, tlsConfig *tls.Config) (processor.Client, error)
+
+// Balancer spreads the requests of a backend across its discovered endpoints.
+type Balancer interface {
+	// Run refreshes the endpoints until the context is cancelled.
+	Run(ctx context.Context)
+	// Transport wraps the base transport to send each request to the next endpoint.
+	Transport(base http.RoundTripper) http.RoundTripper
+}
+
+// Discovery creates the Balancer of a backend, it returns nil when no Service is configured.
+type Discovery func(cfg *config.Discovery) (Balancer, error)
+
+// Feature reports whether a subsystem is compiled into the binary and enabled by the configuration.
+type Feature struct {
+	Name     string `json:"name"`
+	Compiled bool   `json:"compiled"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// NamedRunner is a configured receiver with the name of its integration.
+type NamedRunner struct {
+	Name string
+	Runner
+}
+
+// optional is an integration that can be excluded from the binary, with whether the configuration enables it.
+type optional struct {
+	name    string
+	enabled func(cfg *config.Config) bool
+}
+
+// optionals are the integrations of this package, checked by Validate whether they are compiled in or not.
+var optionals = []optional{
+	{name: "discovery", enabled: func(cfg *config.Config) bool {
+		return slices.ContainsFunc(endpoints(cfg), func(e *config.Endpoint) bool { return e.Discovery.Service != "" })
+	}},
+	{name: "firehose", enabled: func(cfg *config.Config) bool {
+		return cfg.Firehose.Enabled
+	}},
+	{name: "fluentforward", enabled: func(cfg *config.Config) bool {
+		return cfg.FluentForward.Address != ""
+	}},
+	{name: "kafka", enabled: func(cfg *config.Config) bool {
+		return len(cfg.Kafka.Brokers) > 0 ||
+			slices.ContainsFunc(endpoints(cfg), func(e *config.Endpoint) bool { return e.Protocol == config.ProtocolKafka })
+	}},
+	{name: "scraper", enabled: func(cfg *config.Config) bool {
+		return len(cfg.Scrape.Targets) > 0 || cfg.Scrape.FileSD != ""
+	}},
+	{name: "statsd", enabled: func(cfg *config.Config) bool {
+		return cfg.StatsD.TCPAddress != "" || cfg.StatsD.UDPAddress != ""
+	}},
+	{name: "syslog", enabled: func(cfg *config.Config) bool {
+		return cfg.Syslog.TCPAddress != "" || cfg.Syslog.UDPAddress != ""
+	}},
+}
+
+var (
+	mu        sync.RWMutex
+	compiled  = map[string]bool{}
+	receivers = map[string]Receiver{}
+	producers = map[string]Producer{}
+	discover  Discovery
+)
+
+// RegisterReceiver registers a receiver under the given name, replacing any receiver previously registered with the
+// same name.
+func RegisterReceiver(name string, receiver Receiver) {
+	mu.Lock()
+	defer mu.Unlock()
+	compiled[name] = true
+	receivers[name] = receiver
+}
+
+// RegisterHandler registers an integration served by the HTTP handlers under the given name. The handlers create its
+// routes from a file guarded by the same build tags.
+func RegisterHandler(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	compiled[name] = true
+}
+
+// RegisterProducer registers the producer of the backends written to with the given protocol.
+func RegisterProducer(protocol string, producer Producer) {
+	mu.Lock()
+	defer mu.Unlock()
+	compiled[protocol] = true
+	producers[protocol] = producer
+}
+
+// RegisterDiscovery registers the service discovery of the backends.
+func RegisterDiscovery(d Discovery) {
+	mu.Lock()
+	defer mu.Unlock()
+	compiled["discovery"] = true
+	discover = d
+}
+
+// Compiled returns the sorted names of the integrations compiled into the binary.
+func Compiled() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(compiled))
+	for name := range compiled {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Validate returns an error when the configuration enables an integration the binary was built without.
+func Validate(cfg *config.Config) error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, o := range optionals {
+		if o.enabled(cfg) && !compiled[o.name] {
+			return fmt.Errorf("%s is configured but not compiled in, build with the %q tag", o.name, o.name)
+		}
+	}
+
+	return nil
+}
+
+// Features returns the integrations of this package, whether they are compiled in or not, sorted by name. An
+// integration is only enabled when it is compiled in.
+func Features(cfg *config.Config) []Feature {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	features := make([]Feature, 0, len(optionals))
+	for _, o := range optionals {
+		features = append(features, Feature{
+			Name:     o.name,
+			Compiled: compiled[o.name],
+			Enabled:  compiled[o.name] && o.enabled(cfg),
+		})
+	}
+
+	return features
+}
+
+// NewReceivers creates the configured receivers, sorted by name.
+func NewReceivers(cfg *config.Config, sinks Sinks, meter metric.Meter) ([]NamedRunner, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(receivers))
+	for name := range receivers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var runners []NamedRunner
+	for _, name := range names {
+		runner, err := receivers[name](cfg, sinks, meter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s receiver: %w", name, err)
+		}
+		if runner != nil {
+			runners = append(runners, NamedRunner{Name: name, Runner: runner})
+		}
+	}
+
+	return runners, nil
+}
+
+// NewProducer creates the client of a backend written to with the protocol of the endpoint.
+func NewProducer(endpoint *config.Endpoint, tenantHeader string, tlsConfig *tls.Config) (processor.Client, error) {
+	mu.RLock()
+	producer, ok := producers[endpoint.Protocol]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("protocol %q is not compiled in, build with the %q tag", endpoint.Protocol, endpoint.Protocol)
+	}
+	return producer(endpoint, tenantHeader, tlsConfig)
+}
+
+// NewBalancer creates the Balancer of a backend, it returns nil when no Service is configured.
+func NewBalancer(cfg *config.Discovery) (Balancer, error) {
+	if cfg.Service == "" {
+		return nil, nil
+	}
+
+	mu.RLock()
+	d := discover
+	mu.RUnlock()
+
+	if d == nil {
+		return nil, fmt.Errorf("discovery is not compiled in, build with the %q tag", "discovery")
+	}
+	return d(cfg)
+}
+
+// endpoints returns the backend endpoints of the configuration, the profiles one only when it has an address.
+func endpoints(cfg *config.Config) []*config.Endpoint {
+	endpoints := []*config.Endpoint{&cfg.Logs, &cfg.Metrics, &cfg.Traces}
+	if cfg.Profiles.Address != "" {
+		endpoints = append(endpoints, &cfg.Profiles)
+	}
+	return endpoints
+}
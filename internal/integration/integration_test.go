@@ -0,0 +1,187 @@
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+)
+
+// emptyRegistry replaces the registry with an empty one for the duration of the test.
+func emptyRegistry(t *testing.T) {
+	t.Helper()
+
+	mu.Lock()
+	savedCompiled, savedReceivers, savedProducers, savedDiscover := compiled, receivers, producers, discover
+	compiled, receivers, producers, discover = map[string]bool{}, map[string]Receiver{}, map[string]Producer{}, nil
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		compiled, receivers, producers, discover = savedCompiled, savedReceivers, savedProducers, savedDiscover
+		mu.Unlock()
+	})
+}
+
+type runnerFunc func(ctx context.Context) error
+
+func (f runnerFunc) Run(ctx context.Context) error { return f(ctx) }
+
+type balancer struct{}
+
+func (balancer) Run(context.Context) {}
+
+func (balancer) Transport(base http.RoundTripper) http.RoundTripper { return base }
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		compiled []string
+		cfg      config.Config
+		wantErr  string
+	}{
+		{name: "nothing configured"},
+		{
+			name:    "kafka consumer not compiled in",
+			cfg:     config.Config{Kafka: config.Kafka{Brokers: []string{"kafka:9092"}}},
+			wantErr: `kafka is configured but not compiled in, build with the "kafka" tag`,
+		},
+		{
+			name:    "kafka protocol not compiled in",
+			cfg:     config.Config{Traces: config.Endpoint{Protocol: config.ProtocolKafka}},
+			wantErr: `build with the "kafka" tag`,
+		},
+		{
+			name: "discovery of the profiles backend not compiled in",
+			cfg: config.Config{Profiles: config.Endpoint{
+				Address:   "http://pyroscope:4040",
+				Discovery: config.Discovery{Service: "o/p/http"},
+			}},
+			wantErr: `build with the "discovery" tag`,
+		},
+		{
+			name: "discovery without a profiles backend",
+			cfg:  config.Config{Profiles: config.Endpoint{Discovery: config.Discovery{Service: "o/p/http"}}},
+		},
+		{
+			name:    "syslog not compiled in",
+			cfg:     config.Config{Syslog: config.Syslog{UDPAddress: ":514"}},
+			wantErr: `build with the "syslog" tag`,
+		},
+		{
+			name:     "compiled in",
+			compiled: []string{"fluentforward", "scraper", "statsd"},
+			cfg: config.Config{
+				FluentForward: config.FluentForward{Address: ":24224"},
+				Scrape:        config.Scrape{FileSD: "targets.json"},
+				StatsD:        config.StatsD{TCPAddress: ":8125"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emptyRegistry(t)
+			for _, name := range tt.compiled {
+				RegisterReceiver(name, func(*config.Config, Sinks, metric.Meter) (Runner, error) { return nil, nil })
+			}
+
+			err := Validate(&tt.cfg)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	emptyRegistry(t)
+	RegisterReceiver("syslog", func(*config.Config, Sinks, metric.Meter) (Runner, error) { return nil, nil })
+	RegisterReceiver("statsd", func(*config.Config, Sinks, metric.Meter) (Runner, error) { return nil, nil })
+
+	features := Features(&config.Config{
+		Kafka:  config.Kafka{Brokers: []string{"kafka:9092"}},
+		Syslog: config.Syslog{UDPAddress: ":514"},
+	})
+
+	assert.Equal(t, []Feature{
+		{Name: "discovery"},
+		{Name: "firehose"},
+		{Name: "fluentforward"},
+		{Name: "kafka"},
+		{Name: "scraper"},
+		{Name: "statsd", Compiled: true},
+		{Name: "syslog", Compiled: true, Enabled: true},
+	}, features)
+}
+
+func TestNewReceivers(t *testing.T) {
+	emptyRegistry(t)
+
+	errCreate := errors.New("bad address")
+	RegisterReceiver("b", func(*config.Config, Sinks, metric.Meter) (Runner, error) {
+		return runnerFunc(func(context.Context) error { return nil }), nil
+	})
+	RegisterReceiver("a", func(*config.Config, Sinks, metric.Meter) (Runner, error) {
+		return runnerFunc(func(context.Context) error { return nil }), nil
+	})
+	RegisterReceiver("disabled", func(*config.Config, Sinks, metric.Meter) (Runner, error) { return nil, nil })
+
+	runners, err := NewReceivers(&config.Config{}, Sinks{}, noopmetric.NewMeterProvider().Meter("test"))
+	require.NoError(t, err)
+	require.Len(t, runners, 2)
+	assert.Equal(t, "a", runners[0].Name)
+	assert.Equal(t, "b", runners[1].Name)
+	assert.Equal(t, []string{"a", "b", "disabled"}, Compiled())
+
+	RegisterReceiver("failing", func(*config.Config, Sinks, metric.Meter) (Runner, error) { return nil, errCreate })
+	_, err = NewReceivers(&config.Config{}, Sinks{}, noopmetric.NewMeterProvider().Meter("test"))
+	assert.ErrorIs(t, err, errCreate)
+	assert.ErrorContains(t, err, "failed to create failing receiver")
+}
+
+func TestNewProducer(t *testing.T) {
+	emptyRegistry(t)
+
+	endpoint := &config.Endpoint{Protocol: config.ProtocolKafka}
+	_, err := NewProducer(endpoint, "X-Scope-OrgID", nil)
+	assert.EqualError(t, err, `protocol "kafka" is not compiled in, build with the "kafka" tag`)
+
+	var gotHeader string
+	RegisterProducer(config.ProtocolKafka,
+		func(_ *config.Endpoint, tenantHeader string, _ *tls.Config) (processor.Client, error) {
+			gotHeader = tenantHeader
+			return &http.Client{}, nil
+		},
+	)
+	client, err := NewProducer(endpoint, "X-Scope-OrgID", nil)
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+	assert.Equal(t, "X-Scope-OrgID", gotHeader)
+}
+
+func TestNewBalancer(t *testing.T) {
+	emptyRegistry(t)
+
+	b, err := NewBalancer(&config.Discovery{})
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	_, err = NewBalancer(&config.Discovery{Service: "observability/loki/http"})
+	assert.EqualError(t, err, `discovery is not compiled in, build with the "discovery" tag`)
+
+	RegisterDiscovery(func(*config.Discovery) (Balancer, error) { return balancer{}, nil })
+	b, err = NewBalancer(&config.Discovery{Service: "observability/loki/http"})
+	require.NoError(t, err)
+	assert.Equal(t, balancer{}, b)
+}
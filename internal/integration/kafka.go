@@ -0,0 +1,32 @@
+//go:build !minimal || kafka
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"crypto/tls"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/kafka"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func init() {
+	RegisterReceiver("kafka", func(cfg *config.Config, sinks Sinks, meter metric.Meter) (Runner, error) {
+		if len(cfg.Kafka.Brokers) == 0 {
+			return nil, nil
+		}
+		return kafka.New(&cfg.Kafka, kafka.Sinks{
+			Logs:    sinks.Logs,
+			Metrics: sinks.Metrics,
+			Traces:  sinks.Traces,
+		}, kafka.WithMeter(meter))
+	})
+
+	RegisterProducer(config.ProtocolKafka,
+		func(endpoint *config.Endpoint, tenantHeader string, tlsConfig *tls.Config) (processor.Client, error) {
+			return kafka.NewProducer(endpoint, tenantHeader, tlsConfig)
+		},
+	)
+}
@@ -0,0 +1,19 @@
+//go:build !minimal || scraper
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/scraper"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func init() {
+	RegisterReceiver("scraper", func(cfg *config.Config, sinks Sinks, _ metric.Meter) (Runner, error) {
+		if len(cfg.Scrape.Targets) == 0 && cfg.Scrape.FileSD == "" {
+			return nil, nil
+		}
+		return scraper.New(&cfg.Scrape, &cfg.Tenant, sinks.Metrics)
+	})
+}
@@ -0,0 +1,19 @@
+//go:build !minimal || statsd
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/statsd"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func init() {
+	RegisterReceiver("statsd", func(cfg *config.Config, sinks Sinks, _ metric.Meter) (Runner, error) {
+		if cfg.StatsD.TCPAddress == "" && cfg.StatsD.UDPAddress == "" {
+			return nil, nil
+		}
+		return statsd.New(&cfg.StatsD, &cfg.Tenant, sinks.Metrics)
+	})
+}
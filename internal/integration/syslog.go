@@ -0,0 +1,22 @@
+//go:build !minimal || syslog
+
+// Package integration registers the optional subsystems compiled into the proxy.
+package integration
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/syslog"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Ensure that the syslog server reports its pending messages in the admin stats.
+var _ Queue = (*syslog.Server)(nil)
+
+func init() {
+	RegisterReceiver("syslog", func(cfg *config.Config, sinks Sinks, meter metric.Meter) (Runner, error) {
+		if cfg.Syslog.TCPAddress == "" && cfg.Syslog.UDPAddress == "" {
+			return nil, nil
+		}
+		return syslog.New(&cfg.Syslog, &cfg.Tenant, sinks.Logs, syslog.WithMeter(meter))
+	})
+}
@@ -0,0 +1,17 @@
+// Package jaeger converts Jaeger span batches into OTLP traces.
+//
+// Batches posted to /api/traces are decoded from either encoding used by
+// Jaeger clients and agents:
+//   - Thrift binary, the jaeger.thrift Batch sent by the HTTP sender of the Jaeger clients
+//   - Protobuf, the model.proto Batch of the Jaeger API v2
+//
+// They are converted like the Jaeger receiver of the OpenTelemetry Collector:
+//   - The process becomes the resource, its service name the service.name attribute
+//   - The span.kind tag becomes the kind of the span, the error and otel.status_code tags its status
+//   - Other tags become span attributes, and logs become span events
+//   - The first CHILD_OF reference without a parent span ID becomes the parent, other references become links
+//
+// The tenant of a span is read from a configured tag of the span, falling back
+// to the process tags, and written to the tenant resource attribute, so the
+// converted resources are partitioned like OTLP payloads.
+package jaeger
@@ -0,0 +1,323 @@
+// Package jaeger converts Jaeger span batches into OTLP traces.
+package jaeger
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+var serviceNameAttrKey = "service.name"
+
+// Tags and log fields mapped to the fields of a span.
+const (
+	spanKindTag          = "span.kind"
+	errorTag             = "error"
+	statusCodeTag        = "otel.status_code"
+	statusDescriptionTag = "otel.status_description"
+	eventField           = "event"
+)
+
+// scopeName is the instrumentation scope of the converted spans.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/jaeger"
+
+// kinds maps the values of the span.kind tag to OTLP span kinds.
+var kinds = map[string]tracepb.Span_SpanKind{
+	"client":   tracepb.Span_SPAN_KIND_CLIENT,
+	"server":   tracepb.Span_SPAN_KIND_SERVER,
+	"producer": tracepb.Span_SPAN_KIND_PRODUCER,
+	"consumer": tracepb.Span_SPAN_KIND_CONSUMER,
+	"internal": tracepb.Span_SPAN_KIND_INTERNAL,
+}
+
+// TagType is the type of the value of a tag.
+type TagType int
+
+// Tag types.
+const (
+	TagString TagType = iota
+	TagBool
+	TagLong
+	TagDouble
+	TagBinary
+)
+
+// RefType is the type of a reference between spans.
+type RefType int
+
+// Reference types.
+const (
+	RefChildOf RefType = iota
+	RefFollowsFrom
+)
+
+// Batch is a batch of spans reported by a process.
+type Batch struct {
+	Process Process
+	Spans   []Span
+}
+
+// Process is the process that reported spans.
+type Process struct {
+	ServiceName string
+	Tags        []Tag
+}
+
+// Span is a Jaeger span, with times in nanoseconds.
+type Span struct {
+	TraceIDHigh   uint64
+	TraceIDLow    uint64
+	SpanID        uint64
+	ParentSpanID  uint64
+	OperationName string
+	References    []SpanRef
+	StartTime     uint64
+	Duration      uint64
+	Tags          []Tag
+	Logs          []Log
+	// Process is the process of the span when it differs from the process of its batch.
+	Process *Process
+}
+
+// SpanRef is a reference to another span.
+type SpanRef struct {
+	Type        RefType
+	TraceIDHigh uint64
+	TraceIDLow  uint64
+	SpanID      uint64
+}
+
+// Log is a timestamped event of a span, with a time in nanoseconds.
+type Log struct {
+	Timestamp uint64
+	Fields    []Tag
+}
+
+// Tag is a typed key-value pair.
+type Tag struct {
+	Key    string
+	Type   TagType
+	String string
+	Bool   bool
+	Long   int64
+	Double float64
+	Binary []byte
+}
+
+// text returns the value of the tag as a string.
+func (t Tag) text() string {
+	switch t.Type {
+	case TagBool:
+		return strconv.FormatBool(t.Bool)
+	case TagLong:
+		return strconv.FormatInt(t.Long, 10)
+	case TagDouble:
+		return strconv.FormatFloat(t.Double, 'g', -1, 64)
+	case TagBinary:
+		return string(t.Binary)
+	default:
+		return t.String
+	}
+}
+
+// keyValue returns the tag as an attribute.
+func (t Tag) keyValue() *commonpb.KeyValue {
+	value := &commonpb.AnyValue{}
+	switch t.Type {
+	case TagBool:
+		value.Value = &commonpb.AnyValue_BoolValue{BoolValue: t.Bool}
+	case TagLong:
+		value.Value = &commonpb.AnyValue_IntValue{IntValue: t.Long}
+	case TagDouble:
+		value.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: t.Double}
+	case TagBinary:
+		value.Value = &commonpb.AnyValue_BytesValue{BytesValue: t.Binary}
+	default:
+		value.Value = &commonpb.AnyValue_StringValue{StringValue: t.String}
+	}
+	return &commonpb.KeyValue{Key: t.Key, Value: value}
+}
+
+// Converter converts Jaeger batches into OTLP resources grouped by tenant.
+type Converter struct {
+	tenantLabel string
+	tenantTag   string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a span is read
+// from its tenantTag tag, or the tenantTag tag of its process, when set.
+func New(tenantLabel, tenantTag string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantTag: tenantTag}
+}
+
+// resourceKey identifies the resource of the spans of a process and tenant.
+type resourceKey struct {
+	process *Process
+	tenant  string
+}
+
+// ResourceSpans converts the batches into OTLP traces, grouping the spans of a batch into one resource per process
+// and tenant.
+func (c *Converter) ResourceSpans(batches []Batch) []*tracepb.ResourceSpans {
+	var resources []*tracepb.ResourceSpans
+
+	for i := range batches {
+		batch := &batches[i]
+		scopes := make(map[resourceKey]*tracepb.ScopeSpans)
+
+		for _, span := range batch.Spans {
+			process := &batch.Process
+			if span.Process != nil {
+				process = span.Process
+			}
+			tenant := c.tenant(span.Tags, process.Tags)
+
+			key := resourceKey{process: process, tenant: tenant}
+			scope, ok := scopes[key]
+			if !ok {
+				scope = &tracepb.ScopeSpans{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+				resources = append(resources, &tracepb.ResourceSpans{
+					Resource:   c.resource(process, tenant),
+					ScopeSpans: []*tracepb.ScopeSpans{scope},
+				})
+				scopes[key] = scope
+			}
+
+			scope.Spans = append(scope.Spans, convertSpan(span))
+		}
+	}
+
+	return resources
+}
+
+// tenant returns the value of the tenant tag of the span or its process, or an empty string when there is none.
+func (c *Converter) tenant(tags ...[]Tag) string {
+	if c.tenantTag == "" {
+		return ""
+	}
+	for _, tags := range tags {
+		for _, tag := range tags {
+			if tag.Key == c.tenantTag {
+				return tag.text()
+			}
+		}
+	}
+	return ""
+}
+
+// resource returns the resource of a process: the tenant attribute when there is a tenant, the service name and the
+// process tags.
+func (c *Converter) resource(process *Process, tenant string) *resourcepb.Resource {
+	var attributes []*commonpb.KeyValue
+	if tenant != "" {
+		attributes = append(attributes, stringKeyValue(c.tenantLabel, tenant))
+	}
+	if process.ServiceName != "" {
+		attributes = append(attributes, stringKeyValue(serviceNameAttrKey, process.ServiceName))
+	}
+	for _, tag := range process.Tags {
+		if tenant != "" && tag.Key == c.tenantLabel {
+			continue
+		}
+		attributes = append(attributes, tag.keyValue())
+	}
+	return &resourcepb.Resource{Attributes: attributes}
+}
+
+// convertSpan converts a Jaeger span into an OTLP span.
+func convertSpan(span Span) *tracepb.Span {
+	converted := &tracepb.Span{
+		TraceId:           traceID(span.TraceIDHigh, span.TraceIDLow),
+		SpanId:            spanID(span.SpanID),
+		Name:              span.OperationName,
+		Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: span.StartTime,
+		EndTimeUnixNano:   span.StartTime + span.Duration,
+	}
+
+	parent := -1
+	if span.ParentSpanID != 0 {
+		converted.ParentSpanId = spanID(span.ParentSpanID)
+	} else {
+		for i, ref := range span.References {
+			if ref.Type == RefChildOf && ref.TraceIDHigh == span.TraceIDHigh && ref.TraceIDLow == span.TraceIDLow {
+				converted.ParentSpanId = spanID(ref.SpanID)
+				parent = i
+				break
+			}
+		}
+	}
+	for i, ref := range span.References {
+		if i == parent || (ref.SpanID == span.ParentSpanID && ref.Type == RefChildOf) {
+			continue
+		}
+		converted.Links = append(converted.Links, &tracepb.Span_Link{
+			TraceId: traceID(ref.TraceIDHigh, ref.TraceIDLow),
+			SpanId:  spanID(ref.SpanID),
+		})
+	}
+
+	var statusCode, statusDescription string
+	var failed bool
+	for _, tag := range span.Tags {
+		switch tag.Key {
+		case spanKindTag:
+			if k, ok := kinds[strings.ToLower(tag.text())]; ok {
+				converted.Kind = k
+				continue
+			}
+		case errorTag:
+			failed = tag.text() == "true"
+			continue
+		case statusCodeTag:
+			statusCode = strings.ToUpper(tag.text())
+			continue
+		case statusDescriptionTag:
+			statusDescription = tag.text()
+			continue
+		}
+		converted.Attributes = append(converted.Attributes, tag.keyValue())
+	}
+	switch {
+	case statusCode == "ERROR" || (statusCode == "" && failed):
+		converted.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: statusDescription}
+	case statusCode == "OK":
+		converted.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK}
+	}
+
+	for _, log := range span.Logs {
+		event := &tracepb.Span_Event{TimeUnixNano: log.Timestamp}
+		for _, field := range log.Fields {
+			if field.Key == eventField && event.Name == "" {
+				event.Name = field.text()
+				continue
+			}
+			event.Attributes = append(event.Attributes, field.keyValue())
+		}
+		converted.Events = append(converted.Events, event)
+	}
+
+	return converted
+}
+
+// traceID returns the 16 byte trace ID made of the high and low 64 bits.
+func traceID(high, low uint64) []byte {
+	id := make([]byte, 16)
+	binary.BigEndian.PutUint64(id, high)
+	binary.BigEndian.PutUint64(id[8:], low)
+	return id
+}
+
+// spanID returns the 8 byte span ID.
+func spanID(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
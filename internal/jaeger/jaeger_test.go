@@ -0,0 +1,165 @@
+package jaeger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// testBatch is the batch encoded by the thrift and protobuf tests.
+func testBatch() Batch {
+	return Batch{
+		Process: Process{
+			ServiceName: "checkout",
+			Tags: []Tag{
+				{Key: "tenant", Type: TagString, String: "tenant-a"},
+				{Key: "hostname", Type: TagString, String: "host-1"},
+			},
+		},
+		Spans: []Span{
+			{
+				TraceIDHigh:   0x0af7651916cd43dd,
+				TraceIDLow:    0x8448eb211c80319c,
+				SpanID:        0xb7ad6b7169203331,
+				OperationName: "charge",
+				References: []SpanRef{
+					{Type: RefChildOf, TraceIDHigh: 0x0af7651916cd43dd, TraceIDLow: 0x8448eb211c80319c, SpanID: 0x1},
+					{Type: RefFollowsFrom, TraceIDLow: 0x2, SpanID: 0x3},
+				},
+				StartTime: 1700000000000000000,
+				Duration:  1500000,
+				Tags: []Tag{
+					{Key: "span.kind", Type: TagString, String: "client"},
+					{Key: "error", Type: TagBool, Bool: true},
+					{Key: "http.status_code", Type: TagLong, Long: 500},
+					{Key: "retry.ratio", Type: TagDouble, Double: 0.5},
+				},
+				Logs: []Log{{
+					Timestamp: 1700000000000500000,
+					Fields: []Tag{
+						{Key: "event", Type: TagString, String: "retry"},
+						{Key: "attempt", Type: TagLong, Long: 2},
+					},
+				}},
+			},
+			{
+				TraceIDLow:    0x2,
+				SpanID:        0x4,
+				ParentSpanID:  0x3,
+				OperationName: "audit",
+				StartTime:     1700000000000000000,
+				Tags:          []Tag{{Key: "tenant", Type: TagString, String: "tenant-b"}},
+			},
+		},
+	}
+}
+
+func TestConverter_ResourceSpans(t *testing.T) {
+	got := New("tenant.id", "tenant").ResourceSpans([]Batch{testBatch()})
+
+	resource := func(tenant string) *resourcepb.Resource {
+		return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringKeyValue("tenant.id", tenant),
+			stringKeyValue("service.name", "checkout"),
+			stringKeyValue("tenant", "tenant-a"),
+			stringKeyValue("hostname", "host-1"),
+		}}
+	}
+	scope := &commonpb.InstrumentationScope{Name: scopeName}
+	want := []*tracepb.ResourceSpans{
+		{
+			Resource: resource("tenant-a"),
+			ScopeSpans: []*tracepb.ScopeSpans{{Scope: scope, Spans: []*tracepb.Span{{
+				TraceId: []byte{
+					0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c,
+				},
+				SpanId:            []byte{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31},
+				ParentSpanId:      []byte{0, 0, 0, 0, 0, 0, 0, 1},
+				Name:              "charge",
+				Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+				StartTimeUnixNano: 1700000000000000000,
+				EndTimeUnixNano:   1700000000001500000,
+				Attributes: []*commonpb.KeyValue{
+					{Key: "http.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 500}}},
+					{Key: "retry.ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+				},
+				Events: []*tracepb.Span_Event{{
+					TimeUnixNano: 1700000000000500000,
+					Name:         "retry",
+					Attributes: []*commonpb.KeyValue{
+						{Key: "attempt", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 2}}},
+					},
+				}},
+				Links: []*tracepb.Span_Link{{
+					TraceId: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+					SpanId:  []byte{0, 0, 0, 0, 0, 0, 0, 3},
+				}},
+				Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR},
+			}}}},
+		},
+		{
+			// The tenant tag of the span takes precedence over the tenant tag of the process
+			Resource: resource("tenant-b"),
+			ScopeSpans: []*tracepb.ScopeSpans{{Scope: scope, Spans: []*tracepb.Span{{
+				TraceId:           []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+				SpanId:            []byte{0, 0, 0, 0, 0, 0, 0, 4},
+				ParentSpanId:      []byte{0, 0, 0, 0, 0, 0, 0, 3},
+				Name:              "audit",
+				Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+				StartTimeUnixNano: 1700000000000000000,
+				EndTimeUnixNano:   1700000000000000000,
+				Attributes:        []*commonpb.KeyValue{stringKeyValue("tenant", "tenant-b")},
+			}}}},
+		},
+	}
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[i]), "resource %d:\nwant %v\ngot  %v", i, want[i], got[i])
+	}
+}
+
+func TestConverter_ResourceSpans_Status(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []Tag
+		want *tracepb.Status
+	}{
+		{name: "unset"},
+		{name: "error tag", tags: []Tag{{Key: "error", Type: TagString, String: "true"}}, want: &tracepb.Status{
+			Code: tracepb.Status_STATUS_CODE_ERROR,
+		}},
+		{name: "false error tag", tags: []Tag{{Key: "error", Type: TagBool}}},
+		{
+			name: "otel status",
+			tags: []Tag{
+				{Key: "otel.status_code", Type: TagString, String: "ERROR"},
+				{Key: "otel.status_description", Type: TagString, String: "card declined"},
+			},
+			want: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "card declined"},
+		},
+		{
+			name: "otel status takes precedence",
+			tags: []Tag{
+				{Key: "error", Type: TagBool, Bool: true},
+				{Key: "otel.status_code", Type: TagString, String: "OK"},
+			},
+			want: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New("tenant.id", "").ResourceSpans([]Batch{{Spans: []Span{{SpanID: 1, Tags: tt.tags}}}})
+
+			span := got[0].GetScopeSpans()[0].GetSpans()[0]
+			assert.True(t, proto.Equal(tt.want, span.GetStatus()), "want %v, got %v", tt.want, span.GetStatus())
+			assert.Empty(t, span.GetAttributes())
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package jaeger converts Jaeger span batches into OTLP traces.
+package jaeger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the model.proto messages of the Jaeger API v2.
+const (
+	batchSpansField   = 1
+	batchProcessField = 2
+
+	spanTraceIDField       = 1
+	spanSpanIDField        = 2
+	spanOperationNameField = 3
+	spanReferencesField    = 4
+	spanStartTimeField     = 6
+	spanDurationField      = 7
+	spanTagsField          = 8
+	spanLogsField          = 9
+	spanProcessField       = 10
+
+	refTraceIDField = 1
+	refSpanIDField  = 2
+	refTypeField    = 3
+
+	processServiceNameField = 1
+	processTagsField        = 2
+
+	logTimestampField = 1
+	logFieldsField    = 2
+
+	keyValueKeyField     = 1
+	keyValueTypeField    = 2
+	keyValueStringField  = 3
+	keyValueBoolField    = 4
+	keyValueInt64Field   = 5
+	keyValueFloat64Field = 6
+	keyValueBinaryField  = 7
+
+	secondsField = 1
+	nanosField   = 2
+)
+
+// protoTagTypes maps the value types of model.proto to tag types.
+var protoTagTypes = map[uint64]TagType{0: TagString, 1: TagBool, 2: TagLong, 3: TagDouble, 4: TagBinary}
+
+// UnmarshalProto decodes a model.proto Batch, the fields the conversion does not use are skipped.
+func UnmarshalProto(b []byte) (*Batch, error) {
+	batch := &Batch{}
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case batchSpansField:
+			span, err := unmarshalSpan(v)
+			if err != nil {
+				return err
+			}
+			batch.Spans = append(batch.Spans, span)
+		case batchProcessField:
+			return unmarshalProcess(v, &batch.Process)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid jaeger protobuf batch: %w", err)
+	}
+	return batch, nil
+}
+
+// unmarshalSpan decodes a Span message.
+func unmarshalSpan(b []byte) (Span, error) {
+	var span Span
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		switch num {
+		case spanTraceIDField:
+			span.TraceIDHigh, span.TraceIDLow, err = unmarshalTraceID(v)
+		case spanSpanIDField:
+			span.SpanID, err = unmarshalSpanID(v)
+		case spanOperationNameField:
+			span.OperationName = string(v)
+		case spanReferencesField:
+			var ref SpanRef
+			ref, err = unmarshalSpanRef(v)
+			span.References = append(span.References, ref)
+		case spanStartTimeField:
+			span.StartTime, err = unmarshalNanos(v)
+		case spanDurationField:
+			span.Duration, err = unmarshalNanos(v)
+		case spanTagsField:
+			var tag Tag
+			tag, err = unmarshalKeyValue(v)
+			span.Tags = append(span.Tags, tag)
+		case spanLogsField:
+			var log Log
+			log, err = unmarshalLog(v)
+			span.Logs = append(span.Logs, log)
+		case spanProcessField:
+			span.Process = &Process{}
+			err = unmarshalProcess(v, span.Process)
+		}
+		return err
+	})
+	return span, err
+}
+
+// unmarshalSpanRef decodes a SpanRef message.
+func unmarshalSpanRef(b []byte) (SpanRef, error) {
+	var ref SpanRef
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		var err error
+		switch {
+		case num == refTraceIDField && typ == protowire.BytesType:
+			ref.TraceIDHigh, ref.TraceIDLow, err = unmarshalTraceID(v)
+		case num == refSpanIDField && typ == protowire.BytesType:
+			ref.SpanID, err = unmarshalSpanID(v)
+		case num == refTypeField && typ == protowire.VarintType:
+			ref.Type = RefType(n)
+		}
+		return err
+	})
+	return ref, err
+}
+
+// unmarshalProcess decodes a Process message.
+func unmarshalProcess(b []byte, process *Process) error {
+	return proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case processServiceNameField:
+			process.ServiceName = string(v)
+		case processTagsField:
+			tag, err := unmarshalKeyValue(v)
+			if err != nil {
+				return err
+			}
+			process.Tags = append(process.Tags, tag)
+		}
+		return nil
+	})
+}
+
+// unmarshalLog decodes a Log message.
+func unmarshalLog(b []byte) (Log, error) {
+	var log Log
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		switch num {
+		case logTimestampField:
+			log.Timestamp, err = unmarshalNanos(v)
+		case logFieldsField:
+			var tag Tag
+			tag, err = unmarshalKeyValue(v)
+			log.Fields = append(log.Fields, tag)
+		}
+		return err
+	})
+	return log, err
+}
+
+// unmarshalKeyValue decodes a KeyValue message.
+func unmarshalKeyValue(b []byte) (Tag, error) {
+	var tag Tag
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch {
+		case num == keyValueKeyField && typ == protowire.BytesType:
+			tag.Key = string(v)
+		case num == keyValueTypeField && typ == protowire.VarintType:
+			tag.Type = protoTagTypes[n]
+		case num == keyValueStringField && typ == protowire.BytesType:
+			tag.String = string(v)
+		case num == keyValueBoolField && typ == protowire.VarintType:
+			tag.Bool = n != 0
+		case num == keyValueInt64Field && typ == protowire.VarintType:
+			tag.Long = int64(n)
+		case num == keyValueFloat64Field && typ == protowire.Fixed64Type:
+			tag.Double = math.Float64frombits(n)
+		case num == keyValueBinaryField && typ == protowire.BytesType:
+			tag.Binary = v
+		}
+		return nil
+	})
+	return tag, err
+}
+
+// unmarshalNanos decodes a Timestamp or Duration message into nanoseconds.
+func unmarshalNanos(b []byte) (uint64, error) {
+	var seconds, nanos uint64
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+		switch {
+		case num == secondsField && typ == protowire.VarintType:
+			seconds = n
+		case num == nanosField && typ == protowire.VarintType:
+			nanos = n
+		}
+		return nil
+	})
+	return seconds*1e9 + nanos, err
+}
+
+// unmarshalTraceID decodes a 16 byte trace ID into its high and low 64 bits.
+func unmarshalTraceID(b []byte) (uint64, uint64, error) {
+	if len(b) != 16 {
+		return 0, 0, fmt.Errorf("trace id of %d bytes: %w", len(b), proto.ErrInvalid)
+	}
+	return binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:]), nil
+}
+
+// unmarshalSpanID decodes an 8 byte span ID.
+func unmarshalSpanID(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, fmt.Errorf("span id of %d bytes: %w", len(b), proto.ErrInvalid)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
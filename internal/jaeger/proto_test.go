@@ -0,0 +1,150 @@
+package jaeger
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// marshalProto encodes a batch as a model.proto Batch.
+func marshalProto(batch Batch) []byte {
+	message := func(num protowire.Number, b []byte, fields []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, fields)
+	}
+	nanos := func(num protowire.Number, b []byte, v uint64) []byte {
+		var fields []byte
+		fields = protowire.AppendTag(fields, secondsField, protowire.VarintType)
+		fields = protowire.AppendVarint(fields, v/1e9)
+		fields = protowire.AppendTag(fields, nanosField, protowire.VarintType)
+		fields = protowire.AppendVarint(fields, v%1e9)
+		return message(num, b, fields)
+	}
+	tag := func(num protowire.Number, b []byte, tag Tag) []byte {
+		var fields []byte
+		fields = protowire.AppendTag(fields, keyValueKeyField, protowire.BytesType)
+		fields = protowire.AppendString(fields, tag.Key)
+		fields = protowire.AppendTag(fields, keyValueTypeField, protowire.VarintType)
+		switch tag.Type {
+		case TagString:
+			fields = protowire.AppendVarint(fields, 0)
+			fields = protowire.AppendTag(fields, keyValueStringField, protowire.BytesType)
+			fields = protowire.AppendString(fields, tag.String)
+		case TagBool:
+			fields = protowire.AppendVarint(fields, 1)
+			fields = protowire.AppendTag(fields, keyValueBoolField, protowire.VarintType)
+			fields = protowire.AppendVarint(fields, protowire.EncodeBool(tag.Bool))
+		case TagLong:
+			fields = protowire.AppendVarint(fields, 2)
+			fields = protowire.AppendTag(fields, keyValueInt64Field, protowire.VarintType)
+			fields = protowire.AppendVarint(fields, uint64(tag.Long))
+		case TagDouble:
+			fields = protowire.AppendVarint(fields, 3)
+			fields = protowire.AppendTag(fields, keyValueFloat64Field, protowire.Fixed64Type)
+			fields = protowire.AppendFixed64(fields, math.Float64bits(tag.Double))
+		case TagBinary:
+			fields = protowire.AppendVarint(fields, 4)
+			fields = protowire.AppendTag(fields, keyValueBinaryField, protowire.BytesType)
+			fields = protowire.AppendBytes(fields, tag.Binary)
+		}
+		return message(num, b, fields)
+	}
+	process := func(num protowire.Number, b []byte, process Process) []byte {
+		var fields []byte
+		fields = protowire.AppendTag(fields, processServiceNameField, protowire.BytesType)
+		fields = protowire.AppendString(fields, process.ServiceName)
+		for _, t := range process.Tags {
+			fields = tag(processTagsField, fields, t)
+		}
+		return message(num, b, fields)
+	}
+
+	var b []byte
+	for _, span := range batch.Spans {
+		var fields []byte
+		fields = message(spanTraceIDField, fields, traceID(span.TraceIDHigh, span.TraceIDLow))
+		fields = message(spanSpanIDField, fields, spanID(span.SpanID))
+		fields = protowire.AppendTag(fields, spanOperationNameField, protowire.BytesType)
+		fields = protowire.AppendString(fields, span.OperationName)
+		refs := span.References
+		if span.ParentSpanID != 0 {
+			// The protobuf model has no parent span ID field, the parent is a reference
+			refs = append([]SpanRef{{TraceIDHigh: span.TraceIDHigh, TraceIDLow: span.TraceIDLow, SpanID: span.ParentSpanID}}, refs...)
+		}
+		for _, ref := range refs {
+			var refFields []byte
+			refFields = message(refTraceIDField, refFields, traceID(ref.TraceIDHigh, ref.TraceIDLow))
+			refFields = message(refSpanIDField, refFields, spanID(ref.SpanID))
+			refFields = protowire.AppendTag(refFields, refTypeField, protowire.VarintType)
+			refFields = protowire.AppendVarint(refFields, uint64(ref.Type))
+			fields = message(spanReferencesField, fields, refFields)
+		}
+		fields = nanos(spanStartTimeField, fields, span.StartTime)
+		fields = nanos(spanDurationField, fields, span.Duration)
+		for _, t := range span.Tags {
+			fields = tag(spanTagsField, fields, t)
+		}
+		for _, log := range span.Logs {
+			logFields := nanos(logTimestampField, nil, log.Timestamp)
+			for _, t := range log.Fields {
+				logFields = tag(logFieldsField, logFields, t)
+			}
+			fields = message(spanLogsField, fields, logFields)
+		}
+		if span.Process != nil {
+			fields = process(spanProcessField, fields, *span.Process)
+		}
+		b = message(batchSpansField, b, fields)
+	}
+	return process(batchProcessField, b, batch.Process)
+}
+
+func TestUnmarshalProto(t *testing.T) {
+	batch := testBatch()
+	batch.Spans[1].Process = &Process{ServiceName: "audit", Tags: []Tag{{Key: "binary", Type: TagBinary, Binary: []byte{1}}}}
+
+	got, err := UnmarshalProto(marshalProto(batch))
+	require.NoError(t, err)
+	assert.Equal(t, batch.Process, got.Process)
+	require.Len(t, got.Spans, 2)
+	assert.Equal(t, batch.Spans[0], got.Spans[0])
+
+	// The parent of the second span is decoded as a reference
+	second := batch.Spans[1]
+	second.References = []SpanRef{{TraceIDLow: second.TraceIDLow, SpanID: second.ParentSpanID}}
+	second.ParentSpanID = 0
+	assert.Equal(t, second, got.Spans[1])
+
+	// Both encode the same span
+	converter := New("tenant.id", "tenant")
+	assert.Equal(t,
+		converter.ResourceSpans([]Batch{testBatch()})[1].GetScopeSpans()[0].GetSpans()[0].GetParentSpanId(),
+		converter.ResourceSpans([]Batch{*got})[1].GetScopeSpans()[0].GetSpans()[0].GetParentSpanId(),
+	)
+}
+
+func TestUnmarshalProto_Invalid(t *testing.T) {
+	span := func(fields ...byte) []byte {
+		return append([]byte{byte(batchSpansField<<3 | protowire.BytesType), byte(len(fields))}, fields...)
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "truncated", body: marshalProto(testBatch())[:20]},
+		{name: "invalid tag", body: []byte{0x80}},
+		{name: "short trace id", body: span(byte(spanTraceIDField<<3|protowire.BytesType), 2, 1, 2)},
+		{name: "short span id", body: span(byte(spanSpanIDField<<3|protowire.BytesType), 1, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalProto(tt.body)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,386 @@
+// Package jaeger converts Jaeger span batches into OTLP traces.
+package jaeger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Thrift binary protocol field types.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// maxThriftDepth bounds the nesting of the skipped structs and containers.
+const maxThriftDepth = 64
+
+// thriftTagTypes maps the tag types of jaeger.thrift to tag types.
+var thriftTagTypes = map[int32]TagType{0: TagString, 1: TagDouble, 2: TagBool, 3: TagLong, 4: TagBinary}
+
+var errInvalidThrift = errors.New("invalid thrift")
+
+// UnmarshalThrift decodes a jaeger.thrift Batch encoded with the Thrift binary protocol, the fields the conversion does
+// not use are skipped.
+func UnmarshalThrift(b []byte) (*Batch, error) {
+	r := &thriftReader{b: b}
+	batch := &Batch{}
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return r.readProcess(&batch.Process)
+		case id == 2 && typ == thriftList:
+			return r.readList(thriftStruct, func() error {
+				var span Span
+				if err := r.readSpan(&span); err != nil {
+					return err
+				}
+				batch.Spans = append(batch.Spans, span)
+				return nil
+			})
+		}
+		return r.skip(typ, 0)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid jaeger thrift batch: %w", err)
+	}
+	return batch, nil
+}
+
+// thriftReader reads values of the Thrift binary protocol.
+type thriftReader struct {
+	b []byte
+}
+
+// next returns the next n bytes.
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errInvalidThrift
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	v, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	v, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(v)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	v, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(v)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	v, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(v)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	v, err := r.readI64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(int(n))
+}
+
+func (r *thriftReader) readString() (string, error) {
+	v, err := r.readBinary()
+	return string(v), err
+}
+
+// readStruct calls fn with the id and type of every field of a struct, fn must read or skip the value.
+func (r *thriftReader) readStruct(fn func(id int16, typ byte) error) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := fn(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readListHeader reads the element type and size of a list or set.
+func (r *thriftReader) readListHeader() (byte, int, error) {
+	elem, err := r.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	n, err := r.readI32()
+	if err != nil {
+		return 0, 0, err
+	}
+	// Every element takes at least a byte, which bounds the size of a corrupt list
+	if n < 0 || int(n) > len(r.b) {
+		return 0, 0, errInvalidThrift
+	}
+	return elem, int(n), nil
+}
+
+// readList calls fn for every element of a list of the given element type.
+func (r *thriftReader) readList(elem byte, fn func() error) error {
+	typ, n, err := r.readListHeader()
+	if err != nil {
+		return err
+	}
+	if typ != elem {
+		return errInvalidThrift
+	}
+	for range n {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value of the given type.
+func (r *thriftReader) skip(typ byte, depth int) error {
+	if depth > maxThriftDepth {
+		return errInvalidThrift
+	}
+
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct:
+		err = r.readStruct(func(_ int16, typ byte) error { return r.skip(typ, depth+1) })
+	case thriftMap:
+		var key, value byte
+		var n int32
+		if key, err = r.readByte(); err != nil {
+			return err
+		}
+		if value, err = r.readByte(); err != nil {
+			return err
+		}
+		if n, err = r.readI32(); err != nil {
+			return err
+		}
+		if n < 0 || int(n) > len(r.b) {
+			return errInvalidThrift
+		}
+		for range n {
+			if err := r.skip(key, depth+1); err != nil {
+				return err
+			}
+			if err := r.skip(value, depth+1); err != nil {
+				return err
+			}
+		}
+	case thriftSet, thriftList:
+		elem, n, err := r.readListHeader()
+		if err != nil {
+			return err
+		}
+		for range n {
+			if err := r.skip(elem, depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		err = errInvalidThrift
+	}
+	return err
+}
+
+// readProcess reads a Process struct.
+func (r *thriftReader) readProcess(process *Process) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			process.ServiceName, err = r.readString()
+		case id == 2 && typ == thriftList:
+			process.Tags, err = r.readTags()
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+}
+
+// readSpan reads a Span struct, converting its times from microseconds to nanoseconds.
+func (r *thriftReader) readSpan(span *Span) error {
+	return r.readStruct(func(id int16, typ byte) error {
+		var err error
+		var v int64
+		switch {
+		case id == 1 && typ == thriftI64:
+			v, err = r.readI64()
+			span.TraceIDLow = uint64(v)
+		case id == 2 && typ == thriftI64:
+			v, err = r.readI64()
+			span.TraceIDHigh = uint64(v)
+		case id == 3 && typ == thriftI64:
+			v, err = r.readI64()
+			span.SpanID = uint64(v)
+		case id == 4 && typ == thriftI64:
+			v, err = r.readI64()
+			span.ParentSpanID = uint64(v)
+		case id == 5 && typ == thriftString:
+			span.OperationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				ref, err := r.readSpanRef()
+				span.References = append(span.References, ref)
+				return err
+			})
+		case id == 8 && typ == thriftI64:
+			v, err = r.readI64()
+			span.StartTime = uint64(v) * 1000
+		case id == 9 && typ == thriftI64:
+			v, err = r.readI64()
+			span.Duration = uint64(v) * 1000
+		case id == 10 && typ == thriftList:
+			span.Tags, err = r.readTags()
+		case id == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				log, err := r.readLog()
+				span.Logs = append(span.Logs, log)
+				return err
+			})
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+}
+
+// readSpanRef reads a SpanRef struct.
+func (r *thriftReader) readSpanRef() (SpanRef, error) {
+	var ref SpanRef
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		var v int64
+		switch {
+		case id == 1 && typ == thriftI32:
+			var refType int32
+			refType, err = r.readI32()
+			ref.Type = RefType(refType)
+		case id == 2 && typ == thriftI64:
+			v, err = r.readI64()
+			ref.TraceIDLow = uint64(v)
+		case id == 3 && typ == thriftI64:
+			v, err = r.readI64()
+			ref.TraceIDHigh = uint64(v)
+		case id == 4 && typ == thriftI64:
+			v, err = r.readI64()
+			ref.SpanID = uint64(v)
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return ref, err
+}
+
+// readLog reads a Log struct, converting its timestamp from microseconds to nanoseconds.
+func (r *thriftReader) readLog() (Log, error) {
+	var log Log
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			var v int64
+			v, err = r.readI64()
+			log.Timestamp = uint64(v) * 1000
+		case id == 2 && typ == thriftList:
+			log.Fields, err = r.readTags()
+		default:
+			err = r.skip(typ, 0)
+		}
+		return err
+	})
+	return log, err
+}
+
+// readTags reads a list of Tag structs.
+func (r *thriftReader) readTags() ([]Tag, error) {
+	var tags []Tag
+	err := r.readList(thriftStruct, func() error {
+		var tag Tag
+		err := r.readStruct(func(id int16, typ byte) error {
+			var err error
+			switch {
+			case id == 1 && typ == thriftString:
+				tag.Key, err = r.readString()
+			case id == 2 && typ == thriftI32:
+				var v int32
+				v, err = r.readI32()
+				tag.Type = thriftTagTypes[v]
+			case id == 3 && typ == thriftString:
+				tag.String, err = r.readString()
+			case id == 4 && typ == thriftDouble:
+				tag.Double, err = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				var v byte
+				v, err = r.readByte()
+				tag.Bool = v != 0
+			case id == 6 && typ == thriftI64:
+				tag.Long, err = r.readI64()
+			case id == 7 && typ == thriftString:
+				tag.Binary, err = r.readBinary()
+			default:
+				err = r.skip(typ, 0)
+			}
+			return err
+		})
+		tags = append(tags, tag)
+		return err
+	})
+	return tags, err
+}
@@ -0,0 +1,158 @@
+package jaeger
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftWriter writes values of the Thrift binary protocol.
+type thriftWriter struct {
+	b []byte
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.b = append(w.b, typ)
+	w.b = binary.BigEndian.AppendUint16(w.b, uint16(id))
+}
+
+func (w *thriftWriter) stop() { w.b = append(w.b, thriftStop) }
+
+func (w *thriftWriter) list(elem byte, n int) {
+	w.b = append(w.b, elem)
+	w.b = binary.BigEndian.AppendUint32(w.b, uint32(n))
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	w.b = binary.BigEndian.AppendUint32(w.b, uint32(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	w.b = binary.BigEndian.AppendUint64(w.b, uint64(v))
+}
+
+func (w *thriftWriter) string(id int16, v string) {
+	w.field(thriftString, id)
+	w.b = binary.BigEndian.AppendUint32(w.b, uint32(len(v)))
+	w.b = append(w.b, v...)
+}
+
+func (w *thriftWriter) tags(id int16, tags []Tag) {
+	w.field(thriftList, id)
+	w.list(thriftStruct, len(tags))
+	for _, tag := range tags {
+		w.string(1, tag.Key)
+		switch tag.Type {
+		case TagString:
+			w.i32(2, 0)
+			w.string(3, tag.String)
+		case TagDouble:
+			w.i32(2, 1)
+			w.field(thriftDouble, 4)
+			w.b = binary.BigEndian.AppendUint64(w.b, math.Float64bits(tag.Double))
+		case TagBool:
+			w.i32(2, 2)
+			w.field(thriftBool, 5)
+			if tag.Bool {
+				w.b = append(w.b, 1)
+			} else {
+				w.b = append(w.b, 0)
+			}
+		case TagLong:
+			w.i32(2, 3)
+			w.i64(6, tag.Long)
+		case TagBinary:
+			w.i32(2, 4)
+			w.string(7, string(tag.Binary))
+		}
+		w.stop()
+	}
+}
+
+// marshalThrift encodes a batch as a jaeger.thrift Batch, with times truncated to microseconds.
+func marshalThrift(batch Batch) []byte {
+	w := &thriftWriter{}
+
+	w.field(thriftStruct, 1)
+	w.string(1, batch.Process.ServiceName)
+	w.tags(2, batch.Process.Tags)
+	w.stop()
+
+	w.field(thriftList, 2)
+	w.list(thriftStruct, len(batch.Spans))
+	for _, span := range batch.Spans {
+		w.i64(1, int64(span.TraceIDLow))
+		w.i64(2, int64(span.TraceIDHigh))
+		w.i64(3, int64(span.SpanID))
+		w.i64(4, int64(span.ParentSpanID))
+		w.string(5, span.OperationName)
+		w.field(thriftList, 6)
+		w.list(thriftStruct, len(span.References))
+		for _, ref := range span.References {
+			w.i32(1, int32(ref.Type))
+			w.i64(2, int64(ref.TraceIDLow))
+			w.i64(3, int64(ref.TraceIDHigh))
+			w.i64(4, int64(ref.SpanID))
+			w.stop()
+		}
+		w.i32(7, 1)
+		w.i64(8, int64(span.StartTime/1000))
+		w.i64(9, int64(span.Duration/1000))
+		w.tags(10, span.Tags)
+		w.field(thriftList, 11)
+		w.list(thriftStruct, len(span.Logs))
+		for _, log := range span.Logs {
+			w.i64(1, int64(log.Timestamp/1000))
+			w.tags(2, log.Fields)
+			w.stop()
+		}
+		w.stop()
+	}
+
+	// The sequence number and client stats are skipped
+	w.i64(3, 42)
+	w.field(thriftStruct, 4)
+	w.i64(1, 1)
+	w.field(thriftMap, 2)
+	w.b = append(w.b, thriftString, thriftI32, 0, 0, 0, 1, 0, 0, 0, 1, 'a', 0, 0, 0, 7)
+	w.stop()
+
+	w.stop()
+	return w.b
+}
+
+func TestUnmarshalThrift(t *testing.T) {
+	want := testBatch()
+
+	got, err := UnmarshalThrift(marshalThrift(want))
+	require.NoError(t, err)
+	assert.Equal(t, want, *got)
+}
+
+func TestUnmarshalThrift_Invalid(t *testing.T) {
+	valid := marshalThrift(testBatch())
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "empty", body: nil},
+		{name: "truncated", body: valid[:len(valid)/2]},
+		{name: "missing stop", body: valid[:len(valid)-1]},
+		{name: "unknown type", body: []byte{0x7f, 0, 1}},
+		{name: "list too large", body: []byte{thriftList, 0, 2, thriftStruct, 0x7f, 0xff, 0xff, 0xff}},
+		{name: "negative string", body: []byte{thriftString, 0, 9, 0xff, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalThrift(tt.body)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package kafka provides a Kafka consumer and producer carrying OTLP payloads in and out of the signal pipelines.
+package kafka
+
+import (
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+)
+
+// budgetBuckets is the number of buckets the window of a retry budget is split into.
+const budgetBuckets = 10
+
+// budgetBucket counts the forwards and failures of a slice of the window of a retry budget.
+type budgetBucket struct {
+	start    time.Time
+	forwards int
+	failures int
+}
+
+// retryBudget suppresses the retries of a signal while the failure rate of its recent forwards exceeds a threshold,
+// so a failing backend is not sent the retries of every payload on top of the new ones. A nil retryBudget never
+// suppresses retries.
+type retryBudget struct {
+	threshold   float64
+	minForwards int
+	width       time.Duration
+	clock       clock.Clock
+
+	mu      sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+// newRetryBudget creates a retry budget over the window, returning nil when the threshold is not positive so the
+// budget is disabled.
+func newRetryBudget(threshold float64, minForwards int, window time.Duration, c clock.Clock) *retryBudget {
+	if threshold <= 0 || window <= 0 {
+		return nil
+	}
+	return &retryBudget{
+		threshold:   threshold,
+		minForwards: minForwards,
+		width:       max(window/budgetBuckets, time.Millisecond),
+		clock:       c,
+	}
+}
+
+// record records the outcome of a forward, first attempts and retries alike.
+func (b *retryBudget) record(failed bool) {
+	if b == nil {
+		return
+	}
+
+	now := b.clock.Now()
+	start := now.Truncate(b.width)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bucket := &b.buckets[(start.UnixNano()/int64(b.width))%budgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	bucket.forwards++
+	if failed {
+		bucket.failures++
+	}
+}
+
+// exhausted reports whether the failure rate of the forwards within the window exceeds the threshold, once there are
+// enough forwards for the rate to be meaningful.
+func (b *retryBudget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+
+	oldest := b.clock.Now().Truncate(b.width).Add(-b.width * (budgetBuckets - 1))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var forwards, failures int
+	for _, bucket := range b.buckets {
+		if bucket.start.Before(oldest) {
+			continue
+		}
+		forwards += bucket.forwards
+		failures += bucket.failures
+	}
+	return forwards > 0 && forwards >= b.minForwards && float64(failures)/float64(forwards) > b.threshold
+}
@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		window    time.Duration
+		forwards  []bool
+		advance   time.Duration
+		want      bool
+	}{
+		{
+			name:      "disabled",
+			threshold: 0,
+			window:    time.Minute,
+			forwards:  []bool{true, true, true, true},
+		},
+		{
+			name:      "too few forwards",
+			threshold: 0.5,
+			window:    time.Minute,
+			forwards:  []bool{true, true, true},
+		},
+		{
+			name:      "failure rate at the threshold",
+			threshold: 0.5,
+			window:    time.Minute,
+			forwards:  []bool{true, true, false, false},
+		},
+		{
+			name:      "failure rate above the threshold",
+			threshold: 0.5,
+			window:    time.Minute,
+			forwards:  []bool{true, true, true, false},
+			want:      true,
+		},
+		{
+			name:      "failures within the window",
+			threshold: 0.5,
+			window:    time.Minute,
+			forwards:  []bool{true, true, true, true},
+			advance:   50 * time.Second,
+			want:      true,
+		},
+		{
+			name:      "failures out of the window",
+			threshold: 0.5,
+			window:    time.Minute,
+			forwards:  []bool{true, true, true, true},
+			advance:   2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := clock.NewFake(time.Unix(1700000000, 0))
+			b := newRetryBudget(tt.threshold, 4, tt.window, c)
+			for _, failed := range tt.forwards {
+				b.record(failed)
+			}
+			c.Advance(tt.advance)
+			assert.Equal(t, tt.want, b.exhausted())
+		})
+	}
+}
+
+func TestSource_handle_RetryBudget(t *testing.T) {
+	payload, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	var forwards int
+	cfg := &config.Kafka{
+		LogsTopic:     "otlp_logs",
+		Encoding:      config.EncodingProtobuf,
+		InitialOffset: config.KafkaOffsetEarliest,
+		MaxRetries:    3,
+		RetryBackoff:  time.Millisecond,
+		RetryBudget:   config.RetryBudget{Threshold: 0.5, Window: time.Minute, MinForwards: 2},
+	}
+	s, err := New(cfg, Sinks{Logs: func(context.Context, []*logpb.ResourceLogs) error {
+		forwards++
+		return errors.New("backend unavailable")
+	}}, WithMeter(meter), WithClock(clock.NewFake(time.Unix(1700000000, 0))))
+	require.NoError(t, err)
+
+	// The first message is retried until the budget is exhausted, the second one is not retried at all
+	ctx := t.Context()
+	assert.True(t, s.handle(ctx, ctx, &kgo.Record{Topic: "otlp_logs", Value: payload}))
+	assert.Equal(t, 2, forwards)
+	assert.True(t, s.handle(ctx, ctx, &kgo.Record{Topic: "otlp_logs", Value: payload}))
+	assert.Equal(t, 3, forwards)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	values := make(map[string]int64)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		sum, ok := m.Data.(metricdata.Sum[int64])
+		require.True(t, ok)
+		for _, dp := range sum.DataPoints {
+			values[m.Name] += dp.Value
+		}
+	}
+	assert.Equal(t, int64(2), values["otel_lgtm_proxy_kafka_retries_suppressed_total"])
+	assert.Equal(t, int64(2), values["otel_lgtm_proxy_kafka_messages_total"])
+}
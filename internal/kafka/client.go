@@ -0,0 +1,45 @@
+// Package kafka provides a Kafka consumer and producer carrying OTLP payloads in and out of the signal pipelines.
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// saslOptions returns the options authenticating the connections to the brokers, none when no mechanism is set.
+func saslOptions(cfg *config.KafkaSASL) ([]kgo.Opt, error) {
+	switch cfg.Mechanism {
+	case "":
+		return nil, nil
+	case config.KafkaSASLPlain:
+		return []kgo.Opt{kgo.SASL(plain.Auth{User: cfg.Username, Pass: cfg.Password}.AsMechanism())}, nil
+	case config.KafkaSASLScramSHA256:
+		return []kgo.Opt{kgo.SASL(scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha256Mechanism())}, nil
+	case config.KafkaSASLScramSHA512:
+		return []kgo.Opt{kgo.SASL(scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha512Mechanism())}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kafka SASL mechanism %q", cfg.Mechanism)
+	}
+}
+
+// compressionCodec returns the codec compressing the record batches written to the brokers.
+func compressionCodec(name string) (kgo.CompressionCodec, error) {
+	switch name {
+	case "", config.KafkaCompressionNone:
+		return kgo.NoCompression(), nil
+	case config.KafkaCompressionGzip:
+		return kgo.GzipCompression(), nil
+	case config.KafkaCompressionSnappy:
+		return kgo.SnappyCompression(), nil
+	case config.KafkaCompressionLz4:
+		return kgo.Lz4Compression(), nil
+	case config.KafkaCompressionZstd:
+		return kgo.ZstdCompression(), nil
+	default:
+		return kgo.CompressionCodec{}, fmt.Errorf("unsupported kafka compression %q", name)
+	}
+}
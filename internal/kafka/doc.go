@@ -0,0 +1,35 @@
+// Package kafka provides a Kafka consumer and producer carrying OTLP payloads in and out of the signal pipelines.
+//
+// High-volume environments can buffer telemetry in Kafka instead of pushing it
+// over HTTP. The proxy joins a consumer group and reads a topic per signal,
+// each message holding an OTLP LogsData, MetricsData or TracesData payload
+// like those written by the Kafka exporter of the OpenTelemetry Collector:
+//   - Payloads are protobuf or JSON encoded, a content-type record header
+//     overriding the configured encoding
+//   - The partitions are balanced across the proxies of the group with the
+//     range assignor, so the group can be shared with other Kafka clients
+//   - Offsets are committed once the payloads are forwarded, so a restart
+//     consumes again the payloads that were not forwarded
+//   - Rebalances of the group wait for the payloads being forwarded, so a
+//     proxy never commits the offsets of partitions it no longer owns
+//   - Payloads that cannot be decoded are dropped, and payloads that keep
+//     failing to be forwarded are dropped after the configured retries
+//   - A retry budget per signal stops the retries while most of the recent
+//     forwards of the signal failed, so retries do not deepen an outage
+//
+// Backend endpoints using the kafka protocol write the payload of each
+// tenant to a topic with a Producer instead of sending it over HTTP. The
+// producer is a backend client of the processors, so the payloads are
+// partitioned, hooked and retried like backend requests:
+//   - The message key is the tenant, hashed like the Java producer does so
+//     the payloads of a tenant land in the same partition
+//   - The request headers, including the tenant header and the content type,
+//     become the message headers
+//   - Messages the brokers refuse or cannot write yet are answered with the
+//     status codes of an HTTP backend
+//
+// Both are built on the franz-go client, which negotiates the request
+// versions with the brokers and reads and writes record batches compressed
+// with gzip, snappy, lz4 or zstd. Connections can use TLS and SASL PLAIN or
+// SCRAM authentication.
+package kafka
@@ -0,0 +1,163 @@
+// Package kafka provides a Kafka consumer and producer carrying OTLP payloads in and out of the signal pipelines.
+package kafka
+
+import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/processor"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// producerClientID identifies the producer to the brokers.
+const producerClientID = "otel-lgtm-proxy"
+
+// defaultProduceTimeout is the time the brokers are given to write a payload when the endpoint has no timeout.
+const defaultProduceTimeout = 30 * time.Second
+
+// Producer is a backend client writing the payloads of the requests to a Kafka topic instead of sending them over
+// HTTP. The topic is the path of the request address, and each payload is keyed by the tenant of the request so the
+// payloads of a tenant land in the same partition, in order.
+type Producer struct {
+	client       *kgo.Client
+	tenantHeader string
+}
+
+// Ensure that Producer implements the processor Client interface.
+var _ processor.Client = (*Producer)(nil)
+
+// NewProducer creates a producer for an endpoint whose address has the form kafka://<brokers>/<topic>, the brokers
+// being separated by commas. The tenant of a request is read from the tenant header.
+func NewProducer(endpoint *config.Endpoint, tenantHeader string, tlsConfig *tls.Config) (*Producer, error) {
+	u, err := url.Parse(endpoint.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka address: %w", err)
+	}
+	if u.Scheme != "kafka" || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid kafka address %q, want kafka://<brokers>/<topic>", endpoint.Address)
+	}
+	if endpoint.TLS.TenantCertDir != "" {
+		return nil, errors.New("tenant certificates are not supported by the kafka protocol")
+	}
+	if endpoint.Discovery.Service != "" {
+		return nil, errors.New("backend discovery is not supported by the kafka protocol")
+	}
+
+	codec, err := compressionCodec(endpoint.Kafka.Compression)
+	if err != nil {
+		return nil, err
+	}
+	sasl, err := saslOptions(&endpoint.Kafka.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payloads are acknowledged by all in-sync replicas within the timeout of the endpoint. Keyed payloads are hashed
+	// with murmur2 like the default partitioner of the Java producer does, so the payloads of a tenant land in the same
+	// partition as those written by other Kafka clients with the same key
+	timeout := cmp.Or(endpoint.Timeout, defaultProduceTimeout)
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(strings.Split(u.Host, ",")...),
+		kgo.ClientID(producerClientID),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.ProducerBatchCompression(codec),
+		kgo.RecordDeliveryTimeout(timeout),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+	client, err := kgo.NewClient(append(opts, sasl...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
+	}
+
+	return &Producer{
+		client:       client,
+		tenantHeader: tenantHeader,
+	}, nil
+}
+
+// Do writes the body of the request to the topic of its address, answering with the status code an HTTP backend
+// would give: payloads refused by the brokers are rejected, and payloads the brokers could not write yet are
+// answered as unavailable so they are retried.
+func (p *Producer) Do(req *http.Request) (*http.Response, error) {
+	var value []byte
+	if req.Body != nil {
+		var err error
+		value, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rec := &kgo.Record{
+		Topic:   strings.TrimPrefix(req.URL.Path, "/"),
+		Key:     []byte(req.Header.Get(p.tenantHeader)),
+		Headers: recordHeaders(req.Header),
+		Value:   value,
+	}
+	status, err := produceStatus(p.client.ProduceSync(req.Context(), rec).FirstErr())
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+// Close closes the connections to the brokers.
+func (p *Producer) Close() {
+	p.client.Close()
+}
+
+// recordHeaders returns the headers of the request as record headers, named in lower case like HTTP/2 headers.
+func recordHeaders(h http.Header) []kgo.RecordHeader {
+	var headers []kgo.RecordHeader
+	for _, key := range slices.Sorted(maps.Keys(h)) {
+		for _, value := range h[key] {
+			headers = append(headers, kgo.RecordHeader{Key: strings.ToLower(key), Value: []byte(value)})
+		}
+	}
+	return headers
+}
+
+// produceStatus returns the status code of an HTTP backend matching the outcome of a produced record. Errors that
+// are not Kafka errors, such as an expired context, are returned as they are.
+func produceStatus(err error) (int, error) {
+	var kafkaErr *kerr.Error
+	switch {
+	case err == nil:
+		return http.StatusOK, nil
+	case errors.Is(err, kerr.MessageTooLarge), errors.Is(err, kerr.RecordListTooLarge):
+		return http.StatusRequestEntityTooLarge, nil
+	case errors.Is(err, kerr.CorruptMessage), errors.Is(err, kerr.InvalidRecord):
+		return http.StatusBadRequest, nil
+	case errors.Is(err, kerr.TopicAuthorizationFailed):
+		return http.StatusForbidden, nil
+	case errors.As(err, &kafkaErr), errors.Is(err, kgo.ErrRecordTimeout), errors.Is(err, kgo.ErrRecordRetries):
+		return http.StatusServiceUnavailable, nil
+	default:
+		return 0, err
+	}
+}
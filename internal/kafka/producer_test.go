@@ -0,0 +1,236 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// newCluster starts a fake Kafka cluster with the topics and their number of partitions.
+func newCluster(t *testing.T, topics map[string]int32) *kfake.Cluster {
+	t.Helper()
+
+	opts := []kfake.Opt{kfake.NumBrokers(1)}
+	for topic, partitions := range topics {
+		opts = append(opts, kfake.SeedTopics(partitions, topic))
+	}
+	c, err := kfake.NewCluster(opts...)
+	require.NoError(t, err)
+	t.Cleanup(c.Close)
+	return c
+}
+
+// consumeAll returns the records of the topic written to the cluster, by partition.
+func consumeAll(t *testing.T, c *kfake.Cluster, topic string, n int) map[int32][]*kgo.Record {
+	t.Helper()
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(c.ListenAddrs()...),
+		kgo.ConsumeTopics(topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	records := make(map[int32][]*kgo.Record)
+	for consumed := 0; consumed < n; {
+		fetches := client.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+		fetches.EachRecord(func(rec *kgo.Record) {
+			records[rec.Partition] = append(records[rec.Partition], rec)
+			consumed++
+		})
+	}
+	return records
+}
+
+func testProducer(t *testing.T, c *kfake.Cluster, timeout time.Duration) *Producer {
+	t.Helper()
+
+	p, err := NewProducer(&config.Endpoint{
+		Address: "kafka://" + c.ListenAddrs()[0] + "/otlp_logs",
+		Timeout: timeout,
+	}, "X-Scope-OrgID", nil)
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func newProduceRequest(t *testing.T, address, tenant string, body []byte) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, address, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Scope-OrgID", tenant)
+	return req
+}
+
+func TestProducer_Do(t *testing.T) {
+	c := newCluster(t, map[string]int32{"otlp_logs": 3})
+	p := testProducer(t, c, 5*time.Second)
+
+	tenants := []string{"tenant-a", "tenant-b", "tenant-a"}
+	for _, tenant := range tenants {
+		req := newProduceRequest(t, "kafka://"+c.ListenAddrs()[0]+"/otlp_logs", tenant, []byte("payload "+tenant))
+		resp, err := p.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// The payloads of a tenant are written to the partition of its key, in order
+	byTenant := make(map[string][]*kgo.Record)
+	for _, records := range consumeAll(t, c, "otlp_logs", len(tenants)) {
+		for _, rec := range records {
+			byTenant[string(rec.Key)] = append(byTenant[string(rec.Key)], rec)
+		}
+	}
+	require.Len(t, byTenant["tenant-a"], 2)
+	require.Len(t, byTenant["tenant-b"], 1)
+	assert.Equal(t, byTenant["tenant-a"][0].Partition, byTenant["tenant-a"][1].Partition)
+	assert.Less(t, byTenant["tenant-a"][0].Offset, byTenant["tenant-a"][1].Offset)
+
+	rec := byTenant["tenant-b"][0]
+	assert.Equal(t, []byte("payload tenant-b"), rec.Value)
+	assert.Equal(t, []kgo.RecordHeader{
+		{Key: "content-type", Value: []byte("application/x-protobuf")},
+		{Key: "x-scope-orgid", Value: []byte("tenant-b")},
+	}, rec.Headers)
+}
+
+func TestProducer_Do_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		topic      string
+		produceErr *kerr.Error
+		wantStatus int
+	}{
+		{name: "payload too large", topic: "otlp_logs", produceErr: kerr.MessageTooLarge, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "invalid payload", topic: "otlp_logs", produceErr: kerr.InvalidRecord, wantStatus: http.StatusBadRequest},
+		{name: "not authorized", topic: "otlp_logs", produceErr: kerr.TopicAuthorizationFailed, wantStatus: http.StatusForbidden},
+		{name: "not enough replicas", topic: "otlp_logs", produceErr: kerr.NotEnoughReplicas, wantStatus: http.StatusServiceUnavailable},
+		{name: "unknown topic", topic: "unknown", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCluster(t, map[string]int32{"otlp_logs": 1})
+			if tt.produceErr != nil {
+				c.ControlKey(int16(kmsg.Produce), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+					c.KeepControl()
+					req := kreq.(*kmsg.ProduceRequest)
+					resp := req.ResponseKind().(*kmsg.ProduceResponse)
+					for _, topic := range req.Topics {
+						rt := kmsg.NewProduceResponseTopic()
+						rt.Topic = topic.Topic
+						rt.TopicID = topic.TopicID
+						for _, partition := range topic.Partitions {
+							rp := kmsg.NewProduceResponseTopicPartition()
+							rp.Partition = partition.Partition
+							rp.ErrorCode = tt.produceErr.Code
+							rt.Partitions = append(rt.Partitions, rp)
+						}
+						resp.Topics = append(resp.Topics, rt)
+					}
+					return resp, nil, true
+				})
+			}
+			p := testProducer(t, c, time.Second)
+
+			resp, err := p.Do(newProduceRequest(t, "kafka://"+c.ListenAddrs()[0]+"/"+tt.topic, "tenant-a", []byte("payload")))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestNewProducer(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint config.Endpoint
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			endpoint: config.Endpoint{Address: "kafka://kafka-0:9092,kafka-1:9092/otlp_logs"},
+		},
+		{
+			name: "compression and SASL",
+			endpoint: config.Endpoint{
+				Address: "kafka://kafka-0:9092,kafka-1:9092/otlp_logs",
+				Kafka: config.KafkaProducer{
+					Compression: config.KafkaCompressionZstd,
+					SASL:        config.KafkaSASL{Mechanism: config.KafkaSASLScramSHA512, Username: "proxy", Password: "secret"},
+				},
+			},
+		},
+		{
+			name:     "http address",
+			endpoint: config.Endpoint{Address: "http://loki:3100/otlp/v1/logs"},
+			wantErr:  "want kafka://<brokers>/<topic>",
+		},
+		{
+			name:     "no topic",
+			endpoint: config.Endpoint{Address: "kafka://kafka-0:9092/"},
+			wantErr:  "want kafka://<brokers>/<topic>",
+		},
+		{
+			name: "tenant certificates",
+			endpoint: config.Endpoint{
+				Address: "kafka://kafka-0:9092/otlp_logs",
+				TLS:     config.TLSConfig{TenantCertDir: "/etc/tenants"},
+			},
+			wantErr: "tenant certificates are not supported",
+		},
+		{
+			name: "discovery",
+			endpoint: config.Endpoint{
+				Address:   "kafka://kafka-0:9092/otlp_logs",
+				Discovery: config.Discovery{Service: "kafka"},
+			},
+			wantErr: "backend discovery is not supported",
+		},
+		{
+			name: "invalid compression",
+			endpoint: config.Endpoint{
+				Address: "kafka://kafka-0:9092/otlp_logs",
+				Kafka:   config.KafkaProducer{Compression: "brotli"},
+			},
+			wantErr: "unsupported kafka compression",
+		},
+		{
+			name: "invalid SASL mechanism",
+			endpoint: config.Endpoint{
+				Address: "kafka://kafka-0:9092/otlp_logs",
+				Kafka:   config.KafkaProducer{SASL: config.KafkaSASL{Mechanism: "GSSAPI"}},
+			},
+			wantErr: "unsupported kafka SASL mechanism",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProducer(&tt.endpoint, "X-Scope-OrgID", nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer p.Close()
+			assert.Equal(t, []string{"kafka-0:9092", "kafka-1:9092"}, p.client.OptValue(kgo.SeedBrokers))
+		})
+	}
+}
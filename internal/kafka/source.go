@@ -0,0 +1,415 @@
+// Package kafka provides a Kafka consumer and producer carrying OTLP payloads in and out of the signal pipelines.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	otlpproto "github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	topicAttrKey      = "messaging.destination.name"
+	partitionAttrKey  = "messaging.destination.partition.id"
+	offsetAttrKey     = "messaging.kafka.offset"
+	signalTypeAttrKey = "signal.type"
+	outcomeAttrKey    = "kafka.message.outcome"
+	errAttrKey        = "error"
+)
+
+// Outcomes of the consumed messages.
+const (
+	outcomeForwarded = "forwarded"
+	outcomeInvalid   = "invalid"
+	outcomeFailed    = "failed"
+)
+
+// contentTypeHeader is the record header overriding the configured encoding of a message.
+const contentTypeHeader = "content-type"
+
+// commitTimeout limits the time taken to commit the offsets of the handled records, committed even once stopping.
+const commitTimeout = 5 * time.Second
+
+// Sinks receive the resources consumed from the topic of each signal.
+type Sinks struct {
+	Logs    func(ctx context.Context, resources []*logpb.ResourceLogs) error
+	Metrics func(ctx context.Context, resources []*metricpb.ResourceMetrics) error
+	Traces  func(ctx context.Context, resources []*tracepb.ResourceSpans) error
+}
+
+// topic decodes the OTLP payloads consumed from a topic.
+type topic struct {
+	signal string
+	// budget suppresses the retries of the signal while its backend is failing.
+	budget *retryBudget
+	// decode decodes a payload, returning the function forwarding its resources to the pipeline of the signal.
+	decode func(payload []byte, json bool) (func(ctx context.Context) error, error)
+}
+
+// newTopic creates a topic decoding payloads into the messages of a signal.
+func newTopic[T proto.Message, R any](
+	signal string,
+	message func() T,
+	resources func(T) []R,
+	sink func(ctx context.Context, resources []R) error,
+) topic {
+	return topic{signal: signal, decode: func(payload []byte, json bool) (func(ctx context.Context) error, error) {
+		data := message()
+		var err error
+		if json {
+			err = protojson.Unmarshal(payload, data)
+		} else {
+			err = proto.Unmarshal(payload, data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error { return sink(ctx, resources(data)) }, nil
+	}}
+}
+
+// Option configures optional dependencies of a Source.
+type Option func(*Source) error
+
+// WithMeter records the messages consumed by the source to the meter.
+func WithMeter(meter metric.Meter) Option {
+	return func(s *Source) error {
+		counter, err := meter.Int64Counter(
+			"otel_lgtm_proxy_kafka_messages_total",
+			metric.WithDescription("Number of messages consumed from Kafka by outcome"),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create otel lgtm proxy kafka messages counter: %w", err)
+		}
+		s.messages = counter
+
+		suppressed, err := meter.Int64Counter(
+			"otel_lgtm_proxy_kafka_retries_suppressed_total",
+			metric.WithDescription("Number of retries of messages suppressed by the retry budget of the signal"),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create otel lgtm proxy kafka retries suppressed counter: %w", err)
+		}
+		s.suppressed = suppressed
+		return nil
+	}
+}
+
+// WithClock sets the clock of the retry budgets.
+func WithClock(c clock.Clock) Option {
+	return func(s *Source) error {
+		s.clock = c
+		return nil
+	}
+}
+
+// Source consumes OTLP payloads from a Kafka topic per signal as a member of a consumer group, forwarding them to
+// the Sinks and committing their offsets once forwarded.
+type Source struct {
+	config     *config.Kafka
+	topics     map[string]topic
+	opts       []kgo.Opt
+	clock      clock.Clock
+	messages   metric.Int64Counter
+	suppressed metric.Int64Counter
+
+	// mu guards the cancellation of the generation of the records being handled, ended when the group rebalances.
+	mu      sync.Mutex
+	release context.CancelFunc
+}
+
+// New creates a new Source forwarding the consumed payloads to the sinks.
+func New(config *config.Kafka, sinks Sinks, opts ...Option) (*Source, error) {
+	if err := otlpproto.ValidateEncoding(config.Encoding); err != nil {
+		return nil, err
+	}
+	if config.InitialOffset != configOffsetLatest() && config.InitialOffset != configOffsetEarliest() {
+		return nil, fmt.Errorf("invalid kafka initial offset %q", config.InitialOffset)
+	}
+	if config.RetryBudget.Threshold < 0 || config.RetryBudget.Threshold > 1 {
+		return nil, fmt.Errorf("invalid kafka retry budget threshold %v, want a failure rate between 0 and 1",
+			config.RetryBudget.Threshold)
+	}
+
+	topics := make(map[string]topic)
+	for _, t := range []struct {
+		name  string
+		topic topic
+	}{
+		{config.LogsTopic, newTopic("logs", func() *logpb.LogsData { return &logpb.LogsData{} },
+			(*logpb.LogsData).GetResourceLogs, sinks.Logs)},
+		{config.MetricsTopic, newTopic("metrics", func() *metricpb.MetricsData { return &metricpb.MetricsData{} },
+			(*metricpb.MetricsData).GetResourceMetrics, sinks.Metrics)},
+		{config.TracesTopic, newTopic("traces", func() *tracepb.TracesData { return &tracepb.TracesData{} },
+			(*tracepb.TracesData).GetResourceSpans, sinks.Traces)},
+	} {
+		if t.name == "" {
+			continue
+		}
+		if _, ok := topics[t.name]; ok {
+			return nil, fmt.Errorf("kafka topic %s is set for several signals", t.name)
+		}
+		topics[t.name] = t.topic
+	}
+	if len(topics) == 0 {
+		return nil, errors.New("no kafka topic to consume")
+	}
+
+	tlsConfig, err := newTLSConfig(&config.TLS)
+	if err != nil {
+		return nil, err
+	}
+	sasl, err := saslOptions(&config.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		config:     config,
+		topics:     topics,
+		clock:      clock.New(),
+		messages:   noop.Int64Counter{},
+		suppressed: noop.Int64Counter{},
+	}
+
+	// Offsets are committed once the records are forwarded, and the rebalances of the group wait for the records
+	// being handled, so a proxy never commits the offsets of partitions assigned to another one
+	offset := kgo.NewOffset().AtEnd()
+	if config.InitialOffset == configOffsetEarliest() {
+		offset = kgo.NewOffset().AtStart()
+	}
+	s.opts = append([]kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ClientID(config.ClientID),
+		kgo.ConsumerGroup(config.GroupID),
+		kgo.ConsumeTopics(slices.Sorted(maps.Keys(topics))...),
+		kgo.Balancers(kgo.RangeBalancer()),
+		kgo.ConsumeResetOffset(offset),
+		kgo.SessionTimeout(config.SessionTimeout),
+		kgo.RebalanceTimeout(config.RebalanceTimeout),
+		kgo.HeartbeatInterval(config.HeartbeatInterval),
+		kgo.FetchMaxWait(config.MaxWait),
+		kgo.FetchMaxPartitionBytes(config.MaxPartitionBytes),
+		kgo.DisableAutoCommit(),
+		kgo.BlockRebalanceOnPoll(),
+		kgo.OnPartitionsCallbackBlocked(func(context.Context, *kgo.Client) { s.endGeneration() }),
+	}, sasl...)
+	if tlsConfig != nil {
+		s.opts = append(s.opts, kgo.DialTLSConfig(tlsConfig))
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	// Share a retry budget between the topics of a signal, as they are forwarded to the same backend
+	budgets := make(map[string]*retryBudget)
+	for name, t := range s.topics {
+		budget, ok := budgets[t.signal]
+		if !ok {
+			budget = newRetryBudget(config.RetryBudget.Threshold, config.RetryBudget.MinForwards,
+				config.RetryBudget.Window, s.clock)
+			budgets[t.signal] = budget
+		}
+		t.budget = budget
+		s.topics[name] = t
+	}
+	return s, nil
+}
+
+// configOffsetLatest returns the initial offset starting from the end of the partitions.
+func configOffsetLatest() string { return config.KafkaOffsetLatest }
+
+// configOffsetEarliest returns the initial offset starting from the beginning of the partitions.
+func configOffsetEarliest() string { return config.KafkaOffsetEarliest }
+
+// newTLSConfig creates the TLS configuration of the connections to the brokers, nil when TLS is disabled.
+func newTLSConfig(cfg *config.KafkaTLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in kafka CA file %s", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Run consumes the topics until the context is cancelled, leaving the group when it returns.
+func (s *Source) Run(ctx context.Context) error {
+	client, err := kgo.NewClient(s.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create kafka consumer: %w", err)
+	}
+	defer client.CloseAllowingRebalance()
+
+	for {
+		gen := s.newGeneration(ctx)
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return nil
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			logger.Warn(ctx, "failed to fetch kafka partition",
+				attribute.String(topicAttrKey, topic),
+				attribute.Int(partitionAttrKey, int(partition)),
+				attribute.String(errAttrKey, err.Error()),
+			)
+		})
+
+		// Stop at the first record left unhandled, so it is consumed again from the committed offset
+		var handled []*kgo.Record
+		for _, rec := range fetches.Records() {
+			if !s.handle(ctx, gen, rec) {
+				break
+			}
+			handled = append(handled, rec)
+		}
+		if len(handled) > 0 {
+			commitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+			if err := client.CommitRecords(commitCtx, handled...); err != nil {
+				logger.Warn(ctx, "failed to commit kafka offsets", attribute.String(errAttrKey, err.Error()))
+			}
+			cancel()
+		}
+		client.AllowRebalance()
+	}
+}
+
+// newGeneration returns the context of the records of the next poll, ended when a rebalance of the group waits for
+// them to be handled.
+func (s *Source) newGeneration(ctx context.Context) context.Context {
+	gen, cancel := context.WithCancel(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.release != nil {
+		s.release()
+	}
+	s.release = cancel
+	return gen
+}
+
+// endGeneration ends the generation of the records being handled, so the group can rebalance.
+func (s *Source) endGeneration() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.release != nil {
+		s.release()
+	}
+}
+
+// handle forwards the payload of a record, retrying failures with a backoff. It reports false when the generation
+// ends before the record is handled, leaving it to be consumed again.
+func (s *Source) handle(ctx, gen context.Context, rec *kgo.Record) bool {
+	t := s.topics[rec.Topic]
+	attrs := []attribute.KeyValue{
+		attribute.String(topicAttrKey, rec.Topic),
+		attribute.String(signalTypeAttrKey, t.signal),
+	}
+	logAttrs := []attribute.KeyValue{
+		attribute.Int(partitionAttrKey, int(rec.Partition)),
+		attribute.Int64(offsetAttrKey, rec.Offset),
+	}
+
+	json := s.config.Encoding == config.EncodingJSON
+	for _, h := range rec.Headers {
+		if h.Key == contentTypeHeader {
+			json = otlpproto.IsJSON(string(h.Value))
+		}
+	}
+
+	forward, err := t.decode(rec.Value, json)
+	if err != nil {
+		logger.Warn(gen, "dropping invalid kafka message",
+			slices.Concat(attrs, logAttrs, []attribute.KeyValue{attribute.String(errAttrKey, err.Error())})...,
+		)
+		s.messages.Add(gen, 1, metric.WithAttributes(append(attrs, attribute.String(outcomeAttrKey, outcomeInvalid))...))
+		return true
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := forward(ctx)
+		if err == nil {
+			t.budget.record(false)
+			s.messages.Add(gen, 1, metric.WithAttributes(append(attrs, attribute.String(outcomeAttrKey, outcomeForwarded))...))
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		t.budget.record(true)
+
+		// Do not add retries to the load of a backend failing most of its recent forwards
+		if attempt < s.config.MaxRetries && t.budget.exhausted() {
+			s.suppressed.Add(gen, 1, metric.WithAttributes(attrs...))
+			logger.Error(gen, "dropping kafka message, retries are suppressed by the retry budget",
+				slices.Concat(attrs, logAttrs, []attribute.KeyValue{attribute.String(errAttrKey, err.Error())})...,
+			)
+			s.messages.Add(gen, 1, metric.WithAttributes(append(attrs, attribute.String(outcomeAttrKey, outcomeFailed))...))
+			return true
+		}
+		if attempt >= s.config.MaxRetries {
+			logger.Error(gen, "dropping kafka message after failing to forward it",
+				slices.Concat(attrs, logAttrs, []attribute.KeyValue{attribute.String(errAttrKey, err.Error())})...,
+			)
+			s.messages.Add(gen, 1, metric.WithAttributes(append(attrs, attribute.String(outcomeAttrKey, outcomeFailed))...))
+			return true
+		}
+		if !sleep(gen, s.config.RetryBackoff) {
+			return false
+		}
+	}
+}
+
+// sleep waits for the duration, reporting false when the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
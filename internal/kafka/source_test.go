@@ -0,0 +1,344 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func testResource(tenant string) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}}},
+	}}
+}
+
+func testConfig(c *kfake.Cluster) *config.Kafka {
+	return &config.Kafka{
+		Brokers:           c.ListenAddrs(),
+		ClientID:          "test",
+		GroupID:           "test",
+		LogsTopic:         "otlp_logs",
+		MetricsTopic:      "otlp_metrics",
+		TracesTopic:       "otlp_spans",
+		Encoding:          config.EncodingProtobuf,
+		InitialOffset:     config.KafkaOffsetEarliest,
+		SessionTimeout:    10 * time.Second,
+		RebalanceTimeout:  time.Second,
+		HeartbeatInterval: 100 * time.Millisecond,
+		MaxWait:           10 * time.Millisecond,
+		MaxPartitionBytes: 1 << 20,
+		MaxRetries:        3,
+		RetryBackoff:      time.Millisecond,
+	}
+}
+
+// produce writes the records to the partition of the topic.
+func produce(t *testing.T, c *kfake.Cluster, topic string, partition int32, records ...*kgo.Record) {
+	t.Helper()
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(c.ListenAddrs()...), kgo.RecordPartitioner(kgo.ManualPartitioner()))
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, rec := range records {
+		rec.Topic, rec.Partition = topic, partition
+	}
+	require.NoError(t, client.ProduceSync(t.Context(), records...).FirstErr())
+}
+
+// groupState returns the offsets committed by the consumer group by topic and partition, and the state of the group.
+func groupState(t *testing.T, c *kfake.Cluster) (map[string]map[int32]int64, string) {
+	t.Helper()
+
+	client, err := kgo.NewClient(kgo.SeedBrokers(c.ListenAddrs()...))
+	require.NoError(t, err)
+	defer client.Close()
+	adm := kadm.NewClient(client)
+
+	// The group does not exist until the source joins it
+	committed := make(map[string]map[int32]int64)
+	offsets, err := adm.FetchOffsets(t.Context(), "test")
+	if err != nil {
+		return committed, ""
+	}
+	offsets.Each(func(o kadm.OffsetResponse) {
+		if committed[o.Topic] == nil {
+			committed[o.Topic] = make(map[int32]int64)
+		}
+		committed[o.Topic][o.Partition] = o.At
+	})
+
+	groups, err := adm.DescribeGroups(t.Context(), "test")
+	if err != nil {
+		return committed, ""
+	}
+	return committed, groups["test"].State
+}
+
+// tenants records the tenants of the resources received by the sinks.
+type tenants struct {
+	mu      sync.Mutex
+	tenants map[string][]string
+	fail    int
+}
+
+func (r *tenants) add(signal string, resources []*resourcepb.Resource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fail > 0 {
+		r.fail--
+		return errors.New("backend unavailable")
+	}
+	for _, resource := range resources {
+		r.tenants[signal] = append(r.tenants[signal], resource.GetAttributes()[0].GetValue().GetStringValue())
+	}
+	return nil
+}
+
+func (r *tenants) get() map[string][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.tenants
+}
+
+func (r *tenants) sinks() Sinks {
+	return Sinks{
+		Logs: func(_ context.Context, resources []*logpb.ResourceLogs) error {
+			var rs []*resourcepb.Resource
+			for _, r := range resources {
+				rs = append(rs, r.GetResource())
+			}
+			return r.add("logs", rs)
+		},
+		Metrics: func(_ context.Context, resources []*metricpb.ResourceMetrics) error {
+			var rs []*resourcepb.Resource
+			for _, r := range resources {
+				rs = append(rs, r.GetResource())
+			}
+			return r.add("metrics", rs)
+		},
+		Traces: func(_ context.Context, resources []*tracepb.ResourceSpans) error {
+			var rs []*resourcepb.Resource
+			for _, r := range resources {
+				rs = append(rs, r.GetResource())
+			}
+			return r.add("traces", rs)
+		},
+	}
+}
+
+// run runs the source until the test ends, failing the test when it does not stop.
+func run(t *testing.T, s *Source) context.CancelFunc {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	stop := sync.OnceFunc(func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Error("kafka source did not stop")
+		}
+	})
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestSource_Run(t *testing.T) {
+	c := newCluster(t, map[string]int32{"otlp_logs": 2, "otlp_metrics": 1, "otlp_spans": 1})
+
+	logs, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-a")}}})
+	require.NoError(t, err)
+	metrics, err := protojson.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{{Resource: testResource("tenant-b")}}})
+	require.NoError(t, err)
+	traces, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{Resource: testResource("tenant-c")}}})
+	require.NoError(t, err)
+
+	produce(t, c, "otlp_logs", 0, &kgo.Record{Value: logs})
+	produce(t, c, "otlp_logs", 1, &kgo.Record{Value: logs})
+	produce(t, c, "otlp_metrics", 0, &kgo.Record{Value: metrics, Headers: []kgo.RecordHeader{{Key: "content-type", Value: []byte("application/json")}}})
+	produce(t, c, "otlp_spans", 0, &kgo.Record{Value: []byte("invalid")}, &kgo.Record{Value: traces})
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	// The first payload fails to be forwarded and is retried
+	received := &tenants{tenants: make(map[string][]string), fail: 1}
+	s, err := New(testConfig(c), received.sinks(), WithMeter(meter))
+	require.NoError(t, err)
+	stop := run(t, s)
+
+	want := map[string]map[int32]int64{"otlp_logs": {0: 1, 1: 1}, "otlp_metrics": {0: 1}, "otlp_spans": {0: 2}}
+	assert.Eventually(t, func() bool {
+		committed, _ := groupState(t, c)
+		return assert.ObjectsAreEqual(want, committed)
+	}, 10*time.Second, 10*time.Millisecond)
+	stop()
+
+	assert.Equal(t, map[string][]string{
+		"logs":    {"tenant-a", "tenant-a"},
+		"metrics": {"tenant-b"},
+		"traces":  {"tenant-c"},
+	}, received.get())
+
+	// The group is left when the source stops
+	_, state := groupState(t, c)
+	assert.Equal(t, "Empty", state)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	sum, ok := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	counts := make(map[string]int64)
+	for _, dp := range sum.DataPoints {
+		topic, _ := dp.Attributes.Value(attribute.Key(topicAttrKey))
+		outcome, _ := dp.Attributes.Value(attribute.Key(outcomeAttrKey))
+		counts[topic.AsString()+"/"+outcome.AsString()] = dp.Value
+	}
+	assert.Equal(t, map[string]int64{
+		"otlp_logs/forwarded":    2,
+		"otlp_metrics/forwarded": 1,
+		"otlp_spans/invalid":     1,
+		"otlp_spans/forwarded":   1,
+	}, counts)
+}
+
+func TestSource_Run_InitialOffsetLatest(t *testing.T) {
+	c := newCluster(t, map[string]int32{"otlp_logs": 1})
+
+	old, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-old")}}})
+	require.NoError(t, err)
+	produce(t, c, "otlp_logs", 0, &kgo.Record{Value: old})
+
+	// Fetches are only sent once the initial offsets of the partitions are resolved
+	var fetched atomic.Bool
+	c.ControlKey(int16(kmsg.Fetch), func(kmsg.Request) (kmsg.Response, error, bool) {
+		c.KeepControl()
+		fetched.Store(true)
+		return nil, nil, false
+	})
+
+	cfg := testConfig(c)
+	cfg.MetricsTopic, cfg.TracesTopic = "", ""
+	cfg.InitialOffset = config.KafkaOffsetLatest
+
+	received := &tenants{tenants: make(map[string][]string)}
+	s, err := New(cfg, received.sinks())
+	require.NoError(t, err)
+	run(t, s)
+
+	// Records produced before the group started consuming are not consumed
+	require.Eventually(t, fetched.Load, 10*time.Second, 10*time.Millisecond)
+	logs, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{Resource: testResource("tenant-new")}}})
+	require.NoError(t, err)
+	produce(t, c, "otlp_logs", 0, &kgo.Record{Value: logs})
+
+	assert.Eventually(t, func() bool {
+		committed, _ := groupState(t, c)
+		return committed["otlp_logs"][0] == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string][]string{"logs": {"tenant-new"}}, received.get())
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.Kafka)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(*config.Kafka) {},
+		},
+		{
+			name:    "invalid encoding",
+			modify:  func(cfg *config.Kafka) { cfg.Encoding = "avro" },
+			wantErr: "unsupported encoding",
+		},
+		{
+			name:    "invalid initial offset",
+			modify:  func(cfg *config.Kafka) { cfg.InitialOffset = "oldest" },
+			wantErr: "invalid kafka initial offset",
+		},
+		{
+			name:    "topic shared by signals",
+			modify:  func(cfg *config.Kafka) { cfg.MetricsTopic = cfg.LogsTopic },
+			wantErr: "set for several signals",
+		},
+		{
+			name:    "no topic",
+			modify:  func(cfg *config.Kafka) { cfg.LogsTopic, cfg.MetricsTopic, cfg.TracesTopic = "", "", "" },
+			wantErr: "no kafka topic",
+		},
+		{
+			name: "missing ca file",
+			modify: func(cfg *config.Kafka) {
+				cfg.TLS = config.KafkaTLS{Enabled: true, CAFile: "/nonexistent/ca.pem"}
+			},
+			wantErr: "no such file",
+		},
+		{
+			name: "invalid SASL mechanism",
+			modify: func(cfg *config.Kafka) {
+				cfg.SASL = config.KafkaSASL{Mechanism: "GSSAPI"}
+			},
+			wantErr: "unsupported kafka SASL mechanism",
+		},
+		{
+			name: "invalid retry budget threshold",
+			modify: func(cfg *config.Kafka) {
+				cfg.RetryBudget.Threshold = 1.5
+			},
+			wantErr: "invalid kafka retry budget threshold",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Kafka{
+				Brokers:       []string{"localhost:9092"},
+				LogsTopic:     "otlp_logs",
+				MetricsTopic:  "otlp_metrics",
+				TracesTopic:   "otlp_spans",
+				Encoding:      config.EncodingProtobuf,
+				InitialOffset: config.KafkaOffsetLatest,
+			}
+			tt.modify(cfg)
+
+			_, err := New(cfg, Sinks{})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
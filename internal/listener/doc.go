@@ -0,0 +1,9 @@
+// Package listener runs the HTTP servers of the proxy, one per configured listen address.
+//
+// The proxy can listen on several addresses at once, for example with mTLS on
+// a public address for external senders and in plaintext on localhost for
+// sidecars. Each address is served by its own http.Server sharing the router
+// of the proxy, and the Manager starts them together and shuts them down
+// together, so a request in flight on any of them is completed before the
+// proxy exits.
+package listener
@@ -0,0 +1,95 @@
+// Package listener runs the HTTP servers of the proxy, one per configured listen address.
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/matt-gp/core/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	httpAddressAttrKey    = "http.address"
+	httpTLSEnabledAttrKey = "http.tls.enabled"
+)
+
+// server is an HTTP server serving a listener.
+type server struct {
+	server   *http.Server
+	listener net.Listener
+	tls      bool
+}
+
+// attributes returns the attributes logged with the events of the server.
+func (s *server) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(httpAddressAttrKey, s.listener.Addr().String()),
+		attribute.Bool(httpTLSEnabledAttrKey, s.tls),
+	}
+}
+
+// Manager starts and stops a set of HTTP servers together.
+type Manager struct {
+	servers []*server
+	errs    chan error
+	wg      sync.WaitGroup
+}
+
+// New creates a new Manager without any server.
+func New() *Manager {
+	return &Manager{}
+}
+
+// Add adds a server serving the listener, with the TLS configuration of the server when tls is set. Servers must be
+// added before the manager is started.
+func (m *Manager) Add(s *http.Server, listener net.Listener, tls bool) {
+	m.servers = append(m.servers, &server{server: s, listener: listener, tls: tls})
+}
+
+// Start starts serving every server, returning a channel receiving the error of each server that stops serving before
+// it is shut down. The channel is closed once every server stopped.
+func (m *Manager) Start(ctx context.Context) <-chan error {
+	m.errs = make(chan error, len(m.servers))
+	for _, s := range m.servers {
+		m.wg.Go(func() {
+			attrs := s.attributes()
+			logger.Info(ctx, "starting server", attrs...)
+
+			var err error
+			if s.tls {
+				err = s.server.ServeTLS(s.listener, "", "")
+			} else {
+				err = s.server.Serve(s.listener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				m.errs <- fmt.Errorf("server listening on %s failed: %w", s.listener.Addr(), err)
+			}
+		})
+	}
+	go func() {
+		m.wg.Wait()
+		close(m.errs)
+	}()
+	return m.errs
+}
+
+// Shutdown gracefully shuts down every server concurrently, returning the errors of the servers that could not be
+// shut down before the context is done.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(m.servers))
+	var wg sync.WaitGroup
+	for i, s := range m.servers {
+		wg.Go(func() {
+			if err := s.server.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("failed to shut down server listening on %s: %w", s.listener.Addr(), err)
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
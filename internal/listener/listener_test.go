@@ -0,0 +1,80 @@
+package listener
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	})
+
+	// A TLS server for external senders and a plaintext one for local sidecars
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.StartTLS()
+	tlsConfig := tlsServer.TLS
+	client := tlsServer.Client()
+	tlsServer.Close()
+
+	tlsListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	plainListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	m := New()
+	m.Add(&http.Server{Handler: handler, TLSConfig: tlsConfig}, tlsListener, true)
+	m.Add(&http.Server{Handler: handler}, plainListener, false)
+	errs := m.Start(t.Context())
+
+	for _, url := range []string{"https://" + tlsListener.Addr().String(), "http://" + plainListener.Addr().String()} {
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, "ok", string(body))
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Shutdown(ctx))
+
+	// Shut down servers do not report an error
+	select {
+	case err, ok := <-errs:
+		assert.False(t, ok, "unexpected error %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not stop")
+	}
+
+	for _, l := range []net.Listener{tlsListener, plainListener} {
+		_, err := net.Dial("tcp", l.Addr().String())
+		assert.Error(t, err)
+	}
+}
+
+func TestManager_ServeError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	m := New()
+	m.Add(&http.Server{Handler: http.NotFoundHandler()}, listener, false)
+	errs := m.Start(t.Context())
+
+	select {
+	case err := <-errs:
+		assert.ErrorContains(t, err, listener.Addr().String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve error not reported")
+	}
+}
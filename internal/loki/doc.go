@@ -0,0 +1,19 @@
+// Package loki converts OTLP logs to and from the Loki push API format.
+//
+// Logs are grouped into streams by the values of a configured list of resource
+// attributes, which become the stream labels. Everything else the OTLP
+// records carry is kept as structured metadata of each entry: the other
+// resource attributes, the instrumentation scope, the log attributes, the
+// severity and the trace context. Attribute names are sanitized into valid
+// Loki label names, e.g. service.name becomes service_name.
+//
+// The push request is encoded as JSON, accepted by the /loki/api/v1/push
+// endpoint of every Loki version supporting structured metadata.
+//
+// Push requests received from Loki clients such as Promtail, as JSON or as
+// snappy compressed protobuf, are converted the other way: each stream becomes
+// a resource whose attributes are its labels, and each entry a log record with
+// the line as its body and the structured metadata as attributes. The tenant
+// of a stream is read from a configured label, falling back to the tenant of
+// the request, and written to the tenant resource attribute.
+package loki
@@ -0,0 +1,105 @@
+// Package loki converts OTLP logs to and from the Loki push API format.
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// ProtoContentType is the content type of push requests encoded as snappy compressed protobuf, like Promtail sends.
+const ProtoContentType = "application/x-protobuf"
+
+// UnmarshalJSON decodes the [timestamp, line] or [timestamp, line, metadata] array of the push API, the timestamp
+// being a string of Unix nanoseconds.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var values []json.RawMessage
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	if len(values) < 2 || len(values) > 3 {
+		return fmt.Errorf("invalid entry %s: expected [timestamp, line] or [timestamp, line, metadata]", b)
+	}
+
+	var timestamp string
+	if err := json.Unmarshal(values[0], &timestamp); err != nil {
+		return fmt.Errorf("invalid entry timestamp %s: %w", values[0], err)
+	}
+	ns, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid entry timestamp %q: %w", timestamp, errors.Unwrap(err))
+	}
+	e.Timestamp = ns
+
+	if err := json.Unmarshal(values[1], &e.Line); err != nil {
+		return fmt.Errorf("invalid entry line %s: %w", values[1], err)
+	}
+
+	if len(values) == 3 {
+		if err := json.Unmarshal(values[2], &e.Metadata); err != nil {
+			return fmt.Errorf("invalid entry metadata %s: %w", values[2], err)
+		}
+	}
+	return nil
+}
+
+// Parse decodes the body of a push request: snappy compressed protobuf for the protobuf content type, JSON otherwise.
+func Parse(b []byte, contentType string) (*PushRequest, error) {
+	if contentType == ProtoContentType {
+		// Every byte of a block decodes to at most 64 bytes, bounding the allocation of corrupt lengths
+		if n, err := snappy.DecodedLen(b); err != nil || n > len(b)*64 {
+			return nil, fmt.Errorf("invalid push request: %w", snappy.ErrCorrupt)
+		}
+		decoded, err := snappy.Decode(nil, b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid push request: %w", err)
+		}
+		return unmarshalPushProto(decoded)
+	}
+
+	request := &PushRequest{}
+	if err := json.Unmarshal(b, request); err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+	return request, nil
+}
+
+// ParseLabels parses the labels of a stream in the Prometheus format of the protobuf push request, such as
+// {job="varlogs", host="a"}.
+func ParseLabels(s string) (map[string]string, error) {
+	rest := strings.TrimSpace(s)
+	if !strings.HasPrefix(rest, "{") || !strings.HasSuffix(rest, "}") {
+		return nil, fmt.Errorf("invalid stream labels %q: expected {name=\"value\", ...}", s)
+	}
+	rest = strings.TrimSpace(rest[1 : len(rest)-1])
+
+	labels := make(map[string]string)
+	for rest != "" {
+		name, value, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid stream labels %q: missing label name", s)
+		}
+
+		value = strings.TrimSpace(value)
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil || !strings.HasPrefix(quoted, `"`) {
+			return nil, fmt.Errorf("invalid stream labels %q: unquoted value of label %s", s, name)
+		}
+		labels[name], _ = strconv.Unquote(quoted)
+
+		rest = strings.TrimSpace(value[len(quoted):])
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(rest, ",") {
+			return nil, fmt.Errorf("invalid stream labels %q: expected a comma after label %s", s, name)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+	return labels, nil
+}
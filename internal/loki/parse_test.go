@@ -0,0 +1,158 @@
+package loki
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// pushProto encodes a logproto PushRequest with a stream of the labels holding an entry, snappy compressed.
+func pushProto(labels string, seconds, nanos int64, line string, metadata [][2]string) []byte {
+	var timestamp []byte
+	timestamp = protowire.AppendTag(timestamp, timestampSecondsField, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, uint64(seconds))
+	timestamp = protowire.AppendTag(timestamp, timestampNanosField, protowire.VarintType)
+	timestamp = protowire.AppendVarint(timestamp, uint64(nanos))
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, entryTimestampField, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, timestamp)
+	entry = protowire.AppendTag(entry, entryLineField, protowire.BytesType)
+	entry = protowire.AppendString(entry, line)
+	for _, pair := range metadata {
+		var label []byte
+		label = protowire.AppendTag(label, labelNameField, protowire.BytesType)
+		label = protowire.AppendString(label, pair[0])
+		label = protowire.AppendTag(label, labelValueField, protowire.BytesType)
+		label = protowire.AppendString(label, pair[1])
+		entry = protowire.AppendTag(entry, entryMetadataField, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, label)
+	}
+
+	var stream []byte
+	stream = protowire.AppendTag(stream, streamLabelsField, protowire.BytesType)
+	stream = protowire.AppendString(stream, labels)
+	stream = protowire.AppendTag(stream, streamEntriesField, protowire.BytesType)
+	stream = protowire.AppendBytes(stream, entry)
+	// The hash of the labels is skipped
+	stream = protowire.AppendTag(stream, 3, protowire.VarintType)
+	stream = protowire.AppendVarint(stream, 12345)
+
+	var request []byte
+	request = protowire.AppendTag(request, pushStreamsField, protowire.BytesType)
+	request = protowire.AppendBytes(request, stream)
+	return snappy.Encode(nil, request)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        []*Stream
+		wantErr     string
+	}{
+		{
+			name: "json",
+			body: []byte(`{"streams":[{"stream":{"job":"varlogs"},"values":[` +
+				`["1700000000000000001","hello"],["1700000000000000002","world",{"trace_id":"abc"}]]}]}`),
+			contentType: "application/json",
+			want: []*Stream{{
+				Labels: map[string]string{"job": "varlogs"},
+				Entries: []Entry{
+					{Timestamp: 1700000000000000001, Line: "hello"},
+					{Timestamp: 1700000000000000002, Line: "world", Metadata: map[string]string{"trace_id": "abc"}},
+				},
+			}},
+		},
+		{
+			name: "protobuf",
+			body: pushProto(`{job="varlogs", host="a\"b"}`, 1700000000, 5, "hello",
+				[][2]string{{"trace_id", "abc"}}),
+			contentType: ProtoContentType,
+			want: []*Stream{{
+				Labels: map[string]string{"job": "varlogs", "host": `a"b`},
+				Entries: []Entry{
+					{Timestamp: 1700000000000000005, Line: "hello", Metadata: map[string]string{"trace_id": "abc"}},
+				},
+			}},
+		},
+		{
+			name:        "invalid json",
+			body:        []byte(`{"streams":`),
+			contentType: "application/json",
+			wantErr:     "invalid push request",
+		},
+		{
+			name:        "invalid timestamp",
+			body:        []byte(`{"streams":[{"stream":{"job":"varlogs"},"values":[["yesterday","hello"]]}]}`),
+			contentType: "application/json",
+			wantErr:     `invalid entry timestamp "yesterday"`,
+		},
+		{
+			name:        "entry without line",
+			body:        []byte(`{"streams":[{"stream":{"job":"varlogs"},"values":[["1"]]}]}`),
+			contentType: "application/json",
+			wantErr:     "expected [timestamp, line] or [timestamp, line, metadata]",
+		},
+		{
+			name:        "protobuf not snappy compressed",
+			body:        []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			contentType: ProtoContentType,
+			wantErr:     "invalid push request",
+		},
+		{
+			name:        "protobuf with invalid labels",
+			body:        pushProto(`job="varlogs"`, 1, 0, "hello", nil),
+			contentType: ProtoContentType,
+			wantErr:     "invalid stream labels",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.body, tt.contentType)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Streams)
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		labels  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{labels: `{}`, want: map[string]string{}},
+		{labels: `{job="varlogs"}`, want: map[string]string{"job": "varlogs"}},
+		{labels: ` { job = "varlogs" , host="a,b=c" } `, want: map[string]string{"job": "varlogs", "host": "a,b=c"}},
+		{labels: `{path="C:\\logs\\app.log"}`, want: map[string]string{"path": `C:\logs\app.log`}},
+		{labels: `job="varlogs"`, wantErr: true},
+		{labels: `{job=varlogs}`, wantErr: true},
+		{labels: `{job='varlogs'}`, wantErr: true},
+		{labels: `{="varlogs"}`, wantErr: true},
+		{labels: `{job="varlogs" host="a"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.labels, func(t *testing.T) {
+			got, err := ParseLabels(tt.labels)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package loki converts OTLP logs to and from the Loki push API format.
+package loki
+
+import (
+	"fmt"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the logproto PushRequest messages of Loki.
+const (
+	pushStreamsField = 1
+
+	streamLabelsField  = 1
+	streamEntriesField = 2
+
+	entryTimestampField = 1
+	entryLineField      = 2
+	entryMetadataField  = 3
+
+	timestampSecondsField = 1
+	timestampNanosField   = 2
+
+	labelNameField  = 1
+	labelValueField = 2
+)
+
+// unmarshalPushProto decodes a logproto PushRequest message, the fields the conversion does not use, such as the
+// hash of the stream labels, are skipped.
+func unmarshalPushProto(b []byte) (*PushRequest, error) {
+	request := &PushRequest{}
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != pushStreamsField || typ != protowire.BytesType {
+			return nil
+		}
+		stream, err := unmarshalStream(v)
+		if err != nil {
+			return err
+		}
+		request.Streams = append(request.Streams, stream)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid push request: %w", err)
+	}
+	return request, nil
+}
+
+// unmarshalStream decodes a StreamAdapter message.
+func unmarshalStream(b []byte) (*Stream, error) {
+	stream := &Stream{}
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == streamLabelsField && typ == protowire.BytesType:
+			labels, err := ParseLabels(string(v))
+			if err != nil {
+				return err
+			}
+			stream.Labels = labels
+		case num == streamEntriesField && typ == protowire.BytesType:
+			entry, err := unmarshalEntry(v)
+			if err != nil {
+				return err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		return nil
+	})
+	return stream, err
+}
+
+// unmarshalEntry decodes an EntryAdapter message, with its timestamp and structured metadata.
+func unmarshalEntry(b []byte) (Entry, error) {
+	var entry Entry
+	err := proto.Walk(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == entryTimestampField && typ == protowire.BytesType:
+			var seconds, nanos int64
+			if err := proto.Walk(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+				switch {
+				case num == timestampSecondsField && typ == protowire.VarintType:
+					seconds = int64(n)
+				case num == timestampNanosField && typ == protowire.VarintType:
+					nanos = int64(int32(n))
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			entry.Timestamp = seconds*1e9 + nanos
+		case num == entryLineField && typ == protowire.BytesType:
+			entry.Line = string(v)
+		case num == entryMetadataField && typ == protowire.BytesType:
+			var name, value string
+			if err := proto.Walk(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				switch {
+				case num == labelNameField && typ == protowire.BytesType:
+					name = string(v)
+				case num == labelValueField && typ == protowire.BytesType:
+					value = string(v)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if entry.Metadata == nil {
+				entry.Metadata = make(map[string]string)
+			}
+			entry.Metadata[name] = value
+		}
+		return nil
+	})
+	return entry, err
+}
@@ -0,0 +1,228 @@
+// Package loki converts OTLP logs to and from the Loki push API format.
+package loki
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// ContentType is the content type of the encoded push requests.
+const ContentType = "application/json"
+
+// unknownService is the service name label of streams without any label attribute, as Loki requires at least one
+// label, matching the service name Loki assigns to OTLP logs without one.
+const unknownService = "unknown_service"
+
+// PushRequest is a request to the Loki push API.
+type PushRequest struct {
+	Streams []*Stream `json:"streams"`
+}
+
+// Stream is a Loki stream, the entries sharing a set of labels.
+type Stream struct {
+	Labels  map[string]string `json:"stream"`
+	Entries []Entry           `json:"values"`
+}
+
+// Entry is a log line of a stream with its structured metadata.
+type Entry struct {
+	Timestamp int64
+	Line      string
+	Metadata  map[string]string
+}
+
+// MarshalJSON encodes the entry as the [timestamp, line, metadata] array of the push API, the timestamp being a string
+// of Unix nanoseconds.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	values := []any{strconv.FormatInt(e.Timestamp, 10), e.Line}
+	if len(e.Metadata) > 0 {
+		values = append(values, e.Metadata)
+	}
+	return json.Marshal(values)
+}
+
+// Marshal converts the resource logs into a push request encoded as JSON.
+func Marshal(resources []*logpb.ResourceLogs, labels []string, now time.Time) ([]byte, error) {
+	return json.Marshal(Convert(resources, labels, now))
+}
+
+// Convert converts the resource logs into a push request, grouping the records into streams by the values of the
+// label resource attributes. Records without a timestamp are stamped with the observed time, or now.
+func Convert(resources []*logpb.ResourceLogs, labels []string, now time.Time) *PushRequest {
+	request := &PushRequest{}
+	streams := make(map[string]*Stream)
+
+	for _, resourceLogs := range resources {
+		streamLabels := make(map[string]string)
+		resourceMetadata := make(map[string]string)
+		for _, attr := range resourceLogs.GetResource().GetAttributes() {
+			if slices.Contains(labels, attr.GetKey()) {
+				streamLabels[Sanitize(attr.GetKey())] = valueString(attr.GetValue())
+				continue
+			}
+			resourceMetadata[Sanitize(attr.GetKey())] = valueString(attr.GetValue())
+		}
+		if len(streamLabels) == 0 {
+			streamLabels["service_name"] = unknownService
+		}
+
+		key := streamKey(streamLabels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &Stream{Labels: streamLabels}
+			streams[key] = stream
+			request.Streams = append(request.Streams, stream)
+		}
+
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				stream.Entries = append(stream.Entries, entry(record, scopeLogs.GetScope(), resourceMetadata, now))
+			}
+		}
+	}
+
+	return request
+}
+
+// entry converts a log record into a stream entry, keeping the resource attributes that are not labels, the scope,
+// the log attributes, the severity and the trace context as structured metadata.
+func entry(
+	record *logpb.LogRecord,
+	scope *commonpb.InstrumentationScope,
+	resourceMetadata map[string]string,
+	now time.Time,
+) Entry {
+	metadata := make(map[string]string, len(resourceMetadata)+len(record.GetAttributes())+6)
+	for key, value := range resourceMetadata {
+		metadata[key] = value
+	}
+	if scope.GetName() != "" {
+		metadata["scope_name"] = scope.GetName()
+	}
+	if scope.GetVersion() != "" {
+		metadata["scope_version"] = scope.GetVersion()
+	}
+	for _, attr := range record.GetAttributes() {
+		metadata[Sanitize(attr.GetKey())] = valueString(attr.GetValue())
+	}
+	if record.GetSeverityText() != "" {
+		metadata["severity_text"] = record.GetSeverityText()
+	}
+	if record.GetSeverityNumber() != logpb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED {
+		metadata["severity_number"] = strconv.Itoa(int(record.GetSeverityNumber()))
+	}
+	if len(record.GetTraceId()) > 0 {
+		metadata["trace_id"] = hex.EncodeToString(record.GetTraceId())
+	}
+	if len(record.GetSpanId()) > 0 {
+		metadata["span_id"] = hex.EncodeToString(record.GetSpanId())
+	}
+
+	timestamp := int64(record.GetTimeUnixNano())
+	if timestamp == 0 {
+		timestamp = int64(record.GetObservedTimeUnixNano())
+	}
+	if timestamp == 0 {
+		timestamp = now.UnixNano()
+	}
+
+	return Entry{
+		Timestamp: timestamp,
+		Line:      valueString(record.GetBody()),
+		Metadata:  metadata,
+	}
+}
+
+// Sanitize returns the attribute name as a valid Loki label name, replacing the characters other than letters, digits
+// and underscores with underscores, and prefixing names starting with a digit with an underscore.
+func Sanitize(name string) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// streamKey returns the key identifying the stream of the labels.
+func streamKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+// valueString returns the value as a log line or label value: strings as is, scalars formatted, bytes base64 encoded
+// and arrays and maps encoded as JSON.
+func valueString(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		b, err := json.Marshal(goValue(value))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return ""
+	}
+}
+
+// goValue returns the value as a Go value encodable as JSON.
+func goValue(value *commonpb.AnyValue) any {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return v.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(v.ArrayValue.GetValues()))
+		for _, item := range v.ArrayValue.GetValues() {
+			values = append(values, goValue(item))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = goValue(kv.GetValue())
+		}
+		return values
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func resourceLogs(attrs []*commonpb.KeyValue, records ...*logpb.LogRecord) *logpb.ResourceLogs {
+	return &logpb.ResourceLogs{
+		Resource: &resourcepb.Resource{Attributes: attrs},
+		ScopeLogs: []*logpb.ScopeLogs{{
+			Scope:      &commonpb.InstrumentationScope{Name: "scope", Version: "1.0.0"},
+			LogRecords: records,
+		}},
+	}
+}
+
+func TestConvert(t *testing.T) {
+	now := time.Unix(0, 42)
+	labels := []string{"service.name", "deployment.environment"}
+	body := func(line string) *commonpb.AnyValue {
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: line}}
+	}
+
+	tests := []struct {
+		name      string
+		resources []*logpb.ResourceLogs
+		want      []*Stream
+	}{
+		{
+			name: "labels and structured metadata",
+			resources: []*logpb.ResourceLogs{resourceLogs(
+				[]*commonpb.KeyValue{stringAttr("service.name", "api"), stringAttr("host.name", "node-1")},
+				&logpb.LogRecord{
+					TimeUnixNano:   10,
+					Body:           body("hello"),
+					Attributes:     []*commonpb.KeyValue{stringAttr("http.method", "GET")},
+					SeverityText:   "INFO",
+					SeverityNumber: logpb.SeverityNumber_SEVERITY_NUMBER_INFO,
+					TraceId:        []byte{0x01, 0x02},
+					SpanId:         []byte{0x0a},
+				},
+			)},
+			want: []*Stream{{
+				Labels: map[string]string{"service_name": "api"},
+				Entries: []Entry{{Timestamp: 10, Line: "hello", Metadata: map[string]string{
+					"host_name":       "node-1",
+					"scope_name":      "scope",
+					"scope_version":   "1.0.0",
+					"http_method":     "GET",
+					"severity_text":   "INFO",
+					"severity_number": "9",
+					"trace_id":        "0102",
+					"span_id":         "0a",
+				}}},
+			}},
+		},
+		{
+			name: "resources with the same labels share a stream",
+			resources: []*logpb.ResourceLogs{
+				resourceLogs([]*commonpb.KeyValue{stringAttr("service.name", "api")}, &logpb.LogRecord{TimeUnixNano: 1}),
+				resourceLogs([]*commonpb.KeyValue{stringAttr("service.name", "web")}, &logpb.LogRecord{TimeUnixNano: 2}),
+				resourceLogs([]*commonpb.KeyValue{stringAttr("service.name", "api")}, &logpb.LogRecord{TimeUnixNano: 3}),
+			},
+			want: []*Stream{
+				{
+					Labels: map[string]string{"service_name": "api"},
+					Entries: []Entry{
+						{Timestamp: 1, Metadata: map[string]string{"scope_name": "scope", "scope_version": "1.0.0"}},
+						{Timestamp: 3, Metadata: map[string]string{"scope_name": "scope", "scope_version": "1.0.0"}},
+					},
+				},
+				{
+					Labels: map[string]string{"service_name": "web"},
+					Entries: []Entry{
+						{Timestamp: 2, Metadata: map[string]string{"scope_name": "scope", "scope_version": "1.0.0"}},
+					},
+				},
+			},
+		},
+		{
+			name: "no label attributes",
+			resources: []*logpb.ResourceLogs{
+				resourceLogs(nil, &logpb.LogRecord{ObservedTimeUnixNano: 5}, &logpb.LogRecord{}),
+			},
+			want: []*Stream{{
+				Labels: map[string]string{"service_name": unknownService},
+				Entries: []Entry{
+					{Timestamp: 5, Metadata: map[string]string{"scope_name": "scope", "scope_version": "1.0.0"}},
+					{Timestamp: 42, Metadata: map[string]string{"scope_name": "scope", "scope_version": "1.0.0"}},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Convert(tt.resources, labels, now)
+			assert.Equal(t, tt.want, got.Streams)
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	resources := []*logpb.ResourceLogs{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{stringAttr("service.name", "api")}},
+		ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{
+			{TimeUnixNano: 1700000000000000000, Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}}},
+			{TimeUnixNano: 1700000000000000001, SeverityText: "WARN"},
+		}}},
+	}}
+
+	b, err := Marshal(resources, []string{"service.name"}, time.Now())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"streams":[{
+		"stream":{"service_name":"api"},
+		"values":[
+			["1700000000000000000","hello"],
+			["1700000000000000001","",{"severity_text":"WARN"}]
+		]
+	}]}`, string(b))
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid", in: "service_name", want: "service_name"},
+		{name: "dots", in: "service.name", want: "service_name"},
+		{name: "dashes and slashes", in: "k8s.pod/name-x", want: "k8s_pod_name_x"},
+		{name: "leading digit", in: "1st", want: "_1st"},
+		{name: "unicode", in: "größe", want: "gr__e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Sanitize(tt.in))
+		})
+	}
+}
+
+func TestValueString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *commonpb.AnyValue
+		want  string
+	}{
+		{name: "nil", want: ""},
+		{name: "string", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "s"}}, want: "s"},
+		{name: "bool", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}, want: "true"},
+		{name: "int", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: -3}}, want: "-3"},
+		{name: "double", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}}, want: "1.5"},
+		{name: "bytes", value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte("hi")}}, want: "aGk="},
+		{
+			name: "kvlist",
+			value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+				Values: []*commonpb.KeyValue{
+					stringAttr("a", "b"),
+					{Key: "list", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+						Values: []*commonpb.AnyValue{{Value: &commonpb.AnyValue_IntValue{IntValue: 1}}},
+					}}}},
+				},
+			}}},
+			want: `{"a":"b","list":[1]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, valueString(tt.value))
+		})
+	}
+}
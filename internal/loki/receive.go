@@ -0,0 +1,99 @@
+// Package loki converts OTLP logs to and from the Loki push API format.
+package loki
+
+import (
+	"slices"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+// scopeName is the instrumentation scope of the logs converted from push requests.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/loki"
+
+// Converter converts push requests received from Loki clients into OTLP resources grouped by tenant.
+type Converter struct {
+	tenantLabel       string
+	tenantStreamLabel string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a stream is read
+// from its tenantStreamLabel label when set.
+func New(tenantLabel, tenantStreamLabel string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantStreamLabel: tenantStreamLabel}
+}
+
+// ResourceLogs converts the streams of the push request into OTLP logs, one resource per stream.
+//
+// The stream labels become resource attributes and the entries log records, with the line as the body and the
+// structured metadata as attributes. The tenant of a stream is the value of its tenant label, falling back to the
+// tenant given, such as the one of the tenant header of the request; streams without either are left without a
+// tenant.
+func (c *Converter) ResourceLogs(request *PushRequest, tenant string, now time.Time) []*logpb.ResourceLogs {
+	resources := make([]*logpb.ResourceLogs, 0, len(request.Streams))
+	for _, stream := range request.Streams {
+		if stream == nil || len(stream.Entries) == 0 {
+			continue
+		}
+
+		streamTenant := tenant
+		if value := stream.Labels[c.tenantStreamLabel]; c.tenantStreamLabel != "" && value != "" {
+			streamTenant = value
+		}
+
+		var attributes []*commonpb.KeyValue
+		if streamTenant != "" {
+			attributes = append(attributes, stringKeyValue(c.tenantLabel, streamTenant))
+		}
+		for _, name := range sortedKeys(stream.Labels) {
+			if streamTenant != "" && name == c.tenantLabel {
+				continue
+			}
+			attributes = append(attributes, stringKeyValue(name, stream.Labels[name]))
+		}
+
+		scope := &logpb.ScopeLogs{
+			Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+			LogRecords: make([]*logpb.LogRecord, 0, len(stream.Entries)),
+		}
+		for _, entry := range stream.Entries {
+			scope.LogRecords = append(scope.LogRecords, logRecord(entry, now))
+		}
+
+		resources = append(resources, &logpb.ResourceLogs{
+			Resource:  &resourcepb.Resource{Attributes: attributes},
+			ScopeLogs: []*logpb.ScopeLogs{scope},
+		})
+	}
+	return resources
+}
+
+// logRecord converts a stream entry into an OTLP log record.
+func logRecord(entry Entry, now time.Time) *logpb.LogRecord {
+	record := &logpb.LogRecord{
+		TimeUnixNano:         uint64(entry.Timestamp),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: entry.Line}},
+	}
+	for _, name := range sortedKeys(entry.Metadata) {
+		record.Attributes = append(record.Attributes, stringKeyValue(name, entry.Metadata[name]))
+	}
+	return record
+}
+
+// sortedKeys returns the keys of the labels in order.
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
@@ -0,0 +1,70 @@
+package loki
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestConverter_ResourceLogs(t *testing.T) {
+	now := time.Unix(1700000100, 0)
+	request := &PushRequest{Streams: []*Stream{
+		{
+			Labels:  map[string]string{"job": "varlogs", "tenant": "tenant-b"},
+			Entries: []Entry{{Timestamp: 1700000000000000001, Line: "hello", Metadata: map[string]string{"b": "2", "a": "1"}}},
+		},
+		{
+			Labels:  map[string]string{"job": "app", "tenant.id": "spoofed"},
+			Entries: []Entry{{Timestamp: 1700000000000000002, Line: "world"}},
+		},
+		{Labels: map[string]string{"job": "empty"}},
+		nil,
+	}}
+
+	tests := []struct {
+		name              string
+		tenantStreamLabel string
+		tenant            string
+		want              [][]*commonpb.KeyValue
+	}{
+		{
+			name:              "tenant label and header",
+			tenantStreamLabel: "tenant",
+			tenant:            "tenant-a",
+			want: [][]*commonpb.KeyValue{
+				{stringAttr("tenant.id", "tenant-b"), stringAttr("job", "varlogs"), stringAttr("tenant", "tenant-b")},
+				{stringAttr("tenant.id", "tenant-a"), stringAttr("job", "app")},
+			},
+		},
+		{
+			name: "no tenant",
+			want: [][]*commonpb.KeyValue{
+				{stringAttr("job", "varlogs"), stringAttr("tenant", "tenant-b")},
+				{stringAttr("job", "app"), stringAttr("tenant.id", "spoofed")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New("tenant.id", tt.tenantStreamLabel).ResourceLogs(request, tt.tenant, now)
+
+			assert.Len(t, got, len(tt.want))
+			for i, attributes := range tt.want {
+				assert.Equal(t, attributes, got[i].GetResource().GetAttributes())
+			}
+
+			record := got[0].GetScopeLogs()[0].GetLogRecords()[0]
+			assert.Equal(t, scopeName, got[0].GetScopeLogs()[0].GetScope().GetName())
+			assert.Equal(t, &logpb.LogRecord{
+				TimeUnixNano:         1700000000000000001,
+				ObservedTimeUnixNano: uint64(now.UnixNano()),
+				Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "hello"}},
+				Attributes:           []*commonpb.KeyValue{stringAttr("a", "1"), stringAttr("b", "2")},
+			}, record)
+		})
+	}
+}
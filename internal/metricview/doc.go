@@ -0,0 +1,8 @@
+// Package metricview applies views to the self-instrumentation of the proxy.
+//
+// The OpenTelemetry meter provider is created by the shared otel package, which takes no SDK views. MeterProvider
+// wraps it instead, matching each instrument against the configured views when it is created: a dropped instrument is
+// replaced by a no-op one, so it is never exported, and a renamed one is created under its new name. Views are SDK
+// views, so a dropped name may contain * and ? wildcards. Low-resource deployments use them to drop the instruments
+// they do not need, such as the per-status-code counters, without changing the code recording them.
+package metricview
@@ -0,0 +1,281 @@
+// Package metricview applies views to the self-instrumentation of the proxy.
+package metricview
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// MeterProvider is a meter provider applying views to the instruments of its meters.
+type MeterProvider struct {
+	metric.MeterProvider
+	views []sdkmetric.View
+}
+
+// New creates a meter provider applying the configured views to the instruments of the provider. The drop views are
+// matched first, then the rename views, and the first matching view applies.
+func New(cfg *config.MetricViews, provider metric.MeterProvider) (*MeterProvider, error) {
+	views := make([]sdkmetric.View, 0, len(cfg.Drop)+len(cfg.Rename))
+	for _, name := range cfg.Drop {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid metric view drop %q, expected an instrument name", name)
+		}
+		views = append(views, sdkmetric.NewView(
+			sdkmetric.Instrument{Name: name},
+			sdkmetric.Stream{Aggregation: sdkmetric.AggregationDrop{}},
+		))
+	}
+
+	for _, entry := range cfg.Rename {
+		from, to, ok := strings.Cut(entry, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid metric view rename %q, expected old=new", entry)
+		}
+		// A wildcard would rename several instruments to the same name
+		if strings.ContainsAny(from, "*?") {
+			return nil, fmt.Errorf("invalid metric view rename %q, the old name cannot contain wildcards", entry)
+		}
+		views = append(views, sdkmetric.NewView(sdkmetric.Instrument{Name: from}, sdkmetric.Stream{Name: to}))
+	}
+
+	return &MeterProvider{MeterProvider: provider, views: views}, nil
+}
+
+// Meter returns the named meter of the provider, applying the views to its instruments.
+func (p *MeterProvider) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	meter := p.MeterProvider.Meter(name, opts...)
+	if len(p.views) == 0 {
+		return meter
+	}
+	return &viewMeter{Meter: meter, views: p.views}
+}
+
+// viewMeter is a meter creating its instruments according to the views.
+type viewMeter struct {
+	metric.Meter
+	views []sdkmetric.View
+}
+
+// apply returns the name of an instrument under the first matching view, and false when the instrument is dropped.
+func (m *viewMeter) apply(name string, kind sdkmetric.InstrumentKind) (string, bool) {
+	for _, view := range m.views {
+		stream, ok := view(sdkmetric.Instrument{Name: name, Kind: kind})
+		if !ok {
+			continue
+		}
+		if _, drop := stream.Aggregation.(sdkmetric.AggregationDrop); drop {
+			return "", false
+		}
+		return stream.Name, true
+	}
+	return name, true
+}
+
+// Int64Counter creates the counter under its view.
+func (m *viewMeter) Int64Counter(name string, opts ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindCounter)
+	if !ok {
+		return noop.Int64Counter{}, nil
+	}
+	return m.Meter.Int64Counter(name, opts...)
+}
+
+// Int64UpDownCounter creates the up-down counter under its view.
+func (m *viewMeter) Int64UpDownCounter(
+	name string,
+	opts ...metric.Int64UpDownCounterOption,
+) (metric.Int64UpDownCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindUpDownCounter)
+	if !ok {
+		return noop.Int64UpDownCounter{}, nil
+	}
+	return m.Meter.Int64UpDownCounter(name, opts...)
+}
+
+// Int64Histogram creates the histogram under its view.
+func (m *viewMeter) Int64Histogram(name string, opts ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindHistogram)
+	if !ok {
+		return noop.Int64Histogram{}, nil
+	}
+	return m.Meter.Int64Histogram(name, opts...)
+}
+
+// Int64Gauge creates the gauge under its view.
+func (m *viewMeter) Int64Gauge(name string, opts ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindGauge)
+	if !ok {
+		return noop.Int64Gauge{}, nil
+	}
+	return m.Meter.Int64Gauge(name, opts...)
+}
+
+// Int64ObservableCounter creates the observable counter under its view.
+func (m *viewMeter) Int64ObservableCounter(
+	name string,
+	opts ...metric.Int64ObservableCounterOption,
+) (metric.Int64ObservableCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableCounter)
+	if !ok {
+		return noop.Int64ObservableCounter{}, nil
+	}
+	return m.Meter.Int64ObservableCounter(name, opts...)
+}
+
+// Int64ObservableUpDownCounter creates the observable up-down counter under its view.
+func (m *viewMeter) Int64ObservableUpDownCounter(
+	name string,
+	opts ...metric.Int64ObservableUpDownCounterOption,
+) (metric.Int64ObservableUpDownCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableUpDownCounter)
+	if !ok {
+		return noop.Int64ObservableUpDownCounter{}, nil
+	}
+	return m.Meter.Int64ObservableUpDownCounter(name, opts...)
+}
+
+// Int64ObservableGauge creates the observable gauge under its view.
+func (m *viewMeter) Int64ObservableGauge(
+	name string,
+	opts ...metric.Int64ObservableGaugeOption,
+) (metric.Int64ObservableGauge, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableGauge)
+	if !ok {
+		return noop.Int64ObservableGauge{}, nil
+	}
+	return m.Meter.Int64ObservableGauge(name, opts...)
+}
+
+// Float64Counter creates the counter under its view.
+func (m *viewMeter) Float64Counter(name string, opts ...metric.Float64CounterOption) (metric.Float64Counter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindCounter)
+	if !ok {
+		return noop.Float64Counter{}, nil
+	}
+	return m.Meter.Float64Counter(name, opts...)
+}
+
+// Float64UpDownCounter creates the up-down counter under its view.
+func (m *viewMeter) Float64UpDownCounter(
+	name string,
+	opts ...metric.Float64UpDownCounterOption,
+) (metric.Float64UpDownCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindUpDownCounter)
+	if !ok {
+		return noop.Float64UpDownCounter{}, nil
+	}
+	return m.Meter.Float64UpDownCounter(name, opts...)
+}
+
+// Float64Histogram creates the histogram under its view.
+func (m *viewMeter) Float64Histogram(
+	name string,
+	opts ...metric.Float64HistogramOption,
+) (metric.Float64Histogram, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindHistogram)
+	if !ok {
+		return noop.Float64Histogram{}, nil
+	}
+	return m.Meter.Float64Histogram(name, opts...)
+}
+
+// Float64Gauge creates the gauge under its view.
+func (m *viewMeter) Float64Gauge(name string, opts ...metric.Float64GaugeOption) (metric.Float64Gauge, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindGauge)
+	if !ok {
+		return noop.Float64Gauge{}, nil
+	}
+	return m.Meter.Float64Gauge(name, opts...)
+}
+
+// Float64ObservableCounter creates the observable counter under its view.
+func (m *viewMeter) Float64ObservableCounter(
+	name string,
+	opts ...metric.Float64ObservableCounterOption,
+) (metric.Float64ObservableCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableCounter)
+	if !ok {
+		return noop.Float64ObservableCounter{}, nil
+	}
+	return m.Meter.Float64ObservableCounter(name, opts...)
+}
+
+// Float64ObservableUpDownCounter creates the observable up-down counter under its view.
+func (m *viewMeter) Float64ObservableUpDownCounter(
+	name string,
+	opts ...metric.Float64ObservableUpDownCounterOption,
+) (metric.Float64ObservableUpDownCounter, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableUpDownCounter)
+	if !ok {
+		return noop.Float64ObservableUpDownCounter{}, nil
+	}
+	return m.Meter.Float64ObservableUpDownCounter(name, opts...)
+}
+
+// Float64ObservableGauge creates the observable gauge under its view.
+func (m *viewMeter) Float64ObservableGauge(
+	name string,
+	opts ...metric.Float64ObservableGaugeOption,
+) (metric.Float64ObservableGauge, error) {
+	name, ok := m.apply(name, sdkmetric.InstrumentKindObservableGauge)
+	if !ok {
+		return noop.Float64ObservableGauge{}, nil
+	}
+	return m.Meter.Float64ObservableGauge(name, opts...)
+}
+
+// RegisterCallback registers the callback for the instruments that are not dropped. The observations of the dropped
+// instruments are ignored, as the meter does not know them.
+func (m *viewMeter) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	observed := make([]metric.Observable, 0, len(instruments))
+	for _, instrument := range instruments {
+		if !dropped(instrument) {
+			observed = append(observed, instrument)
+		}
+	}
+	if len(observed) == 0 {
+		return noop.Registration{}, nil
+	}
+
+	return m.Meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		return f(ctx, viewObserver{Observer: o})
+	}, observed...)
+}
+
+// viewObserver is an observer ignoring the observations of dropped instruments.
+type viewObserver struct {
+	metric.Observer
+}
+
+// ObserveInt64 records the value of an instrument that is not dropped.
+func (o viewObserver) ObserveInt64(instrument metric.Int64Observable, value int64, opts ...metric.ObserveOption) {
+	if !dropped(instrument) {
+		o.Observer.ObserveInt64(instrument, value, opts...)
+	}
+}
+
+// ObserveFloat64 records the value of an instrument that is not dropped.
+func (o viewObserver) ObserveFloat64(instrument metric.Float64Observable, value float64, opts ...metric.ObserveOption) {
+	if !dropped(instrument) {
+		o.Observer.ObserveFloat64(instrument, value, opts...)
+	}
+}
+
+// dropped reports whether an observable instrument was dropped by a view.
+func dropped(instrument metric.Observable) bool {
+	switch instrument.(type) {
+	case noop.Int64ObservableCounter, noop.Int64ObservableUpDownCounter, noop.Int64ObservableGauge,
+		noop.Float64ObservableCounter, noop.Float64ObservableUpDownCounter, noop.Float64ObservableGauge:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,281 @@
+package metricview
+
+import (
+	"context"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.MetricViews
+		wantErr string
+	}{
+		{name: "no views"},
+		{
+			name: "valid",
+			cfg: config.MetricViews{
+				Drop:   []string{"otel_lgtm_proxy_*_status_total", "otel_lgtm_proxy_requests_total"},
+				Rename: []string{"otel_lgtm_proxy_records_total = proxy_records_total"},
+			},
+		},
+		{name: "empty drop", cfg: config.MetricViews{Drop: []string{" "}}, wantErr: "invalid metric view drop"},
+		{name: "rename without new name", cfg: config.MetricViews{Rename: []string{"a="}}, wantErr: "expected old=new"},
+		{name: "rename without separator", cfg: config.MetricViews{Rename: []string{"a"}}, wantErr: "expected old=new"},
+		{
+			name:    "rename with wildcard",
+			cfg:     config.MetricViews{Rename: []string{"otel_*=proxy"}},
+			wantErr: "cannot contain wildcards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&tt.cfg, sdkmetric.NewMeterProvider())
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMeter(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider, err := New(&config.MetricViews{
+		Drop:   []string{"dropped_*", "dropped_gauge"},
+		Rename: []string{"renamed_total=new_total", "dropped_total=kept_total"},
+	}, sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	require.NoError(t, err)
+	meter := provider.Meter("test")
+
+	ctx := context.Background()
+	for _, name := range []string{"kept_total", "renamed_total", "dropped_total"} {
+		counter, err := meter.Int64Counter(name)
+		require.NoError(t, err)
+		counter.Add(ctx, 1)
+	}
+	histogram, err := meter.Float64Histogram("dropped_seconds")
+	require.NoError(t, err)
+	histogram.Record(ctx, 1)
+
+	kept, err := meter.Int64ObservableGauge("kept_gauge")
+	require.NoError(t, err)
+	dropped, err := meter.Int64ObservableGauge("dropped_gauge")
+	require.NoError(t, err)
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(kept, 42)
+		o.ObserveInt64(dropped, 7)
+		return nil
+	}, kept, dropped)
+	require.NoError(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] += dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] = dp.Value
+				}
+			default:
+				t.Errorf("unexpected metric %s", m.Name)
+			}
+		}
+	}
+
+	// The drop views match first, so dropped_total is not renamed
+	assert.Equal(t, map[string]int64{"kept_total": 1, "new_total": 1, "kept_gauge": 42}, got)
+}
+
+func TestMeter_NoViews(t *testing.T) {
+	sdk := sdkmetric.NewMeterProvider()
+	provider, err := New(&config.MetricViews{}, sdk)
+	require.NoError(t, err)
+
+	assert.Equal(t, sdk.Meter("test"), provider.Meter("test"))
+}
+
+func TestRegisterCallback_Dropped(t *testing.T) {
+	provider, err := New(&config.MetricViews{Drop: []string{"*"}}, sdkmetric.NewMeterProvider())
+	require.NoError(t, err)
+	meter := provider.Meter("test")
+
+	gauge, err := meter.Float64ObservableGauge("gauge")
+	require.NoError(t, err)
+	registration, err := meter.RegisterCallback(func(context.Context, metric.Observer) error {
+		t.Error("the callback of dropped instruments should not be called")
+		return nil
+	}, gauge)
+	require.NoError(t, err)
+	assert.NoError(t, registration.Unregister())
+}
+
+func TestMeter_Instruments(t *testing.T) {
+	tests := []struct {
+		name   string
+		create func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error)
+	}{
+		{
+			name: "int64 counter",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				counter, err := meter.Int64Counter(name)
+				counter.Add(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "int64 up-down counter",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				counter, err := meter.Int64UpDownCounter(name)
+				counter.Add(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "int64 histogram",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				histogram, err := meter.Int64Histogram(name)
+				histogram.Record(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "int64 gauge",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				gauge, err := meter.Int64Gauge(name)
+				gauge.Record(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "int64 observable counter",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Int64ObservableCounter(name)
+			},
+		},
+		{
+			name: "int64 observable up-down counter",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Int64ObservableUpDownCounter(name)
+			},
+		},
+		{
+			name: "int64 observable gauge",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Int64ObservableGauge(name)
+			},
+		},
+		{
+			name: "float64 counter",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				counter, err := meter.Float64Counter(name)
+				counter.Add(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "float64 up-down counter",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				counter, err := meter.Float64UpDownCounter(name)
+				counter.Add(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "float64 histogram",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				histogram, err := meter.Float64Histogram(name)
+				histogram.Record(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "float64 gauge",
+			create: func(ctx context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				gauge, err := meter.Float64Gauge(name)
+				gauge.Record(ctx, 1)
+				return nil, err
+			},
+		},
+		{
+			name: "float64 observable counter",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Float64ObservableCounter(name)
+			},
+		},
+		{
+			name: "float64 observable up-down counter",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Float64ObservableUpDownCounter(name)
+			},
+		},
+		{
+			name: "float64 observable gauge",
+			create: func(_ context.Context, meter metric.Meter, name string) (metric.Observable, error) {
+				return meter.Float64ObservableGauge(name)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			provider, err := New(&config.MetricViews{Drop: []string{"dropped"}, Rename: []string{"renamed=new"}},
+				sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+			require.NoError(t, err)
+			meter := provider.Meter("test")
+
+			ctx := context.Background()
+			var observables []metric.Observable
+			for _, name := range []string{"renamed", "dropped"} {
+				observable, err := tt.create(ctx, meter, name)
+				require.NoError(t, err)
+				if observable != nil {
+					observables = append(observables, observable)
+				}
+			}
+			if len(observables) > 0 {
+				_, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+					for _, observable := range observables {
+						switch instrument := observable.(type) {
+						case metric.Int64Observable:
+							o.ObserveInt64(instrument, 1)
+						case metric.Float64Observable:
+							o.ObserveFloat64(instrument, 1)
+						}
+					}
+					return nil
+				}, observables...)
+				require.NoError(t, err)
+			}
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(ctx, &rm))
+
+			var names []string
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					names = append(names, m.Name)
+				}
+			}
+			// The renamed instrument is reported under its new name and the dropped one not at all
+			assert.Equal(t, []string{"new"}, names)
+		})
+	}
+}
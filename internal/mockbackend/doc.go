@@ -0,0 +1,11 @@
+// Package mockbackend provides a mock LGTM backend for local development.
+//
+// The mock backend accepts OTLP requests on one or more listeners and records,
+// per signal and tenant header value:
+//   - The number of requests received
+//   - The number of records (log records, data points or spans) received
+//   - The number of payload bytes received
+//
+// A JSON summary of the recorded counts is served at /summary on every listener,
+// allowing tenant routing configuration to be verified without a real LGTM stack.
+package mockbackend
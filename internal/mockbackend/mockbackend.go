@@ -0,0 +1,218 @@
+// Package mockbackend provides a mock LGTM backend for local development.
+package mockbackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
+	"go.opentelemetry.io/otel/attribute"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	addressAttrKey = "mockbackend.address"
+	errAttrKey     = "error"
+)
+
+// Counts holds the totals recorded for a signal and tenant.
+type Counts struct {
+	Requests int64 `json:"requests"`
+	Records  int64 `json:"records"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// Recorder records the requests received by the mock backend.
+type Recorder struct {
+	tenantHeader string
+	mu           sync.Mutex
+	counts       map[string]map[string]*Counts
+}
+
+// NewRecorder creates a new Recorder reading the tenant from the given header.
+func NewRecorder(tenantHeader string) *Recorder {
+	return &Recorder{
+		tenantHeader: tenantHeader,
+		counts:       make(map[string]map[string]*Counts),
+	}
+}
+
+// Summary returns a snapshot of the recorded counts keyed by signal and tenant.
+func (rec *Recorder) Summary() map[string]map[string]Counts {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	summary := make(map[string]map[string]Counts, len(rec.counts))
+	for signal, tenants := range rec.counts {
+		summary[signal] = make(map[string]Counts, len(tenants))
+		for tenant, counts := range tenants {
+			summary[signal][tenant] = *counts
+		}
+	}
+
+	return summary
+}
+
+// Handler returns the HTTP handler accepting OTLP requests and serving the summary.
+func (rec *Recorder) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /summary", rec.serveSummary)
+	mux.HandleFunc("POST /", rec.serveOTLP)
+	return mux
+}
+
+// serveOTLP records an incoming OTLP request.
+func (rec *Recorder) serveOTLP(w http.ResponseWriter, r *http.Request) {
+	signal := signalFromPath(r.URL.Path)
+
+	records, size, err := countRecords(r, signal)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant := r.Header.Get(rec.tenantHeader)
+
+	rec.mu.Lock()
+	if rec.counts[signal] == nil {
+		rec.counts[signal] = make(map[string]*Counts)
+	}
+	counts, ok := rec.counts[signal][tenant]
+	if !ok {
+		counts = &Counts{}
+		rec.counts[signal][tenant] = counts
+	}
+	counts.Requests++
+	counts.Records += records
+	counts.Bytes += size
+	rec.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveSummary writes the recorded counts as JSON.
+func (rec *Recorder) serveSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rec.Summary()); err != nil {
+		logger.Error(r.Context(), err.Error())
+	}
+}
+
+// signalFromPath derives the signal type from the request path.
+func signalFromPath(path string) string {
+	for _, signal := range []string{"logs", "metrics", "traces"} {
+		if strings.HasSuffix(path, "/"+signal) {
+			return signal
+		}
+	}
+	return "unknown"
+}
+
+// countRecords unmarshals the request and returns the number of records and payload bytes. The records of metrics
+// are their data points, as the proxy counts them.
+func countRecords(r *http.Request, signal string) (int64, int64, error) {
+	body := &countingReader{reader: r.Body}
+	r.Body = body
+
+	var records int64
+	switch signal {
+	case "logs":
+		data, err := proto.Unmarshal(r, &logpb.LogsData{})
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, rl := range data.GetResourceLogs() {
+			for _, sl := range rl.GetScopeLogs() {
+				records += int64(len(sl.GetLogRecords()))
+			}
+		}
+	case "metrics":
+		data, err := proto.Unmarshal(r, &metricpb.MetricsData{})
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, rm := range data.GetResourceMetrics() {
+			records += int64(proto.CountRecords(rm))
+		}
+	case "traces":
+		data, err := proto.Unmarshal(r, &tracepb.TracesData{})
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, rs := range data.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				records += int64(len(ss.GetSpans()))
+			}
+		}
+	default:
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return records, body.read, nil
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	reader io.ReadCloser
+	read   int64
+}
+
+// Read reads from the underlying reader and counts the bytes read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.read += int64(n)
+	return n, err
+}
+
+// Close closes the underlying reader.
+func (c *countingReader) Close() error {
+	return c.reader.Close()
+}
+
+// Run starts the mock backend listeners and blocks until the context is cancelled.
+func Run(ctx context.Context, cfg *config.Config) error {
+	rec := NewRecorder(cfg.MockBackend.TenantHeader)
+
+	errGroup, ctx := errgroup.WithContext(ctx)
+	for _, address := range cfg.MockBackend.Addresses {
+		server := &http.Server{
+			Addr:              address,
+			Handler:           rec.Handler(),
+			ReadHeaderTimeout: cfg.MockBackend.Timeout,
+		}
+
+		errGroup.Go(func() error {
+			logger.Info(ctx, "starting mock backend", attribute.String(addressAttrKey, address))
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error(ctx, "mock backend error",
+					attribute.String(addressAttrKey, address),
+					attribute.String(errAttrKey, err.Error()),
+				)
+				return err
+			}
+			return nil
+		})
+
+		errGroup.Go(func() error {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			return server.Shutdown(shutdownCtx)
+		})
+	}
+
+	return errGroup.Wait()
+}
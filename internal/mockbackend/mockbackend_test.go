@@ -0,0 +1,170 @@
+package mockbackend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRecorder(t *testing.T) {
+	logs, err := proto.Marshal(&logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{
+		{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{}, {}}}}},
+	}})
+	require.NoError(t, err)
+
+	metrics, err := proto.Marshal(&metricpb.MetricsData{ResourceMetrics: []*metricpb.ResourceMetrics{
+		{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{
+			{Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{}, {}}}}},
+			{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{{}}}}},
+		}}}},
+	}})
+	require.NoError(t, err)
+
+	traces, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{
+		{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{}}}}},
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		path       string
+		tenant     string
+		body       []byte
+		wantStatus int
+	}{
+		{name: "logs for tenant a", path: "/otlp/v1/logs", tenant: "tenant-a", body: logs, wantStatus: http.StatusOK},
+		{name: "logs for tenant a again", path: "/otlp/v1/logs", tenant: "tenant-a", body: logs, wantStatus: http.StatusOK},
+		{
+			name: "metrics for tenant a", path: "/otlp/v1/metrics", tenant: "tenant-a", body: metrics,
+			wantStatus: http.StatusOK,
+		},
+		{name: "traces for tenant b", path: "/v1/traces", tenant: "tenant-b", body: traces, wantStatus: http.StatusOK},
+		{name: "invalid payload", path: "/v1/traces", tenant: "tenant-b", body: []byte("invalid"), wantStatus: http.StatusBadRequest},
+	}
+
+	rec := NewRecorder("X-Scope-OrgID")
+	handler := rec.Handler()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+			req.Header.Set("X-Scope-OrgID", tt.tenant)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	want := map[string]map[string]Counts{
+		"logs":    {"tenant-a": {Requests: 2, Records: 4, Bytes: int64(2 * len(logs))}},
+		"metrics": {"tenant-a": {Requests: 1, Records: 3, Bytes: int64(len(metrics))}},
+		"traces":  {"tenant-b": {Requests: 1, Records: 1, Bytes: int64(len(traces))}},
+	}
+	assert.Equal(t, want, rec.Summary())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/summary", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.True(t, strings.Contains(w.Body.String(), `"tenant-a"`))
+}
+
+func TestSignalFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/otlp/v1/logs", want: "logs"},
+		{path: "/v1/metrics", want: "metrics"},
+		{path: "/v1/traces", want: "traces"},
+		{path: "/api/v1/push", want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, signalFromPath(tt.path))
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, &config.Config{MockBackend: config.MockBackend{
+			Addresses:    []string{address},
+			TenantHeader: "X-Scope-OrgID",
+			Timeout:      time.Second,
+		}})
+	}()
+
+	// The summary is served once the listener is up
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + address + "/summary")
+		if err != nil {
+			return false
+		}
+		defer func() { _ = resp.Body.Close() }()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Cancelling the context shuts the listeners down
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("mock backend did not stop")
+	}
+}
+
+func TestRun_ListenError(t *testing.T) {
+	err := Run(t.Context(), &config.Config{MockBackend: config.MockBackend{Addresses: []string{"invalid address"}}})
+	assert.Error(t, err)
+}
+
+func TestCountingReader(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	reader := &countingReader{reader: body}
+
+	b, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(b))
+	assert.Equal(t, int64(len(b)), reader.read)
+
+	require.NoError(t, reader.Close())
+	assert.True(t, body.closed)
+}
+
+// closeRecorder records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+// Close records the reader as closed.
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
@@ -0,0 +1,36 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// countingReader counts the bytes read from the wrapped reader into a counter, shared by the bodies re-sent when the
+// request is redirected. The count is atomic as the transport may read the request body from its own goroutine.
+type countingReader struct {
+	io.Reader
+	n *atomic.Int64
+}
+
+// Read reads from the wrapped reader and counts the bytes read.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// recordBandwidth records the request body bytes sent to and the response body bytes received from the backend.
+func (p *Processor[T]) recordBandwidth(ctx context.Context, tenant string, sent, received int64) {
+	attrs := metric.WithAttributes(
+		p.signalTypeAttr,
+		attribute.String(signalTenantAttrKey, tenant),
+		p.backendAttr,
+	)
+	p.backendSentBytesMetric.Add(ctx, sent, attrs)
+	p.backendReceivedBytesMetric.Add(ctx, received, attrs)
+}
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func TestSend_Bandwidth(t *testing.T) {
+	tests := []struct {
+		name         string
+		stream       bool
+		wantSent     int64
+		wantReceived int64
+	}{
+		{name: "buffered", wantSent: 4, wantReceived: 8},
+		{name: "streamed", stream: true, wantSent: 2, wantReceived: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				_, _ = w.Write([]byte("accepted"))
+			}))
+			defer backend.Close()
+
+			reader := sdkmetric.NewManualReader()
+			meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+			cfg := &config.Config{Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"}}
+			proc, err := New(
+				cfg,
+				&config.Endpoint{Address: backend.URL, Stream: tt.stream},
+				attribute.String(signalTypeAttrKey, "logs"),
+				backend.Client(),
+				meter,
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("data"), nil },
+			)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			_, _, err = proc.send(ctx, "tenant-a", []*logpb.ResourceLogs{{}})
+			require.NoError(t, err)
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(ctx, &rm))
+
+			got := map[string]int64{}
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					sum, ok := m.Data.(metricdata.Sum[int64])
+					if !ok {
+						continue
+					}
+					for _, dp := range sum.DataPoints {
+						tenant, _ := dp.Attributes.Value(attribute.Key(signalTenantAttrKey))
+						backendHost, _ := dp.Attributes.Value(attribute.Key(signalBackendAttrKey))
+						if tenant.AsString() == "tenant-a" && backendHost.AsString() != "" {
+							got[m.Name] += dp.Value
+						}
+					}
+				}
+			}
+
+			assert.Equal(t, tt.wantSent, got["otel_lgtm_proxy_backend_sent_bytes_total"])
+			assert.Equal(t, tt.wantReceived, got["otel_lgtm_proxy_backend_received_bytes_total"])
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var connectionReusedAttrKey = "connection.reused"
+
+// connTrace collects the connection level timings of a single outbound request.
+type connTrace struct {
+	mu           sync.Mutex
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tlsHandshake time.Duration
+	server       time.Duration
+	gotConn      bool
+	reused       bool
+}
+
+// clientTrace returns the httptrace hooks that populate the connection trace.
+func (c *connTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if !c.dnsStart.IsZero() {
+				c.dns = time.Since(c.dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			// Dual-stack dialing may start several connections, measure from the first one.
+			if c.connectStart.IsZero() {
+				c.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if err == nil && !c.connectStart.IsZero() {
+				c.connect = time.Since(c.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if err == nil && !c.tlsStart.IsZero() {
+				c.tlsHandshake = time.Since(c.tlsStart)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.gotConn = true
+			c.reused = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if !c.wroteRequest.IsZero() {
+				c.server = time.Since(c.wroteRequest)
+			}
+		},
+	}
+}
+
+// recordConnTrace records the collected connection timings to the backend connection metrics.
+func (p *Processor[T]) recordConnTrace(ctx context.Context, c *connTrace, attrs []attribute.KeyValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	attrs = append(slices.Clip(attrs), p.backendAttr)
+	opt := metric.WithAttributes(attrs...)
+
+	if c.dns > 0 {
+		p.backendDNSMetric.Record(ctx, c.dns.Milliseconds(), opt)
+	}
+	if c.connect > 0 {
+		p.backendConnectMetric.Record(ctx, c.connect.Milliseconds(), opt)
+	}
+	if c.tlsHandshake > 0 {
+		p.backendTLSMetric.Record(ctx, c.tlsHandshake.Milliseconds(), opt)
+	}
+	if c.server > 0 {
+		p.backendServerMetric.Record(ctx, c.server.Milliseconds(), opt)
+	}
+	if c.gotConn {
+		p.backendConnectionsMetric.Add(ctx, 1, metric.WithAttributes(
+			append(attrs, attribute.String(connectionReusedAttrKey, strconv.FormatBool(c.reused)))...,
+		))
+	}
+}
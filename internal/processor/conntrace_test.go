@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := server.Client()
+
+	tests := []struct {
+		name       string
+		wantReused bool
+	}{
+		{
+			name:       "first request opens a new connection",
+			wantReused: false,
+		},
+		{
+			name:       "second request reuses the connection",
+			wantReused: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &connTrace{}
+			ctx := httptrace.WithClientTrace(context.Background(), conn.clientTrace())
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+			require.NoError(t, err)
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			require.NoError(t, resp.Body.Close())
+
+			assert.True(t, conn.gotConn)
+			assert.Equal(t, tt.wantReused, conn.reused)
+			if !tt.wantReused {
+				assert.False(t, conn.connectStart.IsZero())
+			}
+			assert.False(t, conn.wroteRequest.IsZero())
+		})
+	}
+}
+
+func TestBackendHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{name: "url with port", address: "http://loki:3100/otlp/v1/logs", want: "loki:3100"},
+		{name: "url without port", address: "https://tempo.example.com/v1/traces", want: "tempo.example.com"},
+		{name: "not a url", address: "loki", want: "loki"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, backendHost(tt.address))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/request"
+)
+
+// ErrHeaderLimit is returned for requests whose headers exceed the header limits of the backend, which would otherwise
+// be answered by the backend with an opaque 431.
+var ErrHeaderLimit = errors.New("outbound request headers exceed the limit")
+
+// checkHeaderLimit returns ErrHeaderLimit when the headers exceed the size or count limit of the endpoint.
+func checkHeaderLimit(limit *config.HeaderLimit, header http.Header) error {
+	size, count := request.HeaderSize(header)
+	if limit.Bytes > 0 && size > limit.Bytes {
+		return fmt.Errorf("%w: %d bytes of headers, limit %d", ErrHeaderLimit, size, limit.Bytes)
+	}
+	if limit.Count > 0 && count > limit.Count {
+		return fmt.Errorf("%w: %d headers, limit %d", ErrHeaderLimit, count, limit.Count)
+	}
+	return nil
+}
+
+// validateHeaderLimit returns an error when the headers sent with every request already exceed the header limits of
+// the endpoint: the custom headers, the content type and the tenant header of the default tenant.
+func validateHeaderLimit(cfg *config.Config, endpoint *config.Endpoint, header http.Header, contentType string) error {
+	header = header.Clone()
+	header.Set("Content-Type", contentType)
+	header.Add(cfg.Tenant.Header, cfg.Tenant.FormatTenant(cfg.Tenant.Default))
+	if err := checkHeaderLimit(&endpoint.HeaderLimit, header); err != nil {
+		return fmt.Errorf("invalid headers of backend %s: %w", endpoint.Address, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSend_HeaderLimit(t *testing.T) {
+	// "Content-Type: application/x-protobuf\r\n" and "X-Scope-Orgid: tenant-a\r\n"
+	const size = 38 + 25
+
+	tests := []struct {
+		name        string
+		tenant      string
+		hooks       []string
+		headerLimit config.HeaderLimit
+		wantErr     bool
+	}{
+		{name: "within the limits", tenant: "tenant-a", headerLimit: config.HeaderLimit{Bytes: size, Count: 2}},
+		{name: "unlimited", tenant: "tenant-a", hooks: []string{"content-sha256"}},
+		{
+			name:        "size exceeded by the tenant",
+			tenant:      "tenant-with-a-long-name",
+			headerLimit: config.HeaderLimit{Bytes: size, Count: 2},
+			wantErr:     true,
+		},
+		{
+			name:        "count exceeded by a hook",
+			tenant:      "tenant-a",
+			hooks:       []string{"content-sha256"},
+			headerLimit: config.HeaderLimit{Count: 2},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			if !tt.wantErr {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			reader := sdkmetric.NewManualReader()
+			meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+			cfg := &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Logs:   config.Endpoint{Address: "http://backend:8080", Hooks: tt.hooks, HeaderLimit: tt.headerLimit},
+			}
+			proc, err := New(
+				cfg,
+				&cfg.Logs,
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				meter,
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("data"), nil },
+			)
+			require.NoError(t, err)
+
+			_, _, err = proc.send(context.Background(), tt.tenant, []*logpb.ResourceLogs{{}})
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrHeaderLimit)
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.Background(), &rm))
+			var exceeded int64
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name != "otel_lgtm_proxy_backend_header_limit_exceeded_total" {
+						continue
+					}
+					for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						tenant, _ := dp.Attributes.Value(attribute.Key(signalTenantAttrKey))
+						assert.Equal(t, tt.tenant, tenant.AsString())
+						exceeded += dp.Value
+					}
+				}
+			}
+			assert.Equal(t, int64(1), exceeded)
+		})
+	}
+}
+
+func TestNew_HeaderLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     string
+		headerLimit config.HeaderLimit
+		wantErr     bool
+	}{
+		{name: "default limits", headers: "X-Custom=value", headerLimit: config.HeaderLimit{Bytes: 16384, Count: 100}},
+		{name: "custom headers too large", headers: "X-Custom=value", headerLimit: config.HeaderLimit{Bytes: 64}, wantErr: true},
+		{name: "too many custom headers", headers: "X-A=1,X-B=2", headerLimit: config.HeaderLimit{Count: 3}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID", Default: "default"},
+				Logs:   config.Endpoint{Address: "http://backend:8080", Headers: tt.headers, HeaderLimit: tt.headerLimit},
+			}
+			_, err := New(
+				cfg,
+				&cfg.Logs,
+				attribute.String(signalTypeAttrKey, "logs"),
+				&http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return nil, nil },
+			)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrHeaderLimit)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clientpool"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/decisions"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/health"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/topk"
+)
+
+// Option configures optional dependencies of a Processor.
+type Option func(*options)
+
+// options holds the optional dependencies of a Processor.
+type options struct {
+	stats    *stats.Tracker
+	topK     *topk.Tracker
+	circuits *circuit.Overrides
+	clients  *clientpool.Pool
+	health   *health.Tracker
+
+	contentType string
+	headers     map[string]string
+	decisions   *decisions.Feed
+}
+
+// WithStats records every backend request to the given stats tracker.
+func WithStats(tracker *stats.Tracker) Option {
+	return func(o *options) {
+		o.stats = tracker
+	}
+}
+
+// WithTopK adds the volume of every backend request to the given top-K tracker.
+func WithTopK(tracker *topk.Tracker) Option {
+	return func(o *options) {
+		o.topK = tracker
+	}
+}
+
+// WithCircuits rejects the data of tenants whose circuit is open in the given overrides instead of forwarding it.
+func WithCircuits(overrides *circuit.Overrides) Option {
+	return func(o *options) {
+		o.circuits = overrides
+	}
+}
+
+// WithClientPool sends the data of tenants with their own client certificate through a dedicated client cached in the
+// given pool, instead of the shared client.
+func WithClientPool(pool *clientpool.Pool) Option {
+	return func(o *options) {
+		o.clients = pool
+	}
+}
+
+// WithHealth reports the outcome of every backend request to the given health tracker.
+func WithHealth(tracker *health.Tracker) Option {
+	return func(o *options) {
+		o.health = tracker
+	}
+}
+
+// WithContentType sets the content type of backend requests, for payloads marshalled into another format than the OTLP
+// encoding of the endpoint.
+func WithContentType(contentType string) Option {
+	return func(o *options) {
+		o.contentType = contentType
+	}
+}
+
+// WithHeader sets a header on every backend request, for payloads marshalled into a format requiring it.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		o.headers[key] = value
+	}
+}
+
+// WithDecisions publishes the routing decision made for the resources of every tenant to the given feed.
+func WithDecisions(feed *decisions.Feed) Option {
+	return func(o *options) {
+		o.decisions = feed
+	}
+}
@@ -4,15 +4,39 @@ package processor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
 	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/circuit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clientpool"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/debug"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/decisions"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/health"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/hook"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/profilepb"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/ratelimit"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/signature"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/topk"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/cert"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/ipfamily"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/util/request"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -24,12 +48,21 @@ import (
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"golang.org/x/sync/errgroup"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 var (
-	signalTenantAttrKey             = "signal.tenant"
-	signalResponseStatusCodeAttrKey = "signal.response.status.code"
-	signalTenantRecordsAttrKey      = "signal.tenant.records"
+	signalTenantAttrKey              = "signal.tenant"
+	signalResponseStatusCodeAttrKey  = "signal.response.status.code"
+	signalResponseStatusClassAttrKey = "signal.response.status.class"
+	signalTenantRecordsAttrKey       = "signal.tenant.records"
+	signalBackendAttrKey             = "signal.backend"
+	signalTenantAliasAttrKey         = "signal.tenant.alias"
+	signalAliasModeAttrKey           = "signal.alias.mode"
+	signalTenantRegionAttrKey        = "signal.tenant.region"
+	signalBackendRegionAttrKey       = "signal.backend.region"
+	errAttrKey                       = "error"
 )
 
 // Client is an interface for making HTTP requests.
@@ -44,21 +77,99 @@ var _ Client = (*http.Client)(nil)
 
 // ResourceData is an interface for OTLP resource types.
 type ResourceData interface {
-	*logpb.ResourceLogs | *metricpb.ResourceMetrics | *tracepb.ResourceSpans
+	*logpb.ResourceLogs | *metricpb.ResourceMetrics | *tracepb.ResourceSpans | *profilepb.ResourceProfiles
+	protoreflect.ProtoMessage
+	GetSchemaUrl() string
+	GetResource() *resourcepb.Resource
+}
+
+// ErrResidency is returned for data of a tenant bound to another region than the one of the backend.
+var ErrResidency = errors.New("backend outside the data residency region of the tenant")
+
+// ErrBackendUnavailable is matched by the errors of data that could not be sent because the backend could not be
+// reached, or that it answered with a server error.
+var ErrBackendUnavailable = errors.New("backend unavailable")
+
+// ErrThrottled is matched by the errors of data that was throttled by the backend or held back by the outbound rate
+// limits.
+var ErrThrottled = errors.New("throttled")
+
+// ErrPartialFailure is returned by Dispatch when the data of some tenants was forwarded and the data of others was
+// not. It wraps the errors of the failed tenants, joined with errors.Join.
+var ErrPartialFailure = errors.New("partial failure")
+
+// StatusError is returned when the backend answers a request with a non-success status code.
+type StatusError struct {
+	StatusCode int
+	// RetryAfter is the delay the backend asked to wait before retrying a throttled or unavailable request, or zero.
+	RetryAfter time.Duration
+}
+
+// Error returns the error message including the status code.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received non-success status code: %d", e.StatusCode)
+}
+
+// Is reports whether the status code is one of a throttled request or of an unavailable backend, so the error matches
+// ErrThrottled or ErrBackendUnavailable.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrBackendUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
 }
 
 // Processor is a generic struct that processes incoming telemetry resource data and forwards it to the appropriate backend.
 type Processor[T ResourceData] struct {
 	config              *config.Config
 	endpoint            *config.Endpoint
+	headers             http.Header
 	signalTypeAttr      attribute.KeyValue
 	client              Client
 	tracer              trace.Tracer
 	proxyRecordsMetric  metric.Int64Counter
 	proxyRequestsMetric metric.Int64Counter
+	proxyBytesMetric    metric.Int64Counter
 	proxyLatencyMetric  metric.Int64Histogram
+	tenantsMetric       metric.Int64Histogram
+	resourcesMetric     metric.Int64Histogram
+	fanoutMetric        metric.Int64Counter
+	aliasMetric         metric.Int64Counter
+	stageMetric         metric.Float64Histogram
+	residencyMetric     metric.Int64Counter
+	aliases             map[string]config.Alias
+	regions             map[string]string
+	tenantHeaders       map[string]http.Header
+	hooks               []hook.Hook
+	signer              *signature.Signer
+	stats               *stats.Tracker
+	topK                *topk.Tracker
+	circuits            *circuit.Overrides
+	clients             *clientpool.Pool
+	health              *health.Tracker
+	decisions           *decisions.Feed
 	getResource         func(T) *resourcepb.Resource
 	marshalResources    func([]T) ([]byte, error)
+	contentType         string
+
+	backendAttr              attribute.KeyValue
+	backendDNSMetric         metric.Int64Histogram
+	backendConnectMetric     metric.Int64Histogram
+	backendTLSMetric         metric.Int64Histogram
+	backendServerMetric      metric.Int64Histogram
+	backendConnectionsMetric metric.Int64Counter
+
+	backendSentBytesMetric     metric.Int64Counter
+	backendReceivedBytesMetric metric.Int64Counter
+
+	requestLimit        *ratelimit.Bucket
+	byteLimit           *ratelimit.Bucket
+	rateLimitWaitMetric metric.Int64Histogram
+
+	headerLimitMetric metric.Int64Counter
 }
 
 // New creates a new generic Processor for any resource type.
@@ -71,7 +182,50 @@ func New[T ResourceData](
 	tracer trace.Tracer,
 	getResource func(T) *resourcepb.Resource,
 	marshalResources func([]T) ([]byte, error),
+	opts ...Option,
 ) (*Processor[T], error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Validate the outbound encoding
+	if err := proto.ValidateEncoding(endpoint.Encoding); err != nil {
+		return nil, err
+	}
+
+	// Resolve the hooks mutating outbound requests
+	hooks, err := hook.Resolve(endpoint.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the signer of the request bodies of the tenants with a signing secret
+	signer, err := signature.NewSigner(&endpoint.Signing)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateStream(endpoint, hooks, signer); err != nil {
+		return nil, err
+	}
+
+	if endpoint.MaxRecords < 0 {
+		return nil, fmt.Errorf("invalid max records %d", endpoint.MaxRecords)
+	}
+
+	if err := validateRedirect(endpoint); err != nil {
+		return nil, err
+	}
+
+	if err := ipfamily.Validate(endpoint.IPFamily); err != nil {
+		return nil, err
+	}
+
+	if err := validateHeaderLimit(config, endpoint, headers(endpoint, o), contentType(endpoint, o)); err != nil {
+		return nil, err
+	}
+
 	// Create a counter for the total number of records processed by the proxy
 	proxyRecordsMetric, err := meter.Int64Counter(
 		"otel_lgtm_proxy_records_total",
@@ -90,6 +244,16 @@ func New[T ResourceData](
 		return nil, fmt.Errorf("failed to create otel lgtm proxy requests counter: %w", err)
 	}
 
+	// Create a counter for the total number of payload bytes forwarded by the proxy
+	proxyBytesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_bytes_total",
+		metric.WithDescription("Total number of otel lgtm proxy payload bytes forwarded"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy bytes counter: %w", err)
+	}
+
 	// Create a histogram for the latency of requests processed by the proxy
 	proxyLatencyMetric, err := meter.Int64Histogram(
 		"otel_lgtm_proxy_request_duration_ms",
@@ -100,20 +264,271 @@ func New[T ResourceData](
 		return nil, fmt.Errorf("failed to create otel lgtm proxy latency histogram: %w", err)
 	}
 
+	// Create histograms describing how fragmented inbound batches are
+	tenantsMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_request_tenants",
+		metric.WithDescription("Number of tenants per inbound request"),
+		metric.WithExplicitBucketBoundaries(1, 2, 3, 5, 10, 20, 50, 100),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy request tenants histogram: %w", err)
+	}
+
+	resourcesMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_request_tenant_resources",
+		metric.WithDescription("Number of resources per tenant per inbound request"),
+		metric.WithExplicitBucketBoundaries(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy request tenant resources histogram: %w", err)
+	}
+
+	// Create a counter for the extra resource copies made for resources shared by several tenants
+	fanoutMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_fanout_resources_total",
+		metric.WithDescription("Total number of resource copies made for resources belonging to multiple tenants"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy fanout resources counter: %w", err)
+	}
+
+	// Create a counter for the resources written to a renamed tenant, labelled by whether they are duplicated
+	aliasMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_alias_resources_total",
+		metric.WithDescription("Total number of resources written to the new tenant of a tenant alias"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy alias resources counter: %w", err)
+	}
+
+	// Create a histogram for the duration of each pipeline stage, with sub-millisecond buckets for the decoding stages
+	stageMetric, err := meter.Float64Histogram(
+		"otel_lgtm_proxy_stage_duration_ms",
+		metric.WithDescription("Duration of the unmarshal, partition, marshal and send pipeline stages"),
+		metric.WithUnit("ms"),
+		metric.WithExplicitBucketBoundaries(0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy stage duration histogram: %w", err)
+	}
+
+	// Create a counter for the resources refused because the backend is outside the region of their tenant
+	residencyMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_residency_violations_total",
+		metric.WithDescription("Total number of resources refused because the backend is outside the region of their tenant"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy residency violations counter: %w", err)
+	}
+
+	// Parse the tenant aliases
+	aliases, err := config.Tenant.ParseAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the tenant data residency regions
+	regions, err := config.Tenant.ParseRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the extra headers of the tenants sent with the signal
+	tenantHeaders, err := config.Tenant.ParseHeaders(signalTypeAttr.Value.AsString())
+	if err != nil {
+		return nil, err
+	}
+
+	// Create histograms breaking down the backend request latency into network and server time
+	backendDNSMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_backend_dns_duration_ms",
+		metric.WithDescription("Duration of DNS lookups for backend requests"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend dns histogram: %w", err)
+	}
+
+	backendConnectMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_backend_connect_duration_ms",
+		metric.WithDescription("Duration of establishing new connections to the backend"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend connect histogram: %w", err)
+	}
+
+	backendTLSMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_backend_tls_handshake_duration_ms",
+		metric.WithDescription("Duration of TLS handshakes with the backend"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend tls handshake histogram: %w", err)
+	}
+
+	backendServerMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_backend_server_duration_ms",
+		metric.WithDescription("Duration between writing a request and receiving the first response byte from the backend"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend server histogram: %w", err)
+	}
+
+	// Create a counter for the connections used by backend requests, labelled by reuse
+	backendConnectionsMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_backend_connections_total",
+		metric.WithDescription("Total number of connections used for backend requests"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend connections counter: %w", err)
+	}
+
+	// Create counters for the bytes exchanged with each backend, to attribute egress costs to tenants
+	backendSentBytesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_backend_sent_bytes_total",
+		metric.WithDescription("Total number of request body bytes sent to the backend"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend sent bytes counter: %w", err)
+	}
+
+	backendReceivedBytesMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_backend_received_bytes_total",
+		metric.WithDescription("Total number of response body bytes received from the backend"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend received bytes counter: %w", err)
+	}
+
+	// Create a counter for the requests refused because their headers exceed the header limits of the backend
+	headerLimitMetric, err := meter.Int64Counter(
+		"otel_lgtm_proxy_backend_header_limit_exceeded_total",
+		metric.WithDescription("Total number of backend requests refused because their headers exceed the header limits"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend header limit counter: %w", err)
+	}
+
+	// Create a histogram for the time requests are held back by the outbound rate limits of the backend
+	rateLimitWaitMetric, err := meter.Int64Histogram(
+		"otel_lgtm_proxy_backend_rate_limit_wait_duration_ms",
+		metric.WithDescription("Time backend requests were held back by the outbound rate limits"),
+		metric.WithUnit("ms"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy backend rate limit wait histogram: %w", err)
+	}
+
 	return &Processor[T]{
-		config:              config,
-		endpoint:            endpoint,
-		signalTypeAttr:      signalTypeAttr,
-		client:              client,
-		tracer:              tracer,
-		proxyRecordsMetric:  proxyRecordsMetric,
-		proxyRequestsMetric: proxyRequestsMetric,
-		proxyLatencyMetric:  proxyLatencyMetric,
-		getResource:         getResource,
-		marshalResources:    marshalResources,
+		config:                   config,
+		endpoint:                 endpoint,
+		headers:                  headers(endpoint, o),
+		signalTypeAttr:           signalTypeAttr,
+		client:                   client,
+		tracer:                   tracer,
+		proxyRecordsMetric:       proxyRecordsMetric,
+		proxyRequestsMetric:      proxyRequestsMetric,
+		proxyBytesMetric:         proxyBytesMetric,
+		proxyLatencyMetric:       proxyLatencyMetric,
+		tenantsMetric:            tenantsMetric,
+		resourcesMetric:          resourcesMetric,
+		fanoutMetric:             fanoutMetric,
+		aliasMetric:              aliasMetric,
+		stageMetric:              stageMetric,
+		residencyMetric:          residencyMetric,
+		aliases:                  aliasesByTenant(aliases),
+		regions:                  regions,
+		tenantHeaders:            tenantHeaders,
+		hooks:                    hooks,
+		signer:                   signer,
+		stats:                    o.stats,
+		topK:                     o.topK,
+		circuits:                 o.circuits,
+		clients:                  o.clients,
+		health:                   o.health,
+		decisions:                o.decisions,
+		getResource:              getResource,
+		marshalResources:         marshalResources,
+		contentType:              contentType(endpoint, o),
+		backendAttr:              attribute.String(signalBackendAttrKey, backendHost(endpoint.Address)),
+		backendDNSMetric:         backendDNSMetric,
+		backendConnectMetric:     backendConnectMetric,
+		backendTLSMetric:         backendTLSMetric,
+		backendServerMetric:      backendServerMetric,
+		backendConnectionsMetric: backendConnectionsMetric,
+
+		backendSentBytesMetric:     backendSentBytesMetric,
+		backendReceivedBytesMetric: backendReceivedBytesMetric,
+
+		requestLimit:        ratelimit.New(endpoint.RateLimit.Requests, endpoint.RateLimit.RequestBurst),
+		byteLimit:           ratelimit.New(endpoint.RateLimit.Bytes, endpoint.RateLimit.ByteBurst),
+		rateLimitWaitMetric: rateLimitWaitMetric,
+
+		headerLimitMetric: headerLimitMetric,
 	}, nil
 }
 
+// validateStream returns an error when the endpoint streams requests but cannot do so. Streamed bodies are written
+// resource by resource, so they must be protobuf and cannot be inspected by request hooks or signed.
+func validateStream(endpoint *config.Endpoint, hooks []hook.Hook, signer *signature.Signer) error {
+	if !endpoint.Stream {
+		return nil
+	}
+	if endpoint.Encoding == config.EncodingJSON {
+		return errors.New("streaming requests requires the protobuf encoding")
+	}
+	if endpoint.Protocol != "" && endpoint.Protocol != config.ProtocolOTLP {
+		return errors.New("streaming requests requires the otlp protocol")
+	}
+	if len(hooks) > 0 {
+		return errors.New("streaming requests cannot be combined with request hooks")
+	}
+	if signer != nil {
+		return errors.New("streaming requests cannot be combined with request signing")
+	}
+	return nil
+}
+
+// contentType returns the content type of backend requests, the one of the OTLP encoding of the endpoint unless the
+// payloads are marshalled into another format.
+func contentType(endpoint *config.Endpoint, o *options) string {
+	if o.contentType != "" {
+		return o.contentType
+	}
+	return proto.ContentType(endpoint.Encoding)
+}
+
+// headers returns the configured headers of the endpoint, with the headers required by the format of the payloads.
+func headers(endpoint *config.Endpoint, o *options) http.Header {
+	header := request.ParseHeaders(endpoint.Headers)
+	for key, value := range o.headers {
+		header.Set(key, value)
+	}
+	return header
+}
+
+// aliasesByTenant indexes the tenant aliases by the tenant they rename.
+func aliasesByTenant(aliases []config.Alias) map[string]config.Alias {
+	byTenant := make(map[string]config.Alias, len(aliases))
+	for _, alias := range aliases {
+		byTenant[alias.From] = alias
+	}
+	return byTenant
+}
+
+// backendHost returns the host of the backend address, falling back to the address itself.
+func backendHost(address string) string {
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return address
+	}
+	return u.Host
+}
+
 // proxyRecordsMetricAdd adds the given count to the proxy records metric with common attributes.
 func (p *Processor[T]) proxyRecordsMetricAdd(ctx context.Context, count int64, attrs []attribute.KeyValue) {
 	p.proxyRecordsMetric.Add(ctx, count, metric.WithAttributes(attrs...))
@@ -129,121 +544,657 @@ func (p *Processor[T]) proxyLatencyMetricRecord(ctx context.Context, latency int
 	p.proxyLatencyMetric.Record(ctx, latency, metric.WithAttributes(attrs...))
 }
 
-// Partition partitions the resources by tenant.
-func (p *Processor[T]) Partition(ctx context.Context, resources []T) map[string][]T {
+// Partition partitions the resources by tenant, returning the resources without a tenant separately. It stops early
+// with the context error when the context is cancelled.
+//
+// When dispatch is ordered, the resources of each tenant keep the order of the input, including those written to the
+// tenant by an alias.
+func (p *Processor[T]) Partition(ctx context.Context, resources []T) (map[string][]T, []T, error) {
+	defer p.RecordStage(ctx, StagePartition, time.Now())
+
 	tenantMap := make(map[string][]T)
 
-	for _, resourceData := range resources {
+	// The input position of each resource, to restore the order of the tenants receiving aliased resources
+	var positions map[T]int
+	if p.config.Dispatch.Ordered {
+		positions = make(map[T]int, len(resources))
+	}
+	place := func(tenant string, resourceData T, position int) {
+		tenantMap[tenant] = append(tenantMap[tenant], resourceData)
+		if positions != nil {
+			positions[resourceData] = position
+		}
+	}
+
+	var dropped []T
+	for i, resourceData := range resources {
+		// Stop burning CPU on requests whose client has gone away
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
 		tenant := p.extractTenantFromResource(resourceData)
 		if tenant == "" {
 			logger.Warn(ctx, "No tenant found in attributes and no default tenant configured", p.signalTypeAttr)
+			dropped = append(dropped, resourceData)
 			continue
 		}
 
-		tenantMap[tenant] = append(tenantMap[tenant], resourceData)
+		tenants := p.splitTenants(tenant)
+		if len(tenants) == 1 {
+			place(tenants[0], resourceData, i)
+			continue
+		}
+
+		// The resource is shared by several tenants, so each receives its own copy
+		for _, t := range tenants {
+			place(t, p.copyForTenant(resourceData, tenant, t), i)
+		}
+		p.fanoutMetric.Add(ctx, int64(len(tenants)-1), metric.WithAttributes(p.signalTypeAttr))
 	}
 
-	return tenantMap
+	p.applyAliases(ctx, tenantMap, positions)
+	if positions != nil {
+		for _, tenantResources := range tenantMap {
+			slices.SortStableFunc(tenantResources, func(a, b T) int { return positions[a] - positions[b] })
+		}
+	}
+
+	if len(dropped) > 0 {
+		p.report(ctx, "", dropped, debug.OutcomeNoTenant)
+	}
+
+	// Record how fragmented the request is across tenants
+	p.tenantsMetric.Record(ctx, int64(len(tenantMap)), metric.WithAttributes(p.signalTypeAttr))
+	for _, tenantResources := range tenantMap {
+		p.resourcesMetric.Record(ctx, int64(len(tenantResources)), metric.WithAttributes(p.signalTypeAttr))
+	}
+
+	return tenantMap, dropped, nil
 }
 
-// Dispatch sends all the requests to the target.
+// Dispatch sends all the requests to the target. Requests not yet started when the context is cancelled are skipped
+// and the context error is returned.
+//
+// The dispatch error policy decides how a failing tenant affects the others: best-effort attempts every tenant and
+// returns the errors of all the failed tenants joined, fail-fast cancels the remaining tenants on the first error and
+// returns it, and at-least-one attempts every tenant but only returns an error when none of them succeeded. The error
+// of each tenant is prefixed with the tenant, and wrapped in ErrPartialFailure when the data of other tenants was
+// forwarded.
+//
+// Tenants are forwarded concurrently, or one at a time in the order of their names when dispatch is ordered.
 func (p *Processor[T]) Dispatch(ctx context.Context, tenantMap map[string][]T) error {
-	errGroup, ctx := errgroup.WithContext(ctx)
-	for tenant, resources := range tenantMap {
+	_, err := p.DispatchPartial(ctx, tenantMap)
+	return err
+}
+
+// Rejected describes the records of the tenants that could not be forwarded in a request accepted nonetheless.
+type Rejected struct {
+	Records int
+	Tenants []string
+}
+
+// Message describes the rejected records and their tenants.
+func (r Rejected) Message() string {
+	return fmt.Sprintf("failed to forward %d records of tenants %s", r.Records, strings.Join(r.Tenants, ", "))
+}
+
+// DispatchPartial sends all the requests to the target like Dispatch, and also returns the records of the tenants that
+// failed when the request is accepted nonetheless by the at-least-one policy.
+func (p *Processor[T]) DispatchPartial(ctx context.Context, tenantMap map[string][]T) (Rejected, error) {
+	inbound := ctx
+	errGroup := &errgroup.Group{}
+	if p.config.Dispatch.ErrorPolicy == config.DispatchFailFast {
+		errGroup, ctx = errgroup.WithContext(ctx)
+	}
+
+	var (
+		succeeded atomic.Int64
+		mu        sync.Mutex
+		rejected  Rejected
+		failures  []tenantError
+	)
+	reject := func(tenant string, resources []T) {
+		records := 0
+		for _, resource := range resources {
+			records += proto.CountRecords(resource)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		rejected.Records += records
+		rejected.Tenants = append(rejected.Tenants, tenant)
+	}
+
+	tenants := slices.Collect(maps.Keys(tenantMap))
+	if p.config.Dispatch.Ordered {
+		slices.Sort(tenants)
+		errGroup.SetLimit(1)
+	}
+
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			break
+		}
+
+		resources := tenantMap[tenant]
 		errGroup.Go(func() error {
-			sharedAttributes := []attribute.KeyValue{
-				attribute.String(signalTenantAttrKey, tenant),
-				p.signalTypeAttr,
-			}
-			statusCode, err := p.send(ctx, tenant, resources)
-			if err != nil {
-				p.proxyRecordsMetricAdd(ctx, int64(len(resources)), sharedAttributes)
-				logger.Error(ctx, err.Error(), sharedAttributes...)
-				return err
+			err := p.dispatchTenant(ctx, tenant, resources, reject)
+			if err == nil {
+				succeeded.Add(1)
+				return nil
 			}
 
-			sharedAttributes = append(sharedAttributes, attribute.String(
-				signalResponseStatusCodeAttrKey,
-				strconv.Itoa(statusCode),
-			))
+			err = fmt.Errorf("tenant %s: %w", tenant, err)
+			mu.Lock()
+			failures = append(failures, tenantError{tenant: tenant, err: err})
+			mu.Unlock()
+			return err
+		})
+	}
 
-			p.proxyRecordsMetricAdd(ctx, int64(len(resources)), sharedAttributes)
-			p.proxyRequestsMetricAdd(ctx, sharedAttributes)
+	err := errGroup.Wait()
+	if err != nil && p.config.Dispatch.ErrorPolicy != config.DispatchFailFast {
+		// Report the failure of every tenant rather than the first one, in a stable order
+		slices.SortFunc(failures, func(a, b tenantError) int { return strings.Compare(a.tenant, b.tenant) })
+		errs := make([]error, len(failures))
+		for i, failure := range failures {
+			errs[i] = failure.err
+		}
+		err = errors.Join(errs...)
+	}
+	if err != nil && succeeded.Load() > 0 {
+		err = fmt.Errorf("%w: %w", ErrPartialFailure, err)
+	}
 
-			if statusCode >= http.StatusBadRequest {
-				logger.Error(ctx, fmt.Sprintf("received non-success status code: %d", statusCode), sharedAttributes...)
-				return fmt.Errorf("received non-success status code: %d", statusCode)
-			}
+	if err != nil && p.config.Dispatch.ErrorPolicy == config.DispatchAtLeastOne && succeeded.Load() > 0 {
+		logger.Warn(ctx, "accepting request forwarded to at least one tenant", p.signalTypeAttr,
+			attribute.String(errAttrKey, err.Error()))
+		slices.Sort(rejected.Tenants)
+		return rejected, inbound.Err()
+	}
+	if err != nil {
+		return Rejected{}, err
+	}
+	return Rejected{}, inbound.Err()
+}
 
-			logger.Debug(ctx, fmt.Sprintf("sent %d records", len(resources)), sharedAttributes...)
-			logger.Trace(ctx, fmt.Sprintf("%+v", resources), sharedAttributes...)
+// tenantError is the error of a tenant whose data could not be forwarded.
+type tenantError struct {
+	tenant string
+	err    error
+}
 
-			return nil
-		})
+// dispatchTenant sends the resources of a tenant to the target, calling reject with them when they are not forwarded.
+func (p *Processor[T]) dispatchTenant(
+	ctx context.Context,
+	tenant string,
+	resources []T,
+	reject func(tenant string, resources []T),
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	sharedAttributes := []attribute.KeyValue{
+		attribute.String(signalTenantAttrKey, tenant),
+		p.signalTypeAttr,
+	}
+
+	// Never send the records of a tenant bound to a region to a backend outside of it
+	if region, ok := p.regions[tenant]; ok && region != p.endpoint.Region {
+		reject(tenant, resources)
+		p.report(ctx, tenant, resources, debug.OutcomeResidency)
+		p.residencyMetric.Add(ctx, int64(len(resources)), metric.WithAttributes(append(sharedAttributes,
+			attribute.String(signalTenantRegionAttrKey, region),
+			attribute.String(signalBackendRegionAttrKey, p.endpoint.Region),
+		)...))
+		logger.Error(ctx, "not forwarding records of a tenant to a backend outside its region", withRequest(ctx, sharedAttributes)...)
+		return ErrResidency
+	}
+
+	// Hold back the records of tenants whose circuit was opened by an operator
+	if p.circuits.IsOpen(p.signalTypeAttr.Value.AsString(), tenant) {
+		reject(tenant, resources)
+		p.report(ctx, tenant, resources, debug.OutcomeCircuitOpen)
+		logger.Warn(ctx, "not forwarding records of a tenant with an open circuit", withRequest(ctx, sharedAttributes)...)
+		return circuit.ErrOpen
 	}
 
-	return errGroup.Wait()
+	statusCode, retryAfter, err := p.sendRecords(ctx, tenant, resources)
+	if err != nil {
+		reject(tenant, resources)
+		p.report(ctx, tenant, resources, debug.OutcomeError)
+		p.proxyRecordsMetricAdd(ctx, int64(len(resources)), sharedAttributes)
+		logger.Error(ctx, err.Error(), withRequest(ctx, sharedAttributes)...)
+		return err
+	}
+
+	p.report(ctx, tenant, resources, strconv.Itoa(statusCode))
+	sharedAttributes = append(sharedAttributes, attribute.String(
+		signalResponseStatusCodeAttrKey,
+		strconv.Itoa(statusCode),
+	))
+
+	p.proxyRecordsMetricAdd(ctx, int64(len(resources)), sharedAttributes)
+	p.proxyRequestsMetricAdd(ctx, sharedAttributes)
+
+	if statusCode >= http.StatusBadRequest {
+		reject(tenant, resources)
+		err := &StatusError{StatusCode: statusCode, RetryAfter: retryAfter}
+		logger.Error(ctx, err.Error(), withRequest(ctx, sharedAttributes)...)
+		return err
+	}
+
+	logger.Debug(ctx, fmt.Sprintf("sent %d records", len(resources)), withRequest(ctx, sharedAttributes)...)
+	logger.Trace(ctx, fmt.Sprintf("%+v", resources), withRequest(ctx, sharedAttributes)...)
+
+	return nil
 }
 
-// send sends an individual request to the target.
-func (p *Processor[T]) send(ctx context.Context, tenant string, resources []T) (int, error) {
+// withRequest returns the attributes followed by those of the metadata of the inbound request carried by the
+// context, for the logs and spans attributing the data to its sender. The processor metrics leave the request
+// metadata out, as they are recorded for every tenant of every request.
+func withRequest(ctx context.Context, attrs []attribute.KeyValue) []attribute.KeyValue {
+	return append(slices.Clip(attrs), requestmeta.FromContext(ctx).Attributes()...)
+}
+
+// report adds the outcome of the resources of a tenant to the debug report of the request, and publishes it to the
+// decisions feed when sampled.
+func (p *Processor[T]) report(ctx context.Context, tenant string, resources []T, outcome string) {
+	debug.FromContext(ctx).Add(debug.Entry{
+		Signal:    p.signalTypeAttr.Value.AsString(),
+		Tenant:    tenant,
+		Resources: len(resources),
+		Outcome:   outcome,
+	})
+
+	if !p.decisions.Sample() {
+		return
+	}
+	records := 0
+	for _, resource := range resources {
+		records += proto.CountRecords(resource)
+	}
+	p.decisions.Publish(decisions.Decision{
+		Time:        time.Now(),
+		Signal:      p.signalTypeAttr.Value.AsString(),
+		Tenant:      tenant,
+		Resources:   len(resources),
+		Records:     records,
+		Destination: p.backendAttr.Value.AsString(),
+		Outcome:     outcome,
+	})
+}
+
+// sendRecords sends the resources of a tenant in requests holding at most the configured number of records each, one
+// after the other, stopping at the first request that fails or is rejected by the target.
+func (p *Processor[T]) sendRecords(
+	ctx context.Context,
+	tenant string,
+	resources []T,
+) (statusCode int, retryAfter time.Duration, err error) {
+	for _, chunk := range proto.SplitRecords(resources, p.endpoint.MaxRecords) {
+		statusCode, retryAfter, err = p.send(ctx, tenant, chunk)
+		if err != nil || statusCode >= http.StatusBadRequest {
+			return statusCode, retryAfter, err
+		}
+	}
+	return statusCode, retryAfter, nil
+}
+
+// send sends an individual request to the target, returning the response status code and the delay the backend asked
+// to wait before retrying.
+func (p *Processor[T]) send(
+	ctx context.Context,
+	tenant string,
+	resources []T,
+) (statusCode int, retryAfter time.Duration, err error) {
 	start := time.Now()
 
+	var size int
+	defer func() {
+		var records int64
+		for _, resource := range resources {
+			records += int64(proto.CountRecords(resource))
+		}
+		p.stats.Record(p.signalTypeAttr.Value.AsString(), tenant, records, int64(size),
+			err != nil || statusCode >= http.StatusBadRequest,
+		)
+		p.topK.Add(tenant, records, int64(size))
+
+		switch {
+		case errors.Is(err, ratelimit.ErrLimited), errors.Is(err, ErrHeaderLimit):
+			// The request was held back or refused by the proxy, the backend was not tried
+			p.stats.RecordError(p.signalTypeAttr.Value.AsString(), tenant, err.Error())
+		case errors.Is(err, ErrRedirect):
+			// The backend is up but redirected the request somewhere the redirect policy does not allow
+			p.stats.RecordError(p.signalTypeAttr.Value.AsString(), tenant, err.Error())
+			p.health.Success(p.signalTypeAttr.Value.AsString())
+		case err != nil:
+			p.stats.RecordError(p.signalTypeAttr.Value.AsString(), tenant, err.Error())
+			p.health.Failure(p.signalTypeAttr.Value.AsString(), err)
+		case statusCode >= http.StatusBadRequest:
+			p.stats.RecordError(p.signalTypeAttr.Value.AsString(), tenant,
+				fmt.Sprintf("backend responded with status %d", statusCode))
+			// Rejections of the data are not failures of the pipeline, the backend is up
+			if statusCode >= http.StatusInternalServerError {
+				p.health.Failure(p.signalTypeAttr.Value.AsString(), &StatusError{StatusCode: statusCode})
+			} else {
+				p.health.Success(p.signalTypeAttr.Value.AsString())
+			}
+		default:
+			p.health.Success(p.signalTypeAttr.Value.AsString())
+		}
+	}()
+
 	sharedAttributes := []attribute.KeyValue{
 		attribute.String(signalTenantAttrKey, tenant),
 		p.signalTypeAttr,
 	}
 	ctx, span := p.tracer.Start(ctx, "processor.send",
 		trace.WithAttributes(
-			append(sharedAttributes, attribute.Int(signalTenantRecordsAttrKey, len(resources)))...,
+			append(withRequest(ctx, sharedAttributes), attribute.Int(signalTenantRecordsAttrKey, len(resources)))...,
 		),
 	)
 	defer span.End()
 
-	body, err := p.marshalResources(resources)
+	client, err := p.clientFor(tenant)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "failed to marshal data")
-		return 0, fmt.Errorf("failed to marshal data: %w", err)
+		span.SetStatus(codes.Error, "failed to create tenant client")
+		return 0, 0, fmt.Errorf("failed to create tenant client: %w", err)
+	}
+
+	var (
+		body    []byte
+		reqBody io.Reader
+	)
+	streamed := func() {}
+	if p.endpoint.Stream {
+		// Marshal the resources while they are sent, the size is known once the body has been written
+		pr, pw := io.Pipe()
+		written := make(chan struct{})
+		go func() {
+			defer close(written)
+			n, err := proto.WriteResources(pw, resources)
+			size = int(n)
+			pw.CloseWithError(err)
+		}()
+		streamed = sync.OnceFunc(func() {
+			_ = pr.Close()
+			<-written
+		})
+		defer streamed()
+		reqBody = pr
+	} else {
+		marshalStart := time.Now()
+		body, err = p.marshalResources(resources)
+		p.RecordStage(ctx, StageMarshal, marshalStart)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "failed to marshal data")
+			return 0, 0, fmt.Errorf("failed to marshal data: %w", err)
+		}
+		size = len(body)
+		reqBody = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
-		p.endpoint.Address, io.NopCloser(bytes.NewReader(body)),
+	conn := &connTrace{}
+	var sent atomic.Int64
+	address := request.ExpandURL(p.endpoint.Address, tenant, p.signalTypeAttr.Value.AsString(), p.config)
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, conn.clientTrace()), http.MethodPost,
+		address, io.NopCloser(&countingReader{Reader: reqBody, n: &sent}),
 	)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to create request")
-		return 0, fmt.Errorf("failed to create request: %w", err)
+		return 0, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if !p.endpoint.Stream {
+		// Let the client send the body again when the backend redirects the request with 307 or 308
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(&countingReader{Reader: bytes.NewReader(body), n: &sent}), nil
+		}
+	}
+
+	request.AddHeaders(ctx, tenant, req, p.config, p.headers)
+	req.Header.Set("Content-Type", p.contentType)
+
+	// Add the extra headers of the tenant, replacing the custom headers of the same name
+	for key, values := range p.tenantHeaders[tenant] {
+		req.Header[key] = slices.Clone(values)
 	}
 
-	request.AddHeaders(ctx, tenant, req, p.config, p.endpoint.Headers)
+	for _, h := range p.hooks {
+		if err := h.Mutate(req, body); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "request hook failed")
+			return 0, 0, fmt.Errorf("request hook failed: %w", err)
+		}
+	}
+
+	// Sign the body with the secret of the tenant, once the hooks have set the other headers
+	p.signer.Sign(req.Header, tenant, body)
+
+	// Refuse the request when its headers exceed the header limits of the backend
+	if err := checkHeaderLimit(&p.endpoint.HeaderLimit, req.Header); err != nil {
+		p.headerLimitMetric.Add(ctx, 1, metric.WithAttributes(append(sharedAttributes, p.backendAttr)...))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "outbound header limit exceeded")
+		return 0, 0, err
+	}
+
+	// Hold the request back until the outbound rate limits of the backend allow it
+	if err := p.waitRateLimit(ctx, tenant, len(body)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "outbound rate limit exceeded")
+		return 0, 0, fmt.Errorf("%w: %w", ErrThrottled, err)
+	}
 
-	resp, err := p.client.Do(req)
+	sendStart := time.Now()
+	resp, err := client.Do(req)
+	streamed()
+	if p.endpoint.Stream {
+		p.byteLimit.Charge(float64(size))
+	}
+	p.RecordStage(ctx, StageSend, sendStart)
 	if err != nil {
+		p.recordBandwidth(ctx, tenant, sent.Load(), 0)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "failed to send")
-		return 0, fmt.Errorf("failed to send request: %w", err)
+		p.proxyLatencyMetricRecord(ctx, time.Since(start).Milliseconds(), append(sharedAttributes,
+			attribute.String(signalResponseStatusClassAttrKey, statusClass(0, err)),
+			p.backendAttr,
+		))
+		if (ctx.Err() != nil && errors.Is(err, context.Canceled)) || errors.Is(err, ErrRedirect) {
+			return 0, 0, fmt.Errorf("failed to send request: %w", err)
+		}
+		return 0, 0, fmt.Errorf("%w: failed to send request: %w", ErrBackendUnavailable, err)
 	}
 
+	// Drain the response so its size can be accounted for and the connection reused
 	defer func() {
+		received, _ := io.Copy(io.Discard, resp.Body)
+		p.recordBandwidth(ctx, tenant, sent.Load(), received)
 		if closeErr := resp.Body.Close(); closeErr != nil {
 			span.RecordError(closeErr)
 		}
 	}()
 
+	p.recordConnTrace(ctx, conn, sharedAttributes)
+
 	statusCodeAttr := attribute.String(signalResponseStatusCodeAttrKey, strconv.Itoa(resp.StatusCode))
 	span.SetAttributes(statusCodeAttr)
 	sharedAttributes = append(sharedAttributes, statusCodeAttr)
 
-	if resp.StatusCode >= http.StatusBadRequest {
+	if resp.StatusCode >= http.StatusMultipleChoices {
 		span.SetStatus(codes.Error, fmt.Sprintf("non-success status: %d", resp.StatusCode))
 	} else {
 		span.SetStatus(codes.Ok, "sent successfully")
 	}
 
-	p.proxyLatencyMetricRecord(ctx, time.Since(start).Milliseconds(), sharedAttributes)
+	p.proxyLatencyMetricRecord(ctx, time.Since(start).Milliseconds(), append(slices.Clip(sharedAttributes),
+		attribute.String(signalResponseStatusClassAttrKey, statusClass(resp.StatusCode, nil)),
+		p.backendAttr,
+	))
+
+	// The data was not delivered when the client did not follow a redirect, such as a 307 of a streamed body
+	if resp.StatusCode >= http.StatusMultipleChoices && resp.StatusCode < http.StatusBadRequest {
+		return resp.StatusCode, 0, fmt.Errorf("%w: status %d to %q", ErrRedirect, resp.StatusCode,
+			resp.Header.Get("Location"))
+	}
+	p.proxyBytesMetric.Add(ctx, int64(size), metric.WithAttributes(sharedAttributes...))
+
+	return resp.StatusCode, parseRetryAfter(resp, time.Now()), nil
+}
 
-	return resp.StatusCode, nil
+// clientFor returns the client sending the data of the tenant: a pooled client presenting the tenant's own certificate
+// when one exists in the tenant certificate directory, the shared client otherwise.
+func (p *Processor[T]) clientFor(tenant string) (Client, error) {
+	if p.clients == nil {
+		return p.client, nil
+	}
+
+	certFile, keyFile, ok := cert.TenantCertFiles(&p.endpoint.TLS, tenant)
+	if !ok {
+		return p.client, nil
+	}
+
+	// Key the client by the certificate modification time as well, so rotated certificates are picked up
+	info, err := os.Stat(certFile)
+	if err != nil {
+		return nil, err
+	}
+	key := p.endpoint.Address + "\x00" + certFile + "\x00" + info.ModTime().String()
+
+	client, err := p.clients.Get(key, func() (*http.Client, error) {
+		tlsConfig, err := cert.CreateTenantTLSConfig(&p.endpoint.TLS, certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		transport.DialContext = ipfamily.DialContext(p.endpoint)
+		return &http.Client{
+			Timeout:       p.endpoint.Timeout,
+			Transport:     transport,
+			CheckRedirect: CheckRedirect(p.endpoint.Redirect),
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// parseRetryAfter returns the delay of the Retry-After header of a throttled or unavailable response, given in seconds
+// or as an HTTP date, or zero when there is none.
+func parseRetryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// statusClass returns the outcome class of a backend request: the class of the response status code such as 2xx or
+// 5xx, timeout when the request timed out, or error when it failed without a response.
+func statusClass(statusCode int, err error) string {
+	if err != nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return "timeout"
+		}
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
+
+// applyAliases writes the resources of renamed tenants to their new tenant, keeping them on the old tenant as well
+// while the migration is in progress. When positions are given, the aliases are applied in the order of their tenant
+// and each copy takes the position of its original.
+func (p *Processor[T]) applyAliases(ctx context.Context, tenantMap map[string][]T, positions map[T]int) {
+	froms := slices.Collect(maps.Keys(p.aliases))
+	if positions != nil {
+		slices.Sort(froms)
+	}
+
+	now := time.Now()
+	for _, from := range froms {
+		alias := p.aliases[from]
+		resources, ok := tenantMap[from]
+		if !ok {
+			continue
+		}
+
+		for _, resourceData := range resources {
+			clone := p.copyForTenant(resourceData, from, alias.To)
+			tenantMap[alias.To] = append(tenantMap[alias.To], clone)
+			if positions != nil {
+				positions[clone] = positions[resourceData]
+			}
+		}
+
+		mode := "duplicate"
+		if !alias.DualWrite(now) {
+			mode = "rewrite"
+			delete(tenantMap, from)
+		}
+
+		p.aliasMetric.Add(ctx, int64(len(resources)), metric.WithAttributes(
+			p.signalTypeAttr,
+			attribute.String(signalTenantAttrKey, from),
+			attribute.String(signalTenantAliasAttrKey, alias.To),
+			attribute.String(signalAliasModeAttrKey, mode),
+		))
+	}
+}
+
+// splitTenants splits a tenant value holding several tenants on the configured delimiter,
+// dropping empty and duplicate entries. Without a delimiter the tenant is returned as is.
+func (p *Processor[T]) splitTenants(tenant string) []string {
+	if p.config.Tenant.Delimiter == "" || !strings.Contains(tenant, p.config.Tenant.Delimiter) {
+		return []string{tenant}
+	}
+
+	var tenants []string
+	for t := range strings.SplitSeq(tenant, p.config.Tenant.Delimiter) {
+		t = strings.TrimSpace(t)
+		if t != "" && !slices.Contains(tenants, t) {
+			tenants = append(tenants, t)
+		}
+	}
+	if len(tenants) == 0 {
+		return []string{tenant}
+	}
+	return tenants
+}
+
+// copyForTenant clones the resource data for a single tenant of a shared resource, replacing
+// the tenant attribute holding the list so the backend does not see the other tenants.
+func (p *Processor[T]) copyForTenant(resourceData T, shared, tenant string) T {
+	clone := protobuf.Clone(resourceData).(T)
+	for _, attr := range p.getResource(clone).GetAttributes() {
+		if attr.GetKey() != p.config.Tenant.Label && !slices.Contains(p.config.Tenant.Labels, attr.GetKey()) {
+			continue
+		}
+		if attr.GetValue().GetStringValue() == shared {
+			attr.Value = &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}}
+		}
+	}
+	return clone
 }
 
 // extractTenantFromResource extracts the tenant information from the resource attributes
@@ -3,21 +3,50 @@ package processor
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
+	"maps"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clientpool"
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/debug"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/hook"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/requestmeta"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/signature"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/stats"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/topk"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	nooptrace "go.opentelemetry.io/otel/trace/noop"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	"go.uber.org/mock/gomock"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 var signalTypeAttrKey = "signal.type"
@@ -71,6 +100,66 @@ func TestNew(t *testing.T) {
 			client:  &http.Client{},
 			wantErr: false,
 		},
+		{
+			name: "negative max records",
+			config: &config.Config{
+				Tenant: config.Tenant{
+					Label:   "tenant.id",
+					Default: "default",
+				},
+			},
+			endpoint: &config.Endpoint{
+				Address:    "http://localhost:3100",
+				MaxRecords: -1,
+			},
+			signalTypeAttr: attribute.KeyValue{
+				Key:   attribute.Key(string(signalTypeAttrKey)),
+				Value: attribute.StringValue("logs"),
+			},
+			client:      &http.Client{},
+			wantErr:     true,
+			errContains: "invalid max records",
+		},
+		{
+			name: "invalid redirect policy",
+			config: &config.Config{
+				Tenant: config.Tenant{
+					Label:   "tenant.id",
+					Default: "default",
+				},
+			},
+			endpoint: &config.Endpoint{
+				Address:  "http://localhost:3100",
+				Redirect: "always",
+			},
+			signalTypeAttr: attribute.KeyValue{
+				Key:   attribute.Key(string(signalTypeAttrKey)),
+				Value: attribute.StringValue("logs"),
+			},
+			client:      &http.Client{},
+			wantErr:     true,
+			errContains: "invalid redirect policy",
+		},
+		{
+			name: "invalid ip family",
+			config: &config.Config{
+				Tenant: config.Tenant{
+					Label:   "tenant.id",
+					Default: "default",
+				},
+			},
+			endpoint: &config.Endpoint{
+				Address:  "http://[::1]:3100",
+				IPFamily: "ipv5",
+			},
+			signalTypeAttr: attribute.KeyValue{
+				Key:   attribute.Key(string(signalTypeAttrKey)),
+				Value: attribute.StringValue("logs"),
+			},
+			client:      &http.Client{},
+			wantErr:     true,
+			errContains: "invalid ip family",
+		},
 	}
 
 	for _, tt := range tests {
@@ -116,6 +205,44 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNew_CustomClientWithTLSEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// TLS transport configuration is owned by the caller that builds the client, so
+	// any Client implementation must be accepted regardless of the endpoint TLS settings.
+	mockClient := NewMockClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString("ok")),
+	}, nil).Times(1)
+
+	proc, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"}},
+		&config.Endpoint{
+			Address: "https://localhost:3100",
+			TLS: config.TLSConfig{
+				CertFile: "/certs/client.crt",
+				KeyFile:  "/certs/client.key",
+				CAFile:   "/certs/ca.crt",
+			},
+		},
+		attribute.String(signalTypeAttrKey, "logs"),
+		mockClient,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("marshaled"), nil },
+	)
+	require.NoError(t, err)
+	require.NotNil(t, proc)
+
+	err = proc.Dispatch(context.Background(), map[string][]*logpb.ResourceLogs{
+		"tenant-a": {{Resource: &resourcepb.Resource{}}},
+	})
+	assert.NoError(t, err)
+}
+
 func TestExtractTenantFromResource(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -316,6 +443,7 @@ func TestPartition(t *testing.T) {
 		resources       []*logpb.ResourceLogs
 		config          *config.Config
 		expectedTenants map[string]int // tenant -> number of resources
+		expectedDropped int
 	}{
 		{
 			name:      "empty resources returns empty map",
@@ -449,6 +577,7 @@ func TestPartition(t *testing.T) {
 				"tenant-a": 1,
 				"tenant-b": 1,
 			},
+			expectedDropped: 1,
 		},
 		{
 			name: "resources with default tenant are grouped",
@@ -478,6 +607,57 @@ func TestPartition(t *testing.T) {
 				"shared": 2,
 			},
 		},
+		{
+			name: "shared resources are copied to each tenant",
+			resources: []*logpb.ResourceLogs{
+				{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "tenant-a, tenant-b,tenant-a,"}}},
+						},
+					},
+				},
+				{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "tenant-b"}}},
+						},
+					},
+				},
+			},
+			config: &config.Config{
+				Tenant: config.Tenant{
+					Label:     "tenant.id",
+					Default:   "default",
+					Delimiter: ",",
+				},
+			},
+			expectedTenants: map[string]int{
+				"tenant-a": 1,
+				"tenant-b": 2,
+			},
+		},
+		{
+			name: "delimited tenants are kept whole without a delimiter",
+			resources: []*logpb.ResourceLogs{
+				{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "tenant-a,tenant-b"}}},
+						},
+					},
+				},
+			},
+			config: &config.Config{
+				Tenant: config.Tenant{
+					Label:   "tenant.id",
+					Default: "default",
+				},
+			},
+			expectedTenants: map[string]int{
+				"tenant-a,tenant-b": 1,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -504,9 +684,11 @@ func TestPartition(t *testing.T) {
 			)
 			require.NoError(t, err)
 
-			result := proc.Partition(context.Background(), tt.resources)
+			result, dropped, err := proc.Partition(context.Background(), tt.resources)
+			require.NoError(t, err)
 
 			assert.Equal(t, len(tt.expectedTenants), len(result), "unexpected number of tenants")
+			assert.Len(t, dropped, tt.expectedDropped, "unexpected number of resources without tenant")
 
 			for tenant, expectedCount := range tt.expectedTenants {
 				resources, ok := result[tenant]
@@ -517,6 +699,519 @@ func TestPartition(t *testing.T) {
 	}
 }
 
+func TestPartition_FanOut(t *testing.T) {
+	proc, err := New(
+		&config.Config{
+			Tenant: config.Tenant{
+				Label:     "tenant.id",
+				Labels:    []string{"tenant_id"},
+				Delimiter: "|",
+			},
+		},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.KeyValue{Key: attribute.Key(string(signalTypeAttrKey)), Value: attribute.StringValue("logs")},
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	shared := &logpb.ResourceLogs{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{
+				{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "node-exporter"}}},
+				{Key: "tenant_id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "team-a|team-b"}}},
+			},
+		},
+	}
+
+	result, dropped, err := proc.Partition(context.Background(), []*logpb.ResourceLogs{shared})
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+	require.Len(t, result, 2)
+
+	for _, tenant := range []string{"team-a", "team-b"} {
+		require.Len(t, result[tenant], 1)
+		attrs := result[tenant][0].GetResource().GetAttributes()
+		assert.Equal(t, "node-exporter", attrs[0].GetValue().GetStringValue())
+		assert.Equal(t, tenant, attrs[1].GetValue().GetStringValue(), "tenant attribute should only name its own tenant")
+	}
+
+	// The inbound resource is left untouched
+	assert.Equal(t, "team-a|team-b", shared.GetResource().GetAttributes()[1].GetValue().GetStringValue())
+}
+
+func TestPartition_Aliases(t *testing.T) {
+	tests := []struct {
+		name            string
+		alias           string
+		expectedTenants []string
+	}{
+		{
+			name:            "dual-writes during the migration",
+			alias:           "old-team=new-team@" + time.Now().Add(time.Hour).Format(time.RFC3339),
+			expectedTenants: []string{"new-team", "old-team", "other"},
+		},
+		{
+			name:            "dual-writes without an end time",
+			alias:           "old-team=new-team",
+			expectedTenants: []string{"new-team", "old-team", "other"},
+		},
+		{
+			name:            "rewrites after the migration",
+			alias:           "old-team=new-team@" + time.Now().Add(-time.Hour).Format(time.RFC3339),
+			expectedTenants: []string{"new-team", "other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proc, err := New(
+				&config.Config{
+					Tenant: config.Tenant{Label: "tenant.id", Aliases: []string{tt.alias}},
+				},
+				&config.Endpoint{Address: "http://localhost:3100"},
+				attribute.KeyValue{Key: attribute.Key(string(signalTypeAttrKey)), Value: attribute.StringValue("logs")},
+				&http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			require.NoError(t, err)
+
+			resources := []*logpb.ResourceLogs{
+				{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "old-team"}}},
+						},
+					},
+				},
+				{
+					Resource: &resourcepb.Resource{
+						Attributes: []*commonpb.KeyValue{
+							{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "other"}}},
+						},
+					},
+				},
+			}
+
+			result, _, err := proc.Partition(context.Background(), resources)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectedTenants, slices.Collect(maps.Keys(result)))
+
+			require.Len(t, result["new-team"], 1)
+			assert.Equal(t, "new-team", result["new-team"][0].GetResource().GetAttributes()[0].GetValue().GetStringValue())
+			if old, ok := result["old-team"]; ok {
+				assert.Equal(t, "old-team", old[0].GetResource().GetAttributes()[0].GetValue().GetStringValue())
+			}
+		})
+	}
+}
+
+func TestPartition_Ordered(t *testing.T) {
+	proc, err := New(
+		&config.Config{
+			Tenant:   config.Tenant{Label: "tenant.id", Aliases: []string{"team-b=team-a", "team-c=team-a"}},
+			Dispatch: config.Dispatch{Ordered: true},
+		},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	resource := func(tenant, service string) *logpb.ResourceLogs {
+		return &logpb.ResourceLogs{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{
+					{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}}},
+					{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: service}}},
+				},
+			},
+		}
+	}
+	services := func(resources []*logpb.ResourceLogs) []string {
+		var names []string
+		for _, resourceData := range resources {
+			names = append(names, resourceData.GetResource().GetAttributes()[1].GetValue().GetStringValue())
+		}
+		return names
+	}
+
+	result, _, err := proc.Partition(context.Background(), []*logpb.ResourceLogs{
+		resource("team-c", "first"),
+		resource("team-a", "second"),
+		resource("team-b", "third"),
+		resource("team-a", "fourth"),
+	})
+	require.NoError(t, err)
+
+	// The resources written by the aliases are interleaved with those of the tenant in the input order
+	assert.Equal(t, []string{"first", "second", "third", "fourth"}, services(result["team-a"]))
+	assert.Equal(t, []string{"third"}, services(result["team-b"]))
+	assert.Equal(t, []string{"first"}, services(result["team-c"]))
+}
+
+func TestNew_InvalidAlias(t *testing.T) {
+	_, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id", Aliases: []string{"old-team"}}},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.KeyValue{Key: attribute.Key(string(signalTypeAttrKey)), Value: attribute.StringValue("logs")},
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	assert.Error(t, err)
+}
+
+func TestPartition_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel the context while the third resource is being partitioned
+	var partitioned int
+	proc, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id", Default: "default"}},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource {
+			partitioned++
+			if partitioned == 3 {
+				cancel()
+			}
+			return rl.GetResource()
+		},
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	resources := make([]*logpb.ResourceLogs, 100)
+	for i := range resources {
+		resources[i] = &logpb.ResourceLogs{Resource: &resourcepb.Resource{}}
+	}
+
+	result, dropped, err := proc.Partition(ctx, resources)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+	assert.Nil(t, dropped)
+	assert.Equal(t, 3, partitioned, "partitioning should stop after the context is cancelled")
+}
+
+func TestDispatch_Cancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	// The client has no expectations, so any request sent fails the test
+	proc, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id"}},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		NewMockClient(ctrl),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = proc.Dispatch(ctx, map[string][]*logpb.ResourceLogs{
+		"tenant-a": {{}},
+		"tenant-b": {{}},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDispatch_ErrorPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		failing   []string
+		wantErr   bool
+		wantSent  []string
+		cancelled bool
+	}{
+		{
+			name:     "best-effort attempts every tenant",
+			policy:   config.DispatchBestEffort,
+			failing:  []string{"tenant-a"},
+			wantErr:  true,
+			wantSent: []string{"tenant-a", "tenant-b", "tenant-c"},
+		},
+		{
+			name:     "at-least-one accepts a partial success",
+			policy:   config.DispatchAtLeastOne,
+			failing:  []string{"tenant-a", "tenant-b"},
+			wantSent: []string{"tenant-a", "tenant-b", "tenant-c"},
+		},
+		{
+			name:     "at-least-one fails when every tenant fails",
+			policy:   config.DispatchAtLeastOne,
+			failing:  []string{"tenant-a", "tenant-b", "tenant-c"},
+			wantErr:  true,
+			wantSent: []string{"tenant-a", "tenant-b", "tenant-c"},
+		},
+		{
+			name:      "fail-fast cancels the remaining tenants",
+			policy:    config.DispatchFailFast,
+			failing:   []string{"tenant-a"},
+			wantErr:   true,
+			cancelled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				sent []string
+			)
+			unblock := make(chan struct{})
+			defer close(unblock)
+
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				tenant := req.Header.Get("X-Scope-OrgID")
+				mu.Lock()
+				sent = append(sent, tenant)
+				mu.Unlock()
+
+				if slices.Contains(tt.failing, tenant) {
+					return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+				}
+				if tt.cancelled {
+					// Block until the failing tenant cancels the request
+					select {
+					case <-req.Context().Done():
+						return nil, req.Context().Err()
+					case <-unblock:
+					}
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).AnyTimes()
+
+			proc, err := New(
+				&config.Config{
+					Tenant:   config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+					Dispatch: config.Dispatch{ErrorPolicy: tt.policy},
+				},
+				&config.Endpoint{Address: "http://localhost:3100"},
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			require.NoError(t, err)
+
+			err = proc.Dispatch(context.Background(), map[string][]*logpb.ResourceLogs{
+				"tenant-a": {{}},
+				"tenant-b": {{}},
+				"tenant-c": {{}},
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.wantSent != nil {
+				mu.Lock()
+				assert.ElementsMatch(t, tt.wantSent, sent)
+				mu.Unlock()
+			}
+		})
+	}
+}
+
+func TestDispatch_Ordered(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		failing  string
+		wantSent []string
+	}{
+		{
+			name:     "forwards the tenants in order",
+			policy:   config.DispatchBestEffort,
+			wantSent: []string{"tenant-a", "tenant-b", "tenant-c"},
+		},
+		{
+			name:     "best-effort forwards the tenants after a failure",
+			policy:   config.DispatchBestEffort,
+			failing:  "tenant-b",
+			wantSent: []string{"tenant-a", "tenant-b", "tenant-c"},
+		},
+		{
+			name:     "fail-fast stops at the first failure",
+			policy:   config.DispatchFailFast,
+			failing:  "tenant-b",
+			wantSent: []string{"tenant-a", "tenant-b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				tenant := req.Header.Get("X-Scope-OrgID")
+				sent = append(sent, tenant)
+				if tenant == tt.failing {
+					return &http.Response{StatusCode: http.StatusUnauthorized, Body: http.NoBody}, nil
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).AnyTimes()
+
+			proc, err := New(
+				&config.Config{
+					Tenant:   config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+					Dispatch: config.Dispatch{ErrorPolicy: tt.policy, Ordered: true},
+				},
+				&config.Endpoint{Address: "http://localhost:3100"},
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			require.NoError(t, err)
+
+			err = proc.Dispatch(context.Background(), map[string][]*logpb.ResourceLogs{
+				"tenant-c": {{}},
+				"tenant-a": {{}},
+				"tenant-b": {{}},
+			})
+			assert.Equal(t, tt.failing != "", err != nil)
+			assert.Equal(t, tt.wantSent, sent)
+		})
+	}
+}
+
+func TestDispatchPartial(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Scope-OrgID") == "tenant-a" {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	}).Times(3)
+
+	proc, err := New(
+		&config.Config{
+			Tenant:   config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+			Dispatch: config.Dispatch{ErrorPolicy: config.DispatchAtLeastOne},
+		},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		client,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	records := func(n int) []*logpb.ResourceLogs {
+		return []*logpb.ResourceLogs{{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: make([]*logpb.LogRecord, n)}}}}
+	}
+	rejected, err := proc.DispatchPartial(context.Background(), map[string][]*logpb.ResourceLogs{
+		"tenant-a": records(1),
+		"tenant-c": records(3),
+		"tenant-b": records(2),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Rejected{Records: 5, Tenants: []string{"tenant-b", "tenant-c"}}, rejected)
+	assert.Equal(t, "failed to forward 5 records of tenants tenant-b, tenant-c", rejected.Message())
+}
+
+func TestDispatch_Residency(t *testing.T) {
+	tests := []struct {
+		name          string
+		backendRegion string
+		tenant        string
+		wantSent      bool
+		wantRefused   int64
+	}{
+		{name: "tenant in the backend region", backendRegion: "eu-west-1", tenant: "acme", wantSent: true},
+		{name: "tenant without a region", backendRegion: "eu-west-1", tenant: "globex", wantSent: true},
+		{name: "tenant in another region", backendRegion: "us-east-1", tenant: "acme", wantRefused: 2},
+		{name: "backend without a region", tenant: "acme", wantRefused: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			if tt.wantSent {
+				client.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil)
+			}
+
+			reader := sdkmetric.NewManualReader()
+			proc, err := New(
+				&config.Config{Tenant: config.Tenant{
+					Label:   "tenant.id",
+					Header:  "X-Scope-OrgID",
+					Regions: []string{"acme=eu-west-1"},
+				}},
+				&config.Endpoint{Address: "http://localhost:3100", Region: tt.backendRegion},
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			require.NoError(t, err)
+
+			ctx := context.Background()
+			err = proc.Dispatch(ctx, map[string][]*logpb.ResourceLogs{tt.tenant: {{}, {}}})
+			if tt.wantSent {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrResidency)
+			}
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(ctx, &rm))
+
+			var refused int64
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if m.Name != "otel_lgtm_proxy_residency_violations_total" {
+						continue
+					}
+					for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						region, _ := dp.Attributes.Value(attribute.Key(signalTenantRegionAttrKey))
+						assert.Equal(t, "eu-west-1", region.AsString())
+						refused += dp.Value
+					}
+				}
+			}
+			assert.Equal(t, tt.wantRefused, refused)
+		})
+	}
+}
+
 func TestDispatch(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -762,6 +1457,58 @@ func TestDispatch(t *testing.T) {
 	}
 }
 
+func TestDispatch_DebugReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := NewMockClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("X-Scope-OrgID") == "tenant-b" {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusAccepted, Body: io.NopCloser(bytes.NewBufferString(""))}, nil
+	}).Times(2)
+
+	proc, err := New(
+		&config.Config{
+			Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID", Format: "%s"},
+		},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		mockClient,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func(resources []*logpb.ResourceLogs) ([]byte, error) { return []byte("marshaled"), nil },
+	)
+	require.NoError(t, err)
+
+	resource := func(tenant string) *logpb.ResourceLogs {
+		var attributes []*commonpb.KeyValue
+		if tenant != "" {
+			attributes = append(attributes, &commonpb.KeyValue{
+				Key:   "tenant.id",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}},
+			})
+		}
+		return &logpb.ResourceLogs{Resource: &resourcepb.Resource{Attributes: attributes}}
+	}
+
+	report := &debug.Report{}
+	ctx := debug.NewContext(t.Context(), report)
+	tenantMap, _, err := proc.Partition(ctx, []*logpb.ResourceLogs{
+		resource("tenant-a"), resource("tenant-a"), resource("tenant-b"), resource(""),
+	})
+	require.NoError(t, err)
+	assert.Error(t, proc.Dispatch(ctx, tenantMap))
+
+	assert.Equal(t, []debug.Entry{
+		{Signal: "logs", Resources: 1, Outcome: debug.OutcomeNoTenant},
+		{Signal: "logs", Tenant: "tenant-a", Resources: 2, Outcome: "202"},
+		{Signal: "logs", Tenant: "tenant-b", Resources: 1, Outcome: debug.OutcomeError},
+	}, report.Entries())
+}
+
 func TestSend(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -888,7 +1635,7 @@ func TestSend(t *testing.T) {
 			)
 			require.NoError(t, err)
 
-			statusCode, err := proc.send(context.Background(), tt.tenant, tt.resources)
+			statusCode, _, err := proc.send(context.Background(), tt.tenant, tt.resources)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -902,3 +1649,691 @@ func TestSend(t *testing.T) {
 		})
 	}
 }
+
+func TestSend_ExpandsURLTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "http://backend:8080/logs/push/org-tenant-a", req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	cfg := &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Format: "org-%s", Header: "X-Scope-OrgID"},
+		Logs:   config.Endpoint{Address: "http://backend:8080/{signal}/push/{tenant}"},
+	}
+	proc, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		client,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("test"), nil },
+	)
+	require.NoError(t, err)
+
+	statusCode, _, err := proc.send(context.Background(), "tenant-a", []*logpb.ResourceLogs{{}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+}
+
+func TestSend_TenantHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, []string{"90d"}, req.Header.Values("X-Retention"))
+		assert.Equal(t, "hot", req.Header.Get("X-Tier"))
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		assert.Equal(t, "tenant-a", req.Header.Get("X-Scope-OrgID"))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	cfg := &config.Config{
+		Tenant: config.Tenant{
+			Label:  "tenant.id",
+			Format: "%s",
+			Header: "X-Scope-OrgID",
+			Headers: []string{
+				"tenant-a:X-Tier=hot",
+				"tenant-a:logs:X-Retention=90d",
+				"tenant-a:traces:X-Retention=7d",
+				"tenant-b:X-Tier=cold",
+			},
+		},
+		Logs: config.Endpoint{
+			Address: "http://backend:8080",
+			Headers: "Authorization=Bearer token,X-Retention=30d",
+		},
+	}
+	proc, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		client,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("test"), nil },
+	)
+	require.NoError(t, err)
+
+	_, _, err = proc.send(context.Background(), "tenant-a", []*logpb.ResourceLogs{{}})
+	require.NoError(t, err)
+}
+
+func TestSendRecords(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantStatus   int
+		wantRequests []int
+		wantRecords  int64
+	}{
+		{
+			name:         "sends every chunk",
+			statuses:     []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantRequests: []int{2, 2, 1},
+			wantRecords:  5,
+		},
+		{
+			name:         "stops at the first rejected chunk",
+			statuses:     []int{http.StatusOK, http.StatusBadRequest},
+			wantStatus:   http.StatusBadRequest,
+			wantRequests: []int{2, 2},
+			wantRecords:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []int
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				body, err := io.ReadAll(req.Body)
+				require.NoError(t, err)
+				records, err := strconv.Atoi(string(body))
+				require.NoError(t, err)
+				requests = append(requests, records)
+				return &http.Response{StatusCode: tt.statuses[len(requests)-1], Body: http.NoBody}, nil
+			}).Times(len(tt.wantRequests))
+
+			cfg := &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Logs:   config.Endpoint{Address: "http://backend:8080", MaxRecords: 2},
+			}
+			tracker := stats.New("test")
+			top := topk.New(&config.TopK{Size: 1, Window: time.Minute, Buckets: 1})
+			proc, err := New(
+				cfg,
+				&cfg.Logs,
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func(resources []*logpb.ResourceLogs) ([]byte, error) {
+					records := 0
+					for _, rl := range resources {
+						records += proto.CountRecords(rl)
+					}
+					return []byte(strconv.Itoa(records)), nil
+				},
+				WithStats(tracker),
+				WithTopK(top),
+			)
+			require.NoError(t, err)
+
+			// A single resource holding more records than fit in a request
+			records := []*logpb.LogRecord{{}, {}, {}, {}, {}}
+			resources := []*logpb.ResourceLogs{{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: records}}}}
+
+			statusCode, _, err := proc.sendRecords(context.Background(), "tenant-a", resources)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, statusCode)
+			assert.Equal(t, tt.wantRequests, requests)
+
+			counters := tracker.Counters()
+			require.Len(t, counters, 1)
+			assert.Equal(t, tt.wantRecords, counters[0].Records)
+			assert.Equal(t, uint64(tt.wantRecords), top.Top()[0].Records)
+		})
+	}
+}
+
+func TestSend_Stream(t *testing.T) {
+	var (
+		received         []byte
+		transferEncoding []string
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		transferEncoding = r.TransferEncoding
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	cfg := &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+		Logs:   config.Endpoint{Address: backend.URL, Stream: true},
+	}
+	proc, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		backend.Client(),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return nil, errors.New("buffered marshal used") },
+	)
+	require.NoError(t, err)
+
+	resources := []*logpb.ResourceLogs{
+		{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{
+					{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "tenant-a"}}},
+				},
+			},
+		},
+		{SchemaUrl: "https://opentelemetry.io/schemas/1.21.0"},
+	}
+
+	statusCode, _, err := proc.send(context.Background(), "tenant-a", resources)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	assert.Equal(t, []string{"chunked"}, transferEncoding)
+
+	got := &logpb.LogsData{}
+	require.NoError(t, protobuf.Unmarshal(received, got))
+	assert.True(t, protobuf.Equal(&logpb.LogsData{ResourceLogs: resources}, got))
+}
+
+func TestSend_TenantCertificate(t *testing.T) {
+	var commonName string
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonName = ""
+		if len(r.TLS.PeerCertificates) > 0 {
+			commonName = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	backend.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	backend.StartTLS()
+	defer backend.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}), 0o600))
+	writeTenantCertificate(t, dir, "tenant-a")
+
+	cfg := &config.Config{
+		Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"},
+		Logs: config.Endpoint{
+			Address: backend.URL,
+			TLS:     config.TLSConfig{CAFile: caFile, TenantCertDir: dir},
+		},
+	}
+	clients := clientpool.New(8, 0)
+	proc, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		backend.Client(),
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+		WithClientPool(clients),
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		tenant         string
+		wantCommonName string
+		wantClients    int
+	}{
+		{tenant: "tenant-a", wantCommonName: "tenant-a", wantClients: 1},
+		{tenant: "tenant-b", wantCommonName: "", wantClients: 1},
+		{tenant: "tenant-a", wantCommonName: "tenant-a", wantClients: 1},
+	}
+
+	for _, tt := range tests {
+		statusCode, _, err := proc.send(context.Background(), tt.tenant, []*logpb.ResourceLogs{{}})
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, statusCode)
+		assert.Equal(t, tt.wantCommonName, commonName, tt.tenant)
+		assert.Equal(t, tt.wantClients, clients.Len(), tt.tenant)
+	}
+}
+
+// writeTenantCertificate writes a self-signed client certificate and key for the tenant into dir.
+func writeTenantCertificate(t *testing.T, dir, tenant string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: tenant},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tenant+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tenant+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestNew_InvalidStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint config.Endpoint
+	}{
+		{
+			name:     "json encoding",
+			endpoint: config.Endpoint{Address: "http://localhost:3100", Stream: true, Encoding: config.EncodingJSON},
+		},
+		{
+			name:     "request hooks",
+			endpoint: config.Endpoint{Address: "http://localhost:3100", Stream: true, Hooks: []string{"content-sha256"}},
+		},
+		{
+			name:     "loki push protocol",
+			endpoint: config.Endpoint{Address: "http://localhost:3100", Stream: true, Protocol: config.ProtocolLokiPush},
+		},
+		{
+			name:     "remote write protocol",
+			endpoint: config.Endpoint{Address: "http://localhost:9009", Stream: true, Protocol: config.ProtocolRemoteWrite},
+		},
+		{
+			name: "request signing",
+			endpoint: config.Endpoint{
+				Address: "http://localhost:3100",
+				Stream:  true,
+				Signing: config.Signing{Secrets: []string{"tenant-a=secret"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(
+				&config.Config{Tenant: config.Tenant{Label: "tenant.id"}},
+				&tt.endpoint,
+				attribute.String(signalTypeAttrKey, "logs"),
+				&http.Client{},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSend_RequestHooks(t *testing.T) {
+	errHook := errors.New("signing failed")
+	hook.Register("test-signer", hook.Func(func(req *http.Request, body []byte) error {
+		req.Header.Set("X-Signature", string(body))
+		return nil
+	}))
+	hook.Register("test-failing", hook.Func(func(*http.Request, []byte) error { return errHook }))
+
+	tests := []struct {
+		name        string
+		hooks       []string
+		backendCall bool
+		wantErr     error
+	}{
+		{name: "hooks mutate the request", hooks: []string{"content-sha256", "test-signer"}, backendCall: true},
+		{name: "failing hook aborts the request", hooks: []string{"test-signer", "test-failing"}, wantErr: errHook},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			if tt.backendCall {
+				client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+					assert.Equal(t, "test", req.Header.Get("X-Signature"))
+					assert.NotEmpty(t, req.Header.Get(hook.ContentSHA256Header))
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+				})
+			}
+
+			cfg := &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Logs:   config.Endpoint{Address: "http://backend:8080", Hooks: tt.hooks},
+			}
+			proc, err := New(
+				cfg,
+				&cfg.Logs,
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("test"), nil },
+			)
+			require.NoError(t, err)
+
+			_, _, err = proc.send(context.Background(), "tenant-a", []*logpb.ResourceLogs{{}})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSend_Signing(t *testing.T) {
+	tests := []struct {
+		name      string
+		tenant    string
+		secrets   []string
+		wantKeyID string
+		wantKey   string
+	}{
+		{
+			name:      "tenant secret",
+			tenant:    "tenant-a",
+			secrets:   []string{"tenant-a=secret-a"},
+			wantKeyID: "tenant-a",
+			wantKey:   "secret-a",
+		},
+		{
+			name:      "wildcard secret",
+			tenant:    "tenant-b",
+			secrets:   []string{"tenant-a=secret-a", "*=shared"},
+			wantKeyID: "tenant-b",
+			wantKey:   "shared",
+		},
+		{name: "no secret for tenant", tenant: "tenant-b", secrets: []string{"tenant-a=secret-a"}},
+		{name: "signing disabled", tenant: "tenant-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				if tt.wantKey == "" {
+					assert.Empty(t, req.Header.Get("X-Signature"))
+					assert.Empty(t, req.Header.Get("X-Signature-Key-Id"))
+				} else {
+					assert.Equal(t, signature.Sign([]byte(tt.wantKey), []byte("test")), req.Header.Get("X-Signature"))
+					assert.Equal(t, tt.wantKeyID, req.Header.Get("X-Signature-Key-Id"))
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			})
+
+			cfg := &config.Config{
+				Tenant: config.Tenant{Label: "tenant.id", Format: "%s", Header: "X-Scope-OrgID"},
+				Logs:   config.Endpoint{Address: "http://backend:8080", Signing: config.Signing{Secrets: tt.secrets}},
+			}
+			proc, err := New(
+				cfg,
+				&cfg.Logs,
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("test"), nil },
+			)
+			require.NoError(t, err)
+
+			_, _, err = proc.send(context.Background(), tt.tenant, []*logpb.ResourceLogs{{}})
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNew_UnknownHook(t *testing.T) {
+	cfg := &config.Config{Logs: config.Endpoint{Address: "http://backend:8080", Hooks: []string{"missing"}}}
+
+	_, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return nil, nil },
+	)
+	assert.Error(t, err)
+}
+
+func TestNew_UnsupportedEncoding(t *testing.T) {
+	cfg := &config.Config{Logs: config.Endpoint{Address: "http://backend:8080", Encoding: "xml"}}
+
+	_, err := New(
+		cfg,
+		&cfg.Logs,
+		attribute.String(signalTypeAttrKey, "logs"),
+		&http.Client{},
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return nil, nil },
+	)
+	assert.Error(t, err)
+}
+
+func TestStatusClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		err        error
+		want       string
+	}{
+		{name: "success", statusCode: http.StatusAccepted, want: "2xx"},
+		{name: "client error", statusCode: http.StatusTooManyRequests, want: "4xx"},
+		{name: "server error", statusCode: http.StatusBadGateway, want: "5xx"},
+		{name: "context deadline", err: fmt.Errorf("send: %w", context.DeadlineExceeded), want: "timeout"},
+		{name: "client timeout", err: &url.Error{Op: "Post", URL: "http://backend", Err: os.ErrDeadlineExceeded}, want: "timeout"},
+		{name: "connection refused", err: errors.New("connection refused"), want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, statusClass(tt.statusCode, tt.err))
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		want       time.Duration
+	}{
+		{name: "seconds", statusCode: http.StatusTooManyRequests, retryAfter: "30", want: 30 * time.Second},
+		{name: "http date", statusCode: http.StatusServiceUnavailable, retryAfter: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute},
+		{name: "date in the past", statusCode: http.StatusServiceUnavailable, retryAfter: now.Add(-time.Minute).Format(http.TimeFormat)},
+		{name: "negative seconds", statusCode: http.StatusTooManyRequests, retryAfter: "-5"},
+		{name: "invalid", statusCode: http.StatusTooManyRequests, retryAfter: "soon"},
+		{name: "missing", statusCode: http.StatusTooManyRequests},
+		{name: "not retryable", statusCode: http.StatusBadRequest, retryAfter: "30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.statusCode, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			assert.Equal(t, tt.want, parseRetryAfter(resp, now))
+		})
+	}
+}
+
+func TestDispatch_RetryAfter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"7"}},
+		Body:       http.NoBody,
+	}, nil)
+
+	proc, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"}},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.String(signalTypeAttrKey, "logs"),
+		client,
+		noopmetric.NewMeterProvider().Meter("test"),
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+	)
+	require.NoError(t, err)
+
+	err = proc.Dispatch(context.Background(), map[string][]*logpb.ResourceLogs{"tenant-a": {{}}})
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+	assert.Equal(t, 7*time.Second, statusErr.RetryAfter)
+}
+
+func TestStatusError_Is(t *testing.T) {
+	tests := []struct {
+		statusCode      int
+		wantThrottled   bool
+		wantUnavailable bool
+	}{
+		{statusCode: http.StatusBadRequest},
+		{statusCode: http.StatusTooManyRequests, wantThrottled: true},
+		{statusCode: http.StatusInternalServerError, wantUnavailable: true},
+		{statusCode: http.StatusServiceUnavailable, wantUnavailable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.statusCode), func(t *testing.T) {
+			err := fmt.Errorf("tenant a: %w", &StatusError{StatusCode: tt.statusCode})
+			assert.Equal(t, tt.wantThrottled, errors.Is(err, ErrThrottled))
+			assert.Equal(t, tt.wantUnavailable, errors.Is(err, ErrBackendUnavailable))
+		})
+	}
+}
+
+func TestDispatch_Errors(t *testing.T) {
+	// tenant-a is accepted, tenant-b is throttled and tenant-c cannot be reached
+	do := func(req *http.Request) (*http.Response, error) {
+		switch req.Header.Get("X-Scope-OrgID") {
+		case "tenant-b":
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody}, nil
+		case "tenant-c":
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusAccepted, Body: http.NoBody}, nil
+	}
+
+	tests := []struct {
+		name            string
+		policy          string
+		tenants         []string
+		wantPartial     bool
+		wantThrottled   bool
+		wantUnavailable bool
+		wantMessage     string
+	}{
+		{
+			name:            "best-effort partial failure",
+			policy:          config.DispatchBestEffort,
+			tenants:         []string{"tenant-a", "tenant-b", "tenant-c"},
+			wantPartial:     true,
+			wantThrottled:   true,
+			wantUnavailable: true,
+			wantMessage: "partial failure: tenant tenant-b: received non-success status code: 429\n" +
+				"tenant tenant-c: backend unavailable: failed to send request: connection refused",
+		},
+		{
+			name:            "best-effort failure of every tenant",
+			policy:          config.DispatchBestEffort,
+			tenants:         []string{"tenant-b", "tenant-c"},
+			wantThrottled:   true,
+			wantUnavailable: true,
+			wantMessage: "tenant tenant-b: received non-success status code: 429\n" +
+				"tenant tenant-c: backend unavailable: failed to send request: connection refused",
+		},
+		{
+			name:          "throttled",
+			policy:        config.DispatchBestEffort,
+			tenants:       []string{"tenant-b"},
+			wantThrottled: true,
+			wantMessage:   "tenant tenant-b: received non-success status code: 429",
+		},
+		{
+			name:            "fail-fast",
+			policy:          config.DispatchFailFast,
+			tenants:         []string{"tenant-c"},
+			wantUnavailable: true,
+			wantMessage:     "tenant tenant-c: backend unavailable: failed to send request: connection refused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(do).Times(len(tt.tenants))
+
+			proc, err := New(
+				&config.Config{
+					Tenant:   config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID", Format: "%s"},
+					Dispatch: config.Dispatch{ErrorPolicy: tt.policy},
+				},
+				&config.Endpoint{Address: "http://localhost:3100"},
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte{}, nil },
+			)
+			require.NoError(t, err)
+
+			tenantMap := make(map[string][]*logpb.ResourceLogs)
+			for _, tenant := range tt.tenants {
+				tenantMap[tenant] = []*logpb.ResourceLogs{{}}
+			}
+			err = proc.Dispatch(t.Context(), tenantMap)
+
+			require.Error(t, err)
+			assert.Equal(t, tt.wantPartial, errors.Is(err, ErrPartialFailure))
+			assert.Equal(t, tt.wantThrottled, errors.Is(err, ErrThrottled))
+			assert.Equal(t, tt.wantUnavailable, errors.Is(err, ErrBackendUnavailable))
+			assert.EqualError(t, err, tt.wantMessage)
+		})
+	}
+}
+
+func TestWithRequest(t *testing.T) {
+	attrs := []attribute.KeyValue{attribute.String(signalTenantAttrKey, "acme")}
+	assert.Equal(t, attrs, withRequest(t.Context(), attrs))
+
+	ctx := requestmeta.NewContext(t.Context(), requestmeta.Metadata{ClientAddress: "10.0.0.1", Signer: "acme"})
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String(signalTenantAttrKey, "acme"),
+		attribute.String("client.address", "10.0.0.1"),
+		attribute.String("signature.tenant", "acme"),
+	}, withRequest(ctx, attrs))
+	assert.Len(t, attrs, 1)
+}
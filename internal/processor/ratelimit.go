@@ -0,0 +1,31 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// waitRateLimit waits until the outbound rate limits of the backend allow a request with a body of the given size,
+// recording how long the request was held back. Streamed bodies are of unknown size and charged once sent.
+func (p *Processor[T]) waitRateLimit(ctx context.Context, tenant string, size int) error {
+	if p.requestLimit == nil && p.byteLimit == nil {
+		return nil
+	}
+
+	start := time.Now()
+	err := p.requestLimit.Wait(ctx, 1)
+	if err == nil {
+		err = p.byteLimit.Wait(ctx, float64(size))
+	}
+
+	p.rateLimitWaitMetric.Record(ctx, time.Since(start).Milliseconds(), metric.WithAttributes(
+		p.signalTypeAttr,
+		attribute.String(signalTenantAttrKey, tenant),
+		p.backendAttr,
+	))
+	return err
+}
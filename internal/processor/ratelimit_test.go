@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSend_RateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit config.RateLimit
+		stream    bool
+		wantSent  int
+	}{
+		{name: "unlimited", wantSent: 2},
+		{name: "requests", rateLimit: config.RateLimit{Requests: 1, RequestBurst: 1}, wantSent: 1},
+		{name: "bytes", rateLimit: config.RateLimit{Bytes: 4, ByteBurst: 6}, wantSent: 1},
+		{name: "bytes within the burst", rateLimit: config.RateLimit{Bytes: 4, ByteBurst: 8}, wantSent: 2},
+		{name: "streamed bytes", rateLimit: config.RateLimit{Bytes: 1, ByteBurst: 1}, stream: true, wantSent: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := NewMockClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				_, _ = io.Copy(io.Discard, req.Body)
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}).Times(tt.wantSent)
+
+			proc, err := New(
+				&config.Config{Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"}},
+				&config.Endpoint{Address: "http://localhost:3100", Stream: tt.stream, RateLimit: tt.rateLimit},
+				attribute.String(signalTypeAttrKey, "logs"),
+				client,
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("data"), nil },
+			)
+			require.NoError(t, err)
+
+			// The second request would have to wait for a second, beyond its deadline
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			sent := 0
+			for range 2 {
+				_, _, err := proc.send(ctx, "tenant-a", []*logpb.ResourceLogs{{}})
+				if err != nil {
+					assert.ErrorIs(t, err, ratelimit.ErrLimited)
+					continue
+				}
+				sent++
+			}
+			assert.Equal(t, tt.wantSent, sent)
+		})
+	}
+}
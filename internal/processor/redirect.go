@@ -0,0 +1,61 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+)
+
+// ErrRedirect is returned for requests the backend redirected when the redirect policy does not allow following the
+// redirect, or it cannot be followed.
+var ErrRedirect = errors.New("backend redirect not followed")
+
+// maxRedirects limits the redirects followed for a single request, like the default policy of http.Client.
+const maxRedirects = 10
+
+// validateRedirect returns an error when the redirect policy of the endpoint is unknown.
+func validateRedirect(endpoint *config.Endpoint) error {
+	switch endpoint.Redirect {
+	case "", config.RedirectFollow, config.RedirectSameHost, config.RedirectRefuse:
+		return nil
+	}
+	return fmt.Errorf("invalid redirect policy %q, expected %s, %s or %s", endpoint.Redirect,
+		config.RedirectFollow, config.RedirectSameHost, config.RedirectRefuse)
+}
+
+// CheckRedirect returns the CheckRedirect function of the clients of a backend with the redirect policy, same-host
+// when it is empty.
+//
+// The follow policy follows redirects to any host and the same-host policy only redirects to the scheme and host of
+// the original request. Both send the headers of the original request with the redirected one, including the
+// authorization and tenant headers the default policy strips when the host changes, and never follow a redirect from
+// HTTPS to plain HTTP. The refuse policy follows no redirect.
+func CheckRedirect(policy string) func(req *http.Request, via []*http.Request) error {
+	if policy == "" {
+		policy = config.RedirectSameHost
+	}
+
+	return func(req *http.Request, via []*http.Request) error {
+		original := via[0]
+		switch {
+		case policy != config.RedirectFollow && policy != config.RedirectSameHost:
+			return fmt.Errorf("%w: redirected to %s", ErrRedirect, req.URL.Redacted())
+		case len(via) >= maxRedirects:
+			return fmt.Errorf("%w: stopped after %d redirects", ErrRedirect, maxRedirects)
+		case original.URL.Scheme == "https" && req.URL.Scheme != "https":
+			return fmt.Errorf("%w: redirected from https to %s", ErrRedirect, req.URL.Redacted())
+		case policy == config.RedirectSameHost && req.URL.Host != original.URL.Host:
+			return fmt.Errorf("%w: redirected to another host %s", ErrRedirect, req.URL.Host)
+		}
+
+		for key, values := range original.Header {
+			if _, ok := req.Header[key]; !ok {
+				req.Header[key] = values
+			}
+		}
+		return nil
+	}
+}
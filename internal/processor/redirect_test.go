@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+func TestCheckRedirect(t *testing.T) {
+	newRequest := func(t *testing.T, rawURL string) *http.Request {
+		t.Helper()
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		return &http.Request{URL: u, Header: http.Header{}}
+	}
+
+	tests := []struct {
+		name     string
+		policy   string
+		from     string
+		to       string
+		via      int
+		wantErr  string
+		wantAuth bool
+	}{
+		{
+			name:     "follow to another host",
+			policy:   config.RedirectFollow,
+			from:     "https://loki:3100/otlp",
+			to:       "https://loki-2:3100/otlp",
+			wantAuth: true,
+		},
+		{
+			name:     "same-host to the same host",
+			policy:   config.RedirectSameHost,
+			from:     "https://loki:3100/otlp",
+			to:       "https://loki:3100/v2/otlp",
+			wantAuth: true,
+		},
+		{
+			name:    "same-host to another host",
+			policy:  config.RedirectSameHost,
+			from:    "https://loki:3100/otlp",
+			to:      "https://loki-2:3100/otlp",
+			wantErr: "redirected to another host loki-2:3100",
+		},
+		{
+			name:    "empty policy is same-host",
+			from:    "https://loki:3100/otlp",
+			to:      "https://loki-2:3100/otlp",
+			wantErr: "redirected to another host",
+		},
+		{
+			name:    "refuse",
+			policy:  config.RedirectRefuse,
+			from:    "https://loki:3100/otlp",
+			to:      "https://loki:3100/v2/otlp",
+			wantErr: "redirected to https://loki:3100/v2/otlp",
+		},
+		{
+			name:    "https to http",
+			policy:  config.RedirectFollow,
+			from:    "https://loki:3100/otlp",
+			to:      "http://loki:3100/otlp",
+			wantErr: "redirected from https to http://loki:3100/otlp",
+		},
+		{
+			name:    "too many redirects",
+			policy:  config.RedirectFollow,
+			from:    "https://loki:3100/otlp",
+			to:      "https://loki:3100/otlp",
+			via:     maxRedirects,
+			wantErr: "stopped after 10 redirects",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := newRequest(t, tt.from)
+			original.Header.Set("Authorization", "Bearer token")
+			original.Header.Set("X-Scope-OrgID", "tenant-a")
+			via := []*http.Request{original}
+			for len(via) < max(tt.via, 1) {
+				via = append(via, newRequest(t, tt.from))
+			}
+
+			req := newRequest(t, tt.to)
+			err := CheckRedirect(tt.policy)(req, via)
+			if tt.wantErr != "" {
+				require.ErrorIs(t, err, ErrRedirect)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.wantAuth {
+				assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+				assert.Equal(t, "tenant-a", req.Header.Get("X-Scope-OrgID"))
+			}
+		})
+	}
+}
+
+func TestSend_Redirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		stream    bool
+		otherHost bool
+		wantErr   bool
+	}{
+		{name: "follow to another host", policy: config.RedirectFollow, otherHost: true},
+		{name: "same-host", policy: config.RedirectSameHost},
+		{name: "same-host to another host", policy: config.RedirectSameHost, otherHost: true, wantErr: true},
+		{name: "refuse", policy: config.RedirectRefuse, wantErr: true},
+		{name: "streamed body", policy: config.RedirectFollow, stream: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody, gotTenant string
+			target := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+				gotTenant = r.Header.Get("X-Scope-OrgID")
+				w.WriteHeader(http.StatusOK)
+			})
+
+			other := httptest.NewServer(target)
+			defer other.Close()
+
+			mux := http.NewServeMux()
+			mux.Handle("/target", target)
+			backend := httptest.NewServer(mux)
+			defer backend.Close()
+
+			location := backend.URL + "/target"
+			if tt.otherHost {
+				location = other.URL + "/target"
+			}
+			mux.HandleFunc("/otlp", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				http.Redirect(w, r, location, http.StatusTemporaryRedirect)
+			})
+
+			cfg := &config.Config{Tenant: config.Tenant{Label: "tenant.id", Header: "X-Scope-OrgID"}}
+			proc, err := New(
+				cfg,
+				&config.Endpoint{Address: backend.URL + "/otlp", Stream: tt.stream, Redirect: tt.policy},
+				attribute.String(signalTypeAttrKey, "logs"),
+				&http.Client{CheckRedirect: CheckRedirect(tt.policy)},
+				noopmetric.NewMeterProvider().Meter("test"),
+				nooptrace.NewTracerProvider().Tracer("test"),
+				func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+				func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("data"), nil },
+			)
+			require.NoError(t, err)
+
+			_, _, err = proc.send(context.Background(), "tenant-a", []*logpb.ResourceLogs{{}})
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrRedirect)
+				assert.NotErrorIs(t, err, ErrBackendUnavailable)
+				assert.Empty(t, gotBody)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "data", gotBody)
+			assert.Equal(t, "tenant-a", gotTenant)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+// Package processor contains the Processor struct and related types for processing incoming telemetry data and forwarding it to the appropriate backend.
+package processor
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Pipeline stages whose duration is recorded.
+const (
+	StageUnmarshal = "unmarshal"
+	StagePartition = "partition"
+	StageMarshal   = "marshal"
+	StageSend      = "send"
+)
+
+var signalStageAttrKey = "signal.stage"
+
+// RecordStage records the duration of a pipeline stage that started at the given time.
+func (p *Processor[T]) RecordStage(ctx context.Context, stage string, start time.Time) {
+	p.stageMetric.Record(ctx, float64(time.Since(start))/float64(time.Millisecond), metric.WithAttributes(
+		p.signalTypeAttr,
+		attribute.String(signalStageAttrKey, stage),
+	))
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	nooptrace "go.opentelemetry.io/otel/trace/noop"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRecordStage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := NewMockClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewBufferString("ok")),
+	}, nil)
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	proc, err := New(
+		&config.Config{Tenant: config.Tenant{Label: "tenant.id", Default: "default"}},
+		&config.Endpoint{Address: "http://localhost:3100"},
+		attribute.KeyValue{Key: attribute.Key(string(signalTypeAttrKey)), Value: attribute.StringValue("logs")},
+		client,
+		meter,
+		nooptrace.NewTracerProvider().Tracer("test"),
+		func(rl *logpb.ResourceLogs) *resourcepb.Resource { return rl.GetResource() },
+		func([]*logpb.ResourceLogs) ([]byte, error) { return []byte("data"), nil },
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tenantMap, _, err := proc.Partition(ctx, []*logpb.ResourceLogs{{Resource: &resourcepb.Resource{}}})
+	require.NoError(t, err)
+	require.NoError(t, proc.Dispatch(ctx, tenantMap))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	stages := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "otel_lgtm_proxy_stage_duration_ms" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+				stage, _ := dp.Attributes.Value(attribute.Key(signalStageAttrKey))
+				stages[stage.AsString()] += dp.Count
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]uint64{StagePartition: 1, StageMarshal: 1, StageSend: 1}, stages)
+}
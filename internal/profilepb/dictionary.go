@@ -0,0 +1,80 @@
+// Package profilepb contains a partial Go schema of the OTLP profiles signal.
+package profilepb
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// dictionaryField is the number of the unknown field carrying the dictionary of a request on each of its resources,
+// the highest valid number so it cannot collide with a field of the schema.
+const dictionaryField = protowire.MaxValidNumber
+
+// Resources returns the resources of the profiles, each carrying the dictionary their profiles reference, so the
+// resources of a tenant can be sent on their own, or copied for several tenants, and still be marshalled with it by
+// NewProfilesData.
+func Resources(data *ProfilesData) []*ResourceProfiles {
+	resources := data.GetResourceProfiles()
+	if data.GetDictionary() == nil {
+		return resources
+	}
+
+	dictionary, err := proto.Marshal(data.GetDictionary())
+	if err != nil {
+		return resources
+	}
+	for _, resource := range resources {
+		unknown, _ := splitDictionary(resource.ProtoReflect().GetUnknown())
+		unknown = protowire.AppendTag(unknown, dictionaryField, protowire.BytesType)
+		resource.ProtoReflect().SetUnknown(protowire.AppendBytes(unknown, dictionary))
+	}
+	return resources
+}
+
+// NewProfilesData returns the profiles of the resources along with the dictionary they carry, without the field
+// carrying it. The resources are left untouched, they all come from the same request and carry the same dictionary.
+func NewProfilesData(resources []*ResourceProfiles) (*ProfilesData, error) {
+	data := &ProfilesData{ResourceProfiles: make([]*ResourceProfiles, len(resources))}
+	for i, resource := range resources {
+		unknown, dictionary := splitDictionary(resource.ProtoReflect().GetUnknown())
+		if dictionary == nil {
+			data.ResourceProfiles[i] = resource
+			continue
+		}
+
+		if data.Dictionary == nil {
+			data.Dictionary = &ProfilesDictionary{}
+			if err := proto.Unmarshal(dictionary, data.Dictionary); err != nil {
+				return nil, err
+			}
+		}
+
+		stripped := &ResourceProfiles{
+			Resource:      resource.GetResource(),
+			ScopeProfiles: resource.GetScopeProfiles(),
+			SchemaUrl:     resource.GetSchemaUrl(),
+		}
+		stripped.ProtoReflect().SetUnknown(unknown)
+		data.ResourceProfiles[i] = stripped
+	}
+	return data, nil
+}
+
+// splitDictionary splits the unknown fields of a resource into the other fields and the dictionary they carry, nil
+// when they carry none.
+func splitDictionary(unknown []byte) (rest, dictionary []byte) {
+	for b := unknown; len(b) > 0; {
+		num, typ, n := protowire.ConsumeField(b)
+		if n < 0 {
+			return unknown, nil
+		}
+		if num == dictionaryField && typ == protowire.BytesType {
+			_, _, m := protowire.ConsumeTag(b)
+			dictionary, _ = protowire.ConsumeBytes(b[m:n])
+		} else {
+			rest = append(rest, b[:n]...)
+		}
+		b = b[n:]
+	}
+	return rest, dictionary
+}
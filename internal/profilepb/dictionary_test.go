@@ -0,0 +1,54 @@
+package profilepb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewProfilesData(t *testing.T) {
+	stringTable := protowire.AppendString(protowire.AppendTag(nil, 5, protowire.BytesType), "main")
+	newer := protowire.AppendString(protowire.AppendTag(nil, 1000, protowire.BytesType), "newer schema field")
+
+	tests := []struct {
+		name       string
+		dictionary *ProfilesDictionary
+	}{
+		{name: "with a dictionary", dictionary: &ProfilesDictionary{}},
+		{name: "without a dictionary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dictionary != nil {
+				tt.dictionary.ProtoReflect().SetUnknown(stringTable)
+			}
+			resource := &ResourceProfiles{SchemaUrl: "https://opentelemetry.io/schemas/1.30.0"}
+			resource.ProtoReflect().SetUnknown(newer)
+
+			resources := Resources(&ProfilesData{ResourceProfiles: []*ResourceProfiles{resource}, Dictionary: tt.dictionary})
+			require.Len(t, resources, 1)
+
+			// Copies of a resource keep the dictionary
+			clone := proto.Clone(resources[0]).(*ResourceProfiles)
+
+			data, err := NewProfilesData([]*ResourceProfiles{clone})
+			require.NoError(t, err)
+			require.Len(t, data.GetResourceProfiles(), 1)
+			assert.Equal(t, newer, []byte(data.GetResourceProfiles()[0].ProtoReflect().GetUnknown()))
+			assert.Equal(t, "https://opentelemetry.io/schemas/1.30.0", data.GetResourceProfiles()[0].GetSchemaUrl())
+			if tt.dictionary == nil {
+				assert.Nil(t, data.GetDictionary())
+				return
+			}
+			assert.Equal(t, stringTable, []byte(data.GetDictionary().ProtoReflect().GetUnknown()))
+
+			// The resources are left untouched
+			_, dictionary := splitDictionary(clone.ProtoReflect().GetUnknown())
+			assert.NotNil(t, dictionary)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+// Package profilepb contains a partial Go schema of the OTLP profiles signal, which the otlp module does not provide
+// while the signal is in development.
+//
+// Only the fields the proxy partitions the profiles by are declared: the resource and scope of each group of
+// profiles. The dictionary shared by the profiles of a request and the profiles themselves are kept as unknown fields,
+// so they are forwarded byte for byte whatever the revision of the development schema the senders use. For the same
+// reason the profiles can only be encoded as protobuf, the JSON encoding drops unknown fields.
+//
+// The code is generated from the partial .proto files of this package with protoc-gen-go, resolving the common and
+// resource imports against a checkout of the opentelemetry-proto repository:
+//
+//	protoc -I . -I <opentelemetry-proto> --go_out=. --go_opt=paths=source_relative profiles.proto profiles_service.proto
+//
+// It is not part of go generate, so building the proxy does not require protoc.
+package profilepb
@@ -0,0 +1,180 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: profiles_service.proto
+
+package profilepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExportProfilesServiceResponse struct {
+	state          protoimpl.MessageState        `protogen:"open.v1"`
+	PartialSuccess *ExportProfilesPartialSuccess `protobuf:"bytes,1,opt,name=partial_success,json=partialSuccess,proto3" json:"partial_success,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ExportProfilesServiceResponse) Reset() {
+	*x = ExportProfilesServiceResponse{}
+	mi := &file_profiles_service_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportProfilesServiceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportProfilesServiceResponse) ProtoMessage() {}
+
+func (x *ExportProfilesServiceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_profiles_service_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportProfilesServiceResponse.ProtoReflect.Descriptor instead.
+func (*ExportProfilesServiceResponse) Descriptor() ([]byte, []int) {
+	return file_profiles_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExportProfilesServiceResponse) GetPartialSuccess() *ExportProfilesPartialSuccess {
+	if x != nil {
+		return x.PartialSuccess
+	}
+	return nil
+}
+
+type ExportProfilesPartialSuccess struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	RejectedProfiles int64                  `protobuf:"varint,1,opt,name=rejected_profiles,json=rejectedProfiles,proto3" json:"rejected_profiles,omitempty"`
+	ErrorMessage     string                 `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ExportProfilesPartialSuccess) Reset() {
+	*x = ExportProfilesPartialSuccess{}
+	mi := &file_profiles_service_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportProfilesPartialSuccess) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportProfilesPartialSuccess) ProtoMessage() {}
+
+func (x *ExportProfilesPartialSuccess) ProtoReflect() protoreflect.Message {
+	mi := &file_profiles_service_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportProfilesPartialSuccess.ProtoReflect.Descriptor instead.
+func (*ExportProfilesPartialSuccess) Descriptor() ([]byte, []int) {
+	return file_profiles_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExportProfilesPartialSuccess) GetRejectedProfiles() int64 {
+	if x != nil {
+		return x.RejectedProfiles
+	}
+	return 0
+}
+
+func (x *ExportProfilesPartialSuccess) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+var File_profiles_service_proto protoreflect.FileDescriptor
+
+const file_profiles_service_proto_rawDesc = "" +
+	"\n" +
+	"\x16profiles_service.proto\x124opentelemetry.proto.collector.profiles.v1development\x1a\x0eprofiles.proto\"\x9c\x01\n" +
+	"\x1dExportProfilesServiceResponse\x12{\n" +
+	"\x0fpartial_success\x18\x01 \x01(\v2R.opentelemetry.proto.collector.profiles.v1development.ExportProfilesPartialSuccessR\x0epartialSuccess\"p\n" +
+	"\x1cExportProfilesPartialSuccess\x12+\n" +
+	"\x11rejected_profiles\x18\x01 \x01(\x03R\x10rejectedProfiles\x12#\n" +
+	"\rerror_message\x18\x02 \x01(\tR\ferrorMessageB7Z5github.com/matt-gp/otel-lgtm-proxy/internal/profilepbb\x06proto3"
+
+var (
+	file_profiles_service_proto_rawDescOnce sync.Once
+	file_profiles_service_proto_rawDescData []byte
+)
+
+func file_profiles_service_proto_rawDescGZIP() []byte {
+	file_profiles_service_proto_rawDescOnce.Do(func() {
+		file_profiles_service_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_profiles_service_proto_rawDesc), len(file_profiles_service_proto_rawDesc)))
+	})
+	return file_profiles_service_proto_rawDescData
+}
+
+var file_profiles_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_profiles_service_proto_goTypes = []any{
+	(*ExportProfilesServiceResponse)(nil), // 0: opentelemetry.proto.collector.profiles.v1development.ExportProfilesServiceResponse
+	(*ExportProfilesPartialSuccess)(nil),  // 1: opentelemetry.proto.collector.profiles.v1development.ExportProfilesPartialSuccess
+}
+var file_profiles_service_proto_depIdxs = []int32{
+	1, // 0: opentelemetry.proto.collector.profiles.v1development.ExportProfilesServiceResponse.partial_success:type_name -> opentelemetry.proto.collector.profiles.v1development.ExportProfilesPartialSuccess
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_profiles_service_proto_init() }
+func file_profiles_service_proto_init() {
+	if File_profiles_service_proto != nil {
+		return
+	}
+	file_profiles_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_profiles_service_proto_rawDesc), len(file_profiles_service_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_profiles_service_proto_goTypes,
+		DependencyIndexes: file_profiles_service_proto_depIdxs,
+		MessageInfos:      file_profiles_service_proto_msgTypes,
+	}.Build()
+	File_profiles_service_proto = out.File
+	file_profiles_service_proto_goTypes = nil
+	file_profiles_service_proto_depIdxs = nil
+}
@@ -0,0 +1,124 @@
+// Package ratelimit provides the token buckets enforcing the outbound rate limits of the backends.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+)
+
+// ErrLimited is returned when the wait for tokens could not be completed before the context is done.
+var ErrLimited = errors.New("outbound rate limit exceeded")
+
+// Option configures optional dependencies of a Bucket.
+type Option func(*Bucket)
+
+// WithClock sets the clock refilling the bucket.
+func WithClock(c clock.Clock) Option {
+	return func(b *Bucket) {
+		b.clock = c
+	}
+}
+
+// Bucket is a token bucket, safe for concurrent use. A nil Bucket never limits.
+type Bucket struct {
+	rate  float64
+	burst float64
+	clock clock.Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New creates a new Bucket refilled with rate tokens per second up to burst tokens, starting full. It returns nil when
+// the rate is not positive, so the limit is disabled. A burst below one defaults to one second worth of tokens.
+func New(rate, burst float64, opts ...Option) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = max(rate, 1)
+	}
+
+	b := &Bucket{
+		rate:  rate,
+		burst: burst,
+		clock: clock.New(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.tokens = burst
+	b.last = b.clock.Now()
+
+	return b
+}
+
+// Wait takes n tokens from the bucket, waiting until the debt they leave is repaid. Taking no tokens waits for the
+// current debt. It returns ErrLimited, and gives the tokens back, when the context is done before or the wait would
+// outlast the context deadline.
+func (b *Bucket) Wait(ctx context.Context, n float64) error {
+	if b == nil || n < 0 {
+		return nil
+	}
+
+	delay := b.take(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && b.clock.Now().Add(delay).After(deadline) {
+		b.refund(n)
+		return fmt.Errorf("%w: waiting %s would outlast the deadline", ErrLimited, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.refund(n)
+		return fmt.Errorf("%w: %w", ErrLimited, ctx.Err())
+	}
+}
+
+// Charge takes n tokens from the bucket without waiting, for usage only known once it happened. The debt it leaves
+// delays the next callers.
+func (b *Bucket) Charge(n float64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.take(n)
+}
+
+// take refills the bucket, takes n tokens and returns how long it takes to repay the debt they leave.
+func (b *Bucket) take(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.rate, b.burst)
+	}
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// refund gives back tokens taken by a caller that gave up waiting.
+func (b *Bucket) refund(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.tokens+n, b.burst)
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		burst     float64
+		wantNil   bool
+		wantBurst float64
+	}{
+		{name: "disabled", rate: 0, wantNil: true},
+		{name: "negative rate", rate: -1, wantNil: true},
+		{name: "explicit burst", rate: 10, burst: 50, wantBurst: 50},
+		{name: "default burst", rate: 10, wantBurst: 10},
+		{name: "default burst of a slow rate", rate: 0.5, wantBurst: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.rate, tt.burst)
+			if tt.wantNil {
+				assert.Nil(t, b)
+				return
+			}
+			require.NotNil(t, b)
+			assert.Equal(t, tt.wantBurst, b.burst)
+			assert.Equal(t, tt.wantBurst, b.tokens)
+		})
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New(10, 20, WithClock(fake))
+
+	// The burst is available straight away
+	assert.Zero(t, b.take(20))
+
+	// Taking more leaves a debt repaid at the rate
+	assert.Equal(t, 500*time.Millisecond, b.take(5))
+	assert.Equal(t, time.Second, b.take(5))
+
+	// The debt is repaid over time, and the bucket never refills beyond its burst
+	fake.Advance(time.Second)
+	assert.Zero(t, b.take(0))
+	fake.Advance(time.Hour)
+	assert.Zero(t, b.take(20))
+	assert.Equal(t, 100*time.Millisecond, b.take(1))
+}
+
+func TestBucket_Wait(t *testing.T) {
+	t.Run("within the burst", func(t *testing.T) {
+		b := New(1, 2)
+		assert.NoError(t, b.Wait(context.Background(), 2))
+	})
+
+	t.Run("waits for the debt", func(t *testing.T) {
+		b := New(1000, 1)
+		require.NoError(t, b.Wait(context.Background(), 1))
+
+		start := time.Now()
+		require.NoError(t, b.Wait(context.Background(), 20))
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("wait outlasting the deadline", func(t *testing.T) {
+		b := New(1, 1)
+		require.NoError(t, b.Wait(context.Background(), 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		assert.ErrorIs(t, b.Wait(ctx, 3600), ErrLimited)
+
+		// The tokens of the caller giving up are given back
+		assert.InDelta(t, 0, b.tokens, 0.1)
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		b := New(1, 1)
+		require.NoError(t, b.Wait(context.Background(), 1))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		err := b.Wait(ctx, 3600)
+		assert.ErrorIs(t, err, ErrLimited)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("nil bucket", func(t *testing.T) {
+		var b *Bucket
+		assert.NoError(t, b.Wait(context.Background(), 1<<30))
+		b.Charge(1 << 30)
+	})
+}
+
+func TestBucket_Charge(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := New(10, 10, WithClock(fake))
+
+	b.Charge(30)
+	assert.Equal(t, 2*time.Second+100*time.Millisecond, b.take(1))
+
+	// Callers without tokens to take still wait for the debt
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx, 0), ErrLimited)
+}
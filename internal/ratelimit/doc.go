@@ -0,0 +1,12 @@
+// Package ratelimit provides the token buckets enforcing the outbound rate limits of the backends.
+//
+// A Bucket is refilled at a constant rate up to its burst size. Taking tokens
+// may leave the bucket in debt, in which case the caller waits until the debt
+// is repaid, so that bursts are smoothed into the sustained rate instead of
+// being rejected. Waiting callers are served in the order they took their
+// tokens.
+//
+// A caller gives up with ErrLimited when its context is done before the wait
+// is over, or straight away when the wait would outlast its deadline, and its
+// tokens are returned to the bucket.
+package ratelimit
@@ -0,0 +1,17 @@
+// Package remotewrite converts OTLP metrics into Prometheus remote write requests.
+//
+// The conversion follows the Prometheus OTLP translation conventions: metric
+// and attribute names are sanitized into valid Prometheus names, monotonic sums
+// get a _total suffix, explicit bucket histograms become _bucket, _sum and
+// _count series and summaries become quantile, _sum and _count series. The
+// service.namespace and service.name resource attributes become the job label
+// and service.instance.id the instance label, while the other resource
+// attributes are written once per resource to a target_info series. The
+// instrumentation scope is kept as the otel_scope_name and otel_scope_version
+// labels.
+//
+// Prometheus has no equivalent of delta temporality, and exponential histograms
+// would require native histograms, so their points are dropped and counted
+// instead. Requests are encoded as remote write 1.0 protobuf messages
+// compressed with Snappy.
+package remotewrite
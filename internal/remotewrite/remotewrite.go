@@ -0,0 +1,403 @@
+// Package remotewrite converts OTLP metrics into Prometheus remote write requests.
+package remotewrite
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Headers of remote write requests.
+const (
+	ContentType     = "application/x-protobuf"
+	ContentEncoding = "snappy"
+	Version         = "0.1.0"
+)
+
+// staleNaN is the value marking a series as stale, written for points flagged as having no recorded value.
+var staleNaN = math.Float64frombits(0x7ff0000000000002)
+
+// MetricType is the type of a metric family in the remote write metadata.
+type MetricType int32
+
+// Metric types of the remote write metadata.
+const (
+	MetricTypeUnknown   MetricType = 0
+	MetricTypeCounter   MetricType = 1
+	MetricTypeGauge     MetricType = 2
+	MetricTypeHistogram MetricType = 3
+	MetricTypeSummary   MetricType = 5
+)
+
+// WriteRequest is a remote write request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+	Metadata   []Metadata
+}
+
+// TimeSeries is a series identified by its labels, sorted by name, and its samples.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// Label is a label of a series.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a series at a timestamp in milliseconds.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// Metadata describes a metric family.
+type Metadata struct {
+	Type   MetricType
+	Family string
+	Help   string
+	Unit   string
+}
+
+// Marshal converts the resource metrics into a Snappy compressed remote write request, returning the number of data
+// points that cannot be represented and were dropped.
+func Marshal(resources []*metricpb.ResourceMetrics) ([]byte, int) {
+	request, dropped := Convert(resources)
+	return snappy.Encode(nil, request.Encode()), dropped
+}
+
+// Convert converts the resource metrics into a remote write request, returning the number of data points that cannot
+// be represented and were dropped: the points of delta sums and histograms and of exponential histograms.
+func Convert(resources []*metricpb.ResourceMetrics) (*WriteRequest, int) {
+	c := &converter{request: &WriteRequest{}, metadata: make(map[string]bool)}
+	for _, resourceMetrics := range resources {
+		c.convertResource(resourceMetrics)
+	}
+	return c.request, c.dropped
+}
+
+// converter accumulates the series and metadata of a request.
+type converter struct {
+	request  *WriteRequest
+	metadata map[string]bool
+	dropped  int
+}
+
+// convertResource converts the metrics of a resource, followed by its target_info series.
+func (c *converter) convertResource(resourceMetrics *metricpb.ResourceMetrics) {
+	var (
+		resourceLabels []Label
+		infoLabels     []Label
+		service        string
+		namespace      string
+	)
+	for _, attr := range resourceMetrics.GetResource().GetAttributes() {
+		switch attr.GetKey() {
+		case "service.name":
+			service = valueString(attr.GetValue())
+		case "service.namespace":
+			namespace = valueString(attr.GetValue())
+		case "service.instance.id":
+			resourceLabels = append(resourceLabels, Label{Name: "instance", Value: valueString(attr.GetValue())})
+		default:
+			infoLabels = append(infoLabels, Label{Name: SanitizeLabel(attr.GetKey()), Value: valueString(attr.GetValue())})
+		}
+	}
+	if service != "" {
+		job := service
+		if namespace != "" {
+			job = namespace + "/" + service
+		}
+		resourceLabels = append(resourceLabels, Label{Name: "job", Value: job})
+	} else if namespace != "" {
+		infoLabels = append(infoLabels, Label{Name: "service_namespace", Value: namespace})
+	}
+
+	var latest int64
+	for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+		labels := slices.Clone(resourceLabels)
+		if name := scopeMetrics.GetScope().GetName(); name != "" {
+			labels = append(labels, Label{Name: "otel_scope_name", Value: name})
+		}
+		if version := scopeMetrics.GetScope().GetVersion(); version != "" {
+			labels = append(labels, Label{Name: "otel_scope_version", Value: version})
+		}
+		for _, m := range scopeMetrics.GetMetrics() {
+			latest = max(latest, c.convertMetric(m, labels))
+		}
+	}
+
+	// Resources without samples have no timestamp for their target_info
+	if len(infoLabels) > 0 && latest > 0 {
+		c.addMetadata("target_info", MetricTypeGauge, "Target metadata", "")
+		c.addSeries("target_info", slices.Concat(resourceLabels, infoLabels), 1, latest)
+	}
+}
+
+// convertMetric converts the data points of a metric, returning the latest timestamp written.
+func (c *converter) convertMetric(m *metricpb.Metric, labels []Label) int64 {
+	name := SanitizeName(m.GetName())
+	var latest int64
+	add := func(name string, pointLabels []Label, value float64, timestamp int64, flags uint32) {
+		if flags&uint32(metricpb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+			value = staleNaN
+		}
+		c.addSeries(name, pointLabels, value, timestamp)
+		latest = max(latest, timestamp)
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricpb.Metric_Gauge:
+		c.addMetadata(name, MetricTypeGauge, m.GetDescription(), m.GetUnit())
+		for _, point := range data.Gauge.GetDataPoints() {
+			add(name, pointLabels(labels, point.GetAttributes()), numberValue(point), millis(point.GetTimeUnixNano()),
+				point.GetFlags())
+		}
+
+	case *metricpb.Metric_Sum:
+		if data.Sum.GetAggregationTemporality() == metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+			c.dropped += len(data.Sum.GetDataPoints())
+			break
+		}
+		metricType := MetricTypeGauge
+		if data.Sum.GetIsMonotonic() {
+			metricType = MetricTypeCounter
+			if !strings.HasSuffix(name, "_total") {
+				name += "_total"
+			}
+		}
+		c.addMetadata(name, metricType, m.GetDescription(), m.GetUnit())
+		for _, point := range data.Sum.GetDataPoints() {
+			add(name, pointLabels(labels, point.GetAttributes()), numberValue(point), millis(point.GetTimeUnixNano()),
+				point.GetFlags())
+		}
+
+	case *metricpb.Metric_Histogram:
+		if data.Histogram.GetAggregationTemporality() == metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+			c.dropped += len(data.Histogram.GetDataPoints())
+			break
+		}
+		c.addMetadata(name, MetricTypeHistogram, m.GetDescription(), m.GetUnit())
+		for _, point := range data.Histogram.GetDataPoints() {
+			base := pointLabels(labels, point.GetAttributes())
+			timestamp := millis(point.GetTimeUnixNano())
+
+			var cumulative uint64
+			for i, bound := range point.GetExplicitBounds() {
+				if i < len(point.GetBucketCounts()) {
+					cumulative += point.GetBucketCounts()[i]
+				}
+				add(name+"_bucket", withLabel(base, "le", formatFloat(bound)), float64(cumulative), timestamp, point.GetFlags())
+			}
+			add(name+"_bucket", withLabel(base, "le", "+Inf"), float64(point.GetCount()), timestamp, point.GetFlags())
+			if point.Sum != nil {
+				add(name+"_sum", base, point.GetSum(), timestamp, point.GetFlags())
+			}
+			add(name+"_count", base, float64(point.GetCount()), timestamp, point.GetFlags())
+		}
+
+	case *metricpb.Metric_Summary:
+		c.addMetadata(name, MetricTypeSummary, m.GetDescription(), m.GetUnit())
+		for _, point := range data.Summary.GetDataPoints() {
+			base := pointLabels(labels, point.GetAttributes())
+			timestamp := millis(point.GetTimeUnixNano())
+			for _, quantile := range point.GetQuantileValues() {
+				add(name, withLabel(base, "quantile", formatFloat(quantile.GetQuantile())), quantile.GetValue(), timestamp,
+					point.GetFlags())
+			}
+			add(name+"_sum", base, point.GetSum(), timestamp, point.GetFlags())
+			add(name+"_count", base, float64(point.GetCount()), timestamp, point.GetFlags())
+		}
+
+	case *metricpb.Metric_ExponentialHistogram:
+		c.dropped += len(data.ExponentialHistogram.GetDataPoints())
+	}
+
+	return latest
+}
+
+// addSeries adds a series of a single sample.
+func (c *converter) addSeries(name string, labels []Label, value float64, timestamp int64) {
+	labels = append(slices.Clone(labels), Label{Name: "__name__", Value: name})
+	slices.SortStableFunc(labels, func(a, b Label) int { return cmp.Compare(a.Name, b.Name) })
+	c.request.Timeseries = append(c.request.Timeseries, TimeSeries{
+		Labels:  labels,
+		Samples: []Sample{{Value: value, Timestamp: timestamp}},
+	})
+}
+
+// addMetadata adds the metadata of a metric family, once per request.
+func (c *converter) addMetadata(family string, metricType MetricType, help, unit string) {
+	if c.metadata[family] {
+		return
+	}
+	c.metadata[family] = true
+	c.request.Metadata = append(c.request.Metadata, Metadata{Type: metricType, Family: family, Help: help, Unit: unit})
+}
+
+// pointLabels returns the labels of a data point: the resource and scope labels followed by its attributes. Attributes
+// whose sanitized names collide have their values joined with semicolons, and attributes take precedence over the
+// resource and scope labels.
+func pointLabels(labels []Label, attrs []*commonpb.KeyValue) []Label {
+	result := slices.Clone(labels)
+	seen := make(map[string]int, len(attrs))
+	for _, attr := range attrs {
+		name := SanitizeLabel(attr.GetKey())
+		value := valueString(attr.GetValue())
+		if i, ok := seen[name]; ok {
+			result[i].Value += ";" + value
+			continue
+		}
+		if i := slices.IndexFunc(result, func(l Label) bool { return l.Name == name }); i >= 0 {
+			result[i].Value = value
+			seen[name] = i
+			continue
+		}
+		seen[name] = len(result)
+		result = append(result, Label{Name: name, Value: value})
+	}
+	return result
+}
+
+// withLabel returns a copy of the labels with one more label.
+func withLabel(labels []Label, name, value string) []Label {
+	return append(slices.Clone(labels), Label{Name: name, Value: value})
+}
+
+// numberValue returns the value of a number data point as a float.
+func numberValue(point *metricpb.NumberDataPoint) float64 {
+	if v, ok := point.GetValue().(*metricpb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return point.GetAsDouble()
+}
+
+// millis returns the Unix nanoseconds in milliseconds.
+func millis(nanos uint64) int64 {
+	return int64(nanos / 1e6)
+}
+
+// formatFloat formats a bucket bound or quantile the way Prometheus does.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// SanitizeName returns the metric name with the characters invalid in Prometheus metric names replaced with
+// underscores.
+func SanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// SanitizeLabel returns the attribute name with the characters invalid in Prometheus label names replaced with
+// underscores.
+func SanitizeLabel(name string) string {
+	return sanitize(name, false)
+}
+
+// sanitize replaces the characters other than letters, digits, underscores and, when allowed, colons with
+// underscores, and prefixes names starting with a digit with an underscore.
+func sanitize(name string, colons bool) string {
+	if name == "" {
+		return "_"
+	}
+
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', colons && r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// valueString returns the attribute value as a label value.
+func valueString(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// Encode returns the protobuf encoding of the request.
+func (r *WriteRequest) Encode() []byte {
+	var dst, series, label []byte
+	for _, ts := range r.Timeseries {
+		series = series[:0]
+		for _, l := range ts.Labels {
+			label = protowire.AppendTag(label[:0], 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.Name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.Value)
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+		for _, s := range ts.Samples {
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendVarint(series, uint64(sampleSize(s)))
+			series = protowire.AppendTag(series, 1, protowire.Fixed64Type)
+			series = protowire.AppendFixed64(series, math.Float64bits(s.Value))
+			series = protowire.AppendTag(series, 2, protowire.VarintType)
+			series = protowire.AppendVarint(series, uint64(s.Timestamp))
+		}
+		dst = protowire.AppendTag(dst, 1, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, series)
+	}
+
+	var metadata []byte
+	for _, m := range r.Metadata {
+		metadata = protowire.AppendTag(metadata[:0], 1, protowire.VarintType)
+		metadata = protowire.AppendVarint(metadata, uint64(m.Type))
+		metadata = protowire.AppendTag(metadata, 2, protowire.BytesType)
+		metadata = protowire.AppendString(metadata, m.Family)
+		if m.Help != "" {
+			metadata = protowire.AppendTag(metadata, 4, protowire.BytesType)
+			metadata = protowire.AppendString(metadata, m.Help)
+		}
+		if m.Unit != "" {
+			metadata = protowire.AppendTag(metadata, 5, protowire.BytesType)
+			metadata = protowire.AppendString(metadata, m.Unit)
+		}
+		dst = protowire.AppendTag(dst, 3, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, metadata)
+	}
+
+	return dst
+}
+
+// sampleSize returns the size of the encoding of a sample.
+func sampleSize(s Sample) int {
+	return protowire.SizeTag(1) + protowire.SizeFixed64() + protowire.SizeTag(2) + protowire.SizeVarint(uint64(s.Timestamp))
+}
@@ -0,0 +1,334 @@
+package remotewrite
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const ts = 1700000000000 // milliseconds
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func resourceMetrics(metrics ...*metricpb.Metric) []*metricpb.ResourceMetrics {
+	return []*metricpb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringAttr("service.name", "api"),
+			stringAttr("service.namespace", "shop"),
+			stringAttr("service.instance.id", "pod-1"),
+		}},
+		ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: metrics}},
+	}}
+}
+
+func series(name string, value float64, labels ...Label) TimeSeries {
+	all := append([]Label{{Name: "__name__", Value: name}, {Name: "instance", Value: "pod-1"}, {Name: "job", Value: "shop/api"}},
+		labels...)
+	slices.SortFunc(all, func(a, b Label) int { return strings.Compare(a.Name, b.Name) })
+	return TimeSeries{Labels: all, Samples: []Sample{{Value: value, Timestamp: ts}}}
+}
+
+func TestConvert(t *testing.T) {
+	sum := 12.5
+
+	tests := []struct {
+		name         string
+		resources    []*metricpb.ResourceMetrics
+		want         []TimeSeries
+		wantMetadata []Metadata
+		wantDropped  int
+	}{
+		{
+			name: "gauge",
+			resources: resourceMetrics(&metricpb.Metric{
+				Name: "memory.usage", Description: "Memory in use", Unit: "By",
+				Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+					TimeUnixNano: ts * 1e6,
+					Attributes:   []*commonpb.KeyValue{stringAttr("host.name", "node-1")},
+					Value:        &metricpb.NumberDataPoint_AsInt{AsInt: 42},
+				}}}},
+			}),
+			want:         []TimeSeries{series("memory_usage", 42, Label{Name: "host_name", Value: "node-1"})},
+			wantMetadata: []Metadata{{Type: MetricTypeGauge, Family: "memory_usage", Help: "Memory in use", Unit: "By"}},
+		},
+		{
+			name: "monotonic cumulative sum",
+			resources: resourceMetrics(&metricpb.Metric{
+				Name: "http.requests",
+				Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+					IsMonotonic:            true,
+					AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					DataPoints: []*metricpb.NumberDataPoint{{
+						TimeUnixNano: ts * 1e6,
+						Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: 3},
+					}},
+				}},
+			}),
+			want:         []TimeSeries{series("http_requests_total", 3)},
+			wantMetadata: []Metadata{{Type: MetricTypeCounter, Family: "http_requests_total"}},
+		},
+		{
+			name: "delta sum and exponential histogram",
+			resources: resourceMetrics(
+				&metricpb.Metric{Name: "delta", Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+					AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+					DataPoints:             []*metricpb.NumberDataPoint{{}, {}},
+				}}},
+				&metricpb.Metric{Name: "exp", Data: &metricpb.Metric_ExponentialHistogram{
+					ExponentialHistogram: &metricpb.ExponentialHistogram{
+						DataPoints: []*metricpb.ExponentialHistogramDataPoint{{}},
+					},
+				}},
+			),
+			wantDropped: 3,
+		},
+		{
+			name: "histogram",
+			resources: resourceMetrics(&metricpb.Metric{
+				Name: "latency",
+				Data: &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{
+					AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+					DataPoints: []*metricpb.HistogramDataPoint{{
+						TimeUnixNano:   ts * 1e6,
+						Count:          6,
+						Sum:            &sum,
+						ExplicitBounds: []float64{0.5, 1},
+						BucketCounts:   []uint64{1, 2, 3},
+					}},
+				}},
+			}),
+			want: []TimeSeries{
+				series("latency_bucket", 1, Label{Name: "le", Value: "0.5"}),
+				series("latency_bucket", 3, Label{Name: "le", Value: "1"}),
+				series("latency_bucket", 6, Label{Name: "le", Value: "+Inf"}),
+				series("latency_sum", 12.5),
+				series("latency_count", 6),
+			},
+			wantMetadata: []Metadata{{Type: MetricTypeHistogram, Family: "latency"}},
+		},
+		{
+			name: "summary",
+			resources: resourceMetrics(&metricpb.Metric{
+				Name: "rpc",
+				Data: &metricpb.Metric_Summary{Summary: &metricpb.Summary{DataPoints: []*metricpb.SummaryDataPoint{{
+					TimeUnixNano:   ts * 1e6,
+					Count:          4,
+					Sum:            10,
+					QuantileValues: []*metricpb.SummaryDataPoint_ValueAtQuantile{{Quantile: 0.99, Value: 7}},
+				}}}},
+			}),
+			want: []TimeSeries{
+				series("rpc", 7, Label{Name: "quantile", Value: "0.99"}),
+				series("rpc_sum", 10),
+				series("rpc_count", 4),
+			},
+			wantMetadata: []Metadata{{Type: MetricTypeSummary, Family: "rpc"}},
+		},
+		{
+			name: "colliding attributes",
+			resources: resourceMetrics(&metricpb.Metric{
+				Name: "g",
+				Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+					TimeUnixNano: ts * 1e6,
+					Attributes:   []*commonpb.KeyValue{stringAttr("a.b", "1"), stringAttr("a-b", "2"), stringAttr("job", "override")},
+				}}}},
+			}),
+			want: []TimeSeries{{
+				Labels: []Label{
+					{Name: "__name__", Value: "g"},
+					{Name: "a_b", Value: "1;2"},
+					{Name: "instance", Value: "pod-1"},
+					{Name: "job", Value: "override"},
+				},
+				Samples: []Sample{{Value: 0, Timestamp: ts}},
+			}},
+			wantMetadata: []Metadata{{Type: MetricTypeGauge, Family: "g"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := Convert(tt.resources)
+			assert.Equal(t, tt.want, got.Timeseries)
+			assert.Equal(t, tt.wantMetadata, got.Metadata)
+			assert.Equal(t, tt.wantDropped, dropped)
+		})
+	}
+}
+
+func TestConvert_TargetInfo(t *testing.T) {
+	resources := []*metricpb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			stringAttr("service.name", "api"),
+			stringAttr("k8s.namespace.name", "prod"),
+		}},
+		ScopeMetrics: []*metricpb.ScopeMetrics{{
+			Scope: &commonpb.InstrumentationScope{Name: "meter", Version: "1.2.3"},
+			Metrics: []*metricpb.Metric{{
+				Name: "g",
+				Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{
+					{TimeUnixNano: (ts - 1000) * 1e6},
+					{TimeUnixNano: ts * 1e6, Flags: uint32(metricpb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)},
+				}}},
+			}},
+		}},
+	}}
+
+	got, _ := Convert(resources)
+	require.Len(t, got.Timeseries, 3)
+
+	// Points without a recorded value mark their series as stale
+	assert.True(t, math.IsNaN(got.Timeseries[1].Samples[0].Value))
+	assert.Equal(t, []Label{
+		{Name: "__name__", Value: "g"},
+		{Name: "job", Value: "api"},
+		{Name: "otel_scope_name", Value: "meter"},
+		{Name: "otel_scope_version", Value: "1.2.3"},
+	}, got.Timeseries[0].Labels)
+
+	// The other resource attributes are written once, at the latest timestamp of the resource
+	assert.Equal(t, TimeSeries{
+		Labels: []Label{
+			{Name: "__name__", Value: "target_info"},
+			{Name: "job", Value: "api"},
+			{Name: "k8s_namespace_name", Value: "prod"},
+		},
+		Samples: []Sample{{Value: 1, Timestamp: ts}},
+	}, got.Timeseries[2])
+}
+
+func TestMarshal(t *testing.T) {
+	body, dropped := Marshal(resourceMetrics(&metricpb.Metric{
+		Name: "up",
+		Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+			TimeUnixNano: ts * 1e6,
+			Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: 1},
+		}}}},
+	}))
+	assert.Zero(t, dropped)
+
+	encoded, err := snappy.Decode(nil, body)
+	require.NoError(t, err)
+	assert.Equal(t, &WriteRequest{
+		Timeseries: []TimeSeries{series("up", 1)},
+		Metadata:   []Metadata{{Type: MetricTypeGauge, Family: "up"}},
+	}, decode(t, encoded))
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		wantName  string
+		wantLabel string
+	}{
+		{name: "valid", in: "http_requests", wantName: "http_requests", wantLabel: "http_requests"},
+		{name: "dots", in: "http.server.duration", wantName: "http_server_duration", wantLabel: "http_server_duration"},
+		{name: "colons", in: "job:rate5m", wantName: "job:rate5m", wantLabel: "job_rate5m"},
+		{name: "leading digit", in: "5xx", wantName: "_5xx", wantLabel: "_5xx"},
+		{name: "empty", in: "", wantName: "_", wantLabel: "_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantName, SanitizeName(tt.in))
+			assert.Equal(t, tt.wantLabel, SanitizeLabel(tt.in))
+		})
+	}
+}
+
+// decode decodes a remote write request encoded by Encode.
+func decode(t *testing.T, b []byte) *WriteRequest {
+	t.Helper()
+
+	request := &WriteRequest{}
+	fields(t, b, func(num protowire.Number, v []byte, _ uint64) {
+		switch num {
+		case 1:
+			var ts TimeSeries
+			fields(t, v, func(num protowire.Number, v []byte, _ uint64) {
+				switch num {
+				case 1:
+					var l Label
+					fields(t, v, func(num protowire.Number, v []byte, _ uint64) {
+						if num == 1 {
+							l.Name = string(v)
+						} else {
+							l.Value = string(v)
+						}
+					})
+					ts.Labels = append(ts.Labels, l)
+				case 2:
+					var s Sample
+					fields(t, v, func(num protowire.Number, _ []byte, n uint64) {
+						if num == 1 {
+							s.Value = math.Float64frombits(n)
+						} else {
+							s.Timestamp = int64(n)
+						}
+					})
+					ts.Samples = append(ts.Samples, s)
+				}
+			})
+			request.Timeseries = append(request.Timeseries, ts)
+		case 3:
+			var m Metadata
+			fields(t, v, func(num protowire.Number, v []byte, n uint64) {
+				switch num {
+				case 1:
+					m.Type = MetricType(n)
+				case 2:
+					m.Family = string(v)
+				case 4:
+					m.Help = string(v)
+				case 5:
+					m.Unit = string(v)
+				}
+			})
+			request.Metadata = append(request.Metadata, m)
+		}
+	})
+	return request
+}
+
+// fields calls fn with the number and the bytes or numeric value of every field of a message.
+func fields(t *testing.T, b []byte, fn func(num protowire.Number, v []byte, n uint64)) {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fn(num, v, 0)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fn(num, nil, v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			require.GreaterOrEqual(t, n, 0)
+			fn(num, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package requestmeta carries the metadata of an inbound request through the context.
+//
+// The handlers record who sent a request (the client address, its user agent
+// and the tenant that signed it) once, when the request is received. The
+// metadata then follows the request through tenant partitioning, dispatch and
+// the backend requests it results in, so logs and spans at every stage can be
+// attributed to the sender without passing the request around.
+package requestmeta
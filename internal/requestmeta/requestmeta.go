@@ -0,0 +1,53 @@
+// Package requestmeta carries the metadata of an inbound request through the context.
+package requestmeta
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	clientAddressAttrKey = "client.address"
+	userAgentAttrKey     = "user_agent.original"
+	signerAttrKey        = "signature.tenant"
+)
+
+// Metadata describes the sender of an inbound request.
+type Metadata struct {
+	// ClientAddress is the address of the client, resolved through the trusted proxies.
+	ClientAddress string
+	// UserAgent is the user agent of the client.
+	UserAgent string
+	// Signer is the tenant that signed the request, empty when the request was not signed.
+	Signer string
+}
+
+// metadataKey is the context key of the metadata.
+type metadataKey struct{}
+
+// NewContext returns a copy of the context carrying the metadata.
+func NewContext(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// FromContext returns the metadata carried by the context, or the zero Metadata when there is none.
+func FromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
+
+// Attributes returns the attributes of the metadata that are set, for logs and spans.
+func (m Metadata) Attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if m.ClientAddress != "" {
+		attrs = append(attrs, attribute.String(clientAddressAttrKey, m.ClientAddress))
+	}
+	if m.UserAgent != "" {
+		attrs = append(attrs, attribute.String(userAgentAttrKey, m.UserAgent))
+	}
+	if m.Signer != "" {
+		attrs = append(attrs, attribute.String(signerAttrKey, m.Signer))
+	}
+	return attrs
+}
@@ -0,0 +1,55 @@
+package requestmeta
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Metadata{}, FromContext(context.Background()))
+
+	metadata := Metadata{ClientAddress: "10.0.0.1", UserAgent: "otel-collector", Signer: "acme"}
+	ctx := NewContext(context.Background(), metadata)
+	assert.Equal(t, metadata, FromContext(ctx))
+
+	// The metadata of the context is not changed by derived contexts
+	signed := FromContext(ctx)
+	signed.Signer = "globex"
+	assert.Equal(t, "globex", FromContext(NewContext(ctx, signed)).Signer)
+	assert.Equal(t, "acme", FromContext(ctx).Signer)
+}
+
+func TestMetadata_Attributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata Metadata
+		want     []attribute.KeyValue
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "unsigned request",
+			metadata: Metadata{ClientAddress: "10.0.0.1"},
+			want:     []attribute.KeyValue{attribute.String(clientAddressAttrKey, "10.0.0.1")},
+		},
+		{
+			name:     "signed request",
+			metadata: Metadata{ClientAddress: "10.0.0.1", UserAgent: "otel-collector", Signer: "acme"},
+			want: []attribute.KeyValue{
+				attribute.String(clientAddressAttrKey, "10.0.0.1"),
+				attribute.String(userAgentAttrKey, "otel-collector"),
+				attribute.String(signerAttrKey, "acme"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.metadata.Attributes())
+		})
+	}
+}
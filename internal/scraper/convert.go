@@ -0,0 +1,123 @@
+// Package scraper provides a scraper pushing Prometheus metrics through the metrics pipeline.
+package scraper
+
+import (
+	"math"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// convertFamily converts a Prometheus metric family into an OTLP metric, samples without a timestamp are given the
+// scrape time.
+func convertFamily(family *dto.MetricFamily, scraped time.Time) *metricpb.Metric {
+	metric := &metricpb.Metric{Name: family.GetName(), Description: family.GetHelp(), Unit: family.GetUnit()}
+
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := &metricpb.Sum{
+			AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}
+		for _, m := range family.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, numberDataPoint(m, m.GetCounter().GetValue(), scraped))
+		}
+		metric.Data = &metricpb.Metric_Sum{Sum: sum}
+	case dto.MetricType_SUMMARY:
+		summary := &metricpb.Summary{}
+		for _, m := range family.GetMetric() {
+			point := &metricpb.SummaryDataPoint{
+				Attributes:   attributes(m),
+				TimeUnixNano: timestamp(m, scraped),
+				Count:        m.GetSummary().GetSampleCount(),
+				Sum:          m.GetSummary().GetSampleSum(),
+			}
+			for _, q := range m.GetSummary().GetQuantile() {
+				point.QuantileValues = append(point.QuantileValues, &metricpb.SummaryDataPoint_ValueAtQuantile{
+					Quantile: q.GetQuantile(),
+					Value:    q.GetValue(),
+				})
+			}
+			summary.DataPoints = append(summary.DataPoints, point)
+		}
+		metric.Data = &metricpb.Metric_Summary{Summary: summary}
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		histogram := &metricpb.Histogram{
+			AggregationTemporality: metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}
+		for _, m := range family.GetMetric() {
+			histogram.DataPoints = append(histogram.DataPoints, histogramDataPoint(m, scraped))
+		}
+		metric.Data = &metricpb.Metric_Histogram{Histogram: histogram}
+	default:
+		gauge := &metricpb.Gauge{}
+		for _, m := range family.GetMetric() {
+			value := m.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = m.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, numberDataPoint(m, value, scraped))
+		}
+		metric.Data = &metricpb.Metric_Gauge{Gauge: gauge}
+	}
+
+	return metric
+}
+
+// numberDataPoint returns the number data point of a sample.
+func numberDataPoint(m *dto.Metric, value float64, scraped time.Time) *metricpb.NumberDataPoint {
+	return &metricpb.NumberDataPoint{
+		Attributes:   attributes(m),
+		TimeUnixNano: timestamp(m, scraped),
+		Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// histogramDataPoint converts the cumulative buckets of a classic histogram into explicit bucket counts.
+func histogramDataPoint(m *dto.Metric, scraped time.Time) *metricpb.HistogramDataPoint {
+	h := m.GetHistogram()
+	sum := h.GetSampleSum()
+	point := &metricpb.HistogramDataPoint{
+		Attributes:   attributes(m),
+		TimeUnixNano: timestamp(m, scraped),
+		Count:        h.GetSampleCount(),
+		Sum:          &sum,
+	}
+
+	var previous uint64
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-min(previous, h.GetSampleCount()))
+
+	return point
+}
+
+// attributes returns the labels of a sample as attributes.
+func attributes(m *dto.Metric) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		attributes = append(attributes, stringKeyValue(label.GetName(), label.GetValue()))
+	}
+	return attributes
+}
+
+// timestamp returns the timestamp of a sample, or the scrape time when it has none.
+func timestamp(m *dto.Metric, scraped time.Time) uint64 {
+	if m.TimestampMs != nil {
+		return uint64(time.UnixMilli(m.GetTimestampMs()).UnixNano())
+	}
+	return uint64(scraped.UnixNano())
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
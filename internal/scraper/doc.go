@@ -0,0 +1,16 @@
+// Package scraper provides a scraper pushing Prometheus metrics through the metrics pipeline.
+//
+// Edge sites without a local collector can have the proxy scrape their
+// Prometheus targets. The targets are read from a static list and an optional
+// Prometheus file_sd file, which is re-read before every scrape. The text
+// exposition format of each target is converted into OTLP metrics:
+//   - Counters become cumulative monotonic sums
+//   - Gauges and untyped metrics become gauges
+//   - Summaries become summaries and histograms become explicit bucket histograms
+//   - Labels become data point attributes
+//   - An up gauge reports whether the scrape succeeded
+//
+// Every target becomes one resource carrying its tenant, its address as
+// service.instance.id and its file_sd labels, so the metrics of each target
+// are partitioned to its own tenant.
+package scraper
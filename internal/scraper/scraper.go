@@ -0,0 +1,196 @@
+// Package scraper provides a scraper pushing Prometheus metrics through the metrics pipeline.
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.opentelemetry.io/otel/attribute"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+var (
+	targetAttrKey = "scrape.target"
+	errAttrKey    = "error"
+
+	instanceAttrKey = "service.instance.id"
+)
+
+// scopeName is the instrumentation scope of the scraped metrics.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/scraper"
+
+// upMetric reports whether the last scrape of a target succeeded.
+const upMetric = "up"
+
+// acceptHeader requests the Prometheus text exposition format.
+const acceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+
+// Sink receives the metric resources scraped from the targets.
+type Sink func(ctx context.Context, resources []*metricpb.ResourceMetrics) error
+
+// Scraper periodically scrapes Prometheus targets and hands the converted metrics to a Sink.
+type Scraper struct {
+	config  *config.Scrape
+	tenant  *config.Tenant
+	sink    Sink
+	client  *http.Client
+	targets []Target
+	now     func() time.Time
+}
+
+// New creates a new Scraper handing the scraped metrics to the sink.
+func New(config *config.Scrape, tenant *config.Tenant, sink Sink) (*Scraper, error) {
+	targets, err := parseTargets(config.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scraper{
+		config:  config,
+		tenant:  tenant,
+		sink:    sink,
+		client:  &http.Client{Timeout: config.Timeout},
+		targets: targets,
+		now:     time.Now,
+	}, nil
+}
+
+// Run scrapes the targets every interval until the context is cancelled.
+func (s *Scraper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Scrape(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scrape scrapes the static and file_sd targets concurrently and hands the metrics of all targets to the sink.
+func (s *Scraper) Scrape(ctx context.Context) {
+	targets := slices.Clone(s.targets)
+	if s.config.FileSD != "" {
+		discovered, err := readFileSD(s.config.FileSD, s.config.TenantLabel)
+		if err != nil {
+			logger.Error(ctx, "failed to read scrape targets", attribute.String(errAttrKey, err.Error()))
+		}
+		targets = append(targets, discovered...)
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	resources := make([]*metricpb.ResourceMetrics, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Go(func() { resources[i] = s.scrape(ctx, target) })
+	}
+	wg.Wait()
+
+	if err := s.sink(ctx, resources); err != nil {
+		logger.Error(ctx, "failed to forward scraped metrics", attribute.String(errAttrKey, err.Error()))
+	}
+}
+
+// scrape scrapes a target, returning its metrics together with the up metric.
+func (s *Scraper) scrape(ctx context.Context, target Target) *metricpb.ResourceMetrics {
+	scraped := s.now()
+	families, err := s.fetch(ctx, target.URL)
+
+	up := 1.0
+	if err != nil {
+		up = 0
+		logger.Warn(ctx, "failed to scrape target", attribute.String(targetAttrKey, target.URL), attribute.String(errAttrKey, err.Error()))
+	}
+
+	metrics := make([]*metricpb.Metric, 0, len(families)+1)
+	for _, family := range families {
+		metrics = append(metrics, convertFamily(family, scraped))
+	}
+	metrics = append(metrics, &metricpb.Metric{
+		Name: upMetric,
+		Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+			TimeUnixNano: uint64(scraped.UnixNano()),
+			Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: up},
+		}}}},
+	})
+
+	return &metricpb.ResourceMetrics{
+		Resource: &resourcepb.Resource{Attributes: s.resourceAttributes(target)},
+		ScopeMetrics: []*metricpb.ScopeMetrics{{
+			Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+			Metrics: metrics,
+		}},
+	}
+}
+
+// fetch scrapes the metric families of a target.
+func (s *Scraper) fetch(ctx context.Context, target string) ([]*dto.MetricFamily, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var families []*dto.MetricFamily
+	decoder := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header))
+	for {
+		family := &dto.MetricFamily{}
+		if err := decoder.Decode(family); err != nil {
+			if errors.Is(err, io.EOF) {
+				return families, nil
+			}
+			return nil, err
+		}
+		families = append(families, family)
+	}
+}
+
+// resourceAttributes returns the tenant, instance and target labels of a target as resource attributes.
+func (s *Scraper) resourceAttributes(target Target) []*commonpb.KeyValue {
+	var attributes []*commonpb.KeyValue
+	if target.Tenant != "" {
+		attributes = append(attributes, stringKeyValue(s.tenant.Label, target.Tenant))
+	}
+	if u, err := url.Parse(target.URL); err == nil {
+		attributes = append(attributes, stringKeyValue(instanceAttrKey, u.Host))
+	}
+
+	names := make([]string, 0, len(target.Labels))
+	for name := range target.Labels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		attributes = append(attributes, stringKeyValue(name, target.Labels[name]))
+	}
+	return attributes
+}
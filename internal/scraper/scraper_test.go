@@ -0,0 +1,187 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+const exposition = `# HELP http_requests_total Total requests.
+# TYPE http_requests_total counter
+http_requests_total{code="200"} 10
+# TYPE temperature gauge
+temperature 21.5 1700000000000
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 5
+latency_seconds_bucket{le="+Inf"} 6
+latency_seconds_sum 3.5
+latency_seconds_count 6
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5"} 0.2
+rpc_seconds_sum 1
+rpc_seconds_count 4
+`
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name:    "with and without tenant",
+			entries: []string{"http://10.0.0.1:9100/metrics=tenant-a", " http://10.0.0.2:9100/metrics ", ""},
+			want: []Target{
+				{URL: "http://10.0.0.1:9100/metrics", Tenant: "tenant-a"},
+				{URL: "http://10.0.0.2:9100/metrics"},
+			},
+		},
+		{
+			name:    "missing scheme",
+			entries: []string{"10.0.0.1:9100/metrics"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTargets(tt.entries)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadFileSD(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Target
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			content: `[{"targets":["10.0.0.1:9100","10.0.0.2:9100"],"labels":{"tenant":"tenant-a","site":"lon","__scheme__":"https"}}]`,
+			want: []Target{
+				{URL: "https://10.0.0.1:9100/metrics", Tenant: "tenant-a", Labels: map[string]string{"site": "lon"}},
+				{URL: "https://10.0.0.2:9100/metrics", Tenant: "tenant-a", Labels: map[string]string{"site": "lon"}},
+			},
+		},
+		{
+			name:    "yaml",
+			content: "- targets: [\"10.0.0.3:8080\"]\n  labels:\n    __metrics_path__: /federate\n",
+			want:    []Target{{URL: "http://10.0.0.3:8080/federate", Labels: map[string]string{}}},
+		},
+		{
+			name:    "invalid",
+			content: `{"targets":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "targets.json")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := readFileSD(path, "tenant")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScraper_Scrape(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(exposition))
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000100, 0)
+	var received []*metricpb.ResourceMetrics
+	s, err := New(
+		&config.Scrape{Targets: []string{server.URL + "/metrics=tenant-a", server.URL + "/missing"}, Timeout: time.Second},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*metricpb.ResourceMetrics) error {
+			received = resources
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	s.now = func() time.Time { return now }
+
+	s.Scrape(t.Context())
+
+	require.Len(t, received, 2)
+	host := strings.TrimPrefix(server.URL, "http://")
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue("tenant.id", "tenant-a"),
+		stringKeyValue(instanceAttrKey, host),
+	}, received[0].GetResource().GetAttributes())
+
+	metrics := make(map[string]*metricpb.Metric)
+	for _, metric := range received[0].GetScopeMetrics()[0].GetMetrics() {
+		metrics[metric.GetName()] = metric
+	}
+	require.Len(t, metrics, 5)
+
+	requests := metrics["http_requests_total"]
+	assert.Equal(t, "Total requests.", requests.GetDescription())
+	assert.True(t, requests.GetSum().GetIsMonotonic())
+	assert.Equal(t, &metricpb.NumberDataPoint{
+		Attributes:   []*commonpb.KeyValue{stringKeyValue("code", "200")},
+		TimeUnixNano: uint64(now.UnixNano()),
+		Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: 10},
+	}, requests.GetSum().GetDataPoints()[0])
+
+	temperature := metrics["temperature"].GetGauge().GetDataPoints()[0]
+	assert.Equal(t, 21.5, temperature.GetAsDouble())
+	assert.Equal(t, uint64(time.UnixMilli(1700000000000).UnixNano()), temperature.GetTimeUnixNano())
+
+	latency := metrics["latency_seconds"].GetHistogram().GetDataPoints()[0]
+	assert.Equal(t, uint64(6), latency.GetCount())
+	assert.Equal(t, 3.5, latency.GetSum())
+	assert.Equal(t, []float64{0.1, 1}, latency.GetExplicitBounds())
+	assert.Equal(t, []uint64{2, 3, 1}, latency.GetBucketCounts())
+
+	rpc := metrics["rpc_seconds"].GetSummary().GetDataPoints()[0]
+	assert.Equal(t, uint64(4), rpc.GetCount())
+	assert.Equal(t, []*metricpb.SummaryDataPoint_ValueAtQuantile{{Quantile: 0.5, Value: 0.2}}, rpc.GetQuantileValues())
+
+	assert.Equal(t, 1.0, metrics[upMetric].GetGauge().GetDataPoints()[0].GetAsDouble())
+
+	// The failed target only reports that it is down.
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue(instanceAttrKey, host)}, received[1].GetResource().GetAttributes())
+	down := received[1].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, down, 1)
+	assert.Equal(t, upMetric, down[0].GetName())
+	assert.Equal(t, 0.0, down[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+}
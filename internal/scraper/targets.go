@@ -0,0 +1,97 @@
+// Package scraper provides a scraper pushing Prometheus metrics through the metrics pipeline.
+package scraper
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prometheus target labels of file_sd target groups.
+const (
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+	reservedPrefix   = "__"
+)
+
+// Target is a scrape target.
+type Target struct {
+	URL    string
+	Tenant string
+	Labels map[string]string
+}
+
+// targetGroup is a file_sd target group.
+type targetGroup struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+// parseTargets parses static targets of the form url or url=tenant, the tenant follows the last equals sign.
+func parseTargets(entries []string) ([]Target, error) {
+	targets := make([]Target, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		target := Target{URL: entry}
+		if i := strings.LastIndex(entry, "="); i >= 0 {
+			target.URL, target.Tenant = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+
+		if u, err := url.Parse(target.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid scrape target %q", entry)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// readFileSD reads the targets of a Prometheus file_sd file, in YAML or JSON.
+//
+// The scheme and metrics path are taken from the __scheme__ and __metrics_path__ labels, the tenant from the tenant
+// label, and the remaining labels not starting with __ are kept as target labels.
+func readFileSD(path, tenantLabel string) ([]Target, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	if err := yaml.Unmarshal(b, &groups); err != nil {
+		return nil, fmt.Errorf("invalid file_sd file %s: %w", path, err)
+	}
+
+	var targets []Target
+	for _, group := range groups {
+		scheme, path := group.Labels[schemeLabel], group.Labels[metricsPathLabel]
+		if scheme == "" {
+			scheme = "http"
+		}
+		if path == "" {
+			path = "/metrics"
+		}
+
+		labels := make(map[string]string)
+		for name, value := range group.Labels {
+			if name != tenantLabel && !strings.HasPrefix(name, reservedPrefix) {
+				labels[name] = value
+			}
+		}
+
+		for _, address := range group.Targets {
+			targets = append(targets, Target{
+				URL:    (&url.URL{Scheme: scheme, Host: address, Path: path}).String(),
+				Tenant: group.Labels[tenantLabel],
+				Labels: maps.Clone(labels),
+			})
+		}
+	}
+	return targets, nil
+}
@@ -0,0 +1,14 @@
+// Package signature verifies the HMAC signatures of inbound requests.
+//
+// Each tenant allowed to sign requests shares a secret with the proxy. A client
+// signs the raw request body, as sent on the wire, with HMAC-SHA256 and the
+// secret of its tenant, and sends the signature as sha256=<hex> along with the
+// tenant as the key ID:
+//
+//	X-Signature-Key-Id: team-a
+//	X-Signature: sha256=5d41402abc4b2a76b9719d911017c592...
+//
+// A Verifier rejects requests without a signature, signed with an unknown key
+// ID, or whose signature does not match the body, and returns the tenant of
+// the key so the request can be bound to it.
+package signature
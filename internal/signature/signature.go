@@ -0,0 +1,165 @@
+// Package signature verifies the HMAC signatures of inbound requests.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+)
+
+// prefix is the prefix of the hex encoded HMAC-SHA256 signatures.
+const prefix = "sha256="
+
+// Default headers carrying the signature and the key ID.
+const (
+	defaultHeader      = "X-Signature"
+	defaultKeyIDHeader = "X-Signature-Key-Id"
+)
+
+// Reasons a request signature is rejected.
+var (
+	ErrMissing    = errors.New("missing request signature")
+	ErrUnknownKey = errors.New("unknown request signature key id")
+	ErrMismatch   = errors.New("request signature does not match the body")
+)
+
+// Verifier verifies the signatures of inbound requests against the secrets of the tenants.
+type Verifier struct {
+	header      string
+	keyIDHeader string
+	secrets     map[string][]byte
+}
+
+// New creates a new Verifier from the configured tenant secrets of the form tenant=secret. It returns nil when no
+// secret is configured, so requests are not verified.
+func New(cfg *config.Signature) (*Verifier, error) {
+	secrets, err := parseSecrets(cfg.Secrets)
+	if err != nil || len(secrets) == 0 {
+		return nil, err
+	}
+
+	return &Verifier{
+		header:      cfg.Header,
+		keyIDHeader: cfg.KeyIDHeader,
+		secrets:     secrets,
+	}, nil
+}
+
+// Signer returns the tenant of the key named by the headers, or an error when the headers carry no signature or name
+// an unknown key. It only needs the headers, so requests that cannot be verified are rejected before their body is
+// read.
+func (v *Verifier) Signer(header http.Header) (string, error) {
+	signature := strings.TrimSpace(header.Get(v.header))
+	tenant := strings.TrimSpace(header.Get(v.keyIDHeader))
+	if signature == "" || tenant == "" {
+		return "", ErrMissing
+	}
+	if _, ok := v.secrets[tenant]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, tenant)
+	}
+	return tenant, nil
+}
+
+// Verify verifies the signature of the request body carried by the headers, returning the tenant of the key that
+// signed it.
+func (v *Verifier) Verify(header http.Header, body []byte) (string, error) {
+	tenant, err := v.Signer(header)
+	if err != nil {
+		return "", err
+	}
+	signature, secret := strings.TrimSpace(header.Get(v.header)), v.secrets[tenant]
+
+	presented, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil || !strings.HasPrefix(signature, prefix) || !hmac.Equal(presented, sum(secret, body)) {
+		return "", ErrMismatch
+	}
+	return tenant, nil
+}
+
+// parseSecrets parses tenant secrets of the form tenant=secret.
+func parseSecrets(entries []string) (map[string][]byte, error) {
+	secrets := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tenant, secret, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" || secret == "" {
+			return nil, errors.New("invalid request signature secret, expected tenant=secret")
+		}
+		if _, ok := secrets[tenant]; ok {
+			return nil, fmt.Errorf("multiple request signature secrets for tenant %q", tenant)
+		}
+		secrets[tenant] = []byte(secret)
+	}
+	return secrets, nil
+}
+
+// anyTenant is the tenant of the secret signing the requests of the tenants without a secret of their own.
+const anyTenant = "*"
+
+// Signer signs the bodies of outbound requests with the secrets of their tenants, in the format the Verifier expects.
+type Signer struct {
+	header      string
+	keyIDHeader string
+	secrets     map[string][]byte
+}
+
+// NewSigner creates a new Signer from the configured tenant secrets of the form tenant=secret, the secret of the *
+// tenant signing the requests of the other tenants. It returns nil when no secret is configured, so requests are not
+// signed.
+func NewSigner(cfg *config.Signing) (*Signer, error) {
+	secrets, err := parseSecrets(cfg.Secrets)
+	if err != nil || len(secrets) == 0 {
+		return nil, err
+	}
+
+	signer := &Signer{header: cfg.Header, keyIDHeader: cfg.KeyIDHeader, secrets: secrets}
+	if signer.header == "" {
+		signer.header = defaultHeader
+	}
+	if signer.keyIDHeader == "" {
+		signer.keyIDHeader = defaultKeyIDHeader
+	}
+	return signer, nil
+}
+
+// Sign sets the signature of the body with the secret of the tenant and the tenant as the key ID, leaving the headers
+// unchanged when the tenant has no secret. It reports whether the headers were signed.
+func (s *Signer) Sign(header http.Header, tenant string, body []byte) bool {
+	if s == nil {
+		return false
+	}
+
+	secret, ok := s.secrets[tenant]
+	if !ok {
+		if secret, ok = s.secrets[anyTenant]; !ok {
+			return false
+		}
+	}
+
+	header.Set(s.header, Sign(secret, body))
+	header.Set(s.keyIDHeader, tenant)
+	return true
+}
+
+// Sign returns the signature of the body with the secret, in the format expected in the signature header.
+func Sign(secret, body []byte) string {
+	return prefix + hex.EncodeToString(sum(secret, body))
+}
+
+// sum returns the HMAC-SHA256 of the body with the secret.
+func sum(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,193 @@
+package signature
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "empty entries", secrets: []string{"", " "}, wantNil: true},
+		{name: "secrets", secrets: []string{"team-a=s3cr3t", " team-b = with=equals"}},
+		{name: "missing secret", secrets: []string{"team-a="}, wantErr: true},
+		{name: "missing tenant", secrets: []string{"=s3cr3t"}, wantErr: true},
+		{name: "missing separator", secrets: []string{"team-a"}, wantErr: true},
+		{name: "duplicate tenant", secrets: []string{"team-a=one", "team-a=two"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := New(&config.Signature{Header: "X-Signature", KeyIDHeader: "X-Signature-Key-Id", Secrets: tt.secrets})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNil, v == nil)
+		})
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	v, err := New(&config.Signature{
+		Header:      "X-Signature",
+		KeyIDHeader: "X-Signature-Key-Id",
+		Secrets:     []string{"team-a=s3cr3t", "team-b=other"},
+	})
+	require.NoError(t, err)
+
+	body := []byte("payload")
+
+	tests := []struct {
+		name       string
+		keyID      string
+		signature  string
+		wantTenant string
+		wantErr    error
+	}{
+		{name: "valid", keyID: "team-a", signature: Sign([]byte("s3cr3t"), body), wantTenant: "team-a"},
+		{name: "missing signature", keyID: "team-a", wantErr: ErrMissing},
+		{name: "missing key id", signature: Sign([]byte("s3cr3t"), body), wantErr: ErrMissing},
+		{name: "unknown key id", keyID: "team-c", signature: Sign([]byte("s3cr3t"), body), wantErr: ErrUnknownKey},
+		{name: "signed by another tenant", keyID: "team-b", signature: Sign([]byte("s3cr3t"), body), wantErr: ErrMismatch},
+		{name: "tampered body", keyID: "team-a", signature: Sign([]byte("s3cr3t"), []byte("tampered")), wantErr: ErrMismatch},
+		{name: "missing prefix", keyID: "team-a", signature: Sign([]byte("s3cr3t"), body)[len(prefix):], wantErr: ErrMismatch},
+		{name: "not hex", keyID: "team-a", signature: "sha256=zz", wantErr: ErrMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.keyID != "" {
+				header.Set("X-Signature-Key-Id", tt.keyID)
+			}
+			if tt.signature != "" {
+				header.Set("X-Signature", tt.signature)
+			}
+
+			tenant, err := v.Verify(header, body)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTenant, tenant)
+		})
+	}
+}
+
+func TestVerifier_Signer(t *testing.T) {
+	v, err := New(&config.Signature{
+		Header:      "X-Signature",
+		KeyIDHeader: "X-Signature-Key-Id",
+		Secrets:     []string{"team-a=s3cr3t"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		keyID      string
+		signature  string
+		wantTenant string
+		wantErr    error
+	}{
+		// The signature itself is only checked against the body by Verify
+		{name: "known key", keyID: "team-a", signature: "sha256=00", wantTenant: "team-a"},
+		{name: "missing signature", keyID: "team-a", wantErr: ErrMissing},
+		{name: "missing key id", signature: "sha256=00", wantErr: ErrMissing},
+		{name: "unknown key id", keyID: "team-c", signature: "sha256=00", wantErr: ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.keyID != "" {
+				header.Set("X-Signature-Key-Id", tt.keyID)
+			}
+			if tt.signature != "" {
+				header.Set("X-Signature", tt.signature)
+			}
+
+			tenant, err := v.Signer(header)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantTenant, tenant)
+		})
+	}
+}
+
+func TestSigner_Sign(t *testing.T) {
+	signer, err := NewSigner(&config.Signing{Secrets: []string{"team-a=s3cr3t", "*=shared"}})
+	require.NoError(t, err)
+	verifier, err := New(&config.Signature{
+		Header:      "X-Signature",
+		KeyIDHeader: "X-Signature-Key-Id",
+		Secrets:     []string{"team-a=s3cr3t", "team-b=shared"},
+	})
+	require.NoError(t, err)
+
+	body := []byte("payload")
+
+	tests := []struct {
+		name       string
+		signer     *Signer
+		tenant     string
+		wantSigned bool
+	}{
+		{name: "tenant secret", signer: signer, tenant: "team-a", wantSigned: true},
+		{name: "shared secret", signer: signer, tenant: "team-b", wantSigned: true},
+		{name: "disabled", tenant: "team-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			assert.Equal(t, tt.wantSigned, tt.signer.Sign(header, tt.tenant, body))
+			if !tt.wantSigned {
+				assert.Empty(t, header)
+				return
+			}
+
+			// The signed headers verify with the secret of the tenant on the receiving side
+			tenant, err := verifier.Verify(header, body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	signer, err := NewSigner(&config.Signing{})
+	require.NoError(t, err)
+	assert.Nil(t, signer)
+
+	_, err = NewSigner(&config.Signing{Secrets: []string{"team-a"}})
+	assert.Error(t, err)
+
+	signer, err = NewSigner(&config.Signing{
+		Header:      "X-Payload-Signature",
+		KeyIDHeader: "X-Payload-Tenant",
+		Secrets:     []string{"team-a=s3cr3t"},
+	})
+	require.NoError(t, err)
+	header := http.Header{}
+	require.True(t, signer.Sign(header, "team-a", []byte("payload")))
+	assert.Equal(t, Sign([]byte("s3cr3t"), []byte("payload")), header.Get("X-Payload-Signature"))
+	assert.Equal(t, "team-a", header.Get("X-Payload-Tenant"))
+
+	// Tenants without a secret are not signed when there is no shared secret
+	assert.False(t, signer.Sign(http.Header{}, "team-b", []byte("payload")))
+}
@@ -0,0 +1,13 @@
+// Package startup summarizes the configuration of the proxy when it boots.
+//
+// The banner is a single set of log attributes telling where the proxy
+// listens, which signals it forwards, where to and over which protocol, the
+// TLS mode of the listener and of each backend, and how tenants are resolved.
+// Backend addresses are redacted of their password.
+//
+// The self-check returns a warning for every suspicious combination of
+// settings that is accepted but unlikely to be meant, such as TLS files
+// partially set, which leave TLS disabled, or backend credentials sent over
+// plaintext HTTP, so misconfigurations are visible as soon as the proxy
+// starts rather than when the first request fails.
+package startup
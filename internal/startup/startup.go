@@ -0,0 +1,193 @@
+// Package startup summarizes the configuration of the proxy when it boots.
+package startup
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/cert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	serviceVersionAttrKey = "service.version"
+	httpAddressesAttrKey  = "listen.http.addresses"
+	grpcAddressAttrKey    = "listen.grpc.address"
+	listenTLSAttrKey      = "listen.tls"
+	signalsAttrKey        = "signals"
+	tenantStrategyAttrKey = "tenant.strategy"
+	tenantLabelsAttrKey   = "tenant.labels"
+	tenantHeaderAttrKey   = "tenant.header"
+	tenantDefaultAttrKey  = "tenant.default"
+)
+
+// TLS modes of the listener and of the backends.
+const (
+	TLSOff    = "off"
+	TLSServer = "tls"
+	TLSMutual = "mtls"
+)
+
+// Tenant resolution strategies.
+const (
+	TenantAttribute = "attribute"
+	TenantStatic    = "static"
+)
+
+// signal is a signal forwarded to a backend, with the prefix of its environment variables.
+type signal struct {
+	name     string
+	prefix   string
+	endpoint *config.Endpoint
+}
+
+// signals returns the signals forwarded by the proxy, profiles only when a backend is configured for them.
+func signals(cfg *config.Config) []signal {
+	forwarded := []signal{
+		{name: "logs", prefix: "OLP_LOGS_", endpoint: &cfg.Logs},
+		{name: "metrics", prefix: "OLP_METRICS_", endpoint: &cfg.Metrics},
+		{name: "traces", prefix: "OLP_TRACES_", endpoint: &cfg.Traces},
+	}
+	if cfg.Profiles.Address != "" {
+		forwarded = append(forwarded, signal{name: "profiles", prefix: "OLP_PROFILES_", endpoint: &cfg.Profiles})
+	}
+	return forwarded
+}
+
+// Banner returns the attributes summarizing the configuration: the listen addresses and TLS mode, the forwarded
+// signals with the redacted address, protocol and TLS mode of their backend, and the tenant resolution.
+func Banner(cfg *config.Config) []attribute.KeyValue {
+	addresses := append([]string{cfg.HTTP.Address}, cfg.HTTP.PlaintextAddresses...)
+	attributes := []attribute.KeyValue{
+		attribute.String(serviceVersionAttrKey, cfg.Service.Version),
+		attribute.StringSlice(httpAddressesAttrKey, addresses),
+		attribute.String(listenTLSAttrKey, listenerTLS(&cfg.HTTP.TLS)),
+	}
+	if cfg.GRPC.Address != "" {
+		attributes = append(attributes, attribute.String(grpcAddressAttrKey, cfg.GRPC.Address))
+	}
+
+	forwarded := signals(cfg)
+	names := make([]string, 0, len(forwarded))
+	for _, s := range forwarded {
+		names = append(names, s.name)
+	}
+	attributes = append(attributes, attribute.StringSlice(signalsAttrKey, names))
+	for _, s := range forwarded {
+		attributes = append(attributes,
+			attribute.String("backend."+s.name+".address", redactAddress(s.endpoint.Address)),
+			attribute.String("backend."+s.name+".protocol", s.endpoint.Protocol),
+			attribute.String("backend."+s.name+".tls", backendTLS(s.endpoint)),
+		)
+	}
+
+	strategy := TenantAttribute
+	if cfg.Tenant.Static() {
+		strategy = TenantStatic
+	}
+	return append(attributes,
+		attribute.String(tenantStrategyAttrKey, strategy),
+		attribute.StringSlice(tenantLabelsAttrKey, append([]string{cfg.Tenant.Label}, cfg.Tenant.Labels...)),
+		attribute.String(tenantHeaderAttrKey, cfg.Tenant.Header),
+		attribute.String(tenantDefaultAttrKey, cfg.Tenant.Default),
+	)
+}
+
+// Check returns a warning for each suspicious combination of settings, in the order of the configuration.
+func Check(cfg *config.Config) []string {
+	var warnings []string
+
+	listener := &cfg.HTTP.TLS
+	if partialTLS(listener) {
+		warnings = append(warnings, "HTTP_LISTEN_TLS_CERT_FILE, HTTP_LISTEN_TLS_KEY_FILE and HTTP_LISTEN_TLS_CA_FILE "+
+			"are not all set, the listener serves plaintext")
+	}
+	if !cert.TLSEnabled(listener) && clientAuth(listener) {
+		warnings = append(warnings, "HTTP_LISTEN_TLS_CLIENT_AUTH_TYPE is set but TLS is disabled, "+
+			"client certificates are not requested")
+	}
+	if listener.InsecureSkipVerify {
+		warnings = append(warnings, "HTTP_LISTEN_TLS_INSECURE_SKIP_VERIFY has no effect on the listener")
+	}
+
+	for _, s := range signals(cfg) {
+		endpoint := s.endpoint
+		if partialTLS(&endpoint.TLS) {
+			warnings = append(warnings, fmt.Sprintf("%[1]sTLS_CERT_FILE, %[1]sTLS_KEY_FILE and %[1]sTLS_CA_FILE "+
+				"are not all set, no client certificate is presented to the %[2]s backend", s.prefix, s.name))
+		}
+		if endpoint.TLS.InsecureSkipVerify {
+			warnings = append(warnings, fmt.Sprintf("%sTLS_INSECURE_SKIP_VERIFY is not applied to backend "+
+				"connections, the certificate of the %s backend is verified", s.prefix, s.name))
+		}
+		if u, err := url.Parse(endpoint.Address); err == nil && u.Scheme == "http" && hasCredentials(endpoint.Headers) {
+			warnings = append(warnings, fmt.Sprintf("%sHEADERS sends credentials to the %s backend over plaintext "+
+				"HTTP", s.prefix, s.name))
+		}
+	}
+
+	kafka := &cfg.Kafka.TLS
+	if kafka.Enabled && kafka.InsecureSkipVerify && kafka.CertFile != "" {
+		warnings = append(warnings, "KAFKA_TLS_INSECURE_SKIP_VERIFY is set with a client certificate, "+
+			"which is presented to brokers whose certificate is not verified")
+	}
+	return warnings
+}
+
+// listenerTLS returns the TLS mode of the listener, mutual when client certificates are requested.
+func listenerTLS(cfg *config.TLSConfig) string {
+	switch {
+	case !cert.TLSEnabled(cfg):
+		return TLSOff
+	case clientAuth(cfg):
+		return TLSMutual
+	default:
+		return TLSServer
+	}
+}
+
+// backendTLS returns the TLS mode of a backend, mutual when client certificates are presented.
+func backendTLS(endpoint *config.Endpoint) string {
+	switch {
+	case cert.TLSEnabled(&endpoint.TLS) || endpoint.TLS.TenantCertDir != "":
+		return TLSMutual
+	case strings.HasPrefix(endpoint.Address, "https://"):
+		return TLSServer
+	default:
+		return TLSOff
+	}
+}
+
+// clientAuth reports whether the listener requests client certificates, or reads its policy from a file.
+func clientAuth(cfg *config.TLSConfig) bool {
+	return cfg.ClientAuthTypeFile != "" || cert.StringClientAuthType(cfg.ClientAuthType) != tls.NoClientCert
+}
+
+// partialTLS reports whether some but not all of the files enabling TLS are set.
+func partialTLS(cfg *config.TLSConfig) bool {
+	return !cert.TLSEnabled(cfg) && (cfg.CertFile != "" || cfg.KeyFile != "" || cfg.CAFile != "")
+}
+
+// hasCredentials reports whether the comma-separated key=value headers carry an Authorization header.
+func hasCredentials(headers string) bool {
+	for _, pair := range strings.Split(headers, ",") {
+		key, _, _ := strings.Cut(pair, "=")
+		if strings.EqualFold(strings.TrimSpace(key), "Authorization") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAddress returns the address with the password of its user info redacted, an address that does not parse being
+// redacted entirely.
+func redactAddress(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return config.Redacted
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,16 @@
+// Package stats provides in-memory statistics about the proxy for the admin API.
+//
+// The Tracker aggregates, per signal and tenant:
+//   - The number of backend requests and failed backend requests
+//   - The number of resource records and payload bytes forwarded
+//
+// Components owning queues or circuit breakers register callbacks reporting their
+// current depth or state, so a single snapshot describes the whole proxy. The
+// snapshot is the data contract backing the bundled Grafana dashboard.
+//
+// The most recent failed backend requests are also kept, so they can be included
+// in support bundles.
+//
+// The tenant counters can be persisted to a local bbolt Store and restored on
+// startup, so usage accounting and quota enforcement survive restarts.
+package stats
@@ -0,0 +1,45 @@
+// Package stats provides in-memory statistics about the proxy for the admin API.
+package stats
+
+import (
+	"time"
+)
+
+// recentErrorsSize is the number of recent backend errors kept by a Tracker.
+const recentErrorsSize = 100
+
+// ErrorEvent describes a failed backend request.
+type ErrorEvent struct {
+	Time   time.Time `json:"time"`
+	Signal string    `json:"signal"`
+	Tenant string    `json:"tenant"`
+	Error  string    `json:"error"`
+}
+
+// RecordError records a failed backend request, keeping only the most recent errors.
+func (t *Tracker) RecordError(signal, tenant, message string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	event := ErrorEvent{Time: time.Now(), Signal: signal, Tenant: tenant, Error: message}
+	if len(t.errors) < recentErrorsSize {
+		t.errors = append(t.errors, event)
+		return
+	}
+	t.errors[t.nextError] = event
+	t.nextError = (t.nextError + 1) % recentErrorsSize
+}
+
+// RecentErrors returns the most recent backend errors, oldest first.
+func (t *Tracker) RecentErrors() []ErrorEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := make([]ErrorEvent, 0, len(t.errors))
+	events = append(events, t.errors[t.nextError:]...)
+	return append(events, t.errors[:t.nextError]...)
+}
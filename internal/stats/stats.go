@@ -0,0 +1,238 @@
+// Package stats provides in-memory statistics about the proxy for the admin API.
+package stats
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var errAttrKey = "error"
+
+// TenantStats holds the statistics of a single signal and tenant.
+type TenantStats struct {
+	Signal           string  `json:"signal"`
+	Tenant           string  `json:"tenant"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	Records          int64   `json:"records"`
+	Bytes            int64   `json:"bytes"`
+	ErrorRate        float64 `json:"error_rate"`
+	RecordsPerSecond float64 `json:"records_per_second"`
+	BytesPerSecond   float64 `json:"bytes_per_second"`
+}
+
+// QueueStats holds the depth of a named queue.
+type QueueStats struct {
+	Name  string `json:"name"`
+	Depth int64  `json:"depth"`
+}
+
+// CircuitStats holds the state of a named circuit breaker.
+type CircuitStats struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// Snapshot is a point in time view of the proxy statistics.
+type Snapshot struct {
+	Instance      string         `json:"instance"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	Tenants       []TenantStats  `json:"tenants"`
+	Queues        []QueueStats   `json:"queues"`
+	Circuits      []CircuitStats `json:"circuits"`
+}
+
+// key identifies the statistics of a signal and tenant.
+type key struct {
+	signal string
+	tenant string
+}
+
+// Tracker aggregates proxy statistics. A nil Tracker discards everything recorded.
+type Tracker struct {
+	mu       sync.Mutex
+	instance string
+	start    time.Time
+	tenants  map[key]*TenantStats
+	restored map[key]TenantStats
+	queues   map[string]func() int64
+	circuits []func() []CircuitStats
+
+	errors    []ErrorEvent
+	nextError int
+}
+
+// New creates a new Tracker for the given instance.
+func New(instance string) *Tracker {
+	return &Tracker{
+		instance: instance,
+		start:    time.Now(),
+		tenants:  make(map[key]*TenantStats),
+		restored: make(map[key]TenantStats),
+		queues:   make(map[string]func() int64),
+	}
+}
+
+// Record records a backend request for the given signal and tenant.
+func (t *Tracker) Record(signal, tenant string, records, bytes int64, failed bool) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{signal: signal, tenant: tenant}
+	s, ok := t.tenants[k]
+	if !ok {
+		s = &TenantStats{Signal: signal, Tenant: tenant}
+		t.tenants[k] = s
+	}
+
+	s.Requests++
+	s.Records += records
+	s.Bytes += bytes
+	if failed {
+		s.Errors++
+	}
+}
+
+// RegisterQueue registers a callback reporting the depth of the named queue.
+func (t *Tracker) RegisterQueue(name string, depth func() int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queues[name] = depth
+}
+
+// RegisterCircuits registers a callback listing the state of a set of named circuit breakers, such as those opened
+// per tenant that come and go.
+func (t *Tracker) RegisterCircuits(states func() []CircuitStats) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.circuits = append(t.circuits, states)
+}
+
+// Snapshot returns the current statistics sorted by signal, tenant and name.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	uptime := time.Since(t.start).Seconds()
+	snapshot := Snapshot{
+		Instance:      t.instance,
+		UptimeSeconds: uptime,
+		Tenants:       make([]TenantStats, 0, len(t.tenants)),
+		Queues:        make([]QueueStats, 0, len(t.queues)),
+		Circuits:      []CircuitStats{},
+	}
+
+	for _, tenant := range t.counters() {
+		// Rates only cover what was recorded by this process.
+		if live, ok := t.tenants[key{signal: tenant.Signal, tenant: tenant.Tenant}]; ok && uptime > 0 {
+			tenant.RecordsPerSecond = float64(live.Records) / uptime
+			tenant.BytesPerSecond = float64(live.Bytes) / uptime
+		}
+		if tenant.Requests > 0 {
+			tenant.ErrorRate = float64(tenant.Errors) / float64(tenant.Requests)
+		}
+		snapshot.Tenants = append(snapshot.Tenants, tenant)
+	}
+
+	for name, depth := range t.queues {
+		snapshot.Queues = append(snapshot.Queues, QueueStats{Name: name, Depth: depth()})
+	}
+	slices.SortFunc(snapshot.Queues, func(a, b QueueStats) int { return cmp.Compare(a.Name, b.Name) })
+
+	for _, states := range t.circuits {
+		snapshot.Circuits = append(snapshot.Circuits, states()...)
+	}
+	slices.SortFunc(snapshot.Circuits, func(a, b CircuitStats) int { return cmp.Compare(a.Name, b.Name) })
+
+	return snapshot
+}
+
+// Restore adds previously persisted tenant counters to the tracker.
+func (t *Tracker) Restore(counters []TenantStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tenant := range counters {
+		k := key{signal: tenant.Signal, tenant: tenant.Tenant}
+		restored := t.restored[k]
+		restored.Signal, restored.Tenant = tenant.Signal, tenant.Tenant
+		restored.Requests += tenant.Requests
+		restored.Errors += tenant.Errors
+		restored.Records += tenant.Records
+		restored.Bytes += tenant.Bytes
+		t.restored[k] = restored
+	}
+}
+
+// Counters returns the total tenant counters, including restored counters, sorted by signal and tenant.
+func (t *Tracker) Counters() []TenantStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.counters()
+}
+
+// counters returns the total tenant counters, the caller must hold the lock.
+func (t *Tracker) counters() []TenantStats {
+	totals := make(map[key]TenantStats, len(t.tenants)+len(t.restored))
+	for k, restored := range t.restored {
+		totals[k] = restored
+	}
+	for k, live := range t.tenants {
+		total := totals[k]
+		total.Signal, total.Tenant = live.Signal, live.Tenant
+		total.Requests += live.Requests
+		total.Errors += live.Errors
+		total.Records += live.Records
+		total.Bytes += live.Bytes
+		totals[k] = total
+	}
+
+	counters := make([]TenantStats, 0, len(totals))
+	for _, total := range totals {
+		counters = append(counters, total)
+	}
+	slices.SortFunc(counters, func(a, b TenantStats) int {
+		return cmp.Or(cmp.Compare(a.Signal, b.Signal), cmp.Compare(a.Tenant, b.Tenant))
+	})
+
+	return counters
+}
+
+// Persist saves the tenant counters to the store at every interval and once more when the context is cancelled.
+func (t *Tracker) Persist(ctx context.Context, store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := store.Save(t.Counters()); err != nil {
+				logger.Error(context.WithoutCancel(ctx), err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := store.Save(t.Counters()); err != nil {
+				logger.Warn(ctx, "failed to persist tenant stats", attribute.String(errAttrKey, err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := New("instance-a")
+	tracker.Record("traces", "tenant-b", 2, 200, false)
+	tracker.Record("logs", "tenant-a", 1, 100, false)
+	tracker.Record("logs", "tenant-a", 3, 300, true)
+	tracker.RegisterQueue("logs", func() int64 { return 7 })
+	tracker.RegisterCircuits(func() []CircuitStats {
+		return []CircuitStats{{Name: "traces/tenant-b", State: "open"}, {Name: "logs/tenant-a", State: "open"}}
+	})
+
+	snapshot := tracker.Snapshot()
+	assert.Equal(t, "instance-a", snapshot.Instance)
+	require.Len(t, snapshot.Tenants, 2)
+
+	logs := snapshot.Tenants[0]
+	assert.Equal(t, "logs", logs.Signal)
+	assert.Equal(t, "tenant-a", logs.Tenant)
+	assert.Equal(t, int64(2), logs.Requests)
+	assert.Equal(t, int64(1), logs.Errors)
+	assert.Equal(t, int64(4), logs.Records)
+	assert.Equal(t, int64(400), logs.Bytes)
+	assert.InDelta(t, 0.5, logs.ErrorRate, 0.0001)
+
+	traces := snapshot.Tenants[1]
+	assert.Equal(t, "traces", traces.Signal)
+	assert.Zero(t, traces.ErrorRate)
+
+	assert.Equal(t, []QueueStats{{Name: "logs", Depth: 7}}, snapshot.Queues)
+	assert.Equal(t, []CircuitStats{
+		{Name: "logs/tenant-a", State: "open"},
+		{Name: "traces/tenant-b", State: "open"},
+	}, snapshot.Circuits)
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	assert.NotPanics(t, func() {
+		tracker.Record("logs", "tenant-a", 1, 1, false)
+		tracker.RegisterQueue("logs", func() int64 { return 0 })
+		tracker.RegisterCircuits(func() []CircuitStats { return nil })
+		tracker.RecordError("logs", "tenant-a", "failed")
+	})
+}
+
+func TestTracker_RecentErrors(t *testing.T) {
+	tracker := New("instance-a")
+	assert.Empty(t, tracker.RecentErrors())
+
+	for i := range recentErrorsSize + 2 {
+		tracker.RecordError("logs", "tenant-a", fmt.Sprintf("error %d", i))
+	}
+
+	events := tracker.RecentErrors()
+	require.Len(t, events, recentErrorsSize)
+	assert.Equal(t, "error 2", events[0].Error)
+	assert.Equal(t, fmt.Sprintf("error %d", recentErrorsSize+1), events[len(events)-1].Error)
+	assert.Equal(t, "logs", events[0].Signal)
+	assert.Equal(t, "tenant-a", events[0].Tenant)
+}
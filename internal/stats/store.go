@@ -0,0 +1,82 @@
+// Package stats provides in-memory statistics about the proxy for the admin API.
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var tenantStatsBucket = []byte("tenant_stats")
+
+// Store persists the tenant counters in a local bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens, creating it if needed, the bbolt database at the given path.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stats store %q: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tenantStatsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialise stats store %q: %w", path, err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Load returns the persisted tenant counters.
+func (s *Store) Load() ([]TenantStats, error) {
+	counters := []TenantStats{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tenantStatsBucket).ForEach(func(_, value []byte) error {
+			var tenant TenantStats
+			if err := json.Unmarshal(value, &tenant); err != nil {
+				return err
+			}
+			counters = append(counters, tenant)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant stats: %w", err)
+	}
+
+	return counters, nil
+}
+
+// Save persists the given tenant counters, replacing the stored counters of the same signal and tenant.
+func (s *Store) Save(counters []TenantStats) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tenantStatsBucket)
+		for _, tenant := range counters {
+			value, err := json.Marshal(tenant)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(tenant.Signal+"\x00"+tenant.Tenant), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save tenant stats: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the underlying database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.db")
+
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+
+	counters, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, counters)
+
+	want := []TenantStats{
+		{Signal: "logs", Tenant: "tenant-a", Requests: 2, Errors: 1, Records: 4, Bytes: 400},
+		{Signal: "logs", Tenant: "tenant/with/slashes", Requests: 1, Records: 1, Bytes: 10},
+	}
+	require.NoError(t, store.Save(want))
+	require.NoError(t, store.Close())
+
+	store, err = OpenStore(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, store.Close()) }()
+
+	got, err := store.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, got)
+}
+
+func TestTracker_Restore(t *testing.T) {
+	tracker := New("instance-a")
+	tracker.Restore([]TenantStats{{Signal: "logs", Tenant: "tenant-a", Requests: 10, Errors: 5, Records: 20, Bytes: 2000}})
+	tracker.Record("logs", "tenant-a", 2, 200, false)
+	tracker.Record("traces", "tenant-b", 1, 100, false)
+
+	counters := tracker.Counters()
+	require.Len(t, counters, 2)
+	assert.Equal(t, TenantStats{Signal: "logs", Tenant: "tenant-a", Requests: 11, Errors: 5, Records: 22, Bytes: 2200}, counters[0])
+
+	snapshot := tracker.Snapshot()
+	require.Len(t, snapshot.Tenants, 2)
+	assert.Equal(t, int64(11), snapshot.Tenants[0].Requests)
+	assert.InDelta(t, 5.0/11.0, snapshot.Tenants[0].ErrorRate, 0.0001)
+}
+
+func TestTracker_Persist(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "stats.db"))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, store.Close()) }()
+
+	tracker := New("instance-a")
+	tracker.Record("metrics", "tenant-a", 3, 300, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tracker.Persist(ctx, store, time.Hour)
+	}()
+	cancel()
+	<-done
+
+	got, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, tracker.Counters(), got)
+}
@@ -0,0 +1,21 @@
+// Package statsd provides a StatsD and DogStatsD receiver for metrics.
+//
+// The receiver accepts newline separated StatsD lines over UDP and TCP,
+// including the DogStatsD sample rate and tag extensions, and aggregates them
+// over a flush interval into OTLP metrics:
+//   - Counters become monotonic sums, scaled by their sample rate
+//   - Gauges become gauges holding the last value, signed values adjust it
+//   - Timings, histograms and distributions become summaries with the
+//     count, sum and the 0, 0.5, 0.9, 0.99 and 1 quantiles
+//   - Sets become gauges of the number of distinct members
+//   - Tags become data point attributes
+//
+// Counters and the counts and sums of summaries are deltas of the flush
+// interval by default. With cumulative temporality they keep adding up from
+// the first flush of their series instead, for backends such as Mimir that
+// drop delta sums.
+//
+// The tenant of a metric is taken from the first tag:value mapping matching
+// one of its tags, falling back to the value of the tenant tag. The metrics of
+// each tenant are handed to a Sink as one resource on every flush.
+package statsd
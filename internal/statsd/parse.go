@@ -0,0 +1,109 @@
+// Package statsd provides a StatsD and DogStatsD receiver for metrics.
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of a StatsD metric.
+type Type string
+
+// StatsD metric types.
+const (
+	Counter      Type = "c"
+	Gauge        Type = "g"
+	Timing       Type = "ms"
+	Histogram    Type = "h"
+	Distribution Type = "d"
+	Set          Type = "s"
+)
+
+// Tag is a DogStatsD tag, tags without a value have an empty value.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Metric is a parsed StatsD line.
+type Metric struct {
+	Name       string
+	Type       Type
+	Values     []float64
+	Members    []string
+	SampleRate float64
+	Tags       []Tag
+	// Relative is set for gauges whose values carry a sign, which adjust the current value rather than replace it.
+	Relative bool
+}
+
+// Parse parses a StatsD line of the form name:value[:value...]|type[|@rate][|#tag:value,...], returning nil for
+// DogStatsD events and service checks, which are not metrics.
+func Parse(line string) (*Metric, error) {
+	if strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+		return nil, nil
+	}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid statsd line %q: missing name", line)
+	}
+
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid statsd line %q: missing type", line)
+	}
+
+	metric := &Metric{Name: name, Type: Type(fields[1]), SampleRate: 1}
+	switch metric.Type {
+	case Counter, Gauge, Timing, Histogram, Distribution, Set:
+	default:
+		return nil, fmt.Errorf("invalid statsd line %q: unsupported type %q", line, fields[1])
+	}
+
+	for _, field := range fields[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			rate, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid statsd line %q: invalid sample rate %q", line, field[1:])
+			}
+			metric.SampleRate = rate
+		case strings.HasPrefix(field, "#"):
+			metric.Tags = parseTags(field[1:])
+		}
+		// Other DogStatsD extensions such as container IDs and timestamps are ignored.
+	}
+
+	if metric.Type == Set {
+		metric.Members = []string{fields[0]}
+		return metric, nil
+	}
+
+	for value := range strings.SplitSeq(fields[0], ":") {
+		if metric.Type == Gauge && (strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-")) {
+			metric.Relative = true
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid statsd line %q: %w", line, errors.Unwrap(err))
+		}
+		metric.Values = append(metric.Values, v)
+	}
+	return metric, nil
+}
+
+// parseTags parses a comma separated list of key:value tags.
+func parseTags(s string) []Tag {
+	var tags []Tag
+	for tag := range strings.SplitSeq(s, ",") {
+		if tag == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(tag, ":")
+		tags = append(tags, Tag{Key: key, Value: value})
+	}
+	return tags
+}
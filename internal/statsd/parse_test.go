@@ -0,0 +1,101 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    *Metric
+		wantErr bool
+	}{
+		{
+			name: "counter",
+			line: "page.views:1|c",
+			want: &Metric{Name: "page.views", Type: Counter, Values: []float64{1}, SampleRate: 1},
+		},
+		{
+			name: "counter with sample rate and tags",
+			line: "page.views:2|c|@0.5|#tenant:tenant-a,env:prod,canary",
+			want: &Metric{
+				Name:       "page.views",
+				Type:       Counter,
+				Values:     []float64{2},
+				SampleRate: 0.5,
+				Tags:       []Tag{{Key: "tenant", Value: "tenant-a"}, {Key: "env", Value: "prod"}, {Key: "canary"}},
+			},
+		},
+		{
+			name: "relative gauge",
+			line: "queue.depth:-3|g",
+			want: &Metric{Name: "queue.depth", Type: Gauge, Values: []float64{-3}, SampleRate: 1, Relative: true},
+		},
+		{
+			name: "multi-value timing",
+			line: "request.duration:10:20.5|ms|#route:/api",
+			want: &Metric{
+				Name:       "request.duration",
+				Type:       Timing,
+				Values:     []float64{10, 20.5},
+				SampleRate: 1,
+				Tags:       []Tag{{Key: "route", Value: "/api"}},
+			},
+		},
+		{
+			name: "set",
+			line: "users.unique:alice|s",
+			want: &Metric{Name: "users.unique", Type: Set, Members: []string{"alice"}, SampleRate: 1},
+		},
+		{
+			name: "dogstatsd extensions ignored",
+			line: "page.views:1|c|c:container-id|T1700000000",
+			want: &Metric{Name: "page.views", Type: Counter, Values: []float64{1}, SampleRate: 1},
+		},
+		{
+			name: "event",
+			line: "_e{5,4}:title|text",
+		},
+		{
+			name: "service check",
+			line: "_sc|db|0",
+		},
+		{
+			name:    "missing type",
+			line:    "page.views:1",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			line:    "page.views:1|x",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			line:    "page.views:one|c",
+			wantErr: true,
+		},
+		{
+			name:    "invalid sample rate",
+			line:    "page.views:1|c|@2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,534 @@
+// Package statsd provides a StatsD and DogStatsD receiver for metrics.
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	addressAttrKey  = "statsd.address"
+	protocolAttrKey = "statsd.protocol"
+	remoteAttrKey   = "statsd.remote"
+	errAttrKey      = "error"
+)
+
+// scopeName is the instrumentation scope of the converted metrics.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/statsd"
+
+// maxLineSize limits the size of a single StatsD line or datagram.
+const maxLineSize = 64 << 10
+
+// quantiles are the quantiles reported for timings, histograms and distributions.
+var quantiles = []float64{0, 0.5, 0.9, 0.99, 1}
+
+// units are the units of the metric types that have one.
+var units = map[Type]string{Timing: "ms"}
+
+// Sink receives the metric resources aggregated from StatsD lines.
+type Sink func(ctx context.Context, resources []*metricpb.ResourceMetrics) error
+
+// mapping maps the metrics carrying a tag to a tenant.
+type mapping struct {
+	tag    Tag
+	tenant string
+}
+
+// series aggregates the values of a metric with the same name, type, tags and tenant over a flush interval.
+type series struct {
+	key     string
+	start   time.Time
+	tenant  string
+	name    string
+	kind    Type
+	tags    []Tag
+	value   float64
+	count   float64
+	samples []float64
+	members map[string]struct{}
+}
+
+// total is the running total of the counts and sums of a series since it was first flushed, for cumulative
+// temporality.
+type total struct {
+	start time.Time
+	value float64
+	count float64
+}
+
+// Option configures optional dependencies of a Server.
+type Option func(*Server) error
+
+// WithClock sets the clock timestamping the aggregated metrics and driving the flush interval.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) error {
+		s.clock = c
+		return nil
+	}
+}
+
+// Server receives StatsD lines, aggregates them per tenant and hands them to a Sink on every flush interval.
+type Server struct {
+	config   *config.StatsD
+	tenant   *config.Tenant
+	sink     Sink
+	mappings []mapping
+	clock    clock.Clock
+
+	mu     sync.Mutex
+	series map[string]*series
+	gauges map[string]float64
+	totals map[string]*total
+	start  time.Time
+}
+
+// New creates a new Server handing the aggregated metrics to the sink.
+func New(config *config.StatsD, tenant *config.Tenant, sink Sink, opts ...Option) (*Server, error) {
+	mappings, err := parseMappings(config.TenantMappings)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateTemporality(config.Temporality); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:   config,
+		tenant:   tenant,
+		sink:     sink,
+		mappings: mappings,
+		clock:    clock.New(),
+		series:   make(map[string]*series),
+		gauges:   make(map[string]float64),
+		totals:   make(map[string]*total),
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	s.start = s.clock.Now()
+	return s, nil
+}
+
+// parseMappings parses tenant mappings of the form tag:value=tenant.
+func parseMappings(entries []string) ([]mapping, error) {
+	mappings := make([]mapping, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tag, tenant, ok := strings.Cut(entry, "=")
+		key, value, hasValue := strings.Cut(strings.TrimSpace(tag), ":")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || !hasValue || key == "" || tenant == "" {
+			return nil, fmt.Errorf("invalid statsd tenant mapping %q, expected tag:value=tenant", entry)
+		}
+		mappings = append(mappings, mapping{tag: Tag{Key: key, Value: value}, tenant: tenant})
+	}
+	return mappings, nil
+}
+
+// validateTemporality returns an error when the temporality is unknown, an empty temporality being delta.
+func validateTemporality(temporality string) error {
+	switch temporality {
+	case "", config.TemporalityDelta, config.TemporalityCumulative:
+		return nil
+	}
+	return fmt.Errorf("invalid statsd temporality %q, expected %s or %s", temporality,
+		config.TemporalityDelta, config.TemporalityCumulative)
+}
+
+// Run listens on the configured TCP and UDP addresses and serves them until the context is cancelled, the metrics
+// still pending are flushed before returning.
+func (s *Server) Run(ctx context.Context) error {
+	g, gctx := errgroup.WithContext(ctx)
+
+	if s.config.TCPAddress != "" {
+		listener, err := net.Listen("tcp", s.config.TCPAddress)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(ctx, "starting statsd receiver",
+			attribute.String(addressAttrKey, s.config.TCPAddress), attribute.String(protocolAttrKey, "tcp"))
+		g.Go(func() error { return s.ServeTCP(gctx, listener) })
+	}
+
+	if s.config.UDPAddress != "" {
+		conn, err := net.ListenPacket("udp", s.config.UDPAddress)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(ctx, "starting statsd receiver",
+			attribute.String(addressAttrKey, s.config.UDPAddress), attribute.String(protocolAttrKey, "udp"))
+		g.Go(func() error { return s.ServeUDP(gctx, conn) })
+	}
+
+	g.Go(func() error {
+		ticker := s.clock.NewTicker(s.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-gctx.Done():
+				return nil
+			case <-ticker.C():
+				s.Flush(gctx)
+			}
+		}
+	})
+
+	err := g.Wait()
+	s.Flush(context.WithoutCancel(ctx))
+	return err
+}
+
+// ServeTCP serves the connections accepted by the listener until the context is cancelled.
+func (s *Server) ServeTCP(ctx context.Context, listener net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Go(func() { s.serveConn(ctx, conn) })
+	}
+}
+
+// serveConn reads the newline delimited lines of a connection.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReaderSize(conn, maxLineSize)
+	for {
+		if s.config.Timeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.config.Timeout))
+		}
+
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 && (err == nil || errors.Is(err, io.EOF)) {
+			s.addLines(ctx, line)
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				logger.Warn(ctx, "failed to read statsd line",
+					attribute.String(remoteAttrKey, conn.RemoteAddr().String()), attribute.String(errAttrKey, err.Error()))
+			}
+			return
+		}
+	}
+}
+
+// ServeUDP serves the datagrams received on the connection until the context is cancelled.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	buf := make([]byte, maxLineSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		s.addLines(ctx, buf[:n])
+	}
+}
+
+// addLines parses and aggregates the newline separated lines of a datagram or stream.
+func (s *Server) addLines(ctx context.Context, b []byte) {
+	for line := range bytes.SplitSeq(b, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+
+		metric, err := Parse(string(line))
+		if err != nil {
+			logger.Debug(ctx, "failed to parse statsd line", attribute.String(errAttrKey, err.Error()))
+			continue
+		}
+		if metric != nil {
+			s.add(metric)
+		}
+	}
+}
+
+// add aggregates a metric into its series.
+func (s *Server) add(metric *Metric) {
+	tenant := s.resolveTenant(metric.Tags)
+
+	tags := slices.Clone(metric.Tags)
+	slices.SortFunc(tags, func(a, b Tag) int {
+		if c := strings.Compare(a.Key, b.Key); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+
+	var key strings.Builder
+	for _, part := range []string{tenant, metric.Name, string(metric.Type)} {
+		key.WriteString(part)
+		key.WriteByte(0)
+	}
+	for _, tag := range tags {
+		key.WriteString(tag.Key)
+		key.WriteByte(':')
+		key.WriteString(tag.Value)
+		key.WriteByte(0)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.series[key.String()]
+	if !ok {
+		entry = &series{key: key.String(), tenant: tenant, name: metric.Name, kind: metric.Type, tags: tags}
+		s.series[key.String()] = entry
+	}
+
+	switch metric.Type {
+	case Counter:
+		for _, value := range metric.Values {
+			entry.value += value / metric.SampleRate
+		}
+	case Gauge:
+		value := s.gauges[key.String()]
+		for _, v := range metric.Values {
+			if metric.Relative {
+				value += v
+			} else {
+				value = v
+			}
+		}
+		s.gauges[key.String()] = value
+		entry.value = value
+	case Timing, Histogram, Distribution:
+		for _, value := range metric.Values {
+			entry.samples = append(entry.samples, value)
+			entry.value += value / metric.SampleRate
+			entry.count += 1 / metric.SampleRate
+		}
+	case Set:
+		if entry.members == nil {
+			entry.members = make(map[string]struct{})
+		}
+		for _, member := range metric.Members {
+			entry.members[member] = struct{}{}
+		}
+	}
+}
+
+// resolveTenant returns the tenant of the first mapping matching a tag, falling back to the value of the tenant tag.
+func (s *Server) resolveTenant(tags []Tag) string {
+	for _, mapping := range s.mappings {
+		if slices.Contains(tags, mapping.tag) {
+			return mapping.tenant
+		}
+	}
+
+	if s.config.TenantTag != "" {
+		for _, tag := range tags {
+			if tag.Key == s.config.TenantTag {
+				return tag.Value
+			}
+		}
+	}
+	return ""
+}
+
+// Flush hands the metrics aggregated since the previous flush to the sink, grouping them into one resource per tenant.
+func (s *Server) Flush(ctx context.Context) {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	pending := s.series
+	start := s.start
+	s.series = make(map[string]*series)
+	s.start = now
+	for _, entry := range pending {
+		entry.start = start
+		if s.config.Temporality == config.TemporalityCumulative {
+			s.accumulate(entry)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var resources []*metricpb.ResourceMetrics
+	byTenant := make(map[string]*metricpb.ScopeMetrics)
+	byName := make(map[string]*metricpb.Metric)
+
+	for _, key := range keys {
+		entry := pending[key]
+
+		scope, ok := byTenant[entry.tenant]
+		if !ok {
+			resource := &resourcepb.Resource{}
+			if entry.tenant != "" {
+				resource.Attributes = []*commonpb.KeyValue{stringKeyValue(s.tenant.Label, entry.tenant)}
+			}
+			scope = &metricpb.ScopeMetrics{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &metricpb.ResourceMetrics{Resource: resource, ScopeMetrics: []*metricpb.ScopeMetrics{scope}})
+			byTenant[entry.tenant] = scope
+		}
+
+		metricKey := entry.tenant + "\x00" + entry.name + "\x00" + string(entry.kind)
+		metric, ok := byName[metricKey]
+		if !ok {
+			metric = newMetric(entry.name, entry.kind, s.config.Temporality)
+			scope.Metrics = append(scope.Metrics, metric)
+			byName[metricKey] = metric
+		}
+		appendDataPoint(metric, entry, now)
+	}
+
+	if err := s.sink(ctx, resources); err != nil {
+		logger.Error(ctx, "failed to forward statsd metrics", attribute.String(errAttrKey, err.Error()))
+	}
+}
+
+// accumulate adds the counts and sums of a counter, timing, histogram or distribution series to its running total, so
+// the series reports the total since it was first flushed. It must be called with the lock held.
+func (s *Server) accumulate(entry *series) {
+	if entry.kind == Gauge || entry.kind == Set {
+		return
+	}
+
+	running, ok := s.totals[entry.key]
+	if !ok {
+		running = &total{start: entry.start}
+		s.totals[entry.key] = running
+	}
+	running.value += entry.value
+	running.count += entry.count
+
+	entry.start = running.start
+	entry.value = running.value
+	entry.count = running.count
+}
+
+// newMetric returns an empty OTLP metric for a StatsD metric type, with counters of the given temporality.
+func newMetric(name string, kind Type, temporality string) *metricpb.Metric {
+	metric := &metricpb.Metric{Name: name, Unit: units[kind]}
+	switch kind {
+	case Counter:
+		aggregation := metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+		if temporality == config.TemporalityCumulative {
+			aggregation = metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+		}
+		metric.Data = &metricpb.Metric_Sum{Sum: &metricpb.Sum{
+			AggregationTemporality: aggregation,
+			IsMonotonic:            true,
+		}}
+	case Gauge, Set:
+		metric.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{}}
+	default:
+		metric.Data = &metricpb.Metric_Summary{Summary: &metricpb.Summary{}}
+	}
+	return metric
+}
+
+// appendDataPoint adds the aggregated value of a series to its metric.
+func appendDataPoint(metric *metricpb.Metric, entry *series, now time.Time) {
+	attributes := make([]*commonpb.KeyValue, 0, len(entry.tags))
+	for _, tag := range entry.tags {
+		attributes = append(attributes, stringKeyValue(tag.Key, tag.Value))
+	}
+	startTime, timestamp := uint64(entry.start.UnixNano()), uint64(now.UnixNano())
+
+	switch data := metric.Data.(type) {
+	case *metricpb.Metric_Sum:
+		data.Sum.DataPoints = append(data.Sum.DataPoints, &metricpb.NumberDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: startTime,
+			TimeUnixNano:      timestamp,
+			Value:             &metricpb.NumberDataPoint_AsDouble{AsDouble: entry.value},
+		})
+	case *metricpb.Metric_Gauge:
+		value := entry.value
+		if entry.kind == Set {
+			value = float64(len(entry.members))
+		}
+		data.Gauge.DataPoints = append(data.Gauge.DataPoints, &metricpb.NumberDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: timestamp,
+			Value:        &metricpb.NumberDataPoint_AsDouble{AsDouble: value},
+		})
+	case *metricpb.Metric_Summary:
+		slices.Sort(entry.samples)
+		values := make([]*metricpb.SummaryDataPoint_ValueAtQuantile, 0, len(quantiles))
+		for _, q := range quantiles {
+			values = append(values, &metricpb.SummaryDataPoint_ValueAtQuantile{Quantile: q, Value: quantile(entry.samples, q)})
+		}
+		data.Summary.DataPoints = append(data.Summary.DataPoints, &metricpb.SummaryDataPoint{
+			Attributes:        attributes,
+			StartTimeUnixNano: startTime,
+			TimeUnixNano:      timestamp,
+			Count:             uint64(math.Round(entry.count)),
+			Sum:               entry.value,
+			QuantileValues:    values,
+		})
+	}
+}
+
+// quantile returns the nearest-rank quantile of the sorted samples.
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
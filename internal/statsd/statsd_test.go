@@ -0,0 +1,290 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func TestParseMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []mapping
+		wantErr bool
+	}{
+		{
+			name:    "valid mappings",
+			entries: []string{"team:payments=tenant-a", " service:checkout = tenant-b ", ""},
+			want: []mapping{
+				{tag: Tag{Key: "team", Value: "payments"}, tenant: "tenant-a"},
+				{tag: Tag{Key: "service", Value: "checkout"}, tenant: "tenant-b"},
+			},
+		},
+		{
+			name:    "missing tag value",
+			entries: []string{"team=tenant-a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing tenant",
+			entries: []string{"team:payments="},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMappings(tt.entries)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServer_Flush(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	fake := clock.NewFake(start)
+
+	var received []*metricpb.ResourceMetrics
+	s, err := New(
+		&config.StatsD{TenantTag: "tenant", TenantMappings: []string{"team:payments=tenant-b"}},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*metricpb.ResourceMetrics) error {
+			received = resources
+			return nil
+		},
+		WithClock(fake),
+	)
+	require.NoError(t, err)
+	fake.Advance(10 * time.Second)
+
+	s.addLines(t.Context(), []byte("hits:1|c|@0.5|#tenant:tenant-a,env:prod\nhits:3|c|#env:prod,tenant:tenant-a\n"+
+		"queue:10|g\nqueue:+5|g\nlatency:30:10:20|ms|#team:payments\nusers:a|s\nusers:b|s\nusers:a|s\nbad line\n"))
+	s.Flush(t.Context())
+
+	require.Len(t, received, 3)
+
+	// Metrics without a tenant.
+	assert.Empty(t, received[0].GetResource().GetAttributes())
+	metrics := received[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "queue", metrics[0].GetName())
+	assert.Equal(t, 15.0, metrics[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+	assert.Equal(t, "users", metrics[1].GetName())
+	assert.Equal(t, 2.0, metrics[1].GetGauge().GetDataPoints()[0].GetAsDouble())
+
+	// Metrics of the tenant tag.
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-a")}, received[1].GetResource().GetAttributes())
+	hits := received[1].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, hits, 1)
+	sum := hits[0].GetSum()
+	assert.Equal(t, metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, sum.GetAggregationTemporality())
+	assert.True(t, sum.GetIsMonotonic())
+	require.Len(t, sum.GetDataPoints(), 1)
+	assert.Equal(t, 5.0, sum.GetDataPoints()[0].GetAsDouble())
+	assert.Equal(t, uint64(start.UnixNano()), sum.GetDataPoints()[0].GetStartTimeUnixNano())
+	assert.Equal(t, uint64(fake.Now().UnixNano()), sum.GetDataPoints()[0].GetTimeUnixNano())
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("env", "prod"), stringKeyValue("tenant", "tenant-a")},
+		sum.GetDataPoints()[0].GetAttributes())
+
+	// Metrics of the tenant mapping.
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-b")}, received[2].GetResource().GetAttributes())
+	latency := received[2].GetScopeMetrics()[0].GetMetrics()[0]
+	assert.Equal(t, "ms", latency.GetUnit())
+	point := latency.GetSummary().GetDataPoints()[0]
+	assert.Equal(t, uint64(3), point.GetCount())
+	assert.Equal(t, 60.0, point.GetSum())
+	assert.Equal(t, []*metricpb.SummaryDataPoint_ValueAtQuantile{
+		{Quantile: 0, Value: 10},
+		{Quantile: 0.5, Value: 20},
+		{Quantile: 0.9, Value: 30},
+		{Quantile: 0.99, Value: 30},
+		{Quantile: 1, Value: 30},
+	}, point.GetQuantileValues())
+
+	// Gauges keep their value across flushes, other series start over.
+	received = nil
+	s.addLines(t.Context(), []byte("queue:-1|g"))
+	s.Flush(t.Context())
+	require.Len(t, received, 1)
+	metrics = received[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, 14.0, metrics[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+
+	received = nil
+	s.Flush(t.Context())
+	assert.Nil(t, received)
+}
+
+func TestServer_FlushCumulative(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	fake := clock.NewFake(start)
+
+	var received []*metricpb.ResourceMetrics
+	s, err := New(
+		&config.StatsD{Temporality: config.TemporalityCumulative},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*metricpb.ResourceMetrics) error {
+			received = resources
+			return nil
+		},
+		WithClock(fake),
+	)
+	require.NoError(t, err)
+
+	flush := func(lines string) []*metricpb.Metric {
+		t.Helper()
+		received = nil
+		fake.Advance(10 * time.Second)
+		s.addLines(t.Context(), []byte(lines))
+		s.Flush(t.Context())
+		require.Len(t, received, 1)
+		return received[0].GetScopeMetrics()[0].GetMetrics()
+	}
+
+	metrics := flush("hits:2|c\nlatency:10:30|ms\nqueue:5|g\n")
+	require.Len(t, metrics, 3)
+	assert.Equal(t, metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		metrics[0].GetSum().GetAggregationTemporality())
+	assert.Equal(t, 2.0, metrics[0].GetSum().GetDataPoints()[0].GetAsDouble())
+
+	// Counts and sums keep adding up from the first flush, quantiles and gauges are those of the interval.
+	metrics = flush("hits:3|c\nlatency:20|ms\nqueue:1|g\n")
+	require.Len(t, metrics, 3)
+	hits := metrics[0].GetSum().GetDataPoints()[0]
+	assert.Equal(t, 5.0, hits.GetAsDouble())
+	assert.Equal(t, uint64(start.UnixNano()), hits.GetStartTimeUnixNano())
+	assert.Equal(t, uint64(fake.Now().UnixNano()), hits.GetTimeUnixNano())
+
+	latency := metrics[1].GetSummary().GetDataPoints()[0]
+	assert.Equal(t, uint64(3), latency.GetCount())
+	assert.Equal(t, 60.0, latency.GetSum())
+	assert.Equal(t, uint64(start.UnixNano()), latency.GetStartTimeUnixNano())
+	assert.Equal(t, 20.0, latency.GetQuantileValues()[4].GetValue())
+
+	assert.Equal(t, 1.0, metrics[2].GetGauge().GetDataPoints()[0].GetAsDouble())
+}
+
+func TestNew_InvalidTemporality(t *testing.T) {
+	_, err := New(&config.StatsD{Temporality: "monthly"}, &config.Tenant{Label: "tenant.id"},
+		func(context.Context, []*metricpb.ResourceMetrics) error { return nil },
+	)
+	assert.ErrorContains(t, err, "invalid statsd temporality")
+}
+
+func TestServer_FlushInterval(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	flushed := make(chan []*metricpb.ResourceMetrics, 1)
+	s, err := New(
+		&config.StatsD{FlushInterval: 10 * time.Second},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*metricpb.ResourceMetrics) error {
+			flushed <- resources
+			return nil
+		},
+		WithClock(fake),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	s.addLines(t.Context(), []byte("hits:1|c\n"))
+	fake.BlockUntilTickers(1)
+	fake.Advance(10 * time.Second)
+
+	select {
+	case resources := <-flushed:
+		require.Len(t, resources, 1)
+		sum := resources[0].GetScopeMetrics()[0].GetMetrics()[0].GetSum()
+		assert.Equal(t, uint64(fake.Now().UnixNano()), sum.GetDataPoints()[0].GetTimeUnixNano())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the flush")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server to stop")
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error)
+	}{
+		{
+			name: "tcp",
+			serve: func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error) {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+
+				done := make(chan error, 1)
+				go func() { done <- s.ServeTCP(ctx, listener) }()
+				return "tcp", listener.Addr().String(), done
+			},
+		},
+		{
+			name: "udp",
+			serve: func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error) {
+				conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+				require.NoError(t, err)
+
+				done := make(chan error, 1)
+				go func() { done <- s.ServeUDP(ctx, conn) }()
+				return "udp", conn.LocalAddr().String(), done
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&config.StatsD{Timeout: time.Second}, &config.Tenant{Label: "tenant.id"},
+				func(context.Context, []*metricpb.ResourceMetrics) error { return nil },
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			network, address, done := tt.serve(t, ctx, s)
+
+			conn, err := net.Dial(network, address)
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+
+			_, err = conn.Write([]byte("hits:1|c\n"))
+			require.NoError(t, err)
+
+			assert.Eventually(t, func() bool {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				return len(s.series) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+
+			cancel()
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the server to stop")
+			}
+		})
+	}
+}
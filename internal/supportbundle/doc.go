@@ -0,0 +1,8 @@
+// Package supportbundle builds the support bundles attached to bug reports.
+//
+// A bundle is a gzip-compressed tarball of files describing the state of a
+// running proxy: its version and build information, its configuration with the
+// secrets redacted, its statistics and recent errors, runtime statistics and a
+// dump of all goroutine stacks. Files are added as JSON or plain text and the
+// tarball is written in a single pass, so it can be streamed to an HTTP response.
+package supportbundle
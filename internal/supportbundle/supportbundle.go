@@ -0,0 +1,138 @@
+// Package supportbundle builds the support bundles attached to bug reports.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+)
+
+// File is a file of a support bundle.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// JSON returns a file holding the indented JSON encoding of the value.
+func JSON(name string, v any) (File, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return File{}, fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return File{Name: name, Data: append(data, '\n')}, nil
+}
+
+// Goroutines returns a file holding the stack traces of all goroutines.
+func Goroutines(name string) (File, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		return File{}, fmt.Errorf("failed to dump goroutines: %w", err)
+	}
+	return File{Name: name, Data: buf.Bytes()}, nil
+}
+
+// Version describes the running build of the proxy.
+type Version struct {
+	Service   string `json:"service"`
+	Version   string `json:"version"`
+	Instance  string `json:"instance"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// BuildVersion returns the version of the running build, completed with the version control information embedded by
+// the Go toolchain when available.
+func BuildVersion(service, version, instance string) Version {
+	v := Version{
+		Service:   service,
+		Version:   version,
+		Instance:  instance,
+		GoVersion: runtime.Version(),
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.Module = info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			v.Revision = setting.Value
+		case "vcs.time":
+			v.BuildTime = setting.Value
+		case "vcs.modified":
+			v.Modified = setting.Value == "true"
+		}
+	}
+	return v
+}
+
+// Runtime holds the runtime statistics of the process.
+type Runtime struct {
+	Goroutines     int    `json:"goroutines"`
+	CPUs           int    `json:"cpus"`
+	GOMAXPROCS     int    `json:"gomaxprocs"`
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	PauseTotalNs   uint64 `json:"pause_total_ns"`
+}
+
+// ReadRuntime returns the current runtime statistics of the process.
+func ReadRuntime() Runtime {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return Runtime{
+		Goroutines:     runtime.NumGoroutine(),
+		CPUs:           runtime.NumCPU(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapInuseBytes: mem.HeapInuse,
+		SysBytes:       mem.Sys,
+		NumGC:          mem.NumGC,
+		PauseTotalNs:   mem.PauseTotalNs,
+	}
+}
+
+// Write writes the files as a gzip-compressed tarball, all stamped with the given modification time.
+func Write(w io.Writer, modTime time.Time, files []File) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.Name,
+			Mode:    0o644,
+			Size:    int64(len(file.Data)),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s header: %w", file.Name, err)
+		}
+		if _, err := tw.Write(file.Data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tarball: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip stream: %w", err)
+	}
+	return nil
+}
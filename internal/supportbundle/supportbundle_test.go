@@ -0,0 +1,69 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	modTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	config, err := JSON("config.json", map[string]string{"address": "http://localhost:3100"})
+	require.NoError(t, err)
+	goroutines, err := Goroutines("goroutines.txt")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, modTime, []File{config, goroutines}))
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	got := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.True(t, modTime.Equal(header.ModTime), header.Name)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		got[header.Name] = string(data)
+	}
+
+	assert.Equal(t, "{\n  \"address\": \"http://localhost:3100\"\n}\n", got["config.json"])
+	assert.Contains(t, got["goroutines.txt"], "goroutine ")
+	assert.Contains(t, got["goroutines.txt"], "TestWrite")
+}
+
+func TestJSON_Error(t *testing.T) {
+	_, err := JSON("invalid.json", func() {})
+	assert.ErrorContains(t, err, "invalid.json")
+}
+
+func TestBuildVersion(t *testing.T) {
+	v := BuildVersion("otel-lgtm-proxy", "1.2.3", "replica-1")
+
+	assert.Equal(t, "otel-lgtm-proxy", v.Service)
+	assert.Equal(t, "1.2.3", v.Version)
+	assert.Equal(t, "replica-1", v.Instance)
+	assert.Equal(t, runtime.Version(), v.GoVersion)
+}
+
+func TestReadRuntime(t *testing.T) {
+	r := ReadRuntime()
+
+	assert.Positive(t, r.Goroutines)
+	assert.Positive(t, r.GOMAXPROCS)
+	assert.Positive(t, r.SysBytes)
+}
@@ -0,0 +1,20 @@
+// Package syslog provides a syslog receiver for logs.
+//
+// The receiver accepts RFC5424 and RFC3164 messages over UDP, TCP and TLS, for
+// network devices that cannot run an OpenTelemetry agent. TCP streams may use
+// octet counting or newline delimited framing. Each message is converted into
+// an OTLP LogRecord:
+//   - The MSG part becomes the body and the syslog severity the severity
+//   - The facility, hostname, app name, proc ID and msg ID become syslog.* attributes
+//   - Structured data parameters become syslog.sd.<SD-ID>.<name> attributes
+//   - The sender address is recorded as the client.address attribute
+//   - Messages that cannot be parsed are forwarded unchanged as the body
+//
+// Structured data parameters may instead be mapped to resource attributes, the
+// records of a tenant being grouped into one resource per set of mapped values.
+//
+// The tenant is taken from the id parameter of the configured structured data
+// element, falling back to the first source network containing the sender.
+// Records are batched per tenant and handed to a Sink on a flush interval, or
+// once the batch size is reached.
+package syslog
@@ -0,0 +1,252 @@
+// Package syslog provides a syslog receiver for logs.
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// nilValue is the RFC5424 NILVALUE of absent header fields and structured data.
+const nilValue = "-"
+
+// byteOrderMark prefixes RFC5424 messages encoded in UTF-8.
+const byteOrderMark = "\ufeff"
+
+// rfc3164Timestamp is the timestamp layout of RFC3164 messages, which carry no year.
+const rfc3164Timestamp = time.Stamp
+
+var errInvalidPriority = errors.New("invalid syslog priority")
+
+// Param is a structured data parameter.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Element is a structured data element.
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+// Message is a parsed RFC5424 or RFC3164 syslog message.
+type Message struct {
+	Facility       int
+	Severity       int
+	Version        int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData []Element
+	Message        string
+}
+
+// Param returns the value of a structured data parameter.
+func (m *Message) Param(id, name string) (string, bool) {
+	for _, element := range m.StructuredData {
+		if element.ID != id {
+			continue
+		}
+		for _, param := range element.Params {
+			if param.Name == name {
+				return param.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Parse parses an RFC5424 message, falling back to RFC3164 for messages without a version.
+//
+// RFC3164 timestamps carry no year, the year of now is assumed unless that places the message more than a day in the
+// future, in which case the previous year is used.
+func Parse(b []byte, now time.Time) (*Message, error) {
+	s := strings.TrimRight(string(b), "\r\n")
+
+	msg, rest, err := parsePriority(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if version, after, ok := strings.Cut(rest, " "); ok && version != "" && version[0] >= '1' && version[0] <= '9' {
+		if v, err := strconv.Atoi(version); err == nil {
+			msg.Version = v
+			return msg, parseRFC5424(msg, after)
+		}
+	}
+
+	parseRFC3164(msg, rest, now)
+	return msg, nil
+}
+
+// parsePriority parses the <PRI> prefix of a message.
+func parsePriority(s string) (*Message, string, error) {
+	if !strings.HasPrefix(s, "<") {
+		return nil, "", errInvalidPriority
+	}
+
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return nil, "", errInvalidPriority
+	}
+
+	priority, err := strconv.Atoi(s[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return nil, "", errInvalidPriority
+	}
+
+	return &Message{Facility: priority / 8, Severity: priority % 8}, s[end+1:], nil
+}
+
+// parseRFC5424 parses the header, structured data and message following the version of an RFC5424 message.
+func parseRFC5424(msg *Message, s string) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		field, rest, ok := strings.Cut(s, " ")
+		if !ok {
+			return fmt.Errorf("invalid rfc5424 header: missing %d fields", len(fields)-i)
+		}
+		fields[i], s = field, rest
+	}
+
+	if fields[0] != nilValue {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid rfc5424 timestamp: %w", err)
+		}
+		msg.Timestamp = timestamp
+	}
+	msg.Hostname = header(fields[1])
+	msg.AppName = header(fields[2])
+	msg.ProcID = header(fields[3])
+	msg.MsgID = header(fields[4])
+
+	rest, err := parseStructuredData(msg, s)
+	if err != nil {
+		return err
+	}
+
+	msg.Message = strings.TrimPrefix(strings.TrimPrefix(rest, " "), byteOrderMark)
+	return nil
+}
+
+// parseStructuredData parses the structured data elements, returning the remainder of the message.
+func parseStructuredData(msg *Message, s string) (string, error) {
+	if s == "" || strings.HasPrefix(s, nilValue) {
+		return strings.TrimPrefix(s, nilValue), nil
+	}
+
+	for strings.HasPrefix(s, "[") {
+		end := strings.IndexAny(s, " ]")
+		if end < 0 {
+			return "", errors.New("invalid structured data: unterminated element")
+		}
+		element := Element{ID: s[1:end]}
+		s = s[end:]
+
+		for strings.HasPrefix(s, " ") {
+			name, rest, ok := strings.Cut(s[1:], "=\"")
+			if !ok {
+				return "", fmt.Errorf("invalid structured data: parameter of %s", element.ID)
+			}
+
+			value, rest, err := parseParamValue(rest)
+			if err != nil {
+				return "", fmt.Errorf("invalid structured data: parameter %s of %s: %w", name, element.ID, err)
+			}
+			element.Params = append(element.Params, Param{Name: name, Value: value})
+			s = rest
+		}
+
+		if !strings.HasPrefix(s, "]") {
+			return "", fmt.Errorf("invalid structured data: unterminated element %s", element.ID)
+		}
+		s = s[1:]
+		msg.StructuredData = append(msg.StructuredData, element)
+	}
+
+	if len(msg.StructuredData) == 0 {
+		return "", errors.New("invalid structured data")
+	}
+	return s, nil
+}
+
+// parseParamValue parses a quoted parameter value, unescaping '"', '\' and ']'.
+func parseParamValue(s string) (string, string, error) {
+	var value strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return value.String(), s[i+1:], nil
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+				i++
+				c = s[i]
+			}
+			value.WriteByte(c)
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", "", errors.New("unterminated value")
+}
+
+// parseRFC3164 parses the timestamp, hostname, tag and content of an RFC3164 message on a best effort basis.
+func parseRFC3164(msg *Message, s string, now time.Time) {
+	if len(s) < len(rfc3164Timestamp) {
+		msg.Message = s
+		return
+	}
+
+	timestamp, err := time.ParseInLocation(rfc3164Timestamp, s[:len(rfc3164Timestamp)], now.Location())
+	if err != nil {
+		msg.Message = s
+		return
+	}
+	timestamp = timestamp.AddDate(now.Year(), 0, 0)
+	if timestamp.After(now.Add(24 * time.Hour)) {
+		timestamp = timestamp.AddDate(-1, 0, 0)
+	}
+	msg.Timestamp = timestamp
+	s = strings.TrimPrefix(s[len(rfc3164Timestamp):], " ")
+
+	hostname, rest, ok := strings.Cut(s, " ")
+	if !ok {
+		msg.Message = s
+		return
+	}
+	msg.Hostname = hostname
+	s = rest
+
+	// The tag is the alphanumeric prefix of the content, optionally followed by the process ID in brackets.
+	end := strings.IndexFunc(s, func(r rune) bool { return r == ':' || r == '[' || r == ' ' })
+	if end <= 0 || end > 48 || !utf8.ValidString(s[:end]) {
+		msg.Message = s
+		return
+	}
+	msg.AppName = s[:end]
+	s = s[end:]
+
+	if strings.HasPrefix(s, "[") {
+		if pid, rest, ok := strings.Cut(s[1:], "]"); ok {
+			msg.ProcID = pid
+			s = rest
+		}
+	}
+	s = strings.TrimPrefix(s, ":")
+	msg.Message = strings.TrimPrefix(s, " ")
+}
+
+// header returns the value of a header field, or an empty string for the NILVALUE.
+func header(field string) string {
+	if field == nilValue {
+		return ""
+	}
+	return field
+}
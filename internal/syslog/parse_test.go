@@ -0,0 +1,140 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    *Message
+		wantErr bool
+	}{
+		{
+			name:  "rfc5424 with structured data",
+			input: `<165>1 2023-10-11T22:14:15.003Z router1 evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][tenant@32473 id="tenant-a"] ` + byteOrderMark + "An application event",
+			want: &Message{
+				Facility:  20,
+				Severity:  5,
+				Version:   1,
+				Timestamp: time.Date(2023, time.October, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "router1",
+				AppName:   "evntslog",
+				ProcID:    "1234",
+				MsgID:     "ID47",
+				StructuredData: []Element{
+					{ID: "exampleSDID@32473", Params: []Param{{Name: "iut", Value: "3"}, {Name: "eventSource", Value: `App"lication`}}},
+					{ID: "tenant@32473", Params: []Param{{Name: "id", Value: "tenant-a"}}},
+				},
+				Message: "An application event",
+			},
+		},
+		{
+			name:  "rfc5424 with nil values",
+			input: "<34>1 - - - - - -",
+			want:  &Message{Facility: 4, Severity: 2, Version: 1},
+		},
+		{
+			name:  "rfc5424 without structured data",
+			input: "<14>1 2023-10-11T22:14:15Z host app - - - link down\n",
+			want: &Message{
+				Facility:  1,
+				Severity:  6,
+				Version:   1,
+				Timestamp: time.Date(2023, time.October, 11, 22, 14, 15, 0, time.UTC),
+				Hostname:  "host",
+				AppName:   "app",
+				Message:   "link down",
+			},
+		},
+		{
+			name:  "rfc3164",
+			input: "<34>Jan  2 11:22:33 mymachine su[42]: 'su root' failed for lonvick",
+			want: &Message{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: time.Date(2024, time.January, 2, 11, 22, 33, 0, time.UTC),
+				Hostname:  "mymachine",
+				AppName:   "su",
+				ProcID:    "42",
+				Message:   "'su root' failed for lonvick",
+			},
+		},
+		{
+			name:  "rfc3164 from the previous year",
+			input: "<13>Dec 31 23:59:59 host kernel: eth0 up",
+			want: &Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC),
+				Hostname:  "host",
+				AppName:   "kernel",
+				Message:   "eth0 up",
+			},
+		},
+		{
+			name:  "rfc3164 without timestamp",
+			input: "<13>just some text",
+			want:  &Message{Facility: 1, Severity: 5, Message: "just some text"},
+		},
+		{
+			name:    "missing priority",
+			input:   "no priority",
+			wantErr: true,
+		},
+		{
+			name:    "priority out of range",
+			input:   "<192>1 - - - - - -",
+			wantErr: true,
+		},
+		{
+			name:    "rfc5424 truncated header",
+			input:   "<14>1 2023-10-11T22:14:15Z host",
+			wantErr: true,
+		},
+		{
+			name:    "rfc5424 invalid timestamp",
+			input:   "<14>1 yesterday host app - - -",
+			wantErr: true,
+		},
+		{
+			name:    "rfc5424 unterminated structured data",
+			input:   `<14>1 - host app - - [id@1 a="b"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input), now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMessage_Param(t *testing.T) {
+	msg := &Message{StructuredData: []Element{
+		{ID: "a@1", Params: []Param{{Name: "x", Value: "1"}}},
+		{ID: "b@1", Params: []Param{{Name: "id", Value: "tenant-b"}}},
+	}}
+
+	value, ok := msg.Param("b@1", "id")
+	assert.True(t, ok)
+	assert.Equal(t, "tenant-b", value)
+
+	_, ok = msg.Param("a@1", "id")
+	assert.False(t, ok)
+}
@@ -0,0 +1,568 @@
+// Package syslog provides a syslog receiver for logs.
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/batchmetrics"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/util/cert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	addressAttrKey  = "syslog.address"
+	protocolAttrKey = "syslog.protocol"
+	remoteAttrKey   = "syslog.remote"
+	errAttrKey      = "error"
+
+	clientAddressAttrKey = "client.address"
+	facilityAttrKey      = "syslog.facility"
+	versionAttrKey       = "syslog.version"
+	hostnameAttrKey      = "syslog.hostname"
+	appNameAttrKey       = "syslog.appname"
+	procIDAttrKey        = "syslog.procid"
+	msgIDAttrKey         = "syslog.msgid"
+	structuredDataPrefix = "syslog.sd."
+)
+
+// scopeName is the instrumentation scope of the converted log records.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/syslog"
+
+// tenantParam is the parameter of the tenant structured data element holding the tenant.
+const tenantParam = "id"
+
+// maxMessageSize limits the size of a single syslog message.
+const maxMessageSize = 64 << 10
+
+// severities maps syslog severities to OTLP severity numbers and texts.
+var severities = [8]struct {
+	number logpb.SeverityNumber
+	text   string
+}{
+	{logpb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "emerg"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_FATAL3, "alert"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_FATAL, "crit"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_ERROR, "err"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_WARN, "warning"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_INFO2, "notice"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_INFO, "info"},
+	{logpb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "debug"},
+}
+
+// Sink receives the log resources converted from syslog messages.
+type Sink func(ctx context.Context, resources []*logpb.ResourceLogs) error
+
+// source maps the messages received from a network to a tenant.
+type source struct {
+	prefix netip.Prefix
+	tenant string
+}
+
+// resourceAttribute maps a structured data parameter to a resource attribute.
+type resourceAttribute struct {
+	id   string
+	name string
+	key  string
+}
+
+// batch holds the pending records of a tenant sharing the same resource attributes.
+type batch struct {
+	tenant     string
+	attributes []*commonpb.KeyValue
+	records    []*logpb.LogRecord
+}
+
+// Option configures optional dependencies of a Server.
+type Option func(*Server) error
+
+// WithMeter records the batches and pending messages of the server to the meter.
+func WithMeter(meter metric.Meter) Option {
+	return func(s *Server) error {
+		metrics, err := batchmetrics.New(meter, "syslog", "logs", int64(s.config.BatchSize), s.Pending)
+		s.metrics = metrics
+		return err
+	}
+}
+
+// WithClock sets the clock timestamping the messages and driving the flush interval.
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) error {
+		s.clock = c
+		return nil
+	}
+}
+
+// Server receives syslog messages, batches them per tenant and hands them to a Sink.
+type Server struct {
+	config     *config.Syslog
+	tenant     *config.Tenant
+	sink       Sink
+	sources    []source
+	attributes []resourceAttribute
+	clock      clock.Clock
+	metrics    *batchmetrics.Metrics
+
+	mu      sync.Mutex
+	pending map[string]*batch
+	count   int
+}
+
+// New creates a new Server handing the converted messages to the sink.
+func New(config *config.Syslog, tenant *config.Tenant, sink Sink, opts ...Option) (*Server, error) {
+	sources, err := parseSources(config.TenantSources)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := parseResourceAttributes(config.ResourceAttributes, tenant.Label)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:     config,
+		tenant:     tenant,
+		sink:       sink,
+		sources:    sources,
+		attributes: attributes,
+		clock:      clock.New(),
+		pending:    make(map[string]*batch),
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Pending returns the number of messages waiting to be flushed.
+func (s *Server) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(s.count)
+}
+
+// parseSources parses source entries of the form cidr=tenant, a bare address matches that address only.
+func parseSources(entries []string) ([]source, error) {
+	sources := make([]source, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		network, tenant, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid syslog tenant source %q, expected cidr=tenant", entry)
+		}
+
+		network = strings.TrimSpace(network)
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(network)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid syslog tenant source %q: %w", entry, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		sources = append(sources, source{prefix: prefix.Masked(), tenant: tenant})
+	}
+	return sources, nil
+}
+
+// parseResourceAttributes parses entries of the form <SD-ID>.<name>=<attribute>, the parameter name being the part
+// after the last dot. The tenant label cannot be mapped, the tenant is read from the tenant structured data element.
+func parseResourceAttributes(entries []string, tenantLabel string) ([]resourceAttribute, error) {
+	attributes := make([]resourceAttribute, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		param, key, _ := strings.Cut(entry, "=")
+		param, key = strings.TrimSpace(param), strings.TrimSpace(key)
+		dot := strings.LastIndex(param, ".")
+		if key == "" || dot <= 0 || dot == len(param)-1 {
+			return nil, fmt.Errorf("invalid syslog resource attribute %q, expected <SD-ID>.<name>=<attribute>", entry)
+		}
+		if key == tenantLabel {
+			return nil, fmt.Errorf("invalid syslog resource attribute %q, the tenant label is set from the tenant "+
+				"structured data element", entry)
+		}
+
+		attributes = append(attributes, resourceAttribute{
+			id:   param[:dot],
+			name: param[dot+1:],
+			key:  key,
+		})
+	}
+	return attributes, nil
+}
+
+// Run listens on the configured TCP and UDP addresses and serves them until the context is cancelled, the messages
+// still pending are flushed before returning.
+func (s *Server) Run(ctx context.Context) error {
+	g, gctx := errgroup.WithContext(ctx)
+
+	if s.config.TCPAddress != "" {
+		listener, err := net.Listen("tcp", s.config.TCPAddress)
+		if err != nil {
+			return err
+		}
+
+		protocol := "tcp"
+		if cert.TLSEnabled(&s.config.TLS) {
+			tlsConfig, err := cert.CreateServerTLSConfig(&s.config.TLS)
+			if err != nil {
+				_ = listener.Close()
+				return err
+			}
+			listener = tls.NewListener(listener, tlsConfig)
+			protocol = "tls"
+		}
+
+		logger.Info(ctx, "starting syslog receiver",
+			attribute.String(addressAttrKey, s.config.TCPAddress), attribute.String(protocolAttrKey, protocol))
+		g.Go(func() error { return s.ServeTCP(gctx, listener) })
+	}
+
+	if s.config.UDPAddress != "" {
+		conn, err := net.ListenPacket("udp", s.config.UDPAddress)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(ctx, "starting syslog receiver",
+			attribute.String(addressAttrKey, s.config.UDPAddress), attribute.String(protocolAttrKey, "udp"))
+		g.Go(func() error { return s.ServeUDP(gctx, conn) })
+	}
+
+	g.Go(func() error {
+		ticker := s.clock.NewTicker(s.config.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-gctx.Done():
+				return nil
+			case <-ticker.C():
+				s.flush(gctx, batchmetrics.TriggerTimeout)
+			}
+		}
+	})
+
+	err := g.Wait()
+	s.flush(context.WithoutCancel(ctx), batchmetrics.TriggerShutdown)
+	return err
+}
+
+// ServeTCP serves the connections accepted by the listener until the context is cancelled.
+func (s *Server) ServeTCP(ctx context.Context, listener net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Go(func() { s.serveConn(ctx, conn) })
+	}
+}
+
+// serveConn reads the octet-counted or newline delimited messages of a connection.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	defer func() { _ = conn.Close() }()
+
+	remote := remoteAddr(conn.RemoteAddr())
+	reader := bufio.NewReaderSize(conn, maxMessageSize)
+
+	for {
+		if s.config.Timeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.config.Timeout))
+		}
+
+		frame, err := readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				logger.Warn(ctx, "failed to read syslog message",
+					attribute.String(remoteAttrKey, conn.RemoteAddr().String()), attribute.String(errAttrKey, err.Error()))
+			}
+			return
+		}
+
+		s.add(ctx, frame, remote)
+	}
+}
+
+// ServeUDP serves the datagrams received on the connection until the context is cancelled.
+func (s *Server) ServeUDP(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		frame := bytes.TrimRight(buf[:n], "\r\n\x00")
+		if len(frame) > 0 {
+			s.add(ctx, bytes.Clone(frame), remoteAddr(addr))
+		}
+	}
+}
+
+// readFrame reads an octet-counted message, or a newline delimited message when the frame does not start with a
+// message length.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		if first[0] >= '0' && first[0] <= '9' {
+			prefix, err := reader.ReadSlice(' ')
+			if err != nil {
+				return nil, fmt.Errorf("invalid octet count: %w", err)
+			}
+			length, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+			if err != nil || length <= 0 || length > maxMessageSize {
+				return nil, fmt.Errorf("invalid octet count %q", prefix[:len(prefix)-1])
+			}
+
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return nil, err
+			}
+			return frame, nil
+		}
+
+		line, err := reader.ReadSlice('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			return nil, err
+		}
+		if line = bytes.TrimRight(line, "\r\n"); len(line) > 0 {
+			return bytes.Clone(line), nil
+		}
+	}
+}
+
+// add converts a message into a log record and queues it for its tenant, flushing once the batch is full.
+func (s *Server) add(ctx context.Context, frame []byte, remote netip.Addr) {
+	now := s.clock.Now()
+	record := &logpb.LogRecord{ObservedTimeUnixNano: uint64(now.UnixNano())}
+	if remote.IsValid() {
+		record.Attributes = append(record.Attributes, stringKeyValue(clientAddressAttrKey, remote.String()))
+	}
+
+	msg, err := Parse(frame, now)
+	if err != nil {
+		// Forward messages that cannot be parsed unchanged rather than dropping them.
+		record.Body = stringValue(string(frame))
+		s.queue(ctx, s.sourceTenant(remote), record)
+		return
+	}
+
+	fillRecord(record, msg, s.mapped)
+
+	tenant, ok := msg.Param(s.config.TenantSDID, tenantParam)
+	if s.config.TenantSDID == "" || !ok {
+		tenant = s.sourceTenant(remote)
+	}
+	s.queue(ctx, tenant, record, s.resourceAttributes(msg)...)
+}
+
+// resourceAttributes returns the resource attributes mapped from the structured data of a message.
+func (s *Server) resourceAttributes(msg *Message) []*commonpb.KeyValue {
+	var attributes []*commonpb.KeyValue
+	for _, mapping := range s.attributes {
+		if value, ok := msg.Param(mapping.id, mapping.name); ok {
+			attributes = append(attributes, stringKeyValue(mapping.key, value))
+		}
+	}
+	return attributes
+}
+
+// mapped reports whether a structured data parameter is mapped to a resource attribute.
+func (s *Server) mapped(id, name string) bool {
+	return slices.ContainsFunc(s.attributes, func(mapping resourceAttribute) bool {
+		return mapping.id == id && mapping.name == name
+	})
+}
+
+// sourceTenant returns the tenant of the first source containing the address.
+func (s *Server) sourceTenant(remote netip.Addr) string {
+	for _, source := range s.sources {
+		if source.prefix.Contains(remote) {
+			return source.tenant
+		}
+	}
+	return ""
+}
+
+// queue adds a record to the pending batch of the tenant and resource attributes.
+func (s *Server) queue(ctx context.Context, tenant string, record *logpb.LogRecord, attributes ...*commonpb.KeyValue) {
+	key := tenant
+	for _, kv := range attributes {
+		key += "\x00" + kv.GetKey() + "=" + kv.GetValue().GetStringValue()
+	}
+
+	s.mu.Lock()
+	group, ok := s.pending[key]
+	if !ok {
+		group = &batch{tenant: tenant, attributes: attributes}
+		s.pending[key] = group
+	}
+	group.records = append(group.records, record)
+	s.count++
+	full := s.count >= s.config.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		s.flush(ctx, batchmetrics.TriggerSize)
+	}
+}
+
+// Flush hands the pending records to the sink, grouping them into one resource per tenant and resource attributes.
+func (s *Server) Flush(ctx context.Context) {
+	s.flush(ctx, "")
+}
+
+// flush hands the pending records to the sink, recording the trigger of the batch.
+func (s *Server) flush(ctx context.Context, trigger string) {
+	s.mu.Lock()
+	pending := s.pending
+	count := s.count
+	s.pending = make(map[string]*batch)
+	s.count = 0
+	s.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	s.metrics.Sent(ctx, count, trigger)
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	resources := make([]*logpb.ResourceLogs, 0, len(keys))
+	for _, key := range keys {
+		group := pending[key]
+		resource := &resourcepb.Resource{}
+		if group.tenant != "" {
+			resource.Attributes = []*commonpb.KeyValue{stringKeyValue(s.tenant.Label, group.tenant)}
+		}
+		resource.Attributes = append(resource.Attributes, group.attributes...)
+		resources = append(resources, &logpb.ResourceLogs{
+			Resource: resource,
+			ScopeLogs: []*logpb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+				LogRecords: group.records,
+			}},
+		})
+	}
+
+	if err := s.sink(ctx, resources); err != nil {
+		logger.Error(ctx, "failed to forward syslog messages", attribute.String(errAttrKey, err.Error()))
+	}
+}
+
+// fillRecord sets the timestamp, severity, body and attributes of the record from a parsed message, leaving out the
+// structured data parameters mapped to resource attributes.
+func fillRecord(record *logpb.LogRecord, msg *Message, mapped func(id, name string) bool) {
+	if !msg.Timestamp.IsZero() {
+		record.TimeUnixNano = uint64(msg.Timestamp.UnixNano())
+	}
+	record.SeverityNumber = severities[msg.Severity].number
+	record.SeverityText = severities[msg.Severity].text
+	record.Body = stringValue(msg.Message)
+
+	record.Attributes = append(record.Attributes, intKeyValue(facilityAttrKey, int64(msg.Facility)))
+	if msg.Version > 0 {
+		record.Attributes = append(record.Attributes, intKeyValue(versionAttrKey, int64(msg.Version)))
+	}
+	for key, value := range map[string]string{
+		hostnameAttrKey: msg.Hostname,
+		appNameAttrKey:  msg.AppName,
+		procIDAttrKey:   msg.ProcID,
+		msgIDAttrKey:    msg.MsgID,
+	} {
+		if value != "" {
+			record.Attributes = append(record.Attributes, stringKeyValue(key, value))
+		}
+	}
+	slices.SortFunc(record.Attributes, func(a, b *commonpb.KeyValue) int { return strings.Compare(a.GetKey(), b.GetKey()) })
+
+	for _, element := range msg.StructuredData {
+		for _, param := range element.Params {
+			if mapped(element.ID, param.Name) {
+				continue
+			}
+			record.Attributes = append(record.Attributes, stringKeyValue(structuredDataPrefix+element.ID+"."+param.Name, param.Value))
+		}
+	}
+}
+
+// remoteAddr returns the IP address of a network address.
+func remoteAddr(addr net.Addr) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(addr.String()); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	return netip.Addr{}
+}
+
+// stringValue returns a string value.
+func stringValue(value string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: stringValue(value)}
+}
+
+// intKeyValue returns an integer attribute.
+func intKeyValue(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
@@ -0,0 +1,402 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestParseSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []source
+		wantErr bool
+	}{
+		{
+			name:    "networks and addresses",
+			entries: []string{"10.1.2.3/16=tenant-a", " 192.168.1.1 = tenant-b ", ""},
+			want: []source{
+				{prefix: netip.MustParsePrefix("10.1.0.0/16"), tenant: "tenant-a"},
+				{prefix: netip.MustParsePrefix("192.168.1.1/32"), tenant: "tenant-b"},
+			},
+		},
+		{
+			name:    "missing tenant",
+			entries: []string{"10.0.0.0/8="},
+			wantErr: true,
+		},
+		{
+			name:    "invalid network",
+			entries: []string{"not-a-network=tenant-a"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSources(tt.entries)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseResourceAttributes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []resourceAttribute
+		wantErr bool
+	}{
+		{
+			name:    "mappings",
+			entries: []string{"origin.ip=host.ip", " meta@32473.service = service.name ", ""},
+			want: []resourceAttribute{
+				{id: "origin", name: "ip", key: "host.ip"},
+				{id: "meta@32473", name: "service", key: "service.name"},
+			},
+		},
+		{
+			name:    "parameter name after the last dot",
+			entries: []string{"site.example.region=cloud.region"},
+			want:    []resourceAttribute{{id: "site.example", name: "region", key: "cloud.region"}},
+		},
+		{
+			name:    "missing attribute",
+			entries: []string{"origin.ip="},
+			wantErr: true,
+		},
+		{
+			name:    "missing parameter name",
+			entries: []string{"origin=host.ip"},
+			wantErr: true,
+		},
+		{
+			name:    "tenant label",
+			entries: []string{"meta@1.team=tenant.id"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResourceAttributes(tt.entries, "tenant.id")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "newline delimited",
+			input: "<13>first\r\n\n<13>second",
+			want:  []string{"<13>first", "<13>second"},
+		},
+		{
+			name:  "octet counted",
+			input: "11 <13>a\nb c d11 <13>second\n",
+			want:  []string{"<13>a\nb c d", "<13>second\n"},
+		},
+		{
+			name:    "invalid octet count",
+			input:   "99999999 <13>a",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tt.input))
+
+			var got []string
+			for range tt.want {
+				frame, err := readFrame(reader)
+				require.NoError(t, err)
+				got = append(got, string(frame))
+			}
+			assert.Equal(t, tt.want, got)
+
+			_, err := readFrame(reader)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "invalid octet count")
+			} else {
+				assert.ErrorIs(t, err, io.EOF)
+			}
+		})
+	}
+}
+
+func TestServer_Add(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	var received []*logpb.ResourceLogs
+	s, err := New(
+		&config.Syslog{BatchSize: 100, TenantSDID: "tenant@32473", TenantSources: []string{"10.0.0.0/8=tenant-net"}},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*logpb.ResourceLogs) error {
+			received = resources
+			return nil
+		},
+		WithClock(clock.NewFake(now)),
+	)
+	require.NoError(t, err)
+
+	s.add(t.Context(), []byte(`<165>1 2023-10-11T22:14:15Z router1 app - ID47 [tenant@32473 id="tenant-sd"][meta@1 site="lon"] link down`), netip.MustParseAddr("10.1.2.3"))
+	s.add(t.Context(), []byte("<12>Jan  2 11:22:33 switch1 kernel: port flap"), netip.MustParseAddr("10.1.2.4"))
+	s.add(t.Context(), []byte("garbage"), netip.MustParseAddr("192.168.1.1"))
+	s.Flush(t.Context())
+
+	require.Len(t, received, 3)
+
+	assert.Empty(t, received[0].GetResource().GetAttributes())
+	raw := received[0].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Equal(t, stringValue("garbage"), raw.GetBody())
+	assert.Equal(t, uint64(now.UnixNano()), raw.GetObservedTimeUnixNano())
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue(clientAddressAttrKey, "192.168.1.1")}, raw.GetAttributes())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-net")}, received[1].GetResource().GetAttributes())
+	flap := received[1].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Equal(t, logpb.SeverityNumber_SEVERITY_NUMBER_WARN, flap.GetSeverityNumber())
+	assert.Equal(t, "warning", flap.GetSeverityText())
+	assert.Equal(t, stringValue("port flap"), flap.GetBody())
+
+	assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-sd")}, received[2].GetResource().GetAttributes())
+	assert.Equal(t, scopeName, received[2].GetScopeLogs()[0].GetScope().GetName())
+	record := received[2].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Equal(t, uint64(time.Date(2023, time.October, 11, 22, 14, 15, 0, time.UTC).UnixNano()), record.GetTimeUnixNano())
+	assert.Equal(t, logpb.SeverityNumber_SEVERITY_NUMBER_INFO2, record.GetSeverityNumber())
+	assert.Equal(t, stringValue("link down"), record.GetBody())
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue(clientAddressAttrKey, "10.1.2.3"),
+		stringKeyValue(appNameAttrKey, "app"),
+		intKeyValue(facilityAttrKey, 20),
+		stringKeyValue(hostnameAttrKey, "router1"),
+		stringKeyValue(msgIDAttrKey, "ID47"),
+		intKeyValue(versionAttrKey, 1),
+		stringKeyValue("syslog.sd.tenant@32473.id", "tenant-sd"),
+		stringKeyValue("syslog.sd.meta@1.site", "lon"),
+	}, record.GetAttributes())
+}
+
+func TestServer_ResourceAttributes(t *testing.T) {
+	var received []*logpb.ResourceLogs
+	s, err := New(
+		&config.Syslog{
+			BatchSize:          100,
+			TenantSDID:         "tenant@32473",
+			ResourceAttributes: []string{"meta@1.site=site.name", "meta@1.service=service.name"},
+		},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*logpb.ResourceLogs) error {
+			received = resources
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	s.add(t.Context(), []byte(`<165>1 - - - - - [tenant@32473 id="acme"][meta@1 site="lon" rack="4"] first`), netip.Addr{})
+	s.add(t.Context(), []byte(`<165>1 - - - - - [tenant@32473 id="acme"][meta@1 site="par"] second`), netip.Addr{})
+	s.add(t.Context(), []byte(`<165>1 - - - - - [tenant@32473 id="acme"][meta@1 site="lon"] third`), netip.Addr{})
+	s.Flush(t.Context())
+
+	// Records of a tenant are grouped into one resource per set of mapped parameters
+	require.Len(t, received, 2)
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue("tenant.id", "acme"),
+		stringKeyValue("site.name", "lon"),
+	}, received[0].GetResource().GetAttributes())
+	assert.Len(t, received[0].GetScopeLogs()[0].GetLogRecords(), 2)
+	assert.Equal(t, []*commonpb.KeyValue{
+		stringKeyValue("tenant.id", "acme"),
+		stringKeyValue("site.name", "par"),
+	}, received[1].GetResource().GetAttributes())
+
+	// Mapped parameters are moved to the resource, the others stay on the record
+	record := received[0].GetScopeLogs()[0].GetLogRecords()[0]
+	assert.Contains(t, record.GetAttributes(), stringKeyValue("syslog.sd.meta@1.rack", "4"))
+	assert.NotContains(t, record.GetAttributes(), stringKeyValue("syslog.sd.meta@1.site", "lon"))
+}
+
+func TestServer_BatchMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	s, err := New(
+		&config.Syslog{BatchSize: 2},
+		&config.Tenant{Label: "tenant.id"},
+		func(context.Context, []*logpb.ResourceLogs) error { return nil },
+		WithMeter(meter),
+	)
+	require.NoError(t, err)
+
+	s.add(t.Context(), []byte("<12>first"), netip.Addr{})
+	s.add(t.Context(), []byte("<12>second"), netip.Addr{})
+	s.add(t.Context(), []byte("<12>third"), netip.Addr{})
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+			case metricdata.Gauge[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"otel_lgtm_proxy_processor_batch_batch_size_trigger_send_total": 1,
+		"otel_lgtm_proxy_exporter_queue_size":                           1,
+		"otel_lgtm_proxy_exporter_queue_capacity":                       2,
+	}, got)
+}
+
+func TestServer_FlushInterval(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, time.January, 2, 12, 0, 0, 0, time.UTC))
+	flushed := make(chan int, 1)
+	s, err := New(
+		&config.Syslog{BatchSize: 100, FlushInterval: time.Second},
+		&config.Tenant{Label: "tenant.id"},
+		func(_ context.Context, resources []*logpb.ResourceLogs) error {
+			flushed <- len(resources[0].GetScopeLogs()[0].GetLogRecords())
+			return nil
+		},
+		WithClock(fake),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	s.add(t.Context(), []byte("<12>first"), netip.Addr{})
+	s.add(t.Context(), []byte("<12>second"), netip.Addr{})
+
+	// Nothing is flushed before the interval elapses
+	fake.BlockUntilTickers(1)
+	fake.Advance(999 * time.Millisecond)
+	assert.Equal(t, int64(2), s.Pending())
+
+	fake.Advance(time.Millisecond)
+	select {
+	case records := <-flushed:
+		assert.Equal(t, 2, records)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the flush")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the server to stop")
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error)
+	}{
+		{
+			name: "tcp",
+			serve: func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error) {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+
+				done := make(chan error, 1)
+				go func() { done <- s.ServeTCP(ctx, listener) }()
+				return "tcp", listener.Addr().String(), done
+			},
+		},
+		{
+			name: "udp",
+			serve: func(t *testing.T, ctx context.Context, s *Server) (string, string, <-chan error) {
+				conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+				require.NoError(t, err)
+
+				done := make(chan error, 1)
+				go func() { done <- s.ServeUDP(ctx, conn) }()
+				return "udp", conn.LocalAddr().String(), done
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan []*logpb.ResourceLogs, 1)
+			s, err := New(
+				&config.Syslog{Timeout: time.Second, BatchSize: 1, TenantSources: []string{"127.0.0.0/8=tenant-local"}},
+				&config.Tenant{Label: "tenant.id"},
+				func(_ context.Context, resources []*logpb.ResourceLogs) error {
+					received <- resources
+					return nil
+				},
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			network, address, done := tt.serve(t, ctx, s)
+
+			conn, err := net.Dial(network, address)
+			require.NoError(t, err)
+			defer func() { _ = conn.Close() }()
+
+			_, err = conn.Write([]byte("<14>1 - host app - - - hello\n"))
+			require.NoError(t, err)
+
+			select {
+			case resources := <-received:
+				require.Len(t, resources, 1)
+				assert.Equal(t, []*commonpb.KeyValue{stringKeyValue("tenant.id", "tenant-local")}, resources[0].GetResource().GetAttributes())
+				assert.Equal(t, stringValue("hello"), resources[0].GetScopeLogs()[0].GetLogRecords()[0].GetBody())
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for messages")
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				assert.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the server to stop")
+			}
+		})
+	}
+}
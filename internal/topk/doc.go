@@ -0,0 +1,10 @@
+// Package topk tracks the tenants sending the most data without full-cardinality metrics.
+//
+// Volumes are counted in count-min sketches, which use a fixed amount of memory
+// regardless of the number of tenants and never underestimate a count. The
+// sliding window is split into buckets that are rotated as time passes, and each
+// bucket keeps a bounded set of candidate tenants with the highest estimates.
+//
+// The top tenants by bytes, together with their estimated record counts, are
+// exposed through the admin API and as gauges limited to the top K tenants.
+package topk
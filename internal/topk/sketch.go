@@ -0,0 +1,50 @@
+// Package topk tracks the tenants sending the most data without full-cardinality metrics.
+package topk
+
+import "hash/maphash"
+
+// sketch is a count-min sketch estimating the counts of string keys.
+type sketch struct {
+	seeds  []maphash.Seed
+	counts [][]uint64
+}
+
+// newSketch creates a sketch with the given number of rows and columns.
+func newSketch(depth, width int) *sketch {
+	s := &sketch{
+		seeds:  make([]maphash.Seed, depth),
+		counts: make([][]uint64, depth),
+	}
+	for i := range depth {
+		s.seeds[i] = maphash.MakeSeed()
+		s.counts[i] = make([]uint64, width)
+	}
+	return s
+}
+
+// add adds n to the count of the key and returns the new estimate.
+func (s *sketch) add(key string, n uint64) uint64 {
+	estimate := ^uint64(0)
+	for i, row := range s.counts {
+		col := maphash.String(s.seeds[i], key) % uint64(len(row))
+		row[col] += n
+		estimate = min(estimate, row[col])
+	}
+	return estimate
+}
+
+// estimate returns the estimated count of the key.
+func (s *sketch) estimate(key string) uint64 {
+	estimate := ^uint64(0)
+	for i, row := range s.counts {
+		estimate = min(estimate, row[maphash.String(s.seeds[i], key)%uint64(len(row))])
+	}
+	return estimate
+}
+
+// reset clears all counts of the sketch.
+func (s *sketch) reset() {
+	for _, row := range s.counts {
+		clear(row)
+	}
+}
@@ -0,0 +1,202 @@
+// Package topk tracks the tenants sending the most data without full-cardinality metrics.
+package topk
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+
+	// candidatesPerSize is the number of candidates kept per bucket for each tenant reported.
+	candidatesPerSize = 4
+)
+
+var (
+	signalTenantAttrKey = "signal.tenant"
+	topKRankAttrKey     = "topk.rank"
+)
+
+// Tenant is the estimated volume of a tenant over the sliding window.
+type Tenant struct {
+	Tenant  string `json:"tenant"`
+	Bytes   uint64 `json:"bytes"`
+	Records uint64 `json:"records"`
+}
+
+// bucket holds the volumes counted during a slice of the sliding window.
+type bucket struct {
+	start      time.Time
+	bytes      *sketch
+	records    *sketch
+	candidates map[string]uint64
+}
+
+// Tracker tracks the tenants with the highest volume over a sliding window. A nil Tracker discards everything added.
+type Tracker struct {
+	mu       sync.Mutex
+	size     int
+	interval time.Duration
+	buckets  []*bucket
+	current  int
+	now      func() time.Time
+}
+
+// New creates a new Tracker reporting the configured number of tenants.
+func New(cfg *config.TopK) *Tracker {
+	buckets := max(cfg.Buckets, 1)
+	t := &Tracker{
+		size:     max(cfg.Size, 1),
+		interval: max(cfg.Window/time.Duration(buckets), time.Second),
+		buckets:  make([]*bucket, buckets),
+		now:      time.Now,
+	}
+
+	start := t.now()
+	for i := range t.buckets {
+		t.buckets[i] = &bucket{
+			start:      start,
+			bytes:      newSketch(sketchDepth, sketchWidth),
+			records:    newSketch(sketchDepth, sketchWidth),
+			candidates: make(map[string]uint64),
+		}
+	}
+
+	return t
+}
+
+// Add adds the given volume to the tenant.
+func (t *Tracker) Add(tenant string, records, bytes int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.rotate()
+	b.records.add(tenant, uint64(max(records, 0)))
+	estimate := b.bytes.add(tenant, uint64(max(bytes, 0)))
+
+	if _, ok := b.candidates[tenant]; ok || len(b.candidates) < t.size*candidatesPerSize {
+		b.candidates[tenant] = estimate
+		return
+	}
+
+	// Replace the smallest candidate when the tenant has overtaken it.
+	smallest, smallestEstimate := "", ^uint64(0)
+	for candidate, candidateEstimate := range b.candidates {
+		if candidateEstimate < smallestEstimate {
+			smallest, smallestEstimate = candidate, candidateEstimate
+		}
+	}
+	if estimate > smallestEstimate {
+		delete(b.candidates, smallest)
+		b.candidates[tenant] = estimate
+	}
+}
+
+// Top returns the tenants with the highest estimated bytes over the sliding window.
+func (t *Tracker) Top() []Tenant {
+	if t == nil {
+		return []Tenant{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rotate()
+
+	candidates := make(map[string]struct{})
+	for _, b := range t.buckets {
+		for candidate := range b.candidates {
+			candidates[candidate] = struct{}{}
+		}
+	}
+
+	top := make([]Tenant, 0, len(candidates))
+	for candidate := range candidates {
+		tenant := Tenant{Tenant: candidate}
+		for _, b := range t.buckets {
+			tenant.Bytes += b.bytes.estimate(candidate)
+			tenant.Records += b.records.estimate(candidate)
+		}
+		top = append(top, tenant)
+	}
+
+	slices.SortFunc(top, func(a, b Tenant) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Tenant, b.Tenant))
+	})
+
+	return top[:min(len(top), t.size)]
+}
+
+// rotate advances the current bucket, clearing buckets that fell out of the window, and returns it.
+func (t *Tracker) rotate() *bucket {
+	now := t.now()
+	for steps := 0; steps < len(t.buckets); steps++ {
+		current := t.buckets[t.current]
+		if now.Sub(current.start) < t.interval {
+			break
+		}
+
+		t.current = (t.current + 1) % len(t.buckets)
+		next := t.buckets[t.current]
+		next.start = current.start.Add(t.interval)
+		if now.Sub(next.start) >= time.Duration(len(t.buckets))*t.interval {
+			next.start = now
+		}
+		next.bytes.reset()
+		next.records.reset()
+		clear(next.candidates)
+	}
+
+	return t.buckets[t.current]
+}
+
+// RegisterMetrics registers gauges reporting the volume of the top tenants.
+func (t *Tracker) RegisterMetrics(meter metric.Meter) error {
+	bytesGauge, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_topk_tenant_bytes",
+		metric.WithDescription("Estimated bytes sent by the top tenants over the sliding window"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create otel lgtm proxy topk bytes gauge: %w", err)
+	}
+
+	recordsGauge, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_topk_tenant_records",
+		metric.WithDescription("Estimated records sent by the top tenants over the sliding window"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create otel lgtm proxy topk records gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for rank, tenant := range t.Top() {
+			opt := metric.WithAttributes(
+				attribute.String(signalTenantAttrKey, tenant.Tenant),
+				attribute.Int(topKRankAttrKey, rank+1),
+			)
+			o.ObserveInt64(bytesGauge, int64(tenant.Bytes), opt)
+			o.ObserveInt64(recordsGauge, int64(tenant.Records), opt)
+		}
+		return nil
+	}, bytesGauge, recordsGauge)
+	if err != nil {
+		return fmt.Errorf("failed to register otel lgtm proxy topk callback: %w", err)
+	}
+
+	return nil
+}
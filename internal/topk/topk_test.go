@@ -0,0 +1,118 @@
+package topk
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketch(t *testing.T) {
+	s := newSketch(sketchDepth, sketchWidth)
+
+	for i := range 500 {
+		s.add(fmt.Sprintf("tenant-%d", i), uint64(i))
+	}
+
+	for i := range 500 {
+		assert.GreaterOrEqual(t, s.estimate(fmt.Sprintf("tenant-%d", i)), uint64(i), "estimates must never be lower than the count")
+	}
+
+	s.reset()
+	assert.Zero(t, s.estimate("tenant-1"))
+}
+
+func TestTracker_Top(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		adds map[string]int64
+		want []string
+	}{
+		{
+			name: "ordered by bytes",
+			size: 3,
+			adds: map[string]int64{"tenant-a": 100, "tenant-b": 300, "tenant-c": 200},
+			want: []string{"tenant-b", "tenant-c", "tenant-a"},
+		},
+		{
+			name: "limited to size",
+			size: 2,
+			adds: map[string]int64{"tenant-a": 100, "tenant-b": 300, "tenant-c": 200, "tenant-d": 50},
+			want: []string{"tenant-b", "tenant-c"},
+		},
+		{
+			name: "no data",
+			size: 2,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := New(&config.TopK{Size: tt.size, Window: time.Minute, Buckets: 6})
+			for tenant, bytes := range tt.adds {
+				tracker.Add(tenant, 1, bytes)
+			}
+
+			got := []string{}
+			for _, tenant := range tracker.Top() {
+				got = append(got, tenant.Tenant)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTracker_SurgeAmongManyTenants(t *testing.T) {
+	tracker := New(&config.TopK{Size: 1, Window: time.Minute, Buckets: 1})
+
+	for i := range 1000 {
+		tracker.Add(fmt.Sprintf("tenant-%d", i), 1, 10)
+	}
+	tracker.Add("noisy", 50, 100000)
+
+	top := tracker.Top()
+	require.Len(t, top, 1)
+	assert.Equal(t, "noisy", top[0].Tenant)
+	assert.GreaterOrEqual(t, top[0].Bytes, uint64(100000))
+	assert.GreaterOrEqual(t, top[0].Records, uint64(50))
+}
+
+func TestTracker_SlidingWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	tracker := New(&config.TopK{Size: 5, Window: time.Minute, Buckets: 3})
+	tracker.now = func() time.Time { return now }
+	for _, b := range tracker.buckets {
+		b.start = now
+	}
+
+	tracker.Add("tenant-a", 1, 100)
+
+	now = now.Add(30 * time.Second)
+	tracker.Add("tenant-b", 1, 50)
+
+	top := tracker.Top()
+	require.Len(t, top, 2)
+	assert.Equal(t, "tenant-a", top[0].Tenant)
+
+	// tenant-a falls out of the window once its bucket is reused.
+	now = now.Add(45 * time.Second)
+	top = tracker.Top()
+	require.Len(t, top, 1)
+	assert.Equal(t, "tenant-b", top[0].Tenant)
+
+	// Nothing remains after a full window of inactivity.
+	now = now.Add(2 * time.Minute)
+	assert.Empty(t, tracker.Top())
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+
+	assert.NotPanics(t, func() { tracker.Add("tenant-a", 1, 1) })
+	assert.Empty(t, tracker.Top())
+}
@@ -0,0 +1,270 @@
+// Package transform provides transformations applied to partitioned telemetry before it is forwarded.
+package transform
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultTenant is the allowlist key applied to tenants without their own allowlist.
+const DefaultTenant = "*"
+
+// MetricAttributeAllowlist restricts the data point attributes forwarded for each tenant.
+type MetricAttributeAllowlist struct {
+	tenants map[string][]string
+}
+
+// NewMetricAttributeAllowlist parses allowlist entries of the form tenant=attribute|attribute.
+func NewMetricAttributeAllowlist(entries []string) (*MetricAttributeAllowlist, error) {
+	tenants := make(map[string][]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		tenant, attributes, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid metric attribute allowlist entry %q, expected tenant=attribute|attribute", entry)
+		}
+
+		allowed := []string{}
+		for attribute := range strings.SplitSeq(attributes, "|") {
+			if attribute = strings.TrimSpace(attribute); attribute != "" {
+				allowed = append(allowed, attribute)
+			}
+		}
+		tenants[tenant] = allowed
+	}
+
+	return &MetricAttributeAllowlist{tenants: tenants}, nil
+}
+
+// Apply drops the data point attributes not allowed for the tenant and merges the resulting duplicate series.
+//
+// Tenants without an allowlist, and without a default allowlist, are left untouched.
+func (a *MetricAttributeAllowlist) Apply(tenant string, resources []*metricpb.ResourceMetrics) {
+	if a == nil {
+		return
+	}
+
+	allowed, ok := a.tenants[tenant]
+	if !ok {
+		if allowed, ok = a.tenants[DefaultTenant]; !ok {
+			return
+		}
+	}
+
+	for _, rm := range resources {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				applyToMetric(m, allowed)
+			}
+		}
+	}
+}
+
+// applyToMetric filters and merges the data points of a single metric.
+func applyToMetric(m *metricpb.Metric, allowed []string) {
+	switch data := m.GetData().(type) {
+	case *metricpb.Metric_Gauge:
+		data.Gauge.DataPoints = mergeDataPoints(data.Gauge.GetDataPoints(), allowed, mergeGauge)
+	case *metricpb.Metric_Sum:
+		data.Sum.DataPoints = mergeDataPoints(data.Sum.GetDataPoints(), allowed, mergeSum)
+	case *metricpb.Metric_Histogram:
+		data.Histogram.DataPoints = mergeDataPoints(data.Histogram.GetDataPoints(), allowed, mergeHistogram)
+	case *metricpb.Metric_ExponentialHistogram:
+		data.ExponentialHistogram.DataPoints = mergeDataPoints(data.ExponentialHistogram.GetDataPoints(), allowed, mergeExponentialHistogram)
+	case *metricpb.Metric_Summary:
+		data.Summary.DataPoints = mergeDataPoints(data.Summary.GetDataPoints(), allowed, mergeSummary)
+	}
+}
+
+// dataPoint is implemented by every OTLP data point type.
+type dataPoint interface {
+	*metricpb.NumberDataPoint | *metricpb.HistogramDataPoint | *metricpb.ExponentialHistogramDataPoint | *metricpb.SummaryDataPoint
+	proto.Message
+	GetAttributes() []*commonpb.KeyValue
+	GetStartTimeUnixNano() uint64
+	GetTimeUnixNano() uint64
+}
+
+// mergeDataPoints drops the attributes that are not allowed and merges data points of the same series and time.
+//
+// merge folds the second data point into the first and reports whether they could be merged.
+func mergeDataPoints[D dataPoint](points []D, allowed []string, merge func(D, D) bool) []D {
+	merged := make([]D, 0, len(points))
+	index := make(map[string]int, len(points))
+
+	for _, point := range points {
+		setAttributes(point, slices.DeleteFunc(point.GetAttributes(), func(kv *commonpb.KeyValue) bool {
+			return !slices.Contains(allowed, kv.GetKey())
+		}))
+
+		key := seriesKey(point)
+		if i, ok := index[key]; ok && merge(merged[i], point) {
+			continue
+		}
+
+		index[key] = len(merged)
+		merged = append(merged, point)
+	}
+
+	return merged
+}
+
+// setAttributes replaces the attributes of the data point.
+func setAttributes[D dataPoint](point D, attrs []*commonpb.KeyValue) {
+	switch p := any(point).(type) {
+	case *metricpb.NumberDataPoint:
+		p.Attributes = attrs
+	case *metricpb.HistogramDataPoint:
+		p.Attributes = attrs
+	case *metricpb.ExponentialHistogramDataPoint:
+		p.Attributes = attrs
+	case *metricpb.SummaryDataPoint:
+		p.Attributes = attrs
+	}
+}
+
+// seriesKey identifies the series and time of a data point.
+func seriesKey[D dataPoint](point D) string {
+	attrs := slices.Clone(point.GetAttributes())
+	slices.SortFunc(attrs, func(a, b *commonpb.KeyValue) int { return strings.Compare(a.GetKey(), b.GetKey()) })
+
+	var key strings.Builder
+	fmt.Fprintf(&key, "%d/%d", point.GetStartTimeUnixNano(), point.GetTimeUnixNano())
+	for _, kv := range attrs {
+		value, _ := proto.MarshalOptions{Deterministic: true}.Marshal(kv.GetValue())
+		fmt.Fprintf(&key, "\x00%s\x00%x", kv.GetKey(), value)
+	}
+
+	return key.String()
+}
+
+// mergeGauge keeps the first gauge value, as gauge values of merged series cannot be combined.
+func mergeGauge(dst, src *metricpb.NumberDataPoint) bool {
+	dst.Exemplars = append(dst.Exemplars, src.GetExemplars()...)
+	return true
+}
+
+// mergeSum adds the value of src to dst.
+func mergeSum(dst, src *metricpb.NumberDataPoint) bool {
+	switch {
+	case dst.GetValue() == nil:
+		dst.Value = src.GetValue()
+	case src.GetValue() == nil:
+	default:
+		_, dstInt := dst.GetValue().(*metricpb.NumberDataPoint_AsInt)
+		_, srcInt := src.GetValue().(*metricpb.NumberDataPoint_AsInt)
+		if dstInt && srcInt {
+			dst.Value = &metricpb.NumberDataPoint_AsInt{AsInt: dst.GetAsInt() + src.GetAsInt()}
+		} else {
+			dst.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: numberValue(dst) + numberValue(src)}
+		}
+	}
+
+	dst.Exemplars = append(dst.Exemplars, src.GetExemplars()...)
+	dst.Flags |= src.GetFlags()
+	return true
+}
+
+// numberValue returns the value of a number data point as a float.
+func numberValue(point *metricpb.NumberDataPoint) float64 {
+	if v, ok := point.GetValue().(*metricpb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return point.GetAsDouble()
+}
+
+// mergeHistogram adds src to dst when both use the same bucket boundaries.
+func mergeHistogram(dst, src *metricpb.HistogramDataPoint) bool {
+	if !slices.Equal(dst.GetExplicitBounds(), src.GetExplicitBounds()) || len(dst.GetBucketCounts()) != len(src.GetBucketCounts()) {
+		return false
+	}
+
+	dst.Count += src.GetCount()
+	for i, count := range src.GetBucketCounts() {
+		dst.BucketCounts[i] += count
+	}
+	dst.Sum = addOptional(dst.Sum, src.Sum)
+	dst.Min = combineOptional(dst.Min, src.Min, math.Min)
+	dst.Max = combineOptional(dst.Max, src.Max, math.Max)
+	dst.Exemplars = append(dst.Exemplars, src.GetExemplars()...)
+	dst.Flags |= src.GetFlags()
+	return true
+}
+
+// mergeExponentialHistogram adds src to dst when both use the same scale.
+func mergeExponentialHistogram(dst, src *metricpb.ExponentialHistogramDataPoint) bool {
+	if dst.GetScale() != src.GetScale() {
+		return false
+	}
+
+	dst.Count += src.GetCount()
+	dst.ZeroCount += src.GetZeroCount()
+	dst.ZeroThreshold = max(dst.GetZeroThreshold(), src.GetZeroThreshold())
+	dst.Positive = mergeBuckets(dst.GetPositive(), src.GetPositive())
+	dst.Negative = mergeBuckets(dst.GetNegative(), src.GetNegative())
+	dst.Sum = addOptional(dst.Sum, src.Sum)
+	dst.Min = combineOptional(dst.Min, src.Min, math.Min)
+	dst.Max = combineOptional(dst.Max, src.Max, math.Max)
+	dst.Exemplars = append(dst.Exemplars, src.GetExemplars()...)
+	dst.Flags |= src.GetFlags()
+	return true
+}
+
+// mergeBuckets adds the exponential histogram buckets of a and b, aligning them by offset.
+func mergeBuckets(a, b *metricpb.ExponentialHistogramDataPoint_Buckets) *metricpb.ExponentialHistogramDataPoint_Buckets {
+	if len(b.GetBucketCounts()) == 0 {
+		return a
+	}
+	if len(a.GetBucketCounts()) == 0 {
+		return b
+	}
+
+	offset := min(a.GetOffset(), b.GetOffset())
+	end := max(a.GetOffset()+int32(len(a.GetBucketCounts())), b.GetOffset()+int32(len(b.GetBucketCounts())))
+	counts := make([]uint64, end-offset)
+	for _, buckets := range []*metricpb.ExponentialHistogramDataPoint_Buckets{a, b} {
+		for i, count := range buckets.GetBucketCounts() {
+			counts[buckets.GetOffset()-offset+int32(i)] += count
+		}
+	}
+
+	return &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: offset, BucketCounts: counts}
+}
+
+// mergeSummary adds the count and sum of src to dst, dropping quantiles as they cannot be combined.
+func mergeSummary(dst, src *metricpb.SummaryDataPoint) bool {
+	dst.Count += src.GetCount()
+	dst.Sum += src.GetSum()
+	dst.QuantileValues = nil
+	dst.Flags |= src.GetFlags()
+	return true
+}
+
+// addOptional adds two optional values.
+func addOptional(a, b *float64) *float64 {
+	return combineOptional(a, b, func(x, y float64) float64 { return x + y })
+}
+
+// combineOptional combines two optional values, returning whichever is set when only one is.
+func combineOptional(a, b *float64, combine func(float64, float64) float64) *float64 {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	default:
+		v := combine(*a, *b)
+		return &v
+	}
+}
@@ -0,0 +1,176 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func kv(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func resources(metrics ...*metricpb.Metric) []*metricpb.ResourceMetrics {
+	return []*metricpb.ResourceMetrics{{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: metrics}}}}
+}
+
+func TestNewMetricAttributeAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			entries: []string{},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "tenants and default",
+			entries: []string{"tenant-a=service.name| http.route ", "*=service.name", "tenant-b="},
+			want: map[string][]string{
+				"tenant-a": {"service.name", "http.route"},
+				"*":        {"service.name"},
+				"tenant-b": {},
+			},
+		},
+		{
+			name:    "missing separator",
+			entries: []string{"tenant-a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing tenant",
+			entries: []string{"=service.name"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewMetricAttributeAllowlist(tt.entries)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.tenants)
+		})
+	}
+}
+
+func TestMetricAttributeAllowlist_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		tenant  string
+		metric  *metricpb.Metric
+		want    *metricpb.Metric
+	}{
+		{
+			name:    "tenant without allowlist is untouched",
+			entries: []string{"tenant-b=service.name"},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 1}},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 1}},
+			}}}},
+		},
+		{
+			name:    "sums of merged series are added",
+			entries: []string{"*=route"},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("route", "/a"), kv("pod", "a")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 1}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "b"), kv("route", "/a")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 2}},
+				{Attributes: []*commonpb.KeyValue{kv("route", "/b"), kv("pod", "a")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 0.5}},
+				{Attributes: []*commonpb.KeyValue{kv("route", "/b"), kv("pod", "b")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 1}},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("route", "/a")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsInt{AsInt: 3}},
+				{Attributes: []*commonpb.KeyValue{kv("route", "/b")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 1.5}},
+			}}}},
+		},
+		{
+			name:    "points at different times are not merged",
+			entries: []string{"tenant-a="},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 1}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "b")}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 2}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, TimeUnixNano: 20, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 3}},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{
+				{Attributes: []*commonpb.KeyValue{}, TimeUnixNano: 10, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 1}},
+				{Attributes: []*commonpb.KeyValue{}, TimeUnixNano: 20, Value: &metricpb.NumberDataPoint_AsDouble{AsDouble: 3}},
+			}}}},
+		},
+		{
+			name:    "histograms with the same bounds are merged",
+			entries: []string{"*="},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{DataPoints: []*metricpb.HistogramDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, Count: 2, Sum: float(3), Min: float(1), Max: float(2), ExplicitBounds: []float64{1}, BucketCounts: []uint64{1, 1}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "b")}, Count: 1, Sum: float(5), Min: float(5), Max: float(5), ExplicitBounds: []float64{1}, BucketCounts: []uint64{0, 1}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "c")}, Count: 1, ExplicitBounds: []float64{10}, BucketCounts: []uint64{1, 0}},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_Histogram{Histogram: &metricpb.Histogram{DataPoints: []*metricpb.HistogramDataPoint{
+				{Attributes: []*commonpb.KeyValue{}, Count: 3, Sum: float(8), Min: float(1), Max: float(5), ExplicitBounds: []float64{1}, BucketCounts: []uint64{1, 2}},
+				{Attributes: []*commonpb.KeyValue{}, Count: 1, ExplicitBounds: []float64{10}, BucketCounts: []uint64{1, 0}},
+			}}}},
+		},
+		{
+			name:    "exponential histogram buckets are aligned by offset",
+			entries: []string{"*="},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: &metricpb.ExponentialHistogram{DataPoints: []*metricpb.ExponentialHistogramDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, Scale: 2, Count: 3, ZeroCount: 1, Positive: &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: 1, BucketCounts: []uint64{1, 1}}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "b")}, Scale: 2, Count: 2, Positive: &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: 2, BucketCounts: []uint64{1, 1}}},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_ExponentialHistogram{ExponentialHistogram: &metricpb.ExponentialHistogram{DataPoints: []*metricpb.ExponentialHistogramDataPoint{
+				{Attributes: []*commonpb.KeyValue{}, Scale: 2, Count: 5, ZeroCount: 1, Positive: &metricpb.ExponentialHistogramDataPoint_Buckets{Offset: 1, BucketCounts: []uint64{1, 2, 1}}},
+			}}}},
+		},
+		{
+			name:    "summary quantiles are dropped when merged",
+			entries: []string{"*="},
+			tenant:  "tenant-a",
+			metric: &metricpb.Metric{Data: &metricpb.Metric_Summary{Summary: &metricpb.Summary{DataPoints: []*metricpb.SummaryDataPoint{
+				{Attributes: []*commonpb.KeyValue{kv("pod", "a")}, Count: 1, Sum: 1, QuantileValues: []*metricpb.SummaryDataPoint_ValueAtQuantile{{Quantile: 0.5, Value: 1}}},
+				{Attributes: []*commonpb.KeyValue{kv("pod", "b")}, Count: 2, Sum: 4},
+			}}}},
+			want: &metricpb.Metric{Data: &metricpb.Metric_Summary{Summary: &metricpb.Summary{DataPoints: []*metricpb.SummaryDataPoint{
+				{Attributes: []*commonpb.KeyValue{}, Count: 3, Sum: 5},
+			}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowlist, err := NewMetricAttributeAllowlist(tt.entries)
+			require.NoError(t, err)
+
+			allowlist.Apply(tt.tenant, resources(tt.metric))
+
+			assert.True(t, proto.Equal(tt.want, tt.metric), "got %v, want %v", tt.metric, tt.want)
+		})
+	}
+}
+
+func TestMetricAttributeAllowlist_Nil(t *testing.T) {
+	var allowlist *MetricAttributeAllowlist
+
+	assert.NotPanics(t, func() { allowlist.Apply("tenant-a", resources(&metricpb.Metric{})) })
+}
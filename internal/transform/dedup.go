@@ -0,0 +1,82 @@
+// Package transform provides transformations applied to partitioned telemetry before it is forwarded.
+package transform
+
+import (
+	"crypto/sha256"
+
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// logRecordKey identifies a log record by its timestamps and the hash of its body.
+type logRecordKey struct {
+	time     uint64
+	observed uint64
+	body     [sha256.Size]byte
+}
+
+// DedupSpans drops spans whose trace and span ID were already seen in the resources, keeping the first occurrence.
+//
+// It returns the number of spans dropped.
+func DedupSpans(resources []*tracepb.ResourceSpans) int {
+	seen := make(map[string]struct{})
+	dropped := 0
+
+	for _, resource := range resources {
+		for _, scope := range resource.GetScopeSpans() {
+			spans := scope.GetSpans()[:0]
+			for _, span := range scope.GetSpans() {
+				key := string(span.GetTraceId()) + string(span.GetSpanId())
+				if _, ok := seen[key]; ok {
+					dropped++
+					continue
+				}
+				seen[key] = struct{}{}
+				spans = append(spans, span)
+			}
+			scope.Spans = spans
+		}
+	}
+
+	return dropped
+}
+
+// DedupLogRecords drops log records whose timestamps and body were already seen in the resources, keeping the first
+// occurrence.
+//
+// It returns the number of log records dropped.
+func DedupLogRecords(resources []*logpb.ResourceLogs) int {
+	seen := make(map[logRecordKey]struct{})
+	marshal := proto.MarshalOptions{Deterministic: true}
+	dropped := 0
+
+	for _, resource := range resources {
+		for _, scope := range resource.GetScopeLogs() {
+			records := scope.GetLogRecords()[:0]
+			for _, record := range scope.GetLogRecords() {
+				// Marshalling an AnyValue only fails on invalid UTF-8, in which case the record is kept.
+				body, err := marshal.Marshal(record.GetBody())
+				if err != nil {
+					records = append(records, record)
+					continue
+				}
+
+				key := logRecordKey{
+					time:     record.GetTimeUnixNano(),
+					observed: record.GetObservedTimeUnixNano(),
+					body:     sha256.Sum256(body),
+				}
+				if _, ok := seen[key]; ok {
+					dropped++
+					continue
+				}
+				seen[key] = struct{}{}
+				records = append(records, record)
+			}
+			scope.LogRecords = records
+		}
+	}
+
+	return dropped
+}
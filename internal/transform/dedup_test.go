@@ -0,0 +1,125 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func span(traceID, spanID byte, name string) *tracepb.Span {
+	return &tracepb.Span{
+		TraceId: []byte{traceID, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		SpanId:  []byte{spanID, 0, 0, 0, 0, 0, 0, 0},
+		Name:    name,
+	}
+}
+
+func logRecord(time uint64, body string) *logpb.LogRecord {
+	return &logpb.LogRecord{
+		TimeUnixNano: time,
+		Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+	}
+}
+
+func TestDedupSpans(t *testing.T) {
+	tests := []struct {
+		name        string
+		resources   []*tracepb.ResourceSpans
+		want        [][]string // resource -> span names
+		wantDropped int
+	}{
+		{
+			name: "unique spans are kept",
+			resources: []*tracepb.ResourceSpans{
+				{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span(1, 1, "a"), span(1, 2, "b"), span(2, 1, "c")}}}},
+			},
+			want: [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "duplicates within a scope keep the first occurrence",
+			resources: []*tracepb.ResourceSpans{
+				{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span(1, 1, "a"), span(1, 2, "b"), span(1, 1, "a-retry")}}}},
+			},
+			want:        [][]string{{"a", "b"}},
+			wantDropped: 1,
+		},
+		{
+			name: "duplicates across overlapping batches",
+			resources: []*tracepb.ResourceSpans{
+				{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span(1, 1, "a"), span(1, 2, "b")}}}},
+				{ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{span(1, 2, "b"), span(1, 3, "c")}}}},
+			},
+			want:        [][]string{{"a", "b"}, {"c"}},
+			wantDropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantDropped, DedupSpans(tt.resources))
+
+			got := [][]string{}
+			for _, resource := range tt.resources {
+				names := []string{}
+				for _, scope := range resource.GetScopeSpans() {
+					for _, span := range scope.GetSpans() {
+						names = append(names, span.GetName())
+					}
+				}
+				got = append(got, names)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDedupLogRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		resources   []*logpb.ResourceLogs
+		want        int
+		wantDropped int
+	}{
+		{
+			name: "unique records are kept",
+			resources: []*logpb.ResourceLogs{
+				{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{logRecord(1, "a"), logRecord(1, "b"), logRecord(2, "a")}}}},
+			},
+			want: 3,
+		},
+		{
+			name: "same timestamp and body are dropped",
+			resources: []*logpb.ResourceLogs{
+				{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{logRecord(1, "a"), logRecord(2, "b")}}}},
+				{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{logRecord(1, "a"), logRecord(2, "b"), logRecord(3, "c")}}}},
+			},
+			want:        3,
+			wantDropped: 2,
+		},
+		{
+			name: "records without a body",
+			resources: []*logpb.ResourceLogs{
+				{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{TimeUnixNano: 1}, {TimeUnixNano: 1}, {TimeUnixNano: 2}}}}},
+			},
+			want:        2,
+			wantDropped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantDropped, DedupLogRecords(tt.resources))
+
+			got := 0
+			for _, resource := range tt.resources {
+				for _, scope := range resource.GetScopeLogs() {
+					got += len(scope.GetLogRecords())
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+// Package transform provides transformations applied to partitioned telemetry before it is forwarded.
+//
+// Transformations operate on the resources of a single tenant, allowing tenant
+// specific policies to be applied:
+//   - Metric attribute allowlists, dropping data point attributes that are not
+//     listed and merging the series that become identical, to control the number
+//     of series billed by Mimir
+//   - Deduplication of spans by trace and span ID, and of log records by
+//     timestamp and body hash, within a single request to avoid storing the
+//     overlapping batches re-sent by some agents twice
+//   - Enrichment of resources with the time the proxy received them and the
+//     proxy instance forwarding them, to measure ingestion lag downstream
+package transform
@@ -0,0 +1,83 @@
+// Package transform provides transformations applied to partitioned telemetry before it is forwarded.
+package transform
+
+import (
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Enrichment stamps the time the proxy received the data and the instance forwarding it onto resources.
+type Enrichment struct {
+	receivedAtKey string
+	instanceKey   string
+	instance      string
+}
+
+// NewEnrichment creates an Enrichment setting the given resource attributes, an empty key disables its attribute.
+//
+// A nil Enrichment is returned when both attributes are disabled.
+func NewEnrichment(receivedAtKey, instanceKey, instance string) *Enrichment {
+	if receivedAtKey == "" && instanceKey == "" {
+		return nil
+	}
+	return &Enrichment{receivedAtKey: receivedAtKey, instanceKey: instanceKey, instance: instance}
+}
+
+// Enrich sets the enrichment attributes on the resource of each of the resources, replacing existing values so they
+// describe the last proxy the data went through.
+//
+// The received time is formatted as RFC 3339 with nanoseconds.
+func Enrich[T proto.Message](e *Enrichment, resources []T, receivedAt time.Time) {
+	if e == nil {
+		return
+	}
+
+	var attrs []*commonpb.KeyValue
+	if e.receivedAtKey != "" {
+		attrs = append(attrs, stringAttribute(e.receivedAtKey, receivedAt.UTC().Format(time.RFC3339Nano)))
+	}
+	if e.instanceKey != "" {
+		attrs = append(attrs, stringAttribute(e.instanceKey, e.instance))
+	}
+
+	for _, r := range resources {
+		resource := mutableResource(r)
+		if resource == nil {
+			continue
+		}
+		for _, attr := range attrs {
+			setAttribute(resource, attr)
+		}
+	}
+}
+
+// mutableResource returns the resource of the message, creating it when missing, or nil when the message has none.
+func mutableResource(m proto.Message) *resourcepb.Resource {
+	msg := m.ProtoReflect()
+	field := msg.Descriptor().Fields().ByName("resource")
+	if field == nil {
+		return nil
+	}
+	resource, _ := msg.Mutable(field).Message().Interface().(*resourcepb.Resource)
+	return resource
+}
+
+// setAttribute sets a copy of the attribute on the resource, replacing the value of an attribute with the same key.
+func setAttribute(resource *resourcepb.Resource, attr *commonpb.KeyValue) {
+	attr = proto.CloneOf(attr)
+	for i, existing := range resource.Attributes {
+		if existing.GetKey() == attr.GetKey() {
+			resource.Attributes[i] = attr
+			return
+		}
+	}
+	resource.Attributes = append(resource.Attributes, attr)
+}
+
+// stringAttribute creates a string attribute.
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
@@ -0,0 +1,69 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEnrich(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name          string
+		receivedAtKey string
+		instanceKey   string
+		resource      *resourcepb.Resource
+		want          []*commonpb.KeyValue
+	}{
+		{
+			name:          "both attributes",
+			receivedAtKey: "proxy.received_at",
+			instanceKey:   "proxy.instance",
+			resource:      &resourcepb.Resource{Attributes: []*commonpb.KeyValue{kv("service.name", "api")}},
+			want: []*commonpb.KeyValue{
+				kv("service.name", "api"),
+				kv("proxy.received_at", "2024-01-02T02:04:05.000000006Z"),
+				kv("proxy.instance", "replica-1"),
+			},
+		},
+		{
+			name:        "existing attribute replaced",
+			instanceKey: "proxy.instance",
+			resource:    &resourcepb.Resource{Attributes: []*commonpb.KeyValue{kv("proxy.instance", "upstream")}},
+			want:        []*commonpb.KeyValue{kv("proxy.instance", "replica-1")},
+		},
+		{
+			name:        "missing resource created",
+			instanceKey: "proxy.instance",
+			want:        []*commonpb.KeyValue{kv("proxy.instance", "replica-1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources := []*logpb.ResourceLogs{{Resource: tt.resource}}
+
+			Enrich(NewEnrichment(tt.receivedAtKey, tt.instanceKey, "replica-1"), resources, receivedAt)
+
+			want := &resourcepb.Resource{Attributes: tt.want}
+			got := resources[0].GetResource()
+			assert.True(t, proto.Equal(want, got), "got %v, want %v", got, want)
+		})
+	}
+}
+
+func TestEnrich_Disabled(t *testing.T) {
+	resources := []*logpb.ResourceLogs{{}}
+
+	enrichment := NewEnrichment("", "", "replica-1")
+	assert.Nil(t, enrichment)
+
+	Enrich(enrichment, resources, time.Now())
+	assert.Nil(t, resources[0].GetResource())
+}
@@ -5,6 +5,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
 )
@@ -52,3 +54,78 @@ func CreateTLSConfig(config *config.Endpoint) (*tls.Config, error) {
 		MinVersion:   tls.VersionTLS13,
 	}, nil
 }
+
+// CreateServerTLSConfig creates a TLS configuration for a listener, verifying client certificates against the CA.
+func CreateServerTLSConfig(config *config.TLSConfig) (*tls.Config, error) {
+	certs, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPool := x509.NewCertPool()
+	caCert, err := os.ReadFile(config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	caPool.AppendCertsFromPEM(caCert)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certs},
+		ClientCAs:    caPool,
+		ClientAuth:   StringClientAuthType(config.ClientAuthType),
+		MinVersion:   tls.VersionTLS13,
+	}, nil
+}
+
+// NewClientSessionCache creates a TLS session cache holding up to size sessions, or nil when size is not positive.
+//
+// The cache is safe for concurrent use and is intended to be shared by the TLS configurations of all backend
+// clients, so connections to the same backend resume sessions instead of performing full handshakes.
+func NewClientSessionCache(size int) tls.ClientSessionCache {
+	if size <= 0 {
+		return nil
+	}
+	return tls.NewLRUClientSessionCache(size)
+}
+
+// TenantCertFiles returns the client certificate and key files of the tenant, named <tenant>.crt and <tenant>.key in
+// the tenant certificate directory. It reports false when no directory is configured, the tenant is not a plain file
+// name or its certificate and key do not both exist.
+func TenantCertFiles(cfg *config.TLSConfig, tenant string) (certFile, keyFile string, ok bool) {
+	if cfg.TenantCertDir == "" || tenant == "" || tenant != filepath.Base(tenant) || strings.HasPrefix(tenant, ".") {
+		return "", "", false
+	}
+
+	certFile = filepath.Join(cfg.TenantCertDir, tenant+".crt")
+	keyFile = filepath.Join(cfg.TenantCertDir, tenant+".key")
+	for _, file := range []string{certFile, keyFile} {
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			return "", "", false
+		}
+	}
+	return certFile, keyFile, true
+}
+
+// CreateTenantTLSConfig creates a client TLS configuration presenting the given certificate, verifying the backend
+// against the configured CA or the system roots when no CA is configured.
+func CreateTenantTLSConfig(cfg *config.TLSConfig, certFile, keyFile string) (*tls.Config, error) {
+	certs, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certs},
+		MinVersion:   tls.VersionTLS13,
+	}
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(caCert)
+	}
+	return tlsConfig, nil
+}
@@ -3,10 +3,16 @@ package cert
 
 import (
 	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTLSEnabled(t *testing.T) {
@@ -192,3 +198,80 @@ func TestCreateTLSConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateServerTLSConfig(t *testing.T) {
+	tlsConfig, err := CreateServerTLSConfig(&config.TLSConfig{
+		CertFile: "nonexistent.crt",
+		KeyFile:  "nonexistent.key",
+		CAFile:   "nonexistent.ca",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no such file or directory")
+	assert.Nil(t, tlsConfig)
+}
+
+func TestNewClientSessionCache(t *testing.T) {
+	assert.Nil(t, NewClientSessionCache(0))
+	assert.Nil(t, NewClientSessionCache(-1))
+	assert.NotNil(t, NewClientSessionCache(8))
+}
+
+func TestNewClientSessionCache_SharedAcrossClients(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sessionCache := NewClientSessionCache(8)
+	newClient := func() *http.Client {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		transport.TLSClientConfig.ClientSessionCache = sessionCache
+		transport.DisableKeepAlives = true
+		return &http.Client{Transport: transport}
+	}
+
+	// Each client represents the client of a different signal sharing the same cache.
+	for i, wantResumed := range []bool{false, true} {
+		resp, err := newClient().Get(server.URL)
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, wantResumed, resp.TLS.DidResume, "request %d", i)
+	}
+}
+
+func TestTenantCertFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"tenant-a.crt", "tenant-a.key", "tenant-b.crt", ".hidden.crt", ".hidden.key"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o600))
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		tenant string
+		wantOK bool
+	}{
+		{name: "certificate and key", dir: dir, tenant: "tenant-a", wantOK: true},
+		{name: "missing key", dir: dir, tenant: "tenant-b"},
+		{name: "unknown tenant", dir: dir, tenant: "tenant-c"},
+		{name: "no directory", tenant: "tenant-a"},
+		{name: "empty tenant", dir: dir},
+		{name: "path traversal", dir: dir, tenant: "../tenant-a"},
+		{name: "hidden file", dir: dir, tenant: ".hidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certFile, keyFile, ok := TenantCertFiles(&config.TLSConfig{TenantCertDir: tt.dir}, tt.tenant)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, filepath.Join(dir, tt.tenant+".crt"), certFile)
+				assert.Equal(t, filepath.Join(dir, tt.tenant+".key"), keyFile)
+			}
+		})
+	}
+}
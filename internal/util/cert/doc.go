@@ -5,6 +5,7 @@
 //   - Loading CA certificates for client verification
 //   - Creating TLS configurations for HTTP servers
 //   - Creating TLS configurations for HTTP clients
+//   - Creating TLS session caches shared across client connections
 //   - Converting string representations of client auth types to TLS constants
 //
 // The package supports mutual TLS (mTLS) authentication with configurable
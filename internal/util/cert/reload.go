@@ -0,0 +1,98 @@
+// Package cert provides common utility functions for TLS certificate management.
+package cert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+)
+
+// clientAuthTypes are the client authentication policies accepted from a client auth type file.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// ServerReloader holds the TLS configuration of a listener, which can be reloaded without restarting the listener.
+//
+// Handshakes use the configuration current when they start, so connections already established keep the policy they
+// were accepted with.
+type ServerReloader struct {
+	config  *config.TLSConfig
+	current atomic.Pointer[tls.Config]
+}
+
+// NewServerReloader creates a new ServerReloader, loading the initial TLS configuration.
+func NewServerReloader(config *config.TLSConfig) (*ServerReloader, error) {
+	r := &ServerReloader{config: config}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate, the key, the CA and the client auth type again. The current configuration is kept
+// when any of them cannot be read.
+//
+// The client auth type is read from the client auth type file when one is configured, so the policy can be changed
+// at runtime, and from the configuration otherwise.
+func (r *ServerReloader) Reload() error {
+	clientAuth, err := r.clientAuthType()
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := CreateServerTLSConfig(r.config)
+	if err != nil {
+		return err
+	}
+	tlsConfig.ClientAuth = clientAuth
+
+	r.current.Store(tlsConfig)
+	return nil
+}
+
+// ClientAuth returns the current client authentication policy.
+func (r *ServerReloader) ClientAuth() tls.ClientAuthType {
+	return r.current.Load().ClientAuth
+}
+
+// TLSConfig returns a TLS configuration for a listener negotiating the given application protocols, using the current
+// configuration of the reloader for every handshake.
+func (r *ServerReloader) TLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			tlsConfig := r.current.Load().Clone()
+			tlsConfig.NextProtos = nextProtos
+			return tlsConfig, nil
+		},
+	}
+}
+
+// clientAuthType returns the client authentication policy, read from the client auth type file when configured.
+func (r *ServerReloader) clientAuthType() (tls.ClientAuthType, error) {
+	if r.config.ClientAuthTypeFile == "" {
+		return StringClientAuthType(r.config.ClientAuthType), nil
+	}
+
+	b, err := os.ReadFile(r.config.ClientAuthTypeFile)
+	if err != nil {
+		return tls.NoClientCert, err
+	}
+
+	name := strings.TrimSpace(string(b))
+	clientAuth, ok := clientAuthTypes[name]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("invalid client auth type %q in %s", name, r.config.ClientAuthTypeFile)
+	}
+	return clientAuth, nil
+}
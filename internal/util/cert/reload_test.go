@@ -0,0 +1,114 @@
+// Package cert provides common utility functions for TLS certificate management.
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeServerCertificate writes a self-signed server certificate, which is also its own CA, and its key into dir.
+func writeServerCertificate(t *testing.T, dir string) *config.TLSConfig {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "proxy"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return &config.TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: certFile}
+}
+
+func TestServerReloader(t *testing.T) {
+	dir := t.TempDir()
+	tlsConfig := writeServerCertificate(t, dir)
+	tlsConfig.ClientAuthTypeFile = filepath.Join(dir, "client-auth-type")
+	require.NoError(t, os.WriteFile(tlsConfig.ClientAuthTypeFile, []byte("VerifyClientCertIfGiven\n"), 0o600))
+
+	reloader, err := NewServerReloader(tlsConfig)
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, reloader.ClientAuth())
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = reloader.TLSConfig("http/1.1")
+	server.StartTLS()
+	defer server.Close()
+
+	// A new client per request, so every request performs a handshake with the current policy.
+	get := func() error {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	require.NoError(t, get(), "client certificate is optional")
+
+	// Tighten the policy
+	require.NoError(t, os.WriteFile(tlsConfig.ClientAuthTypeFile, []byte("RequireAndVerifyClientCert"), 0o600))
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, tls.RequireAndVerifyClientCert, reloader.ClientAuth())
+	assert.Error(t, get(), "client certificate is required")
+
+	// An invalid policy keeps the current one
+	require.NoError(t, os.WriteFile(tlsConfig.ClientAuthTypeFile, []byte("RequireEverything"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, tls.RequireAndVerifyClientCert, reloader.ClientAuth())
+}
+
+func TestServerReloader_ClientAuthType(t *testing.T) {
+	tlsConfig := writeServerCertificate(t, t.TempDir())
+	tlsConfig.ClientAuthType = "RequireAnyClientCert"
+
+	reloader, err := NewServerReloader(tlsConfig)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAnyClientCert, reloader.ClientAuth())
+}
+
+func TestNewServerReloader_MissingFiles(t *testing.T) {
+	_, err := NewServerReloader(&config.TLSConfig{
+		CertFile: "nonexistent.crt",
+		KeyFile:  "nonexistent.key",
+		CAFile:   "nonexistent.ca",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,10 @@
+// Package ipfamily selects the IP families the proxy listens and dials on.
+//
+// By default listeners and backend clients are dual-stack: a wildcard listen
+// address accepts both IPv4 and IPv6 connections, and a backend host with both
+// A and AAAA records is dialled with Happy Eyeballs (RFC 6555), racing the
+// second family after a short fallback delay when the first one is slow to
+// connect. Pinning the family to ipv4 or ipv6 restricts listeners and dialers
+// to addresses of that family, for single-stack clusters where the other
+// family resolves but is not routable.
+package ipfamily
@@ -0,0 +1,58 @@
+// Package ipfamily selects the IP families the proxy listens and dials on.
+package ipfamily
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+)
+
+// dialKeepAlive is the keep-alive period of backend connections, like the one of http.DefaultTransport.
+const dialKeepAlive = 30 * time.Second
+
+// Validate returns an error when the IP family is unknown, an empty family being dual-stack.
+func Validate(family string) error {
+	switch family {
+	case "", config.IPFamilyDual, config.IPFamilyIPv4, config.IPFamilyIPv6:
+		return nil
+	}
+	return fmt.Errorf("invalid ip family %q, expected %s, %s or %s", family,
+		config.IPFamilyDual, config.IPFamilyIPv4, config.IPFamilyIPv6)
+}
+
+// Network returns the network of the IP family for a tcp or udp network, such as tcp6 for tcp over IPv6, or the
+// network itself when dual-stack.
+func Network(family, network string) string {
+	switch family {
+	case config.IPFamilyIPv4:
+		return network + "4"
+	case config.IPFamilyIPv6:
+		return network + "6"
+	}
+	return network
+}
+
+// Listen listens for TCP connections on the address with the IP family. A wildcard address listens on all the
+// addresses of the family, of both families when dual-stack.
+func Listen(family, address string) (net.Listener, error) {
+	return net.Listen(Network(family, "tcp"), address)
+}
+
+// DialContext returns the dial function of the clients of a backend, dialling the addresses of its IP family only.
+// Hosts resolving to addresses of both families are dialled with Happy Eyeballs, after the fallback delay of the
+// endpoint, or the 300ms default of the net package when zero; a negative delay dials the addresses one at a time.
+func DialContext(endpoint *config.Endpoint) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:       endpoint.Timeout,
+		KeepAlive:     dialKeepAlive,
+		FallbackDelay: endpoint.FallbackDelay,
+	}
+	family := endpoint.IPFamily
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, Network(family, network), address)
+	}
+}
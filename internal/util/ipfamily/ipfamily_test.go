@@ -0,0 +1,134 @@
+// Package ipfamily selects the IP families the proxy listens and dials on.
+package ipfamily
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenIPv6 listens on the IPv6 loopback address, skipping the test when the host has no IPv6.
+func listenIPv6(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	return listener
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		family  string
+		wantErr bool
+	}{
+		{family: ""},
+		{family: config.IPFamilyDual},
+		{family: config.IPFamilyIPv4},
+		{family: config.IPFamilyIPv6},
+		{family: "ipv5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.family, func(t *testing.T) {
+			err := Validate(tt.family)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "invalid ip family")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	assert.Equal(t, "tcp", Network("", "tcp"))
+	assert.Equal(t, "tcp", Network(config.IPFamilyDual, "tcp"))
+	assert.Equal(t, "tcp4", Network(config.IPFamilyIPv4, "tcp"))
+	assert.Equal(t, "udp6", Network(config.IPFamilyIPv6, "udp"))
+}
+
+func TestListen(t *testing.T) {
+	listenIPv6(t).Close()
+
+	tests := []struct {
+		name    string
+		family  string
+		address string
+		hosts   []string
+		wantErr bool
+	}{
+		{name: "dual-stack wildcard", family: config.IPFamilyDual, address: ":0", hosts: []string{"127.0.0.1", "::1"}},
+		{name: "dual-stack IPv6 literal", family: config.IPFamilyDual, address: "[::1]:0", hosts: []string{"::1"}},
+		{name: "IPv6 literal", family: config.IPFamilyIPv6, address: "[::1]:0", hosts: []string{"::1"}},
+		{name: "IPv6 wildcard", family: config.IPFamilyIPv6, address: "[::]:0", hosts: []string{"::1"}},
+		{name: "IPv4 literal", family: config.IPFamilyIPv4, address: "127.0.0.1:0", hosts: []string{"127.0.0.1"}},
+		{name: "IPv6 literal with IPv4 family", family: config.IPFamilyIPv4, address: "[::1]:0", wantErr: true},
+		{name: "IPv4 literal with IPv6 family", family: config.IPFamilyIPv6, address: "127.0.0.1:0", wantErr: true},
+		{name: "IPv6 literal without brackets", family: config.IPFamilyDual, address: "::1:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := Listen(tt.family, tt.address)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer func() { _ = listener.Close() }()
+
+			_, port, err := net.SplitHostPort(listener.Addr().String())
+			require.NoError(t, err)
+			for _, host := range tt.hosts {
+				conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), time.Second)
+				require.NoError(t, err, host)
+				_ = conn.Close()
+			}
+		})
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	listener := listenIPv6(t)
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	backend.Listener = listener
+	backend.Start()
+	defer backend.Close()
+
+	tests := []struct {
+		name    string
+		family  string
+		wantErr bool
+	}{
+		{name: "dual-stack", family: config.IPFamilyDual},
+		{name: "empty family", family: ""},
+		{name: "IPv6", family: config.IPFamilyIPv6},
+		{name: "IPv4", family: config.IPFamilyIPv4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &config.Endpoint{Timeout: time.Second, IPFamily: tt.family, FallbackDelay: 50 * time.Millisecond}
+			client := &http.Client{Transport: &http.Transport{DialContext: DialContext(endpoint)}}
+
+			// The URL of the backend carries the IPv6 literal in brackets, such as http://[::1]:port
+			resp, err := client.Get(backend.URL)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		})
+	}
+}
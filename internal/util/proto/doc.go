@@ -4,8 +4,10 @@
 // in HTTP requests and responses:
 //   - Unmarshaling protobuf binary format (application/x-protobuf)
 //   - Unmarshaling protobuf JSON format (application/json)
-//   - Marshaling protobuf messages to binary format
+//   - Leniently unmarshaling payloads, skipping the resources that cannot be parsed
+//   - Marshaling protobuf messages to binary or JSON format for backends
 //   - Content-type negotiation based on HTTP headers
+//   - Walking the fields of messages decoded without generated code
 //
 // The package uses Google's protobuf library for binary encoding and protojson
 // for JSON encoding, supporting both formats as specified in the OpenTelemetry
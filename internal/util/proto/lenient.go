@@ -0,0 +1,287 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// resourcesFieldNumber is the field number of the repeated resources field of the OTLP data and export messages.
+const resourcesFieldNumber = 1
+
+// recordPaths lists, for each resource message, the nested fields leading to its records. A level with several
+// names matches any of them.
+var recordPaths = map[protoreflect.FullName][][]protoreflect.Name{
+	"opentelemetry.proto.logs.v1.ResourceLogs":   {{"scope_logs"}, {"log_records"}},
+	"opentelemetry.proto.trace.v1.ResourceSpans": {{"scope_spans"}, {"spans"}},
+	"opentelemetry.proto.metrics.v1.ResourceMetrics": {
+		{"scope_metrics"},
+		{"metrics"},
+		{"gauge", "sum", "histogram", "exponential_histogram", "summary"},
+		{"data_points"},
+	},
+	"opentelemetry.proto.profiles.v1development.ResourceProfiles": {{"scope_profiles"}, {"profiles"}},
+}
+
+// Skipped describes the resources dropped by UnmarshalLenient because they could not be parsed.
+type Skipped struct {
+	// Resources is the number of resource entries skipped.
+	Resources int
+	// Records is the number of records found in the skipped resources, on a best effort basis.
+	Records int
+}
+
+// UnmarshalLenient unmarshals the request like Unmarshal, skipping the entries of the repeated resources field that
+// cannot be parsed instead of failing the whole request.
+//
+// An error is only returned when the envelope itself cannot be parsed.
+func UnmarshalLenient[T proto.Message](req *http.Request, targetType T) (T, Skipped, error) {
+	var zero T
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return zero, Skipped{}, err
+	}
+
+	resources := targetType.ProtoReflect().Descriptor().Fields().ByNumber(resourcesFieldNumber)
+	if resources == nil || !resources.IsList() || resources.Message() == nil {
+		return zero, Skipped{}, fmt.Errorf("%s has no repeated resources field", targetType.ProtoReflect().Descriptor().FullName())
+	}
+
+	var skipped Skipped
+	if IsJSON(req.Header.Get("Content-Type")) {
+		skipped, err = unmarshalJSONLenient(body, targetType, resources)
+	} else {
+		// Default to binary protobuf
+		skipped, err = unmarshalBinaryLenient(body, targetType, resources)
+	}
+	if err != nil {
+		return zero, Skipped{}, err
+	}
+
+	return targetType, skipped, nil
+}
+
+// unmarshalBinaryLenient unmarshals each resource entry of a binary payload on its own, the remaining fields of the
+// envelope are unmarshalled as usual.
+func unmarshalBinaryLenient(body []byte, target proto.Message, resources protoreflect.FieldDescriptor) (Skipped, error) {
+	var (
+		skipped Skipped
+		entries [][]byte
+		rest    []byte
+	)
+
+	for b := body; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return Skipped{}, protowire.ParseError(n)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return Skipped{}, protowire.ParseError(m)
+		}
+		field := b[:n+m]
+		b = b[n+m:]
+
+		if num != resources.Number() || typ != protowire.BytesType {
+			rest = append(rest, field...)
+			continue
+		}
+
+		entry, _ := protowire.ConsumeBytes(field[n:])
+		entries = append(entries, entry)
+	}
+
+	if err := proto.Unmarshal(rest, target); err != nil {
+		return Skipped{}, err
+	}
+
+	list := target.ProtoReflect().Mutable(resources).List()
+	path := recordPaths[resources.Message().FullName()]
+	for _, entry := range entries {
+		element := list.NewElement()
+		if err := proto.Unmarshal(entry, element.Message().Interface()); err != nil {
+			skipped.Resources++
+			skipped.Records += countBinaryRecords(entry, resources.Message(), path)
+			continue
+		}
+		list.Append(element)
+	}
+
+	return skipped, nil
+}
+
+// unmarshalJSONLenient unmarshals each resource entry of a JSON payload on its own, the remaining fields of the
+// envelope are unmarshalled as usual.
+func unmarshalJSONLenient(body []byte, target proto.Message, resources protoreflect.FieldDescriptor) (Skipped, error) {
+	var (
+		skipped Skipped
+		fields  map[string]json.RawMessage
+		entries []json.RawMessage
+	)
+
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return Skipped{}, err
+	}
+
+	for _, key := range []string{resources.JSONName(), string(resources.Name())} {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		delete(fields, key)
+
+		var values []json.RawMessage
+		if err := json.Unmarshal(value, &values); err != nil {
+			return Skipped{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+		entries = append(entries, values...)
+	}
+
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return Skipped{}, err
+	}
+	if err := protojson.Unmarshal(rest, target); err != nil {
+		return Skipped{}, err
+	}
+
+	list := target.ProtoReflect().Mutable(resources).List()
+	path := recordPaths[resources.Message().FullName()]
+	for _, entry := range entries {
+		element := list.NewElement()
+		if err := protojson.Unmarshal(entry, element.Message().Interface()); err != nil {
+			skipped.Resources++
+			skipped.Records += countJSONRecords(entry, resources.Message(), path)
+			continue
+		}
+		list.Append(element)
+	}
+
+	return skipped, nil
+}
+
+// CountRecords returns the number of records of an OTLP resource message, or 0 for any other message.
+func CountRecords(resource proto.Message) int {
+	message := resource.ProtoReflect()
+	path, ok := recordPaths[message.Descriptor().FullName()]
+	if !ok {
+		return 0
+	}
+	return countMessageRecords(message, path)
+}
+
+// countMessageRecords counts the records of a parsed message by following the record path.
+func countMessageRecords(message protoreflect.Message, path [][]protoreflect.Name) int {
+	count := 0
+	for _, name := range path[0] {
+		field := message.Descriptor().Fields().ByName(name)
+		if field == nil || field.Message() == nil || !message.Has(field) {
+			continue
+		}
+
+		if !field.IsList() {
+			if len(path) == 1 {
+				count++
+			} else {
+				count += countMessageRecords(message.Get(field).Message(), path[1:])
+			}
+			continue
+		}
+
+		list := message.Get(field).List()
+		if len(path) == 1 {
+			count += list.Len()
+			continue
+		}
+		for i := range list.Len() {
+			count += countMessageRecords(list.Get(i).Message(), path[1:])
+		}
+	}
+
+	return count
+}
+
+// countBinaryRecords counts the records of a binary message by following the record path, stopping at the first
+// malformed field.
+func countBinaryRecords(b []byte, message protoreflect.MessageDescriptor, path [][]protoreflect.Name) int {
+	if len(path) == 0 {
+		return 0
+	}
+
+	count := 0
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return count
+		}
+		b = b[n:]
+
+		field := message.Fields().ByNumber(num)
+		if typ != protowire.BytesType || field == nil || field.Message() == nil || !slices.Contains(path[0], field.Name()) {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return count
+			}
+			b = b[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return count
+		}
+		b = b[n:]
+
+		if len(path) == 1 {
+			count++
+		} else {
+			count += countBinaryRecords(value, field.Message(), path[1:])
+		}
+	}
+
+	return count
+}
+
+// countJSONRecords counts the records of a JSON message by following the record path, ignoring malformed values.
+func countJSONRecords(b []byte, message protoreflect.MessageDescriptor, path [][]protoreflect.Name) int {
+	var fields map[string]json.RawMessage
+	if len(path) == 0 || json.Unmarshal(b, &fields) != nil {
+		return 0
+	}
+
+	count := 0
+	for key, value := range fields {
+		field := message.Fields().ByJSONName(key)
+		if field == nil {
+			field = message.Fields().ByTextName(key)
+		}
+		if field == nil || field.Message() == nil || !slices.Contains(path[0], field.Name()) {
+			continue
+		}
+
+		values := []json.RawMessage{value}
+		if field.IsList() {
+			if err := json.Unmarshal(value, &values); err != nil {
+				continue
+			}
+		}
+
+		if len(path) == 1 {
+			count += len(values)
+			continue
+		}
+		for _, v := range values {
+			count += countJSONRecords(v, field.Message(), path[1:])
+		}
+	}
+
+	return count
+}
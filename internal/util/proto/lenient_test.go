@@ -0,0 +1,145 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUnmarshalLenient(t *testing.T) {
+	valid, err := proto.Marshal(&logpb.ResourceLogs{SchemaUrl: "valid", ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{}}}}})
+	require.NoError(t, err)
+
+	// A resource with two log records followed by a schema url that is not valid UTF-8.
+	invalid, err := proto.Marshal(&logpb.ResourceLogs{ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{}, {}}}}})
+	require.NoError(t, err)
+	invalid = protowire.AppendBytes(protowire.AppendTag(invalid, 3, protowire.BytesType), []byte{0xff})
+
+	envelope := func(entries ...[]byte) []byte {
+		var b []byte
+		for _, entry := range entries {
+			b = protowire.AppendBytes(protowire.AppendTag(b, 1, protowire.BytesType), entry)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name          string
+		contentType   string
+		body          []byte
+		target        proto.Message
+		wantResources int
+		wantSkipped   Skipped
+		wantErr       bool
+	}{
+		{
+			name:          "binary valid payload",
+			contentType:   contentTypeProtoBinary,
+			body:          envelope(valid, valid),
+			target:        &logpb.LogsData{},
+			wantResources: 2,
+		},
+		{
+			name:          "binary invalid resources are skipped",
+			contentType:   contentTypeProtoBinary,
+			body:          envelope(invalid, valid, invalid),
+			target:        &logpb.LogsData{},
+			wantResources: 1,
+			wantSkipped:   Skipped{Resources: 2, Records: 4},
+		},
+		{
+			name:        "binary malformed envelope",
+			contentType: contentTypeProtoBinary,
+			body:        []byte{0x0a, 0x05, 0x01},
+			target:      &logpb.LogsData{},
+			wantErr:     true,
+		},
+		{
+			name:          "json invalid resources are skipped",
+			contentType:   contentTypeProtoJSON,
+			body:          []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{}]}]},{"scopeLogs":[{"logRecords":[{},{}]}],"schemaUrl":5}]}`),
+			target:        &logpb.LogsData{},
+			wantResources: 1,
+			wantSkipped:   Skipped{Resources: 1, Records: 2},
+		},
+		{
+			name:          "json metric data points are counted",
+			contentType:   contentTypeProtoJSON,
+			body:          []byte(`{"resource_metrics":[{"scopeMetrics":[{"metrics":[{"sum":{"dataPoints":[{},{}]}},{"gauge":{"dataPoints":[{}]}}]}],"unknown":true}]}`),
+			target:        &metricpb.MetricsData{},
+			wantResources: 0,
+			wantSkipped:   Skipped{Resources: 1, Records: 3},
+		},
+		{
+			name:        "json malformed envelope",
+			contentType: contentTypeProtoJSON,
+			body:        []byte(`{"resourceLogs":{}}`),
+			target:      &logpb.LogsData{},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tt.contentType)
+
+			got, skipped, err := UnmarshalLenient(req, tt.target)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSkipped, skipped)
+			resources := got.ProtoReflect().Get(got.ProtoReflect().Descriptor().Fields().ByNumber(resourcesFieldNumber)).List()
+			assert.Equal(t, tt.wantResources, resources.Len())
+		})
+	}
+}
+
+func TestCountRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource proto.Message
+		want     int
+	}{
+		{
+			name: "log records",
+			resource: &logpb.ResourceLogs{ScopeLogs: []*logpb.ScopeLogs{
+				{LogRecords: []*logpb.LogRecord{{}, {}}},
+				{LogRecords: []*logpb.LogRecord{{}}},
+			}},
+			want: 3,
+		},
+		{
+			name: "metric data points",
+			resource: &metricpb.ResourceMetrics{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{
+				{Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{}, {}}}}},
+				{Data: &metricpb.Metric_Summary{Summary: &metricpb.Summary{DataPoints: []*metricpb.SummaryDataPoint{{}}}}},
+				{Name: "no data"},
+			}}}},
+			want: 3,
+		},
+		{
+			name:     "not a resource",
+			resource: &logpb.LogRecord{},
+			want:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CountRecords(tt.resource))
+		})
+	}
+}
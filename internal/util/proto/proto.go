@@ -2,15 +2,20 @@
 package proto
 
 import (
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
 const (
-	contentTypeProtoJSON = "application/json"
+	contentTypeProtoJSON   = "application/json"
+	contentTypeProtoBinary = "application/x-protobuf"
 )
 
 // Marshal marshals the request using protobuf binary format.
@@ -18,7 +23,40 @@ func Marshal(payload proto.Message) ([]byte, error) {
 	return proto.Marshal(payload)
 }
 
+// MarshalEncoding marshals the payload using the given outbound encoding, protobuf binary or protobuf JSON.
+func MarshalEncoding(payload proto.Message, encoding string) ([]byte, error) {
+	if err := ValidateEncoding(encoding); err != nil {
+		return nil, err
+	}
+
+	if encoding == config.EncodingJSON {
+		return protojson.Marshal(payload)
+	}
+	return proto.Marshal(payload)
+}
+
+// ValidateEncoding returns an error if the outbound encoding is not supported, an empty encoding defaults to protobuf.
+func ValidateEncoding(encoding string) error {
+	switch encoding {
+	case "", config.EncodingProtobuf, config.EncodingJSON:
+		return nil
+	default:
+		return fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// ContentType returns the content type of payloads marshaled with the given outbound encoding.
+func ContentType(encoding string) string {
+	if encoding == config.EncodingJSON {
+		return contentTypeProtoJSON
+	}
+	return contentTypeProtoBinary
+}
+
 // Unmarshal unmarshals the request.
+//
+// Unknown fields of binary payloads are retained by the message and written back by Marshal,
+// JSON payloads containing unknown fields are rejected rather than silently dropping them.
 func Unmarshal[T proto.Message](req *http.Request, targetType T) (T, error) {
 	var zero T
 
@@ -27,17 +65,42 @@ func Unmarshal[T proto.Message](req *http.Request, targetType T) (T, error) {
 		return zero, err
 	}
 
-	switch req.Header.Get("Content-Type") {
-	case contentTypeProtoJSON:
+	if IsJSON(req.Header.Get("Content-Type")) {
 		if err := protojson.Unmarshal(body, targetType); err != nil {
 			return zero, err
 		}
-	default:
-		// Default to binary protobuf
-		if err := proto.Unmarshal(body, targetType); err != nil {
-			return zero, err
-		}
+		return targetType, nil
+	}
+
+	// Default to binary protobuf
+	if err := proto.Unmarshal(body, targetType); err != nil {
+		return zero, err
 	}
 
 	return targetType, nil
 }
+
+// IsJSON reports whether the Content-Type header denotes a protobuf JSON payload, ignoring parameters such as charset.
+func IsJSON(contentType string) bool {
+	return mediaType(contentType) == contentTypeProtoJSON
+}
+
+// IsSupported reports whether the Content-Type header denotes a protobuf binary or protobuf JSON payload.
+func IsSupported(contentType string) bool {
+	switch mediaType(contentType) {
+	case contentTypeProtoJSON, contentTypeProtoBinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// mediaType returns the media type of the Content-Type header without its parameters, or an empty string when the
+// header is missing or malformed.
+func mediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
@@ -13,6 +13,7 @@ import (
 	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -244,3 +245,135 @@ func (e *errorReader) Read(p []byte) (n int, err error) {
 func (e *errorReader) Close() error {
 	return nil
 }
+
+// withUnknownField appends an unknown string field to the message, as sent by a client using a newer OTLP schema.
+func withUnknownField[M proto.Message](msg M, value string) M {
+	raw := protowire.AppendTag(nil, 1000, protowire.BytesType)
+	raw = protowire.AppendString(raw, value)
+	msg.ProtoReflect().SetUnknown(append(msg.ProtoReflect().GetUnknown(), raw...))
+	return msg
+}
+
+func TestUnmarshal_PreservesUnknownFields(t *testing.T) {
+	logsData := withUnknownField(&logpb.LogsData{
+		ResourceLogs: []*logpb.ResourceLogs{
+			withUnknownField(&logpb.ResourceLogs{
+				ScopeLogs: []*logpb.ScopeLogs{
+					withUnknownField(&logpb.ScopeLogs{
+						LogRecords: []*logpb.LogRecord{
+							withUnknownField(&logpb.LogRecord{
+								Body: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "body"}},
+							}, "record"),
+						},
+					}, "scope"),
+				},
+			}, "resource"),
+		},
+	}, "data")
+
+	body, err := proto.Marshal(logsData)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	req := &http.Request{
+		Body:   io.NopCloser(bytes.NewReader(body)),
+		Header: http.Header{"Content-Type": []string{"application/x-protobuf"}},
+	}
+	result, err := Unmarshal(req, &logpb.LogsData{})
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	record := result.GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()[0]
+	if len(record.ProtoReflect().GetUnknown()) == 0 {
+		t.Errorf("unknown fields of the log record were dropped")
+	}
+
+	got, err := Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("Marshal() = %x, want %x", got, body)
+	}
+}
+
+func TestUnmarshal_JSONUnknownFieldsRejected(t *testing.T) {
+	req := &http.Request{
+		Body:   io.NopCloser(bytes.NewReader([]byte(`{"resourceLogs":[{"newerField":"value"}]}`))),
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+	}
+
+	if _, err := Unmarshal(req, &logpb.LogsData{}); err == nil {
+		t.Errorf("Unmarshal() error = nil, want error for unknown JSON field")
+	}
+}
+
+func TestMarshalEncoding(t *testing.T) {
+	payload := &logpb.LogsData{ResourceLogs: []*logpb.ResourceLogs{{SchemaUrl: "https://opentelemetry.io/schemas/1.21.0"}}}
+
+	tests := []struct {
+		name            string
+		encoding        string
+		wantContentType string
+		unmarshal       func([]byte, proto.Message) error
+		wantErr         bool
+	}{
+		{name: "default", encoding: "", wantContentType: "application/x-protobuf", unmarshal: proto.Unmarshal},
+		{name: "protobuf", encoding: "protobuf", wantContentType: "application/x-protobuf", unmarshal: proto.Unmarshal},
+		{name: "json", encoding: "json", wantContentType: "application/json", unmarshal: protojson.Unmarshal},
+		{name: "unsupported", encoding: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := MarshalEncoding(payload, tt.encoding)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MarshalEncoding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := &logpb.LogsData{}
+			if err := tt.unmarshal(body, got); err != nil {
+				t.Fatalf("unmarshal error = %v", err)
+			}
+			if !proto.Equal(got, payload) {
+				t.Errorf("MarshalEncoding() round trip = %v, want %v", got, payload)
+			}
+			if contentType := ContentType(tt.encoding); contentType != tt.wantContentType {
+				t.Errorf("ContentType() = %v, want %v", contentType, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentType   string
+		wantSupported bool
+		wantJSON      bool
+	}{
+		{name: "protobuf", contentType: "application/x-protobuf", wantSupported: true},
+		{name: "json", contentType: "application/json", wantSupported: true, wantJSON: true},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantSupported: true, wantJSON: true},
+		{name: "upper case", contentType: "Application/X-Protobuf", wantSupported: true},
+		{name: "missing", contentType: ""},
+		{name: "other", contentType: "text/plain"},
+		{name: "malformed", contentType: "application/json;;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSupported(tt.contentType); got != tt.wantSupported {
+				t.Errorf("IsSupported(%q) = %v, want %v", tt.contentType, got, tt.wantSupported)
+			}
+			if got := IsJSON(tt.contentType); got != tt.wantJSON {
+				t.Errorf("IsJSON(%q) = %v, want %v", tt.contentType, got, tt.wantJSON)
+			}
+		})
+	}
+}
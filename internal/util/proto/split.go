@@ -0,0 +1,117 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SplitRecords splits OTLP resource messages into chunks of at most limit records each, keeping the order of the
+// records. A resource holding more records than fit in the current chunk is split across chunks, each part keeping
+// the fields of the resource and of the scopes and metrics leading to its records. The parts share those fields and
+// the records with the original resource, so none of them must be modified.
+//
+// The resources are returned as a single chunk when the limit is not positive or they do not exceed it.
+func SplitRecords[T proto.Message](resources []T, limit int) [][]T {
+	if limit <= 0 || len(resources) == 0 {
+		return [][]T{resources}
+	}
+
+	var (
+		chunks [][]T
+		chunk  []T
+		count  int
+	)
+	for _, resource := range resources {
+		records := CountRecords(resource)
+		if count+records <= limit {
+			chunk = append(chunk, resource)
+			count += records
+			continue
+		}
+
+		// Fill the current chunk with the first records of the resource, and the next chunks with the others
+		message := resource.ProtoReflect()
+		path := recordPaths[message.Descriptor().FullName()]
+		for skip := 0; skip < records; {
+			take := min(limit-count, records-skip)
+			if take == 0 {
+				chunks = append(chunks, chunk)
+				chunk, count = nil, 0
+				continue
+			}
+
+			start, n := skip, take
+			chunk = append(chunk, sliceRecords(message, path, &start, &n).Interface().(T))
+			count += take
+			skip += take
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// sliceRecords returns a copy of the message holding the records of the record path following the first skip ones,
+// at most take, decrementing skip and take by the records skipped and taken. The other fields are shared with the
+// message, and the messages of the path holding none of the records are left out.
+func sliceRecords(message protoreflect.Message, path [][]protoreflect.Name, skip, take *int) protoreflect.Message {
+	sliced := message.New()
+	sliced.SetUnknown(message.GetUnknown())
+	onPath := make(map[protoreflect.Name]bool, len(path[0]))
+	for _, name := range path[0] {
+		onPath[name] = true
+	}
+
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if !onPath[field.Name()] || field.Message() == nil {
+			sliced.Set(field, value)
+			return true
+		}
+
+		if !field.IsList() {
+			records := 1
+			if len(path) > 1 {
+				records = countMessageRecords(value.Message(), path[1:])
+			}
+			switch {
+			case *skip >= records:
+				*skip -= records
+			case *take == 0:
+			case len(path) == 1:
+				*take--
+				sliced.Set(field, value)
+			default:
+				sliced.Set(field, protoreflect.ValueOfMessage(sliceRecords(value.Message(), path[1:], skip, take)))
+			}
+			return true
+		}
+
+		list := value.List()
+		out := sliced.NewField(field).List()
+		if len(path) == 1 {
+			start := min(*skip, list.Len())
+			*skip -= start
+			for i := start; i < list.Len() && *take > 0; i++ {
+				out.Append(list.Get(i))
+				*take--
+			}
+		} else {
+			for i := 0; i < list.Len() && *take > 0; i++ {
+				element := list.Get(i).Message()
+				if records := countMessageRecords(element, path[1:]); *skip >= records {
+					*skip -= records
+					continue
+				}
+				out.Append(protoreflect.ValueOfMessage(sliceRecords(element, path[1:], skip, take)))
+			}
+		}
+		if out.Len() > 0 {
+			sliced.Set(field, protoreflect.ValueOfList(out))
+		}
+		return true
+	})
+
+	return sliced
+}
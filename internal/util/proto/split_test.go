@@ -0,0 +1,151 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// testLogs returns a resource with a scope per count, each holding count log records with increasing bodies.
+func testLogs(schemaURL string, counts ...int) *logpb.ResourceLogs {
+	resource := &logpb.ResourceLogs{SchemaUrl: schemaURL}
+	body := 0
+	for _, count := range counts {
+		scope := &logpb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: "scope"}}
+		for range count {
+			scope.LogRecords = append(scope.LogRecords, &logpb.LogRecord{
+				Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(body)}},
+			})
+			body++
+		}
+		resource.ScopeLogs = append(resource.ScopeLogs, scope)
+	}
+	return resource
+}
+
+// bodies returns the bodies of the log records of each resource of each chunk.
+func bodies(chunks [][]*logpb.ResourceLogs) [][][]int64 {
+	var out [][][]int64
+	for _, chunk := range chunks {
+		var resources [][]int64
+		for _, resource := range chunk {
+			var values []int64
+			for _, scope := range resource.GetScopeLogs() {
+				for _, record := range scope.GetLogRecords() {
+					values = append(values, record.GetBody().GetIntValue())
+				}
+			}
+			resources = append(resources, values)
+		}
+		out = append(out, resources)
+	}
+	return out
+}
+
+func TestSplitRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []*logpb.ResourceLogs
+		limit     int
+		want      [][][]int64
+	}{
+		{
+			name:      "disabled",
+			resources: []*logpb.ResourceLogs{testLogs("a", 3)},
+			limit:     0,
+			want:      [][][]int64{{{0, 1, 2}}},
+		},
+		{
+			name:      "within the limit",
+			resources: []*logpb.ResourceLogs{testLogs("a", 2), testLogs("b", 1)},
+			limit:     3,
+			want:      [][][]int64{{{0, 1}, {0}}},
+		},
+		{
+			name:      "whole resources",
+			resources: []*logpb.ResourceLogs{testLogs("a", 2), testLogs("b", 2)},
+			limit:     2,
+			want:      [][][]int64{{{0, 1}}, {{0, 1}}},
+		},
+		{
+			name:      "resource split across chunks",
+			resources: []*logpb.ResourceLogs{testLogs("a", 7)},
+			limit:     3,
+			want:      [][][]int64{{{0, 1, 2}}, {{3, 4, 5}}, {{6}}},
+		},
+		{
+			name:      "resource split across scopes",
+			resources: []*logpb.ResourceLogs{testLogs("a", 1), testLogs("b", 2, 2, 1)},
+			limit:     2,
+			want:      [][][]int64{{{0}, {0}}, {{1, 2}}, {{3, 4}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := make([]*logpb.ResourceLogs, len(tt.resources))
+			for i, resource := range tt.resources {
+				original[i] = proto.Clone(resource).(*logpb.ResourceLogs)
+			}
+
+			chunks := SplitRecords(tt.resources, tt.limit)
+			assert.Equal(t, tt.want, bodies(chunks))
+
+			// Every part keeps the fields of its resource and scopes, and the resources are left untouched
+			for _, chunk := range chunks {
+				for _, resource := range chunk {
+					assert.NotEmpty(t, resource.GetSchemaUrl())
+					for _, scope := range resource.GetScopeLogs() {
+						assert.Equal(t, "scope", scope.GetScope().GetName())
+						assert.NotEmpty(t, scope.GetLogRecords())
+					}
+				}
+			}
+			for i := range original {
+				assert.True(t, proto.Equal(original[i], tt.resources[i]))
+			}
+		})
+	}
+}
+
+func TestSplitRecords_Metrics(t *testing.T) {
+	points := func(n int) []*metricpb.NumberDataPoint {
+		var dps []*metricpb.NumberDataPoint
+		for i := range n {
+			dps = append(dps, &metricpb.NumberDataPoint{Value: &metricpb.NumberDataPoint_AsInt{AsInt: int64(i)}})
+		}
+		return dps
+	}
+	resource := &metricpb.ResourceMetrics{ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{
+		{Name: "gauge", Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: points(3)}}},
+		{Name: "sum", Data: &metricpb.Metric_Sum{Sum: &metricpb.Sum{DataPoints: points(2), IsMonotonic: true}}},
+	}}}}
+	resource.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 1000, protowire.VarintType), 1))
+
+	chunks := SplitRecords([]*metricpb.ResourceMetrics{resource}, 2)
+	require.Len(t, chunks, 3)
+
+	var (
+		names  []string
+		counts []int
+	)
+	for _, chunk := range chunks {
+		require.Len(t, chunk, 1)
+		counts = append(counts, CountRecords(chunk[0]))
+		assert.Equal(t, resource.ProtoReflect().GetUnknown(), chunk[0].ProtoReflect().GetUnknown())
+		for _, m := range chunk[0].GetScopeMetrics()[0].GetMetrics() {
+			names = append(names, m.GetName())
+		}
+	}
+	assert.Equal(t, []int{2, 2, 1}, counts)
+	assert.Equal(t, []string{"gauge", "gauge", "sum", "sum"}, names)
+	assert.True(t, chunks[2][0].GetScopeMetrics()[0].GetMetrics()[0].GetSum().GetIsMonotonic())
+	assert.Equal(t, int64(2), chunks[1][0].GetScopeMetrics()[0].GetMetrics()[0].GetGauge().GetDataPoints()[0].GetAsInt())
+}
@@ -0,0 +1,35 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"io"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// WriteResources writes the protobuf binary encoding of an OTLP export request holding the resources to the writer,
+// marshaling one resource at a time so that the full payload is never held in memory. It returns the number of bytes
+// written.
+func WriteResources[T proto.Message](w io.Writer, resources []T) (int64, error) {
+	var (
+		written int64
+		buf     []byte
+	)
+	for _, resource := range resources {
+		buf = protowire.AppendTag(buf[:0], resourcesFieldNumber, protowire.BytesType)
+		buf = protowire.AppendVarint(buf, uint64(proto.Size(resource)))
+
+		var err error
+		if buf, err = (proto.MarshalOptions{UseCachedSize: true}).MarshalAppend(buf, resource); err != nil {
+			return written, err
+		}
+
+		n, err := w.Write(buf)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
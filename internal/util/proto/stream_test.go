@@ -0,0 +1,58 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	common "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestWriteResources(t *testing.T) {
+	resources := []*logpb.ResourceLogs{
+		{
+			Resource: &resourcepb.Resource{Attributes: []*common.KeyValue{
+				{Key: "tenant.id", Value: &common.AnyValue{Value: &common.AnyValue_StringValue{StringValue: "tenant-a"}}},
+			}},
+			ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{SeverityText: "INFO"}, {SeverityText: "WARN"}}}},
+		},
+		{},
+		{SchemaUrl: "https://opentelemetry.io/schemas/1.21.0"},
+	}
+
+	var buf bytes.Buffer
+	n, err := WriteResources(&buf, resources)
+	if err != nil {
+		t.Fatalf("WriteResources() error = %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteResources() = %d, want %d", n, buf.Len())
+	}
+
+	got := &logpb.LogsData{}
+	if err := proto.Unmarshal(buf.Bytes(), got); err != nil {
+		t.Fatalf("failed to unmarshal written resources: %v", err)
+	}
+	if want := (&logpb.LogsData{ResourceLogs: resources}); !proto.Equal(got, want) {
+		t.Errorf("WriteResources() wrote %v, want %v", got, want)
+	}
+}
+
+func TestWriteResources_WriteError(t *testing.T) {
+	errWrite := errors.New("write failed")
+	if _, err := WriteResources(failingWriter{err: errWrite}, []*logpb.ResourceLogs{{}}); !errors.Is(err, errWrite) {
+		t.Errorf("WriteResources() error = %v, want %v", err, errWrite)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,49 @@
+// Package proto provides utility functions for working with protobuf messages in the context of HTTP requests and responses.
+package proto
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalid is returned by Walk when the message is not valid protobuf.
+var ErrInvalid = errors.New("invalid protobuf")
+
+// Walk calls fn for every field of a message with the bytes of length-delimited fields or the value of varint and
+// fixed fields, for decoders reading the fields of a schema without generated code.
+func Walk(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, length := protowire.ConsumeTag(b)
+		if length < 0 {
+			return ErrInvalid
+		}
+		b = b[length:]
+
+		var v []byte
+		var n uint64
+		switch typ {
+		case protowire.BytesType:
+			v, length = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			n, length = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			n, length = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var n32 uint32
+			n32, length = protowire.ConsumeFixed32(b)
+			n = uint64(n32)
+		default:
+			length = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if length < 0 {
+			return ErrInvalid
+		}
+		b = b[length:]
+
+		if err := fn(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
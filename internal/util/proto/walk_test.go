@@ -0,0 +1,83 @@
+package proto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestWalk(t *testing.T) {
+	type field struct {
+		num protowire.Number
+		typ protowire.Type
+		v   string
+		n   uint64
+	}
+
+	var message []byte
+	message = protowire.AppendTag(message, 1, protowire.BytesType)
+	message = protowire.AppendString(message, "hello")
+	message = protowire.AppendTag(message, 2, protowire.VarintType)
+	message = protowire.AppendVarint(message, 150)
+	message = protowire.AppendTag(message, 3, protowire.Fixed64Type)
+	message = protowire.AppendFixed64(message, 1<<40)
+	message = protowire.AppendTag(message, 4, protowire.Fixed32Type)
+	message = protowire.AppendFixed32(message, 7)
+
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name    string
+		b       []byte
+		stopAt  protowire.Number
+		want    []field
+		wantErr error
+	}{
+		{
+			name: "every field type",
+			b:    message,
+			want: []field{
+				{num: 1, typ: protowire.BytesType, v: "hello"},
+				{num: 2, typ: protowire.VarintType, n: 150},
+				{num: 3, typ: protowire.Fixed64Type, n: 1 << 40},
+				{num: 4, typ: protowire.Fixed32Type, n: 7},
+			},
+		},
+		{name: "empty"},
+		{
+			name:    "error of the callback",
+			b:       message,
+			stopAt:  2,
+			want:    []field{{num: 1, typ: protowire.BytesType, v: "hello"}, {num: 2, typ: protowire.VarintType, n: 150}},
+			wantErr: errStop,
+		},
+		{
+			name:    "truncated value",
+			b:       message[:4],
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "invalid tag",
+			b:       []byte{0x80},
+			wantErr: ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []field
+			err := Walk(tt.b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+				got = append(got, field{num: num, typ: typ, v: string(v), n: n})
+				if num == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -5,6 +5,7 @@
 //   - Adding tenant identification headers (X-Scope-OrgID)
 //   - Setting content-type headers for protobuf payloads
 //   - Parsing and adding custom headers from configuration
+//   - Expanding {tenant} and {signal} variables in outbound URLs
 //
 // The tenant header format is configurable to support different naming
 // conventions and multi-tenant authentication schemes required by
@@ -3,8 +3,8 @@ package request
 
 import (
 	"context"
-	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
@@ -12,19 +12,52 @@ import (
 	"go.opentelemetry.io/otel/propagation"
 )
 
-// AddHeaders adds the headers to the request.
-func AddHeaders(ctx context.Context, tenant string, req *http.Request, config *config.Config, headers string) {
-	req.Header.Set("Content-Type", "application/x-protobuf")
-	req.Header.Add(config.Tenant.Header, fmt.Sprintf(config.Tenant.Format, tenant))
-
-	// Add custom headers
-	customHeaders := strings.SplitSeq(headers, ",")
-	for customHeader := range customHeaders {
-		kv := strings.SplitN(customHeader, "=", 2)
-		if len(kv) == 2 {
-			req.Header.Add(kv[0], kv[1])
+// ParseHeaders parses custom headers of the form key=value separated by commas into a header template, entries
+// without a value are ignored.
+func ParseHeaders(headers string) http.Header {
+	template := make(http.Header)
+	for customHeader := range strings.SplitSeq(headers, ",") {
+		if key, value, ok := strings.Cut(customHeader, "="); ok {
+			template.Add(key, value)
 		}
 	}
+	return template
+}
+
+// AddHeaders replaces the headers of the request with a clone of the custom header template parsed by ParseHeaders,
+// then adds the content type, tenant and trace context headers.
+func AddHeaders(ctx context.Context, tenant string, req *http.Request, config *config.Config, headers http.Header) {
+	req.Header = headers.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Add(config.Tenant.Header, config.Tenant.FormatTenant(tenant))
 
 	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 }
+
+// ExpandURL replaces the {tenant} and {signal} variables of the address with the formatted tenant and the signal type.
+func ExpandURL(address, tenant, signal string, config *config.Config) string {
+	if !strings.Contains(address, "{") {
+		return address
+	}
+
+	return strings.NewReplacer(
+		"{tenant}", url.PathEscape(config.Tenant.FormatTenant(tenant)),
+		"{signal}", url.PathEscape(signal),
+	).Replace(address)
+}
+
+// HeaderSize returns the size of the headers as sent over HTTP/1.1, each value being a "Key: value" line ended by
+// CRLF, and the number of those lines.
+func HeaderSize(header http.Header) (size, count int) {
+	for key, values := range header {
+		for _, value := range values {
+			size += len(key) + len(": ") + len(value) + len("\r\n")
+			count++
+		}
+	}
+	return size, count
+}
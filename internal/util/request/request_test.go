@@ -3,6 +3,7 @@ package request
 
 import (
 	"context"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -100,7 +101,7 @@ func TestAddHeaders(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("POST", "/test", nil)
-			AddHeaders(context.Background(), tt.tenant, req, tt.config, tt.headers)
+			AddHeaders(context.Background(), tt.tenant, req, tt.config, ParseHeaders(tt.headers))
 
 			for key, expectedValue := range tt.want {
 				actualValue := req.Header.Get(key)
@@ -116,3 +117,139 @@ func TestAddHeaders(t *testing.T) {
 		})
 	}
 }
+
+func TestAddHeaders_TemplateUnchanged(t *testing.T) {
+	cfg := &config.Config{Tenant: config.Tenant{Header: "X-Scope-OrgID", Format: "%s"}}
+	template := ParseHeaders("Authorization=Bearer token123,X-Custom-Header=a=b")
+
+	for _, tenant := range []string{"tenant1", "tenant2"} {
+		req := httptest.NewRequest("POST", "/test", nil)
+		AddHeaders(context.Background(), tenant, req, cfg, template)
+
+		if got := req.Header.Values("X-Scope-OrgID"); len(got) != 1 || got[0] != tenant {
+			t.Errorf("AddHeaders() X-Scope-OrgID = %v, want [%s]", got, tenant)
+		}
+		if got := req.Header.Get("X-Custom-Header"); got != "a=b" {
+			t.Errorf("AddHeaders() X-Custom-Header = %v, want a=b", got)
+		}
+	}
+
+	if len(template) != 2 {
+		t.Errorf("AddHeaders() modified the template: %v", template)
+	}
+}
+
+func BenchmarkAddHeaders(b *testing.B) {
+	cfg := &config.Config{Tenant: config.Tenant{Header: "X-Scope-OrgID", Format: "%s"}}
+	headers := "Authorization=Bearer token123,X-Custom-Header=CustomValue,X-Another-Header=AnotherValue"
+
+	b.Run("template", func(b *testing.B) {
+		template := ParseHeaders(headers)
+		req := httptest.NewRequest("POST", "/test", nil)
+		b.ReportAllocs()
+		for b.Loop() {
+			AddHeaders(context.Background(), "tenant1", req, cfg, template)
+		}
+	})
+
+	b.Run("parse per request", func(b *testing.B) {
+		req := httptest.NewRequest("POST", "/test", nil)
+		b.ReportAllocs()
+		for b.Loop() {
+			AddHeaders(context.Background(), "tenant1", req, cfg, ParseHeaders(headers))
+		}
+	})
+}
+
+func TestExpandURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		tenant  string
+		signal  string
+		format  string
+		want    string
+	}{
+		{
+			name:    "address without variables",
+			address: "http://loki:3100/otlp/v1/logs",
+			tenant:  "tenant1",
+			signal:  "logs",
+			format:  "%s",
+			want:    "http://loki:3100/otlp/v1/logs",
+		},
+		{
+			name:    "tenant in path",
+			address: "http://backend:8080/api/v1/push/{tenant}",
+			tenant:  "tenant1",
+			signal:  "metrics",
+			format:  "%s",
+			want:    "http://backend:8080/api/v1/push/tenant1",
+		},
+		{
+			name:    "static tenant format",
+			address: "http://backend:8080/api/v1/push/{tenant}",
+			tenant:  "tenant1",
+			signal:  "metrics",
+			format:  "shared",
+			want:    "http://backend:8080/api/v1/push/shared",
+		},
+		{
+			name:    "tenant and signal with tenant format",
+			address: "http://backend:8080/{signal}/{tenant}/v1/{signal}",
+			tenant:  "tenant1",
+			signal:  "traces",
+			format:  "org-%s",
+			want:    "http://backend:8080/traces/org-tenant1/v1/traces",
+		},
+		{
+			name:    "tenant is path escaped",
+			address: "http://backend:8080/push/{tenant}",
+			tenant:  "team a/b",
+			signal:  "logs",
+			format:  "%s",
+			want:    "http://backend:8080/push/team%20a%2Fb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Tenant: config.Tenant{Format: tt.format}}
+			if got := ExpandURL(tt.address, tt.tenant, tt.signal, cfg); got != tt.want {
+				t.Errorf("ExpandURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaderSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    http.Header
+		wantSize  int
+		wantCount int
+	}{
+		{name: "empty"},
+		{
+			name:      "single",
+			header:    http.Header{"X-Scope-Orgid": {"tenant1"}},
+			wantSize:  len("X-Scope-Orgid: tenant1\r\n"),
+			wantCount: 1,
+		},
+		{
+			name:      "repeated values are separate lines",
+			header:    http.Header{"X-Custom": {"a", "bc"}},
+			wantSize:  len("X-Custom: a\r\nX-Custom: bc\r\n"),
+			wantCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, count := HeaderSize(tt.header)
+			if size != tt.wantSize || count != tt.wantCount {
+				t.Errorf("HeaderSize() = %d, %d, want %d, %d", size, count, tt.wantSize, tt.wantCount)
+			}
+		})
+	}
+}
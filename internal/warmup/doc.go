@@ -0,0 +1,7 @@
+// Package warmup pre-establishes the connections to the backends at startup.
+//
+// Each backend is sent a HEAD request to the root of its address before the proxy starts serving, so that DNS lookups,
+// TCP connections and TLS handshakes are paid before the first telemetry arrives and unreachable backends are reported
+// straight after a deploy. The response status is ignored, the connection is returned to the idle pool of the backend
+// client for reuse by the first requests.
+package warmup
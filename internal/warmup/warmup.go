@@ -0,0 +1,104 @@
+// Package warmup pre-establishes the connections to the backends at startup.
+package warmup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matt-gp/core/logger"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	signalTypeAttrKey = "signal.type"
+	addressAttrKey    = "server.address"
+	durationAttrKey   = "duration_ms"
+	errAttrKey        = "error"
+)
+
+// Backend is a backend whose connection is warmed up.
+type Backend struct {
+	Signal  string
+	Address string
+	Client  *http.Client
+}
+
+// Run warms up the connections to the backends concurrently, giving up after the timeout. Backends without an address
+// are skipped. It returns the errors of the backends that could not be reached.
+func Run(ctx context.Context, timeout time.Duration, backends []Backend) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for _, backend := range backends {
+		if backend.Address == "" {
+			continue
+		}
+
+		wg.Go(func() {
+			if err := warm(ctx, backend); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s backend: %w", backend.Signal, err))
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// warm establishes a connection to the backend by sending a HEAD request to the root of its address.
+func warm(ctx context.Context, backend Backend) error {
+	root, err := origin(backend.Address)
+	if err != nil {
+		return err
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String(signalTypeAttrKey, backend.Signal),
+		attribute.String(addressAttrKey, root),
+	}
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, root, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := backend.Client.Do(req)
+	if err != nil {
+		logger.Warn(ctx, "failed to warm up backend connection", append(attrs, attribute.String(errAttrKey, err.Error()))...)
+		return err
+	}
+
+	// Drain the response so the connection is returned to the idle pool
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	logger.Info(ctx, "warmed up backend connection", append(attrs, attribute.Int64(durationAttrKey, time.Since(start).Milliseconds()))...)
+	return nil
+}
+
+// origin returns the scheme and host of the address, the path is dropped as it may contain variables.
+func origin(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" || strings.ContainsAny(u.Host, "{}") {
+		return "", fmt.Errorf("cannot warm up address %q without a static scheme and host", address)
+	}
+	return u.Scheme + "://" + u.Host + "/", nil
+}
@@ -0,0 +1,79 @@
+package warmup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	var methods []string
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer backend.Close()
+
+	client := backend.Client()
+	err := Run(context.Background(), time.Second, []Backend{
+		{Signal: "logs", Address: backend.URL + "/otlp/v1/logs/{tenant}", Client: client},
+		{Signal: "traces", Client: client},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HEAD /"}, methods)
+
+	// The first request reuses the warmed up connection
+	var reused bool
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, backend.URL+"/otlp/v1/logs", http.NoBody)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.True(t, reused, "the warmed up connection should be reused")
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	address := backend.URL
+	backend.Close()
+
+	err := Run(context.Background(), time.Second, []Backend{
+		{Signal: "metrics", Address: address, Client: &http.Client{}},
+	})
+	assert.ErrorContains(t, err, "metrics backend")
+}
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+		wantErr bool
+	}{
+		{name: "root", address: "https://loki:3100", want: "https://loki:3100/"},
+		{name: "path with variables", address: "http://mimir:9009/api/v1/push/{tenant}", want: "http://mimir:9009/"},
+		{name: "no scheme", address: "loki:3100", wantErr: true},
+		{name: "host with variables", address: "http://{tenant}.loki:3100", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := origin(tt.address)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+// Package watchdog watches the resources held by the proxy to catch leaks in long-running deployments.
+//
+// The Watchdog periodically samples:
+//   - The number of goroutines, catching dispatch goroutines that never return
+//   - The number of open file descriptors, catching connections and files never closed
+//   - The number of live heap objects, catching data retained after it was forwarded
+//
+// The samples are exported as gauges. A warning is logged and counted when a sample
+// rises above its configured threshold, and an informational message is logged when
+// it falls back below it. File descriptors are only counted where /proc is available.
+package watchdog
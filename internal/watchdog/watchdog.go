@@ -0,0 +1,209 @@
+// Package watchdog watches the resources held by the proxy to catch leaks in long-running deployments.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/matt-gp/core/logger"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	resourceAttrKey  = "resource"
+	valueAttrKey     = "value"
+	thresholdAttrKey = "threshold"
+)
+
+// Watched resources.
+const (
+	ResourceGoroutines      = "goroutines"
+	ResourceFileDescriptors = "file_descriptors"
+	ResourceHeapObjects     = "heap_objects"
+)
+
+// Sample is a sample of the resources held by the process, a negative value is not available.
+type Sample struct {
+	Goroutines      int64
+	FileDescriptors int64
+	HeapObjects     int64
+}
+
+// Read samples the resources held by the process.
+func Read() Sample {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return Sample{
+		Goroutines:      int64(runtime.NumGoroutine()),
+		FileDescriptors: openFileDescriptors(),
+		HeapObjects:     int64(mem.HeapObjects),
+	}
+}
+
+// openFileDescriptors returns the number of file descriptors open by the process, or -1 without /proc.
+func openFileDescriptors() int64 {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	// Do not count the descriptor used to read the directory
+	return int64(len(entries)) - 1
+}
+
+// Option configures optional dependencies of a Watchdog.
+type Option func(*Watchdog)
+
+// WithClock sets the clock driving the sampling interval.
+func WithClock(c clock.Clock) Option {
+	return func(w *Watchdog) {
+		w.clock = c
+	}
+}
+
+// Watchdog periodically samples the resources held by the process and warns when they exceed their thresholds.
+type Watchdog struct {
+	config *config.Watchdog
+	clock  clock.Clock
+	read   func() Sample
+
+	warningsMetric metric.Int64Counter
+
+	mu       sync.Mutex
+	sample   Sample
+	exceeded map[string]bool
+}
+
+// New creates a new Watchdog exporting its samples through the meter.
+func New(config *config.Watchdog, meter metric.Meter, opts ...Option) (*Watchdog, error) {
+	w := &Watchdog{
+		config:   config,
+		clock:    clock.New(),
+		read:     Read,
+		exceeded: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.sample = w.read()
+
+	goroutines, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_goroutines",
+		metric.WithDescription("Number of goroutines of the proxy at the last watchdog check"),
+		metric.WithUnit("{goroutines}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy goroutines gauge: %w", err)
+	}
+
+	fileDescriptors, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_open_file_descriptors",
+		metric.WithDescription("Number of file descriptors open by the proxy at the last watchdog check"),
+		metric.WithUnit("{file_descriptors}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy open file descriptors gauge: %w", err)
+	}
+
+	heapObjects, err := meter.Int64ObservableGauge(
+		"otel_lgtm_proxy_heap_objects",
+		metric.WithDescription("Number of live heap objects of the proxy at the last watchdog check"),
+		metric.WithUnit("{objects}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy heap objects gauge: %w", err)
+	}
+
+	if _, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		sample := w.Sample()
+		o.ObserveInt64(goroutines, sample.Goroutines)
+		if sample.FileDescriptors >= 0 {
+			o.ObserveInt64(fileDescriptors, sample.FileDescriptors)
+		}
+		o.ObserveInt64(heapObjects, sample.HeapObjects)
+		return nil
+	}, goroutines, fileDescriptors, heapObjects); err != nil {
+		return nil, fmt.Errorf("failed to register watchdog callback: %w", err)
+	}
+
+	w.warningsMetric, err = meter.Int64Counter(
+		"otel_lgtm_proxy_watchdog_warnings_total",
+		metric.WithDescription("Total number of times a watched resource rose above its threshold"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel lgtm proxy watchdog warnings counter: %w", err)
+	}
+
+	return w, nil
+}
+
+// Sample returns the last sample taken.
+func (w *Watchdog) Sample() Sample {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.sample
+}
+
+// Run samples the resources every interval until the context is cancelled, it returns immediately when the interval
+// is not positive.
+func (w *Watchdog) Run(ctx context.Context) {
+	if w.config.Interval <= 0 {
+		return
+	}
+
+	ticker := w.clock.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			w.Check(ctx)
+		}
+	}
+}
+
+// Check takes a sample and warns about the resources that rose above their threshold since the last check.
+func (w *Watchdog) Check(ctx context.Context) {
+	sample := w.read()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.sample = sample
+	w.check(ctx, ResourceGoroutines, sample.Goroutines, w.config.GoroutineThreshold)
+	w.check(ctx, ResourceFileDescriptors, sample.FileDescriptors, w.config.FileDescriptorThreshold)
+	w.check(ctx, ResourceHeapObjects, sample.HeapObjects, w.config.HeapObjectThreshold)
+}
+
+// check warns when the value of the resource rises above its threshold, a threshold of zero disables the check. The
+// caller must hold the lock.
+func (w *Watchdog) check(ctx context.Context, resource string, value, threshold int64) {
+	if threshold <= 0 || value < 0 {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String(resourceAttrKey, resource),
+		attribute.Int64(valueAttrKey, value),
+		attribute.Int64(thresholdAttrKey, threshold),
+	}
+
+	exceeded := value > threshold
+	switch {
+	case exceeded && !w.exceeded[resource]:
+		logger.Warn(ctx, "watched resource above threshold, possible leak", attrs...)
+		w.warningsMetric.Add(ctx, 1, metric.WithAttributes(attribute.String(resourceAttrKey, resource)))
+	case !exceeded && w.exceeded[resource]:
+		logger.Info(ctx, "watched resource back below threshold", attrs...)
+	}
+	w.exceeded[resource] = exceeded
+}
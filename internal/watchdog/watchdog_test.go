@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matt-gp/otel-lgtm-proxy/internal/clock"
+	"github.com/matt-gp/otel-lgtm-proxy/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	noopmetric "go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRead(t *testing.T) {
+	sample := Read()
+
+	assert.Positive(t, sample.Goroutines)
+	assert.Positive(t, sample.HeapObjects)
+	assert.NotZero(t, sample.FileDescriptors)
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	w, err := New(&config.Watchdog{GoroutineThreshold: 100, HeapObjectThreshold: 1000}, meter)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, goroutines := range []int64{50, 150, 200, 80, 120} {
+		w.read = func() Sample { return Sample{Goroutines: goroutines, FileDescriptors: -1, HeapObjects: 10} }
+		w.Check(ctx)
+	}
+	assert.Equal(t, Sample{Goroutines: 120, FileDescriptors: -1, HeapObjects: 10}, w.Sample())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					got[m.Name] = dp.Value
+				}
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					resource, _ := dp.Attributes.Value(attribute.Key(resourceAttrKey))
+					got[m.Name+"/"+resource.AsString()] = dp.Value
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int64{
+		"otel_lgtm_proxy_goroutines":                         120,
+		"otel_lgtm_proxy_heap_objects":                       10,
+		"otel_lgtm_proxy_watchdog_warnings_total/goroutines": 2,
+	}, got)
+}
+
+func TestWatchdog_Run(t *testing.T) {
+	fake := clock.NewFake(time.Now())
+	w, err := New(&config.Watchdog{Interval: 30 * time.Second}, noopmetric.NewMeterProvider().Meter("test"), WithClock(fake))
+	require.NoError(t, err)
+
+	checked := make(chan struct{}, 1)
+	w.read = func() Sample {
+		checked <- struct{}{}
+		return Sample{Goroutines: 7}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+
+	fake.BlockUntilTickers(1)
+	fake.Advance(30 * time.Second)
+	<-checked
+
+	cancel()
+	<-done
+	assert.Equal(t, int64(7), w.Sample().Goroutines)
+}
@@ -0,0 +1,14 @@
+// Package zipkin converts Zipkin v2 JSON spans into OTLP traces.
+//
+// Spans posted to /api/v2/spans are converted like the Zipkin receiver of the
+// OpenTelemetry Collector:
+//   - The service name of the local endpoint becomes the service.name resource attribute
+//   - The remote endpoint becomes the peer.service, network.peer.address and network.peer.port attributes
+//   - Tags become span attributes, the error tag sets the status of the span to error
+//   - Annotations become span events
+//   - Timestamps and durations in microseconds become start and end times
+//
+// The tenant of a span is read from a configured tag and written to the tenant
+// resource attribute, so the converted resources are partitioned like OTLP
+// payloads.
+package zipkin
@@ -0,0 +1,257 @@
+// Package zipkin converts Zipkin v2 JSON spans into OTLP traces.
+package zipkin
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+var (
+	serviceNameAttrKey = "service.name"
+	peerServiceAttrKey = "peer.service"
+	peerAddressAttrKey = "network.peer.address"
+	peerPortAttrKey    = "network.peer.port"
+)
+
+// Tags mapped to the status of a span.
+const (
+	errorTag             = "error"
+	statusCodeTag        = "otel.status_code"
+	statusDescriptionTag = "otel.status_description"
+)
+
+// scopeName is the instrumentation scope of the converted spans.
+const scopeName = "github.com/matt-gp/otel-lgtm-proxy/internal/zipkin"
+
+// kinds maps Zipkin span kinds to OTLP span kinds.
+var kinds = map[string]tracepb.Span_SpanKind{
+	"CLIENT":   tracepb.Span_SPAN_KIND_CLIENT,
+	"SERVER":   tracepb.Span_SPAN_KIND_SERVER,
+	"PRODUCER": tracepb.Span_SPAN_KIND_PRODUCER,
+	"CONSUMER": tracepb.Span_SPAN_KIND_CONSUMER,
+}
+
+// Span is a Zipkin v2 span.
+type Span struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId"`
+	Name           string            `json:"name"`
+	Kind           string            `json:"kind"`
+	Timestamp      uint64            `json:"timestamp"`
+	Duration       uint64            `json:"duration"`
+	LocalEndpoint  *Endpoint         `json:"localEndpoint"`
+	RemoteEndpoint *Endpoint         `json:"remoteEndpoint"`
+	Annotations    []Annotation      `json:"annotations"`
+	Tags           map[string]string `json:"tags"`
+}
+
+// Endpoint is the network context of a Zipkin span.
+type Endpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+// Annotation is a timestamped event of a Zipkin span.
+type Annotation struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// Parse parses the JSON body of a /api/v2/spans request.
+func Parse(b []byte) ([]Span, error) {
+	var spans []Span
+	if err := json.Unmarshal(b, &spans); err != nil {
+		return nil, fmt.Errorf("invalid zipkin spans: %w", err)
+	}
+	return spans, nil
+}
+
+// Converter converts Zipkin spans into OTLP resources grouped by tenant.
+type Converter struct {
+	tenantLabel string
+	tenantTag   string
+}
+
+// New creates a new Converter writing the tenant to the tenantLabel resource attribute, the tenant of a span is read
+// from its tenantTag tag when set.
+func New(tenantLabel, tenantTag string) *Converter {
+	return &Converter{tenantLabel: tenantLabel, tenantTag: tenantTag}
+}
+
+// ResourceSpans converts the spans into OTLP traces, grouping them into one resource per tenant and local service. It
+// returns an error when a trace or span ID is not valid hex.
+func (c *Converter) ResourceSpans(spans []Span) ([]*tracepb.ResourceSpans, error) {
+	var resources []*tracepb.ResourceSpans
+	scopes := make(map[[2]string]*tracepb.ScopeSpans)
+
+	for _, span := range spans {
+		converted, err := convertSpan(span)
+		if err != nil {
+			return nil, err
+		}
+
+		var tenant string
+		if c.tenantTag != "" {
+			tenant = span.Tags[c.tenantTag]
+		}
+		service := span.LocalEndpoint.serviceName()
+
+		key := [2]string{tenant, service}
+		scope, ok := scopes[key]
+		if !ok {
+			var attributes []*commonpb.KeyValue
+			if tenant != "" {
+				attributes = append(attributes, stringKeyValue(c.tenantLabel, tenant))
+			}
+			if service != "" {
+				attributes = append(attributes, stringKeyValue(serviceNameAttrKey, service))
+			}
+
+			scope = &tracepb.ScopeSpans{Scope: &commonpb.InstrumentationScope{Name: scopeName}}
+			resources = append(resources, &tracepb.ResourceSpans{
+				Resource:   &resourcepb.Resource{Attributes: attributes},
+				ScopeSpans: []*tracepb.ScopeSpans{scope},
+			})
+			scopes[key] = scope
+		}
+
+		scope.Spans = append(scope.Spans, converted)
+	}
+
+	return resources, nil
+}
+
+// convertSpan converts a Zipkin span into an OTLP span.
+func convertSpan(span Span) (*tracepb.Span, error) {
+	traceID, err := decodeID(span.TraceID, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace id %q: %w", span.TraceID, err)
+	}
+	spanID, err := decodeID(span.ID, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid span id %q: %w", span.ID, err)
+	}
+	var parentSpanID []byte
+	if span.ParentID != "" {
+		if parentSpanID, err = decodeID(span.ParentID, 8); err != nil {
+			return nil, fmt.Errorf("invalid parent id %q: %w", span.ParentID, err)
+		}
+	}
+
+	converted := &tracepb.Span{
+		TraceId:           traceID,
+		SpanId:            spanID,
+		ParentSpanId:      parentSpanID,
+		Name:              span.Name,
+		Kind:              kind(span.Kind),
+		StartTimeUnixNano: span.Timestamp * 1000,
+		EndTimeUnixNano:   (span.Timestamp + span.Duration) * 1000,
+		Status:            status(span.Tags),
+	}
+
+	if remote := span.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			converted.Attributes = append(converted.Attributes, stringKeyValue(peerServiceAttrKey, remote.ServiceName))
+		}
+		if address := remote.address(); address != "" {
+			converted.Attributes = append(converted.Attributes, stringKeyValue(peerAddressAttrKey, address))
+		}
+		if remote.Port != 0 {
+			converted.Attributes = append(converted.Attributes, intKeyValue(peerPortAttrKey, int64(remote.Port)))
+		}
+	}
+
+	keys := make([]string, 0, len(span.Tags))
+	for key := range span.Tags {
+		switch key {
+		case errorTag, statusCodeTag, statusDescriptionTag:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		converted.Attributes = append(converted.Attributes, stringKeyValue(key, span.Tags[key]))
+	}
+
+	for _, annotation := range span.Annotations {
+		converted.Events = append(converted.Events, &tracepb.Span_Event{
+			TimeUnixNano: annotation.Timestamp * 1000,
+			Name:         annotation.Value,
+		})
+	}
+
+	return converted, nil
+}
+
+// kind returns the OTLP kind of a Zipkin span kind, spans without a kind are internal.
+func kind(k string) tracepb.Span_SpanKind {
+	if converted, ok := kinds[strings.ToUpper(k)]; ok {
+		return converted
+	}
+	return tracepb.Span_SPAN_KIND_INTERNAL
+}
+
+// status returns the status of a span from its tags: the OpenTelemetry status tags when set, an error status when the
+// error tag is set, unset otherwise. The error tag holds the description of the error.
+func status(tags map[string]string) *tracepb.Status {
+	switch strings.ToUpper(tags[statusCodeTag]) {
+	case "ERROR":
+		return &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: tags[statusDescriptionTag]}
+	case "OK":
+		return &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK}
+	}
+
+	description, ok := tags[errorTag]
+	if !ok || description == "false" {
+		return nil
+	}
+	if description == "true" {
+		description = ""
+	}
+	return &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: description}
+}
+
+// serviceName returns the service name of the endpoint, or an empty string without an endpoint.
+func (e *Endpoint) serviceName() string {
+	if e == nil {
+		return ""
+	}
+	return e.ServiceName
+}
+
+// address returns the IPv4 address of the endpoint, falling back to its IPv6 address.
+func (e *Endpoint) address() string {
+	if e.IPv4 != "" {
+		return e.IPv4
+	}
+	return e.IPv6
+}
+
+// decodeID decodes a hex ID of up to size bytes, left padding shorter IDs such as 64-bit trace IDs with zeros.
+func decodeID(id string, size int) ([]byte, error) {
+	if id == "" || len(id) > 2*size {
+		return nil, fmt.Errorf("expected up to %d hex characters", 2*size)
+	}
+	return hex.DecodeString(strings.Repeat("0", 2*size-len(id)) + id)
+}
+
+// stringKeyValue returns a string attribute.
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+// intKeyValue returns an integer attribute.
+func intKeyValue(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
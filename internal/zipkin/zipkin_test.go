@@ -0,0 +1,160 @@
+package zipkin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []Span
+		wantErr bool
+	}{
+		{
+			name: "spans",
+			body: `[{"traceId":"0af7651916cd43dd8448eb211c80319c","id":"b7ad6b7169203331","name":"get",
+				"timestamp":1700000000000000,"duration":1500,"localEndpoint":{"serviceName":"frontend"},
+				"tags":{"http.method":"GET"}}]`,
+			want: []Span{{
+				TraceID:       "0af7651916cd43dd8448eb211c80319c",
+				ID:            "b7ad6b7169203331",
+				Name:          "get",
+				Timestamp:     1700000000000000,
+				Duration:      1500,
+				LocalEndpoint: &Endpoint{ServiceName: "frontend"},
+				Tags:          map[string]string{"http.method": "GET"},
+			}},
+		},
+		{
+			name:    "not an array",
+			body:    `{"traceId":"0af7651916cd43dd8448eb211c80319c"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.body))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConverter_ResourceSpans(t *testing.T) {
+	spans, err := Parse([]byte(`[
+		{"traceId":"0af7651916cd43dd8448eb211c80319c","id":"b7ad6b7169203331","parentId":"00f067aa0ba902b7",
+		 "name":"get /cart","kind":"SERVER","timestamp":1700000000000000,"duration":1500,
+		 "localEndpoint":{"serviceName":"frontend","ipv4":"10.0.0.1"},
+		 "remoteEndpoint":{"serviceName":"browser","ipv6":"fd00::1","port":51000},
+		 "annotations":[{"timestamp":1700000000000500,"value":"ws"}],
+		 "tags":{"tenant":"tenant-a","http.method":"GET","error":"timeout"}},
+		{"traceId":"8448eb211c80319c","id":"00f067aa0ba902b7","name":"select","kind":"client",
+		 "timestamp":1700000000000100,"duration":200,"localEndpoint":{"serviceName":"frontend"},
+		 "tags":{"tenant":"tenant-a","otel.status_code":"OK"}},
+		{"traceId":"8448eb211c80319c","id":"00f067aa0ba902b8","name":"work","timestamp":1700000000000000,
+		 "localEndpoint":{"serviceName":"worker"}}
+	]`))
+	require.NoError(t, err)
+
+	got, err := New("tenant.id", "tenant").ResourceSpans(spans)
+	require.NoError(t, err)
+
+	want := []*tracepb.ResourceSpans{
+		{
+			Resource: resource(stringKeyValue("tenant.id", "tenant-a"), stringKeyValue("service.name", "frontend")),
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: scopeName},
+				Spans: []*tracepb.Span{
+					{
+						TraceId: []byte{
+							0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c,
+						},
+						SpanId:            []byte{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31},
+						ParentSpanId:      []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+						Name:              "get /cart",
+						Kind:              tracepb.Span_SPAN_KIND_SERVER,
+						StartTimeUnixNano: 1700000000000000000,
+						EndTimeUnixNano:   1700000000001500000,
+						Attributes: []*commonpb.KeyValue{
+							stringKeyValue("peer.service", "browser"),
+							stringKeyValue("network.peer.address", "fd00::1"),
+							intKeyValue("network.peer.port", 51000),
+							stringKeyValue("http.method", "GET"),
+							stringKeyValue("tenant", "tenant-a"),
+						},
+						Events: []*tracepb.Span_Event{{TimeUnixNano: 1700000000000500000, Name: "ws"}},
+						Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timeout"},
+					},
+					{
+						TraceId: []byte{
+							0, 0, 0, 0, 0, 0, 0, 0, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c,
+						},
+						SpanId:            []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+						Name:              "select",
+						Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+						StartTimeUnixNano: 1700000000000100000,
+						EndTimeUnixNano:   1700000000000300000,
+						Attributes:        []*commonpb.KeyValue{stringKeyValue("tenant", "tenant-a")},
+						Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+					},
+				},
+			}},
+		},
+		{
+			Resource: resource(stringKeyValue("service.name", "worker")),
+			ScopeSpans: []*tracepb.ScopeSpans{{
+				Scope: &commonpb.InstrumentationScope{Name: scopeName},
+				Spans: []*tracepb.Span{{
+					TraceId:           []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c},
+					SpanId:            []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb8},
+					Name:              "work",
+					Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+					StartTimeUnixNano: 1700000000000000000,
+					EndTimeUnixNano:   1700000000000000000,
+				}},
+			}},
+		},
+	}
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, proto.Equal(want[i], got[i]), "resource %d:\nwant %v\ngot  %v", i, want[i], got[i])
+	}
+}
+
+func TestConverter_ResourceSpans_InvalidID(t *testing.T) {
+	tests := []struct {
+		name string
+		span Span
+	}{
+		{name: "missing trace id", span: Span{ID: "b7ad6b7169203331"}},
+		{name: "trace id too long", span: Span{TraceID: "0af7651916cd43dd8448eb211c80319c00", ID: "b7ad6b7169203331"}},
+		{name: "invalid span id", span: Span{TraceID: "8448eb211c80319c", ID: "zz"}},
+		{name: "invalid parent id", span: Span{TraceID: "8448eb211c80319c", ID: "b7ad6b7169203331", ParentID: "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("tenant.id", "").ResourceSpans([]Span{tt.span})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func resource(attributes ...*commonpb.KeyValue) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: attributes}
+}
@@ -0,0 +1,13 @@
+// Package e2e verifies tenant isolation end to end, against real Loki, Mimir and Tempo backends.
+//
+// The tests are built with the e2e build tag and need Docker with the compose
+// plugin:
+//
+//	go test -tags e2e -v ./test/e2e/
+//
+// They start the stack of docker-compose.yml, building the proxy from the
+// working tree, push OTLP logs, metrics and traces mixing two tenants in each
+// request, then query every backend as each tenant and assert that a tenant
+// only sees its own data. The stack is removed when the tests end, unless
+// E2E_KEEP is set to inspect it.
+package e2e
@@ -0,0 +1,248 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var tenants = []string{"tenant-a", "tenant-b"}
+
+// endpoints holds the host:port published for each service of the stack.
+var endpoints = make(map[string]string)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run starts the stack, runs the tests and removes the stack.
+func run(m *testing.M) int {
+	project := fmt.Sprintf("otel-lgtm-proxy-e2e-%d", os.Getpid())
+	compose := func(args ...string) (string, error) {
+		cmd := exec.Command("docker", append([]string{"compose", "--project-name", project, "--file",
+			"docker-compose.yml"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+
+	if os.Getenv("E2E_KEEP") == "" {
+		defer func() {
+			if out, err := compose("down", "--volumes"); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to remove the stack: %v\n%s", err, out)
+			}
+		}()
+	}
+
+	if out, err := compose("up", "--build", "--detach", "--wait"); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the stack: %v\n%s", err, out)
+		logs, _ := compose("logs")
+		fmt.Fprint(os.Stderr, logs)
+		return 1
+	}
+
+	for service, port := range map[string]string{"otel-lgtm-proxy": "8443", "loki": "3100", "mimir": "8080", "tempo": "3200"} {
+		out, err := compose("port", service, port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to find the port of %s: %v\n%s", service, err, out)
+			return 1
+		}
+		_, published, _ := strings.Cut(strings.TrimSpace(out), ":")
+		endpoints[service] = "127.0.0.1:" + published
+	}
+
+	return m.Run()
+}
+
+// runID returns an identifier of the test run, so data left by previous runs is not matched.
+func runID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+func resource(tenant, service string) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "tenant.id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant}}},
+		{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: service}}},
+	}}
+}
+
+// push posts the request to the proxy as OTLP protobuf.
+func push(t *testing.T, path string, request proto.Message) {
+	t.Helper()
+
+	body, err := proto.Marshal(request)
+	require.NoError(t, err)
+	resp, err := http.Post("http://"+endpoints["otel-lgtm-proxy"]+path, "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Less(t, resp.StatusCode, 300, "unexpected status pushing to %s", path)
+}
+
+// query queries a backend as the tenant, decoding the JSON response into v when the query succeeds.
+func query(c assert.TestingT, service, path, tenant string, v any) int {
+	req, err := http.NewRequest(http.MethodGet, "http://"+endpoints[service]+path, nil)
+	if !assert.NoError(c, err) {
+		return 0
+	}
+	req.Header.Set("X-Scope-OrgID", tenant)
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(c, err) {
+		return 0
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusOK && v != nil {
+		assert.NoError(c, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestTenantIsolation_Logs(t *testing.T) {
+	id := runID()
+	service := "e2e-" + id
+
+	// A single request mixing the logs of both tenants
+	request := &collogspb.ExportLogsServiceRequest{}
+	for _, tenant := range tenants {
+		request.ResourceLogs = append(request.ResourceLogs, &logpb.ResourceLogs{
+			Resource: resource(tenant, service),
+			ScopeLogs: []*logpb.ScopeLogs{{LogRecords: []*logpb.LogRecord{{
+				TimeUnixNano: uint64(time.Now().UnixNano()),
+				Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: tenant + " " + id}},
+			}}}},
+		})
+	}
+	push(t, "/v1/logs", request)
+
+	params := url.Values{
+		"query": {fmt.Sprintf(`{service_name=%q}`, service)},
+		"start": {strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 10)},
+		"end":   {strconv.FormatInt(time.Now().Add(time.Hour).UnixNano(), 10)},
+	}
+	for _, tenant := range tenants {
+		require.EventuallyWithT(t, func(c *assert.CollectT) {
+			var response struct {
+				Data struct {
+					Result []struct {
+						Values [][2]string `json:"values"`
+					} `json:"result"`
+				} `json:"data"`
+			}
+			if query(c, "loki", "/loki/api/v1/query_range?"+params.Encode(), tenant, &response) != http.StatusOK {
+				c.Errorf("loki query as %s failed", tenant)
+				return
+			}
+
+			var lines []string
+			for _, stream := range response.Data.Result {
+				for _, value := range stream.Values {
+					lines = append(lines, value[1])
+				}
+			}
+			assert.Equal(c, []string{tenant + " " + id}, lines)
+		}, 2*time.Minute, 2*time.Second)
+	}
+}
+
+func TestTenantIsolation_Metrics(t *testing.T) {
+	id := runID()
+
+	// A single request mixing the metrics of both tenants, each tenant reporting its own value
+	request := &colmetricspb.ExportMetricsServiceRequest{}
+	for i, tenant := range tenants {
+		request.ResourceMetrics = append(request.ResourceMetrics, &metricpb.ResourceMetrics{
+			Resource: resource(tenant, "e2e"),
+			ScopeMetrics: []*metricpb.ScopeMetrics{{Metrics: []*metricpb.Metric{{
+				Name: "e2e_tenant_gauge",
+				Data: &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: []*metricpb.NumberDataPoint{{
+					Attributes: []*commonpb.KeyValue{
+						{Key: "run", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: id}}},
+					},
+					TimeUnixNano: uint64(time.Now().UnixNano()),
+					Value:        &metricpb.NumberDataPoint_AsInt{AsInt: int64(i + 1)},
+				}}}},
+			}}}},
+		})
+	}
+	push(t, "/v1/metrics", request)
+
+	params := url.Values{"query": {fmt.Sprintf(`e2e_tenant_gauge{run=%q}`, id)}}
+	for i, tenant := range tenants {
+		require.EventuallyWithT(t, func(c *assert.CollectT) {
+			var response struct {
+				Data struct {
+					Result []struct {
+						Value [2]any `json:"value"`
+					} `json:"result"`
+				} `json:"data"`
+			}
+			if query(c, "mimir", "/prometheus/api/v1/query?"+params.Encode(), tenant, &response) != http.StatusOK {
+				c.Errorf("mimir query as %s failed", tenant)
+				return
+			}
+
+			var values []any
+			for _, series := range response.Data.Result {
+				values = append(values, series.Value[1])
+			}
+			assert.Equal(c, []any{strconv.Itoa(i + 1)}, values)
+		}, 2*time.Minute, 2*time.Second)
+	}
+}
+
+func TestTenantIsolation_Traces(t *testing.T) {
+	// A single request mixing the spans of both tenants, each tenant in its own trace
+	request := &coltracepb.ExportTraceServiceRequest{}
+	traceIDs := make(map[string]string)
+	for _, tenant := range tenants {
+		traceID, spanID := make([]byte, 16), make([]byte, 8)
+		_, _ = rand.Read(traceID)
+		_, _ = rand.Read(spanID)
+		traceIDs[tenant] = hex.EncodeToString(traceID)
+
+		request.ResourceSpans = append(request.ResourceSpans, &tracepb.ResourceSpans{
+			Resource: resource(tenant, "e2e"),
+			ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+				TraceId:           traceID,
+				SpanId:            spanID,
+				Name:              "e2e",
+				StartTimeUnixNano: uint64(time.Now().Add(-time.Second).UnixNano()),
+				EndTimeUnixNano:   uint64(time.Now().UnixNano()),
+			}}}},
+		})
+	}
+	push(t, "/v1/traces", request)
+
+	for _, tenant := range tenants {
+		require.EventuallyWithT(t, func(c *assert.CollectT) {
+			assert.Equal(c, http.StatusOK, query(c, "tempo", "/api/traces/"+traceIDs[tenant], tenant, nil))
+		}, 2*time.Minute, 2*time.Second)
+	}
+
+	// Once stored, the trace of a tenant cannot be found by the other
+	assert.Equal(t, http.StatusNotFound, query(t, "tempo", "/api/traces/"+traceIDs["tenant-a"], "tenant-b", nil))
+	assert.Equal(t, http.StatusNotFound, query(t, "tempo", "/api/traces/"+traceIDs["tenant-b"], "tenant-a", nil))
+}